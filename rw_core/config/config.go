@@ -24,117 +24,252 @@ import (
 
 // RW Core service default constants
 const (
-	ConsulStoreName                  = "consul"
-	EtcdStoreName                    = "etcd"
-	defaultGrpcPort                  = 50057
-	defaultGrpcHost                  = ""
-	defaultKafkaAdapterHost          = "127.0.0.1"
-	defaultKafkaAdapterPort          = 9092
-	defaultKafkaClusterHost          = "127.0.0.1"
-	defaultKafkaClusterPort          = 9094
-	defaultKVStoreType               = EtcdStoreName
-	defaultKVStoreTimeout            = 5 //in seconds
-	defaultKVStoreHost               = "127.0.0.1"
-	defaultKVStorePort               = 2379 // Consul = 8500; Etcd = 2379
-	defaultKVTxnKeyDelTime           = 60
-	defaultKVStoreDataPrefix         = "service/voltha"
-	defaultLogLevel                  = "WARN"
-	defaultBanner                    = false
-	defaultDisplayVersionOnly        = false
-	defaultCoreTopic                 = "rwcore"
-	defaultRWCoreEndpoint            = "rwcore"
-	defaultRWCoreKey                 = "pki/voltha.key"
-	defaultRWCoreCert                = "pki/voltha.crt"
-	defaultRWCoreCA                  = "pki/voltha-CA.pem"
-	defaultAffinityRouterTopic       = "affinityRouter"
-	defaultInCompetingMode           = true
-	defaultLongRunningRequestTimeout = 2000 * time.Millisecond
-	defaultDefaultRequestTimeout     = 1000 * time.Millisecond
-	defaultCoreTimeout               = 1000 * time.Millisecond
-	defaultCoreBindingKey            = "voltha_backend_name"
-	defaultCorePairTopic             = "rwcore_1"
-	defaultMaxConnectionRetries      = -1 // retries forever
-	defaultConnectionRetryInterval   = 2 * time.Second
-	defaultLiveProbeInterval         = 60 * time.Second
-	defaultNotLiveProbeInterval      = 5 * time.Second // Probe more frequently when not alive
-	defaultProbeHost                 = ""
-	defaultProbePort                 = 8080
+	ConsulStoreName                   = "consul"
+	EtcdStoreName                     = "etcd"
+	defaultGrpcPort                   = 50057
+	defaultGrpcHost                   = ""
+	defaultKafkaAdapterHost           = "127.0.0.1"
+	defaultKafkaAdapterPort           = 9092
+	defaultKafkaClusterHost           = "127.0.0.1"
+	defaultKafkaClusterPort           = 9094
+	defaultKVStoreType                = EtcdStoreName
+	defaultKVStoreTimeout             = 5 //in seconds
+	defaultKVStoreHost                = "127.0.0.1"
+	defaultKVStorePort                = 2379 // Consul = 8500; Etcd = 2379
+	defaultKVTxnKeyDelTime            = 60
+	defaultKVStoreDataPrefix          = "service/voltha"
+	defaultLogLevel                   = "WARN"
+	defaultBanner                     = false
+	defaultDisplayVersionOnly         = false
+	defaultCoreTopic                  = "rwcore"
+	defaultRWCoreEndpoint             = "rwcore"
+	defaultRWCoreKey                  = "pki/voltha.key"
+	defaultRWCoreCert                 = "pki/voltha.crt"
+	defaultRWCoreCA                   = "pki/voltha-CA.pem"
+	defaultAffinityRouterTopic        = "affinityRouter"
+	defaultInCompetingMode            = true
+	defaultLongRunningRequestTimeout  = 2000 * time.Millisecond
+	defaultDefaultRequestTimeout      = 1000 * time.Millisecond
+	defaultCoreTimeout                = 1000 * time.Millisecond
+	defaultCoreBindingKey             = "voltha_backend_name"
+	defaultCorePairTopic              = "rwcore_1"
+	defaultMaxConnectionRetries       = -1 // retries forever
+	defaultConnectionRetryInterval    = 2 * time.Second
+	defaultLiveProbeInterval          = 60 * time.Second
+	defaultNotLiveProbeInterval       = 5 * time.Second // Probe more frequently when not alive
+	defaultProbeHost                  = ""
+	defaultProbePort                  = 8080
+	defaultDeviceTopicPolicy          = "disabled" // disabled | device-type | message-rate
+	defaultDeviceTopicDeviceTypes     = ""
+	defaultDeviceTopicMsgRate         = 0               // requests per minute; 0 disables rate based assignment
+	defaultSoftDeleteRetention        = 0 * time.Minute // how long deleted devices are retained; 0 purges immediately
+	defaultDeterministicUNINumbering  = false           // derive UNI OF port numbers from the PON topology instead of the adapter-reported numbers
+	defaultPermissiveFlowValidation   = false
+	defaultPermissiveStatusValidation = true // log-only until the offending adapters are cleaned up
+	defaultRejectFlowsAtCapacity      = false
+	defaultSkipFlowPortValidation     = false
+	defaultPacketInBufferWindow       = 5 * time.Second  // how long packet-ins are buffered while the logical device is not ready
+	defaultSkipLoadingDeletedAgents   = true             // do not recreate in-memory agents for devices in DELETED state
+	defaultStuckDeviceSweepInterval   = 10 * time.Minute // how often devices stuck in transient states are swept; 0 disables
+	defaultEnableChaosInjection       = false            // wire the chaos failure injection hooks; test environments only
+	defaultReconcileConcurrency       = 8                // devices reconciled in parallel per adapter after an adapter restart
+	defaultReconcileStartDelay        = 0 * time.Second  // grace period after adapter re-registration before reconcile starts
+	defaultReadConsistency            = "default"        // device read consistency level: cached, default or strong
+	defaultStaleReadThreshold         = 5 * time.Second  // cached copy age beyond which a default-level read hits the KV store
+	defaultImageDownloadRetention     = 24 * time.Hour   // how long finished image download records are kept; 0 disables age pruning
+	defaultImageDownloadMaxRecords    = 16               // finished image download records kept per device; 0 disables count pruning
+	defaultStateHistorySize           = 32               // state transition records kept in memory per device
+	defaultStateHistoryPersist        = 1 * time.Minute  // minimum interval between state history flushes to the KV store
+	defaultDeviceSnapshotCadence      = 10 * time.Minute // interval between agent snapshot writes to the KV store; 0 disables snapshots
+	defaultDeviceFootprintWarnBytes   = 0                // device memory footprint raising a warning event; 0 disables the warning
+	defaultDeviceFootprintLimitBytes  = 0                // device memory footprint above which flow adds are refused; 0 disables the cap
+	defaultImagePreflight             = "off"            // image download preflight: off, core (HEAD probe from the core) or adapter (delegated)
+	defaultImageChecksumRequired      = false            // refuse image download requests that carry no checksum
+	defaultParkUnreachableRequests    = true             // park suppressed requests for replay when an unreachable device returns
+	defaultFlowParkingWindow          = 30 * time.Second // how long flows received during activation may wait before being dropped
+	defaultFlowParkingMax             = 128              // flow updates parked per device while it is activating
+	defaultDeviceAuditInterval        = 10 * time.Minute // how often the agent map is audited against ownership and KV; 0 disables
+	defaultDeviceAuditRepair          = true             // repair the inconsistencies found by the device audit
+	defaultDeletionBarrierTimeout     = 10 * time.Second // how long a deletion waits for the adapter to remove the flows; 0 disables
+	defaultBreakerFailureThreshold    = 5                // consecutive transport failures before an adapter topic's breaker opens; 0 disables
+	defaultBreakerResetInterval       = 30 * time.Second // how long an open breaker waits before letting a probe request through
+	defaultActivityPersistInterval    = 5 * time.Minute  // how often the per-device activity timestamps are flushed to the KV store; 0 disables
+	defaultQuietDeviceThreshold       = 30 * time.Minute // how long a device may be silent before the quiet-device filter flags it
+	defaultDiscoveryBatchWindow       = 1 * time.Second  // how long device discovery notifications are held for batching; 0 publishes immediately
+	defaultDiscoveryBatchSize         = 32               // queued discovery notifications that force a flush before the window closes
+	defaultPerDeviceDiscovery         = false            // publish one discovery message per device instead of aggregated batches
+	defaultAdapterSkewTolerance       = 30 * time.Second // adapter clock skew beyond which the warning event is raised; 0 disables
+	defaultEnableTenantFiltering      = false            // hide devices annotated with a tenant different from the caller's
+	defaultOperationQuotaRate         = 0                // tokens per minute refilled into each caller's NBI operation bucket; 0 disables quotas
+	defaultOperationQuotaBurst        = 10               // capacity of each caller's NBI operation bucket
+	defaultShutdownStageTimeout       = 10 * time.Second
+	defaultDiagnosticsStallThreshold  = 5 * time.Minute // how long an operation may run before diagnostics flags it // how long each stage of the ordered shutdown may take
 )
 
 // RWCoreFlags represents the set of configurations used by the read-write core service
 type RWCoreFlags struct {
 	// Command line parameters
-	RWCoreEndpoint            string
-	GrpcHost                  string
-	GrpcPort                  int
-	KafkaAdapterHost          string
-	KafkaAdapterPort          int
-	KafkaClusterHost          string
-	KafkaClusterPort          int
-	KVStoreType               string
-	KVStoreTimeout            int // in seconds
-	KVStoreHost               string
-	KVStorePort               int
-	KVTxnKeyDelTime           int
-	KVStoreDataPrefix         string
-	CoreTopic                 string
-	LogLevel                  string
-	Banner                    bool
-	DisplayVersionOnly        bool
-	RWCoreKey                 string
-	RWCoreCert                string
-	RWCoreCA                  string
-	AffinityRouterTopic       string
-	InCompetingMode           bool
-	LongRunningRequestTimeout time.Duration
-	DefaultRequestTimeout     time.Duration
-	DefaultCoreTimeout        time.Duration
-	CoreBindingKey            string
-	CorePairTopic             string
-	MaxConnectionRetries      int
-	ConnectionRetryInterval   time.Duration
-	LiveProbeInterval         time.Duration
-	NotLiveProbeInterval      time.Duration
-	ProbeHost                 string
-	ProbePort                 int
+	RWCoreEndpoint             string
+	GrpcHost                   string
+	GrpcPort                   int
+	KafkaAdapterHost           string
+	KafkaAdapterPort           int
+	KafkaClusterHost           string
+	KafkaClusterPort           int
+	KVStoreType                string
+	KVStoreTimeout             int // in seconds
+	KVStoreHost                string
+	KVStorePort                int
+	KVTxnKeyDelTime            int
+	KVStoreDataPrefix          string
+	CoreTopic                  string
+	LogLevel                   string
+	Banner                     bool
+	DisplayVersionOnly         bool
+	RWCoreKey                  string
+	RWCoreCert                 string
+	RWCoreCA                   string
+	AffinityRouterTopic        string
+	InCompetingMode            bool
+	LongRunningRequestTimeout  time.Duration
+	DefaultRequestTimeout      time.Duration
+	DefaultCoreTimeout         time.Duration
+	CoreBindingKey             string
+	CorePairTopic              string
+	MaxConnectionRetries       int
+	ConnectionRetryInterval    time.Duration
+	LiveProbeInterval          time.Duration
+	NotLiveProbeInterval       time.Duration
+	ProbeHost                  string
+	ProbePort                  int
+	DeviceTopicPolicy          string
+	DeviceTopicDeviceTypes     string
+	DeviceTopicMsgRate         int
+	SoftDeleteRetention        time.Duration
+	DeterministicUNINumbering  bool
+	PermissiveFlowValidation   bool
+	PermissiveStatusValidation bool
+	RejectFlowsAtCapacity      bool
+	SkipFlowPortValidation     bool
+	PacketInBufferWindow       time.Duration
+	SkipLoadingDeletedAgents   bool
+	StuckDeviceSweepInterval   time.Duration
+	EnableChaosInjection       bool
+	ReconcileConcurrency       int
+	ReconcileStartDelay        time.Duration
+	ReadConsistency            string
+	StaleReadThreshold         time.Duration
+	ImageDownloadRetention     time.Duration
+	ImageDownloadMaxRecords    int
+	StateHistorySize           int
+	StateHistoryPersist        time.Duration
+	DeviceSnapshotCadence      time.Duration
+	DeviceFootprintWarnBytes   int64
+	DeviceFootprintLimitBytes  int64
+	ImagePreflight             string
+	ImageChecksumRequired      bool
+	ParkUnreachableRequests    bool
+	FlowParkingWindow          time.Duration
+	FlowParkingMax             int
+	DeviceAuditInterval        time.Duration
+	DeviceAuditRepair          bool
+	DeletionBarrierTimeout     time.Duration
+	BreakerFailureThreshold    int
+	BreakerResetInterval       time.Duration
+	ActivityPersistInterval    time.Duration
+	QuietDeviceThreshold       time.Duration
+	DiscoveryBatchWindow       time.Duration
+	DiscoveryBatchSize         int
+	PerDeviceDiscovery         bool
+	AdapterSkewTolerance       time.Duration
+	EnableTenantFiltering      bool
+	OperationQuotaRate         int
+	OperationQuotaBurst        int
+	ShutdownStageTimeout       time.Duration
+	DiagnosticsStallThreshold  time.Duration
 }
 
 // NewRWCoreFlags returns a new RWCore config
 func NewRWCoreFlags() *RWCoreFlags {
 	var rwCoreFlag = RWCoreFlags{ // Default values
-		RWCoreEndpoint:            defaultRWCoreEndpoint,
-		GrpcHost:                  defaultGrpcHost,
-		GrpcPort:                  defaultGrpcPort,
-		KafkaAdapterHost:          defaultKafkaAdapterHost,
-		KafkaAdapterPort:          defaultKafkaAdapterPort,
-		KafkaClusterHost:          defaultKafkaClusterHost,
-		KafkaClusterPort:          defaultKafkaClusterPort,
-		KVStoreType:               defaultKVStoreType,
-		KVStoreTimeout:            defaultKVStoreTimeout,
-		KVStoreHost:               defaultKVStoreHost,
-		KVStorePort:               defaultKVStorePort,
-		KVStoreDataPrefix:         defaultKVStoreDataPrefix,
-		KVTxnKeyDelTime:           defaultKVTxnKeyDelTime,
-		CoreTopic:                 defaultCoreTopic,
-		LogLevel:                  defaultLogLevel,
-		Banner:                    defaultBanner,
-		DisplayVersionOnly:        defaultDisplayVersionOnly,
-		RWCoreKey:                 defaultRWCoreKey,
-		RWCoreCert:                defaultRWCoreCert,
-		RWCoreCA:                  defaultRWCoreCA,
-		AffinityRouterTopic:       defaultAffinityRouterTopic,
-		InCompetingMode:           defaultInCompetingMode,
-		DefaultRequestTimeout:     defaultDefaultRequestTimeout,
-		LongRunningRequestTimeout: defaultLongRunningRequestTimeout,
-		DefaultCoreTimeout:        defaultCoreTimeout,
-		CoreBindingKey:            defaultCoreBindingKey,
-		CorePairTopic:             defaultCorePairTopic,
-		MaxConnectionRetries:      defaultMaxConnectionRetries,
-		ConnectionRetryInterval:   defaultConnectionRetryInterval,
-		LiveProbeInterval:         defaultLiveProbeInterval,
-		NotLiveProbeInterval:      defaultNotLiveProbeInterval,
-		ProbeHost:                 defaultProbeHost,
-		ProbePort:                 defaultProbePort,
+		RWCoreEndpoint:             defaultRWCoreEndpoint,
+		GrpcHost:                   defaultGrpcHost,
+		GrpcPort:                   defaultGrpcPort,
+		KafkaAdapterHost:           defaultKafkaAdapterHost,
+		KafkaAdapterPort:           defaultKafkaAdapterPort,
+		KafkaClusterHost:           defaultKafkaClusterHost,
+		KafkaClusterPort:           defaultKafkaClusterPort,
+		KVStoreType:                defaultKVStoreType,
+		KVStoreTimeout:             defaultKVStoreTimeout,
+		KVStoreHost:                defaultKVStoreHost,
+		KVStorePort:                defaultKVStorePort,
+		KVStoreDataPrefix:          defaultKVStoreDataPrefix,
+		KVTxnKeyDelTime:            defaultKVTxnKeyDelTime,
+		CoreTopic:                  defaultCoreTopic,
+		LogLevel:                   defaultLogLevel,
+		Banner:                     defaultBanner,
+		DisplayVersionOnly:         defaultDisplayVersionOnly,
+		RWCoreKey:                  defaultRWCoreKey,
+		RWCoreCert:                 defaultRWCoreCert,
+		RWCoreCA:                   defaultRWCoreCA,
+		AffinityRouterTopic:        defaultAffinityRouterTopic,
+		InCompetingMode:            defaultInCompetingMode,
+		DefaultRequestTimeout:      defaultDefaultRequestTimeout,
+		LongRunningRequestTimeout:  defaultLongRunningRequestTimeout,
+		DefaultCoreTimeout:         defaultCoreTimeout,
+		CoreBindingKey:             defaultCoreBindingKey,
+		CorePairTopic:              defaultCorePairTopic,
+		MaxConnectionRetries:       defaultMaxConnectionRetries,
+		ConnectionRetryInterval:    defaultConnectionRetryInterval,
+		LiveProbeInterval:          defaultLiveProbeInterval,
+		NotLiveProbeInterval:       defaultNotLiveProbeInterval,
+		ProbeHost:                  defaultProbeHost,
+		ProbePort:                  defaultProbePort,
+		DeviceTopicPolicy:          defaultDeviceTopicPolicy,
+		DeviceTopicDeviceTypes:     defaultDeviceTopicDeviceTypes,
+		DeviceTopicMsgRate:         defaultDeviceTopicMsgRate,
+		SoftDeleteRetention:        defaultSoftDeleteRetention,
+		DeterministicUNINumbering:  defaultDeterministicUNINumbering,
+		PermissiveFlowValidation:   defaultPermissiveFlowValidation,
+		PermissiveStatusValidation: defaultPermissiveStatusValidation,
+		RejectFlowsAtCapacity:      defaultRejectFlowsAtCapacity,
+		SkipFlowPortValidation:     defaultSkipFlowPortValidation,
+		PacketInBufferWindow:       defaultPacketInBufferWindow,
+		SkipLoadingDeletedAgents:   defaultSkipLoadingDeletedAgents,
+		StuckDeviceSweepInterval:   defaultStuckDeviceSweepInterval,
+		EnableChaosInjection:       defaultEnableChaosInjection,
+		ReconcileConcurrency:       defaultReconcileConcurrency,
+		ReconcileStartDelay:        defaultReconcileStartDelay,
+		ReadConsistency:            defaultReadConsistency,
+		StaleReadThreshold:         defaultStaleReadThreshold,
+		ImageDownloadRetention:     defaultImageDownloadRetention,
+		ImageDownloadMaxRecords:    defaultImageDownloadMaxRecords,
+		StateHistorySize:           defaultStateHistorySize,
+		StateHistoryPersist:        defaultStateHistoryPersist,
+		DeviceSnapshotCadence:      defaultDeviceSnapshotCadence,
+		DeviceFootprintWarnBytes:   defaultDeviceFootprintWarnBytes,
+		DeviceFootprintLimitBytes:  defaultDeviceFootprintLimitBytes,
+		ImagePreflight:             defaultImagePreflight,
+		ImageChecksumRequired:      defaultImageChecksumRequired,
+		ParkUnreachableRequests:    defaultParkUnreachableRequests,
+		FlowParkingWindow:          defaultFlowParkingWindow,
+		FlowParkingMax:             defaultFlowParkingMax,
+		DeviceAuditInterval:        defaultDeviceAuditInterval,
+		DeviceAuditRepair:          defaultDeviceAuditRepair,
+		DeletionBarrierTimeout:     defaultDeletionBarrierTimeout,
+		BreakerFailureThreshold:    defaultBreakerFailureThreshold,
+		BreakerResetInterval:       defaultBreakerResetInterval,
+		ActivityPersistInterval:    defaultActivityPersistInterval,
+		QuietDeviceThreshold:       defaultQuietDeviceThreshold,
+		DiscoveryBatchWindow:       defaultDiscoveryBatchWindow,
+		DiscoveryBatchSize:         defaultDiscoveryBatchSize,
+		PerDeviceDiscovery:         defaultPerDeviceDiscovery,
+		AdapterSkewTolerance:       defaultAdapterSkewTolerance,
+		EnableTenantFiltering:      defaultEnableTenantFiltering,
+		OperationQuotaRate:         defaultOperationQuotaRate,
+		OperationQuotaBurst:        defaultOperationQuotaBurst,
+		ShutdownStageTimeout:       defaultShutdownStageTimeout,
+		DiagnosticsStallThreshold:  defaultDiagnosticsStallThreshold,
 	}
 	return &rwCoreFlag
 }
@@ -237,5 +372,140 @@ func (cf *RWCoreFlags) ParseCommandArguments() {
 	help = fmt.Sprintf("The port on which to listen to answer liveness and readiness probe queries over HTTP.")
 	flag.IntVar(&(cf.ProbePort), "probe_port", defaultProbePort, help)
 
+	help = fmt.Sprintf("The policy used to assign dedicated kafka request topics to high-volume devices (disabled | device-type | message-rate)")
+	flag.StringVar(&(cf.DeviceTopicPolicy), "device_topic_policy", defaultDeviceTopicPolicy, help)
+
+	help = fmt.Sprintf("Comma separated list of device types that get a dedicated request topic when the device-type policy is in effect")
+	flag.StringVar(&(cf.DeviceTopicDeviceTypes), "device_topic_device_types", defaultDeviceTopicDeviceTypes, help)
+
+	help = fmt.Sprintf("The number of requests per minute to a device beyond which it gets a dedicated request topic when the message-rate policy is in effect")
+	flag.IntVar(&(cf.DeviceTopicMsgRate), "device_topic_msg_rate", defaultDeviceTopicMsgRate, help)
+
+	help = fmt.Sprintf("How long deleted devices are retained under a tombstone before being purged.  A zero value purges immediately")
+	flag.DurationVar(&(cf.SoftDeleteRetention), "soft_delete_retention", defaultSoftDeleteRetention, help)
+
+	help = fmt.Sprintf("Derive UNI OF port numbers from the PON ID, ONU ID and UNI index instead of reusing the adapter-reported physical port numbers")
+	flag.BoolVar(&(cf.DeterministicUNINumbering), "deterministic_uni_numbering", defaultDeterministicUNINumbering, help)
+
+	help = fmt.Sprintf("Forward flows to the adapter even when they fail device-type validation.  Intended for experimental adapters")
+	flag.BoolVar(&(cf.PermissiveFlowValidation), "permissive_flow_validation", defaultPermissiveFlowValidation, help)
+
+	help = fmt.Sprintf("Log and count invalid device status updates instead of rejecting them.  Intended for the initial rollout of status validation")
+	flag.BoolVar(&(cf.PermissiveStatusValidation), "permissive_status_validation", defaultPermissiveStatusValidation, help)
+
+	help = fmt.Sprintf("Reject flow installs that would exceed the registered flow table capacity of the device type")
+	flag.BoolVar(&(cf.RejectFlowsAtCapacity), "reject_flows_at_capacity", defaultRejectFlowsAtCapacity, help)
+
+	help = fmt.Sprintf("Do not validate flow port references against the device port list")
+	flag.BoolVar(&(cf.SkipFlowPortValidation), "skip_flow_port_validation", defaultSkipFlowPortValidation, help)
+
+	help = fmt.Sprintf("How long packet-ins are buffered while the logical device is not ready.  A zero value disables buffering")
+	flag.DurationVar(&(cf.PacketInBufferWindow), "packet_in_buffer_window", defaultPacketInBufferWindow, help)
+
+	help = fmt.Sprintf("Do not recreate in-memory agents for devices in DELETED state when loading from the model")
+	flag.BoolVar(&(cf.SkipLoadingDeletedAgents), "skip_loading_deleted_agents", defaultSkipLoadingDeletedAgents, help)
+
+	help = fmt.Sprintf("How often devices stuck in transient states are swept for remediation.  A zero value disables the sweeper")
+	flag.DurationVar(&(cf.StuckDeviceSweepInterval), "stuck_device_sweep_interval", defaultStuckDeviceSweepInterval, help)
+
+	help = fmt.Sprintf("Wire the chaos failure injection hooks around adapter and KV store calls.  Test environments only")
+	flag.BoolVar(&(cf.EnableChaosInjection), "enable_chaos_injection", defaultEnableChaosInjection, help)
+
+	help = fmt.Sprintf("The number of devices reconciled in parallel per adapter after an adapter restart")
+	flag.IntVar(&(cf.ReconcileConcurrency), "reconcile_concurrency", defaultReconcileConcurrency, help)
+
+	help = fmt.Sprintf("The grace period after an adapter re-registration before its devices are reconciled")
+	flag.DurationVar(&(cf.ReconcileStartDelay), "reconcile_start_delay", defaultReconcileStartDelay, help)
+
+	help = fmt.Sprintf("The default device read consistency level: cached, default or strong")
+	flag.StringVar(&(cf.ReadConsistency), "read_consistency", defaultReadConsistency, help)
+
+	help = fmt.Sprintf("The cached device copy age beyond which a default-level read hits the KV store")
+	flag.DurationVar(&(cf.StaleReadThreshold), "stale_read_threshold", defaultStaleReadThreshold, help)
+
+	help = fmt.Sprintf("How long finished image download records are kept per device; 0 disables age pruning")
+	flag.DurationVar(&(cf.ImageDownloadRetention), "image_download_retention", defaultImageDownloadRetention, help)
+
+	help = fmt.Sprintf("The number of finished image download records kept per device; 0 disables count pruning")
+	flag.IntVar(&(cf.ImageDownloadMaxRecords), "image_download_max_records", defaultImageDownloadMaxRecords, help)
+
+	help = fmt.Sprintf("The number of state transition records kept in memory per device")
+	flag.IntVar(&(cf.StateHistorySize), "state_history_size", defaultStateHistorySize, help)
+
+	help = fmt.Sprintf("The minimum interval between state history flushes to the KV store")
+	flag.DurationVar(&(cf.StateHistoryPersist), "state_history_persist", defaultStateHistoryPersist, help)
+
+	help = fmt.Sprintf("The interval between device agent snapshot writes to the KV store; 0 disables snapshots")
+	flag.DurationVar(&(cf.DeviceSnapshotCadence), "device_snapshot_cadence", defaultDeviceSnapshotCadence, help)
+
+	help = fmt.Sprintf("The approximate device memory footprint in bytes raising a warning event; 0 disables the warning")
+	flag.Int64Var(&(cf.DeviceFootprintWarnBytes), "device_footprint_warn_bytes", defaultDeviceFootprintWarnBytes, help)
+
+	help = fmt.Sprintf("The approximate device memory footprint in bytes above which flow adds are refused; 0 disables the cap")
+	flag.Int64Var(&(cf.DeviceFootprintLimitBytes), "device_footprint_limit_bytes", defaultDeviceFootprintLimitBytes, help)
+
+	help = fmt.Sprintf("Image download preflight mode: off, core (HEAD probe from the core) or adapter (delegated to the adapter)")
+	flag.StringVar(&(cf.ImagePreflight), "image_preflight", defaultImagePreflight, help)
+
+	help = fmt.Sprintf("Refuse image download requests that carry no checksum")
+	flag.BoolVar(&(cf.ImageChecksumRequired), "image_checksum_required", defaultImageChecksumRequired, help)
+
+	help = fmt.Sprintf("Park requests suppressed against an unreachable device for replay on reachability")
+	flag.BoolVar(&(cf.ParkUnreachableRequests), "park_unreachable_requests", defaultParkUnreachableRequests, help)
+
+	help = fmt.Sprintf("How long flows received during activation may wait before being dropped")
+	flag.DurationVar(&(cf.FlowParkingWindow), "flow_parking_window", defaultFlowParkingWindow, help)
+
+	help = fmt.Sprintf("The number of flow updates parked per device while it is activating")
+	flag.IntVar(&(cf.FlowParkingMax), "flow_parking_max", defaultFlowParkingMax, help)
+
+	help = fmt.Sprintf("How often the in-memory agent map is audited against ownership and the KV store.  A zero value disables the audit")
+	flag.DurationVar(&(cf.DeviceAuditInterval), "device_audit_interval", defaultDeviceAuditInterval, help)
+
+	help = fmt.Sprintf("Repair the inconsistencies found by the device audit instead of only logging them")
+	flag.BoolVar(&(cf.DeviceAuditRepair), "device_audit_repair", defaultDeviceAuditRepair, help)
+
+	help = fmt.Sprintf("How long a deletion waits for the adapter to acknowledge the flow removal.  A zero value disables the barrier")
+	flag.DurationVar(&(cf.DeletionBarrierTimeout), "deletion_barrier_timeout", defaultDeletionBarrierTimeout, help)
+
+	help = fmt.Sprintf("Consecutive transport failures before an adapter topic's circuit breaker opens.  A zero value disables the breakers")
+	flag.IntVar(&(cf.BreakerFailureThreshold), "breaker_failure_threshold", defaultBreakerFailureThreshold, help)
+
+	help = fmt.Sprintf("How long an open circuit breaker waits before letting a probe request through")
+	flag.DurationVar(&(cf.BreakerResetInterval), "breaker_reset_interval", defaultBreakerResetInterval, help)
+
+	help = fmt.Sprintf("How often the per-device activity timestamps are flushed to the KV store.  A zero value disables the flush")
+	flag.DurationVar(&(cf.ActivityPersistInterval), "activity_persist_interval", defaultActivityPersistInterval, help)
+
+	help = fmt.Sprintf("How long a device may be silent before the quiet-device filter flags it")
+	flag.DurationVar(&(cf.QuietDeviceThreshold), "quiet_device_threshold", defaultQuietDeviceThreshold, help)
+
+	help = fmt.Sprintf("How long device discovery notifications are held for batching.  A zero value publishes each immediately")
+	flag.DurationVar(&(cf.DiscoveryBatchWindow), "discovery_batch_window", defaultDiscoveryBatchWindow, help)
+
+	help = fmt.Sprintf("The number of queued discovery notifications that forces a flush before the window closes")
+	flag.IntVar(&(cf.DiscoveryBatchSize), "discovery_batch_size", defaultDiscoveryBatchSize, help)
+
+	help = fmt.Sprintf("Publish one discovery message per device instead of aggregated batches, for consumers that cannot parse the aggregated form")
+	flag.BoolVar(&(cf.PerDeviceDiscovery), "per_device_discovery", defaultPerDeviceDiscovery, help)
+
+	help = fmt.Sprintf("How far an adapter-reported timestamp may stray from the local clock before the skew warning event is raised.  A zero value disables the warning")
+	flag.DurationVar(&(cf.AdapterSkewTolerance), "adapter_skew_tolerance", defaultAdapterSkewTolerance, help)
+
+	help = fmt.Sprintf("Hide devices annotated with a tenant different from the caller's voltha-tenant metadata")
+	flag.BoolVar(&(cf.EnableTenantFiltering), "enable_tenant_filtering", defaultEnableTenantFiltering, help)
+
+	help = fmt.Sprintf("Tokens per minute refilled into each caller's NBI operation quota bucket.  A zero value disables operation quotas")
+	flag.IntVar(&(cf.OperationQuotaRate), "operation_quota_rate", defaultOperationQuotaRate, help)
+
+	help = fmt.Sprintf("Capacity of each caller's NBI operation quota bucket")
+	flag.IntVar(&(cf.OperationQuotaBurst), "operation_quota_burst", defaultOperationQuotaBurst, help)
+
+	help = fmt.Sprintf("How long each stage of the ordered shutdown may take before it is abandoned and the next stage proceeds")
+	flag.DurationVar(&(cf.ShutdownStageTimeout), "shutdown_stage_timeout", defaultShutdownStageTimeout, help)
+
+	help = fmt.Sprintf("How long an operation may run before the diagnostics self-report flags it as stuck")
+	flag.DurationVar(&(cf.DiagnosticsStallThreshold), "diagnostics_stall_threshold", defaultDiagnosticsStallThreshold, help)
+
 	flag.Parse()
 }
@@ -0,0 +1,335 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Operators watch how long device activation takes - from discovery to the first ACTIVE
+// oper status - to catch regressions after software upgrades.  Each device agent stamps
+// the activation milestones (discovery, enable, first ACTIVE, first flow applied); once a
+// device turns ACTIVE the tracker appends an activation record to the per-device history,
+// feeds a latency histogram per device type and persists the history so it survives agent
+// eviction.  An activation that the stuck-device sweeper gives up on is recorded with the
+// transient state it was stuck in as the failure reason.
+const (
+	activationHistoryPath = "activation_history"
+	// maxActivationRecords is how many activation records are kept per device
+	maxActivationRecords = 10
+)
+
+// activationLatencyBuckets are the histogram upper bounds; latencies above the last bucket
+// land in the overflow count
+var activationLatencyBuckets = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// ActivationRecord describes one activation attempt of a device
+type ActivationRecord struct {
+	DeviceID      string
+	DeviceType    string
+	DiscoveredAt  time.Time
+	EnabledAt     time.Time
+	ActiveAt      time.Time
+	FirstFlowAt   time.Time
+	Latency       time.Duration
+	Completed     bool
+	FailureReason string
+}
+
+// ActivationHistogram is a snapshot of the activation latencies of one device type.
+// Buckets[i] counts completed activations no slower than activationLatencyBuckets[i];
+// Overflow counts the slower ones.
+type ActivationHistogram struct {
+	DeviceType string
+	Buckets    []uint64
+	Overflow   uint64
+	Completed  uint64
+	Failed     uint64
+}
+
+// activationTimestamps carries the milestone timestamps of the current activation attempt
+// of one device agent
+type activationTimestamps struct {
+	lock         sync.Mutex
+	discoveredAt time.Time
+	enabledAt    time.Time
+	activeAt     time.Time
+	firstFlowAt  time.Time
+	// recorded guards that one attempt produces at most one record
+	recorded bool
+}
+
+func newActivationTimestamps() *activationTimestamps {
+	return &activationTimestamps{discoveredAt: time.Now()}
+}
+
+// markEnabled stamps the enable milestone of the current attempt
+func (at *activationTimestamps) markEnabled() {
+	at.lock.Lock()
+	defer at.lock.Unlock()
+	if at.enabledAt.IsZero() {
+		at.enabledAt = time.Now()
+	}
+}
+
+// activationTracker aggregates the activation records and latency histograms of all devices
+type activationTracker struct {
+	lock             sync.Mutex
+	history          map[string][]*ActivationRecord // device ID -> last maxActivationRecords records
+	histograms       map[string]*ActivationHistogram
+	clusterDataProxy *model.Proxy
+}
+
+func newActivationTracker(cdProxy *model.Proxy) *activationTracker {
+	return &activationTracker{
+		history:          make(map[string][]*ActivationRecord),
+		histograms:       make(map[string]*ActivationHistogram),
+		clusterDataProxy: cdProxy,
+	}
+}
+
+// recordActivationActive is invoked by the agent on the first transition to ACTIVE; it
+// completes the current attempt and appends the record
+func (agent *DeviceAgent) recordActivationActive(ctx context.Context, device *voltha.Device) {
+	agent.activation.lock.Lock()
+	if agent.activation.recorded || !agent.activation.activeAt.IsZero() {
+		agent.activation.lock.Unlock()
+		return
+	}
+	agent.activation.activeAt = time.Now()
+	agent.activation.recorded = true
+	record := &ActivationRecord{
+		DeviceID:     device.Id,
+		DeviceType:   device.Type,
+		DiscoveredAt: agent.activation.discoveredAt,
+		EnabledAt:    agent.activation.enabledAt,
+		ActiveAt:     agent.activation.activeAt,
+		Latency:      agent.activation.activeAt.Sub(agent.activation.discoveredAt),
+		Completed:    true,
+	}
+	agent.activation.lock.Unlock()
+	ctxInfow(ctx, "device-activation-completed", log.Fields{"device-id": device.Id, "latency": record.Latency})
+	agent.deviceMgr.activationTracker.append(ctx, record)
+}
+
+// recordActivationFailure records an activation that never completed, carrying the
+// transient state the remediation gave up on as the failure reason
+func (agent *DeviceAgent) recordActivationFailure(ctx context.Context, device *voltha.Device, reason string) {
+	agent.activation.lock.Lock()
+	if agent.activation.recorded {
+		agent.activation.lock.Unlock()
+		return
+	}
+	agent.activation.recorded = true
+	record := &ActivationRecord{
+		DeviceID:      device.Id,
+		DeviceType:    device.Type,
+		DiscoveredAt:  agent.activation.discoveredAt,
+		EnabledAt:     agent.activation.enabledAt,
+		FailureReason: reason,
+	}
+	agent.activation.lock.Unlock()
+	ctxWarnw(ctx, "device-activation-failed", log.Fields{"device-id": device.Id, "reason": reason})
+	agent.deviceMgr.activationTracker.append(ctx, record)
+}
+
+// markFirstFlowApplied stamps the first successfully applied flow on the current attempt
+// and folds it into the latest activation record
+func (agent *DeviceAgent) markFirstFlowApplied(ctx context.Context) {
+	agent.activation.lock.Lock()
+	if !agent.activation.firstFlowAt.IsZero() {
+		agent.activation.lock.Unlock()
+		return
+	}
+	agent.activation.firstFlowAt = time.Now()
+	firstFlowAt := agent.activation.firstFlowAt
+	agent.activation.lock.Unlock()
+	agent.deviceMgr.activationTracker.recordFirstFlow(ctx, agent.deviceID, firstFlowAt)
+}
+
+// append adds a record to the device history, trims it to maxActivationRecords, updates the
+// per-type histogram and persists the history
+func (tracker *activationTracker) append(ctx context.Context, record *ActivationRecord) {
+	tracker.lock.Lock()
+	records := append(tracker.history[record.DeviceID], record)
+	if len(records) > maxActivationRecords {
+		records = records[len(records)-maxActivationRecords:]
+	}
+	tracker.history[record.DeviceID] = records
+	tracker.observe(record)
+	tracker.lock.Unlock()
+	tracker.persist(ctx, record.DeviceID, records)
+}
+
+// observe folds one record into the histogram of its device type; the caller holds the lock
+func (tracker *activationTracker) observe(record *ActivationRecord) {
+	histogram, ok := tracker.histograms[record.DeviceType]
+	if !ok {
+		histogram = &ActivationHistogram{DeviceType: record.DeviceType, Buckets: make([]uint64, len(activationLatencyBuckets))}
+		tracker.histograms[record.DeviceType] = histogram
+	}
+	if !record.Completed {
+		histogram.Failed++
+		return
+	}
+	histogram.Completed++
+	for i, bound := range activationLatencyBuckets {
+		if record.Latency <= bound {
+			histogram.Buckets[i]++
+			return
+		}
+	}
+	histogram.Overflow++
+}
+
+// recordFirstFlow stamps the first applied flow on the latest record of a device
+func (tracker *activationTracker) recordFirstFlow(ctx context.Context, deviceID string, firstFlowAt time.Time) {
+	tracker.lock.Lock()
+	records := tracker.history[deviceID]
+	if len(records) == 0 || !records[len(records)-1].FirstFlowAt.IsZero() {
+		tracker.lock.Unlock()
+		return
+	}
+	records[len(records)-1].FirstFlowAt = firstFlowAt
+	tracker.lock.Unlock()
+	tracker.persist(ctx, deviceID, records)
+}
+
+// getHistory returns the activation records of a device, falling back to the persisted
+// history when the device is not tracked in memory
+func (tracker *activationTracker) getHistory(ctx context.Context, deviceID string) []*ActivationRecord {
+	tracker.lock.Lock()
+	records := tracker.history[deviceID]
+	tracker.lock.Unlock()
+	if len(records) > 0 {
+		return records
+	}
+	entry := &ic.StrType{}
+	if found, err := tracker.clusterDataProxy.Get(ctx, activationHistoryPath+"/"+deviceID, entry); err != nil || !found {
+		return nil
+	}
+	return decodeActivationHistory(entry.Val)
+}
+
+// getHistograms returns a snapshot of the latency histograms by device type
+func (tracker *activationTracker) getHistograms() map[string]ActivationHistogram {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	snapshot := make(map[string]ActivationHistogram, len(tracker.histograms))
+	for deviceType, histogram := range tracker.histograms {
+		copied := *histogram
+		copied.Buckets = append([]uint64{}, histogram.Buckets...)
+		snapshot[deviceType] = copied
+	}
+	return snapshot
+}
+
+// persist saves the history of one device to the KV store
+func (tracker *activationTracker) persist(ctx context.Context, deviceID string, records []*ActivationRecord) {
+	entry := &ic.StrType{Val: encodeActivationHistory(records)}
+	if err := tracker.clusterDataProxy.AddWithID(ctx, activationHistoryPath, deviceID, entry); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-activation-history", log.Fields{"device-id": deviceID, "error": err})
+	}
+}
+
+// encodeActivationHistory serializes the records of one device, one per ";"-separated
+// entry with "|"-separated fields; timestamps are unix nanoseconds with zero meaning unset
+func encodeActivationHistory(records []*ActivationRecord) string {
+	entries := make([]string, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, fmt.Sprintf("%s|%s|%d|%d|%d|%d|%d|%t|%s",
+			record.DeviceID, record.DeviceType,
+			timeToNanos(record.DiscoveredAt), timeToNanos(record.EnabledAt),
+			timeToNanos(record.ActiveAt), timeToNanos(record.FirstFlowAt),
+			record.Latency.Nanoseconds(), record.Completed, record.FailureReason))
+	}
+	return strings.Join(entries, ";")
+}
+
+// decodeActivationHistory deserializes an entry produced by encodeActivationHistory,
+// skipping entries that do not parse
+func decodeActivationHistory(val string) []*ActivationRecord {
+	var records []*ActivationRecord
+	for _, entry := range strings.Split(val, ";") {
+		parts := strings.SplitN(entry, "|", 9)
+		if len(parts) != 9 {
+			continue
+		}
+		discovered, err1 := strconv.ParseInt(parts[2], 10, 64)
+		enabled, err2 := strconv.ParseInt(parts[3], 10, 64)
+		active, err3 := strconv.ParseInt(parts[4], 10, 64)
+		firstFlow, err4 := strconv.ParseInt(parts[5], 10, 64)
+		latency, err5 := strconv.ParseInt(parts[6], 10, 64)
+		completed, err6 := strconv.ParseBool(parts[7])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil {
+			continue
+		}
+		records = append(records, &ActivationRecord{
+			DeviceID:      parts[0],
+			DeviceType:    parts[1],
+			DiscoveredAt:  nanosToTime(discovered),
+			EnabledAt:     nanosToTime(enabled),
+			ActiveAt:      nanosToTime(active),
+			FirstFlowAt:   nanosToTime(firstFlow),
+			Latency:       time.Duration(latency),
+			Completed:     completed,
+			FailureReason: parts[8],
+		})
+	}
+	return records
+}
+
+func timeToNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nanosToTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// getActivationHistory returns the last activation records of a device
+func (dMgr *DeviceManager) getActivationHistory(ctx context.Context, deviceID string) []*ActivationRecord {
+	return dMgr.activationTracker.getHistory(ctx, deviceID)
+}
+
+// getActivationHistograms returns the activation latency histograms by device type
+func (dMgr *DeviceManager) getActivationHistograms() map[string]ActivationHistogram {
+	return dMgr.activationTracker.getHistograms()
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivationLatencyRecorded(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:07:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// The first transition to ACTIVE completes the activation attempt
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.Nil(t, agent.updateDeviceStatus(ctx, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	records := dMgr.getActivationHistory(ctx, device.Id)
+	assert.Equal(t, 1, len(records))
+	assert.True(t, records[0].Completed)
+	assert.True(t, records[0].Latency > 0)
+	assert.False(t, records[0].EnabledAt.IsZero())
+
+	// The first applied flow is stamped on the record
+	agent.markFirstFlowApplied(ctx)
+	records = dMgr.getActivationHistory(ctx, device.Id)
+	assert.False(t, records[0].FirstFlowAt.IsZero())
+
+	// A repeated ACTIVE report does not create another record
+	assert.Nil(t, agent.updateDeviceStatus(ctx, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Equal(t, 1, len(dMgr.getActivationHistory(ctx, device.Id)))
+
+	// The latency lands in the per-type histogram
+	histograms := dMgr.getActivationHistograms()
+	assert.Equal(t, uint64(1), histograms["olt"].Completed)
+	bucketed := histograms["olt"].Overflow
+	for _, count := range histograms["olt"].Buckets {
+		bucketed += count
+	}
+	assert.Equal(t, uint64(1), bucketed)
+}
+
+func TestActivationFailureRecorded(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:07:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// The sweeper giving up on the device closes the attempt with the stuck state as reason
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	device, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	dMgr.remediateStuckDevice(ctx, agent, device, stuckStateActivating, remediationMarkFailed)
+
+	records := dMgr.getActivationHistory(ctx, device.Id)
+	assert.Equal(t, 1, len(records))
+	assert.False(t, records[0].Completed)
+	assert.Equal(t, stuckStateActivating, records[0].FailureReason)
+	assert.Equal(t, uint64(1), dMgr.getActivationHistograms()["olt"].Failed)
+}
+
+func TestActivationHistoryCodecAndTrimming(t *testing.T) {
+	ctx := context.Background()
+	tracker := newActivationTracker(newInMemoryClusterProxy())
+
+	now := time.Now()
+	original := &ActivationRecord{
+		DeviceID:     "dev-1",
+		DeviceType:   "onu",
+		DiscoveredAt: now.Add(-time.Minute),
+		EnabledAt:    now.Add(-50 * time.Second),
+		ActiveAt:     now,
+		Latency:      time.Minute,
+		Completed:    true,
+	}
+	decoded := decodeActivationHistory(encodeActivationHistory([]*ActivationRecord{original}))
+	assert.Equal(t, 1, len(decoded))
+	assert.Equal(t, original.DeviceID, decoded[0].DeviceID)
+	assert.Equal(t, original.Latency, decoded[0].Latency)
+	assert.True(t, decoded[0].Completed)
+	assert.True(t, decoded[0].FirstFlowAt.IsZero())
+
+	// Histories are trimmed to the last maxActivationRecords entries
+	for i := 0; i < maxActivationRecords+2; i++ {
+		tracker.append(ctx, &ActivationRecord{
+			DeviceID:   "dev-2",
+			DeviceType: "onu",
+			Latency:    time.Duration(i) * time.Second,
+			Completed:  true,
+		})
+	}
+	records := tracker.getHistory(ctx, "dev-2")
+	assert.Equal(t, maxActivationRecords, len(records))
+	assert.Equal(t, fmt.Sprintf("%ds", maxActivationRecords+1), records[len(records)-1].Latency.String())
+
+	// An untracked device falls back to the persisted history
+	restored := newActivationTracker(tracker.clusterDataProxy)
+	assert.Equal(t, maxActivationRecords, len(restored.getHistory(ctx, "dev-2")))
+}
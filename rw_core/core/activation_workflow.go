@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Different device types need different activation sequences - some want the logical
+// device before flows, some must not touch UNI ports until the ONU finished its MIB sync.
+// Rather than growing the transition table with per-type variants, a device type may
+// register an ordered list of named activation steps; when the activation transition
+// fires for a device of that type the registered sequence replaces the built-in handler
+// list.  Each step name maps to an existing internal operation, unknown names fail the
+// registration, and types without a registered workflow keep the current behavior.  The
+// DeviceType proto carries no step list, so workflows are declared against the core
+// through registerActivationWorkflow.
+
+// Activation step names and the internal operations they map to
+const (
+	activationStepLogicalDeviceSetup = "logical-device-setup" // DeviceManager.CreateLogicalDevice
+	activationStepUNIPortSetup       = "uni-port-setup"       // DeviceManager.SetupUNILogicalPorts
+	activationStepFlowRestore        = "flow-restore"         // DeviceManager.ReEnableDevice
+	activationStepActivate           = "activate"             // DeviceManager.ActivateDevice (PM start, capability fetch, event)
+)
+
+// resolveActivationStep maps a step name to the internal operation executing it
+func (dMgr *DeviceManager) resolveActivationStep(name string) (TransitionHandler, error) {
+	switch name {
+	case activationStepLogicalDeviceSetup:
+		return dMgr.CreateLogicalDevice, nil
+	case activationStepUNIPortSetup:
+		return dMgr.SetupUNILogicalPorts, nil
+	case activationStepFlowRestore:
+		return dMgr.ReEnableDevice, nil
+	case activationStepActivate:
+		return dMgr.ActivateDevice, nil
+	default:
+		return nil, newInvalidArgumentError("unknown-activation-step:" + name)
+	}
+}
+
+// validateActivationWorkflow rejects an empty sequence or any step without a mapping
+func (dMgr *DeviceManager) validateActivationWorkflow(steps []string) error {
+	if len(steps) == 0 {
+		return newInvalidArgumentError("empty-activation-workflow")
+	}
+	for _, step := range steps {
+		if _, err := dMgr.resolveActivationStep(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerActivationWorkflow declares the activation sequence for a device type.  An
+// invalid sequence fails the registration and leaves any earlier declaration in place.
+func (dMgr *DeviceManager) registerActivationWorkflow(deviceType string, steps []string) error {
+	if err := dMgr.validateActivationWorkflow(steps); err != nil {
+		ctxWarnw(context.Background(), "activation-workflow-rejected", log.Fields{"device-type": deviceType, "steps": steps, "error": err})
+		return err
+	}
+	dMgr.lockActivationWorkflows.Lock()
+	defer dMgr.lockActivationWorkflows.Unlock()
+	if dMgr.activationWorkflows == nil {
+		dMgr.activationWorkflows = make(map[string][]string)
+	}
+	dMgr.activationWorkflows[deviceType] = append([]string{}, steps...)
+	return nil
+}
+
+// activationWorkflowHandlers resolves the registered workflow for the device's type; the
+// second return value is false when no workflow is registered
+func (dMgr *DeviceManager) activationWorkflowHandlers(device *voltha.Device) ([]TransitionHandler, bool) {
+	dMgr.lockActivationWorkflows.RLock()
+	steps, have := dMgr.activationWorkflows[device.Type]
+	dMgr.lockActivationWorkflows.RUnlock()
+	if !have {
+		return nil, false
+	}
+	handlers := make([]TransitionHandler, 0, len(steps))
+	for _, step := range steps {
+		// Registration validated the steps; resolution cannot fail here
+		handler, _ := dMgr.resolveActivationStep(step)
+		handlers = append(handlers, handler)
+	}
+	return handlers, true
+}
+
+// isActivationTransition reports whether the handler list came from one of the activate
+// transitions.  Handlers are method values, so they are identified by name.
+func isActivationTransition(handlers []TransitionHandler) bool {
+	for _, handler := range handlers {
+		if strings.HasSuffix(funcName(handler), ".ActivateDevice-fm") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivationWorkflowRegistration(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// Unknown step names and empty sequences fail the registration
+	assert.NotNil(t, dMgr.registerActivationWorkflow("olt", []string{"omci-mib-sync"}))
+	assert.NotNil(t, dMgr.registerActivationWorkflow("olt", nil))
+	_, have := dMgr.activationWorkflowHandlers(&voltha.Device{Type: "olt"})
+	assert.False(t, have)
+
+	// A valid sequence registers and resolves in order
+	assert.Nil(t, dMgr.registerActivationWorkflow("olt", []string{activationStepLogicalDeviceSetup, activationStepActivate}))
+	handlers, have := dMgr.activationWorkflowHandlers(&voltha.Device{Type: "olt"})
+	assert.True(t, have)
+	assert.Equal(t, 2, len(handlers))
+
+	// A later invalid registration leaves the earlier declaration in place
+	assert.NotNil(t, dMgr.registerActivationWorkflow("olt", []string{"bogus"}))
+	handlers, have = dMgr.activationWorkflowHandlers(&voltha.Device{Type: "olt"})
+	assert.True(t, have)
+	assert.Equal(t, 2, len(handlers))
+}
+
+// enableOLT provisions and enables a root device of the given type
+func enableOLT(t *testing.T, dMgr *DeviceManager, mac string) *voltha.Device {
+	ctx := context.Background()
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	return device
+}
+
+func TestActivationWorkflowDrivesTransition(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, fls := newLifecycleTestDeviceManager()
+
+	// Without a registered workflow the built-in sequence creates the logical device
+	first := enableOLT(t, dMgr, "00:00:00:00:0d:01")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, first.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	waitUntil(t, 2*time.Second, "logical device was never created", func() bool {
+		return fls.callCount("createLogicalDevice") == 1
+	})
+
+	// A workflow skipping logical device setup suppresses that step for the type
+	assert.Nil(t, dMgr.registerActivationWorkflow("olt", []string{activationStepActivate}))
+	second := enableOLT(t, dMgr, "00:00:00:00:0d:02")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, second.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Equal(t, 1, fls.callCount("createLogicalDevice"))
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// errBreakerOpen is the cause carried by the Unavailable status of a request refused
+// because the breaker of its destination is open
+var errBreakerOpen = errors.New("adapter-circuit-breaker-open")
+
+// When an adapter stops servicing its topic every request towards it still eats the full
+// request timeout before failing, so a single dead adapter amplifies into seconds of
+// latency on every operation that touches it.  The registry below keeps a circuit breaker
+// per destination topic - one per adapter, and one per device for devices promoted to a
+// dedicated topic.  After a configurable number of consecutive transport failures the
+// breaker opens and requests fail immediately with Unavailable; once the reset interval
+// has passed a single probe request is let through, and its outcome either closes the
+// breaker or re-opens it for another interval.
+
+// breakerState is the position of a circuit breaker
+type breakerState int
+
+const (
+	// breakerClosed lets requests through; the destination is believed healthy
+	breakerClosed breakerState = iota
+	// breakerOpen fails requests immediately; the destination failed repeatedly
+	breakerOpen
+	// breakerHalfOpen lets a single probe through to test whether the destination recovered
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "OPEN"
+	case breakerHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "CLOSED"
+	}
+}
+
+// circuitBreaker tracks the transport health of a single destination topic
+type circuitBreaker struct {
+	lock                sync.Mutex
+	state               breakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probeInFlight       bool
+}
+
+// circuitBreakerRegistry holds the breakers of every destination the proxy has sent to.
+// A nil registry, or one with a zero failure threshold, disables the feature.
+type circuitBreakerRegistry struct {
+	breakers         sync.Map // destination topic -> *circuitBreaker
+	failureThreshold int
+	resetInterval    time.Duration
+	// onStateChange is invoked outside the breaker lock on every open and close
+	onStateChange func(target string, previous breakerState, current breakerState)
+}
+
+func newCircuitBreakerRegistry(failureThreshold int, resetInterval time.Duration) *circuitBreakerRegistry {
+	return &circuitBreakerRegistry{
+		failureThreshold: failureThreshold,
+		resetInterval:    resetInterval,
+	}
+}
+
+func (reg *circuitBreakerRegistry) enabled() bool {
+	return reg != nil && reg.failureThreshold > 0
+}
+
+func (reg *circuitBreakerRegistry) breakerFor(target string) *circuitBreaker {
+	if breaker, ok := reg.breakers.Load(target); ok {
+		return breaker.(*circuitBreaker)
+	}
+	breaker, _ := reg.breakers.LoadOrStore(target, &circuitBreaker{})
+	return breaker.(*circuitBreaker)
+}
+
+// stateOf returns the current position of the destination's breaker; a destination never
+// sent to is closed
+func (reg *circuitBreakerRegistry) stateOf(target string) breakerState {
+	if !reg.enabled() {
+		return breakerClosed
+	}
+	if breaker, ok := reg.breakers.Load(target); ok {
+		b := breaker.(*circuitBreaker)
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		return b.state
+	}
+	return breakerClosed
+}
+
+func (reg *circuitBreakerRegistry) notify(target string, previous breakerState, current breakerState) {
+	if reg.onStateChange != nil {
+		reg.onStateChange(target, previous, current)
+	}
+}
+
+// allowRequest reports whether a request towards the destination may be sent.  An open
+// breaker whose reset interval has elapsed moves to half-open and admits the caller as
+// the single probe; while a probe is in flight every other request is refused.
+func (reg *circuitBreakerRegistry) allowRequest(ctx context.Context, target string) bool {
+	if !reg.enabled() {
+		return true
+	}
+	breaker := reg.breakerFor(target)
+	breaker.lock.Lock()
+	switch breaker.state {
+	case breakerClosed:
+		breaker.lock.Unlock()
+		return true
+	case breakerOpen:
+		if time.Since(breaker.openedAt) < reg.resetInterval {
+			breaker.lock.Unlock()
+			return false
+		}
+		breaker.state = breakerHalfOpen
+		breaker.probeInFlight = true
+		breaker.lock.Unlock()
+		ctxInfow(ctx, "adapter-breaker-probing", log.Fields{"target": target})
+		reg.notify(target, breakerOpen, breakerHalfOpen)
+		return true
+	default: // half-open
+		if breaker.probeInFlight {
+			breaker.lock.Unlock()
+			return false
+		}
+		breaker.probeInFlight = true
+		breaker.lock.Unlock()
+		return true
+	}
+}
+
+// recordSuccess closes the destination's breaker after a successful send
+func (reg *circuitBreakerRegistry) recordSuccess(ctx context.Context, target string) {
+	if !reg.enabled() {
+		return
+	}
+	breaker := reg.breakerFor(target)
+	breaker.lock.Lock()
+	previous := breaker.state
+	breaker.state = breakerClosed
+	breaker.consecutiveFailures = 0
+	breaker.probeInFlight = false
+	breaker.lock.Unlock()
+	if previous != breakerClosed {
+		ctxInfow(ctx, "adapter-breaker-closed", log.Fields{"target": target})
+		reg.notify(target, previous, breakerClosed)
+	}
+}
+
+// recordFailure counts a transport failure towards the destination, opening the breaker
+// at the threshold.  A failed probe re-opens it for another reset interval.
+func (reg *circuitBreakerRegistry) recordFailure(ctx context.Context, target string) {
+	if !reg.enabled() {
+		return
+	}
+	breaker := reg.breakerFor(target)
+	breaker.lock.Lock()
+	previous := breaker.state
+	breaker.consecutiveFailures++
+	breaker.probeInFlight = false
+	if previous == breakerOpen || (previous == breakerClosed && breaker.consecutiveFailures < reg.failureThreshold) {
+		breaker.lock.Unlock()
+		return
+	}
+	breaker.state = breakerOpen
+	breaker.openedAt = time.Now()
+	failures := breaker.consecutiveFailures
+	breaker.lock.Unlock()
+	ctxWarnw(ctx, "adapter-breaker-opened", log.Fields{"target": target, "consecutive-failures": failures})
+	reg.notify(target, previous, breakerOpen)
+}
+
+// breakerStateFor reports the breaker position guarding requests towards the device - the
+// breaker of its dedicated topic when it has one, otherwise the adapter's
+func (ap *AdapterProxy) breakerStateFor(adapterName string, deviceID string) breakerState {
+	topic := ap.getDeviceTopic(adapterName, deviceID)
+	return ap.breakers.stateOf(topic.Name)
+}
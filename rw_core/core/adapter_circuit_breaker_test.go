@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyICProxy fails or acks the send depending on the failSend switch, and counts how
+// many sends actually reached it so a test can tell a fast-fail from a real attempt
+type flakyICProxy struct {
+	mock_kafka.MockKafkaICProxy
+	lock     sync.Mutex
+	failSend bool
+	invoked  int
+}
+
+func (p *flakyICProxy) setFailSend(fail bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.failSend = fail
+}
+
+func (p *flakyICProxy) invocations() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.invoked
+}
+
+func (p *flakyICProxy) InvokeAsyncRPC(ctx context.Context, rpc string, toTopic *kafka.Topic, replyToTopic *kafka.Topic,
+	waitForResponse bool, key string, kvArgs ...*kafka.KVArg) chan *kafka.RpcResponse {
+	p.lock.Lock()
+	p.invoked++
+	fail := p.failSend
+	p.lock.Unlock()
+	ch := make(chan *kafka.RpcResponse, 2)
+	if fail {
+		ch <- &kafka.RpcResponse{MType: kafka.RpcTransportError, Err: status.Error(codes.Unavailable, "broker-down")}
+	} else {
+		ch <- &kafka.RpcResponse{MType: kafka.RpcSent}
+		ch <- &kafka.RpcResponse{MType: kafka.RpcReply}
+	}
+	close(ch)
+	return ch
+}
+
+// breakerTransition records one state change announced by the registry
+type breakerTransition struct {
+	target   string
+	previous breakerState
+	current  breakerState
+}
+
+func newBreakerFixture(failureThreshold int, resetInterval time.Duration) (*AdapterProxy, *flakyICProxy, *[]breakerTransition) {
+	proxy := &flakyICProxy{}
+	transitions := &[]breakerTransition{}
+	var lock sync.Mutex
+	ap := &AdapterProxy{kafkaICProxy: proxy, corePairTopic: "core-pair", pendingRequests: newPendingRequestRegistry()}
+	ap.breakers = newCircuitBreakerRegistry(failureThreshold, resetInterval)
+	ap.breakers.onStateChange = func(target string, previous breakerState, current breakerState) {
+		lock.Lock()
+		defer lock.Unlock()
+		*transitions = append(*transitions, breakerTransition{target: target, previous: previous, current: current})
+	}
+	return ap, proxy, transitions
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	ctx := context.Background()
+	ap, proxy, transitions := newBreakerFixture(3, time.Hour)
+	proxy.setFailSend(true)
+	device := &voltha.Device{Id: "olt-1", Adapter: "adapter-1"}
+
+	// The first three failures are real attempts; each one reached kafka
+	for i := 1; i <= 3; i++ {
+		_, err := ap.rebootDevice(ctx, device)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, i, proxy.invocations())
+	}
+	assert.Equal(t, breakerOpen, ap.breakerStateFor("adapter-1", device.Id))
+
+	// With the breaker open the next request fails immediately without a send
+	_, err := ap.rebootDevice(ctx, device)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "adapter-circuit-breaker-open"))
+	assert.Equal(t, 3, proxy.invocations())
+	assert.Equal(t, []breakerTransition{{"adapter-1", breakerClosed, breakerOpen}}, *transitions)
+
+	// A device promoted to its own topic is guarded by its own breaker and still gets through
+	ap.registerDeviceTopic("onu-1", "adapter-1_onu-1")
+	proxy.setFailSend(false)
+	_, err = ap.rebootDevice(ctx, &voltha.Device{Id: "onu-1", Adapter: "adapter-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, proxy.invocations())
+}
+
+func TestBreakerProbesAndCloses(t *testing.T) {
+	ctx := context.Background()
+	ap, proxy, transitions := newBreakerFixture(2, 50*time.Millisecond)
+	proxy.setFailSend(true)
+	device := &voltha.Device{Id: "olt-2", Adapter: "adapter-1"}
+
+	for i := 0; i < 2; i++ {
+		_, err := ap.rebootDevice(ctx, device)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, breakerOpen, ap.breakerStateFor("adapter-1", device.Id))
+
+	// After the reset interval a single probe goes through; its failure re-opens the breaker
+	time.Sleep(60 * time.Millisecond)
+	_, err := ap.rebootDevice(ctx, device)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, proxy.invocations())
+	assert.Equal(t, breakerOpen, ap.breakerStateFor("adapter-1", device.Id))
+
+	// Once the adapter recovers the next probe closes the breaker for good
+	time.Sleep(60 * time.Millisecond)
+	proxy.setFailSend(false)
+	_, err = ap.rebootDevice(ctx, device)
+	assert.Nil(t, err)
+	assert.Equal(t, breakerClosed, ap.breakerStateFor("adapter-1", device.Id))
+	_, err = ap.rebootDevice(ctx, device)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, proxy.invocations())
+
+	assert.Equal(t, []breakerTransition{
+		{"adapter-1", breakerClosed, breakerOpen},
+		{"adapter-1", breakerOpen, breakerHalfOpen},
+		{"adapter-1", breakerHalfOpen, breakerOpen},
+		{"adapter-1", breakerOpen, breakerHalfOpen},
+		{"adapter-1", breakerHalfOpen, breakerClosed},
+	}, *transitions)
+}
+
+func TestBreakerDisabledWithZeroThreshold(t *testing.T) {
+	ctx := context.Background()
+	ap, proxy, _ := newBreakerFixture(0, time.Hour)
+	proxy.setFailSend(true)
+	device := &voltha.Device{Id: "olt-3", Adapter: "adapter-1"}
+
+	// Every request is a real attempt no matter how many fail
+	for i := 1; i <= 5; i++ {
+		_, err := ap.rebootDevice(ctx, device)
+		assert.NotNil(t, err)
+		assert.Equal(t, i, proxy.invocations())
+	}
+	assert.Equal(t, breakerClosed, ap.breakerStateFor("adapter-1", device.Id))
+}
+
+func TestBreakerStateVisibleOnDeviceView(t *testing.T) {
+	ctx := context.Background()
+	ap, proxy, _ := newBreakerFixture(1, time.Hour)
+	dMgr := &DeviceManager{adapterProxy: ap, adapterMgr: newRegistrationTestAdapterManager()}
+	device := &voltha.Device{Id: "olt-4", Type: "olt", Adapter: "adapter-1"}
+
+	// A healthy destination leaves the view metadata untouched
+	meta := dMgr.deviceViewMeta(ctx, device)
+	_, stamped := meta[adapterBreakerKey]
+	assert.False(t, stamped)
+
+	proxy.setFailSend(true)
+	_, err := ap.rebootDevice(ctx, device)
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPEN", dMgr.deviceViewMeta(ctx, device)[adapterBreakerKey])
+}
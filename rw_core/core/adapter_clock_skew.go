@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The last-communication tracking used to store the timestamp the adapter reported and to
+// drop timestamps "in the future" - so an adapter host with a fast clock had every update
+// dropped and looked dead to the liveness monitor.  Liveness is now immune to the adapter's
+// clock: the recorded communication time is when the message arrived at this core, and the
+// adapter-reported timestamp is only compared against the local clock to measure the skew.
+// The measured skew is kept per adapter, surfaced in the device type summaries, and a
+// warning event is raised once each time it crosses the configured tolerance.
+
+// recordSkew stores the latest observed skew of the adapter's clock and reports whether
+// the tolerance was newly exceeded.  The alarm re-arms when the skew returns inside the
+// window, so a persistently skewed adapter raises one event per excursion, not per message.
+func (aa *AdapterAgent) recordSkew(skew time.Duration, tolerance time.Duration) bool {
+	aa.lock.Lock()
+	defer aa.lock.Unlock()
+	aa.observedSkew = skew
+	aa.skewKnown = true
+	if tolerance <= 0 {
+		return false
+	}
+	exceeded := skew > tolerance || skew < -tolerance
+	if !exceeded {
+		aa.skewAlarmed = false
+		return false
+	}
+	if aa.skewAlarmed {
+		return false
+	}
+	aa.skewAlarmed = true
+	return true
+}
+
+// clockSkew returns the last observed skew of the adapter's clock; positive means the
+// adapter's clock is ahead of this core's
+func (aa *AdapterAgent) clockSkew() (time.Duration, bool) {
+	aa.lock.RLock()
+	defer aa.lock.RUnlock()
+	return aa.observedSkew, aa.skewKnown
+}
+
+// adapterClockSkew returns the last observed clock skew of an adapter by ID
+func (aMgr *AdapterManager) adapterClockSkew(adapterID string) (time.Duration, bool) {
+	aMgr.lockAdaptersMap.RLock()
+	adapterAgent, have := aMgr.adapterAgents[adapterID]
+	aMgr.lockAdaptersMap.RUnlock()
+	if !have {
+		return 0, false
+	}
+	return adapterAgent.clockSkew()
+}
+
+// recordAdapterClockSkew folds one skew observation into the adapter's agent and raises
+// the warning event when the observation newly exceeds the tolerance
+func (aMgr *AdapterManager) recordAdapterClockSkew(adapterID string, adapterAgent *AdapterAgent, skew time.Duration) {
+	if !adapterAgent.recordSkew(skew, aMgr.skewTolerance) {
+		return
+	}
+	ctxWarnw(context.Background(), "adapter-clock-skew-exceeds-tolerance",
+		log.Fields{"adapter-id": adapterID, "skew": skew.String(), "tolerance": aMgr.skewTolerance.String()})
+	if aMgr.deviceMgr != nil && aMgr.deviceMgr.eventMgr != nil {
+		go aMgr.deviceMgr.eventMgr.publishDeviceEvent(&voltha.Device{Id: adapterID}, adapterClockSkewEvent, map[string]string{
+			"adapter-id": adapterID,
+			"skew":       skew.String(),
+			"tolerance":  aMgr.skewTolerance.String(),
+		})
+	}
+}
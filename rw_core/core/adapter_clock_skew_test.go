@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// millis converts a time to the millisecond timestamp the kafka metadata callback delivers
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func TestAdapterSkewMeasuredAndLivenessUsesLocalTime(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	aMgr := dMgr.adapterMgr
+	clock := newFakeClock(time.Now())
+	aMgr.clock = clock
+	aMgr.skewTolerance = 30 * time.Second
+
+	// The adapter host's clock is five seconds ahead; the update used to be dropped
+	aMgr.updateLastAdapterCommunication("adapter-1", millis(clock.Now().Add(5*time.Second)))
+
+	// The skew was measured
+	skew, known := aMgr.adapterClockSkew("adapter-1")
+	assert.True(t, known)
+	assert.True(t, skew > 4*time.Second && skew < 6*time.Second)
+
+	// Liveness recorded the local receive time, so the adapter is alive despite the skew
+	reachable, age, livenessKnown := aMgr.adapterReachability("olt")
+	assert.True(t, livenessKnown)
+	assert.True(t, reachable)
+	assert.True(t, age < time.Second)
+
+	// The health view carries the measured skew
+	summary, err := dMgr.getDeviceTypeSummary(ctx, "olt")
+	assert.Nil(t, err)
+	assert.True(t, summary.AdapterSkewKnown)
+	assert.Equal(t, skew, summary.AdapterClockSkew)
+}
+
+func TestAdapterSkewWarningIsEdgeTriggered(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	aMgr := dMgr.adapterMgr
+	clock := newFakeClock(time.Now())
+	aMgr.clock = clock
+	aMgr.skewTolerance = 30 * time.Second
+	adapterAgent := aMgr.adapterAgents["adapter-1"]
+
+	// The first observation beyond the tolerance raises the warning
+	assert.True(t, adapterAgent.recordSkew(time.Minute, aMgr.skewTolerance))
+	// Staying beyond the tolerance does not raise it again
+	assert.False(t, adapterAgent.recordSkew(61*time.Second, aMgr.skewTolerance))
+	// Returning inside the window re-arms it
+	assert.False(t, adapterAgent.recordSkew(time.Second, aMgr.skewTolerance))
+	assert.True(t, adapterAgent.recordSkew(-time.Minute, aMgr.skewTolerance))
+}
+
+func TestAdapterSkewToleranceDisabled(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	aMgr := dMgr.adapterMgr
+	clock := newFakeClock(time.Now())
+	aMgr.clock = clock
+	// Tolerance zero: the skew is still measured but never alarmed
+	aMgr.skewTolerance = 0
+
+	aMgr.updateLastAdapterCommunication("adapter-1", millis(clock.Now().Add(10*time.Minute)))
+	skew, known := aMgr.adapterClockSkew("adapter-1")
+	assert.True(t, known)
+	assert.True(t, skew > 9*time.Minute)
+	assert.False(t, aMgr.adapterAgents["adapter-1"].skewAlarmed)
+}
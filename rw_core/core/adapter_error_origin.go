@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A failed enableDevice reads the same whether the adapter rejected the request or the
+// kafka message never got through, so operators escalate to the wrong team.  Every error
+// coming back through the adapter proxy now carries an origin classification: the
+// adapter actively said no (ADAPTER_REJECTED, with its code and message preserved), the
+// request expired without an answer (TRANSPORT_TIMEOUT), or it could not be delivered at
+// all (TRANSPORT_UNAVAILABLE).  The origin rides in a structured status-message prefix
+// that survives all the way to the NBI, and the pending-request registry records it with
+// each failed request.
+
+// errorOrigin classifies where a failed adapter operation actually failed
+type errorOrigin string
+
+const (
+	originAdapterRejected      errorOrigin = "ADAPTER_REJECTED"
+	originTransportTimeout     errorOrigin = "TRANSPORT_TIMEOUT"
+	originTransportUnavailable errorOrigin = "TRANSPORT_UNAVAILABLE"
+)
+
+// errorOriginPrefix introduces the classification in the status message
+const errorOriginPrefix = "origin:"
+
+// classifyError stamps an error with its origin, preserving the original status code and
+// message.  An already classified error is returned unchanged; a nil error yields a
+// generic error of the origin's default code.
+func classifyError(origin errorOrigin, err error) error {
+	if err != nil {
+		if _, classified := errorOriginOf(err); classified {
+			return err
+		}
+	}
+	code, message := originDefaultCode(origin), "no-response"
+	if err != nil {
+		message = err.Error()
+		if s, ok := status.FromError(err); ok {
+			code, message = s.Code(), s.Message()
+		}
+	}
+	return status.Errorf(code, "%s%s: %s", errorOriginPrefix, origin, message)
+}
+
+// errorOriginOf extracts the origin classification of an error, if it carries one
+func errorOriginOf(err error) (errorOrigin, bool) {
+	if err == nil {
+		return "", false
+	}
+	message := err.Error()
+	if s, ok := status.FromError(err); ok {
+		message = s.Message()
+	}
+	if idx := strings.Index(message, errorOriginPrefix); idx != -1 {
+		rest := message[idx+len(errorOriginPrefix):]
+		if end := strings.Index(rest, ":"); end != -1 {
+			return errorOrigin(rest[:end]), true
+		}
+	}
+	return "", false
+}
+
+// originDefaultCode is the status code used when the underlying error carries none
+func originDefaultCode(origin errorOrigin) codes.Code {
+	switch origin {
+	case originTransportTimeout:
+		return codes.DeadlineExceeded
+	case originTransportUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorOriginClassification(t *testing.T) {
+	// The adapter's own code and message are preserved under the classification
+	err := classifyError(originAdapterRejected, status.Error(codes.InvalidArgument, "bad-config"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "bad-config")
+	origin, ok := errorOriginOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, originAdapterRejected, origin)
+
+	// Classifying twice does not stack prefixes
+	assert.Equal(t, err.Error(), classifyError(originTransportTimeout, err).Error())
+
+	// A nil cause still yields a classified error with the origin's default code
+	err = classifyError(originTransportUnavailable, nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	origin, ok = errorOriginOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, originTransportUnavailable, origin)
+
+	_, ok = errorOriginOf(status.Error(codes.Internal, "unclassified"))
+	assert.False(t, ok)
+	_, ok = errorOriginOf(nil)
+	assert.False(t, ok)
+}
+
+func TestAdapterRejectionClassifiedInResponses(t *testing.T) {
+	ap, dMgr, proxy := newPendingRequestFixture()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ch, err := ap.disableDevice(ctx, &voltha.Device{Id: "olt-4", Adapter: "adapter-1"})
+	assert.Nil(t, err)
+	proxy.release <- &kafka.RpcResponse{MType: kafka.RpcReply, Err: status.Error(codes.InvalidArgument, "bad-config")}
+	response := <-ch
+
+	// The caller sees the adapter's rejection with its code and message intact
+	origin, ok := errorOriginOf(response.Err)
+	assert.True(t, ok)
+	assert.Equal(t, originAdapterRejected, origin)
+	assert.Equal(t, codes.InvalidArgument, status.Code(response.Err))
+	assert.Contains(t, response.Err.Error(), "bad-config")
+
+	// The failure record carries the same classification
+	failed := dMgr.listTimedOutAdapterRequests()
+	assert.Equal(t, 1, len(failed))
+	assert.Equal(t, originAdapterRejected, failed[0].origin)
+	assert.Contains(t, failed[0].failure, "bad-config")
+}
+
+func TestTransportTimeoutClassifiedInResponses(t *testing.T) {
+	ap, dMgr, proxy := newPendingRequestFixture()
+
+	ch, err := ap.rebootDevice(context.Background(), &voltha.Device{Id: "olt-5", Adapter: "adapter-1"})
+	assert.Nil(t, err)
+	proxy.release <- &kafka.RpcResponse{MType: kafka.RpcTimeout, Err: status.Error(codes.DeadlineExceeded, "timeout")}
+	response := <-ch
+
+	origin, ok := errorOriginOf(response.Err)
+	assert.True(t, ok)
+	assert.Equal(t, originTransportTimeout, origin)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(response.Err))
+
+	failed := dMgr.listTimedOutAdapterRequests()
+	assert.Equal(t, 1, len(failed))
+	assert.Equal(t, originTransportTimeout, failed[0].origin)
+}
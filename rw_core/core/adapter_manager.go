@@ -18,7 +18,6 @@ package core
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
@@ -42,7 +41,15 @@ const (
 type AdapterAgent struct {
 	adapter     *voltha.Adapter
 	deviceTypes map[string]*voltha.DeviceType
-	lock        sync.RWMutex
+	// placeholder is set when the agent was created from a device type registration that
+	// arrived before its adapter; the real registration later fills in the adapter proto
+	placeholder bool
+	// observedSkew is the last measured offset of the adapter host's clock against this
+	// core's; see adapter_clock_skew.go
+	observedSkew time.Duration
+	skewKnown    bool
+	skewAlarmed  bool
+	lock         sync.RWMutex
 }
 
 func newAdapterAgent(adapter *voltha.Adapter, deviceTypes *voltha.DeviceTypes) *AdapterAgent {
@@ -80,6 +87,22 @@ func (aa *AdapterAgent) updateDeviceType(deviceType *voltha.DeviceType) {
 	aa.deviceTypes[deviceType.Id] = deviceType
 }
 
+// isPlaceholder reports whether the agent still awaits its real adapter registration
+func (aa *AdapterAgent) isPlaceholder() bool {
+	aa.lock.RLock()
+	defer aa.lock.RUnlock()
+	return aa.placeholder
+}
+
+// completeRegistration replaces the placeholder adapter proto with the real registration,
+// keeping the device types the placeholder already accumulated
+func (aa *AdapterAgent) completeRegistration(adapter *voltha.Adapter) {
+	aa.lock.Lock()
+	defer aa.lock.Unlock()
+	aa.adapter = adapter
+	aa.placeholder = false
+}
+
 // updateCommunicationTime updates the message to the specified time.
 // No attempt is made to save the time to the db, so only recent times are guaranteed to be accurate.
 func (aa *AdapterAgent) updateCommunicationTime(new time.Time) {
@@ -100,12 +123,23 @@ func (aa *AdapterAgent) updateCommunicationTime(new time.Time) {
 type AdapterManager struct {
 	adapterAgents               map[string]*AdapterAgent
 	deviceTypeToAdapterMap      map[string]string
+	flowValidators              map[string]FlowValidator
+	flowTableCapacities         map[string]flowTableCapacity
 	clusterDataProxy            *model.Proxy
 	deviceMgr                   *DeviceManager
 	coreInstanceID              string
 	exitChannel                 chan int
 	lockAdaptersMap             sync.RWMutex
 	lockdDeviceTypeToAdapterMap sync.RWMutex
+	lockFlowValidators          sync.RWMutex
+	// deviceTypeListeners are told when a registration changes a device type; see
+	// device_type_cache.go
+	deviceTypeListeners     []func(*voltha.DeviceType)
+	lockDeviceTypeListeners sync.RWMutex
+	clock                   coreClock // time source of the liveness computations; injectable for tests
+	// skewTolerance is how far an adapter-reported timestamp may stray from the local
+	// clock before the skew warning event is raised; 0 disables the warning
+	skewTolerance time.Duration
 }
 
 func newAdapterManager(cdProxy *model.Proxy, coreInstanceID string, kafkaClient kafka.Client, deviceMgr *DeviceManager) *AdapterManager {
@@ -115,7 +149,10 @@ func newAdapterManager(cdProxy *model.Proxy, coreInstanceID string, kafkaClient
 		clusterDataProxy:       cdProxy,
 		adapterAgents:          make(map[string]*AdapterAgent),
 		deviceTypeToAdapterMap: make(map[string]string),
+		flowValidators:         make(map[string]FlowValidator),
+		flowTableCapacities:    make(map[string]flowTableCapacity),
 		deviceMgr:              deviceMgr,
+		clock:                  realClock{},
 	}
 	kafkaClient.SubscribeForMetadata(aMgr.updateLastAdapterCommunication)
 	return aMgr
@@ -185,7 +222,13 @@ func (aMgr *AdapterManager) updateLastAdapterCommunication(adapterID string, tim
 	aMgr.lockAdaptersMap.RUnlock()
 
 	if have {
-		adapterAgent.updateCommunicationTime(time.Unix(timestamp/1000, timestamp%1000*1000))
+		// Liveness must be immune to the adapter host's clock: the recorded communication
+		// time is when the message arrived here, and the adapter-reported timestamp only
+		// feeds the skew measurement
+		receivedAt := aMgr.now()
+		adapterAgent.updateCommunicationTime(receivedAt)
+		reported := time.Unix(timestamp/1000, timestamp%1000*1000)
+		aMgr.recordAdapterClockSkew(adapterID, adapterAgent, reported.Sub(receivedAt))
 	}
 }
 
@@ -193,7 +236,8 @@ func (aMgr *AdapterManager) addAdapter(adapter *voltha.Adapter, saveToDb bool) e
 	aMgr.lockAdaptersMap.Lock()
 	defer aMgr.lockAdaptersMap.Unlock()
 	logger.Debugw("adding-adapter", log.Fields{"adapter": adapter})
-	if _, exist := aMgr.adapterAgents[adapter.Id]; !exist {
+	existingAgent, exist := aMgr.adapterAgents[adapter.Id]
+	if !exist || existingAgent.isPlaceholder() {
 		if saveToDb {
 			// Save the adapter to the KV store - first check if it already exist
 			if have, err := aMgr.clusterDataProxy.Get(context.Background(), "adapters/"+adapter.Id, &voltha.Adapter{}); err != nil {
@@ -208,14 +252,20 @@ func (aMgr *AdapterManager) addAdapter(adapter *voltha.Adapter, saveToDb bool) e
 			}
 		}
 		clonedAdapter := (proto.Clone(adapter)).(*voltha.Adapter)
-		aMgr.adapterAgents[adapter.Id] = newAdapterAgent(clonedAdapter, nil)
+		if exist {
+			// The agent was created by a device type registration that beat the
+			// adapter; keep its device types and fill in the real adapter details
+			existingAgent.completeRegistration(clonedAdapter)
+		} else {
+			aMgr.adapterAgents[adapter.Id] = newAdapterAgent(clonedAdapter, nil)
+		}
 	}
 	return nil
 }
 
 func (aMgr *AdapterManager) addDeviceTypes(deviceTypes *voltha.DeviceTypes, saveToDb bool) error {
 	if deviceTypes == nil {
-		return fmt.Errorf("no-device-type")
+		return newInvalidArgumentError("no-device-type")
 	}
 	logger.Debugw("adding-device-types", log.Fields{"deviceTypes": deviceTypes})
 	aMgr.lockAdaptersMap.Lock()
@@ -244,13 +294,26 @@ func (aMgr *AdapterManager) addDeviceTypes(deviceTypes *voltha.DeviceTypes, save
 	for _, deviceType := range deviceTypes.Items {
 		clonedDType := (proto.Clone(deviceType)).(*voltha.DeviceType)
 		if adapterAgent, exist := aMgr.adapterAgents[clonedDType.Adapter]; exist {
+			previous := adapterAgent.getDeviceType(clonedDType.Id)
 			adapterAgent.updateDeviceType(clonedDType)
+			if previous == nil || !proto.Equal(previous, clonedDType) {
+				aMgr.notifyDeviceTypeChanged(clonedDType)
+			}
 		} else {
 			logger.Debugw("adapter-not-exist", log.Fields{"deviceTypes": deviceTypes, "adapterId": clonedDType.Adapter})
-			aMgr.adapterAgents[clonedDType.Adapter] = newAdapterAgent(&voltha.Adapter{Id: clonedDType.Adapter}, deviceTypes)
+			placeholderAgent := newAdapterAgent(&voltha.Adapter{Id: clonedDType.Adapter}, deviceTypes)
+			placeholderAgent.placeholder = true
+			aMgr.adapterAgents[clonedDType.Adapter] = placeholderAgent
 		}
 		aMgr.deviceTypeToAdapterMap[clonedDType.Id] = clonedDType.Adapter
 	}
+	// Devices parked because their type had no adapter can now be cleared and reconciled.
+	// Run outside this call: the device manager needs the type maps this function locks.
+	if aMgr.deviceMgr != nil {
+		for _, deviceType := range deviceTypes.Items {
+			go aMgr.deviceMgr.deviceTypeRegistered(context.Background(), deviceType.Id)
+		}
+	}
 	return nil
 }
 
@@ -280,8 +343,13 @@ func (aMgr *AdapterManager) getAdapter(adapterID string) *voltha.Adapter {
 func (aMgr *AdapterManager) registerAdapter(adapter *voltha.Adapter, deviceTypes *voltha.DeviceTypes) (*voltha.CoreInstance, error) {
 	logger.Debugw("registerAdapter", log.Fields{"adapter": adapter, "deviceTypes": deviceTypes.Items})
 
-	if aMgr.getAdapter(adapter.Id) != nil {
+	aMgr.lockAdaptersMap.RLock()
+	existingAgent := aMgr.adapterAgents[adapter.Id]
+	aMgr.lockAdaptersMap.RUnlock()
+	if existingAgent != nil && !existingAgent.isPlaceholder() {
 		//	Already registered - Adapter may have restarted.  Trigger the reconcile process for that adapter
+		//	A placeholder agent created by an early device type registration does not count;
+		//	it still needs the registration below to fill in the adapter details
 		go func() {
 			err := aMgr.deviceMgr.adapterRestarted(context.Background(), adapter)
 			if err != nil {
@@ -312,7 +380,18 @@ func (aMgr *AdapterManager) getAdapterName(deviceType string) (string, error) {
 	if adapterID, exist := aMgr.deviceTypeToAdapterMap[deviceType]; exist {
 		return adapterID, nil
 	}
-	return "", fmt.Errorf("Adapter-not-registered-for-device-type %s", deviceType)
+	return "", newNotFoundError("adapter-for-device-type", deviceType)
+}
+
+// adapterSupportsDeviceType reports whether the registered adapter serves the device type
+func (aMgr *AdapterManager) adapterSupportsDeviceType(adapterID string, deviceType string) (bool, error) {
+	aMgr.lockAdaptersMap.RLock()
+	adapterAgent, have := aMgr.adapterAgents[adapterID]
+	aMgr.lockAdaptersMap.RUnlock()
+	if !have || adapterAgent.isPlaceholder() {
+		return false, newNotFoundError("adapter", adapterID)
+	}
+	return adapterAgent.getDeviceType(deviceType) != nil, nil
 }
 
 func (aMgr *AdapterManager) listDeviceTypes() []*voltha.DeviceType {
@@ -344,3 +423,21 @@ func (aMgr *AdapterManager) getDeviceType(deviceType string) *voltha.DeviceType
 	}
 	return nil
 }
+
+// registerFlowValidator installs a flow validator for a device type, replacing any previous
+// one.  Device types without a registered validator use the baseline validation
+func (aMgr *AdapterManager) registerFlowValidator(deviceType string, validator FlowValidator) {
+	aMgr.lockFlowValidators.Lock()
+	defer aMgr.lockFlowValidators.Unlock()
+	aMgr.flowValidators[deviceType] = validator
+}
+
+// getFlowValidator returns the flow validator to apply to a device type
+func (aMgr *AdapterManager) getFlowValidator(deviceType string) FlowValidator {
+	aMgr.lockFlowValidators.RLock()
+	defer aMgr.lockFlowValidators.RUnlock()
+	if validator, exist := aMgr.flowValidators[deviceType]; exist {
+		return validator
+	}
+	return validateFlowAgainstBaseline
+}
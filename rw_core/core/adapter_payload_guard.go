@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The full flow table of a large OLT can exceed the broker's max message size, and the
+// resulting send failure says nothing about why.  Every adapter request is now sized
+// before the send and rejected with an error naming the payload and the limit when it
+// cannot fit.  A bulk flow update that is too big is split into sequenced chunk messages
+// followed by a commit marker the adapter acks; the devices whose adapters accept
+// add/remove updates normally avoid the problem entirely because the flow negotiation
+// already prefers incremental deltas for them.
+
+// maxAdapterPayloadBytes leaves margin under kafka's default 1 MiB message limit for the
+// inter-container framing.  It is a variable so tests can lower it.
+var maxAdapterPayloadBytes = 900 * 1024
+
+const (
+	// flowChunkRPC carries one slice of a chunked bulk flow update
+	flowChunkRPC = "update_flows_bulk_chunk"
+	// flowChunkCommitRPC marks the end of a chunked bulk flow update; the adapter applies
+	// the assembled table when acking it
+	flowChunkCommitRPC = "update_flows_bulk_commit"
+	// flowChunkEntryOverhead is the framing each flow or group entry adds inside its
+	// enclosing list message
+	flowChunkEntryOverhead = 4
+)
+
+// estimatePayloadSize sums the serialized size of the proto arguments of a request
+func estimatePayloadSize(kvArgs []*kafka.KVArg) int {
+	total := 0
+	for _, arg := range kvArgs {
+		if msg, ok := arg.Value.(proto.Message); ok && msg != nil {
+			total += proto.Size(msg)
+		}
+	}
+	return total
+}
+
+// newPayloadTooLargeError names the offending size and the limit so the operator knows why
+// the request was refused
+func newPayloadTooLargeError(rpc string, deviceID string, size int) error {
+	return status.Errorf(codes.ResourceExhausted, "adapter-payload-%d-bytes-exceeds-limit-%d-bytes-rpc-%s-device-%s",
+		size, maxAdapterPayloadBytes, rpc, deviceID)
+}
+
+// flowTableChunk is one slice of a chunked bulk flow update
+type flowTableChunk struct {
+	flows  []*ofp.OfpFlowStats
+	groups []*ofp.OfpGroupEntry
+}
+
+// chunkFlowTable packs the flows and groups into chunks that each fit the byte budget.
+// An entry that alone exceeds the budget makes chunking impossible.
+func chunkFlowTable(flows []*ofp.OfpFlowStats, groups []*ofp.OfpGroupEntry, budget int) ([]*flowTableChunk, error) {
+	var chunks []*flowTableChunk
+	current := &flowTableChunk{}
+	used := 0
+	add := func(entrySize int, appendEntry func()) error {
+		// Allow for the per-entry framing the enclosing Flows/FlowGroups message adds
+		size := entrySize + flowChunkEntryOverhead
+		if size > budget {
+			return status.Errorf(codes.ResourceExhausted, "flow-table-entry-%d-bytes-exceeds-chunk-budget-%d-bytes", size, budget)
+		}
+		if used+size > budget && (len(current.flows) > 0 || len(current.groups) > 0) {
+			chunks = append(chunks, current)
+			current = &flowTableChunk{}
+			used = 0
+		}
+		appendEntry()
+		used += size
+		return nil
+	}
+	for _, flow := range flows {
+		entry := flow
+		if err := add(proto.Size(entry), func() { current.flows = append(current.flows, entry) }); err != nil {
+			return nil, err
+		}
+	}
+	for _, group := range groups {
+		entry := group
+		if err := add(proto.Size(entry), func() { current.groups = append(current.groups, entry) }); err != nil {
+			return nil, err
+		}
+	}
+	if len(current.flows) > 0 || len(current.groups) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks, nil
+}
+
+// updateFlowsBulkChunked sends an oversize bulk flow update as sequenced chunk messages
+// followed by a commit marker.  Each chunk is acked before the next is sent; the commit
+// response channel is returned so the caller waits on the adapter applying the assembled
+// table, exactly as it would on a plain bulk update.
+func (ap *AdapterProxy) updateFlowsBulkChunked(ctx context.Context, device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
+	// The device is sent without its flow table; the table travels in the chunks
+	stripped := proto.Clone(device).(*voltha.Device)
+	stripped.Flows = &voltha.Flows{}
+	stripped.FlowGroups = &ofp.FlowGroups{}
+	session := CreateDeviceID()
+	// The chunk envelope - the stripped device, the metadata and the sequencing arguments -
+	// is sent with every chunk and eats into the per-chunk budget
+	envelope := proto.Size(stripped) + proto.Size(flowMetadata) +
+		proto.Size(&ic.StrType{Val: session}) + 2*proto.Size(&ic.IntType{Val: int64(maxAdapterPayloadBytes)})
+	budget := maxAdapterPayloadBytes - envelope
+	if budget <= 0 {
+		return nil, newPayloadTooLargeError(flowChunkRPC, device.Id, envelope)
+	}
+	chunks, err := chunkFlowTable(flows.Items, groups.Items, budget)
+	if err != nil {
+		return nil, err
+	}
+	ctxInfow(ctx, "chunking-bulk-flow-update", log.Fields{"device-id": device.Id, "chunks": len(chunks), "session": session})
+
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
+	replyToTopic := ap.getCoreTopic()
+	for i, chunk := range chunks {
+		args := []*kafka.KVArg{
+			{Key: "device", Value: stripped},
+			{Key: "flows", Value: &voltha.Flows{Items: chunk.flows}},
+			{Key: "groups", Value: &ofp.FlowGroups{Items: chunk.groups}},
+			{Key: "chunk_session", Value: &ic.StrType{Val: session}},
+			{Key: "chunk_index", Value: &ic.IntType{Val: int64(i)}},
+			{Key: "chunk_total", Value: &ic.IntType{Val: int64(len(chunks))}},
+		}
+		ch, err := ap.sendRPC(ctx, flowChunkRPC, &toTopic, &replyToTopic, true, device.Id, args...)
+		if err != nil {
+			return nil, err
+		}
+		// The chunks are sequenced; wait for the adapter ack before sending the next one
+		rpcResponse, ok := <-ch
+		if !ok {
+			return nil, status.Errorf(codes.Aborted, "flow-chunk-%d-channel-closed-device-%s", i, device.Id)
+		}
+		if rpcResponse.Err != nil {
+			return nil, rpcResponse.Err
+		}
+	}
+	commitArgs := []*kafka.KVArg{
+		{Key: "device", Value: stripped},
+		{Key: "chunk_session", Value: &ic.StrType{Val: session}},
+		{Key: "chunk_total", Value: &ic.IntType{Val: int64(len(chunks))}},
+		{Key: "flow_metadata", Value: flowMetadata},
+	}
+	return ap.sendRPC(ctx, flowChunkCommitRPC, &toTopic, &replyToTopic, true, device.Id, commitArgs...)
+}
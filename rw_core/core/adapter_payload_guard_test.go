@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordedRPC is one request captured by the recording kafka proxy
+type recordedRPC struct {
+	rpc    string
+	kvArgs []*kafka.KVArg
+}
+
+// recordingICProxy records every async RPC and acks it immediately
+type recordingICProxy struct {
+	mock_kafka.MockKafkaICProxy
+	lock  sync.Mutex
+	calls []recordedRPC
+}
+
+func (p *recordingICProxy) InvokeAsyncRPC(ctx context.Context, rpc string, toTopic *kafka.Topic, replyToTopic *kafka.Topic,
+	waitForResponse bool, key string, kvArgs ...*kafka.KVArg) chan *kafka.RpcResponse {
+	p.lock.Lock()
+	p.calls = append(p.calls, recordedRPC{rpc: rpc, kvArgs: kvArgs})
+	p.lock.Unlock()
+	ch := make(chan *kafka.RpcResponse, 2)
+	ch <- &kafka.RpcResponse{MType: kafka.RpcSent}
+	ch <- &kafka.RpcResponse{MType: kafka.RpcReply}
+	return ch
+}
+
+// withPayloadLimit lowers the payload limit for the duration of a test
+func withPayloadLimit(t *testing.T, limit int) {
+	previous := maxAdapterPayloadBytes
+	maxAdapterPayloadBytes = limit
+	t.Cleanup(func() { maxAdapterPayloadBytes = previous })
+}
+
+func TestOversizePayloadRejectedWithClearError(t *testing.T) {
+	withPayloadLimit(t, 64)
+	proxy := &recordingICProxy{}
+	ap := &AdapterProxy{kafkaICProxy: proxy, corePairTopic: "core-pair"}
+
+	device := &voltha.Device{Id: "olt-1", Adapter: "adapter-1", Reason: strings.Repeat("x", 200)}
+	_, err := ap.disableDevice(context.Background(), device)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "exceeds-limit-64-bytes")
+	assert.Equal(t, 0, len(proxy.calls))
+}
+
+func TestOversizeBulkFlowUpdateIsChunked(t *testing.T) {
+	withPayloadLimit(t, 220)
+	proxy := &recordingICProxy{}
+	ap := &AdapterProxy{kafkaICProxy: proxy, corePairTopic: "core-pair"}
+
+	device := &voltha.Device{Id: "olt-1", Adapter: "adapter-1"}
+	flows := &voltha.Flows{}
+	for i := 0; i < 40; i++ {
+		flows.Items = append(flows.Items, &ofp.OfpFlowStats{Id: uint64(1<<40 + i), TableId: uint32(i)})
+	}
+	groups := &voltha.FlowGroups{Items: []*ofp.OfpGroupEntry{{Desc: &ofp.OfpGroupDesc{GroupId: 1}}}}
+
+	ch, err := ap.updateFlowsBulk(context.Background(), device, flows, groups, &voltha.FlowMetadata{})
+	assert.Nil(t, err)
+	response := <-ch
+	assert.Equal(t, kafka.RpcReply, response.MType)
+
+	// The update went out as sequenced chunks closed by a commit marker
+	assert.True(t, len(proxy.calls) > 2)
+	commit := proxy.calls[len(proxy.calls)-1]
+	assert.Equal(t, flowChunkCommitRPC, commit.rpc)
+	received := 0
+	var sessions []string
+	for _, call := range proxy.calls[:len(proxy.calls)-1] {
+		assert.Equal(t, flowChunkRPC, call.rpc)
+		for _, arg := range call.kvArgs {
+			switch arg.Key {
+			case "flows":
+				received += len(arg.Value.(*voltha.Flows).Items)
+			case "chunk_session":
+				sessions = append(sessions, arg.Value.(*ic.StrType).Val)
+			case "chunk_total":
+				assert.Equal(t, int64(len(proxy.calls)-1), arg.Value.(*ic.IntType).Val)
+			case "device":
+				// The chunked device travels without its flow table
+				assert.Equal(t, 0, len(arg.Value.(*voltha.Device).Flows.Items))
+			}
+		}
+	}
+	assert.Equal(t, len(flows.Items), received)
+	// All chunks belong to the same session
+	for _, session := range sessions {
+		assert.Equal(t, sessions[0], session)
+	}
+}
+
+func TestChunkingImpossibleForOversizeEntry(t *testing.T) {
+	flow := &ofp.OfpFlowStats{Id: 1 << 40, Cookie: 1 << 40}
+	_, err := chunkFlowTable([]*ofp.OfpFlowStats{flow}, nil, 5)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "exceeds-chunk-budget")
+}
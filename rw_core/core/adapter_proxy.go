@@ -18,6 +18,8 @@ package core
 
 import (
 	"context"
+	"sync"
+
 	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
 	"github.com/opencord/voltha-lib-go/v3/pkg/log"
 	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
@@ -30,6 +32,10 @@ type AdapterProxy struct {
 	deviceTopicRegistered bool
 	corePairTopic         string
 	kafkaICProxy          kafka.InterContainerProxy
+	deviceTopics          sync.Map
+	deviceTopicMgr        *DeviceTopicManager
+	pendingRequests       *pendingRequestRegistry
+	breakers              *circuitBreakerRegistry
 }
 
 // NewAdapterProxy will return adapter proxy instance
@@ -38,6 +44,7 @@ func NewAdapterProxy(kafkaProxy kafka.InterContainerProxy, corePairTopic string)
 		kafkaICProxy:          kafkaProxy,
 		corePairTopic:         corePairTopic,
 		deviceTopicRegistered: false,
+		pendingRequests:       newPendingRequestRegistry(),
 	}
 }
 
@@ -49,9 +56,59 @@ func (ap *AdapterProxy) getAdapterTopic(adapterName string) kafka.Topic {
 	return kafka.Topic{Name: adapterName}
 }
 
+// getDeviceTopic returns the dedicated request topic assigned to the device, if any, otherwise the adapter topic
+func (ap *AdapterProxy) getDeviceTopic(adapterName string, deviceID string) kafka.Topic {
+	if topic, ok := ap.deviceTopics.Load(deviceID); ok {
+		return kafka.Topic{Name: topic.(string)}
+	}
+	return ap.getAdapterTopic(adapterName)
+}
+
+// registerDeviceTopic routes subsequent requests for the device to a dedicated topic
+func (ap *AdapterProxy) registerDeviceTopic(deviceID string, topic string) {
+	ap.deviceTopics.Store(deviceID, topic)
+}
+
+// unregisterDeviceTopic reverts the device to its adapter topic, returning the dedicated topic that was in use
+func (ap *AdapterProxy) unregisterDeviceTopic(deviceID string) (string, bool) {
+	if topic, ok := ap.deviceTopics.Load(deviceID); ok {
+		ap.deviceTopics.Delete(deviceID)
+		return topic.(string), true
+	}
+	return "", false
+}
+
+// getRegisteredDeviceTopic returns the dedicated topic assigned to the device, if any
+func (ap *AdapterProxy) getRegisteredDeviceTopic(deviceID string) (string, bool) {
+	if topic, ok := ap.deviceTopics.Load(deviceID); ok {
+		return topic.(string), true
+	}
+	return "", false
+}
+
 func (ap *AdapterProxy) sendRPC(ctx context.Context, rpc string, toTopic *kafka.Topic, replyToTopic *kafka.Topic,
 	waitForResponse bool, deviceID string, kvArgs ...*kafka.KVArg) (chan *kafka.RpcResponse, error) {
 
+	// Refuse payloads the broker would reject, naming the size and the limit instead of
+	// surfacing an opaque send failure
+	if size := estimatePayloadSize(kvArgs); size > maxAdapterPayloadBytes {
+		err := newPayloadTooLargeError(rpc, deviceID, size)
+		ctxErrorw(ctx, "adapter-payload-too-large", log.Fields{"rpc": rpc, "device-id": deviceID, "size": size, "limit": maxAdapterPayloadBytes})
+		return nil, err
+	}
+
+	// A destination whose breaker is open has failed its last several sends; fail fast
+	// instead of eating the full request timeout against a dead topic
+	if !ap.breakers.allowRequest(ctx, toTopic.Name) {
+		ctxDebugw(ctx, "adapter-breaker-open", log.Fields{"rpc": rpc, "device-id": deviceID, "topic": toTopic.Name})
+		return nil, newUnavailableError(rpc, deviceID, errBreakerOpen)
+	}
+
+	// Track the request rate towards the device; a high-volume device may get promoted to a dedicated topic
+	if ap.deviceTopicMgr != nil {
+		ap.deviceTopicMgr.recordRequest(ctx, deviceID, toTopic.Name)
+	}
+
 	// Sent the request to kafka
 	respChnl := ap.kafkaICProxy.InvokeAsyncRPC(ctx, rpc, toTopic, replyToTopic, waitForResponse, deviceID, kvArgs...)
 
@@ -59,7 +116,13 @@ func (ap *AdapterProxy) sendRPC(ctx context.Context, rpc string, toTopic *kafka.
 	firstResponse, ok := <-respChnl
 	if !ok || firstResponse.MType != kafka.RpcSent {
 		logger.Errorw("failure to request to kafka", log.Fields{"rpc": rpc, "device-id": deviceID, "error": firstResponse.Err})
-		return nil, firstResponse.Err
+		ap.breakers.recordFailure(ctx, toTopic.Name)
+		return nil, classifyError(originTransportUnavailable, firstResponse.Err)
+	}
+	ap.breakers.recordSuccess(ctx, toTopic.Name)
+	// Keep the request visible while it is in flight so a hung operation can be diagnosed
+	if ap.pendingRequests != nil {
+		return ap.pendingRequests.watch(ctx, rpc, deviceID, respChnl), nil
 	}
 	// return the kafka channel for the caller to wait for the response of the RPC call
 	return respChnl, nil
@@ -69,7 +132,7 @@ func (ap *AdapterProxy) sendRPC(ctx context.Context, rpc string, toTopic *kafka.
 func (ap *AdapterProxy) adoptDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("adoptDevice", log.Fields{"device-id": device.Id})
 	rpc := "adopt_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -82,7 +145,7 @@ func (ap *AdapterProxy) adoptDevice(ctx context.Context, device *voltha.Device)
 func (ap *AdapterProxy) disableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("disableDevice", log.Fields{"device-id": device.Id})
 	rpc := "disable_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -94,7 +157,7 @@ func (ap *AdapterProxy) disableDevice(ctx context.Context, device *voltha.Device
 func (ap *AdapterProxy) reEnableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("reEnableDevice", log.Fields{"device-id": device.Id})
 	rpc := "reenable_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -106,7 +169,7 @@ func (ap *AdapterProxy) reEnableDevice(ctx context.Context, device *voltha.Devic
 func (ap *AdapterProxy) rebootDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("rebootDevice", log.Fields{"device-id": device.Id})
 	rpc := "reboot_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -118,7 +181,7 @@ func (ap *AdapterProxy) rebootDevice(ctx context.Context, device *voltha.Device)
 func (ap *AdapterProxy) deleteDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("deleteDevice", log.Fields{"device-id": device.Id})
 	rpc := "delete_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -130,7 +193,7 @@ func (ap *AdapterProxy) deleteDevice(ctx context.Context, device *voltha.Device)
 func (ap *AdapterProxy) getOfpDeviceInfo(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("getOfpDeviceInfo", log.Fields{"device-id": device.Id})
 	rpc := "get_ofp_device_info"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -141,7 +204,7 @@ func (ap *AdapterProxy) getOfpDeviceInfo(ctx context.Context, device *voltha.Dev
 // getOfpPortInfo invokes get ofp port info rpc
 func (ap *AdapterProxy) getOfpPortInfo(ctx context.Context, device *voltha.Device, portNo uint32) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("getOfpPortInfo", log.Fields{"device-id": device.Id, "port-no": portNo})
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "port_no", Value: &ic.IntType{Val: int64(portNo)}},
@@ -154,7 +217,7 @@ func (ap *AdapterProxy) getOfpPortInfo(ctx context.Context, device *voltha.Devic
 func (ap *AdapterProxy) reconcileDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("reconcileDevice", log.Fields{"device-id": device.Id})
 	rpc := "reconcile_device"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 	}
@@ -166,7 +229,21 @@ func (ap *AdapterProxy) reconcileDevice(ctx context.Context, device *voltha.Devi
 func (ap *AdapterProxy) downloadImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("downloadImage", log.Fields{"device-id": device.Id, "image": download.Name})
 	rpc := "download_image"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
+	args := []*kafka.KVArg{
+		{Key: "device", Value: device},
+		{Key: "request", Value: download},
+	}
+	replyToTopic := ap.getCoreTopic()
+	return ap.sendRPC(ctx, rpc, &toTopic, &replyToTopic, true, device.Id, args...)
+}
+
+// preflightImage asks the adapter to probe an image for reachability before the download
+// is dispatched
+func (ap *AdapterProxy) preflightImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	logger.Debugw("preflightImage", log.Fields{"device-id": device.Id, "image": download.Name})
+	rpc := "preflight_image"
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: download},
@@ -179,7 +256,7 @@ func (ap *AdapterProxy) downloadImage(ctx context.Context, device *voltha.Device
 func (ap *AdapterProxy) getImageDownloadStatus(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("getImageDownloadStatus", log.Fields{"device-id": device.Id, "image": download.Name})
 	rpc := "get_image_download_status"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: download},
@@ -192,7 +269,7 @@ func (ap *AdapterProxy) getImageDownloadStatus(ctx context.Context, device *volt
 func (ap *AdapterProxy) cancelImageDownload(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("cancelImageDownload", log.Fields{"device-id": device.Id, "image": download.Name})
 	rpc := "cancel_image_download"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: download},
@@ -205,7 +282,7 @@ func (ap *AdapterProxy) cancelImageDownload(ctx context.Context, device *voltha.
 func (ap *AdapterProxy) activateImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("activateImageUpdate", log.Fields{"device-id": device.Id, "image": download.Name})
 	rpc := "activate_image_update"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: download},
@@ -218,7 +295,7 @@ func (ap *AdapterProxy) activateImageUpdate(ctx context.Context, device *voltha.
 func (ap *AdapterProxy) revertImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("revertImageUpdate", log.Fields{"device-id": device.Id, "image": download.Name})
 	rpc := "revert_image_update"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: download},
@@ -229,7 +306,7 @@ func (ap *AdapterProxy) revertImageUpdate(ctx context.Context, device *voltha.De
 
 func (ap *AdapterProxy) packetOut(ctx context.Context, deviceType string, deviceID string, outPort uint32, packet *openflow_13.OfpPacketOut) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("packetOut", log.Fields{"device-id": deviceID, "device-type": deviceType, "out-port": outPort})
-	toTopic := ap.getAdapterTopic(deviceType)
+	toTopic := ap.getDeviceTopic(deviceType, deviceID)
 	rpc := "receive_packet_out"
 	args := []*kafka.KVArg{
 		{Key: "deviceId", Value: &ic.StrType{Val: deviceID}},
@@ -243,7 +320,7 @@ func (ap *AdapterProxy) packetOut(ctx context.Context, deviceType string, device
 // updateFlowsBulk invokes update flows bulk rpc
 func (ap *AdapterProxy) updateFlowsBulk(ctx context.Context, device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("updateFlowsBulk", log.Fields{"device-id": device.Id, "flow-count": len(flows.Items), "group-count": len(groups.Items), "flow-metadata": flowMetadata})
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	rpc := "update_flows_bulk"
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
@@ -251,6 +328,10 @@ func (ap *AdapterProxy) updateFlowsBulk(ctx context.Context, device *voltha.Devi
 		{Key: "groups", Value: groups},
 		{Key: "flow_metadata", Value: flowMetadata},
 	}
+	// An oversize table is sent as sequenced chunks with a commit marker instead
+	if estimatePayloadSize(args) > maxAdapterPayloadBytes {
+		return ap.updateFlowsBulkChunked(ctx, device, flows, groups, flowMetadata)
+	}
 	replyToTopic := ap.getCoreTopic()
 	return ap.sendRPC(context.TODO(), rpc, &toTopic, &replyToTopic, true, device.Id, args...)
 }
@@ -266,7 +347,7 @@ func (ap *AdapterProxy) updateFlowsIncremental(ctx context.Context, device *volt
 			"group-to-delete-count": len(groupChanges.ToRemove.Items),
 			"group-to-update-count": len(groupChanges.ToUpdate.Items),
 		})
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	rpc := "update_flows_incrementally"
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
@@ -281,7 +362,7 @@ func (ap *AdapterProxy) updateFlowsIncremental(ctx context.Context, device *volt
 // updatePmConfigs invokes update pm configs rpc
 func (ap *AdapterProxy) updatePmConfigs(ctx context.Context, device *voltha.Device, pmConfigs *voltha.PmConfigs) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("updatePmConfigs", log.Fields{"device-id": device.Id, "pm-configs-id": pmConfigs.Id})
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	rpc := "Update_pm_config"
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
@@ -295,7 +376,7 @@ func (ap *AdapterProxy) updatePmConfigs(ctx context.Context, device *voltha.Devi
 func (ap *AdapterProxy) simulateAlarm(ctx context.Context, device *voltha.Device, simulateReq *voltha.SimulateAlarmRequest) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("simulateAlarm", log.Fields{"device-id": device.Id, "simulate-req-id": simulateReq.Id})
 	rpc := "simulate_alarm"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "device", Value: device},
 		{Key: "request", Value: simulateReq},
@@ -308,7 +389,7 @@ func (ap *AdapterProxy) simulateAlarm(ctx context.Context, device *voltha.Device
 func (ap *AdapterProxy) disablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("disablePort", log.Fields{"device-id": device.Id, "port-no": port.PortNo})
 	rpc := "disable_port"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "deviceId", Value: &ic.StrType{Val: device.Id}},
 		{Key: "port", Value: port},
@@ -320,7 +401,7 @@ func (ap *AdapterProxy) disablePort(ctx context.Context, device *voltha.Device,
 func (ap *AdapterProxy) enablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("enablePort", log.Fields{"device-id": device.Id, "port-no": port.PortNo})
 	rpc := "enable_port"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	args := []*kafka.KVArg{
 		{Key: "deviceId", Value: &ic.StrType{Val: device.Id}},
 		{Key: "port", Value: port},
@@ -333,7 +414,7 @@ func (ap *AdapterProxy) enablePort(ctx context.Context, device *voltha.Device, p
 func (ap *AdapterProxy) childDeviceLost(ctx context.Context, deviceType string, pDeviceID string, pPortNo uint32, onuID uint32) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("childDeviceLost", log.Fields{"parent-device-id": pDeviceID, "parent-port-no": pPortNo, "onu-id": onuID})
 	rpc := "child_device_lost"
-	toTopic := ap.getAdapterTopic(deviceType)
+	toTopic := ap.getDeviceTopic(deviceType, pDeviceID)
 	args := []*kafka.KVArg{
 		{Key: "pDeviceId", Value: &ic.StrType{Val: pDeviceID}},
 		{Key: "pPortNo", Value: &ic.IntType{Val: int64(pPortNo)}},
@@ -346,7 +427,7 @@ func (ap *AdapterProxy) childDeviceLost(ctx context.Context, deviceType string,
 func (ap *AdapterProxy) startOmciTest(ctx context.Context, device *voltha.Device, omcitestrequest *voltha.OmciTestRequest) (chan *kafka.RpcResponse, error) {
 	logger.Debugw("Omci_test_Request_adapter_proxy", log.Fields{"device": device, "omciTestRequest": omcitestrequest})
 	rpc := "start_omci_test"
-	toTopic := ap.getAdapterTopic(device.Adapter)
+	toTopic := ap.getDeviceTopic(device.Adapter, device.Id)
 	// Use a device specific topic as we are the only core handling requests for this device
 	replyToTopic := ap.getCoreTopic()
 	// TODO: Perhaps this should have used omcitestrequest.uuid as the second argument rather
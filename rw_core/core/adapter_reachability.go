@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The connect status on a device is whatever the adapter last reported, which can be hours
+// stale.  The view metadata of a device therefore carries a computed "adapter reachable"
+// indication derived from the adapter manager's last-communication tracking for the
+// adapter bound to the device type, together with the age of that last communication.
+// Both are recomputed on every read, never persisted, and cost no adapter round-trip.
+const (
+	adapterReachableKey  = "adapter-reachable"
+	adapterContactAgeKey = "adapter-contact-age"
+	// adapterBreakerKey carries the position of the circuit breaker guarding requests
+	// towards the device; it is only stamped when the breaker is not closed
+	adapterBreakerKey = "adapter-breaker"
+	// adapterLivenessWindow is how recently the adapter must have been heard from for the
+	// devices it manages to be considered reachable
+	adapterLivenessWindow = time.Minute
+)
+
+// breakerStateReporter is satisfied by the concrete adapter proxy; the fakes used in
+// tests do not track breakers, so the decoration is skipped for them
+type breakerStateReporter interface {
+	breakerStateFor(adapterName string, deviceID string) breakerState
+}
+
+// lastCommunication returns when the adapter was last heard from
+func (aa *AdapterAgent) lastCommunication() (time.Time, bool) {
+	aa.lock.RLock()
+	defer aa.lock.RUnlock()
+	if aa.adapter.LastCommunication == nil {
+		return time.Time{}, false
+	}
+	last, err := ptypes.Timestamp(aa.adapter.LastCommunication)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return last, true
+}
+
+// adapterReachability reports whether the adapter bound to a device type was heard from
+// within the liveness window, and how long ago that was.  The last return value is false
+// when no liveness state is known for the type.
+func (aMgr *AdapterManager) adapterReachability(deviceType string) (bool, time.Duration, bool) {
+	adapterID, err := aMgr.getAdapterName(deviceType)
+	if err != nil {
+		return false, 0, false
+	}
+	aMgr.lockAdaptersMap.RLock()
+	adapterAgent, have := aMgr.adapterAgents[adapterID]
+	aMgr.lockAdaptersMap.RUnlock()
+	if !have {
+		return false, 0, false
+	}
+	last, known := adapterAgent.lastCommunication()
+	if !known {
+		return false, 0, false
+	}
+	age := aMgr.now().Sub(last)
+	return age <= adapterLivenessWindow, age, true
+}
+
+// stampAdapterReachabilityMeta stamps the computed reachability entries onto the view
+// metadata of a device.  Nothing is stamped when no liveness state is known for the
+// device type and the breaker is closed.
+func (dMgr *DeviceManager) stampAdapterReachabilityMeta(device *voltha.Device, meta map[string]string) {
+	if dMgr.adapterMgr == nil {
+		return
+	}
+	if reachable, age, known := dMgr.adapterMgr.adapterReachability(device.Type); known {
+		meta[adapterReachableKey] = strconv.FormatBool(reachable)
+		meta[adapterContactAgeKey] = age.Truncate(time.Millisecond).String()
+	}
+	if reporter, ok := dMgr.adapterProxy.(breakerStateReporter); ok && device.Adapter != "" {
+		if breaker := reporter.breakerStateFor(device.Adapter, device.Id); breaker != breakerClosed {
+			meta[adapterBreakerKey] = breaker.String()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeviceExposesAdapterReachability(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:08:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// Without any liveness state no reachability is stamped
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	_, known := dMgr.deviceViewMeta(ctx, view)[adapterReachableKey]
+	assert.False(t, known)
+
+	// A recently heard-from adapter makes its devices reachable
+	adapterAgent := dMgr.adapterMgr.adapterAgents["adapter-1"]
+	adapterAgent.adapter.LastCommunication = ptypes.TimestampNow()
+	meta := dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, "true", meta[adapterReachableKey])
+	assert.NotEqual(t, "", meta[adapterContactAgeKey])
+
+	// A stale adapter makes them unreachable, with the age telling how stale
+	stale, err := ptypes.TimestampProto(time.Now().Add(-10 * time.Minute))
+	assert.Nil(t, err)
+	adapterAgent.adapter.LastCommunication = stale
+	meta = dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, "false", meta[adapterReachableKey])
+	assert.Contains(t, meta[adapterContactAgeKey], "m")
+
+	// The view-only entries never reach the device or the store
+	view, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(view.ExtraArgs, adapterReachableKey))
+	stored := &voltha.Device{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, "devices/"+device.Id, stored)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.False(t, strings.Contains(stored.ExtraArgs, adapterReachableKey))
+}
+
+func TestReachabilityCoexistsWithAnnotations(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:08:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, device.Id, "site", "lab-1"))
+	dMgr.adapterMgr.adapterAgents["adapter-1"].adapter.LastCommunication = ptypes.TimestampNow()
+
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	annotations := deviceAnnotations(view)
+	assert.Equal(t, "lab-1", annotations["site"])
+	assert.Equal(t, "true", dMgr.deviceViewMeta(ctx, view)[adapterReachableKey])
+}
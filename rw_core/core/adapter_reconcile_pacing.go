@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The reconcile triggered when an adapter re-registers used to fire a request per device
+// all at once, hammering an adapter that is still warming up.  The run is now paced: an
+// optional grace period after registration, a configurable number of devices in flight at
+// a time, and parents reconciled before children so the adapter holds the OLT context
+// before the ONUs arrive.  One run per adapter is in progress at a time - a second
+// registration while a run is active joins it instead of starting a duplicate - and the
+// progress of the run can be queried while it goes.
+
+// adapterReconcileRun tracks one paced reconcile towards a restarted adapter
+type adapterReconcileRun struct {
+	adapterID string
+	startedAt time.Time
+	lock      sync.Mutex
+	pending   int
+	succeeded int
+	failed    int
+}
+
+// adapterReconcileProgress is a point-in-time copy of a run handed out on queries
+type adapterReconcileProgress struct {
+	adapterID string
+	startedAt time.Time
+	pending   int
+	succeeded int
+	failed    int
+}
+
+func (run *adapterReconcileRun) setPending(count int) {
+	run.lock.Lock()
+	run.pending = count
+	run.lock.Unlock()
+}
+
+func (run *adapterReconcileRun) recordResult(succeeded bool) {
+	run.lock.Lock()
+	run.pending--
+	if succeeded {
+		run.succeeded++
+	} else {
+		run.failed++
+	}
+	run.lock.Unlock()
+}
+
+func (run *adapterReconcileRun) progress() adapterReconcileProgress {
+	run.lock.Lock()
+	defer run.lock.Unlock()
+	return adapterReconcileProgress{adapterID: run.adapterID, startedAt: run.startedAt,
+		pending: run.pending, succeeded: run.succeeded, failed: run.failed}
+}
+
+// getAdapterReconcileProgress reports the progress of an in-flight reconcile run; the
+// second return value is false when no run is active for the adapter
+func (dMgr *DeviceManager) getAdapterReconcileProgress(adapterID string) (adapterReconcileProgress, bool) {
+	if run, have := dMgr.reconcileRuns.Load(adapterID); have {
+		return run.(*adapterReconcileRun).progress(), true
+	}
+	return adapterReconcileProgress{}, false
+}
+
+// reconcileAdapterDevices runs one paced reconcile of the devices the adapter manages.
+// A run already in progress for the adapter makes this a no-op.
+func (dMgr *DeviceManager) reconcileAdapterDevices(ctx context.Context, adapterID string) error {
+	stored, loaded := dMgr.reconcileRuns.LoadOrStore(adapterID, &adapterReconcileRun{adapterID: adapterID, startedAt: time.Now()})
+	if loaded {
+		ctxInfow(ctx, "reconcile-already-in-progress", log.Fields{"adapter-id": adapterID})
+		return nil
+	}
+	run := stored.(*adapterReconcileRun)
+	defer dMgr.reconcileRuns.Delete(adapterID)
+
+	// Give the adapter its grace period before the first request lands
+	if dMgr.reconcileStartDelay > 0 {
+		time.Sleep(dMgr.reconcileStartDelay)
+	}
+	parents, children := dMgr.collectReconcilableDevices(ctx, adapterID)
+	if len(parents) == 0 && len(children) == 0 {
+		ctxDebugw(ctx, "no-managed-device-to-reconcile", log.Fields{"adapter-id": adapterID})
+		return nil
+	}
+	run.setPending(len(parents) + len(children))
+	ctxInfow(ctx, "paced-reconcile-start", log.Fields{"adapter-id": adapterID, "parents": len(parents), "children": len(children), "concurrency": dMgr.reconcileConcurrency})
+
+	// Parents first so the adapter has the OLT context before the ONUs arrive
+	dMgr.reconcileBatch(ctx, run, parents)
+	dMgr.reconcileBatch(ctx, run, children)
+
+	result := run.progress()
+	if result.failed > 0 {
+		return status.Errorf(codes.Aborted, "reconcile-failed-for-%d-of-%d-devices-adapter-%s",
+			result.failed, result.failed+result.succeeded, adapterID)
+	}
+	return nil
+}
+
+// reconcileBatch pushes the devices through the reconcile with at most the configured
+// number in flight at a time, waiting for the whole batch before returning
+func (dMgr *DeviceManager) reconcileBatch(ctx context.Context, run *adapterReconcileRun, devices []*voltha.Device) {
+	concurrency := dMgr.reconcileConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *voltha.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			response := dMgr.sendReconcileDeviceRequest(ctx, device)
+			if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+				ctxWarnw(ctx, "device-reconcile-failed", log.Fields{"device-id": device.Id, "adapter-id": run.adapterID, "errors": res})
+				run.recordResult(false)
+				return
+			}
+			run.recordResult(true)
+		}(device)
+	}
+	wg.Wait()
+}
+
+// collectReconcilableDevices gathers the devices the adapter manages that are in a state
+// worth reconciling, parents separated from children
+func (dMgr *DeviceManager) collectReconcilableDevices(ctx context.Context, adapterID string) ([]*voltha.Device, []*voltha.Device) {
+	var parents, children []*voltha.Device
+	for rootDeviceID := range dMgr.rootDevices {
+		rootDevice, _ := dMgr.getDeviceFromModel(ctx, rootDeviceID)
+		if rootDevice == nil {
+			continue
+		}
+		rootInMaintenance, maintenanceCoversChildren := deviceInMaintenance(rootDevice)
+		if rootDevice.Adapter == adapterID {
+			if rootInMaintenance {
+				ctxDebugw(ctx, "not-reconciling-device-in-maintenance", log.Fields{"root-id": rootDevice.Id})
+				continue
+			}
+			if isOkToReconcile(rootDevice) {
+				parents = append(parents, rootDevice)
+			} else {
+				ctxDebugw(ctx, "not-reconciling-root-device", log.Fields{"root-id": rootDevice.Id, "state": rootDevice.AdminState})
+			}
+			continue
+		}
+		// Should we be reconciling the root's children instead?
+	childManagedByAdapter:
+		for _, port := range rootDevice.Ports {
+			for _, peer := range port.Peers {
+				if childDevice, _ := dMgr.getDeviceFromModel(ctx, peer.DeviceId); childDevice != nil {
+					if childDevice.Adapter != adapterID {
+						// All child devices under a parent device are typically managed by the same adapter type.
+						// Therefore we only need to check whether the first device we retrieved is managed by that adapter
+						break childManagedByAdapter
+					}
+					childInMaintenance, _ := deviceInMaintenance(childDevice)
+					if childInMaintenance || maintenanceCoversChildren {
+						ctxDebugw(ctx, "not-reconciling-child-in-maintenance", log.Fields{"child-id": childDevice.Id})
+						continue
+					}
+					if isOkToReconcile(childDevice) {
+						children = append(children, childDevice)
+					} else {
+						ctxDebugw(ctx, "not-reconciling-child-device", log.Fields{"child-id": childDevice.Id, "state": childDevice.AdminState})
+					}
+				}
+			}
+		}
+	}
+	return parents, children
+}
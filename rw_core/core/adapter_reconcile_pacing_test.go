@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// reconcileRecorder remembers the order in which devices were reconciled
+type reconcileRecorder struct {
+	*fakeAdapterService
+	lock sync.Mutex
+	ids  []string
+}
+
+func (rr *reconcileRecorder) reconcileDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	rr.lock.Lock()
+	rr.ids = append(rr.ids, device.Id)
+	rr.lock.Unlock()
+	return rr.fakeAdapterService.reconcileDevice(ctx, device)
+}
+
+func (rr *reconcileRecorder) recorded() []string {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	return append([]string{}, rr.ids...)
+}
+
+// seedReconcileModel stores one parent managed by adapter-1 plus a foreign parent whose
+// child is managed by adapter-1
+func seedReconcileModel(t *testing.T, dMgr *DeviceManager) {
+	ctx := context.Background()
+	devices := []*voltha.Device{
+		{Id: "olt-A", Root: true, Adapter: "adapter-1", AdminState: voltha.AdminState_ENABLED},
+		{Id: "olt-B", Root: true, Adapter: "other-adapter", AdminState: voltha.AdminState_ENABLED,
+			Ports: []*voltha.Port{{PortNo: 1, Type: voltha.Port_PON_OLT, Peers: []*voltha.Port_PeerPort{{DeviceId: "onu-1"}}}}},
+		{Id: "onu-1", Adapter: "adapter-1", AdminState: voltha.AdminState_ENABLED},
+	}
+	for _, device := range devices {
+		assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, "devices", device.Id, device))
+	}
+	dMgr.rootDevices["olt-A"] = true
+	dMgr.rootDevices["olt-B"] = true
+}
+
+func TestPacedReconcileOrdersParentsBeforeChildren(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	recorder := &reconcileRecorder{fakeAdapterService: fas}
+	dMgr.adapterProxy = recorder
+	dMgr.reconcileConcurrency = 1
+	seedReconcileModel(t, dMgr)
+
+	assert.Nil(t, dMgr.adapterRestarted(ctx, &voltha.Adapter{Id: "adapter-1"}))
+	assert.Equal(t, []string{"olt-A", "onu-1"}, recorder.recorded())
+
+	// Once the run completed there is no progress left to query
+	_, running := dMgr.getAdapterReconcileProgress("adapter-1")
+	assert.False(t, running)
+}
+
+func TestPacedReconcileDedupesConcurrentRegistrations(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	recorder := &reconcileRecorder{fakeAdapterService: fas}
+	dMgr.adapterProxy = recorder
+	dMgr.reconcileConcurrency = 2
+	dMgr.reconcileStartDelay = 100 * time.Millisecond
+	seedReconcileModel(t, dMgr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, dMgr.adapterRestarted(ctx, &voltha.Adapter{Id: "adapter-1"}))
+		}()
+	}
+	// While the run waits out the grace period it is visible as in progress
+	waitUntil(t, 2*time.Second, "reconcile-run-not-visible", func() bool {
+		_, running := dMgr.getAdapterReconcileProgress("adapter-1")
+		return running
+	})
+	wg.Wait()
+
+	// The second registration joined the running reconcile instead of duplicating it
+	assert.Equal(t, 2, len(recorder.recorded()))
+	assert.Equal(t, 2, fas.callCount("reconcileDevice"))
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRegistrationTestAdapterManager() *AdapterManager {
+	return &AdapterManager{
+		clusterDataProxy:       newInMemoryClusterProxy(),
+		adapterAgents:          make(map[string]*AdapterAgent),
+		deviceTypeToAdapterMap: make(map[string]string),
+		flowValidators:         make(map[string]FlowValidator),
+		flowTableCapacities:    make(map[string]flowTableCapacity),
+		coreInstanceID:         "core-1",
+	}
+}
+
+func TestAdapterRegistrationAfterDeviceTypes(t *testing.T) {
+	aMgr := newRegistrationTestAdapterManager()
+
+	// Device types arrive before their adapter; a placeholder agent is created
+	err := aMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt-x", Adapter: "adapter-x"}}}, true)
+	assert.Nil(t, err)
+	assert.True(t, aMgr.adapterAgents["adapter-x"].isPlaceholder())
+	assert.Equal(t, "", aMgr.getAdapter("adapter-x").Vendor)
+
+	// The real registration must fill in the adapter details instead of being skipped
+	adapter := &voltha.Adapter{Id: "adapter-x", Vendor: "vendor-x", Version: "1.2.3", Endpoint: "adapter-x-endpoint"}
+	_, err = aMgr.registerAdapter(adapter, &voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "onu-x", Adapter: "adapter-x"}}})
+	assert.Nil(t, err)
+
+	registered := aMgr.getAdapter("adapter-x")
+	assert.Equal(t, "vendor-x", registered.Vendor)
+	assert.Equal(t, "1.2.3", registered.Version)
+	assert.Equal(t, "adapter-x-endpoint", registered.Endpoint)
+	assert.False(t, aMgr.adapterAgents["adapter-x"].isPlaceholder())
+
+	// The device types registered before and with the adapter are both served
+	assert.NotNil(t, aMgr.getDeviceType("olt-x"))
+	assert.NotNil(t, aMgr.getDeviceType("onu-x"))
+}
+
+func TestAdapterRegistrationNormalOrderUnaffected(t *testing.T) {
+	aMgr := newRegistrationTestAdapterManager()
+
+	adapter := &voltha.Adapter{Id: "adapter-y", Vendor: "vendor-y"}
+	_, err := aMgr.registerAdapter(adapter, &voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt-y", Adapter: "adapter-y"}}})
+	assert.Nil(t, err)
+
+	assert.False(t, aMgr.adapterAgents["adapter-y"].isPlaceholder())
+	assert.Equal(t, "vendor-y", aMgr.getAdapter("adapter-y").Vendor)
+	assert.NotNil(t, aMgr.getDeviceType("olt-y"))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/opencord/voltha-lib-go/v3/pkg/log"
 	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
 	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/status"
 )
 
 // AdapterRequestHandlerProxy represent adapter request handler proxy attributes
@@ -160,6 +161,131 @@ func (rhp *AdapterRequestHandlerProxy) DeviceUpdate(args []*ic.Argument) (*empty
 	return &empty.Empty{}, nil
 }
 
+// DeviceDescriptionUpdate updates the identification strings of a device without the
+// adapter having to send - and possibly clobber - the rest of the device
+func (rhp *AdapterRequestHandlerProxy) DeviceDescriptionUpdate(args []*ic.Argument) (*empty.Empty, error) {
+	if len(args) < 2 {
+		ctxWarnw(context.TODO(), "device-description-update-invalid-number-of-args", log.Fields{"args": args})
+		return nil, errors.New("invalid-number-of-args")
+	}
+	deviceID := &voltha.ID{}
+	vendor := &ic.StrType{}
+	model := &ic.StrType{}
+	serialNumber := &ic.StrType{}
+	transactionID := &ic.StrType{}
+	for _, arg := range args {
+		switch arg.Key {
+		case "device_id":
+			if err := ptypes.UnmarshalAny(arg.Value, deviceID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-device-id", log.Fields{"error": err})
+				return nil, err
+			}
+		case "vendor":
+			if err := ptypes.UnmarshalAny(arg.Value, vendor); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-vendor", log.Fields{"error": err})
+				return nil, err
+			}
+		case "model":
+			if err := ptypes.UnmarshalAny(arg.Value, model); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-model", log.Fields{"error": err})
+				return nil, err
+			}
+		case "serial_number":
+			if err := ptypes.UnmarshalAny(arg.Value, serialNumber); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-serial-number", log.Fields{"error": err})
+				return nil, err
+			}
+		case kafka.TransactionKey:
+			if err := ptypes.UnmarshalAny(arg.Value, transactionID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-transaction-ID", log.Fields{"error": err})
+				return nil, err
+			}
+		}
+	}
+	ctxDebugw(context.TODO(), "DeviceDescriptionUpdate", log.Fields{"deviceID": deviceID.Id, "transactionID": transactionID.Val})
+
+	if err := rhp.deviceMgr.updateDeviceDescription(context.TODO(), deviceID.Id, vendor.Val, model.Val, serialNumber.Val); err != nil {
+		ctxDebugw(context.TODO(), "unable-to-update-device-description", log.Fields{"error": err})
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeviceTechProfileUpdate stores the technology resource assignments - alloc-IDs, gem
+// ports and the like - an adapter reports for a device, typically after a flow change
+func (rhp *AdapterRequestHandlerProxy) DeviceTechProfileUpdate(args []*ic.Argument) (*empty.Empty, error) {
+	if len(args) < 2 {
+		ctxWarnw(context.TODO(), "device-tech-profile-update-invalid-number-of-args", log.Fields{"args": args})
+		return nil, errors.New("invalid-number-of-args")
+	}
+	deviceID := &voltha.ID{}
+	techProfile := &ic.StrType{}
+	transactionID := &ic.StrType{}
+	for _, arg := range args {
+		switch arg.Key {
+		case "device_id":
+			if err := ptypes.UnmarshalAny(arg.Value, deviceID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-device-id", log.Fields{"error": err})
+				return nil, err
+			}
+		case "tech_profile":
+			if err := ptypes.UnmarshalAny(arg.Value, techProfile); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-tech-profile", log.Fields{"error": err})
+				return nil, err
+			}
+		case kafka.TransactionKey:
+			if err := ptypes.UnmarshalAny(arg.Value, transactionID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-transaction-ID", log.Fields{"error": err})
+				return nil, err
+			}
+		}
+	}
+	ctxDebugw(context.TODO(), "DeviceTechProfileUpdate", log.Fields{"deviceID": deviceID.Id, "transactionID": transactionID.Val})
+
+	if err := rhp.deviceMgr.updateDeviceTechProfile(context.TODO(), deviceID.Id, techProfile.Val); err != nil {
+		ctxDebugw(context.TODO(), "unable-to-update-device-tech-profile", log.Fields{"error": err})
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// DeviceVlanUpdate updates the outer VLAN of a device
+func (rhp *AdapterRequestHandlerProxy) DeviceVlanUpdate(args []*ic.Argument) (*empty.Empty, error) {
+	if len(args) < 2 {
+		ctxWarnw(context.TODO(), "device-vlan-update-invalid-number-of-args", log.Fields{"args": args})
+		return nil, errors.New("invalid-number-of-args")
+	}
+	deviceID := &voltha.ID{}
+	vlan := &ic.IntType{}
+	transactionID := &ic.StrType{}
+	for _, arg := range args {
+		switch arg.Key {
+		case "device_id":
+			if err := ptypes.UnmarshalAny(arg.Value, deviceID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-device-id", log.Fields{"error": err})
+				return nil, err
+			}
+		case "vlan":
+			if err := ptypes.UnmarshalAny(arg.Value, vlan); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-vlan", log.Fields{"error": err})
+				return nil, err
+			}
+		case kafka.TransactionKey:
+			if err := ptypes.UnmarshalAny(arg.Value, transactionID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-transaction-ID", log.Fields{"error": err})
+				return nil, err
+			}
+		}
+	}
+	ctxDebugw(context.TODO(), "DeviceVlanUpdate", log.Fields{"deviceID": deviceID.Id, "vlan": vlan.Val, "transactionID": transactionID.Val})
+
+	if err := rhp.deviceMgr.updateDeviceVlan(context.TODO(), deviceID.Id, uint32(vlan.Val)); err != nil {
+		ctxDebugw(context.TODO(), "unable-to-update-device-vlan", log.Fields{"error": err})
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
 // GetChildDevice returns details of child device
 func (rhp *AdapterRequestHandlerProxy) GetChildDevice(args []*ic.Argument) (*voltha.Device, error) {
 	if len(args) < 3 {
@@ -204,7 +330,14 @@ func (rhp *AdapterRequestHandlerProxy) GetChildDevice(args []*ic.Argument) (*vol
 	}
 	logger.Debugw("GetChildDevice", log.Fields{"parentDeviceID": pID.Id, "args": args, "transactionID": transactionID.Val})
 
-	return rhp.deviceMgr.GetChildDevice(context.TODO(), pID.Id, serialNumber.Val, onuID.Val, parentPortNo.Val)
+	// The lookup failures carry distinct status codes - InvalidArgument, FailedPrecondition,
+	// NotFound - which travel back to the adapter in the error reason so its retry logic can
+	// tell "child never created" from "wrong parent"
+	device, err := rhp.deviceMgr.GetChildDevice(context.TODO(), pID.Id, serialNumber.Val, onuID.Val, parentPortNo.Val)
+	if err != nil {
+		ctxDebugw(context.TODO(), "get-child-device-failed", log.Fields{"parentDeviceID": pID.Id, "code": status.Code(err), "error": err})
+	}
+	return device, err
 }
 
 // GetChildDeviceWithProxyAddress returns details of child device with proxy address
@@ -502,6 +635,50 @@ func (rhp *AdapterRequestHandlerProxy) PortsStateUpdate(args []*ic.Argument) (*e
 	return &empty.Empty{}, nil
 }
 
+// PortsStatesUpdate applies a batch of per-port state changes on the device in a single
+// transaction.  Each port of the payload carries the port number, the port type and the
+// new oper status.
+func (rhp *AdapterRequestHandlerProxy) PortsStatesUpdate(args []*ic.Argument) (*empty.Empty, error) {
+	if len(args) < 2 {
+		ctxWarnw(context.TODO(), "invalid-number-of-args", log.Fields{"args": args})
+		err := errors.New("invalid-number-of-args")
+		return nil, err
+	}
+	deviceID := &voltha.ID{}
+	ports := &voltha.Ports{}
+	transactionID := &ic.StrType{}
+	for _, arg := range args {
+		switch arg.Key {
+		case "device_id":
+			if err := ptypes.UnmarshalAny(arg.Value, deviceID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-device-id", log.Fields{"error": err})
+				return nil, err
+			}
+		case "ports":
+			if err := ptypes.UnmarshalAny(arg.Value, ports); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-ports", log.Fields{"error": err})
+				return nil, err
+			}
+		case kafka.TransactionKey:
+			if err := ptypes.UnmarshalAny(arg.Value, transactionID); err != nil {
+				ctxWarnw(context.TODO(), "cannot-unmarshal-transaction-ID", log.Fields{"error": err})
+				return nil, err
+			}
+		}
+	}
+	ctxDebugw(context.TODO(), "PortsStatesUpdate", log.Fields{"deviceID": deviceID.Id, "ports": len(ports.Items), "transactionID": transactionID.Val})
+
+	changes := make([]portStateChange, 0, len(ports.Items))
+	for _, port := range ports.Items {
+		changes = append(changes, portStateChange{portType: port.Type, portNo: port.PortNo, operStatus: port.OperStatus})
+	}
+	if err := rhp.deviceMgr.updatePortsStates(context.TODO(), deviceID.Id, changes); err != nil {
+		ctxDebugw(context.TODO(), "unable-to-update-ports-states", log.Fields{"error": err})
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
 // PortStateUpdate updates the port state of the device
 func (rhp *AdapterRequestHandlerProxy) PortStateUpdate(args []*ic.Argument) (*empty.Empty, error) {
 	if len(args) < 3 {
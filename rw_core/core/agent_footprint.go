@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A handful of devices with huge flow tables can dominate the core's memory and nothing
+// used to say which ones.  Each agent now keeps an approximate byte count of its retained
+// state - flows, groups, ports and the device type cache - adjusted incrementally at the
+// mutation sites rather than recomputed, so the accounting costs no more than the
+// mutation itself.  The diagnostics endpoint reports the heaviest devices sorted by size,
+// an optional soft cap raises a warning event when a device crosses it, and an optional
+// hard cap refuses further flow adds outright.
+
+// footprintTopDevices bounds how many devices the diagnostics report lists
+const footprintTopDevices = 10
+
+// agentFootprint holds the approximate byte counts per component; read atomically
+type agentFootprint struct {
+	flows  int64
+	groups int64
+	ports  int64
+	caches int64
+}
+
+// flowsFootprint returns the approximate size of the given flows in bytes
+func flowsFootprint(flows []*ofp.OfpFlowStats) int64 {
+	var bytes int64
+	for _, flow := range flows {
+		bytes += int64(proto.Size(flow))
+	}
+	return bytes
+}
+
+// groupsFootprint returns the approximate size of the given groups in bytes
+func groupsFootprint(groups []*ofp.OfpGroupEntry) int64 {
+	var bytes int64
+	for _, group := range groups {
+		bytes += int64(proto.Size(group))
+	}
+	return bytes
+}
+
+// portsFootprint returns the approximate size of the given ports in bytes
+func portsFootprint(ports []*voltha.Port) int64 {
+	var bytes int64
+	for _, port := range ports {
+		bytes += int64(proto.Size(port))
+	}
+	return bytes
+}
+
+// initFootprint seeds the accounting from a full device record; the one full computation,
+// done when the agent starts
+func (agent *DeviceAgent) initFootprint(device *voltha.Device) {
+	atomic.StoreInt64(&agent.footprint.flows, flowsFootprint(device.GetFlows().GetItems()))
+	atomic.StoreInt64(&agent.footprint.groups, groupsFootprint(device.GetFlowGroups().GetItems()))
+	atomic.StoreInt64(&agent.footprint.ports, portsFootprint(device.Ports))
+	agent.deviceTypeCacheLock.RLock()
+	if agent.deviceTypeCache != nil {
+		atomic.StoreInt64(&agent.footprint.caches, int64(proto.Size(agent.deviceTypeCache)))
+	}
+	agent.deviceTypeCacheLock.RUnlock()
+}
+
+// adjustFlowFootprint applies the delta of an incremental flow mutation
+func (agent *DeviceAgent) adjustFlowFootprint(added, removed []*ofp.OfpFlowStats) {
+	atomic.AddInt64(&agent.footprint.flows, flowsFootprint(added)-flowsFootprint(removed))
+}
+
+// adjustGroupFootprint applies the delta of an incremental group mutation
+func (agent *DeviceAgent) adjustGroupFootprint(added, removed []*ofp.OfpGroupEntry) {
+	atomic.AddInt64(&agent.footprint.groups, groupsFootprint(added)-groupsFootprint(removed))
+}
+
+// setFlowsFootprint re-seeds the flow accounting after a table replacement or rollback
+func (agent *DeviceAgent) setFlowsFootprint(flows []*ofp.OfpFlowStats) {
+	atomic.StoreInt64(&agent.footprint.flows, flowsFootprint(flows))
+}
+
+// setGroupsFootprint re-seeds the group accounting after a table replacement or rollback
+func (agent *DeviceAgent) setGroupsFootprint(groups []*ofp.OfpGroupEntry) {
+	atomic.StoreInt64(&agent.footprint.groups, groupsFootprint(groups))
+}
+
+// setPortsFootprint re-seeds the port accounting; port lists are small enough that the
+// set-style update costs no more than the port mutation itself
+func (agent *DeviceAgent) setPortsFootprint(ports []*voltha.Port) {
+	atomic.StoreInt64(&agent.footprint.ports, portsFootprint(ports))
+}
+
+// setCacheFootprint records the size of the resolved device type cache
+func (agent *DeviceAgent) setCacheFootprint(dType *voltha.DeviceType) {
+	if dType == nil {
+		atomic.StoreInt64(&agent.footprint.caches, 0)
+		return
+	}
+	atomic.StoreInt64(&agent.footprint.caches, int64(proto.Size(dType)))
+}
+
+// footprintBytes returns the agent's total approximate footprint in bytes
+func (agent *DeviceAgent) footprintBytes() int64 {
+	return atomic.LoadInt64(&agent.footprint.flows) +
+		atomic.LoadInt64(&agent.footprint.groups) +
+		atomic.LoadInt64(&agent.footprint.ports) +
+		atomic.LoadInt64(&agent.footprint.caches)
+}
+
+// footprintBreakdown returns the per-component byte counts
+func (agent *DeviceAgent) footprintBreakdown() map[string]int64 {
+	return map[string]int64{
+		"flows":  atomic.LoadInt64(&agent.footprint.flows),
+		"groups": atomic.LoadInt64(&agent.footprint.groups),
+		"ports":  atomic.LoadInt64(&agent.footprint.ports),
+		"caches": atomic.LoadInt64(&agent.footprint.caches),
+	}
+}
+
+// footprintWouldExceed reports whether adding the given bytes would cross the configured
+// hard cap; a zero cap never rejects
+func (agent *DeviceAgent) footprintWouldExceed(addedBytes int64) bool {
+	limit := agent.deviceMgr.footprintLimitBytes
+	return limit > 0 && agent.footprintBytes()+addedBytes > limit
+}
+
+// checkFootprint is invoked after a flow mutation; it raises a single warning event when
+// the footprint crosses the soft cap and re-arms once it drops back below.  The caller
+// must hold the request queue.
+func (agent *DeviceAgent) checkFootprint(device *voltha.Device) {
+	warnBytes := agent.deviceMgr.footprintWarnBytes
+	if warnBytes <= 0 {
+		return
+	}
+	total := agent.footprintBytes()
+	if total >= warnBytes {
+		if !agent.footprintWarned {
+			agent.footprintWarned = true
+			logger.Warnw("device-memory-footprint-above-threshold", log.Fields{"device-id": agent.deviceID, "bytes": total, "threshold": warnBytes})
+			go agent.deviceMgr.eventMgr.publishDeviceEvent(device, deviceFootprintEvent, map[string]string{
+				"bytes":     strconv.FormatInt(total, 10),
+				"threshold": strconv.FormatInt(warnBytes, 10),
+			})
+		}
+	} else {
+		agent.footprintWarned = false
+	}
+}
+
+// footprintDiagnostics reports the heaviest devices sorted by size; devices over the soft
+// cap are raised as alerts
+func (dMgr *DeviceManager) footprintDiagnostics() map[string]interface{} {
+	type deviceBytes struct {
+		id    string
+		bytes int64
+	}
+	var devices []deviceBytes
+	var total int64
+	alerts := []string{}
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		bytes := agent.footprintBytes()
+		devices = append(devices, deviceBytes{id: agent.deviceID, bytes: bytes})
+		total += bytes
+		if dMgr.footprintWarnBytes > 0 && bytes >= dMgr.footprintWarnBytes {
+			alerts = append(alerts, "device-"+agent.deviceID+"-footprint-"+strconv.FormatInt(bytes, 10)+"-bytes")
+		}
+		return true
+	})
+	sort.Slice(devices, func(i, j int) bool { return devices[i].bytes > devices[j].bytes })
+	if len(devices) > footprintTopDevices {
+		devices = devices[:footprintTopDevices]
+	}
+	top := make([]map[string]interface{}, 0, len(devices))
+	for _, device := range devices {
+		top = append(top, map[string]interface{}{"device-id": device.id, "bytes": device.bytes})
+	}
+	stats := map[string]interface{}{
+		"total-bytes": total,
+		"top-devices": top,
+	}
+	if len(alerts) > 0 {
+		stats[alertsKey] = alerts
+	}
+	return stats
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFootprintTracksFlowMutations(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:35", 0)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	// The seed covers the port and the device type cache
+	base := agent.footprintBytes()
+	assert.True(t, base > 0)
+	breakdown := agent.footprintBreakdown()
+	assert.True(t, breakdown["ports"] > 0)
+	assert.Equal(t, int64(0), breakdown["flows"])
+
+	// Adding flows grows the accounting by exactly their size
+	flows := []*ofp.OfpFlowStats{{Id: 101, TableId: 1, Priority: 1000}, {Id: 102, TableId: 1, Priority: 2000}}
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, flows, nil, nil))
+	assert.Equal(t, base+flowsFootprint(flows), agent.footprintBytes())
+
+	// Deleting one shrinks it by that flow's share
+	assert.Nil(t, dMgr.deleteFlowsAndGroups(ctx, device.Id, flows[:1], nil, nil))
+	assert.Equal(t, base+flowsFootprint(flows[1:]), agent.footprintBytes())
+}
+
+func TestFootprintHardCapRefusesFlowAdds(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:36", 0)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	dMgr.footprintLimitBytes = agent.footprintBytes() + 1
+
+	err := dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 201, TableId: 1, Priority: 1000}}, nil, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "memory-footprint-limit")
+	refreshed, getErr := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, getErr)
+	assert.Equal(t, 0, len(refreshed.Flows.Items))
+}
+
+func TestFootprintWarningArmsAndRearms(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:37", 0)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	dMgr.footprintWarnBytes = agent.footprintBytes() + 1
+
+	flows := []*ofp.OfpFlowStats{{Id: 301, TableId: 1, Priority: 1000}}
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, flows, nil, nil))
+	assert.True(t, agent.footprintWarned)
+
+	// Dropping back below the threshold re-arms the warning
+	assert.Nil(t, dMgr.deleteFlowsAndGroups(ctx, device.Id, flows, nil, nil))
+	assert.False(t, agent.footprintWarned)
+}
+
+func TestDiagnosticsReportsFootprint(t *testing.T) {
+	dMgr, dc := newDiagnosticsTestManager(time.Minute)
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:38")
+
+	stats := dc.collect()
+	footprint, ok := stats["memory-footprint"]
+	assert.True(t, ok)
+	assert.True(t, footprint["total-bytes"].(int64) > 0)
+	top := footprint["top-devices"].([]map[string]interface{})
+	assert.Equal(t, 1, len(top))
+	assert.Equal(t, device.Id, top[0]["device-id"])
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Failover rebuilds every agent's derived state - the resolved device type, the per-flow
+// confirmation records - piece by piece from adapters and the KV store, which on a large
+// population dominates the warm-up time of the taking-over core.  Each agent therefore
+// writes a compact snapshot of that derived state to a dedicated KV path at a
+// configurable cadence and on clean shutdown.  When an agent is loaded it first tries the
+// snapshot: the snapshot carries a digest of the device record it was taken against, and
+// only a snapshot whose digest still matches the loaded record is restored - anything
+// else is ignored and the agent reconstructs its state the slow way, so a stale snapshot
+// can never resurrect state the device has since moved past.
+
+const deviceSnapshotPath = "device_snapshots"
+
+// agentSnapshot is the serialized derived state of one device agent
+type agentSnapshot struct {
+	DeviceID          string             `json:"device-id"`
+	Digest            string             `json:"digest"`
+	DeviceType        *voltha.DeviceType `json:"device-type,omitempty"`
+	FlowConfirmations map[uint64]string  `json:"flow-confirmations,omitempty"`
+	TakenAt           time.Time          `json:"taken-at"`
+}
+
+// snapshotDigest fingerprints the device record a snapshot was taken against; a restore
+// only trusts a snapshot whose digest still matches the loaded record
+func snapshotDigest(device *voltha.Device) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d",
+		device.AdminState, device.OperStatus, device.ConnectStatus,
+		len(device.GetFlows().GetItems()), len(device.Ports))
+}
+
+// snapshotCadence returns the configured snapshot interval; 0 disables snapshots
+func (agent *DeviceAgent) snapshotCadence() time.Duration {
+	if agent.deviceMgr == nil {
+		return 0
+	}
+	return agent.deviceMgr.snapshotCadence
+}
+
+// buildSnapshot captures the agent's derived state against the given device record
+func (agent *DeviceAgent) buildSnapshot(device *voltha.Device) *agentSnapshot {
+	agent.deviceTypeCacheLock.RLock()
+	dType := agent.deviceTypeCache
+	agent.deviceTypeCacheLock.RUnlock()
+	snapshot := &agentSnapshot{
+		DeviceID:   agent.deviceID,
+		Digest:     snapshotDigest(device),
+		DeviceType: dType,
+		TakenAt:    time.Now(),
+	}
+	if confirmations := agent.flowConfirmationStates(); len(confirmations) > 0 {
+		snapshot.FlowConfirmations = confirmations
+	}
+	return snapshot
+}
+
+// writeSnapshot persists a snapshot of the agent's derived state taken against the given
+// device record; failures are logged and swallowed since the slow restore path always
+// remains available
+func (agent *DeviceAgent) writeSnapshot(ctx context.Context, device *voltha.Device) {
+	encoded, err := json.Marshal(agent.buildSnapshot(device))
+	if err != nil {
+		ctxWarnw(ctx, "failed-to-encode-device-snapshot", log.Fields{"device-id": agent.deviceID, "error": err})
+		return
+	}
+	if err := agent.clusterDataProxy.AddWithID(ctx, deviceSnapshotPath, agent.deviceID, &ic.StrType{Val: string(encoded)}); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-device-snapshot", log.Fields{"device-id": agent.deviceID, "error": err})
+		return
+	}
+	ctxDebugw(ctx, "device-snapshot-persisted", log.Fields{"device-id": agent.deviceID})
+}
+
+// maybeSnapshot writes a snapshot when the configured cadence allows; called after a
+// device write reached the KV store with the state that was persisted
+func (agent *DeviceAgent) maybeSnapshot(ctx context.Context, device *voltha.Device) {
+	cadence := agent.snapshotCadence()
+	if cadence <= 0 || time.Since(agent.lastSnapshot) < cadence {
+		return
+	}
+	agent.lastSnapshot = time.Now()
+	agent.writeSnapshot(ctx, device)
+}
+
+// restoreFromSnapshot tries to rebuild the agent's derived state from its persisted
+// snapshot.  The snapshot is only trusted when its digest matches the loaded device
+// record; on a miss or mismatch the agent falls back to full reconstruction.  Returns
+// whether a snapshot was restored.
+func (agent *DeviceAgent) restoreFromSnapshot(ctx context.Context, device *voltha.Device) bool {
+	if agent.snapshotCadence() <= 0 {
+		return false
+	}
+	entry := &ic.StrType{}
+	if found, err := agent.clusterDataProxy.Get(ctx, deviceSnapshotPath+"/"+agent.deviceID, entry); err != nil {
+		ctxWarnw(ctx, "failed-to-read-device-snapshot", log.Fields{"device-id": agent.deviceID, "error": err})
+		return false
+	} else if !found {
+		return false
+	}
+	snapshot := &agentSnapshot{}
+	if err := json.Unmarshal([]byte(entry.Val), snapshot); err != nil {
+		ctxWarnw(ctx, "failed-to-decode-device-snapshot", log.Fields{"device-id": agent.deviceID, "error": err})
+		return false
+	}
+	if snapshot.Digest != snapshotDigest(device) {
+		ctxWarnw(ctx, "device-snapshot-stale-falling-back", log.Fields{"device-id": agent.deviceID, "taken-at": snapshot.TakenAt})
+		return false
+	}
+	if snapshot.DeviceType != nil {
+		agent.deviceTypeCacheLock.Lock()
+		agent.deviceTypeCache = snapshot.DeviceType
+		agent.deviceTypeCacheLock.Unlock()
+	}
+	for id, state := range snapshot.FlowConfirmations {
+		agent.flowConfirmations.Store(id, state)
+	}
+	ctxInfow(ctx, "device-restored-from-snapshot", log.Fields{"device-id": agent.deviceID, "taken-at": snapshot.TakenAt, "flow-confirmations": len(snapshot.FlowConfirmations)})
+	return true
+}
+
+// removeSnapshot drops the persisted snapshot when the device itself is removed
+func (agent *DeviceAgent) removeSnapshot(ctx context.Context) {
+	if err := agent.clusterDataProxy.Remove(ctx, deviceSnapshotPath+"/"+agent.deviceID); err != nil {
+		ctxWarnw(ctx, "failed-to-remove-device-snapshot", log.Fields{"device-id": agent.deviceID, "error": err})
+	}
+}
+
+// snapshotAllAgents writes a final snapshot for every managed device; run on clean
+// shutdown so the taking-over core can restore the population without rebuilding it
+func (dMgr *DeviceManager) snapshotAllAgents(ctx context.Context) {
+	if dMgr.snapshotCadence <= 0 {
+		return
+	}
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+			ctxWarnw(ctx, "shutdown-snapshot-skipped", log.Fields{"device-id": agent.deviceID, "error": err})
+			return true
+		}
+		device := agent.getDeviceWithoutLock()
+		agent.requestQueue.RequestComplete()
+		agent.writeSnapshot(ctx, device)
+		return true
+	})
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadFreshAgent starts a second agent for an already persisted device, the way the
+// taking-over core does after a failover
+func loadFreshAgent(tb testing.TB, dMgr *DeviceManager, deviceID string) *DeviceAgent {
+	agent := newDeviceAgent(dMgr.adapterProxy, &voltha.Device{Id: deviceID}, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
+	if _, err := agent.start(context.Background(), nil); err != nil {
+		tb.Fatalf("agent load failed: %v", err)
+	}
+	return agent
+}
+
+func TestAgentSnapshotRestoresDerivedState(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.snapshotCadence = time.Minute
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:30", 2)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	waitUntil(t, 2*time.Second, "flow pushes were never confirmed", func() bool {
+		for _, state := range agent.flowConfirmationStates() {
+			if state != flowApplied {
+				return false
+			}
+		}
+		return len(agent.flowConfirmationStates()) == 2
+	})
+
+	current, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	agent.writeSnapshot(ctx, current)
+
+	fresh := loadFreshAgent(t, dMgr, device.Id)
+	assert.Equal(t, agent.flowConfirmationStates(), fresh.flowConfirmationStates())
+}
+
+func TestAgentSnapshotStaleDigestIgnored(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.snapshotCadence = time.Minute
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:31", 1)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	current, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	agent.writeSnapshot(ctx, current)
+
+	// The device moved on after the snapshot was taken; the digest no longer matches
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE))
+	fresh := loadFreshAgent(t, dMgr, device.Id)
+	assert.Equal(t, 0, len(fresh.flowConfirmationStates()))
+}
+
+func TestAgentSnapshotCadenceAndShutdown(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// With snapshots disabled the writes of an enable leave no snapshot behind
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:32")
+	entry := &ic.StrType{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, deviceSnapshotPath+"/"+device.Id, entry)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// A clean shutdown writes a final snapshot for every managed device
+	dMgr.snapshotCadence = time.Minute
+	dMgr.stop(ctx)
+	found, err = dMgr.clusterDataProxy.Get(ctx, deviceSnapshotPath+"/"+device.Id, entry)
+	assert.Nil(t, err)
+	assert.True(t, found)
+}
+
+// The pair below measures the failover warm-up of one agent with and without a snapshot
+// to restore from; run with -bench to compare.
+
+func benchmarkAgentLoad(b *testing.B, withSnapshot bool, mac string) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.snapshotCadence = time.Minute
+	device := newHotPathDevice(b, dMgr, mac, 8)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	current, err := dMgr.GetDevice(ctx, device.Id)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if withSnapshot {
+		agent.writeSnapshot(ctx, current)
+	} else {
+		agent.removeSnapshot(ctx)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		loadFreshAgent(b, dMgr, device.Id)
+	}
+}
+
+func BenchmarkAgentLoadWithSnapshot(b *testing.B) {
+	benchmarkAgentLoad(b, true, "00:00:00:00:4a:33")
+}
+
+func BenchmarkAgentLoadWithoutSnapshot(b *testing.B) {
+	benchmarkAgentLoad(b, false, "00:00:00:00:4a:34")
+}
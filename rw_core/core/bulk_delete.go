@@ -0,0 +1,207 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Lab teardown scripts delete hundreds of devices one call at a time.  The bulk delete
+// below resolves a filter - device type, parent, annotation - to concrete devices and
+// deletes them through the existing per-device path with bounded concurrency, returning a
+// per-device result.  Dry-run mode only returns what would be deleted, an empty filter is
+// refused outright, and devices that are operationally ACTIVE are only included when the
+// caller passes force.
+
+// bulkDeleteConcurrency bounds how many deletes run at once in execute mode
+const bulkDeleteConcurrency = 8
+
+// Actions reported per device by a bulk delete
+const (
+	bulkDeleteWouldDelete     = "would-delete"
+	bulkDeleteDeleted         = "deleted"
+	bulkDeleteSkippedActive   = "skipped-active-requires-force"
+	bulkDeleteCoveredByParent = "covered-by-parent"
+	bulkDeleteFailed          = "failed"
+	bulkDeleteCancelled       = "cancelled-before-delete"
+)
+
+// deviceFilter selects devices for a bulk operation; set fields are ANDed
+type deviceFilter struct {
+	DeviceType string
+	ParentID   string
+	// Annotation matches an ExtraArgs annotation, either "key" or "key=value"
+	Annotation string
+}
+
+// empty reports whether the filter selects nothing on purpose
+func (filter deviceFilter) empty() bool {
+	return filter.DeviceType == "" && filter.ParentID == "" && filter.Annotation == ""
+}
+
+// matches reports whether a device satisfies every set field of the filter
+func (filter deviceFilter) matches(device *voltha.Device) bool {
+	if filter.DeviceType != "" && device.Type != filter.DeviceType {
+		return false
+	}
+	if filter.ParentID != "" && device.ParentId != filter.ParentID {
+		return false
+	}
+	if filter.Annotation != "" {
+		annotations := deviceAnnotations(device)
+		key, want := filter.Annotation, ""
+		if split := strings.SplitN(filter.Annotation, "=", 2); len(split) == 2 {
+			key, want = split[0], split[1]
+		}
+		have, present := annotations[key]
+		if !present || (want != "" && have != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkDeleteResult is the outcome of a bulk delete for one matched device
+type bulkDeleteResult struct {
+	DeviceID string `json:"device-id"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+}
+
+// deleteDevices resolves the filter and deletes the matched devices.  In dry-run mode the
+// per-device results only preview the decisions; in execute mode the deletes run through
+// the existing per-device path - cascading children with it - with bounded concurrency.
+func (dMgr *DeviceManager) deleteDevices(ctx context.Context, filter deviceFilter, dryRun bool, force bool) ([]bulkDeleteResult, error) {
+	return dMgr.deleteDevicesTracked(ctx, filter, dryRun, force, nil)
+}
+
+// deleteDevicesTracked is deleteDevices with job hooks: progress is reported per finished
+// device and a cancelled context stops dispatching, reporting the devices never reached
+func (dMgr *DeviceManager) deleteDevicesTracked(ctx context.Context, filter deviceFilter, dryRun bool, force bool, progress jobProgress) ([]bulkDeleteResult, error) {
+	if filter.empty() {
+		return nil, newInvalidArgumentError("empty-device-filter")
+	}
+	devices, err := dMgr.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]*voltha.Device)
+	for _, device := range devices.Items {
+		if filter.matches(device) {
+			matched[device.Id] = device
+		}
+	}
+
+	var results []bulkDeleteResult
+	var toDelete []*voltha.Device
+	for _, device := range devices.Items {
+		device, ok := matched[device.Id]
+		if !ok {
+			continue
+		}
+		switch {
+		case device.OperStatus == voltha.OperStatus_ACTIVE && !force:
+			results = append(results, bulkDeleteResult{DeviceID: device.Id, Action: bulkDeleteSkippedActive})
+		case !device.Root && matched[device.ParentId] != nil:
+			// The per-device path cascades children, so deleting the parent covers this one
+			results = append(results, bulkDeleteResult{DeviceID: device.Id, Action: bulkDeleteCoveredByParent})
+		case dryRun:
+			results = append(results, bulkDeleteResult{DeviceID: device.Id, Action: bulkDeleteWouldDelete})
+		default:
+			toDelete = append(toDelete, device)
+		}
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	ctxInfow(ctx, "bulk-delete-executing", log.Fields{"matched": len(matched), "deleting": len(toDelete), "force": force})
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	completed := 0
+	semaphore := make(chan struct{}, bulkDeleteConcurrency)
+	for i, device := range toDelete {
+		if ctx.Err() != nil {
+			// A cancelled job stops dispatching; the devices never reached are reported
+			// rather than silently dropped from the result
+			for _, missed := range toDelete[i:] {
+				results = append(results, bulkDeleteResult{DeviceID: missed.Id, Action: bulkDeleteCancelled})
+			}
+			break
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(device *voltha.Device) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			ch := make(chan interface{}, 1)
+			dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+			result := bulkDeleteResult{DeviceID: device.Id, Action: bulkDeleteDeleted}
+			if err, failed := (<-ch).(error); failed {
+				result.Action = bulkDeleteFailed
+				result.Error = err.Error()
+			}
+			lock.Lock()
+			results = append(results, result)
+			completed++
+			if progress != nil {
+				progress(completed*100/len(toDelete), fmt.Sprintf("%d/%d-devices-deleted", completed, len(toDelete)))
+			}
+			lock.Unlock()
+		}(device)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// deleteDevicesJob runs the filtered bulk delete as a tracked job, returning its job ID
+// immediately instead of blocking the caller for the duration.  Dry runs are quick and
+// stay synchronous; only the executing form is offered as a job.
+func (dMgr *DeviceManager) deleteDevicesJob(ctx context.Context, filter deviceFilter, force bool) (*deviceJob, error) {
+	if filter.empty() {
+		return nil, newInvalidArgumentError("empty-device-filter")
+	}
+	return dMgr.startDeviceJob(ctx, "bulk-delete", nil, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		results, err := dMgr.deleteDevicesTracked(jobCtx, filter, false, force, progress)
+		if err != nil {
+			return "", err
+		}
+		encoded, err := json.Marshal(results)
+		return string(encoded), err
+	})
+}
+
+// DeleteDevicesByFilter is the NBI surface of the filtered bulk delete
+func (handler *APIHandler) DeleteDevicesByFilter(ctx context.Context, deviceType string, parentID string, annotation string, dryRun bool, force bool) ([]bulkDeleteResult, error) {
+	ctxDebugw(ctx, "DeleteDevicesByFilter", log.Fields{"device-type": deviceType, "parent-id": parentID, "annotation": annotation, "dry-run": dryRun, "force": force})
+	return handler.deviceMgr.deleteDevices(ctx, deviceFilter{DeviceType: deviceType, ParentID: parentID, Annotation: annotation}, dryRun, force)
+}
+
+// DeleteDevicesByFilterAsync starts the filtered bulk delete as a job and returns its
+// record; progress and the per-device results are tracked through the job queries
+func (handler *APIHandler) DeleteDevicesByFilterAsync(ctx context.Context, deviceType string, parentID string, annotation string, force bool) (*deviceJob, error) {
+	ctxDebugw(ctx, "DeleteDevicesByFilterAsync", log.Fields{"device-type": deviceType, "parent-id": parentID, "annotation": annotation, "force": force})
+	return handler.deviceMgr.deleteDevicesJob(ctx, deviceFilter{DeviceType: deviceType, ParentID: parentID, Annotation: annotation}, force)
+}
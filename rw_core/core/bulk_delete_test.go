@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// actionsByDevice indexes bulk delete results for order-independent assertions
+func actionsByDevice(results []bulkDeleteResult) map[string]string {
+	actions := make(map[string]string)
+	for _, result := range results {
+		actions[result.DeviceID] = result.Action
+	}
+	return actions
+}
+
+func TestBulkDeleteRejectsEmptyFilter(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	_, err := dMgr.deleteDevices(context.Background(), deviceFilter{}, true, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "empty-device-filter")
+}
+
+func TestBulkDeleteDryRunPreviews(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	first := enableOLT(t, dMgr, "00:00:00:00:4a:2c")
+	second := enableOLT(t, dMgr, "00:00:00:00:4a:2d")
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, first.Id, "site", "lab1"))
+
+	// The type filter previews both devices without touching them
+	results, err := dMgr.deleteDevices(ctx, deviceFilter{DeviceType: "olt"}, true, false)
+	assert.Nil(t, err)
+	actions := actionsByDevice(results)
+	assert.Equal(t, 2, len(actions))
+	assert.Equal(t, bulkDeleteWouldDelete, actions[first.Id])
+	assert.Equal(t, bulkDeleteWouldDelete, actions[second.Id])
+	for _, id := range []string{first.Id, second.Id} {
+		device, err := dMgr.GetDevice(ctx, id)
+		assert.Nil(t, err)
+		assert.NotEqual(t, voltha.AdminState_DELETED, device.AdminState)
+	}
+
+	// Annotation filters narrow by key or key=value
+	results, err = dMgr.deleteDevices(ctx, deviceFilter{Annotation: "site=lab1"}, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, first.Id, results[0].DeviceID)
+	results, err = dMgr.deleteDevices(ctx, deviceFilter{Annotation: "site=other"}, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+	results, err = dMgr.deleteDevices(ctx, deviceFilter{Annotation: "site"}, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+}
+
+func TestBulkDeleteRequiresForceForActiveDevices(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:4a:2e", 0)
+
+	// Without force the ACTIVE device is reported but left alone
+	results, err := dMgr.deleteDevices(ctx, deviceFilter{DeviceType: "olt"}, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, bulkDeleteSkippedActive, results[0].Action)
+	refreshed, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.NotEqual(t, voltha.AdminState_DELETED, refreshed.AdminState)
+
+	// With force the delete goes through the regular per-device path
+	results, err = dMgr.deleteDevices(ctx, deviceFilter{DeviceType: "olt"}, false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, bulkDeleteDeleted, results[0].Action)
+	assert.Equal(t, "", results[0].Error)
+	// The per-device delete finishes asynchronously: the device is either marked DELETED
+	// or already purged from the core by the time we look
+	waitUntil(t, 5*time.Second, "device-not-deleted", func() bool {
+		refreshed, err = dMgr.GetDevice(ctx, device.Id)
+		return err != nil || refreshed.AdminState == voltha.AdminState_DELETED
+	})
+}
+
+func TestBulkDeleteParentCascadeCoversChildren(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newHotPathDevice(t, dMgr, "00:00:00:00:4a:2f", 0)
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "BULK-SN-1", 1)
+	assert.Nil(t, err)
+
+	// A parent filter selects only the children
+	results, err := dMgr.deleteDevices(ctx, deviceFilter{ParentID: parent.Id}, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, child.Id, results[0].DeviceID)
+	assert.Equal(t, bulkDeleteWouldDelete, results[0].Action)
+
+	// When the filter matches both, the child is covered by the parent's cascade
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, parent.Id, "teardown", ""))
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, child.Id, "teardown", ""))
+	results, err = dMgr.deleteDevices(ctx, deviceFilter{Annotation: "teardown"}, true, true)
+	assert.Nil(t, err)
+	actions := actionsByDevice(results)
+	assert.Equal(t, 2, len(actions))
+	assert.Equal(t, bulkDeleteWouldDelete, actions[parent.Id])
+	assert.Equal(t, bulkDeleteCoveredByParent, actions[child.Id])
+}
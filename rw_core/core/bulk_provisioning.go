@@ -0,0 +1,197 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Rolling out a region means preprovisioning hundreds of OLTs, and driving createDevice
+// one call at a time is slow and leaves a half-created batch when an entry in the middle
+// is bad.  createDevicesBulk validates the whole manifest up front - known device types,
+// required addressing, no duplicates within the batch or against existing devices - then
+// creates the valid entries with a bounded number in flight and reports a per-entry
+// outcome.  In all-or-nothing mode any validation failure aborts the batch before a
+// single device is created.
+
+// bulkCreateConcurrency bounds how many creations of one batch are in flight at a time
+const bulkCreateConcurrency = 8
+
+// BulkCreateResult is the outcome of one entry of a bulk create, in manifest order
+type BulkCreateResult struct {
+	DeviceID string
+	Err      error
+}
+
+// validateBulkCreateSpec checks one manifest entry in isolation
+func (dMgr *DeviceManager) validateBulkCreateSpec(spec *voltha.Device) error {
+	if spec == nil {
+		return newInvalidArgumentError("nil-device-spec")
+	}
+	if spec.Type == "" {
+		return newInvalidArgumentError("device-type-required")
+	}
+	if _, err := dMgr.adapterMgr.getAdapterName(spec.Type); err != nil {
+		return newInvalidArgumentError(fmt.Sprintf("unknown-device-type:%s", spec.Type))
+	}
+	if spec.MacAddress == "" && spec.GetHostAndPort() == "" {
+		return newInvalidArgumentError("mac-address-or-host-and-port-required")
+	}
+	return nil
+}
+
+// createDevicesBulk creates the devices of a manifest, validating every entry before
+// anything is created.  The returned slice parallels the manifest; each entry carries
+// either the assigned device id or the error that befell it.  With allOrNothing set,
+// any validation failure aborts the whole batch and no device is created.
+func (dMgr *DeviceManager) createDevicesBulk(ctx context.Context, specs []*voltha.Device, allOrNothing bool) ([]*BulkCreateResult, error) {
+	return dMgr.createDevicesBulkTracked(ctx, specs, allOrNothing, nil)
+}
+
+// createDevicesBulkTracked is createDevicesBulk with job hooks: progress is reported per
+// finished entry and a cancelled context marks the entries never dispatched
+func (dMgr *DeviceManager) createDevicesBulkTracked(ctx context.Context, specs []*voltha.Device, allOrNothing bool, progress jobProgress) ([]*BulkCreateResult, error) {
+	results := make([]*BulkCreateResult, len(specs))
+	seenMacs := make(map[string]int)
+	seenHostPorts := make(map[string]int)
+	invalid := 0
+	for i, spec := range specs {
+		results[i] = &BulkCreateResult{}
+		if err := dMgr.validateBulkCreateSpec(spec); err != nil {
+			results[i].Err = err
+			invalid++
+			continue
+		}
+		if mac := spec.MacAddress; mac != "" {
+			if first, dup := seenMacs[mac]; dup {
+				results[i].Err = newInvalidArgumentError(fmt.Sprintf("duplicate-mac-address-of-entry-%d:%s", first, mac))
+				invalid++
+				continue
+			}
+			seenMacs[mac] = i
+		}
+		if hostPort := spec.GetHostAndPort(); hostPort != "" {
+			if first, dup := seenHostPorts[hostPort]; dup {
+				results[i].Err = newInvalidArgumentError(fmt.Sprintf("duplicate-host-and-port-of-entry-%d:%s", first, hostPort))
+				invalid++
+				continue
+			}
+			seenHostPorts[hostPort] = i
+		}
+		if exist, err := dMgr.isParentDeviceExist(ctx, spec); err != nil {
+			results[i].Err = err
+			invalid++
+		} else if exist {
+			results[i].Err = newInvalidArgumentError("device-already-pre-provisioned")
+			invalid++
+		}
+	}
+	if allOrNothing && invalid > 0 {
+		return results, status.Errorf(codes.Aborted, "bulk-create-aborted:%d-of-%d-entries-invalid", invalid, len(specs))
+	}
+
+	sem := make(chan struct{}, bulkCreateConcurrency)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	completed := 0
+	for i, spec := range specs {
+		if results[i].Err != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			// A cancelled job stops dispatching; the entries never reached carry the
+			// cancellation instead of looking like silent successes
+			results[i].Err = status.Errorf(codes.Aborted, "bulk-create-cancelled")
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, spec *voltha.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ch := make(chan interface{}, 1)
+			dMgr.createDevice(ctx, spec, ch)
+			switch res := (<-ch).(type) {
+			case *voltha.Device:
+				results[i].DeviceID = res.Id
+			case error:
+				results[i].Err = res
+			}
+			lock.Lock()
+			completed++
+			if progress != nil {
+				progress(completed*100/len(specs), fmt.Sprintf("%d/%d-entries-processed", completed, len(specs)))
+			}
+			lock.Unlock()
+		}(i, spec)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, result := range results {
+		if result.Err == nil {
+			created++
+		}
+	}
+	ctxInfow(ctx, "bulk-create-done", log.Fields{"entries": len(specs), "created": created, "failed": len(specs) - created})
+	return results, nil
+}
+
+// bulkCreateJobResult is the JSON form a job record keeps of one manifest entry
+type bulkCreateJobResult struct {
+	DeviceID string `json:"device-id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// createDevicesBulkJob runs the bulk create as a tracked job, returning its job ID
+// immediately.  An all-or-nothing batch that fails validation becomes a FAILED job
+// carrying the per-entry findings.
+func (dMgr *DeviceManager) createDevicesBulkJob(ctx context.Context, specs []*voltha.Device, allOrNothing bool) (*deviceJob, error) {
+	if len(specs) == 0 {
+		return nil, newInvalidArgumentError("empty-device-manifest")
+	}
+	return dMgr.startDeviceJob(ctx, "bulk-create", nil, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		results, err := dMgr.createDevicesBulkTracked(jobCtx, specs, allOrNothing, progress)
+		encoded := make([]bulkCreateJobResult, len(results))
+		for i, result := range results {
+			encoded[i].DeviceID = result.DeviceID
+			if result.Err != nil {
+				encoded[i].Error = result.Err.Error()
+			}
+		}
+		serialized, marshalErr := json.Marshal(encoded)
+		if marshalErr != nil {
+			return "", marshalErr
+		}
+		return string(serialized), err
+	})
+}
+
+// CreateDevicesBulkAsync starts the bulk create as a job and returns its record;
+// progress and the per-entry results are tracked through the job queries
+func (handler *APIHandler) CreateDevicesBulkAsync(ctx context.Context, specs []*voltha.Device, allOrNothing bool) (*deviceJob, error) {
+	ctxDebugw(ctx, "CreateDevicesBulkAsync", log.Fields{"entries": len(specs), "all-or-nothing": allOrNothing})
+	return handler.deviceMgr.createDevicesBulkJob(ctx, specs, allOrNothing)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBulkCreateDevices(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	specs := make([]*voltha.Device, 0, 20)
+	for i := 0; i < 20; i++ {
+		specs = append(specs, &voltha.Device{Type: "olt", MacAddress: fmt.Sprintf("00:00:00:00:20:%02x", i)})
+	}
+	results, err := dMgr.createDevicesBulk(ctx, specs, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(results))
+	ids := make(map[string]bool)
+	for _, result := range results {
+		assert.Nil(t, result.Err)
+		assert.NotEmpty(t, result.DeviceID)
+		ids[result.DeviceID] = true
+	}
+	assert.Equal(t, 20, len(ids))
+	devices, err := dMgr.ListDevices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(devices.Items))
+}
+
+func TestBulkCreateReportsPerEntryFailures(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	existing := enableOLT(t, dMgr, "00:00:00:00:21:00")
+
+	specs := []*voltha.Device{
+		{Type: "olt", MacAddress: "00:00:00:00:21:01"},
+		{Type: "dsl-modem", MacAddress: "00:00:00:00:21:02"}, // unknown type
+		{Type: "olt"}, // no addressing at all
+		{Type: "olt", MacAddress: "00:00:00:00:21:01"}, // duplicate within the batch
+		{Type: "olt", MacAddress: existing.MacAddress}, // already provisioned
+	}
+	results, err := dMgr.createDevicesBulk(ctx, specs, false)
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.NotEmpty(t, results[0].DeviceID)
+	assert.Equal(t, codes.InvalidArgument, status.Code(results[1].Err))
+	assert.Contains(t, results[1].Err.Error(), "unknown-device-type")
+	assert.Equal(t, codes.InvalidArgument, status.Code(results[2].Err))
+	assert.Equal(t, codes.InvalidArgument, status.Code(results[3].Err))
+	assert.Contains(t, results[3].Err.Error(), "duplicate-mac-address-of-entry-0")
+	assert.Equal(t, codes.InvalidArgument, status.Code(results[4].Err))
+}
+
+func TestBulkCreateAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	specs := []*voltha.Device{
+		{Type: "olt", MacAddress: "00:00:00:00:22:01"},
+		{Type: "olt", MacAddress: "00:00:00:00:22:02"},
+		{Type: "dsl-modem", MacAddress: "00:00:00:00:22:03"},
+	}
+	results, err := dMgr.createDevicesBulk(ctx, specs, true)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, 3, len(results))
+	assert.Empty(t, results[0].DeviceID)
+	assert.NotNil(t, results[2].Err)
+
+	// Nothing was created
+	devices, err := dMgr.ListDevices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(devices.Items))
+
+	// The same batch without the bad entry goes through atomically
+	results, err = dMgr.createDevicesBulk(ctx, specs[:2], true)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, results[0].DeviceID)
+	assert.NotEmpty(t, results[1].DeviceID)
+}
@@ -0,0 +1,394 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Races like an adapter timing out during a delete or an etcd blip during a flow push are
+// nearly impossible to reproduce on demand.  The chaos injector sits between the device
+// manager and its adapter/KV dependencies and applies scripted failures: fail the Nth matching
+// call, time an operation out, or add latency.  It is wired in only when explicitly enabled
+// via configuration; with no rules it is inert.
+
+// chaosRule describes one scripted failure.  An empty target matches every call of the
+// operation; otherwise the call target (device ID or KV key) must contain it.
+type chaosRule struct {
+	operation string        // operation to match, e.g. "deleteDevice" or "kv-put"
+	target    string        // optional device ID or key fragment filter
+	failNth   int           // fail the Nth matching call; 0 never fails
+	timeout   bool          // swallow matching calls so the caller times out
+	latency   time.Duration // delay applied to every matching call
+	calls     int           // matching calls seen so far
+}
+
+// chaosInjector holds the active failure rules
+type chaosInjector struct {
+	lock  sync.Mutex
+	rules []*chaosRule
+}
+
+func newChaosInjector() *chaosInjector {
+	return &chaosInjector{}
+}
+
+// defaultChaosInjector is the injector wired into the production wrappers when chaos
+// injection is enabled via configuration
+var defaultChaosInjector = newChaosInjector()
+
+// addRule registers a scripted failure
+func (ci *chaosInjector) addRule(rule *chaosRule) {
+	ci.lock.Lock()
+	defer ci.lock.Unlock()
+	ci.rules = append(ci.rules, rule)
+}
+
+// reset drops every rule
+func (ci *chaosInjector) reset() {
+	ci.lock.Lock()
+	defer ci.lock.Unlock()
+	ci.rules = nil
+}
+
+// intercept applies the rules matching the call.  It returns whether the call should be
+// swallowed to simulate a timeout, and an error when a failure is due.
+func (ci *chaosInjector) intercept(operation string, target string) (bool, error) {
+	ci.lock.Lock()
+	var latency time.Duration
+	var timeout bool
+	var err error
+	for _, rule := range ci.rules {
+		if rule.operation != operation {
+			continue
+		}
+		if rule.target != "" && !strings.Contains(target, rule.target) {
+			continue
+		}
+		rule.calls++
+		if rule.latency > latency {
+			latency = rule.latency
+		}
+		if rule.timeout {
+			timeout = true
+		}
+		if rule.failNth > 0 && rule.calls == rule.failNth {
+			err = status.Errorf(codes.Internal, "chaos-injected-failure-%s-%s", operation, target)
+		}
+	}
+	ci.lock.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if timeout || err != nil {
+		ctxWarnw(context.Background(), "chaos-injection", log.Fields{"operation": operation, "target": target, "timeout": timeout, "error": err})
+	}
+	return timeout, err
+}
+
+// chaosAdapterService wraps an adapterService, applying the injector rules before delegating.
+// A timed-out call returns a channel that is never written so the caller waits out its
+// context, mirroring an adapter that went silent.
+type chaosAdapterService struct {
+	inner    adapterService
+	injector *chaosInjector
+}
+
+func newChaosAdapterService(inner adapterService, injector *chaosInjector) *chaosAdapterService {
+	return &chaosAdapterService{inner: inner, injector: injector}
+}
+
+// gate applies the injector to one call.  When proceed is false the returned channel and
+// error are what the wrapped method must return.
+func (cas *chaosAdapterService) gate(operation string, deviceID string) (ch chan *kafka.RpcResponse, err error, proceed bool) {
+	timeout, err := cas.injector.intercept(operation, deviceID)
+	if timeout {
+		return make(chan *kafka.RpcResponse), nil, false
+	}
+	if err != nil {
+		return nil, err, false
+	}
+	return nil, nil, true
+}
+
+func (cas *chaosAdapterService) adoptDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("adoptDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.adoptDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) disableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("disableDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.disableDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) reEnableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("reEnableDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.reEnableDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) rebootDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("rebootDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.rebootDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) deleteDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("deleteDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.deleteDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) getOfpDeviceInfo(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("getOfpDeviceInfo", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.getOfpDeviceInfo(ctx, device)
+}
+
+func (cas *chaosAdapterService) getOfpPortInfo(ctx context.Context, device *voltha.Device, portNo uint32) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("getOfpPortInfo", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.getOfpPortInfo(ctx, device, portNo)
+}
+
+func (cas *chaosAdapterService) reconcileDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("reconcileDevice", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.reconcileDevice(ctx, device)
+}
+
+func (cas *chaosAdapterService) downloadImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("downloadImage", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.downloadImage(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) preflightImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("preflightImage", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.preflightImage(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) getImageDownloadStatus(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("getImageDownloadStatus", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.getImageDownloadStatus(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) cancelImageDownload(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("cancelImageDownload", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.cancelImageDownload(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) activateImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("activateImageUpdate", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.activateImageUpdate(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) revertImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("revertImageUpdate", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.revertImageUpdate(ctx, device, download)
+}
+
+func (cas *chaosAdapterService) packetOut(ctx context.Context, deviceType string, deviceID string, outPort uint32, packet *openflow_13.OfpPacketOut) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("packetOut", deviceID); !proceed {
+		return ch, err
+	}
+	return cas.inner.packetOut(ctx, deviceType, deviceID, outPort, packet)
+}
+
+func (cas *chaosAdapterService) updateFlowsBulk(ctx context.Context, device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("updateFlowsBulk", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.updateFlowsBulk(ctx, device, flows, groups, flowMetadata)
+}
+
+func (cas *chaosAdapterService) updateFlowsIncremental(ctx context.Context, device *voltha.Device, flowChanges *openflow_13.FlowChanges, groupChanges *openflow_13.FlowGroupChanges, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("updateFlowsIncremental", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.updateFlowsIncremental(ctx, device, flowChanges, groupChanges, flowMetadata)
+}
+
+func (cas *chaosAdapterService) updatePmConfigs(ctx context.Context, device *voltha.Device, pmConfigs *voltha.PmConfigs) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("updatePmConfigs", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.updatePmConfigs(ctx, device, pmConfigs)
+}
+
+func (cas *chaosAdapterService) simulateAlarm(ctx context.Context, device *voltha.Device, simulateReq *voltha.SimulateAlarmRequest) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("simulateAlarm", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.simulateAlarm(ctx, device, simulateReq)
+}
+
+func (cas *chaosAdapterService) disablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("disablePort", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.disablePort(ctx, device, port)
+}
+
+func (cas *chaosAdapterService) enablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("enablePort", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.enablePort(ctx, device, port)
+}
+
+func (cas *chaosAdapterService) childDeviceLost(ctx context.Context, deviceType string, pDeviceID string, pPortNo uint32, onuID uint32) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("childDeviceLost", pDeviceID); !proceed {
+		return ch, err
+	}
+	return cas.inner.childDeviceLost(ctx, deviceType, pDeviceID, pPortNo, onuID)
+}
+
+func (cas *chaosAdapterService) startOmciTest(ctx context.Context, device *voltha.Device, omcitestrequest *voltha.OmciTestRequest) (chan *kafka.RpcResponse, error) {
+	if ch, err, proceed := cas.gate("startOmciTest", device.Id); !proceed {
+		return ch, err
+	}
+	return cas.inner.startOmciTest(ctx, device, omcitestrequest)
+}
+
+// chaosKVClient wraps a kvstore.Client, applying the injector rules to the operations the
+// cluster data proxy and the device manager use.  A timed-out call blocks until the context
+// expires, mirroring an unreachable KV store.
+type chaosKVClient struct {
+	inner    kvstore.Client
+	injector *chaosInjector
+}
+
+func newChaosKVClient(inner kvstore.Client, injector *chaosInjector) *chaosKVClient {
+	return &chaosKVClient{inner: inner, injector: injector}
+}
+
+// gate applies the injector to one KV call
+func (ckv *chaosKVClient) gate(ctx context.Context, operation string, key string) error {
+	timeout, err := ckv.injector.intercept(operation, key)
+	if timeout {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+func (ckv *chaosKVClient) List(ctx context.Context, key string) (map[string]*kvstore.KVPair, error) {
+	if err := ckv.gate(ctx, "kv-list", key); err != nil {
+		return nil, err
+	}
+	return ckv.inner.List(ctx, key)
+}
+
+func (ckv *chaosKVClient) Get(ctx context.Context, key string) (*kvstore.KVPair, error) {
+	if err := ckv.gate(ctx, "kv-get", key); err != nil {
+		return nil, err
+	}
+	return ckv.inner.Get(ctx, key)
+}
+
+func (ckv *chaosKVClient) Put(ctx context.Context, key string, value interface{}) error {
+	if err := ckv.gate(ctx, "kv-put", key); err != nil {
+		return err
+	}
+	return ckv.inner.Put(ctx, key, value)
+}
+
+func (ckv *chaosKVClient) Delete(ctx context.Context, key string) error {
+	if err := ckv.gate(ctx, "kv-delete", key); err != nil {
+		return err
+	}
+	return ckv.inner.Delete(ctx, key)
+}
+
+func (ckv *chaosKVClient) Reserve(ctx context.Context, key string, value interface{}, ttl int64) (interface{}, error) {
+	if err := ckv.gate(ctx, "kv-reserve", key); err != nil {
+		return nil, err
+	}
+	return ckv.inner.Reserve(ctx, key, value, ttl)
+}
+
+func (ckv *chaosKVClient) ReleaseReservation(ctx context.Context, key string) error {
+	return ckv.inner.ReleaseReservation(ctx, key)
+}
+
+func (ckv *chaosKVClient) ReleaseAllReservations(ctx context.Context) error {
+	return ckv.inner.ReleaseAllReservations(ctx)
+}
+
+func (ckv *chaosKVClient) RenewReservation(ctx context.Context, key string) error {
+	return ckv.inner.RenewReservation(ctx, key)
+}
+
+func (ckv *chaosKVClient) Watch(ctx context.Context, key string, withPrefix bool) chan *kvstore.Event {
+	return ckv.inner.Watch(ctx, key, withPrefix)
+}
+
+func (ckv *chaosKVClient) AcquireLock(ctx context.Context, lockName string, timeout int) error {
+	return ckv.inner.AcquireLock(ctx, lockName, timeout)
+}
+
+func (ckv *chaosKVClient) ReleaseLock(lockName string) error {
+	return ckv.inner.ReleaseLock(lockName)
+}
+
+func (ckv *chaosKVClient) IsConnectionUp(ctx context.Context) bool {
+	return ckv.inner.IsConnectionUp(ctx)
+}
+
+func (ckv *chaosKVClient) CloseWatch(key string, ch chan *kvstore.Event) {
+	ckv.inner.CloseWatch(key, ch)
+}
+
+func (ckv *chaosKVClient) Close() {
+	ckv.inner.Close()
+}
+
+var _ adapterService = &chaosAdapterService{}
+var _ kvstore.Client = &chaosKVClient{}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/db"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newChaosClusterProxy returns an in-memory cluster proxy whose KV calls pass through the
+// chaos injector
+func newChaosClusterProxy(injector *chaosInjector) *model.Proxy {
+	backend := &db.Backend{Client: newChaosKVClient(newInMemoryKVClient(), injector), StoreType: "memory", PathPrefix: "service/voltha"}
+	return model.NewProxy(backend, "/")
+}
+
+// chaosStep is one step of a scripted chaos scenario
+type chaosStep struct {
+	name string
+	run  func(t *testing.T)
+}
+
+// runChaosScenario runs the steps in order, logging each so a failure points at the step
+// that broke
+func runChaosScenario(t *testing.T, steps []chaosStep) {
+	for _, step := range steps {
+		t.Logf("chaos-step: %s", step.name)
+		step.run(t)
+	}
+}
+
+func TestChaosInjectorRules(t *testing.T) {
+	injector := newChaosInjector()
+	injector.addRule(&chaosRule{operation: "deleteDevice", target: "1234", failNth: 2})
+
+	// Rules only apply to matching calls
+	timeout, err := injector.intercept("adoptDevice", "1234")
+	assert.False(t, timeout)
+	assert.Nil(t, err)
+	timeout, err = injector.intercept("deleteDevice", "other")
+	assert.False(t, timeout)
+	assert.Nil(t, err)
+
+	// The second matching call fails, the others pass
+	_, err = injector.intercept("deleteDevice", "1234")
+	assert.Nil(t, err)
+	_, err = injector.intercept("deleteDevice", "1234")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	_, err = injector.intercept("deleteDevice", "1234")
+	assert.Nil(t, err)
+
+	injector.reset()
+	_, err = injector.intercept("deleteDevice", "1234")
+	assert.Nil(t, err)
+}
+
+// TestChaosDeleteVsChildDetectRace reproduces a delete racing a child discovery: the adapter
+// is slow to acknowledge the parent delete while a child is detected on the same parent
+func TestChaosDeleteVsChildDetectRace(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	injector := newChaosInjector()
+	dMgr.adapterProxy = newChaosAdapterService(fas, injector)
+
+	var device *voltha.Device
+	var child *voltha.Device
+	var detectErr error
+	deleteDone := make(chan interface{}, 1)
+
+	runChaosScenario(t, []chaosStep{
+		{"create and enable the parent device", func(t *testing.T) {
+			ch := make(chan interface{}, 1)
+			dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:00:02"}, ch)
+			var ok bool
+			device, ok = (<-ch).(*voltha.Device)
+			assert.True(t, ok)
+			ch = make(chan interface{}, 1)
+			dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+			assert.Nil(t, <-ch)
+		}},
+		{"slow down the adapter delete", func(t *testing.T) {
+			injector.addRule(&chaosRule{operation: "deleteDevice", target: device.Id, latency: 50 * time.Millisecond})
+		}},
+		{"delete the parent while a child is detected", func(t *testing.T) {
+			go dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, deleteDone)
+			// Either side of the race may win: the detect succeeds, or the delete got
+			// there first and the detect is rejected
+			child, detectErr = dMgr.childDeviceDetected(ctx, device.Id, 1, "onu", 1, "VEND", "SERIAL-2", 1)
+			assert.Nil(t, <-deleteDone)
+		}},
+		{"both operations completed consistently", func(t *testing.T) {
+			parent, err := dMgr.GetDevice(ctx, device.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, voltha.AdminState_DELETED, parent.AdminState)
+			if detectErr == nil && child != nil {
+				// The detect won: the child was created under the now-deleted parent
+				_, err = dMgr.GetDevice(ctx, child.Id)
+				assert.Nil(t, err)
+			} else {
+				// The delete won: the detect was rejected and no child was created
+				assert.NotNil(t, detectErr)
+				_, err = dMgr.GetChildDevice(ctx, device.Id, "SERIAL-2", 1, 1)
+				assert.NotNil(t, err)
+			}
+			assert.Equal(t, 1, fas.callCount("deleteDevice"))
+		}},
+	})
+}
+
+// TestChaosFlowPushDuringKVOutage reproduces an etcd blip during a flow push: the store
+// write fails, the error reaches the caller and the adapter is never asked to apply the
+// flows; the push succeeds once the store recovers
+func TestChaosFlowPushDuringKVOutage(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	injector := newChaosInjector()
+	dMgr.clusterDataProxy = newChaosClusterProxy(injector)
+
+	var device *voltha.Device
+
+	runChaosScenario(t, []chaosStep{
+		{"create and enable the device", func(t *testing.T) {
+			ch := make(chan interface{}, 1)
+			dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:00:03"}, ch)
+			var ok bool
+			device, ok = (<-ch).(*voltha.Device)
+			assert.True(t, ok)
+			ch = make(chan interface{}, 1)
+			dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+			assert.Nil(t, <-ch)
+			// Activation must complete or the pushes below would be parked, not forwarded
+			assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+		}},
+		{"fail the next store write for the device", func(t *testing.T) {
+			injector.addRule(&chaosRule{operation: "kv-put", target: device.Id, failNth: 1})
+		}},
+		{"the flow push surfaces the store failure", func(t *testing.T) {
+			err := dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil)
+			assert.Equal(t, codes.Internal, status.Code(err))
+			assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+		}},
+		{"the push succeeds once the store recovers", func(t *testing.T) {
+			injector.reset()
+			assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil))
+			pushed, err := dMgr.GetDevice(ctx, device.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(pushed.GetFlows().GetItems()))
+		}},
+	})
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Disabling an OLT has always disabled every ONU behind it, but operators disagree on
+// whether that is the right reaction: some want the ONUs administratively disabled so
+// nothing moves until a person re-enables the OLT, others want them left ENABLED so they
+// recover on their own the moment the OLT comes back.  The cascade is therefore a policy,
+// selectable per parent device type or overridden per device:
+//
+//   CASCADE_DISABLE        today's behavior - every child is administratively disabled,
+//                          and re-enabled when the parent recovers.  Re-enabling goes
+//                          through the adapter's reEnableDevice, which re-applies the
+//                          flows held in the model.
+//   MARK_UNREACHABLE_ONLY  the children keep their admin state; only their connection
+//                          status is set to UNREACHABLE.  Their flows stay in the model
+//                          and on the devices, so nothing needs restoring on recovery;
+//                          requests arriving while unreachable are parked and replayed
+//                          when the child is marked REACHABLE again.
+//   NONE                   the children are left completely alone.
+//
+// The policy only governs the disable cascade - deleting the parent still deletes the
+// children regardless of policy.  The recovery path reads the policy again, so it should
+// not be changed while the parent is down.
+
+// childCascadePolicy selects what happens to the children when their parent is disabled
+type childCascadePolicy string
+
+const (
+	cascadeDisable         childCascadePolicy = "CASCADE_DISABLE"
+	cascadeMarkUnreachable childCascadePolicy = "MARK_UNREACHABLE_ONLY"
+	cascadeNone            childCascadePolicy = "NONE"
+)
+
+// Marker persisting a per-device policy override in the device details
+const childCascadeMarker = "child-cascade="
+
+// validateChildCascadePolicy rejects anything but the three known policies
+func validateChildCascadePolicy(policy childCascadePolicy) error {
+	switch policy {
+	case cascadeDisable, cascadeMarkUnreachable, cascadeNone:
+		return nil
+	default:
+		return newInvalidArgumentError("unknown-child-cascade-policy:" + string(policy))
+	}
+}
+
+// registerChildCascadePolicy declares the cascade policy for a parent device type.  An
+// invalid policy fails the registration and leaves any earlier declaration in place.
+func (dMgr *DeviceManager) registerChildCascadePolicy(deviceType string, policy childCascadePolicy) error {
+	if err := validateChildCascadePolicy(policy); err != nil {
+		ctxWarnw(context.Background(), "child-cascade-policy-rejected", log.Fields{"device-type": deviceType, "policy": policy, "error": err})
+		return err
+	}
+	dMgr.lockChildCascadePolicies.Lock()
+	defer dMgr.lockChildCascadePolicies.Unlock()
+	if dMgr.childCascadePolicies == nil {
+		dMgr.childCascadePolicies = make(map[string]childCascadePolicy)
+	}
+	dMgr.childCascadePolicies[deviceType] = policy
+	return nil
+}
+
+// parseChildCascadeMarker extracts the policy override from the device details; the
+// second return value is false when no marker is present
+func parseChildCascadeMarker(extraArgs string) (childCascadePolicy, bool) {
+	for _, entry := range strings.Split(extraArgs, ";") {
+		if strings.HasPrefix(entry, childCascadeMarker) {
+			policy := childCascadePolicy(entry[len(childCascadeMarker):])
+			if validateChildCascadePolicy(policy) == nil {
+				return policy, true
+			}
+		}
+	}
+	return "", false
+}
+
+// updateChildCascadeMarker rewrites the policy override on the device; an empty policy
+// clears it
+func (agent *DeviceAgent) updateChildCascadeMarker(ctx context.Context, policy childCascadePolicy) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	cloned := agent.getDeviceWithoutLock()
+	var entries []string
+	for _, entry := range strings.Split(cloned.ExtraArgs, ";") {
+		if entry == "" || strings.HasPrefix(entry, childCascadeMarker) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if policy != "" {
+		entries = append(entries, childCascadeMarker+string(policy))
+	}
+	cloned.ExtraArgs = strings.Join(entries, ";")
+	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+}
+
+// setDeviceChildCascadePolicy overrides the cascade policy for one parent device; the
+// override survives a core restart and takes precedence over the type's policy
+func (dMgr *DeviceManager) setDeviceChildCascadePolicy(ctx context.Context, deviceID string, policy childCascadePolicy) error {
+	if err := validateChildCascadePolicy(policy); err != nil {
+		return err
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	if err := agent.updateChildCascadeMarker(ctx, policy); err != nil {
+		return err
+	}
+	ctxInfow(ctx, "child-cascade-policy-set", log.Fields{"device-id": deviceID, "policy": policy})
+	return nil
+}
+
+// childCascadePolicyFor resolves the policy for a parent device: a per-device override
+// wins over the type's registration, and the default is today's full cascade
+func (dMgr *DeviceManager) childCascadePolicyFor(parentDevice *voltha.Device) childCascadePolicy {
+	if policy, have := parseChildCascadeMarker(parentDevice.GetExtraArgs()); have {
+		return policy
+	}
+	dMgr.lockChildCascadePolicies.RLock()
+	policy, have := dMgr.childCascadePolicies[parentDevice.Type]
+	dMgr.lockChildCascadePolicies.RUnlock()
+	if have {
+		return policy
+	}
+	return cascadeDisable
+}
+
+// markAllChildDevices sets the connection status on every child of the parent while
+// leaving the administrative and operational state untouched
+func (dMgr *DeviceManager) markAllChildDevices(ctx context.Context, parentDevice *voltha.Device, connStatus voltha.ConnectStatus_Types) error {
+	childDeviceIds, err := dMgr.getAllChildDeviceIds(parentDevice)
+	if err != nil {
+		return newNotFoundError("device", parentDevice.Id)
+	}
+	for _, childDeviceID := range childDeviceIds {
+		agent := dMgr.getDeviceAgent(ctx, childDeviceID)
+		if agent == nil {
+			continue
+		}
+		child, err := agent.getDevice(ctx)
+		if err != nil || child.AdminState == voltha.AdminState_DELETED {
+			continue
+		}
+		// Keep the operational status; only the reachability changes
+		if err := agent.updateDeviceStatus(ctx, child.OperStatus, connStatus); err != nil {
+			ctxWarnw(ctx, "child-connect-status-update-failed", log.Fields{"device-id": childDeviceID, "connect-status": connStatus, "error": err})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newCascadeChild provisions a child under the parent, registers its PON port so the
+// cascade can find it, and waits for its auto-enable so the status updates in the tests
+// are not raced by it
+func newCascadeChild(t *testing.T, dMgr *DeviceManager, parentID string, serial string, onuID int64) *voltha.Device {
+	ctx := context.Background()
+	child, err := dMgr.childDeviceDetected(ctx, parentID, 1, "onu", 1, "VEND", serial, onuID)
+	assert.Nil(t, err)
+	assert.Nil(t, dMgr.addPort(ctx, child.Id, &voltha.Port{PortNo: 1, Label: "pon-onu", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: parentID, PortNo: 1}}}))
+	waitUntil(t, 2*time.Second, "child was not auto-enabled", func() bool {
+		device, err := dMgr.GetDevice(ctx, child.Id)
+		return err == nil && device.AdminState == voltha.AdminState_ENABLED
+	})
+	return child
+}
+
+func TestChildCascadePolicyValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	err := dMgr.registerChildCascadePolicy("olt", "BOGUS")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.setDeviceChildCascadePolicy(ctx, "no-such-device", "BOGUS")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.setDeviceChildCascadePolicy(ctx, "no-such-device", cascadeNone)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Nil(t, dMgr.registerChildCascadePolicy("olt", cascadeMarkUnreachable))
+}
+
+// TestChildCascadeDefaultDisable verifies today's behavior is the default: losing the
+// parent disables the children, recovering it re-enables them through the adapter,
+// which re-applies their flows
+func TestChildCascadeDefaultDisable(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:41:01")
+	child := newCascadeChild(t, dMgr, parent.Id, "SERIAL-CC1", 1)
+
+	assert.Nil(t, dMgr.childDevicesLost(ctx, parent.Id))
+	disabled, err := dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_DISABLED, disabled.AdminState)
+
+	assert.Nil(t, dMgr.childDevicesDetected(ctx, parent.Id))
+	waitUntil(t, 2*time.Second, "child was not re-enabled", func() bool {
+		device, err := dMgr.GetDevice(ctx, child.Id)
+		return err == nil && device.AdminState == voltha.AdminState_ENABLED
+	})
+	assert.Equal(t, 1, fas.callCount("reEnableDevice"))
+}
+
+// TestChildCascadeMarkUnreachableOnly verifies the lighter policy: the children keep
+// their admin and operational state, only their reachability flips, and a flow push
+// parked while unreachable is replayed when the parent comes back
+func TestChildCascadeMarkUnreachableOnly(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.parkUnreachableRequests = true
+	assert.Nil(t, dMgr.registerChildCascadePolicy("olt", cascadeMarkUnreachable))
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:41:02")
+	child := newCascadeChild(t, dMgr, parent.Id, "SERIAL-CC2", 1)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, child.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	assert.Nil(t, dMgr.childDevicesLost(ctx, parent.Id))
+	marked, err := dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_ENABLED, marked.AdminState)
+	assert.Equal(t, voltha.OperStatus_ACTIVE, marked.OperStatus)
+	assert.Equal(t, voltha.ConnectStatus_UNREACHABLE, marked.ConnectStatus)
+	assert.Equal(t, 0, fas.callCount("disableDevice"))
+
+	// A push against the unreachable child is suppressed and parked, not forwarded
+	err = dMgr.addFlowsAndGroups(ctx, child.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+
+	// Recovery restores reachability and replays the parked push
+	assert.Nil(t, dMgr.childDevicesDetected(ctx, parent.Id))
+	restored, err := dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.ConnectStatus_REACHABLE, restored.ConnectStatus)
+	waitUntil(t, 2*time.Second, "parked flow push was not replayed", func() bool {
+		return fas.callCount("updateFlowsBulk") == 1
+	})
+	assert.Equal(t, 0, fas.callCount("reEnableDevice"))
+}
+
+// TestChildCascadeNonePerDeviceOverride verifies that NONE leaves the children alone
+// and that a per-device override wins over the type's registered policy
+func TestChildCascadeNonePerDeviceOverride(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	assert.Nil(t, dMgr.registerChildCascadePolicy("olt", cascadeMarkUnreachable))
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:41:03")
+	assert.Nil(t, dMgr.setDeviceChildCascadePolicy(ctx, parent.Id, cascadeNone))
+	child := newCascadeChild(t, dMgr, parent.Id, "SERIAL-CC3", 1)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, child.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	assert.Nil(t, dMgr.childDevicesLost(ctx, parent.Id))
+	assert.Nil(t, dMgr.childDevicesDetected(ctx, parent.Id))
+	untouched, err := dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_ENABLED, untouched.AdminState)
+	assert.Equal(t, voltha.ConnectStatus_REACHABLE, untouched.ConnectStatus)
+	assert.Equal(t, 0, fas.callCount("disableDevice"))
+	assert.Equal(t, 0, fas.callCount("reEnableDevice"))
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newChildLookupParent provisions an enabled OLT with one PON port
+func newChildLookupParent(t *testing.T, dMgr *DeviceManager, mac string) *voltha.Device {
+	ctx := context.Background()
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+	parent, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: parent.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.addPort(ctx, parent.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT}))
+	return parent
+}
+
+func TestGetChildDeviceDistinguishesFailureModes(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:0b:01")
+
+	// Neither serial number nor onu id supplied
+	_, err := dMgr.GetChildDevice(ctx, parent.Id, "", 0, 1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The parent has no children yet: the child was never created
+	_, err = dMgr.GetChildDevice(ctx, parent.Id, "SERIAL-X", 1, 1)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Contains(t, err.Error(), "no-child-devices")
+
+	// With a child present, a non-matching identity means "wrong parent", not "no children"
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-Y", 1)
+	assert.Nil(t, err)
+	// The child only becomes visible under the parent once it registers its PON port
+	assert.Nil(t, dMgr.addPort(ctx, child.Id, &voltha.Port{PortNo: 1, Label: "pon-onu", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: parent.Id, PortNo: 1}}}))
+	_, err = dMgr.GetChildDevice(ctx, parent.Id, "SERIAL-X", 2, 1)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, err.Error(), "serial:SERIAL-X")
+	assert.Contains(t, err.Error(), "onu:2")
+
+	// A matching identity still resolves the child
+	found, err := dMgr.GetChildDevice(ctx, parent.Id, "SERIAL-Y", 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "SERIAL-Y", found.SerialNumber)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// In a dual-core deployment both cores receive the ONU discovery from the shared topic, and
+// without coordination both create the child device.  A short-lived KV reservation keyed on
+// (parent device id, serial number) arbitrates: the core that wins the reservation creates the
+// device, the loser waits for the winner's device to show up and returns it.  The reservation
+// carries a TTL so a crashed winner cannot block discovery forever.
+const (
+	childClaimPath         = "child_claims"
+	childClaimTTL          = int64(30) // seconds
+	childClaimWaitInterval = time.Second
+)
+
+// childClaimKey builds the KV key arbitrating the creation of a child device
+func (dMgr *DeviceManager) childClaimKey(parentDeviceID string, serialNumber string) string {
+	return fmt.Sprintf("%s/%s/%s_%s", dMgr.kvStorePrefix, childClaimPath, parentDeviceID, serialNumber)
+}
+
+// claimChildDevice tries to reserve the creation of a child device against the peer core.  It
+// returns true when this core won the reservation and may proceed with the creation.
+func (dMgr *DeviceManager) claimChildDevice(ctx context.Context, parentDeviceID string, serialNumber string) (bool, error) {
+	if dMgr.kvClient == nil {
+		// Without a shared KV store this core is the only arbiter
+		return true, nil
+	}
+	value, err := dMgr.kvClient.Reserve(ctx, dMgr.childClaimKey(parentDeviceID, serialNumber), dMgr.coreInstanceID, childClaimTTL)
+	if err != nil {
+		return false, err
+	}
+	owner, err := kvstore.ToString(value)
+	if err != nil {
+		return false, err
+	}
+	return owner == dMgr.coreInstanceID, nil
+}
+
+// releaseChildClaim removes the reservation once the creation has completed, successfully or
+// not.  The TTL covers the case where this core dies before getting here.
+func (dMgr *DeviceManager) releaseChildClaim(parentDeviceID string, serialNumber string) {
+	if dMgr.kvClient == nil {
+		return
+	}
+	if err := dMgr.kvClient.ReleaseReservation(context.Background(), dMgr.childClaimKey(parentDeviceID, serialNumber)); err != nil {
+		logger.Warnw("failed-to-release-child-claim", log.Fields{"parentDeviceId": parentDeviceID, "serialNumber": serialNumber, "error": err})
+	}
+}
+
+// waitForChildDevice polls until the device created by the winning core becomes visible, up to
+// the claim TTL
+func (dMgr *DeviceManager) waitForChildDevice(ctx context.Context, parentDeviceID string, serialNumber string, onuID int64, parentPortNo int64) (*voltha.Device, error) {
+	deadline := time.Now().Add(time.Duration(childClaimTTL) * time.Second)
+	for {
+		if device, err := dMgr.GetChildDevice(ctx, parentDeviceID, serialNumber, onuID, parentPortNo); err == nil {
+			return device, nil
+		}
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return nil, newUnavailableError("child-device", serialNumber, ctx.Err())
+		}
+		time.Sleep(childClaimWaitInterval)
+	}
+}
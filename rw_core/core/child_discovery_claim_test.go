@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClaimChildDeviceWithoutKVStore(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// Without a shared KV store the only core always wins, and releasing is a no-op
+	won, err := dMgr.claimChildDevice(ctx, "olt-1", "SN-1")
+	assert.Nil(t, err)
+	assert.True(t, won)
+	dMgr.releaseChildClaim("olt-1", "SN-1")
+}
+
+func TestClaimChildDeviceArbitration(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	peer, _, _ := newLifecycleTestDeviceManager()
+	peer.coreInstanceID = "core-2"
+	kv := newInMemoryKVClient()
+	dMgr.kvClient, peer.kvClient = kv, kv
+	dMgr.kvStorePrefix, peer.kvStorePrefix = "service/voltha", "service/voltha"
+
+	// The first core to reserve the (parent, serial) key wins; the peer loses
+	won, err := dMgr.claimChildDevice(ctx, "olt-1", "SN-1")
+	assert.Nil(t, err)
+	assert.True(t, won)
+	won, err = peer.claimChildDevice(ctx, "olt-1", "SN-1")
+	assert.Nil(t, err)
+	assert.False(t, won)
+
+	// The winner re-claiming its own reservation still holds it
+	won, err = dMgr.claimChildDevice(ctx, "olt-1", "SN-1")
+	assert.Nil(t, err)
+	assert.True(t, won)
+
+	// A different serial number on the same parent is arbitrated independently
+	won, err = peer.claimChildDevice(ctx, "olt-1", "SN-2")
+	assert.Nil(t, err)
+	assert.True(t, won)
+
+	// Once released, the claim is up for grabs again
+	dMgr.releaseChildClaim("olt-1", "SN-1")
+	won, err = peer.claimChildDevice(ctx, "olt-1", "SN-1")
+	assert.Nil(t, err)
+	assert.True(t, won)
+}
+
+func TestWaitForChildDevice(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newHotPathDevice(t, dMgr, "00:00:00:00:4a:57", 0)
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "CLAIM-SN-1", 1)
+	assert.Nil(t, err)
+	// The child lookup walks the parent's port peers, wired up when the ONU adapter
+	// announces the child's PON port with the parent as its peer
+	assert.Nil(t, dMgr.addPort(ctx, child.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: parent.Id, PortNo: 1}}}))
+
+	// A device the winning core already created is returned right away
+	found, err := dMgr.waitForChildDevice(ctx, parent.Id, "CLAIM-SN-1", 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, child.Id, found.Id)
+
+	// With the context gone the wait gives up instead of polling out the TTL
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dMgr.waitForChildDevice(cancelled, parent.Id, "CLAIM-SN-MISSING", 2, 1)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
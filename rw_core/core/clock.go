@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import "time"
+
+// The timer-driven pieces of the core - flap dampening, adapter liveness, the stuck-device
+// sweeper - read the wall clock directly, which forces their tests to sleep through real
+// windows and makes them slow and flaky.  They instead take their time from the coreClock
+// below: production wires in the wall clock, tests inject a controllable fake and advance
+// it deterministically.  New timer-driven logic should consume the clock rather than the
+// time package.
+
+// coreClock is the time source of the core's timer-driven logic
+type coreClock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel that delivers the current time once the duration has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the wall clock used outside of tests
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// now returns the device manager's view of the current time.  A manager built without a
+// clock - the bare fixtures in tests - falls back to the wall clock.
+func (dMgr *DeviceManager) now() time.Time {
+	if dMgr.clock == nil {
+		return time.Now()
+	}
+	return dMgr.clock.Now()
+}
+
+// after arms a timer on the device manager's clock
+func (dMgr *DeviceManager) after(d time.Duration) <-chan time.Time {
+	if dMgr.clock == nil {
+		return time.After(d)
+	}
+	return dMgr.clock.After(d)
+}
+
+// now returns the adapter manager's view of the current time
+func (aMgr *AdapterManager) now() time.Time {
+	if aMgr.clock == nil {
+		return time.Now()
+	}
+	return aMgr.clock.Now()
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a controllable coreClock: time only moves when the test advances it, and
+// timers armed through After fire the moment the advance crosses their deadline
+type fakeClock struct {
+	lock    sync.Mutex
+	current time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{current: start}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return fc.current
+}
+
+func (fc *fakeClock) After(d time.Duration) <-chan time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	ch := make(chan time.Time, 1)
+	fc.waiters = append(fc.waiters, fakeClockWaiter{deadline: fc.current.Add(d), ch: ch})
+	return ch
+}
+
+func (fc *fakeClock) advance(d time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	fc.current = fc.current.Add(d)
+	kept := fc.waiters[:0]
+	for _, waiter := range fc.waiters {
+		if waiter.deadline.After(fc.current) {
+			kept = append(kept, waiter)
+			continue
+		}
+		waiter.ch <- fc.current
+	}
+	fc.waiters = kept
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	fc := newFakeClock(time.Now())
+	timer := fc.After(time.Minute)
+	fc.advance(59 * time.Second)
+	select {
+	case <-timer:
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+	fc.advance(time.Second)
+	select {
+	case <-timer:
+	default:
+		t.Fatal("timer did not fire at its deadline")
+	}
+}
+
+func TestAdapterLivenessExpiryDeterministic(t *testing.T) {
+	aMgr := newRegistrationTestAdapterManager()
+	fc := newFakeClock(time.Now())
+	aMgr.clock = fc
+	_, err := aMgr.registerAdapter(&voltha.Adapter{Id: "adapter-z", Vendor: "vendor-z"},
+		&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt-z", Adapter: "adapter-z"}}})
+	assert.Nil(t, err)
+	aMgr.adapterAgents["adapter-z"].updateCommunicationTime(fc.Now())
+
+	// Heard from just now: reachable
+	reachable, age, known := aMgr.adapterReachability("olt-z")
+	assert.True(t, known)
+	assert.True(t, reachable)
+	assert.True(t, age < time.Second)
+
+	// No sleeping: advancing the injected clock past the liveness window expires it
+	fc.advance(adapterLivenessWindow + time.Minute)
+	reachable, age, known = aMgr.adapterReachability("olt-z")
+	assert.True(t, known)
+	assert.False(t, reachable)
+	assert.True(t, age >= adapterLivenessWindow+time.Minute)
+}
+
+func TestStuckSweepTransitionTimeoutDeterministic(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Now())
+	dMgr.clock = fc
+	enableOLT(t, dMgr, "00:00:00:00:47:01") // enable leaves the device ACTIVATING
+
+	// The first sweep only starts the observation clock
+	dMgr.sweepStuckDevices(ctx)
+	assert.Equal(t, 0, fas.callCount("reconcileDevice"))
+
+	// Once the injected clock passes the activating threshold the sweep remediates
+	fc.advance(stuckPolicyFor("olt", stuckStateActivating).threshold + time.Minute)
+	dMgr.sweepStuckDevices(ctx)
+	waitUntil(t, 2*time.Second, "stuck device was never reconciled", func() bool {
+		return fas.callCount("reconcileDevice") == 1
+	})
+
+	// The observation clock restarted; the next sweep does not re-remediate
+	dMgr.sweepStuckDevices(ctx)
+	assert.Equal(t, 1, fas.callCount("reconcileDevice"))
+}
+
+func TestFlapDampeningLiftedDeterministic(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Now())
+	dMgr.clock = fc
+	device := enableOLT(t, dMgr, "00:00:00:00:47:02")
+
+	// Five transitions at the same instant push the device into dampening
+	flip := []struct {
+		oper voltha.OperStatus_Types
+		conn voltha.ConnectStatus_Types
+	}{
+		{voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE},
+		{voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE},
+		{voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE},
+		{voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE},
+		{voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE},
+	}
+	for _, status := range flip {
+		assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, status.oper, status.conn))
+	}
+	dampened, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, flapDampenedReason, dampened.Reason)
+	assert.Equal(t, voltha.OperStatus_ACTIVE, dampened.OperStatus)
+
+	// While dampened and not yet stable, further updates are coalesced rather than applied
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE))
+	coalesced, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_ACTIVE, coalesced.OperStatus)
+	assert.Equal(t, flapDampenedReason, coalesced.Reason)
+
+	// Advancing the injected clock through the stability period lifts dampening and the
+	// next update is applied
+	fc.advance(defaultFlapThresholds.stabilityPeriod + time.Second)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE))
+	lifted, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "", lifted.Reason)
+	assert.Equal(t, voltha.OperStatus_FAILED, lifted.OperStatus)
+}
+
+func TestStuckSweeperLoopUsesInjectedClock(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Now())
+	dMgr.clock = fc
+	dMgr.stuckSweepInterval = time.Hour
+
+	done := make(chan struct{})
+	go func() {
+		dMgr.runStuckDeviceSweeper(context.Background())
+		close(done)
+	}()
+	// Give the loop a moment to arm its timer on the fake clock, then drive one iteration
+	waitUntil(t, 2*time.Second, "sweeper never armed its timer", func() bool {
+		fc.lock.Lock()
+		defer fc.lock.Unlock()
+		return len(fc.waiters) == 1
+	})
+	fc.advance(time.Hour)
+	waitUntil(t, 2*time.Second, "sweeper never re-armed after a sweep", func() bool {
+		fc.lock.Lock()
+		defer fc.lock.Unlock()
+		return len(fc.waiters) == 1
+	})
+	dMgr.exitChannel <- 1
+	<-done
+}
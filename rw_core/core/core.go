@@ -43,6 +43,7 @@ type Core struct {
 	grpcServer        *grpcserver.GrpcServer
 	grpcNBIAPIHandler *APIHandler
 	adapterMgr        *AdapterManager
+	eventMgr          *EventManager
 	config            *config.RWCoreFlags
 	kmp               kafka.InterContainerProxy
 	clusterDataProxy  *model.Proxy
@@ -67,6 +68,11 @@ func NewCore(ctx context.Context, id string, cf *config.RWCoreFlags, kvClient kv
 	// so as to avoid trigger of Liveness check (due to Liveness timeout) when backend is alive
 	livenessChannelInterval := cf.LiveProbeInterval / 2
 
+	// Wrap the KV client with the chaos injection hooks when explicitly enabled
+	if cf.EnableChaosInjection {
+		kvClient = newChaosKVClient(kvClient, defaultChaosInjector)
+	}
+
 	// Setup the KV store
 	core.backend = db.Backend{
 		Client:                  kvClient,
@@ -94,6 +100,7 @@ func (core *Core) Start(ctx context.Context) error {
 				"logical-device-manager",
 				"adapter-manager",
 				"grpc-service",
+				diagnosticsProbeService,
 			)
 		}
 	}
@@ -116,9 +123,17 @@ func (core *Core) Start(ctx context.Context) error {
 	core.initKafkaManager(ctx)
 
 	logger.Debugw("values", log.Fields{"kmp": core.kmp})
+	core.eventMgr = newEventManager(core.kafkaClient, core.clusterDataProxy)
 	core.deviceMgr = newDeviceManager(core)
 	core.adapterMgr = newAdapterManager(core.clusterDataProxy, core.instanceID, core.kafkaClient, core.deviceMgr)
+	core.adapterMgr.skewTolerance = core.config.AdapterSkewTolerance
 	core.deviceMgr.adapterMgr = core.adapterMgr
+	core.deviceMgr.watchDeviceTypeChanges()
+	if p != nil {
+		// the diagnostics verdict starts healthy and is refreshed on every poll
+		core.deviceMgr.diagnostics.probe = p
+		p.UpdateStatus(diagnosticsProbeService, probe.ServiceStatusRunning)
+	}
 	core.logicalDeviceMgr = newLogicalDeviceManager(core, core.deviceMgr, core.kmp, core.clusterDataProxy, core.config.DefaultCoreTimeout)
 
 	// Start the KafkaManager. This must be done after the deviceMgr, adapterMgr, and
@@ -130,6 +145,7 @@ func (core *Core) Start(ctx context.Context) error {
 		core.config.LiveProbeInterval,
 		core.config.NotLiveProbeInterval)
 
+	go core.startEventManager(ctx)
 	go core.startDeviceManager(ctx)
 	go core.startLogicalDeviceManager(ctx)
 	go core.startGRPCService(ctx)
@@ -146,18 +162,14 @@ func (core *Core) Stop(ctx context.Context) {
 		logger.Info("stopping-adaptercore")
 		// Signal to the KVStoreMonitor that we are stopping.
 		close(core.exitChannel)
-		// Stop all the started services
-		if core.grpcServer != nil {
-			core.grpcServer.Stop()
-		}
-		if core.logicalDeviceMgr != nil {
-			core.logicalDeviceMgr.stop(ctx)
-		}
-		if core.deviceMgr != nil {
-			core.deviceMgr.stop(ctx)
+		// Stop the services in the explicit order defined in shutdown.go, each stage
+		// bounded by the configured timeout
+		timeout := core.config.ShutdownStageTimeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
 		}
-		if core.kmp != nil {
-			core.kmp.Stop()
+		for _, stage := range core.shutdownSequence() {
+			core.runShutdownStage(ctx, stage, timeout)
 		}
 		logger.Info("adaptercore-stopped")
 	})
@@ -373,6 +385,12 @@ func (core *Core) registerAdapterRequestHandlers(ctx context.Context, coreInstan
 		return err
 	}
 
+	// Register this instance's topic to serve requests forwarded by peer core instances
+	if err := dMgr.interCoreProxy.start(ctx); err != nil {
+		logger.Fatalw("Failed-registering-inter-core-handler", log.Fields{"error": err})
+		return err
+	}
+
 	logger.Info("request-handler-registered")
 	return nil
 }
@@ -383,6 +401,12 @@ func (core *Core) startDeviceManager(ctx context.Context) {
 	logger.Info("DeviceManager-Started")
 }
 
+func (core *Core) startEventManager(ctx context.Context) {
+	logger.Info("EventManager-Starting...")
+	core.eventMgr.start(ctx)
+	logger.Info("EventManager-Started")
+}
+
 func (core *Core) startLogicalDeviceManager(ctx context.Context) {
 	logger.Info("Logical-DeviceManager-Starting...")
 	core.logicalDeviceMgr.start(ctx)
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Deleting an ACTIVE device used to remove the model while the adapter still had the
+// device's flows programmed on hardware; the next device reusing those resources hit
+// conflicts.  A deletion now runs a barrier first: the adapter is asked to remove all
+// flows and groups and the deletion waits for the acknowledgement, bounded by a timeout.
+// When the adapter does not acknowledge in time the deletion proceeds as before - a
+// device must always be deletable - but a prominent warning and an event tell the
+// operator that hardware cleanup may be needed.  A forced deletion skips the barrier by
+// design; the internal cleanup paths use it where the barrier would only add delay.
+
+// deletionForceKey marks a context whose deletions skip the flow removal barrier
+type deletionForceKey struct{}
+
+// withForcedDeletion returns a context under which deleteDevice skips the barrier
+func withForcedDeletion(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletionForceKey{}, true)
+}
+
+// deletionForced reports whether the context requests a forced deletion
+func deletionForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(deletionForceKey{}).(bool)
+	return forced
+}
+
+// runDeletionBarrier asks the adapter to remove the device's flows and groups and waits
+// for the acknowledgement.  It never blocks the deletion: a missing acknowledgement is
+// logged and raised as an event, and the caller proceeds either way.
+func (agent *DeviceAgent) runDeletionBarrier(ctx context.Context, device *voltha.Device) {
+	timeout := agent.deviceMgr.deletionBarrierTimeout
+	if timeout <= 0 {
+		return
+	}
+	if device.OperStatus != voltha.OperStatus_ACTIVE {
+		// Nothing was programmed, or the adapter already lost the device
+		return
+	}
+	if (len(device.GetFlows().GetItems()) | len(device.GetFlowGroups().GetItems())) == 0 {
+		return
+	}
+	ctxInfow(ctx, "deletion-barrier-removing-flows", log.Fields{
+		"device-id": device.Id,
+		"flows":     len(device.GetFlows().GetItems()),
+		"groups":    len(device.GetFlowGroups().GetItems()),
+	})
+	subCtx, cancel := context.WithTimeout(agent.ownershipCtx, timeout)
+	response := coreutils.NewResponse()
+	rpcResponse, err := agent.adapterProxy.updateFlowsBulk(subCtx, device, &voltha.Flows{}, &voltha.FlowGroups{}, nil)
+	if err != nil {
+		cancel()
+		agent.reportDeletionBarrierFailure(ctx, device, err)
+		return
+	}
+	go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
+	if res := coreutils.WaitForNilOrErrorResponses(timeout, response); res != nil {
+		agent.reportDeletionBarrierFailure(ctx, device, res)
+		return
+	}
+	ctxInfow(ctx, "deletion-barrier-complete", log.Fields{"device-id": device.Id})
+}
+
+// reportDeletionBarrierFailure warns the operator that a deletion proceeded without the
+// adapter confirming the flow removal
+func (agent *DeviceAgent) reportDeletionBarrierFailure(ctx context.Context, device *voltha.Device, cause interface{}) {
+	ctxWarnw(ctx, "deletion-barrier-failed-hardware-cleanup-may-be-needed", log.Fields{
+		"device-id": device.Id,
+		"timeout":   agent.deviceMgr.deletionBarrierTimeout,
+		"error":     cause,
+	})
+	go agent.deviceMgr.eventMgr.publishDeviceEvent(device, deletionBarrierFailedEvent, map[string]string{
+		"timeout": agent.deviceMgr.deletionBarrierTimeout.String(),
+		"error":   fmt.Sprint(cause),
+	})
+}
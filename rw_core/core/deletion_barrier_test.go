@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBarrierTestDevice provisions an ACTIVE device carrying one flow, the state in which
+// a deletion must run the flow removal barrier
+func newBarrierTestDevice(t *testing.T, dMgr *DeviceManager, mac string) *voltha.Device {
+	ctx := context.Background()
+	device := enableOLT(t, dMgr, mac)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil))
+	return device
+}
+
+func TestDeletionBarrierRemovesFlowsFirst(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.deletionBarrierTimeout = 2 * time.Second
+	device := newBarrierTestDevice(t, dMgr, "00:00:00:00:43:01")
+	assert.Equal(t, 1, fas.callCount("updateFlowsBulk"))
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// The barrier sent an empty bulk update - the flow removal - before the deletion
+	assert.Equal(t, 2, fas.callCount("updateFlowsBulk"))
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+	deleted, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_DELETED, deleted.AdminState)
+}
+
+func TestDeletionBarrierSkippedWhenForced(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.deletionBarrierTimeout = 2 * time.Second
+	device := newBarrierTestDevice(t, dMgr, "00:00:00:00:43:02")
+	assert.Equal(t, 1, fas.callCount("updateFlowsBulk"))
+
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.NotNil(t, agent)
+	assert.Nil(t, agent.deleteDevice(withForcedDeletion(ctx)))
+
+	// No flow removal was attempted; the adapter only saw the deletion itself
+	assert.Equal(t, 1, fas.callCount("updateFlowsBulk"))
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+}
+
+func TestDeletionBarrierTimeoutDoesNotBlockDeletion(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.deletionBarrierTimeout = 50 * time.Millisecond
+	injector := newChaosInjector()
+	dMgr.adapterProxy = newChaosAdapterService(fas, injector)
+	device := newBarrierTestDevice(t, dMgr, "00:00:00:00:43:03")
+
+	// The adapter never acknowledges the flow removal
+	injector.addRule(&chaosRule{operation: "updateFlowsBulk", target: device.Id, timeout: true})
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// The deletion fell back to the old behavior instead of blocking
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+	deleted, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_DELETED, deleted.AdminState)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A core crashing mid-delete used to leave a half-deleted tree: children partially
+// removed, the logical device gone, the root still present - and a retried delete
+// tripped over whichever pieces the first attempt already removed.  Deletion is now
+// resumable: a deletion-intent marker is persisted before the delete starts and removed
+// when the cleanup chain completes, so a marker found at startup - or by a retried
+// delete call - means an interrupted delete to continue.  Resuming re-runs the cleanup
+// chain with every step tolerating already-removed pieces.  A marker that survives its
+// resume attempt past the staleness threshold raises an event for operator attention.
+
+// deletionIntentPath is where deletion-intent markers live, relative to the cluster proxy
+const deletionIntentPath = "deletion_intents"
+
+// staleDeletionThreshold is how old a marker may grow before its survival is escalated
+// to the operators rather than silently retried
+const staleDeletionThreshold = 30 * time.Minute
+
+// deletionIntent marks a delete that was started but has not completed yet
+type deletionIntent struct {
+	DeviceID    string    `json:"device-id"`
+	Root        bool      `json:"root"`
+	RequestedAt time.Time `json:"requested-at"`
+}
+
+// markDeletionIntent persists the marker making a delete resumable.  A persist failure
+// is logged, not returned - the delete still runs, it just cannot be resumed.
+func (dMgr *DeviceManager) markDeletionIntent(ctx context.Context, device *voltha.Device) {
+	intent := &deletionIntent{DeviceID: device.Id, Root: device.Root, RequestedAt: dMgr.now()}
+	encoded, err := json.Marshal(intent)
+	if err == nil {
+		err = dMgr.clusterDataProxy.AddWithID(ctx, deletionIntentPath, device.Id, &ic.StrType{Val: string(encoded)})
+	}
+	if err != nil {
+		ctxWarnw(ctx, "failed-to-persist-deletion-intent", log.Fields{"device-id": device.Id, "error": err})
+	}
+}
+
+// clearDeletionIntent removes the marker of a completed delete
+func (dMgr *DeviceManager) clearDeletionIntent(ctx context.Context, deviceID string) {
+	if err := dMgr.clusterDataProxy.Remove(ctx, deletionIntentPath+"/"+deviceID); err != nil {
+		ctxWarnw(ctx, "failed-to-clear-deletion-intent", log.Fields{"device-id": deviceID, "error": err})
+	}
+}
+
+// getDeletionIntent returns the marker of a device, if a delete was started and has not
+// completed
+func (dMgr *DeviceManager) getDeletionIntent(ctx context.Context, deviceID string) (*deletionIntent, bool) {
+	entry := &ic.StrType{}
+	if found, err := dMgr.clusterDataProxy.Get(ctx, deletionIntentPath+"/"+deviceID, entry); err != nil || !found {
+		return nil, false
+	}
+	intent := &deletionIntent{}
+	if err := json.Unmarshal([]byte(entry.Val), intent); err != nil {
+		ctxWarnw(ctx, "corrupt-deletion-intent", log.Fields{"device-id": deviceID, "error": err})
+		return nil, false
+	}
+	return intent, true
+}
+
+// listDeletionIntents returns every outstanding marker
+func (dMgr *DeviceManager) listDeletionIntents(ctx context.Context) ([]*deletionIntent, error) {
+	var entries []*ic.StrType
+	if err := dMgr.clusterDataProxy.List(ctx, deletionIntentPath, &entries); err != nil {
+		return nil, err
+	}
+	intents := make([]*deletionIntent, 0, len(entries))
+	for _, entry := range entries {
+		intent := &deletionIntent{}
+		if err := json.Unmarshal([]byte(entry.Val), intent); err != nil {
+			ctxWarnw(ctx, "skipping-corrupt-deletion-intent", log.Fields{"error": err})
+			continue
+		}
+		intents = append(intents, intent)
+	}
+	return intents, nil
+}
+
+// resumeDeletion continues an interrupted delete from where it left off.  Which point
+// that is follows from what survived: a device already gone needs only its marker and
+// agent cleaned up; a device not yet marked DELETED gets an ordinary delete retry; a
+// device marked DELETED re-runs the post-delete cleanup chain, each step skipping the
+// pieces the first attempt already removed.
+func (dMgr *DeviceManager) resumeDeletion(ctx context.Context, intent *deletionIntent) error {
+	device, err := dMgr.getDeviceFromModel(ctx, intent.DeviceID)
+	if err != nil {
+		// The tree is gone; only the marker outlived the crash
+		dMgr.stopManagingDevice(ctx, intent.DeviceID)
+		dMgr.clearDeletionIntent(ctx, intent.DeviceID)
+		return nil
+	}
+	// The first attempt already ran the flow removal barrier; the resume forces
+	ctx = withForcedDeletion(ctx)
+	if device.AdminState != voltha.AdminState_DELETED {
+		// The crash hit before the state write went through - retry from the top
+		if agent := dMgr.getDeviceAgent(ctx, device.Id); agent != nil {
+			return agent.deleteDevice(ctx)
+		}
+		return newNotFoundError("device", device.Id)
+	}
+	ctxInfow(ctx, "resuming-interrupted-deletion", log.Fields{"device-id": device.Id, "root": intent.Root, "requested-at": intent.RequestedAt})
+	var handlers []TransitionHandler
+	if device.Root {
+		handlers = []TransitionHandler{dMgr.DeleteAllUNILogicalPorts, dMgr.deleteRemainingChildren, dMgr.DeleteAllLogicalPorts, dMgr.DeleteLogicalDevice, dMgr.RunPostDeviceDelete}
+	} else {
+		handlers = []TransitionHandler{dMgr.ChildDeviceLost, dMgr.DeleteLogicalPorts, dMgr.RunPostDeviceDelete}
+	}
+	for _, handler := range handlers {
+		if err := handler(ctx, device); err != nil {
+			// Expected for pieces the first attempt already removed; the remaining
+			// steps still run
+			ctxDebugw(ctx, "deletion-resume-step-failed", log.Fields{"device-id": device.Id, "error": err})
+		}
+	}
+	return nil
+}
+
+// deleteRemainingChildren deletes whichever children of a half-deleted parent survived
+// the first attempt.  They are found by listing the model rather than through the
+// parent's port peers - a crashed delete may have dropped the peer entries already.
+func (dMgr *DeviceManager) deleteRemainingChildren(ctx context.Context, parent *voltha.Device) error {
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if device.ParentId != parent.Id || device.Id == parent.Id {
+			continue
+		}
+		if agent := dMgr.getDeviceAgent(ctx, device.Id); agent != nil {
+			if err := agent.deleteDevice(ctx); err != nil {
+				ctxWarnw(ctx, "failed-to-delete-remaining-child", log.Fields{"device-id": device.Id, "error": err})
+			}
+		}
+		dMgr.deviceTopicMgr.releaseDeviceTopic(ctx, device.Id)
+	}
+	return nil
+}
+
+// resumeStuckDeletions is the startup consistency pass over the outstanding markers.
+// A marker older than the staleness threshold has survived at least one earlier pass,
+// so it is escalated to the operators before this pass tries again.
+func (dMgr *DeviceManager) resumeStuckDeletions(ctx context.Context) {
+	intents, err := dMgr.listDeletionIntents(ctx)
+	if err != nil {
+		ctxWarnw(ctx, "failed-to-list-deletion-intents", log.Fields{"error": err})
+		return
+	}
+	for _, intent := range intents {
+		if dMgr.now().Sub(intent.RequestedAt) > staleDeletionThreshold {
+			ctxWarnw(ctx, "stale-deletion-intent", log.Fields{"device-id": intent.DeviceID, "requested-at": intent.RequestedAt})
+			go dMgr.eventMgr.publishDeviceEvent(&voltha.Device{Id: intent.DeviceID}, deletionStuckEvent, map[string]string{
+				"requested-at": intent.RequestedAt.Format(time.RFC3339),
+			})
+		}
+		if err := dMgr.resumeDeletion(ctx, intent); err != nil {
+			ctxWarnw(ctx, "failed-to-resume-deletion", log.Fields{"device-id": intent.DeviceID, "error": err})
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// deviceGone reports whether a device can no longer be read from the manager
+func deviceGone(dMgr *DeviceManager, deviceID string) bool {
+	_, err := dMgr.GetDevice(context.Background(), deviceID)
+	return err != nil
+}
+
+func TestDeleteClearsDeletionIntent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:4e")
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	waitUntil(t, 2*time.Second, "device was never removed", func() bool {
+		return deviceGone(dMgr, device.Id)
+	})
+	waitUntil(t, 2*time.Second, "deletion intent was never cleared", func() bool {
+		_, outstanding := dMgr.getDeletionIntent(ctx, device.Id)
+		return !outstanding
+	})
+}
+
+func TestInterruptedDeletionResumed(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:4a:4f")
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-RESUME", 1)
+	assert.Nil(t, err)
+	waitUntil(t, 2*time.Second, "child was not auto-enabled", func() bool {
+		device, err := dMgr.GetDevice(ctx, child.Id)
+		return err == nil && device.AdminState == voltha.AdminState_ENABLED
+	})
+
+	// Simulate a core that died right after the DELETED state write: the marker and
+	// the state are persisted, but none of the cleanup chain has run
+	device, err := dMgr.GetDevice(ctx, parent.Id)
+	assert.Nil(t, err)
+	dMgr.markDeletionIntent(ctx, device)
+	device.AdminState = voltha.AdminState_DELETED
+	assert.Nil(t, dMgr.clusterDataProxy.Update(ctx, "devices/"+parent.Id, device))
+	agent := dMgr.getDeviceAgent(ctx, parent.Id)
+	_, err = agent.syncFromKVStore(ctx)
+	assert.Nil(t, err)
+
+	dMgr.resumeStuckDeletions(ctx)
+
+	waitUntil(t, 2*time.Second, "half-deleted tree was never cleaned up", func() bool {
+		return deviceGone(dMgr, child.Id) && deviceGone(dMgr, parent.Id)
+	})
+	waitUntil(t, 2*time.Second, "deletion intent outlived the resume", func() bool {
+		_, outstanding := dMgr.getDeletionIntent(ctx, parent.Id)
+		return !outstanding
+	})
+}
+
+func TestStartupPassHandlesRetryAndOrphanMarkers(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// A marker without the DELETED state write means the crash hit before it; the
+	// pass retries the delete from the top
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:50")
+	current, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	dMgr.markDeletionIntent(ctx, current)
+
+	// A stale marker whose device is long gone is raised to the operators and dropped
+	orphan := &deletionIntent{DeviceID: "ghost-device", RequestedAt: time.Now().Add(-time.Hour)}
+	encoded, err := json.Marshal(orphan)
+	assert.Nil(t, err)
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, deletionIntentPath, orphan.DeviceID, &ic.StrType{Val: string(encoded)}))
+
+	dMgr.resumeStuckDeletions(ctx)
+
+	waitUntil(t, 2*time.Second, "retried delete never completed", func() bool {
+		return deviceGone(dMgr, device.Id)
+	})
+	waitUntil(t, 2*time.Second, "markers were never cleared", func() bool {
+		_, retried := dMgr.getDeletionIntent(ctx, device.Id)
+		_, ghost := dMgr.getDeletionIntent(ctx, orphan.DeviceID)
+		return !retried && !ghost
+	})
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Operators asking "when did the core last hear anything about this ONU" used to dig
+// through logs.  The tracker below keeps three timestamps per device - the last
+// adapter-originated update, the last NBI operation and the last successful model write -
+// updated cheaply in memory on the existing paths and flushed to the KV store at a
+// configurable cadence.  The device view metadata carries the ages, devices can be
+// listed least-recently-updated first, and devices silent beyond a threshold can be
+// pulled out for targeted reconciliation.
+
+const deviceActivityPath = "device_activity"
+
+// Activity kinds tracked per device
+const (
+	activityAdapter    = "adapter"
+	activityNBI        = "nbi"
+	activityModelWrite = "model-write"
+)
+
+// Keys of the activity ages in the device view metadata
+const (
+	lastAdapterUpdateKey = "last-adapter-update-age"
+	lastNBIOpKey         = "last-nbi-op-age"
+	lastModelWriteKey    = "last-model-write-age"
+)
+
+// deviceActivity holds the in-memory activity timestamps of one device
+type deviceActivity struct {
+	lock              sync.Mutex
+	lastAdapterUpdate time.Time
+	lastNBIOp         time.Time
+	lastModelWrite    time.Time
+	// dirty marks timestamps that moved since the last flush to the KV store
+	dirty bool
+}
+
+// snapshot returns the three timestamps without holding the lock afterwards
+func (da *deviceActivity) snapshot() (time.Time, time.Time, time.Time) {
+	da.lock.Lock()
+	defer da.lock.Unlock()
+	return da.lastAdapterUpdate, da.lastNBIOp, da.lastModelWrite
+}
+
+// lastActivity returns the most recent of the three timestamps; zero when the device has
+// never been heard about
+func (da *deviceActivity) lastActivity() time.Time {
+	adapter, nbi, write := da.snapshot()
+	last := adapter
+	if nbi.After(last) {
+		last = nbi
+	}
+	if write.After(last) {
+		last = write
+	}
+	return last
+}
+
+// activityOf returns the tracked activity of a device, creating it on first touch and
+// seeding it from the KV store so restarts do not reset the clocks
+func (dMgr *DeviceManager) activityOf(ctx context.Context, deviceID string) *deviceActivity {
+	if value, ok := dMgr.deviceActivities.Load(deviceID); ok {
+		return value.(*deviceActivity)
+	}
+	activity := &deviceActivity{}
+	entry := &ic.StrType{}
+	if found, err := dMgr.clusterDataProxy.Get(ctx, deviceActivityPath+"/"+deviceID, entry); err == nil && found {
+		activity.lastAdapterUpdate, activity.lastNBIOp, activity.lastModelWrite = decodeDeviceActivity(entry.Val)
+	}
+	value, _ := dMgr.deviceActivities.LoadOrStore(deviceID, activity)
+	return value.(*deviceActivity)
+}
+
+// markDeviceActivity moves the timestamp of the given activity kind to now.  It is called
+// from the update paths and must stay cheap; the KV store is only touched by the periodic
+// flush.
+func (dMgr *DeviceManager) markDeviceActivity(ctx context.Context, deviceID string, kind string) {
+	if dMgr == nil || dMgr.clusterDataProxy == nil {
+		return
+	}
+	activity := dMgr.activityOf(ctx, deviceID)
+	now := dMgr.now()
+	activity.lock.Lock()
+	defer activity.lock.Unlock()
+	switch kind {
+	case activityAdapter:
+		activity.lastAdapterUpdate = now
+	case activityNBI:
+		activity.lastNBIOp = now
+	case activityModelWrite:
+		activity.lastModelWrite = now
+	}
+	activity.dirty = true
+}
+
+// runActivityPersister periodically flushes the dirty activity timestamps until the
+// device manager stops
+func (dMgr *DeviceManager) runActivityPersister(ctx context.Context) {
+	for {
+		select {
+		case <-dMgr.after(dMgr.activityPersistInterval):
+			dMgr.persistDeviceActivities(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("device-activity-persister-stopped")
+			return
+		}
+	}
+}
+
+// persistDeviceActivities flushes every activity that moved since the last flush
+func (dMgr *DeviceManager) persistDeviceActivities(ctx context.Context) {
+	dMgr.deviceActivities.Range(func(key, value interface{}) bool {
+		deviceID := key.(string)
+		activity := value.(*deviceActivity)
+		activity.lock.Lock()
+		if !activity.dirty {
+			activity.lock.Unlock()
+			return true
+		}
+		encoded := encodeDeviceActivity(activity.lastAdapterUpdate, activity.lastNBIOp, activity.lastModelWrite)
+		activity.dirty = false
+		activity.lock.Unlock()
+		if err := dMgr.clusterDataProxy.AddWithID(ctx, deviceActivityPath, deviceID, &ic.StrType{Val: encoded}); err != nil {
+			ctxWarnw(ctx, "failed-to-persist-device-activity", log.Fields{"device-id": deviceID, "error": err})
+		}
+		return true
+	})
+}
+
+// stampDeviceActivityMeta stamps the activity ages onto the view metadata of a device;
+// only the timestamps the core has actually seen move are stamped
+func (dMgr *DeviceManager) stampDeviceActivityMeta(ctx context.Context, deviceID string, meta map[string]string) {
+	if dMgr.clusterDataProxy == nil {
+		return
+	}
+	adapter, nbi, write := dMgr.activityOf(ctx, deviceID).snapshot()
+	now := dMgr.now()
+	if !adapter.IsZero() {
+		meta[lastAdapterUpdateKey] = now.Sub(adapter).Truncate(time.Millisecond).String()
+	}
+	if !nbi.IsZero() {
+		meta[lastNBIOpKey] = now.Sub(nbi).Truncate(time.Millisecond).String()
+	}
+	if !write.IsZero() {
+		meta[lastModelWriteKey] = now.Sub(write).Truncate(time.Millisecond).String()
+	}
+}
+
+// listDevicesByLeastRecentActivity returns the devices ordered least recently updated
+// first; devices never heard about sort before everything else
+func (dMgr *DeviceManager) listDevicesByLeastRecentActivity(ctx context.Context) (*voltha.Devices, error) {
+	devices, err := dMgr.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lastOf := make(map[string]time.Time, len(devices.Items))
+	for _, device := range devices.Items {
+		lastOf[device.Id] = dMgr.activityOf(ctx, device.Id).lastActivity()
+	}
+	sort.SliceStable(devices.Items, func(i, j int) bool {
+		return lastOf[devices.Items[i].Id].Before(lastOf[devices.Items[j].Id])
+	})
+	return devices, nil
+}
+
+// listQuietDevices returns the devices silent beyond the threshold - candidates for a
+// targeted reconciliation.  A non-positive threshold falls back to the configured one.
+func (dMgr *DeviceManager) listQuietDevices(ctx context.Context, threshold time.Duration) (*voltha.Devices, error) {
+	if threshold <= 0 {
+		threshold = dMgr.quietDeviceThreshold
+	}
+	devices, err := dMgr.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := dMgr.now()
+	quiet := &voltha.Devices{}
+	for _, device := range devices.Items {
+		last := dMgr.activityOf(ctx, device.Id).lastActivity()
+		if last.IsZero() || now.Sub(last) > threshold {
+			quiet.Items = append(quiet.Items, device)
+		}
+	}
+	return quiet, nil
+}
+
+// ListDevicesLeastRecentlyUpdated is the NBI surface of the activity-ordered listing
+func (handler *APIHandler) ListDevicesLeastRecentlyUpdated(ctx context.Context) (*voltha.Devices, error) {
+	ctxDebugw(ctx, "ListDevicesLeastRecentlyUpdated", nil)
+	return handler.deviceMgr.listDevicesByLeastRecentActivity(ctx)
+}
+
+// ListQuietDevices is the NBI surface of the quiet-device filter; a zero threshold uses
+// the configured one
+func (handler *APIHandler) ListQuietDevices(ctx context.Context, threshold time.Duration) (*voltha.Devices, error) {
+	ctxDebugw(ctx, "ListQuietDevices", log.Fields{"threshold": threshold})
+	return handler.deviceMgr.listQuietDevices(ctx, threshold)
+}
+
+// encodeDeviceActivity serializes the three timestamps as "|"-separated unix nanoseconds;
+// a zero timestamp is encoded as 0
+func encodeDeviceActivity(adapter time.Time, nbi time.Time, write time.Time) string {
+	nanosOf := func(ts time.Time) string {
+		if ts.IsZero() {
+			return "0"
+		}
+		return strconv.FormatInt(ts.UnixNano(), 10)
+	}
+	return strings.Join([]string{nanosOf(adapter), nanosOf(nbi), nanosOf(write)}, "|")
+}
+
+// decodeDeviceActivity is the inverse of encodeDeviceActivity; a malformed entry yields
+// zero timestamps
+func decodeDeviceActivity(encoded string) (time.Time, time.Time, time.Time) {
+	fields := strings.Split(encoded, "|")
+	if len(fields) != 3 {
+		return time.Time{}, time.Time{}, time.Time{}
+	}
+	timeOf := func(field string) time.Time {
+		nanos, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || nanos == 0 {
+			return time.Time{}
+		}
+		return time.Unix(0, nanos)
+	}
+	return timeOf(fields[0]), timeOf(fields[1]), timeOf(fields[2])
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceActivityTimestamps(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Unix(1000, 0))
+	dMgr.clock = fc
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:03")
+
+	// A status report from the adapter moves the adapter timestamp and, through the
+	// resulting store write, the model write timestamp; the NBI one stays untouched
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	adapter, nbi, write := dMgr.activityOf(ctx, device.Id).snapshot()
+	assert.Equal(t, fc.Now(), adapter)
+	assert.Equal(t, fc.Now(), write)
+	assert.True(t, nbi.IsZero())
+
+	// The view metadata carries the ages of the known timestamps only
+	fc.advance(3 * time.Second)
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	meta := dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, "3s", meta[lastAdapterUpdateKey])
+	assert.Equal(t, "3s", meta[lastModelWriteKey])
+	_, stamped := meta[lastNBIOpKey]
+	assert.False(t, stamped)
+	assert.False(t, strings.Contains(view.ExtraArgs, lastAdapterUpdateKey))
+}
+
+func TestQuietDeviceFilterAndOrdering(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Unix(2000, 0))
+	dMgr.clock = fc
+	dMgr.quietDeviceThreshold = time.Minute
+	older := createTestDevice(t, dMgr, "00:00:00:00:4a:04")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, older.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	fc.advance(30 * time.Second)
+	newer := createTestDevice(t, dMgr, "00:00:00:00:4a:05")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, newer.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// The least recently updated device lists first
+	devices, err := dMgr.listDevicesByLeastRecentActivity(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices.Items))
+	assert.Equal(t, older.Id, devices.Items[0].Id)
+	assert.Equal(t, newer.Id, devices.Items[1].Id)
+
+	// Only the device silent past the threshold is flagged; fresh activity clears it
+	fc.advance(45 * time.Second)
+	quiet, err := dMgr.listQuietDevices(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(quiet.Items))
+	assert.Equal(t, older.Id, quiet.Items[0].Id)
+
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, older.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	quiet, err = dMgr.listQuietDevices(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(quiet.Items))
+}
+
+func TestDeviceActivitySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	fc := newFakeClock(time.Unix(3000, 0))
+	dMgr.clock = fc
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:06")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// Flush to the KV store, then drop the in-memory state the way a restart would
+	dMgr.persistDeviceActivities(ctx)
+	dMgr.deviceActivities.Delete(device.Id)
+
+	adapter, nbi, write := dMgr.activityOf(ctx, device.Id).snapshot()
+	assert.Equal(t, fc.Now().UnixNano(), adapter.UnixNano())
+	assert.Equal(t, fc.Now().UnixNano(), write.UnixNano())
+	assert.True(t, nbi.IsZero())
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// When the management address of an OLT changes the only path used to be delete and
+// recreate, losing the children, the flows and the logical device along the way.
+// changeDeviceAddress instead rewrites the host_and_port of a root device in place and
+// asks the adapter to reconcile the device on its new address; nothing below the device
+// is touched.  The new address must actually differ from the current one and must not be
+// claimed by another live root device.  A device that is still ENABLED only accepts the
+// change with the force flag, since re-adopting it disrupts its subscribers.
+
+// changeDeviceAddress moves a root device to a new management address.  The change is
+// rejected while the device is ENABLED unless force is set.
+func (dMgr *DeviceManager) changeDeviceAddress(ctx context.Context, deviceID string, hostAndPort string, force bool) error {
+	ctx = withLogContext(ctx, "change-device-address", deviceID)
+	ctxInfow(ctx, "changeDeviceAddress", log.Fields{"device-id": deviceID, "host-and-port": hostAndPort, "force": force})
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	if !device.Root {
+		return newFailedPreconditionError("device", deviceID, "address-change-only-applies-to-root-devices")
+	}
+	oldAddress := device.GetHostAndPort()
+	if hostAndPort == "" {
+		return newInvalidArgumentError("host-and-port-must-not-be-empty")
+	}
+	if hostAndPort == oldAddress {
+		return newInvalidArgumentError("host-and-port-unchanged")
+	}
+	if inUse, err := dMgr.isAddressInUse(ctx, hostAndPort, deviceID); err != nil {
+		return err
+	} else if inUse {
+		return status.Errorf(codes.AlreadyExists, "host-and-port-%s-already-used-by-another-device", hostAndPort)
+	}
+	if device.AdminState == voltha.AdminState_ENABLED && !force {
+		return newFailedPreconditionError("device", deviceID, "address-change-requires-disabled-state-unless-forced")
+	}
+
+	if err := agent.updateDeviceAddress(ctx, hostAndPort); err != nil {
+		return err
+	}
+	// The address change is part of the operational record of the device
+	go dMgr.eventMgr.publishDeviceEvent(device, deviceAddressChangeEvent, map[string]string{
+		"old-address": oldAddress,
+		"new-address": hostAndPort,
+		"forced":      strconv.FormatBool(force),
+	})
+
+	// A preprovisioned device has never been adopted; the adapter learns the address on enable
+	if device.AdminState == voltha.AdminState_PREPROVISIONED {
+		return nil
+	}
+	// Hand the updated device to the adapter so it re-establishes connectivity on the new
+	// address.  The children, flows and logical device stay as they are.
+	updated, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	response := dMgr.sendReconcileDeviceRequest(ctx, updated)
+	go func() {
+		if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+			ctxWarnw(ctx, "address-change-reconcile-failed", log.Fields{"device-id": deviceID, "errors": res})
+		}
+	}()
+	return nil
+}
+
+// isAddressInUse reports whether another live root device already claims the address.  This
+// is the same duplicate check createDevice applies through isParentDeviceExist, restated for
+// a device that already exists.
+func (dMgr *DeviceManager) isAddressInUse(ctx context.Context, hostAndPort string, excludeDeviceID string) (bool, error) {
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		ctxErrorw(ctx, "failed-to-list-devices-from-cluster-data-proxy", log.Fields{"error": err})
+		return false, err
+	}
+	for _, device := range devices {
+		if !device.Root || device.Id == excludeDeviceID {
+			continue
+		}
+		if hostAndPort == device.GetHostAndPort() && device.AdminState != voltha.AdminState_DELETED {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// updateDeviceAddress rewrites the host_and_port of the device and stores it
+func (agent *DeviceAgent) updateDeviceAddress(ctx context.Context, hostAndPort string) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	cloned := agent.getDeviceWithoutLock()
+	cloned.Address = &voltha.Device_HostAndPort{HostAndPort: hostAndPort}
+	ctxDebugw(ctx, "updateDeviceAddress", log.Fields{"device-id": cloned.Id, "host-and-port": hostAndPort})
+	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+}
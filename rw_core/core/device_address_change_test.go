@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createAddressedOLT preprovisions a root device reachable on the given address
+func createAddressedOLT(t *testing.T, dMgr *DeviceManager, hostAndPort string) *voltha.Device {
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(context.Background(), &voltha.Device{
+		Type:    "olt",
+		Address: &voltha.Device_HostAndPort{HostAndPort: hostAndPort},
+	}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	return device
+}
+
+func TestChangeDeviceAddressValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createAddressedOLT(t, dMgr, "10.0.0.1:830")
+	other := createAddressedOLT(t, dMgr, "10.0.0.9:830")
+
+	// The new address must actually be a change
+	err := dMgr.changeDeviceAddress(ctx, device.Id, "10.0.0.1:830", false)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.changeDeviceAddress(ctx, device.Id, "", false)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// ... and must not be claimed by another live root device
+	err = dMgr.changeDeviceAddress(ctx, device.Id, other.GetHostAndPort(), false)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Unknown devices are reported as such
+	err = dMgr.changeDeviceAddress(ctx, "no-such-device", "10.0.0.2:830", false)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestChangeDeviceAddressBeforeAdoption(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := createAddressedOLT(t, dMgr, "10.0.0.1:830")
+
+	// A preprovisioned device just takes the new address; there is no adapter to tell yet
+	assert.Nil(t, dMgr.changeDeviceAddress(ctx, device.Id, "10.0.0.2:830", false))
+	updated, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2:830", updated.GetHostAndPort())
+	assert.Equal(t, 0, fas.callCount("reconcileDevice"))
+}
+
+func TestChangeDeviceAddressAfterAdoption(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := createAddressedOLT(t, dMgr, "10.0.0.1:830")
+
+	ch := make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// While ENABLED the change is disruptive and needs the force flag
+	err := dMgr.changeDeviceAddress(ctx, device.Id, "10.0.0.2:830", false)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Nil(t, dMgr.changeDeviceAddress(ctx, device.Id, "10.0.0.2:830", true))
+	assert.Equal(t, 1, fas.callCount("reconcileDevice"))
+
+	// Once DISABLED the change goes through without force and still reconciles
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.changeDeviceAddress(ctx, device.Id, "10.0.0.3:830", false))
+	assert.Equal(t, 2, fas.callCount("reconcileDevice"))
+
+	updated, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.3:830", updated.GetHostAndPort())
+	// The rewrite leaves everything else on the device alone
+	assert.Equal(t, voltha.AdminState_DISABLED, updated.AdminState)
+	assert.Equal(t, device.Type, updated.Type)
+}
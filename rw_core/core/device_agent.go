@@ -24,6 +24,7 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
 	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
@@ -41,25 +42,45 @@ import (
 
 // DeviceAgent represents device agent attributes
 type DeviceAgent struct {
-	deviceID         string
-	parentID         string
-	deviceType       string
-	isRootdevice     bool
-	adapterProxy     *AdapterProxy
-	adapterMgr       *AdapterManager
-	deviceMgr        *DeviceManager
-	clusterDataProxy *model.Proxy
-	exitChannel      chan int
-	device           *voltha.Device
-	requestQueue     *coreutils.RequestQueue
-	defaultTimeout   time.Duration
-	startOnce        sync.Once
-	stopOnce         sync.Once
-	stopped          bool
-}
-
-//newDeviceAgent creates a new device agent. The device will be initialized when start() is called.
-func newDeviceAgent(ap *AdapterProxy, device *voltha.Device, deviceMgr *DeviceManager, cdProxy *model.Proxy, timeout time.Duration) *DeviceAgent {
+	deviceID                string
+	parentID                string
+	deviceType              string
+	isRootdevice            bool
+	adapterProxy            adapterService
+	adapterMgr              *AdapterManager
+	deviceMgr               *DeviceManager
+	clusterDataProxy        *model.Proxy
+	exitChannel             chan int
+	device                  *voltha.Device
+	lastKVSync              time.Time
+	imageDownloadTerminalAt map[string]time.Time
+	stateHistory            []*DeviceStateRecord
+	lastHistoryPersist      time.Time
+	lastSnapshot            time.Time
+	parkedFlows             []*ofp.OfpFlowStats
+	parkedGroups            []*ofp.OfpGroupEntry
+	parkedSince             time.Time
+	requestQueue            *coreutils.RequestQueue
+	ownershipCtx            context.Context
+	cancelOwnership         context.CancelFunc
+	flapDamper              *flapDamper
+	activation              *activationTimestamps
+	writeSequencer          deviceWriteSequencer
+	flowUtilizationWarned   bool
+	footprintWarned         bool
+	footprint               agentFootprint
+	flowConfirmations       sync.Map // flow ID -> confirmation status of the last push touching it
+	// deviceTypeCache holds the resolved device type; see device_type_cache.go
+	deviceTypeCache     *voltha.DeviceType
+	deviceTypeCacheLock sync.RWMutex
+	defaultTimeout      time.Duration
+	startOnce           sync.Once
+	stopOnce            sync.Once
+	stopped             bool
+}
+
+// newDeviceAgent creates a new device agent. The device will be initialized when start() is called.
+func newDeviceAgent(ap adapterService, device *voltha.Device, deviceMgr *DeviceManager, cdProxy *model.Proxy, timeout time.Duration) *DeviceAgent {
 	var agent DeviceAgent
 	agent.adapterProxy = ap
 	if device.Id == "" {
@@ -78,6 +99,10 @@ func newDeviceAgent(ap *AdapterProxy, device *voltha.Device, deviceMgr *DeviceMa
 	agent.defaultTimeout = timeout
 	agent.device = proto.Clone(device).(*voltha.Device)
 	agent.requestQueue = coreutils.NewRequestQueue()
+	agent.initOwnershipScope()
+	agent.flapDamper = newFlapDamper(device.Type)
+	agent.activation = newActivationTimestamps()
+	agent.imageDownloadTerminalAt = make(map[string]time.Time)
 	return &agent
 }
 
@@ -111,6 +136,9 @@ func (agent *DeviceAgent) start(ctx context.Context, deviceToCreate *voltha.Devi
 
 		agent.deviceType = device.Adapter
 		agent.device = proto.Clone(device).(*voltha.Device)
+		agent.lastKVSync = time.Now()
+		agent.loadParkedFlows(ctx)
+		agent.restoreFromSnapshot(ctx, device)
 
 		logger.Infow("device-loaded-from-dB", log.Fields{"device-id": agent.deviceID})
 	} else {
@@ -134,9 +162,14 @@ func (agent *DeviceAgent) start(ctx context.Context, deviceToCreate *voltha.Devi
 			return nil, status.Errorf(codes.Aborted, "failed-adding-device-%s: %s", agent.deviceID, err)
 		}
 		agent.device = device
+		agent.lastKVSync = time.Now()
+		agent.deviceMgr.bumpDeviceRevision(agent.deviceID)
 	}
 
 	startSucceeded = true
+	// resolve the device type once so the hot paths answer from the agent's cache
+	agent.loadDeviceType()
+	agent.initFootprint(agent.device)
 	logger.Debugw("device-agent-started", log.Fields{"device-id": agent.deviceID})
 
 	return agent.getDevice(ctx)
@@ -159,6 +192,8 @@ func (agent *DeviceAgent) stop(ctx context.Context) error {
 	if err := agent.clusterDataProxy.Remove(ctx, "devices/"+agent.deviceID); err != nil {
 		return err
 	}
+	agent.deviceMgr.purgeDeviceData(ctx, agent.deviceID)
+	agent.deviceMgr.dropDeviceRevision(agent.deviceID)
 
 	close(agent.exitChannel)
 
@@ -169,6 +204,19 @@ func (agent *DeviceAgent) stop(ctx context.Context) error {
 	return nil
 }
 
+// discard releases the agent's resources after it lost the registration race for its
+// device.  Unlike stop it leaves the KV store alone: the winning agent owns the persisted
+// device, and removing it here would delete it from under the winner.
+func (agent *DeviceAgent) discard() {
+	needToStop := false
+	if agent.stopOnce.Do(func() { needToStop = true }); !needToStop {
+		return
+	}
+	ctxInfow(context.Background(), "discarding-duplicate-device-agent", log.Fields{"device-id": agent.deviceID})
+	close(agent.exitChannel)
+	agent.stopped = true
+}
+
 // Load the most recent state from the KVStore for the device.
 func (agent *DeviceAgent) reconcileWithKVStore(ctx context.Context) {
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
@@ -188,6 +236,7 @@ func (agent *DeviceAgent) reconcileWithKVStore(ctx context.Context) {
 
 	agent.deviceType = device.Adapter
 	agent.device = device
+	agent.lastKVSync = time.Now()
 	logger.Debugw("reconciled-device-agent-devicetype", log.Fields{"device-id": agent.deviceID, "type": agent.deviceType})
 }
 
@@ -215,14 +264,14 @@ func (agent *DeviceAgent) waitForAdapterResponse(ctx context.Context, cancel con
 	select {
 	case rpcResponse, ok := <-ch:
 		if !ok {
-			onFailure(rpc, status.Errorf(codes.Aborted, "channel-closed"), reqArgs)
+			onFailure(rpc, classifyError(originTransportUnavailable, status.Errorf(codes.Aborted, "channel-closed")), reqArgs)
 		} else if rpcResponse.Err != nil {
 			onFailure(rpc, rpcResponse.Err, reqArgs)
 		} else {
 			onSuccess(rpc, rpcResponse.Reply, reqArgs)
 		}
 	case <-ctx.Done():
-		onFailure(rpc, ctx.Err(), reqArgs)
+		onFailure(rpc, classifyError(originTransportTimeout, agent.abortError(ctx.Err())), reqArgs)
 	}
 }
 
@@ -254,7 +303,7 @@ func (agent *DeviceAgent) enableDevice(ctx context.Context) error {
 	// First figure out which adapter will handle this device type.  We do it at this stage as allow devices to be
 	// pre-provisioned with the required adapter not registered.   At this stage, since we need to communicate
 	// with the adapter then we need to know the adapter that will handle this request
-	adapterName, err := agent.adapterMgr.getAdapterName(cloned.Type)
+	adapterName, err := agent.deviceMgr.validateEnableDevicePreconditions(cloned)
 	if err != nil {
 		return err
 	}
@@ -265,13 +314,12 @@ func (agent *DeviceAgent) enableDevice(ctx context.Context) error {
 		return nil
 	}
 
-	if cloned.AdminState == voltha.AdminState_DELETED {
-		// This is a temporary state when a device is deleted before it gets removed from the model.
-		err = status.Error(codes.FailedPrecondition, fmt.Sprintf("cannot-enable-a-deleted-device: %s ", cloned.Id))
-		return err
-	}
+	// Now that the handling adapter is known, assign a dedicated request topic to the device if the
+	// per-device topic policy calls for one
+	agent.deviceMgr.deviceTopicMgr.assignDeviceTopic(ctx, cloned)
 
 	previousAdminState := cloned.AdminState
+	agent.activation.markEnabled()
 
 	// Update the Admin State and set the operational state to activating before sending the request to the
 	// Adapters
@@ -282,7 +330,7 @@ func (agent *DeviceAgent) enableDevice(ctx context.Context) error {
 	// Adopt the device if it was in pre-provision state.  In all other cases, try to re-enable it.
 	device := proto.Clone(cloned).(*voltha.Device)
 	var ch chan *kafka.RpcResponse
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	if previousAdminState == voltha.AdminState_PREPROVISIONED {
 		ch, err = agent.adapterProxy.adoptDevice(subCtx, device)
 	} else {
@@ -309,20 +357,20 @@ func (agent *DeviceAgent) waitForAdapterFlowResponse(ctx context.Context, cancel
 			response.Done()
 		}
 	case <-ctx.Done():
-		response.Error(ctx.Err())
+		response.Error(agent.abortError(ctx.Err()))
 	}
 }
 
-//deleteFlowWithoutPreservingOrder removes a flow specified by index from the flows slice.  This function will
-//panic if the index is out of range.
+// deleteFlowWithoutPreservingOrder removes a flow specified by index from the flows slice.  This function will
+// panic if the index is out of range.
 func deleteFlowWithoutPreservingOrder(flows []*ofp.OfpFlowStats, index int) []*ofp.OfpFlowStats {
 	flows[index] = flows[len(flows)-1]
 	flows[len(flows)-1] = nil
 	return flows[:len(flows)-1]
 }
 
-//deleteGroupWithoutPreservingOrder removes a group specified by index from the groups slice.  This function will
-//panic if the index is out of range.
+// deleteGroupWithoutPreservingOrder removes a group specified by index from the groups slice.  This function will
+// panic if the index is out of range.
 func deleteGroupWithoutPreservingOrder(groups []*ofp.OfpGroupEntry, index int) []*ofp.OfpGroupEntry {
 	groups[index] = groups[len(groups)-1]
 	groups[len(groups)-1] = nil
@@ -372,23 +420,23 @@ func groupsToUpdateToDelete(newGroups, existingGroups []*ofp.OfpGroupEntry) (upd
 	return newGroups, groupsToDelete, updatedAllGroups
 }
 
-func (agent *DeviceAgent) addFlowsAndGroupsToAdapter(ctx context.Context, newFlows []*ofp.OfpFlowStats, newGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, error) {
+func (agent *DeviceAgent) addFlowsAndGroupsToAdapter(ctx context.Context, newFlows []*ofp.OfpFlowStats, newGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, *flowSnapshot, error) {
 	logger.Debugw("add-flows-groups-to-adapters", log.Fields{"device-id": agent.deviceID, "flows": newFlows, "groups": newGroups, "flow-metadata": flowMetadata})
 
 	if (len(newFlows) | len(newGroups)) == 0 {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": newFlows, "groups": newGroups})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
 	}
 
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
-		return coreutils.DoneResponse(), err
+		return coreutils.DoneResponse(), nil, err
 	}
 	defer agent.requestQueue.RequestComplete()
 
 	device := agent.getDeviceWithoutLock()
-	dType := agent.adapterMgr.getDeviceType(device.Type)
+	dType := agent.loadDeviceType()
 	if dType == nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
+		return coreutils.DoneResponse(), nil, status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
 	}
 
 	existingFlows := proto.Clone(device.Flows).(*voltha.Flows)
@@ -403,29 +451,47 @@ func (agent *DeviceAgent) addFlowsAndGroupsToAdapter(ctx context.Context, newFlo
 	// Sanity check
 	if (len(updatedAllFlows) | len(flowsToDelete) | len(updatedAllGroups) | len(groupsToDelete)) == 0 {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": newFlows, "groups": newGroups})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
+	}
+
+	// Optionally reject an install that would exceed the advertised table capacity rather
+	// than forwarding a doomed request to the adapter
+	if agent.deviceMgr.rejectFlowsAtCapacity && agent.adapterMgr.getFlowTableCapacity(device.Type).wouldExceed(len(updatedAllFlows)) {
+		return coreutils.DoneResponse(), nil, newFailedPreconditionError("device", agent.deviceID, "flow-table-full")
 	}
 
-	// store the changed data
+	// Optionally refuse an install that would push the device over its memory cap
+	if agent.footprintWouldExceed(flowsFootprint(newFlows) + groupsFootprint(newGroups)) {
+		return coreutils.DoneResponse(), nil, newFailedPreconditionError("device", agent.deviceID, "memory-footprint-limit")
+	}
+
+	// store the changed data; the pushed flows stay PENDING until the adapter answers
 	device.Flows = &voltha.Flows{Items: updatedAllFlows}
 	device.FlowGroups = &voltha.FlowGroups{Items: updatedAllGroups}
 	if err := agent.updateDeviceWithoutLock(ctx, device); err != nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.Internal, "failure-updating-device-%s", agent.deviceID)
+		return coreutils.DoneResponse(), nil, status.Errorf(codes.Internal, "failure-updating-device-%s", agent.deviceID)
 	}
+	agent.adjustFlowFootprint(newFlows, flowsToDelete)
+	agent.adjustGroupFootprint(newGroups, groupsToDelete)
+	agent.checkFlowTableUtilization(device)
+	agent.checkFootprint(device)
+	snapshot := &flowSnapshot{priorFlows: existingFlows.Items, priorGroups: existingGroups.Items, added: flowIDsOf(newFlows), removed: flowIDsOf(flowsToDelete)}
+	agent.markFlowsPending(snapshot)
 
 	// Send update to adapters
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	response := coreutils.NewResponse()
-	if !dType.AcceptsAddRemoveFlowUpdates {
+	if chooseFlowUpdateStyle(dType, existingFlows.Items, updatedAllFlows, newFlows, flowsToDelete) == flowUpdateBulk {
 		if len(updatedAllGroups) != 0 && reflect.DeepEqual(existingGroups.Items, updatedAllGroups) && len(updatedAllFlows) != 0 && reflect.DeepEqual(existingFlows.Items, updatedAllFlows) {
 			logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": newFlows, "groups": newGroups})
 			cancel()
-			return coreutils.DoneResponse(), nil
+			agent.confirmFlowPush(snapshot)
+			return coreutils.DoneResponse(), nil, nil
 		}
 		rpcResponse, err := agent.adapterProxy.updateFlowsBulk(subCtx, device, &voltha.Flows{Items: updatedAllFlows}, &voltha.FlowGroups{Items: updatedAllGroups}, flowMetadata)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	} else {
@@ -441,56 +507,68 @@ func (agent *DeviceAgent) addFlowsAndGroupsToAdapter(ctx context.Context, newFlo
 		rpcResponse, err := agent.adapterProxy.updateFlowsIncremental(subCtx, device, flowChanges, groupChanges, flowMetadata)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	}
-	return response, nil
+	return response, snapshot, nil
 }
 
-//addFlowsAndGroups adds the "newFlows" and "newGroups" from the existing flows/groups and sends the update to the
-//adapters
+// addFlowsAndGroups adds the "newFlows" and "newGroups" from the existing flows/groups and sends the update to the
+// adapters
 func (agent *DeviceAgent) addFlowsAndGroups(ctx context.Context, newFlows []*ofp.OfpFlowStats, newGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	response, err := agent.addFlowsAndGroupsToAdapter(ctx, newFlows, newGroups, flowMetadata)
+	// Flows arriving mid-activation are held back and replayed once the device is ACTIVE
+	if parked, err := agent.parkFlowsIfActivating(ctx, newFlows, newGroups); err != nil {
+		return err
+	} else if parked {
+		return nil
+	}
+	response, snapshot, err := agent.addFlowsAndGroupsToAdapter(ctx, newFlows, newGroups, flowMetadata)
 	if err != nil {
+		agent.rollbackFlowPush(ctx, snapshot)
 		return err
 	}
 	if errs := coreutils.WaitForNilOrErrorResponses(agent.defaultTimeout, response); errs != nil {
 		logger.Warnw("no-adapter-response", log.Fields{"device-id": agent.deviceID, "result": errs})
-		return status.Errorf(codes.Aborted, "flow-failure-device-%s", agent.deviceID)
+		agent.rollbackFlowPush(ctx, snapshot)
+		return agent.abortError(status.Errorf(codes.Aborted, "flow-failure-device-%s", agent.deviceID))
 	}
+	agent.confirmFlowPush(snapshot)
+	agent.markFirstFlowApplied(ctx)
 	return nil
 }
 
-func (agent *DeviceAgent) deleteFlowsAndGroupsFromAdapter(ctx context.Context, flowsToDel []*ofp.OfpFlowStats, groupsToDel []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, error) {
+func (agent *DeviceAgent) deleteFlowsAndGroupsFromAdapter(ctx context.Context, flowsToDel []*ofp.OfpFlowStats, groupsToDel []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, *flowSnapshot, error) {
 	logger.Debugw("delete-flows-groups-from-adapter", log.Fields{"device-id": agent.deviceID, "flows": flowsToDel, "groups": groupsToDel})
 
 	if (len(flowsToDel) | len(groupsToDel)) == 0 {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": flowsToDel, "groups": groupsToDel})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
 	}
 
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
-		return coreutils.DoneResponse(), err
+		return coreutils.DoneResponse(), nil, err
 	}
 	defer agent.requestQueue.RequestComplete()
 
 	device := agent.getDeviceWithoutLock()
-	dType := agent.adapterMgr.getDeviceType(device.Type)
+	dType := agent.loadDeviceType()
 	if dType == nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
+		return coreutils.DoneResponse(), nil, status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
 	}
 
 	existingFlows := proto.Clone(device.Flows).(*voltha.Flows)
 	existingGroups := proto.Clone(device.FlowGroups).(*ofp.FlowGroups)
 
-	var flowsToKeep []*ofp.OfpFlowStats
-	var groupsToKeep []*ofp.OfpGroupEntry
+	var flowsToKeep, flowsDeleted []*ofp.OfpFlowStats
+	var groupsToKeep, groupsDeleted []*ofp.OfpGroupEntry
 
 	// Process flows
 	for _, flow := range existingFlows.Items {
 		if idx := fu.FindFlows(flowsToDel, flow); idx == -1 {
 			flowsToKeep = append(flowsToKeep, flow)
+		} else {
+			flowsDeleted = append(flowsDeleted, flow)
 		}
 	}
 
@@ -498,6 +576,8 @@ func (agent *DeviceAgent) deleteFlowsAndGroupsFromAdapter(ctx context.Context, f
 	for _, group := range existingGroups.Items {
 		if fu.FindGroup(groupsToDel, group.Desc.GroupId) == -1 { // does not exist now
 			groupsToKeep = append(groupsToKeep, group)
+		} else {
+			groupsDeleted = append(groupsDeleted, group)
 		}
 	}
 
@@ -513,29 +593,36 @@ func (agent *DeviceAgent) deleteFlowsAndGroupsFromAdapter(ctx context.Context, f
 	// Sanity check
 	if (len(flowsToKeep) | len(flowsToDel) | len(groupsToKeep) | len(groupsToDel)) == 0 {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows-to-del": flowsToDel, "groups-to-del": groupsToDel})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
 	}
 
-	// store the changed data
+	// store the changed data; the removals stay PENDING until the adapter answers
 	device.Flows = &voltha.Flows{Items: flowsToKeep}
 	device.FlowGroups = &voltha.FlowGroups{Items: groupsToKeep}
 	if err := agent.updateDeviceWithoutLock(ctx, device); err != nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.Internal, "failure-updating-%s", agent.deviceID)
+		return coreutils.DoneResponse(), nil, status.Errorf(codes.Internal, "failure-updating-%s", agent.deviceID)
 	}
+	agent.adjustFlowFootprint(nil, flowsDeleted)
+	agent.adjustGroupFootprint(nil, groupsDeleted)
+	agent.checkFlowTableUtilization(device)
+	agent.checkFootprint(device)
+	snapshot := &flowSnapshot{priorFlows: existingFlows.Items, priorGroups: existingGroups.Items, removed: flowIDsOf(flowsToDel)}
+	agent.markFlowsPending(snapshot)
 
 	// Send update to adapters
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	response := coreutils.NewResponse()
-	if !dType.AcceptsAddRemoveFlowUpdates {
+	if chooseFlowUpdateStyle(dType, existingFlows.Items, flowsToKeep, nil, flowsToDel) == flowUpdateBulk {
 		if len(groupsToKeep) != 0 && reflect.DeepEqual(existingGroups.Items, groupsToKeep) && len(flowsToKeep) != 0 && reflect.DeepEqual(existingFlows.Items, flowsToKeep) {
 			logger.Debugw("nothing-to-update", log.Fields{"deviceId": agent.deviceID, "flowsToDel": flowsToDel, "groupsToDel": groupsToDel})
 			cancel()
-			return coreutils.DoneResponse(), nil
+			agent.confirmFlowPush(snapshot)
+			return coreutils.DoneResponse(), nil, nil
 		}
 		rpcResponse, err := agent.adapterProxy.updateFlowsBulk(subCtx, device, &voltha.Flows{Items: flowsToKeep}, &voltha.FlowGroups{Items: groupsToKeep}, flowMetadata)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	} else {
@@ -551,46 +638,48 @@ func (agent *DeviceAgent) deleteFlowsAndGroupsFromAdapter(ctx context.Context, f
 		rpcResponse, err := agent.adapterProxy.updateFlowsIncremental(subCtx, device, flowChanges, groupChanges, flowMetadata)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	}
-	return response, nil
+	return response, snapshot, nil
 }
 
-//deleteFlowsAndGroups removes the "flowsToDel" and "groupsToDel" from the existing flows/groups and sends the update to the
-//adapters
+// deleteFlowsAndGroups removes the "flowsToDel" and "groupsToDel" from the existing flows/groups and sends the update to the
+// adapters
 func (agent *DeviceAgent) deleteFlowsAndGroups(ctx context.Context, flowsToDel []*ofp.OfpFlowStats, groupsToDel []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	response, err := agent.deleteFlowsAndGroupsFromAdapter(ctx, flowsToDel, groupsToDel, flowMetadata)
+	response, snapshot, err := agent.deleteFlowsAndGroupsFromAdapter(ctx, flowsToDel, groupsToDel, flowMetadata)
 	if err != nil {
+		agent.rollbackFlowPush(ctx, snapshot)
 		return err
 	}
 	if res := coreutils.WaitForNilOrErrorResponses(agent.defaultTimeout, response); res != nil {
-		return status.Errorf(codes.Aborted, "errors-%s", res)
+		agent.rollbackFlowPush(ctx, snapshot)
+		return agent.abortError(status.Errorf(codes.Aborted, "errors-%s", res))
 	}
+	agent.confirmFlowPush(snapshot)
 	return nil
 }
 
-func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, updatedFlows []*ofp.OfpFlowStats, updatedGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, error) {
+func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, updatedFlows []*ofp.OfpFlowStats, updatedGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) (coreutils.Response, *flowSnapshot, error) {
 	logger.Debugw("updateFlowsAndGroups", log.Fields{"device-id": agent.deviceID, "flows": updatedFlows, "groups": updatedGroups})
 
 	if (len(updatedFlows) | len(updatedGroups)) == 0 {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": updatedFlows, "groups": updatedGroups})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
 	}
 
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
-		return coreutils.DoneResponse(), err
+		return coreutils.DoneResponse(), nil, err
 	}
 	defer agent.requestQueue.RequestComplete()
 
 	device := agent.getDeviceWithoutLock()
-	if device.OperStatus != voltha.OperStatus_ACTIVE || device.ConnectStatus != voltha.ConnectStatus_REACHABLE || device.AdminState != voltha.AdminState_ENABLED {
-		return coreutils.DoneResponse(), status.Errorf(codes.FailedPrecondition, "invalid device states")
-	}
-	dType := agent.adapterMgr.getDeviceType(device.Type)
-	if dType == nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
+	// Flow validation has already happened at the device manager level; only the
+	// state and device-type preconditions need re-checking here
+	dType, err := agent.deviceMgr.validateFlowUpdatePreconditions(device, nil)
+	if err != nil {
+		return coreutils.DoneResponse(), nil, err
 	}
 
 	existingFlows := proto.Clone(device.Flows).(*voltha.Flows)
@@ -598,7 +687,7 @@ func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, upd
 
 	if len(updatedGroups) != 0 && reflect.DeepEqual(existingGroups.Items, updatedGroups) && len(updatedFlows) != 0 && reflect.DeepEqual(existingFlows.Items, updatedFlows) {
 		logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": updatedFlows, "groups": updatedGroups})
-		return coreutils.DoneResponse(), nil
+		return coreutils.DoneResponse(), nil, nil
 	}
 
 	logger.Debugw("updating-flows-and-groups",
@@ -608,53 +697,68 @@ func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, upd
 			"updated-groups": updatedGroups,
 		})
 
-	// store the updated data
+	// Optionally reject a replacement table that exceeds the advertised capacity
+	if agent.deviceMgr.rejectFlowsAtCapacity && agent.adapterMgr.getFlowTableCapacity(device.Type).wouldExceed(len(updatedFlows)) {
+		return coreutils.DoneResponse(), nil, newFailedPreconditionError("device", agent.deviceID, "flow-table-full")
+	}
+
+	// store the updated data; the replacement table stays PENDING until the adapter answers
 	device.Flows = &voltha.Flows{Items: updatedFlows}
 	device.FlowGroups = &voltha.FlowGroups{Items: updatedGroups}
 	if err := agent.updateDeviceWithoutLock(ctx, device); err != nil {
-		return coreutils.DoneResponse(), status.Errorf(codes.Internal, "failure-updating-%s", agent.deviceID)
+		return coreutils.DoneResponse(), nil, status.Errorf(codes.Internal, "failure-updating-%s", agent.deviceID)
+	}
+	// A table replacement re-seeds the accounting; the cost is proportional to the
+	// replacement itself
+	agent.setFlowsFootprint(updatedFlows)
+	agent.setGroupsFootprint(updatedGroups)
+	agent.checkFlowTableUtilization(device)
+	agent.checkFootprint(device)
+
+	// Compute the incremental delta up front - the negotiated update style depends on it
+	var flowsToAdd []*ofp.OfpFlowStats
+	var flowsToDelete []*ofp.OfpFlowStats
+	var groupsToAdd []*ofp.OfpGroupEntry
+	var groupsToDelete []*ofp.OfpGroupEntry
+
+	// Process flows
+	for _, flow := range updatedFlows {
+		if idx := fu.FindFlows(existingFlows.Items, flow); idx == -1 {
+			flowsToAdd = append(flowsToAdd, flow)
+		}
+	}
+	for _, flow := range existingFlows.Items {
+		if idx := fu.FindFlows(updatedFlows, flow); idx != -1 {
+			flowsToDelete = append(flowsToDelete, flow)
+		}
 	}
 
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	// Process groups
+	for _, g := range updatedGroups {
+		if fu.FindGroup(existingGroups.Items, g.Desc.GroupId) == -1 { // does not exist now
+			groupsToAdd = append(groupsToAdd, g)
+		}
+	}
+	for _, group := range existingGroups.Items {
+		if fu.FindGroup(updatedGroups, group.Desc.GroupId) != -1 { // does not exist now
+			groupsToDelete = append(groupsToDelete, group)
+		}
+	}
+
+	snapshot := &flowSnapshot{priorFlows: existingFlows.Items, priorGroups: existingGroups.Items, added: flowIDsOf(flowsToAdd), removed: flowIDsOf(flowsToDelete)}
+	agent.markFlowsPending(snapshot)
+
+	subCtx, cancel := agent.adapterRequestContext()
 	response := coreutils.NewResponse()
 	// Process bulk flow update differently than incremental update
-	if !dType.AcceptsAddRemoveFlowUpdates {
+	if chooseFlowUpdateStyle(dType, existingFlows.Items, updatedFlows, flowsToAdd, flowsToDelete) == flowUpdateBulk {
 		rpcResponse, err := agent.adapterProxy.updateFlowsBulk(subCtx, device, &voltha.Flows{Items: updatedFlows}, &voltha.FlowGroups{Items: updatedGroups}, nil)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	} else {
-		var flowsToAdd []*ofp.OfpFlowStats
-		var flowsToDelete []*ofp.OfpFlowStats
-		var groupsToAdd []*ofp.OfpGroupEntry
-		var groupsToDelete []*ofp.OfpGroupEntry
-
-		// Process flows
-		for _, flow := range updatedFlows {
-			if idx := fu.FindFlows(existingFlows.Items, flow); idx == -1 {
-				flowsToAdd = append(flowsToAdd, flow)
-			}
-		}
-		for _, flow := range existingFlows.Items {
-			if idx := fu.FindFlows(updatedFlows, flow); idx != -1 {
-				flowsToDelete = append(flowsToDelete, flow)
-			}
-		}
-
-		// Process groups
-		for _, g := range updatedGroups {
-			if fu.FindGroup(existingGroups.Items, g.Desc.GroupId) == -1 { // does not exist now
-				groupsToAdd = append(groupsToAdd, g)
-			}
-		}
-		for _, group := range existingGroups.Items {
-			if fu.FindGroup(updatedGroups, group.Desc.GroupId) != -1 { // does not exist now
-				groupsToDelete = append(groupsToDelete, group)
-			}
-		}
-
 		logger.Debugw("updating-flows-and-groups",
 			log.Fields{
 				"device-id":        agent.deviceID,
@@ -668,7 +772,8 @@ func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, upd
 		if (len(flowsToAdd) | len(flowsToDelete) | len(groupsToAdd) | len(groupsToDelete) | len(updatedGroups)) == 0 {
 			logger.Debugw("nothing-to-update", log.Fields{"device-id": agent.deviceID, "flows": updatedFlows, "groups": updatedGroups})
 			cancel()
-			return coreutils.DoneResponse(), nil
+			agent.confirmFlowPush(snapshot)
+			return coreutils.DoneResponse(), nil, nil
 		}
 
 		flowChanges := &ofp.FlowChanges{
@@ -683,28 +788,69 @@ func (agent *DeviceAgent) updateFlowsAndGroupsToAdapter(ctx context.Context, upd
 		rpcResponse, err := agent.adapterProxy.updateFlowsIncremental(subCtx, device, flowChanges, groupChanges, flowMetadata)
 		if err != nil {
 			cancel()
-			return coreutils.DoneResponse(), err
+			return coreutils.DoneResponse(), snapshot, err
 		}
 		go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	}
 
-	return response, nil
+	return response, snapshot, nil
 }
 
-//updateFlowsAndGroups replaces the existing flows and groups with "updatedFlows" and "updatedGroups" respectively. It
-//also sends the updates to the adapters
+// updateFlowsAndGroups replaces the existing flows and groups with "updatedFlows" and "updatedGroups" respectively. It
+// also sends the updates to the adapters
 func (agent *DeviceAgent) updateFlowsAndGroups(ctx context.Context, updatedFlows []*ofp.OfpFlowStats, updatedGroups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	response, err := agent.updateFlowsAndGroupsToAdapter(ctx, updatedFlows, updatedGroups, flowMetadata)
+	response, snapshot, err := agent.updateFlowsAndGroupsToAdapter(ctx, updatedFlows, updatedGroups, flowMetadata)
+	if err != nil {
+		agent.rollbackFlowPush(ctx, snapshot)
+		return err
+	}
+	if res := coreutils.WaitForNilOrErrorResponses(agent.defaultTimeout, response); res != nil {
+		agent.rollbackFlowPush(ctx, snapshot)
+		return agent.abortError(status.Errorf(codes.Aborted, "errors-%s", res))
+	}
+	agent.confirmFlowPush(snapshot)
+	return nil
+}
+
+// deleteAllFlows deletes all flows in the device table
+// restoreFlowsAndGroups re-sends the flows and groups persisted on the device to the adapter.
+// Unlike updateFlowsAndGroups it pushes the data even though the model already holds it, as the
+// adapter lost the actual state while the device was disabled.
+func (agent *DeviceAgent) restoreFlowsAndGroups(ctx context.Context) error {
+	logger.Debugw("restoreFlowsAndGroups", log.Fields{"deviceId": agent.deviceID})
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	device := agent.getDeviceWithoutLock()
+	agent.requestQueue.RequestComplete()
+
+	var flows []*ofp.OfpFlowStats
+	var groups []*ofp.OfpGroupEntry
+	if device.Flows != nil {
+		flows = device.Flows.Items
+	}
+	if device.FlowGroups != nil {
+		groups = device.FlowGroups.Items
+	}
+	if (len(flows) | len(groups)) == 0 {
+		logger.Debugw("nothing-to-restore", log.Fields{"deviceId": agent.deviceID})
+		return nil
+	}
+
+	subCtx, cancel := agent.adapterRequestContext()
+	response := coreutils.NewResponse()
+	rpcResponse, err := agent.adapterProxy.updateFlowsBulk(subCtx, device, &voltha.Flows{Items: flows}, &voltha.FlowGroups{Items: groups}, nil)
 	if err != nil {
+		cancel()
 		return err
 	}
+	go agent.waitForAdapterFlowResponse(subCtx, cancel, rpcResponse, response)
 	if res := coreutils.WaitForNilOrErrorResponses(agent.defaultTimeout, response); res != nil {
-		return status.Errorf(codes.Aborted, "errors-%s", res)
+		return agent.abortError(status.Errorf(codes.Aborted, "errors-%s", res))
 	}
 	return nil
 }
 
-//deleteAllFlows deletes all flows in the device table
 func (agent *DeviceAgent) deleteAllFlows(ctx context.Context) error {
 	logger.Debugw("deleteAllFlows", log.Fields{"deviceId": agent.deviceID})
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
@@ -722,7 +868,7 @@ func (agent *DeviceAgent) deleteAllFlows(ctx context.Context) error {
 	return nil
 }
 
-//disableDevice disable a device
+// disableDevice disable a device
 func (agent *DeviceAgent) disableDevice(ctx context.Context) error {
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
 		return err
@@ -736,9 +882,8 @@ func (agent *DeviceAgent) disableDevice(ctx context.Context) error {
 		logger.Debugw("device-already-disabled", log.Fields{"id": agent.deviceID})
 		return nil
 	}
-	if cloned.AdminState == voltha.AdminState_PREPROVISIONED ||
-		cloned.AdminState == voltha.AdminState_DELETED {
-		return status.Errorf(codes.FailedPrecondition, "deviceId:%s, invalid-admin-state:%s", agent.deviceID, cloned.AdminState)
+	if err := agent.deviceMgr.validateDisableDevicePreconditions(cloned); err != nil {
+		return err
 	}
 
 	// Update the Admin State and operational state before sending the request out
@@ -746,7 +891,7 @@ func (agent *DeviceAgent) disableDevice(ctx context.Context) error {
 		return err
 	}
 
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.disableDevice(subCtx, proto.Clone(cloned).(*voltha.Device))
 	if err != nil {
 		cancel()
@@ -765,7 +910,7 @@ func (agent *DeviceAgent) rebootDevice(ctx context.Context) error {
 	logger.Debugw("rebootDevice", log.Fields{"device-id": agent.deviceID})
 
 	device := agent.getDeviceWithoutLock()
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.rebootDevice(subCtx, device)
 	if err != nil {
 		cancel()
@@ -786,6 +931,16 @@ func (agent *DeviceAgent) deleteDevice(ctx context.Context) error {
 
 	previousState := cloned.AdminState
 
+	// The marker makes the delete resumable if this core dies mid-way; it is removed
+	// once the cleanup chain completes.  See deletion_resume.go.
+	agent.deviceMgr.markDeletionIntent(ctx, cloned)
+
+	// Ask the adapter to remove the flows from the hardware before the model forgets
+	// them; a forced deletion skips the barrier by design
+	if !deletionForced(ctx) {
+		agent.runDeletionBarrier(ctx, cloned)
+	}
+
 	// No check is required when deleting a device.  Changing the state to DELETE will trigger the removal of this
 	// device by the state machine
 	if err := agent.updateDeviceStateInStoreWithoutLock(ctx, cloned, voltha.AdminState_DELETED, cloned.ConnectStatus, cloned.OperStatus); err != nil {
@@ -795,7 +950,7 @@ func (agent *DeviceAgent) deleteDevice(ctx context.Context) error {
 	// If the device was in pre-prov state (only parent device are in that state) then do not send the request to the
 	// adapter
 	if previousState != ic.AdminState_PREPROVISIONED {
-		subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+		subCtx, cancel := agent.adapterRequestContext()
 		ch, err := agent.adapterProxy.deleteDevice(subCtx, cloned)
 		if err != nil {
 			cancel()
@@ -838,7 +993,7 @@ func (agent *DeviceAgent) updatePmConfigs(ctx context.Context, pmConfigs *voltha
 		return err
 	}
 	// Send the request to the adapter
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.updatePmConfigs(subCtx, cloned, pmConfigs)
 	if err != nil {
 		cancel()
@@ -881,41 +1036,30 @@ func (agent *DeviceAgent) downloadImage(ctx context.Context, img *voltha.ImageDo
 
 	device := agent.getDeviceWithoutLock()
 
+	if err := agent.deviceMgr.validateImageDownloadPreconditions(device); err != nil {
+		return nil, err
+	}
 	if device.AdminState != voltha.AdminState_ENABLED {
-		return nil, status.Errorf(codes.FailedPrecondition, "device-id:%s, expected-admin-state:%s", agent.deviceID, voltha.AdminState_ENABLED)
+		logger.Debugw("device-not-enabled", log.Fields{"id": agent.deviceID})
+		return nil, status.Errorf(codes.FailedPrecondition, "deviceId:%s, expected-admin-state:%s", agent.deviceID, voltha.AdminState_ENABLED)
 	}
 	// Save the image
 	clonedImg := proto.Clone(img).(*voltha.ImageDownload)
 	clonedImg.DownloadState = voltha.ImageDownload_DOWNLOAD_REQUESTED
 	cloned := proto.Clone(device).(*voltha.Device)
-	if cloned.ImageDownloads == nil {
-		cloned.ImageDownloads = []*voltha.ImageDownload{clonedImg}
-	} else {
-		if device.AdminState != voltha.AdminState_ENABLED {
-			logger.Debugw("device-not-enabled", log.Fields{"id": agent.deviceID})
-			return nil, status.Errorf(codes.FailedPrecondition, "deviceId:%s, expected-admin-state:%s", agent.deviceID, voltha.AdminState_ENABLED)
-		}
-		// Save the image
-		clonedImg := proto.Clone(img).(*voltha.ImageDownload)
-		clonedImg.DownloadState = voltha.ImageDownload_DOWNLOAD_REQUESTED
-		if device.ImageDownloads == nil {
-			device.ImageDownloads = []*voltha.ImageDownload{clonedImg}
-		} else {
-			device.ImageDownloads = append(device.ImageDownloads, clonedImg)
-		}
-		if err := agent.updateDeviceStateInStoreWithoutLock(ctx, cloned, voltha.AdminState_DOWNLOADING_IMAGE, device.ConnectStatus, device.OperStatus); err != nil {
-			return nil, err
-		}
+	cloned.ImageDownloads = append(cloned.ImageDownloads, clonedImg)
+	if err := agent.updateDeviceStateInStoreWithoutLock(ctx, cloned, voltha.AdminState_DOWNLOADING_IMAGE, device.ConnectStatus, device.OperStatus); err != nil {
+		return nil, err
+	}
 
-		// Send the request to the adapter
-		subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
-		ch, err := agent.adapterProxy.downloadImage(ctx, cloned, clonedImg)
-		if err != nil {
-			cancel()
-			return nil, err
-		}
-		go agent.waitForAdapterResponse(subCtx, cancel, "downloadImage", ch, agent.onSuccess, agent.onFailure)
+	// Send the request to the adapter
+	subCtx, cancel := agent.adapterRequestContext()
+	ch, err := agent.adapterProxy.downloadImage(ctx, cloned, clonedImg)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
+	go agent.waitForAdapterResponse(subCtx, cancel, "downloadImage", ch, agent.onSuccess, agent.onFailure)
 	return &voltha.OperationResp{Code: voltha.OperationResp_OPERATION_SUCCESS}, nil
 }
 
@@ -956,7 +1100,7 @@ func (agent *DeviceAgent) cancelImageDownload(ctx context.Context, img *voltha.I
 		if err := agent.updateDeviceStateInStoreWithoutLock(ctx, device, voltha.AdminState_ENABLED, device.ConnectStatus, device.OperStatus); err != nil {
 			return nil, err
 		}
-		subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+		subCtx, cancel := agent.adapterRequestContext()
 		ch, err := agent.adapterProxy.cancelImageDownload(subCtx, device, img)
 		if err != nil {
 			cancel()
@@ -983,6 +1127,12 @@ func (agent *DeviceAgent) activateImage(ctx context.Context, img *voltha.ImageDo
 	if cloned.AdminState == voltha.AdminState_DOWNLOADING_IMAGE {
 		return nil, status.Errorf(codes.FailedPrecondition, "device-id:%s, device-in-downloading-state:%s", agent.deviceID, img.Name)
 	}
+	// A failed download - a checksum mismatch included - must never be activated
+	for _, image := range cloned.ImageDownloads {
+		if image.Id == img.Id && image.Name == img.Name && image.DownloadState == voltha.ImageDownload_DOWNLOAD_FAILED {
+			return nil, status.Errorf(codes.FailedPrecondition, "device-id:%s, image-download-failed:%s", agent.deviceID, img.Name)
+		}
+	}
 	// Update image download state
 	for _, image := range cloned.ImageDownloads {
 		if image.Id == img.Id && image.Name == img.Name {
@@ -994,7 +1144,7 @@ func (agent *DeviceAgent) activateImage(ctx context.Context, img *voltha.ImageDo
 		return nil, err
 	}
 
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.activateImageUpdate(subCtx, proto.Clone(cloned).(*voltha.Device), img)
 	if err != nil {
 		cancel()
@@ -1035,7 +1185,7 @@ func (agent *DeviceAgent) revertImage(ctx context.Context, img *voltha.ImageDown
 		return nil, err
 	}
 
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.revertImageUpdate(subCtx, proto.Clone(cloned).(*voltha.Device), img)
 	if err != nil {
 		cancel()
@@ -1084,10 +1234,13 @@ func (agent *DeviceAgent) updateImageDownload(ctx context.Context, img *voltha.I
 	cloned := agent.getDeviceWithoutLock()
 
 	// Update the image as well as remove it if the download was cancelled
-	clonedImages := make([]*voltha.ImageDownload, len(cloned.ImageDownloads))
+	clonedImages := make([]*voltha.ImageDownload, 0, len(cloned.ImageDownloads))
 	for _, image := range cloned.ImageDownloads {
 		if image.Id == img.Id && image.Name == img.Name {
 			if image.DownloadState != voltha.ImageDownload_DOWNLOAD_CANCELLED {
+				// A successful report only stands if the checksum the adapter computed
+				// matches the one the download was requested with
+				agent.verifyReportedImageChecksum(ctx, cloned, image, img)
 				clonedImages = append(clonedImages, img)
 			}
 		}
@@ -1224,7 +1377,7 @@ func (agent *DeviceAgent) packetOut(ctx context.Context, outPort uint32, packet
 		agent.reconcileWithKVStore(ctx)
 	}
 	//	Send packet to adapter
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.packetOut(subCtx, agent.deviceType, agent.deviceID, outPort, packet)
 	if err != nil {
 		cancel()
@@ -1255,6 +1408,9 @@ func (agent *DeviceAgent) updateDeviceUsingAdapterData(ctx context.Context, devi
 	defer agent.requestQueue.RequestComplete()
 	logger.Debugw("updateDeviceUsingAdapterData", log.Fields{"device-id": device.Id})
 
+	if err := validateReadOnlyDeviceFields(agent.getDeviceWithoutLock(), device); err != nil {
+		return err
+	}
 	updatedDevice, err := agent.mergeDeviceInfoFromAdapter(device)
 	if err != nil {
 		return status.Errorf(codes.Internal, "%s", err.Error())
@@ -1271,12 +1427,16 @@ func (agent *DeviceAgent) updateDeviceWithoutLock(ctx context.Context, device *v
 }
 
 func (agent *DeviceAgent) updateDeviceStatus(ctx context.Context, operStatus voltha.OperStatus_Types, connStatus voltha.ConnectStatus_Types) error {
+	// status reports come from the adapter unless a transition handler relays them
+	ctx = withStateSource(ctx, stateSourceOf(ctx, stateSourceAdapter))
 	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
 		return err
 	}
 	defer agent.requestQueue.RequestComplete()
 
-	cloned := agent.getDeviceWithoutLock()
+	// Only top-level scalars change on this path; a shallow copy avoids cloning the
+	// flow table of a busy device on every status report
+	cloned := shallowCopyDevice(agent.device)
 
 	newConnStatus, newOperStatus := cloned.ConnectStatus, cloned.OperStatus
 	// Ensure the enums passed in are valid - they will be invalid if they are not set when this function is invoked
@@ -1289,8 +1449,51 @@ func (agent *DeviceAgent) updateDeviceStatus(ctx context.Context, operStatus vol
 		newOperStatus = operStatus
 	}
 	logger.Debugw("updateDeviceStatus", log.Fields{"deviceId": cloned.Id, "operStatus": cloned.OperStatus, "connectStatus": cloned.ConnectStatus})
+
+	statusChanged := newConnStatus != cloned.ConnectStatus || newOperStatus != cloned.OperStatus
+	now := agent.deviceMgr.now()
+	if agent.flapDamper.dampened {
+		if !agent.flapDamper.isStable(now) {
+			// Coalesce the update; only the most recent statuses matter once dampening lifts
+			if statusChanged {
+				agent.flapDamper.recordTransition(now)
+			}
+			agent.flapDamper.coalesce(newConnStatus, newOperStatus)
+			logger.Infow("device-status-update-dampened", log.Fields{"deviceId": cloned.Id, "operStatus": newOperStatus, "connectStatus": newConnStatus})
+			return nil
+		}
+		// The device has been quiet for the stability period; lift dampening and apply this
+		// update, which supersedes anything coalesced while dampened
+		agent.flapDamper.reset()
+		cloned.Reason = ""
+		logger.Infow("device-flap-dampening-lifted", log.Fields{"deviceId": cloned.Id})
+	}
+	if statusChanged && agent.flapDamper.recordTransition(now) {
+		cloned.Reason = flapDampenedReason
+		logger.Warnw("device-flapping-detected", log.Fields{"deviceId": cloned.Id, "transitions": agent.flapDamper.thresholds.maxTransitions, "window": agent.flapDamper.thresholds.window})
+		go agent.deviceMgr.eventMgr.publishDeviceEvent(cloned, deviceFlappingEvent, map[string]string{
+			"transitions": strconv.Itoa(agent.flapDamper.thresholds.maxTransitions),
+			"window":      agent.flapDamper.thresholds.window.String(),
+		})
+	}
+
+	if newOperStatus == voltha.OperStatus_ACTIVE && cloned.OperStatus != voltha.OperStatus_ACTIVE {
+		agent.recordActivationActive(ctx, cloned)
+		go agent.replayParkedFlows(context.Background())
+	}
+	if newOperStatus == voltha.OperStatus_FAILED {
+		agent.dropParkedFlows(ctx)
+	}
+
 	// Store the device
-	return agent.updateDeviceStateInStoreWithoutLock(ctx, cloned, cloned.AdminState, newConnStatus, newOperStatus)
+	previousConnStatus := cloned.ConnectStatus
+	if err := agent.updateDeviceStateInStoreWithoutLock(ctx, cloned, cloned.AdminState, newConnStatus, newOperStatus); err != nil {
+		return err
+	}
+	if newConnStatus == voltha.ConnectStatus_REACHABLE && previousConnStatus != voltha.ConnectStatus_REACHABLE {
+		go agent.deviceMgr.replayParkedRequests(context.Background(), agent.deviceID)
+	}
+	return nil
 }
 
 func (agent *DeviceAgent) updatePortsOperState(ctx context.Context, operStatus voltha.OperStatus_Types) error {
@@ -1299,35 +1502,18 @@ func (agent *DeviceAgent) updatePortsOperState(ctx context.Context, operStatus v
 		return err
 	}
 	defer agent.requestQueue.RequestComplete()
-	cloned := agent.getDeviceWithoutLock()
+	// Only the ports change; copy them and share the rest of the device
+	cloned := shallowCopyDevice(agent.device)
+	copyPortsOf(cloned)
 	for _, port := range cloned.Ports {
 		port.OperStatus = operStatus
 	}
 	// Store the device
-	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+	return agent.storeDeviceOwnedWithoutLock(ctx, cloned)
 }
 
 func (agent *DeviceAgent) updatePortState(ctx context.Context, portType voltha.Port_PortType, portNo uint32, operStatus voltha.OperStatus_Types) error {
-	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
-		return err
-	}
-	defer agent.requestQueue.RequestComplete()
-	// Work only on latest data
-	// TODO: Get list of ports from device directly instead of the entire device
-	cloned := agent.getDeviceWithoutLock()
-
-	// Ensure the enums passed in are valid - they will be invalid if they are not set when this function is invoked
-	if _, ok := voltha.Port_PortType_value[portType.String()]; !ok {
-		return status.Errorf(codes.InvalidArgument, "%s", portType)
-	}
-	for _, port := range cloned.Ports {
-		if port.Type == portType && port.PortNo == portNo {
-			port.OperStatus = operStatus
-		}
-	}
-	logger.Debugw("portStatusUpdate", log.Fields{"deviceId": cloned.Id})
-	// Store the device
-	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+	return agent.updatePortStates(ctx, []portStateChange{{portType: portType, portNo: portNo, operStatus: operStatus}})
 }
 
 func (agent *DeviceAgent) deleteAllPorts(ctx context.Context) error {
@@ -1370,7 +1556,10 @@ func (agent *DeviceAgent) addPort(ctx context.Context, port *voltha.Port) error
 		cloned.Ports = make([]*voltha.Port, 0)
 	} else {
 		for _, p := range cloned.Ports {
-			if p.Type == port.Type && p.PortNo == port.PortNo {
+			if p.PortNo != port.PortNo {
+				continue
+			}
+			if p.Type == port.Type {
 				if p.Label == "" && p.Type == voltha.Port_PON_OLT {
 					//Creation of OLT PON port is being processed after a default PON port was created.  Just update it.
 					logger.Infow("update-pon-port-created-by-default", log.Fields{"default-port": p, "port-to-add": port})
@@ -1379,9 +1568,14 @@ func (agent *DeviceAgent) addPort(ctx context.Context, port *voltha.Port) error
 					updatePort = true
 					break
 				}
-				logger.Debugw("port already exists", log.Fields{"port": port})
-				return nil
+				if !conflictingPortIdentity(p, port) {
+					logger.Debugw("port already exists", log.Fields{"port": port})
+					return nil
+				}
 			}
+			// The same port number announced with a different type or identity is an
+			// adapter bug; keeping both would make the port lookups unpredictable
+			return agent.rejectPortCollision(ctx, cloned, p, port)
 		}
 	}
 	if !updatePort {
@@ -1479,7 +1673,7 @@ func (agent *DeviceAgent) simulateAlarm(ctx context.Context, simulatereq *voltha
 
 	cloned := agent.getDeviceWithoutLock()
 
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.simulateAlarm(subCtx, cloned, simulatereq)
 	if err != nil {
 		cancel()
@@ -1499,34 +1693,39 @@ func (agent *DeviceAgent) updateDeviceStateInStoreWithoutLock(
 	previousState := getDeviceStates(device)
 	device.AdminState, device.ConnectStatus, device.OperStatus = adminState, connectStatus, operStatus
 
-	if err := agent.updateDeviceInStoreWithoutLock(ctx, device, false, ""); err != nil {
+	// Every caller hands over a copy it no longer mutates, so it can be cached directly
+	if err := agent.storeDeviceOwnedWithoutLock(ctx, device); err != nil {
 		return err
 	}
 
+	if *previousState != (DeviceState{Admin: adminState, Connection: connectStatus, Operational: operStatus}) {
+		agent.appendStateRecord(ctx, device, stateSourceOf(ctx, stateSourceNBI))
+	}
+
 	// process state transition in its own thread
 	go func() {
 		if err := agent.deviceMgr.processTransition(context.Background(), device, previousState); err != nil {
 			log.Errorw("failed-process-transition", log.Fields{"deviceId": device.Id, "previousAdminState": previousState.Admin, "currentAdminState": device.AdminState})
 		}
 	}()
+
+	// publish the state change on the event stream
+	go agent.deviceMgr.eventMgr.publishDeviceStateChangeEvent(device, previousState)
 	return nil
 }
 
-//This is an update operation to model without Lock.This function must never be invoked by another function unless the latter holds a lock on the device.
+// This is an update operation to model without Lock.This function must never be invoked by another function unless the latter holds a lock on the device.
 // It is an internal helper function.
 func (agent *DeviceAgent) updateDeviceInStoreWithoutLock(ctx context.Context, device *voltha.Device, strict bool, txid string) error {
 	if agent.stopped {
 		return errors.New("device agent stopped")
 	}
 
-	updateCtx := context.WithValue(ctx, model.RequestTimestamp, time.Now().UnixNano())
-	if err := agent.clusterDataProxy.Update(updateCtx, "devices/"+agent.deviceID, device); err != nil {
-		return status.Errorf(codes.Internal, "failed-update-device:%s: %s", agent.deviceID, err)
-	}
-	logger.Debugw("updated-device-in-store", log.Fields{"deviceId: ": agent.deviceID})
+	// Port lists are small; re-seeding their accounting on every write is cheap
+	agent.setPortsFootprint(device.Ports)
 
-	agent.device = proto.Clone(device).(*voltha.Device)
-	return nil
+	// The caller may keep using its copy afterwards, so a clone becomes the cached device
+	return agent.storeDeviceOwnedWithoutLock(ctx, proto.Clone(device).(*voltha.Device))
 }
 
 func (agent *DeviceAgent) updateDeviceReason(ctx context.Context, reason string) error {
@@ -1573,7 +1772,7 @@ func (agent *DeviceAgent) disablePort(ctx context.Context, Port *voltha.Port) er
 	}
 
 	//send request to adapter
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.disablePort(ctx, device, cp)
 	if err != nil {
 		cancel()
@@ -1614,7 +1813,7 @@ func (agent *DeviceAgent) enablePort(ctx context.Context, Port *voltha.Port) err
 		return err
 	}
 	//send request to adapter
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.enablePort(ctx, device, cp)
 	if err != nil {
 		cancel()
@@ -1649,7 +1848,7 @@ func (agent *DeviceAgent) ChildDeviceLost(ctx context.Context, device *voltha.De
 	}
 
 	//send request to adapter
-	subCtx, cancel := context.WithTimeout(context.Background(), agent.defaultTimeout)
+	subCtx, cancel := agent.adapterRequestContext()
 	ch, err := agent.adapterProxy.childDeviceLost(ctx, agent.deviceType, agent.deviceID, device.ParentPortNo, device.ProxyAddress.OnuId)
 	if err != nil {
 		cancel()
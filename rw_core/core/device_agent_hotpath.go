@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Profiling shows a large fraction of core CPU going to proto cloning in the agent update
+// paths: a status update on a device carrying a large flow table used to deep-clone the
+// whole device twice - once to obtain a mutable copy and once more when installing the
+// result as the cached device.  The helpers below exploit the copy-on-write discipline the
+// agent already follows everywhere: the cached device and its sub-messages are never
+// mutated in place, so a path that only touches top-level scalars (statuses, reason) can
+// work on a shallow struct copy that shares the flow table, and a path that owns its copy
+// outright can install it directly without the second clone.
+
+// shallowCopyDevice returns a struct copy of the device sharing all its sub-messages.
+// Callers may mutate only top-level scalar fields before storing; sub-messages (flows,
+// groups, ports, ...) must be replaced - never modified - if they need to change.
+func shallowCopyDevice(device *voltha.Device) *voltha.Device {
+	cloned := *device
+	return &cloned
+}
+
+// copyPortsOf replaces the port list of a shallow device copy with a deep copy so the
+// caller can mutate port state without touching the ports shared with the cached device
+func copyPortsOf(device *voltha.Device) {
+	ports := make([]*voltha.Port, len(device.Ports))
+	for i, port := range device.Ports {
+		clonedPort := *port
+		ports[i] = &clonedPort
+	}
+	device.Ports = ports
+}
+
+// storeDeviceOwnedWithoutLock persists a device the caller owns outright and installs it
+// as the agent's cached copy without another clone.  The caller must not modify the
+// device after the call; reads remain safe because the cached device is never mutated in
+// place.  Must only be invoked while holding the device lock.
+func (agent *DeviceAgent) storeDeviceOwnedWithoutLock(ctx context.Context, device *voltha.Device) error {
+	if agent.stopped {
+		return errors.New("device agent stopped")
+	}
+	// The sequence is taken while the state is accepted, so the KV store sees the writes
+	// in the same order they were applied in memory
+	return agent.persistSequencedWrite(ctx, agent.writeSequencer.nextSequence(), device)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// newHotPathDevice provisions an ACTIVE device carrying the given number of flows, the
+// shape the update hot paths are optimized for
+func newHotPathDevice(tb testing.TB, dMgr *DeviceManager, mac string, flowCount int) *voltha.Device {
+	ctx := context.Background()
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	if !ok {
+		tb.Fatal("device creation failed")
+	}
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	if res := <-ch; res != nil {
+		tb.Fatalf("device enable failed: %v", res)
+	}
+	if err := dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT}); err != nil {
+		tb.Fatal(err)
+	}
+	if err := dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE); err != nil {
+		tb.Fatal(err)
+	}
+	flows := make([]*ofp.OfpFlowStats, flowCount)
+	for i := range flows {
+		flows[i] = &ofp.OfpFlowStats{Id: uint64(i + 1)}
+	}
+	if err := dMgr.addFlowsAndGroups(ctx, device.Id, flows, nil, nil); err != nil {
+		tb.Fatal(err)
+	}
+	return device
+}
+
+// The hot paths work on shallow copies sharing the cached device's sub-messages; this
+// guards the isolation the rest of the core relies on.
+func TestHotPathUpdatesPreserveIsolation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(t, dMgr, "00:00:00:00:45:01", 3)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.NotNil(t, agent)
+
+	before, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+
+	// A status update must not disturb the flows or a previously handed-out view
+	assert.Nil(t, agent.updateDeviceStatus(ctx, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Nil(t, agent.updatePortState(ctx, voltha.Port_PON_OLT, 1, voltha.OperStatus_DISCOVERED))
+
+	after, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(after.Flows.Items))
+	assert.Equal(t, voltha.OperStatus_DISCOVERED, after.Ports[0].OperStatus)
+	assert.NotEqual(t, voltha.OperStatus_DISCOVERED, before.Ports[0].OperStatus)
+
+	// Mutating a handed-out view must not leak into the cached device
+	after.Flows.Items[0].Id = 999
+	after.Ports[0].OperStatus = voltha.OperStatus_FAILED
+	fresh, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), fresh.Flows.Items[0].Id)
+	assert.Equal(t, voltha.OperStatus_DISCOVERED, fresh.Ports[0].OperStatus)
+}
+
+func BenchmarkUpdateDeviceStatus1000Flows(b *testing.B) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(b, dMgr, "00:00:00:00:45:02", 1000)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := agent.updateDeviceStatus(ctx, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUpdatePortState1000Flows(b *testing.B) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newHotPathDevice(b, dMgr, "00:00:00:00:45:03", 1000)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := agent.updatePortState(ctx, voltha.Port_PON_OLT, 1, voltha.OperStatus_ACTIVE); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimultaneousRegistrationsKeepOneAgent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:1d")
+
+	// Forget the in-memory agent, as if this core had not loaded the device yet
+	dMgr.deviceAgents.Delete(device.Id)
+	dMgr.releaseDeviceOwnership(device.Id)
+
+	// Race several starts of the same device, the way a create racing concurrent
+	// reconciles would; every caller must converge on the same winning agent
+	var wg sync.WaitGroup
+	agents := make([]*DeviceAgent, 8)
+	for i := range agents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			agent := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
+			if _, err := agent.start(ctx, nil); err == nil {
+				agents[i] = dMgr.addDeviceAgentToMap(agent)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	value, have := dMgr.deviceAgents.Load(device.Id)
+	assert.True(t, have)
+	winner := value.(*DeviceAgent)
+	assert.False(t, winner.stopped)
+	for _, agent := range agents {
+		assert.Equal(t, winner, agent)
+	}
+
+	// Discarding the losers left the winner's persisted device alone
+	stored := &voltha.Device{}
+	have, err := dMgr.clusterDataProxy.Get(ctx, "devices/"+device.Id, stored)
+	assert.Nil(t, err)
+	assert.True(t, have)
+	fetched, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, fetched.Id)
+}
+
+func TestLosingAgentIsDiscardedWithoutKVRemoval(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:1e")
+	value, _ := dMgr.deviceAgents.Load(device.Id)
+	winner := value.(*DeviceAgent)
+
+	// A duplicate agent for an already-registered device loses the registration
+	duplicate := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
+	_, err := duplicate.start(ctx, nil)
+	assert.Nil(t, err)
+	returned := dMgr.addDeviceAgentToMap(duplicate)
+	assert.Equal(t, winner, returned)
+
+	// The loser is dead: its resources are released and a later stop is a no-op
+	assert.True(t, duplicate.stopped)
+	assert.Nil(t, duplicate.stop(ctx))
+
+	// Discarding it did not remove the device from the KV store
+	stored := &voltha.Device{}
+	have, err := dMgr.clusterDataProxy.Get(ctx, "devices/"+device.Id, stored)
+	assert.Nil(t, err)
+	assert.True(t, have)
+	assert.False(t, winner.stopped)
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Field operations attach free-form annotations to devices - RMA schedules, site codes,
+// ticket IDs - that travel with the device instead of abusing the Reason field.  Like the
+// event suppression window, annotations are kept in the device details (ExtraArgs) as
+// marked ";"-separated entries, so they are persisted with the device, survive
+// disable/enable cycles, appear in listings and are removed with the device.
+const (
+	// annotationMarker prefixes an annotation entry in the device details
+	annotationMarker = "annotation:"
+	// maxAnnotationEntryLength bounds one encoded key=value entry
+	maxAnnotationEntryLength = 256
+	// maxAnnotationsTotalLength bounds the encoded size of all annotations of a device
+	maxAnnotationsTotalLength = 2048
+)
+
+// deviceAnnotations parses the annotations kept in the device details
+func deviceAnnotations(device *voltha.Device) map[string]string {
+	annotations := make(map[string]string)
+	for _, part := range strings.Split(device.ExtraArgs, ";") {
+		if !strings.HasPrefix(part, annotationMarker) {
+			continue
+		}
+		entry := strings.SplitN(strings.TrimPrefix(part, annotationMarker), "=", 2)
+		if len(entry) == 2 && entry[0] != "" {
+			annotations[entry[0]] = entry[1]
+		}
+	}
+	return annotations
+}
+
+// encodeDeviceAnnotations rewrites the device details with the given annotations, leaving
+// every other entry (e.g. the event suppression marker) untouched
+func encodeDeviceAnnotations(extraArgs string, annotations map[string]string) string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(extraArgs, ";") {
+		if part != "" && !strings.HasPrefix(part, annotationMarker) {
+			parts = append(parts, part)
+		}
+	}
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s%s=%s", annotationMarker, key, annotations[key]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// validateAnnotation verifies the key and value are well formed and within the per-entry limit
+func validateAnnotation(key string, value string) error {
+	if key == "" {
+		return status.Errorf(codes.InvalidArgument, "annotation-key-empty")
+	}
+	if strings.ContainsAny(key, ";=|") || strings.ContainsAny(value, ";|") {
+		return status.Errorf(codes.InvalidArgument, "annotation-contains-reserved-character")
+	}
+	if len(annotationMarker)+len(key)+1+len(value) > maxAnnotationEntryLength {
+		return status.Errorf(codes.InvalidArgument, "annotation-%s-exceeds-%d-bytes", key, maxAnnotationEntryLength)
+	}
+	return nil
+}
+
+// setDeviceAnnotation attaches or overwrites one annotation on a device
+func (dMgr *DeviceManager) setDeviceAnnotation(ctx context.Context, deviceID string, key string, value string) error {
+	ctxDebugw(ctx, "setDeviceAnnotation", log.Fields{"device-id": deviceID, "key": key})
+	if err := validateAnnotation(key, value); err != nil {
+		return err
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	annotations := deviceAnnotations(device)
+	annotations[key] = value
+	extraArgs := encodeDeviceAnnotations(device.ExtraArgs, annotations)
+	if len(extraArgs)-len(device.ExtraArgs) > 0 && len(extraArgs) > maxAnnotationsTotalLength {
+		return status.Errorf(codes.InvalidArgument, "annotations-exceed-%d-bytes", maxAnnotationsTotalLength)
+	}
+	agent.updateDeviceAttribute(ctx, "ExtraArgs", extraArgs)
+	return nil
+}
+
+// removeDeviceAnnotation removes one annotation from a device
+func (dMgr *DeviceManager) removeDeviceAnnotation(ctx context.Context, deviceID string, key string) error {
+	ctxDebugw(ctx, "removeDeviceAnnotation", log.Fields{"device-id": deviceID, "key": key})
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	annotations := deviceAnnotations(device)
+	if _, exist := annotations[key]; !exist {
+		return status.Errorf(codes.NotFound, "annotation-%s-device-%s", key, deviceID)
+	}
+	delete(annotations, key)
+	agent.updateDeviceAttribute(ctx, "ExtraArgs", encodeDeviceAnnotations(device.ExtraArgs, annotations))
+	return nil
+}
+
+// listDevicesWithAnnotation returns the devices carrying the annotation key.  A non-empty
+// value additionally requires an exact value match, so "list devices annotated with
+// site=XYZ" works.
+func (dMgr *DeviceManager) listDevicesWithAnnotation(ctx context.Context, key string, value string) (*voltha.Devices, error) {
+	ctxDebugw(ctx, "listDevicesWithAnnotation", log.Fields{"key": key, "value": value})
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		ctxErrorw(ctx, "failed-to-list-devices-from-cluster-proxy", log.Fields{"error": err})
+		return nil, err
+	}
+	result := &voltha.Devices{Items: make([]*voltha.Device, 0)}
+	for _, device := range devices {
+		annotated, exist := deviceAnnotations(device)[key]
+		if exist && (value == "" || annotated == value) {
+			result.Items = append(result.Items, device)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeviceAnnotationCodec(t *testing.T) {
+	device := &voltha.Device{ExtraArgs: "event-suppression-until=2026;annotation:site=XYZ;annotation:ticket=T-1"}
+	annotations := deviceAnnotations(device)
+	assert.Equal(t, map[string]string{"site": "XYZ", "ticket": "T-1"}, annotations)
+
+	// Re-encoding preserves the non-annotation entries
+	delete(annotations, "ticket")
+	encoded := encodeDeviceAnnotations(device.ExtraArgs, annotations)
+	assert.Equal(t, "event-suppression-until=2026;annotation:site=XYZ", encoded)
+}
+
+func TestSetAndRemoveDeviceAnnotation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:04:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, device.Id, "site", "XYZ"))
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, device.Id, "rma", "scheduled"))
+
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"site": "XYZ", "rma": "scheduled"}, deviceAnnotations(stored))
+
+	// Annotations survive a disable/enable cycle
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	stored, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "XYZ", deviceAnnotations(stored)["site"])
+
+	assert.Nil(t, dMgr.removeDeviceAnnotation(ctx, device.Id, "rma"))
+	stored, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	_, exist := deviceAnnotations(stored)["rma"]
+	assert.False(t, exist)
+
+	// Removing an absent annotation is reported
+	err = dMgr.removeDeviceAnnotation(ctx, device.Id, "rma")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestDeviceAnnotationValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:04:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(dMgr.setDeviceAnnotation(ctx, device.Id, "", "x")))
+	assert.Equal(t, codes.InvalidArgument, status.Code(dMgr.setDeviceAnnotation(ctx, device.Id, "a;b", "x")))
+	assert.Equal(t, codes.InvalidArgument, status.Code(dMgr.setDeviceAnnotation(ctx, device.Id, "big", strings.Repeat("v", maxAnnotationEntryLength))))
+}
+
+func TestListDevicesWithAnnotation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:04:03"}, ch)
+	first, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:04:04"}, ch)
+	second, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, first.Id, "site", "XYZ"))
+	assert.Nil(t, dMgr.setDeviceAnnotation(ctx, second.Id, "site", "ABC"))
+
+	matched, err := dMgr.listDevicesWithAnnotation(ctx, "site", "XYZ")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matched.Items))
+	assert.Equal(t, first.Id, matched.Items[0].Id)
+
+	// An empty value matches any device carrying the key
+	matched, err = dMgr.listDevicesWithAnnotation(ctx, "site", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(matched.Items))
+}
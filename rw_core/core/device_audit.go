@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A long-running core drifts away from the cluster state: agents linger for devices a
+// peer deleted, ownership records outlive the devices they point at, and devices this
+// instance owns sit in the KV store with no agent serving them.  None of this is fatal,
+// which is exactly why nobody notices until memory or CPU climbs.  The audit below
+// periodically cross-checks the three views - the in-memory agent map, the inter-core
+// ownership records and the KV device list - logging every inconsistency and, when
+// repair is enabled, fixing it: stale agents are stopped, orphaned ownership records
+// are dropped, owned devices missing from memory are loaded and auxiliary per-device
+// records orphaned by past deletes (see device_data_registry.go) are removed.  The last
+// report is kept for the debug query and cumulative counters feed the metrics.
+
+// deviceAuditReport summarizes one audit pass
+type deviceAuditReport struct {
+	RanAt            time.Time
+	AgentsInMemory   int
+	OwnershipEntries int
+	KVDevices        int
+	StaleAgents      []string // agents whose device is gone or DELETED in the KV store
+	OrphanOwnerships []string // ownership records for devices not in the KV store
+	MissingAgents    []string // owned devices present in the KV store but not in memory
+	PortCollisions   []string // devices carrying two conflicting ports with the same port number
+	OrphanAuxData    []string // auxiliary per-device records whose device is gone, as "path/deviceID"
+	Repaired         int
+}
+
+// inconsistencies returns the number of findings in the report
+func (report *deviceAuditReport) inconsistencies() int {
+	return len(report.StaleAgents) + len(report.OrphanOwnerships) + len(report.MissingAgents) + len(report.PortCollisions) + len(report.OrphanAuxData)
+}
+
+// runDeviceAudit periodically audits the agent map until the device manager stops
+func (dMgr *DeviceManager) runDeviceAudit(ctx context.Context) {
+	ticker := time.NewTicker(dMgr.deviceAuditInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dMgr.auditDeviceAgents(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("device-audit-stopped")
+			return
+		}
+	}
+}
+
+// auditDeviceAgents runs one audit pass and returns its report
+func (dMgr *DeviceManager) auditDeviceAgents(ctx context.Context) *deviceAuditReport {
+	report := &deviceAuditReport{RanAt: time.Now()}
+
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		ctxWarnw(ctx, "device-audit-kv-list-failed", log.Fields{"error": err})
+		return report
+	}
+	kvDevices := make(map[string]*voltha.Device, len(devices))
+	for _, device := range devices {
+		kvDevices[device.Id] = device
+	}
+	report.KVDevices = len(kvDevices)
+
+	// Agents whose device was deleted - typically by a peer instance - keep consuming
+	// memory and serve stale reads until they are stopped
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		report.AgentsInMemory++
+		deviceID := key.(string)
+		if device, have := kvDevices[deviceID]; !have || device.AdminState == voltha.AdminState_DELETED {
+			report.StaleAgents = append(report.StaleAgents, deviceID)
+		}
+		return true
+	})
+
+	// Ownership records for devices no longer in the KV store point at nothing and keep
+	// request forwarding alive for devices nobody manages
+	if dMgr.interCoreProxy != nil {
+		dMgr.interCoreProxy.deviceOwners.Range(func(key, value interface{}) bool {
+			report.OwnershipEntries++
+			deviceID := key.(string)
+			if _, have := kvDevices[deviceID]; !have {
+				report.OrphanOwnerships = append(report.OrphanOwnerships, deviceID)
+			}
+			return true
+		})
+	}
+
+	// Devices this instance owns must have an agent in memory or their requests go nowhere
+	for deviceID, device := range kvDevices {
+		if device.AdminState == voltha.AdminState_DELETED || dMgr.IsDeviceInCache(deviceID) {
+			continue
+		}
+		if dMgr.interCoreProxy != nil && dMgr.interCoreProxy.ownerOf(deviceID) != "" {
+			// a peer owns the device; it is that instance's job to serve it
+			continue
+		}
+		report.MissingAgents = append(report.MissingAgents, deviceID)
+	}
+
+	// Port number collisions already persisted - announced before the addPort guard
+	// existed, or written by another path - are reported but never auto-repaired: the
+	// core cannot know which of the two ports the hardware actually has
+	for _, device := range kvDevices {
+		report.PortCollisions = append(report.PortCollisions, portCollisionsOf(device)...)
+	}
+
+	// Auxiliary records left behind by deletes that predate the data registry - or by a
+	// purge that failed mid-way - accumulate in the KV store forever unless flagged
+	report.OrphanAuxData = dMgr.orphanedDeviceData(ctx, kvDevices)
+
+	if report.inconsistencies() > 0 {
+		ctxWarnw(ctx, "device-audit-found-inconsistencies", log.Fields{
+			"stale-agents":      report.StaleAgents,
+			"orphan-ownerships": report.OrphanOwnerships,
+			"missing-agents":    report.MissingAgents,
+			"port-collisions":   report.PortCollisions,
+			"orphan-aux-data":   report.OrphanAuxData,
+		})
+		if dMgr.deviceAuditRepair {
+			dMgr.repairAuditFindings(ctx, report)
+		}
+	}
+
+	atomic.AddInt64(&dMgr.deviceAuditRuns, 1)
+	atomic.AddInt64(&dMgr.deviceAuditFindings, int64(report.inconsistencies()))
+	atomic.AddInt64(&dMgr.deviceAuditRepairs, int64(report.Repaired))
+	dMgr.lastDeviceAuditLock.Lock()
+	dMgr.lastDeviceAudit = report
+	dMgr.lastDeviceAuditLock.Unlock()
+	return report
+}
+
+// repairAuditFindings fixes the inconsistencies recorded in the report
+func (dMgr *DeviceManager) repairAuditFindings(ctx context.Context, report *deviceAuditReport) {
+	for _, deviceID := range report.StaleAgents {
+		dMgr.stopManagingDevice(ctx, deviceID)
+		report.Repaired++
+	}
+	for _, deviceID := range report.OrphanOwnerships {
+		dMgr.interCoreProxy.clearDeviceOwner(deviceID)
+		report.Repaired++
+	}
+	for _, deviceID := range report.MissingAgents {
+		if err := dMgr.load(ctx, deviceID); err != nil {
+			ctxWarnw(ctx, "device-audit-load-failed", log.Fields{"device-id": deviceID, "error": err})
+			continue
+		}
+		report.Repaired++
+	}
+	for _, record := range report.OrphanAuxData {
+		if err := dMgr.clusterDataProxy.Remove(ctx, record); err != nil {
+			ctxWarnw(ctx, "device-audit-aux-remove-failed", log.Fields{"record": record, "error": err})
+			continue
+		}
+		report.Repaired++
+	}
+	ctxInfow(ctx, "device-audit-repaired", log.Fields{"repaired": report.Repaired})
+}
+
+// getDeviceAudit is the debug query returning the report of the last audit pass
+func (dMgr *DeviceManager) getDeviceAudit() (*deviceAuditReport, error) {
+	dMgr.lastDeviceAuditLock.Lock()
+	defer dMgr.lastDeviceAuditLock.Unlock()
+	if dMgr.lastDeviceAudit == nil {
+		return nil, newNotFoundError("device-audit", "last-report")
+	}
+	report := *dMgr.lastDeviceAudit
+	return &report, nil
+}
+
+// deviceAuditMetrics returns the cumulative audit counters: passes run, inconsistencies
+// found and inconsistencies repaired
+func (dMgr *DeviceManager) deviceAuditMetrics() (runs int64, findings int64, repairs int64) {
+	return atomic.LoadInt64(&dMgr.deviceAuditRuns), atomic.LoadInt64(&dMgr.deviceAuditFindings), atomic.LoadInt64(&dMgr.deviceAuditRepairs)
+}
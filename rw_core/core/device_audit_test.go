@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeviceAuditDetectsAndRepairs(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.deviceAuditRepair = true
+
+	// No audit has run yet: the debug query has nothing to report
+	_, err := dMgr.getDeviceAudit()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	healthy := createTestDevice(t, dMgr, "00:00:00:00:42:01")
+
+	// A stale agent: the device's KV record was removed behind this core's back,
+	// as happens when a peer instance completes a deletion
+	stale := createTestDevice(t, dMgr, "00:00:00:00:42:02")
+	assert.Nil(t, dMgr.clusterDataProxy.Remove(ctx, "devices/"+stale.Id))
+
+	// An orphaned ownership record: the device it points at does not exist anywhere
+	dMgr.interCoreProxy.setDeviceOwner("ghost-device", "core-2")
+
+	// A missing agent: the device is in the KV store and owned here, but its agent
+	// was evicted from memory
+	missing := createTestDevice(t, dMgr, "00:00:00:00:42:03")
+	dMgr.deviceAgents.Delete(missing.Id)
+
+	report := dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, []string{stale.Id}, report.StaleAgents)
+	assert.Equal(t, []string{"ghost-device"}, report.OrphanOwnerships)
+	assert.Equal(t, []string{missing.Id}, report.MissingAgents)
+	assert.Equal(t, 3, report.Repaired)
+
+	// The repairs landed: the stale agent is gone, the ownership record is dropped
+	// and the missing agent is back in memory
+	assert.False(t, dMgr.IsDeviceInCache(stale.Id))
+	assert.Equal(t, "", dMgr.interCoreProxy.ownerOf("ghost-device"))
+	assert.True(t, dMgr.IsDeviceInCache(missing.Id))
+	assert.True(t, dMgr.IsDeviceInCache(healthy.Id))
+
+	// A consistent tree audits clean
+	report = dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, 0, report.inconsistencies())
+
+	// The debug query serves the last report and the counters accumulated both passes
+	last, err := dMgr.getDeviceAudit()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, last.inconsistencies())
+	runs, findings, repairs := dMgr.deviceAuditMetrics()
+	assert.Equal(t, int64(2), runs)
+	assert.Equal(t, int64(3), findings)
+	assert.Equal(t, int64(3), repairs)
+}
+
+func TestDeviceAuditLogsOnlyWhenRepairDisabled(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.deviceAuditRepair = false
+
+	stale := createTestDevice(t, dMgr, "00:00:00:00:42:04")
+	assert.Nil(t, dMgr.clusterDataProxy.Remove(ctx, "devices/"+stale.Id))
+
+	report := dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, []string{stale.Id}, report.StaleAgents)
+	assert.Equal(t, 0, report.Repaired)
+	// The inconsistency was only reported; the agent is still in memory
+	assert.True(t, dMgr.IsDeviceInCache(stale.Id))
+}
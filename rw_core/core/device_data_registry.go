@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Several features keep per-device records in the KV store under their own paths - the
+// activity trail, the state history, the agent snapshot, the tech profile report, the
+// activation history, the parked flows.  Deleting a device used to leave all of them
+// orphaned under the old device ID.  The registry below names every such path once: the
+// delete path purges them all through it, so a feature added to the registry
+// automatically participates in cleanup, and the device audit lists the registered paths
+// to flag leftovers from deletes that predate this mechanism.  Annotations need no entry
+// here - they live inside the device record and leave with it.  Tombstones are the
+// deliberate archive of a delete and are excluded for that reason.
+
+// deviceDataOwner names one KV path holding per-device auxiliary data keyed by device ID
+type deviceDataOwner struct {
+	name string
+	path string
+}
+
+// registerDeviceData declares a KV path holding per-device data keyed by device ID; the
+// delete path and the audit pick it up from here
+func (dMgr *DeviceManager) registerDeviceData(name string, path string) {
+	dMgr.deviceDataOwnersLock.Lock()
+	defer dMgr.deviceDataOwnersLock.Unlock()
+	dMgr.deviceDataOwners = append(dMgr.deviceDataOwners, deviceDataOwner{name: name, path: path})
+}
+
+// deviceDataRegistry returns the registered owners, seeding the built-in ones on first use
+func (dMgr *DeviceManager) deviceDataRegistry() []deviceDataOwner {
+	dMgr.deviceDataOnce.Do(func() {
+		dMgr.registerDeviceData("activity", deviceActivityPath)
+		dMgr.registerDeviceData("state-history", deviceStateHistoryPath)
+		dMgr.registerDeviceData("snapshot", deviceSnapshotPath)
+		dMgr.registerDeviceData("tech-profile", deviceTechProfilePath)
+		dMgr.registerDeviceData("activation-history", activationHistoryPath)
+		dMgr.registerDeviceData("parked-flows", parkedFlowsPath)
+		dMgr.registerDeviceData("parked-groups", parkedGroupsPath)
+	})
+	dMgr.deviceDataOwnersLock.Lock()
+	defer dMgr.deviceDataOwnersLock.Unlock()
+	return append([]deviceDataOwner{}, dMgr.deviceDataOwners...)
+}
+
+// purgeDeviceData removes every registered per-device record of a deleted device.
+// Failures are logged and skipped: a leftover is caught later by the audit, and a delete
+// must not fail over auxiliary data.
+func (dMgr *DeviceManager) purgeDeviceData(ctx context.Context, deviceID string) {
+	for _, owner := range dMgr.deviceDataRegistry() {
+		if err := dMgr.clusterDataProxy.Remove(ctx, owner.path+"/"+deviceID); err != nil {
+			ctxWarnw(ctx, "failed-to-purge-device-data", log.Fields{"device-id": deviceID, "owner": owner.name, "error": err})
+		}
+	}
+	ctxDebugw(ctx, "device-data-purged", log.Fields{"device-id": deviceID})
+}
+
+// orphanedDeviceData returns the registered records whose device no longer exists, as
+// "path/deviceID" entries the repair can remove directly.  Listing keys needs the raw KV
+// client; without one - direct construction - the check is skipped.
+func (dMgr *DeviceManager) orphanedDeviceData(ctx context.Context, kvDevices map[string]*voltha.Device) []string {
+	if dMgr.kvClient == nil {
+		return nil
+	}
+	var orphans []string
+	for _, owner := range dMgr.deviceDataRegistry() {
+		pairs, err := dMgr.kvClient.List(ctx, fmt.Sprintf("%s/%s", dMgr.kvStorePrefix, owner.path))
+		if err != nil {
+			ctxWarnw(ctx, "device-data-audit-list-failed", log.Fields{"owner": owner.name, "error": err})
+			continue
+		}
+		for key := range pairs {
+			deviceID := key[strings.LastIndex(key, "/")+1:]
+			if _, have := kvDevices[deviceID]; !have {
+				orphans = append(orphans, owner.path+"/"+deviceID)
+			}
+		}
+	}
+	return orphans
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/db"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// auxRecordExists reports whether a registered per-device record is still in the KV store
+func auxRecordExists(ctx context.Context, dMgr *DeviceManager, path string, deviceID string) bool {
+	found, err := dMgr.clusterDataProxy.Get(ctx, path+"/"+deviceID, &ic.StrType{})
+	return err == nil && found
+}
+
+func TestDeviceDataPurgedOnDelete(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:3c")
+
+	// Seed records under a few registered paths, through the feature where one exists and
+	// directly where seeding through the feature would need kafka
+	assert.Nil(t, dMgr.updateDeviceTechProfile(ctx, device.Id, `[{"name":"alloc-ids","resources":{"uni-0":"1024"}}]`))
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, deviceActivityPath, device.Id, &ic.StrType{Val: "{}"}))
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, deviceStateHistoryPath, device.Id, &ic.StrType{Val: "{}"}))
+	for _, owner := range []string{deviceTechProfilePath, deviceActivityPath, deviceStateHistoryPath} {
+		assert.True(t, auxRecordExists(ctx, dMgr, owner, device.Id))
+	}
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	waitUntil(t, 2*time.Second, "auxiliary device data was never purged", func() bool {
+		for _, owner := range []string{deviceTechProfilePath, deviceActivityPath, deviceStateHistoryPath} {
+			if auxRecordExists(ctx, dMgr, owner, device.Id) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestAuditFlagsAndRepairsOrphanDeviceData(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	// The orphan check lists keys through the raw KV client, so the fixture's proxy is
+	// rebuilt around a client the test also hands to the device manager
+	client := newInMemoryKVClient()
+	backend := &db.Backend{Client: client, StoreType: "memory", PathPrefix: "service/voltha"}
+	dMgr.clusterDataProxy = model.NewProxy(backend, "/")
+	dMgr.kvClient = client
+	dMgr.kvStorePrefix = "service/voltha"
+
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:3d")
+	assert.Nil(t, dMgr.updateDeviceTechProfile(ctx, device.Id, `[{"name":"alloc-ids","resources":{"uni-0":"1024"}}]`))
+	// A record under a dead device ID, as a delete from before the purge existed leaves behind
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, deviceSnapshotPath, "ghost-device", &ic.StrType{Val: "{}"}))
+
+	report := dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, []string{deviceSnapshotPath + "/ghost-device"}, report.OrphanAuxData)
+	// The live device's record is not an orphan
+	assert.True(t, auxRecordExists(ctx, dMgr, deviceTechProfilePath, device.Id))
+
+	// With repair enabled the orphan is removed and counted
+	dMgr.deviceAuditRepair = true
+	report = dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, 1, report.Repaired)
+	assert.False(t, auxRecordExists(ctx, dMgr, deviceSnapshotPath, "ghost-device"))
+	report = dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, 0, len(report.OrphanAuxData))
+}
+
+func TestAuditSkipsOrphanCheckWithoutKVClient(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, deviceSnapshotPath, "ghost-device", &ic.StrType{Val: "{}"}))
+
+	// The fixture carries no raw KV client by default; the audit must not flag anything
+	// it cannot list
+	report := dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, 0, len(report.OrphanAuxData))
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Change-management tooling needs to know whether an operation would succeed without
+// executing it.  The precondition validators below are shared between the executing paths in
+// the device agent and the dry-run entry point, so a dry-run result cannot drift from actual
+// behaviour.  A dry run never touches the model nor the adapter.
+
+// Operations supported by dryRunOperation
+const (
+	dryRunEnableDevice  = "enable"
+	dryRunDisableDevice = "disable"
+	dryRunDeleteDevice  = "delete"
+	dryRunFlowUpdate    = "flow-update"
+	dryRunImageDownload = "image-download"
+)
+
+// validateEnableDevicePreconditions checks that a device can be enabled and returns the name
+// of the adapter that would handle it
+func (dMgr *DeviceManager) validateEnableDevicePreconditions(device *voltha.Device) (string, error) {
+	adapterName, err := dMgr.adapterMgr.getAdapterName(device.Type)
+	if err != nil {
+		return "", err
+	}
+	if device.AdminState == voltha.AdminState_DELETED {
+		return "", status.Error(codes.FailedPrecondition, fmt.Sprintf("cannot-enable-a-deleted-device: %s ", device.Id))
+	}
+	return adapterName, nil
+}
+
+// validateDisableDevicePreconditions checks that a device can be disabled
+func (dMgr *DeviceManager) validateDisableDevicePreconditions(device *voltha.Device) error {
+	if device.AdminState == voltha.AdminState_PREPROVISIONED ||
+		device.AdminState == voltha.AdminState_DELETED {
+		return status.Errorf(codes.FailedPrecondition, "deviceId:%s, invalid-admin-state:%s", device.Id, device.AdminState)
+	}
+	return nil
+}
+
+// validateImageDownloadPreconditions checks that a device can accept an image download
+func (dMgr *DeviceManager) validateImageDownloadPreconditions(device *voltha.Device) error {
+	if device.AdminState != voltha.AdminState_ENABLED {
+		return status.Errorf(codes.FailedPrecondition, "device-id:%s, expected-admin-state:%s", device.Id, voltha.AdminState_ENABLED)
+	}
+	return nil
+}
+
+// validateFlowUpdatePreconditions checks the device state, the device type and the flows of
+// a flow update, returning the device type for the caller
+func (dMgr *DeviceManager) validateFlowUpdatePreconditions(device *voltha.Device, flows []*ofp.OfpFlowStats) (*voltha.DeviceType, error) {
+	if device.OperStatus != voltha.OperStatus_ACTIVE || device.ConnectStatus != voltha.ConnectStatus_REACHABLE || device.AdminState != voltha.AdminState_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "invalid device states")
+	}
+	dType := dMgr.adapterMgr.getDeviceType(device.Type)
+	if dType == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "non-existent-device-type-%s", device.Type)
+	}
+	if err := dMgr.validateFlows(device.Type, flows); err != nil {
+		return nil, err
+	}
+	if dMgr.rejectFlowsAtCapacity && dMgr.adapterMgr.getFlowTableCapacity(device.Type).wouldExceed(len(device.GetFlows().GetItems())+len(flows)) {
+		return nil, newFailedPreconditionError("device", device.Id, "flow-table-full")
+	}
+	return dType, nil
+}
+
+// transitionWouldBeLegal consults the transition table with the would-be state change
+func (dMgr *DeviceManager) transitionWouldBeLegal(device *voltha.Device, targetAdmin voltha.AdminState_Types, targetOper voltha.OperStatus_Types) error {
+	previousState := getDeviceStates(device)
+	would := proto.Clone(device).(*voltha.Device)
+	would.AdminState = targetAdmin
+	would.OperStatus = targetOper
+	for _, handler := range dMgr.stateTransitions.GetTransitionHandler(would, previousState) {
+		// Handlers are stored as coreif.DeviceManager method values, so they are identified
+		// by name rather than by function pointer
+		if strings.HasSuffix(funcName(handler), ".NotifyInvalidTransition-fm") {
+			return newFailedPreconditionError("device", device.Id, fmt.Sprintf("illegal-transition-to-%s", targetAdmin))
+		}
+	}
+	return nil
+}
+
+// dryRunOperation reports whether an operation would succeed for a device by running the
+// same validation and precondition checks as the executing path, without modifying the model
+// or contacting the adapter.  The flows argument is only consulted for flow updates.
+func (dMgr *DeviceManager) dryRunOperation(ctx context.Context, operation string, deviceID string, flows []*ofp.OfpFlowStats) error {
+	device, err := dMgr.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	switch operation {
+	case dryRunEnableDevice:
+		if _, err := dMgr.validateEnableDevicePreconditions(device); err != nil {
+			return err
+		}
+		if device.AdminState == voltha.AdminState_ENABLED {
+			// The executing path treats this as a no-op
+			return nil
+		}
+		return dMgr.transitionWouldBeLegal(device, voltha.AdminState_ENABLED, voltha.OperStatus_ACTIVATING)
+	case dryRunDisableDevice:
+		if err := dMgr.validateDisableDevicePreconditions(device); err != nil {
+			return err
+		}
+		if device.AdminState == voltha.AdminState_DISABLED {
+			// The executing path treats this as a no-op
+			return nil
+		}
+		return dMgr.transitionWouldBeLegal(device, voltha.AdminState_DISABLED, voltha.OperStatus_UNKNOWN)
+	case dryRunDeleteDevice:
+		// Deletion has no precondition of its own; only the transition can be illegal
+		return dMgr.transitionWouldBeLegal(device, voltha.AdminState_DELETED, device.OperStatus)
+	case dryRunFlowUpdate:
+		_, err := dMgr.validateFlowUpdatePreconditions(device, flows)
+		return err
+	case dryRunImageDownload:
+		return dMgr.validateImageDownloadPreconditions(device)
+	default:
+		return newInvalidArgumentError("unknown-dry-run-operation-" + operation)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newDryRunDeviceManager() *DeviceManager {
+	dMgr := &DeviceManager{}
+	dMgr.adapterMgr = &AdapterManager{
+		deviceTypeToAdapterMap: map[string]string{"olt": "adapter-1"},
+		adapterAgents: map[string]*AdapterAgent{
+			"adapter-1": newAdapterAgent(&voltha.Adapter{Id: "adapter-1"}, &voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt"}}}),
+		},
+		flowValidators:      make(map[string]FlowValidator),
+		flowTableCapacities: make(map[string]flowTableCapacity),
+	}
+	dMgr.stateTransitions = NewTransitionMap(dMgr)
+	return dMgr
+}
+
+func TestDryRunEnablePreconditions(t *testing.T) {
+	dMgr := newDryRunDeviceManager()
+
+	adapterName, err := dMgr.validateEnableDevicePreconditions(&voltha.Device{Id: "1234", Type: "olt", AdminState: voltha.AdminState_PREPROVISIONED})
+	assert.Nil(t, err)
+	assert.Equal(t, "adapter-1", adapterName)
+
+	_, err = dMgr.validateEnableDevicePreconditions(&voltha.Device{Id: "1234", Type: "olt", AdminState: voltha.AdminState_DELETED})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = dMgr.validateEnableDevicePreconditions(&voltha.Device{Id: "1234", Type: "no-such-type"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestDryRunDisableAndImagePreconditions(t *testing.T) {
+	dMgr := newDryRunDeviceManager()
+
+	assert.Nil(t, dMgr.validateDisableDevicePreconditions(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_ENABLED}))
+	err := dMgr.validateDisableDevicePreconditions(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_PREPROVISIONED})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	assert.Nil(t, dMgr.validateImageDownloadPreconditions(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_ENABLED}))
+	err = dMgr.validateImageDownloadPreconditions(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_DISABLED})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestDryRunFlowUpdatePreconditions(t *testing.T) {
+	dMgr := newDryRunDeviceManager()
+
+	ready := &voltha.Device{
+		Id:            "1234",
+		Type:          "olt",
+		AdminState:    voltha.AdminState_ENABLED,
+		ConnectStatus: voltha.ConnectStatus_REACHABLE,
+		OperStatus:    voltha.OperStatus_ACTIVE,
+	}
+	dType, err := dMgr.validateFlowUpdatePreconditions(ready, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "olt", dType.Id)
+
+	notReady := &voltha.Device{Id: "1234", Type: "olt", AdminState: voltha.AdminState_DISABLED}
+	_, err = dMgr.validateFlowUpdatePreconditions(notReady, nil)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	unknownType := &voltha.Device{
+		Id:            "1234",
+		Type:          "no-such-type",
+		AdminState:    voltha.AdminState_ENABLED,
+		ConnectStatus: voltha.ConnectStatus_REACHABLE,
+		OperStatus:    voltha.OperStatus_ACTIVE,
+	}
+	_, err = dMgr.validateFlowUpdatePreconditions(unknownType, nil)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestDryRunTransitionLegality(t *testing.T) {
+	dMgr := newDryRunDeviceManager()
+
+	// A root device going from ENABLED/ACTIVE back to ENABLED/ACTIVATING is an
+	// invalid transition in the table and must be reported as such
+	active := &voltha.Device{Id: "1234", Root: true, AdminState: voltha.AdminState_ENABLED, OperStatus: voltha.OperStatus_ACTIVE}
+	err := dMgr.transitionWouldBeLegal(active, voltha.AdminState_ENABLED, voltha.OperStatus_ACTIVATING)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Disabling an enabled device is legal
+	assert.Nil(t, dMgr.transitionWouldBeLegal(active, voltha.AdminState_DISABLED, voltha.OperStatus_UNKNOWN))
+}
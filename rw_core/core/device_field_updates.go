@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The whole-device update takes a full voltha.Device and merges a broad set of fields,
+// which lets a caller sending a sparsely-populated proto clobber data it never meant to
+// touch.  The entry points below update one field group at a time with their own
+// validation: updateDeviceDescription for the identification strings, updateDeviceVlan
+// for the outer VLAN, and updateDeviceAddress for the management address (which rides the
+// transition-aware changeDeviceAddress path).  The whole-device update remains for the
+// adapters that still use it but now rejects attempts to modify the read-only fields -
+// Id, Root and ParentId - naming the offending fields.
+
+const (
+	// maxDeviceDescriptionLength bounds each of the free-form identification strings
+	maxDeviceDescriptionLength = 255
+	// maxDeviceVlan is the highest valid outer VLAN id; 0 clears the assignment
+	maxDeviceVlan = 4094
+)
+
+// updateDeviceDescription rewrites the identification strings of a device.  Empty
+// arguments leave the corresponding field untouched.
+func (dMgr *DeviceManager) updateDeviceDescription(ctx context.Context, deviceID string, vendor string, model string, serialNumber string) error {
+	ctxDebugw(ctx, "updateDeviceDescription", log.Fields{"device-id": deviceID, "vendor": vendor, "model": model, "serial-number": serialNumber})
+	if vendor == "" && model == "" && serialNumber == "" {
+		return newInvalidArgumentError("no-description-field-supplied")
+	}
+	for _, value := range []string{vendor, model, serialNumber} {
+		if len(value) > maxDeviceDescriptionLength {
+			return newInvalidArgumentError("description-field-exceeds-" + strconv.Itoa(maxDeviceDescriptionLength) + "-characters")
+		}
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	return agent.updateDeviceDescription(ctx, vendor, model, serialNumber)
+}
+
+// updateDeviceVlan rewrites the outer VLAN of a device; 0 clears the assignment
+func (dMgr *DeviceManager) updateDeviceVlan(ctx context.Context, deviceID string, vlan uint32) error {
+	ctxDebugw(ctx, "updateDeviceVlan", log.Fields{"device-id": deviceID, "vlan": vlan})
+	if vlan > maxDeviceVlan {
+		return newInvalidArgumentError("vlan-out-of-range")
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	return agent.updateDeviceVlan(ctx, vlan)
+}
+
+// updateDeviceAddress rewrites the management address of a device.  The address change
+// has transition implications of its own and therefore rides the changeDeviceAddress path.
+func (dMgr *DeviceManager) updateDeviceAddress(ctx context.Context, deviceID string, hostAndPort string) error {
+	return dMgr.changeDeviceAddress(ctx, deviceID, hostAndPort, false)
+}
+
+// updateDeviceDescription applies the non-empty identification strings and stores the device
+func (agent *DeviceAgent) updateDeviceDescription(ctx context.Context, vendor string, model string, serialNumber string) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	cloned := agent.getDeviceWithoutLock()
+	if vendor != "" {
+		cloned.Vendor = vendor
+	}
+	if model != "" {
+		cloned.Model = model
+	}
+	if serialNumber != "" {
+		cloned.SerialNumber = serialNumber
+	}
+	ctxDebugw(ctx, "updateDeviceDescription", log.Fields{"device-id": cloned.Id})
+	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+}
+
+// updateDeviceVlan applies the outer VLAN and stores the device
+func (agent *DeviceAgent) updateDeviceVlan(ctx context.Context, vlan uint32) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	cloned := agent.getDeviceWithoutLock()
+	cloned.Vlan = vlan
+	ctxDebugw(ctx, "updateDeviceVlan", log.Fields{"device-id": cloned.Id, "vlan": vlan})
+	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+}
+
+// validateReadOnlyDeviceFields rejects a whole-device update that tries to modify the
+// fields only the core itself may write.  An incoming field left at its zero value, or
+// carrying the stored value unchanged, is not a modification.  Only the fields the merge
+// actually applies are guarded: a stale Ports snapshot - cloned before a concurrent
+// addPort - is normal and the merge ignores ports anyway.
+func validateReadOnlyDeviceFields(current *voltha.Device, incoming *voltha.Device) error {
+	var violations []string
+	if incoming.Id != "" && incoming.Id != current.Id {
+		violations = append(violations, "id")
+	}
+	if incoming.Root != current.Root {
+		violations = append(violations, "root")
+	}
+	if incoming.ParentId != "" && incoming.ParentId != current.ParentId {
+		violations = append(violations, "parent_id")
+	}
+	if len(violations) > 0 {
+		return newInvalidArgumentError("read-only-device-fields-modified:" + strings.Join(violations, ","))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFieldScopedDeviceUpdates(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:09:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// Each update touches only its own field group
+	assert.Nil(t, dMgr.updateDeviceDescription(ctx, device.Id, "BBSim", "asfvolt16", "BBSIM_OLT_0"))
+	assert.Nil(t, dMgr.updateDeviceVlan(ctx, device.Id, 100))
+	updated, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "BBSim", updated.Vendor)
+	assert.Equal(t, "asfvolt16", updated.Model)
+	assert.Equal(t, "BBSIM_OLT_0", updated.SerialNumber)
+	assert.Equal(t, uint32(100), updated.Vlan)
+	assert.Equal(t, device.MacAddress, updated.MacAddress)
+
+	// An empty argument leaves the stored value alone
+	assert.Nil(t, dMgr.updateDeviceDescription(ctx, device.Id, "", "", "BBSIM_OLT_1"))
+	updated, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "BBSim", updated.Vendor)
+	assert.Equal(t, "BBSIM_OLT_1", updated.SerialNumber)
+
+	// Validation failures
+	err = dMgr.updateDeviceDescription(ctx, device.Id, "", "", "")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.updateDeviceDescription(ctx, device.Id, strings.Repeat("v", maxDeviceDescriptionLength+1), "", "")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.updateDeviceVlan(ctx, device.Id, maxDeviceVlan+1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = dMgr.updateDeviceVlan(ctx, "no-such-device", 100)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWholeDeviceUpdateRejectsReadOnlyFields(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:09:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// Demoting the root flag is a read-only modification
+	err := dMgr.updateDeviceUsingAdapterData(ctx, &voltha.Device{Id: device.Id, Vendor: "clobber"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "root")
+
+	// So is reparenting
+	err = dMgr.updateDeviceUsingAdapterData(ctx, &voltha.Device{Id: device.Id, Root: true, ParentId: "some-parent"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "parent_id")
+
+	// A stale Ports snapshot is not a modification: the merge never applies ports, and
+	// the adapter may have cloned the device before a concurrent addPort
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT}))
+	assert.Nil(t, dMgr.updateDeviceUsingAdapterData(ctx, &voltha.Device{Id: device.Id, Root: true, Ports: []*voltha.Port{}}))
+	withPorts, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(withPorts.Ports))
+
+	// The rejected updates left the device untouched
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "clobber", stored.Vendor)
+
+	// An update that echoes the read-only fields still goes through
+	assert.Nil(t, dMgr.updateDeviceUsingAdapterData(ctx, &voltha.Device{Id: device.Id, Root: true, Vendor: "BBSim"}))
+	stored, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "BBSim", stored.Vendor)
+}
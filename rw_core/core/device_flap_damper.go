@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A device with a marginal management link can flap its oper/connect status every few
+// seconds, and every flap drives the full transition machinery, logical port updates and an
+// event per change.  The flap damper watches status transitions per device: once too many
+// occur within the detection window the device enters a dampened state where further status
+// changes are coalesced rather than applied, and a single flapping event is raised.
+// Dampening is lifted only after the device has been quiet for the stability period.  The
+// dampened state is surfaced through the device Reason field so it is visible via GetDevice.
+const flapDampenedReason = "flap-dampened"
+
+// flapThresholds holds the flap-detection tuning for a device type
+type flapThresholds struct {
+	maxTransitions  int           // status transitions within window that trigger dampening
+	window          time.Duration // detection window
+	stabilityPeriod time.Duration // quiet time required before dampening is lifted
+}
+
+var defaultFlapThresholds = flapThresholds{maxTransitions: 5, window: 30 * time.Second, stabilityPeriod: time.Minute}
+
+// flapThresholdOverrides maps a device type to its flap-detection tuning.  Types without an
+// entry use defaultFlapThresholds.
+var flapThresholdOverrides = map[string]flapThresholds{}
+
+func flapThresholdsForType(deviceType string) flapThresholds {
+	if thresholds, ok := flapThresholdOverrides[deviceType]; ok {
+		return thresholds
+	}
+	return defaultFlapThresholds
+}
+
+// flapDamper tracks the status transitions of a single device.  It is only manipulated while
+// the agent request queue is held, so it needs no locking of its own.
+type flapDamper struct {
+	thresholds      flapThresholds
+	transitionTimes []time.Time
+	dampened        bool
+	lastTransition  time.Time
+	pendingConn     voltha.ConnectStatus_Types
+	pendingOper     voltha.OperStatus_Types
+	hasPending      bool
+}
+
+func newFlapDamper(deviceType string) *flapDamper {
+	return &flapDamper{thresholds: flapThresholdsForType(deviceType)}
+}
+
+// recordTransition notes a status transition at now and reports whether it pushed the device
+// into dampening
+func (fd *flapDamper) recordTransition(now time.Time) bool {
+	fd.lastTransition = now
+	fd.transitionTimes = append(fd.transitionTimes, now)
+	// Drop transitions that fell outside the detection window
+	cutoff := now.Add(-fd.thresholds.window)
+	kept := fd.transitionTimes[:0]
+	for _, transition := range fd.transitionTimes {
+		if transition.After(cutoff) {
+			kept = append(kept, transition)
+		}
+	}
+	fd.transitionTimes = kept
+	if !fd.dampened && len(fd.transitionTimes) >= fd.thresholds.maxTransitions {
+		fd.dampened = true
+		return true
+	}
+	return false
+}
+
+// coalesce stores the latest statuses received while dampened; earlier coalesced values are
+// superseded
+func (fd *flapDamper) coalesce(connStatus voltha.ConnectStatus_Types, operStatus voltha.OperStatus_Types) {
+	fd.pendingConn = connStatus
+	fd.pendingOper = operStatus
+	fd.hasPending = true
+}
+
+// isStable reports whether the device has been quiet long enough for dampening to be lifted
+func (fd *flapDamper) isStable(now time.Time) bool {
+	return now.Sub(fd.lastTransition) >= fd.thresholds.stabilityPeriod
+}
+
+// reset lifts dampening and clears the transition history
+func (fd *flapDamper) reset() {
+	fd.dampened = false
+	fd.transitionTimes = nil
+	fd.hasPending = false
+}
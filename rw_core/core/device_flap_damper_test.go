@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlapDamperEntersDampening(t *testing.T) {
+	fd := newFlapDamper("olt")
+	now := time.Now()
+
+	for i := 0; i < fd.thresholds.maxTransitions-1; i++ {
+		assert.False(t, fd.recordTransition(now.Add(time.Duration(i)*time.Second)))
+	}
+	assert.False(t, fd.dampened)
+
+	// the Nth transition within the window triggers dampening
+	assert.True(t, fd.recordTransition(now.Add(5*time.Second)))
+	assert.True(t, fd.dampened)
+
+	// subsequent transitions do not re-trigger
+	assert.False(t, fd.recordTransition(now.Add(6*time.Second)))
+}
+
+func TestFlapDamperIgnoresSlowTransitions(t *testing.T) {
+	fd := newFlapDamper("olt")
+	now := time.Now()
+
+	// transitions spaced beyond the window never accumulate
+	for i := 0; i < 3*fd.thresholds.maxTransitions; i++ {
+		assert.False(t, fd.recordTransition(now.Add(time.Duration(i)*fd.thresholds.window)))
+	}
+	assert.False(t, fd.dampened)
+}
+
+func TestFlapDamperStabilityAndReset(t *testing.T) {
+	fd := newFlapDamper("olt")
+	now := time.Now()
+	for i := 0; i < fd.thresholds.maxTransitions; i++ {
+		fd.recordTransition(now)
+	}
+	assert.True(t, fd.dampened)
+
+	fd.coalesce(voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE)
+	assert.True(t, fd.hasPending)
+
+	assert.False(t, fd.isStable(now.Add(fd.thresholds.stabilityPeriod/2)))
+	assert.True(t, fd.isStable(now.Add(fd.thresholds.stabilityPeriod)))
+
+	fd.reset()
+	assert.False(t, fd.dampened)
+	assert.False(t, fd.hasPending)
+	assert.Empty(t, fd.transitionTimes)
+}
+
+func TestFlapThresholdsPerDeviceType(t *testing.T) {
+	flapThresholdOverrides["flappy-onu"] = flapThresholds{maxTransitions: 2, window: 10 * time.Second, stabilityPeriod: 20 * time.Second}
+	defer delete(flapThresholdOverrides, "flappy-onu")
+
+	assert.Equal(t, 2, flapThresholdsForType("flappy-onu").maxTransitions)
+	assert.Equal(t, defaultFlapThresholds, flapThresholdsForType("olt"))
+}
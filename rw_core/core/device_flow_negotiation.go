@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// flowUpdateStyle is how a set of flow changes is sent to the adapter of a device
+type flowUpdateStyle int
+
+const (
+	// flowUpdateBulk replaces the full flow table on the adapter
+	flowUpdateBulk flowUpdateStyle = iota
+	// flowUpdateIncremental sends only the computed additions and removals
+	flowUpdateIncremental
+)
+
+func (s flowUpdateStyle) String() string {
+	if s == flowUpdateIncremental {
+		return "incremental"
+	}
+	return "bulk"
+}
+
+// chooseFlowUpdateStyle negotiates the update style for a device from the capabilities its
+// device type registered.  Incremental is used only when the adapter accepts add/remove
+// updates and the computed delta fully explains the transition from the current flow table
+// to the desired one; when the delta diverges the full table is sent instead so the adapter
+// cannot drift from the core's view.
+func chooseFlowUpdateStyle(dType *voltha.DeviceType, current, desired, toAdd, toRemove []*ofp.OfpFlowStats) flowUpdateStyle {
+	if !dType.AcceptsAddRemoveFlowUpdates {
+		return flowUpdateBulk
+	}
+	if !deltaExplainsTransition(current, desired, toAdd, toRemove) {
+		logger.Warnw("flow-delta-divergence-falling-back-to-bulk", log.Fields{"device-type": dType.Id})
+		return flowUpdateBulk
+	}
+	return flowUpdateIncremental
+}
+
+// deltaExplainsTransition replays toAdd/toRemove over the current flow table and reports
+// whether the result matches the desired table.  Flows are compared by their IDs, which the
+// core derives deterministically from the flow content.
+func deltaExplainsTransition(current, desired, toAdd, toRemove []*ofp.OfpFlowStats) bool {
+	replayed := make(map[uint64]struct{}, len(current)+len(toAdd))
+	for _, flow := range current {
+		replayed[flow.Id] = struct{}{}
+	}
+	for _, flow := range toRemove {
+		delete(replayed, flow.Id)
+	}
+	for _, flow := range toAdd {
+		replayed[flow.Id] = struct{}{}
+	}
+	if len(replayed) != len(desired) {
+		return false
+	}
+	for _, flow := range desired {
+		if _, ok := replayed[flow.Id]; !ok {
+			return false
+		}
+	}
+	return true
+}
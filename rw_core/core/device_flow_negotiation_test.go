@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func flowWithID(id uint64) *ofp.OfpFlowStats {
+	return &ofp.OfpFlowStats{Id: id}
+}
+
+// Both adapter styles are negotiated against the same sequence of logical flow changes:
+// start empty, add flows 1 and 2, then replace flow 2 with flow 3.
+func TestChooseFlowUpdateStyle(t *testing.T) {
+	bulkOnly := &voltha.DeviceType{Id: "bulk-olt", AcceptsBulkFlowUpdate: true}
+	incremental := &voltha.DeviceType{Id: "incr-olt", AcceptsAddRemoveFlowUpdates: true}
+
+	steps := []struct {
+		current, desired, toAdd, toRemove []*ofp.OfpFlowStats
+	}{
+		{
+			current: nil,
+			desired: []*ofp.OfpFlowStats{flowWithID(1), flowWithID(2)},
+			toAdd:   []*ofp.OfpFlowStats{flowWithID(1), flowWithID(2)},
+		},
+		{
+			current:  []*ofp.OfpFlowStats{flowWithID(1), flowWithID(2)},
+			desired:  []*ofp.OfpFlowStats{flowWithID(1), flowWithID(3)},
+			toAdd:    []*ofp.OfpFlowStats{flowWithID(3)},
+			toRemove: []*ofp.OfpFlowStats{flowWithID(2)},
+		},
+	}
+
+	for _, step := range steps {
+		// a bulk-only adapter always gets the full table
+		assert.Equal(t, flowUpdateBulk, chooseFlowUpdateStyle(bulkOnly, step.current, step.desired, step.toAdd, step.toRemove))
+		// an incremental adapter gets the delta as long as it explains the transition
+		assert.Equal(t, flowUpdateIncremental, chooseFlowUpdateStyle(incremental, step.current, step.desired, step.toAdd, step.toRemove))
+	}
+}
+
+func TestChooseFlowUpdateStyleDivergenceFallsBackToBulk(t *testing.T) {
+	incremental := &voltha.DeviceType{Id: "incr-olt", AcceptsAddRemoveFlowUpdates: true}
+
+	// the delta removes flow 2 but the desired table still contains it
+	current := []*ofp.OfpFlowStats{flowWithID(1), flowWithID(2)}
+	desired := []*ofp.OfpFlowStats{flowWithID(1), flowWithID(2), flowWithID(3)}
+	toAdd := []*ofp.OfpFlowStats{flowWithID(3)}
+	toRemove := []*ofp.OfpFlowStats{flowWithID(2)}
+
+	assert.Equal(t, flowUpdateBulk, chooseFlowUpdateStyle(incremental, current, desired, toAdd, toRemove))
+}
+
+func TestDeltaExplainsTransition(t *testing.T) {
+	assert.True(t, deltaExplainsTransition(nil, nil, nil, nil))
+	assert.True(t, deltaExplainsTransition(
+		[]*ofp.OfpFlowStats{flowWithID(1)},
+		[]*ofp.OfpFlowStats{flowWithID(2)},
+		[]*ofp.OfpFlowStats{flowWithID(2)},
+		[]*ofp.OfpFlowStats{flowWithID(1)}))
+	// missing removal
+	assert.False(t, deltaExplainsTransition(
+		[]*ofp.OfpFlowStats{flowWithID(1)},
+		[]*ofp.OfpFlowStats{flowWithID(2)},
+		[]*ofp.OfpFlowStats{flowWithID(2)},
+		nil))
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strconv"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Without visibility into flow table occupancy versus the capacity a device type can hold,
+// table exhaustion only surfaces when installs start failing on the adapter.  A capacity can
+// be registered alongside each device type; the core then tracks utilization on every flow
+// change, raises a warning event when it crosses the threshold, and can optionally reject
+// installs that would exceed the capacity instead of forwarding a doomed request.
+
+// flowUtilizationWarningThreshold is the utilization above which the warning event is raised
+const flowUtilizationWarningThreshold = 0.9
+
+// flowTableCapacity is the advertised capacity of a device type.  A zero value means the
+// capacity is unknown and utilization is not tracked.
+type flowTableCapacity struct {
+	maxFlows  int
+	maxGroups int
+}
+
+// registerFlowTableCapacity records the flow table capacity of a device type, replacing any
+// previous value
+func (aMgr *AdapterManager) registerFlowTableCapacity(deviceType string, capacity flowTableCapacity) {
+	aMgr.lockFlowValidators.Lock()
+	defer aMgr.lockFlowValidators.Unlock()
+	aMgr.flowTableCapacities[deviceType] = capacity
+}
+
+// getFlowTableCapacity returns the registered capacity of a device type, or the zero value
+// when none is known
+func (aMgr *AdapterManager) getFlowTableCapacity(deviceType string) flowTableCapacity {
+	aMgr.lockFlowValidators.RLock()
+	defer aMgr.lockFlowValidators.RUnlock()
+	return aMgr.flowTableCapacities[deviceType]
+}
+
+// flowTableUtilization returns the fraction of the capacity that numFlows occupies, or 0 when
+// the capacity is unknown
+func (capacity flowTableCapacity) utilization(numFlows int) float64 {
+	if capacity.maxFlows <= 0 {
+		return 0
+	}
+	return float64(numFlows) / float64(capacity.maxFlows)
+}
+
+// wouldExceed reports whether a table of numFlows flows is over the capacity
+func (capacity flowTableCapacity) wouldExceed(numFlows int) bool {
+	return capacity.maxFlows > 0 && numFlows > capacity.maxFlows
+}
+
+// checkFlowTableUtilization is invoked after every flow change; it logs the occupancy and
+// raises a single warning event when utilization crosses the threshold.  The warning re-arms
+// once utilization drops back below the threshold.
+func (agent *DeviceAgent) checkFlowTableUtilization(device *voltha.Device) {
+	capacity := agent.adapterMgr.getFlowTableCapacity(device.Type)
+	if capacity.maxFlows <= 0 {
+		return
+	}
+	numFlows := len(device.Flows.Items)
+	utilization := capacity.utilization(numFlows)
+	logger.Debugw("flow-table-utilization", log.Fields{"device-id": agent.deviceID, "flows": numFlows, "capacity": capacity.maxFlows, "utilization": utilization})
+	if utilization >= flowUtilizationWarningThreshold {
+		if !agent.flowUtilizationWarned {
+			agent.flowUtilizationWarned = true
+			logger.Warnw("flow-table-nearing-capacity", log.Fields{"device-id": agent.deviceID, "flows": numFlows, "capacity": capacity.maxFlows})
+			go agent.deviceMgr.eventMgr.publishDeviceEvent(device, flowTableUtilizationEvent, map[string]string{
+				"flows":       strconv.Itoa(numFlows),
+				"capacity":    strconv.Itoa(capacity.maxFlows),
+				"utilization": strconv.FormatFloat(utilization, 'f', 2, 64),
+			})
+		}
+	} else {
+		agent.flowUtilizationWarned = false
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowTableCapacity(t *testing.T) {
+	capacity := flowTableCapacity{maxFlows: 10}
+
+	assert.Equal(t, 0.5, capacity.utilization(5))
+	assert.False(t, capacity.wouldExceed(10))
+	assert.True(t, capacity.wouldExceed(11))
+
+	// an unknown capacity never reports utilization nor rejects
+	unknown := flowTableCapacity{}
+	assert.Equal(t, float64(0), unknown.utilization(100))
+	assert.False(t, unknown.wouldExceed(100))
+}
+
+func TestFlowTableCapacityRegistration(t *testing.T) {
+	aMgr := &AdapterManager{flowTableCapacities: make(map[string]flowTableCapacity)}
+
+	assert.Equal(t, flowTableCapacity{}, aMgr.getFlowTableCapacity("olt"))
+
+	aMgr.registerFlowTableCapacity("olt", flowTableCapacity{maxFlows: 1024, maxGroups: 128})
+	assert.Equal(t, 1024, aMgr.getFlowTableCapacity("olt").maxFlows)
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+)
+
+// Every long-running operation used to invent its own progress tracking: bulk deletes
+// blocked the caller until the last device was gone, bulk creates likewise, and anything
+// slower than a request timeout left the operator guessing.  The job framework below is
+// the one way such operations are tracked: an operation started as a job returns a job ID
+// immediately, runs detached from the request, reports its progress into a record the NBI
+// can query and list, and persists its final result.  Cancellation works through the
+// job's context, so a runner honours it at its natural stopping points rather than being
+// killed mid-device.  Job records live in the KV store, so a finished job's outcome
+// survives the instance that ran it.
+
+const deviceJobPath = "device_jobs"
+
+// States a job moves through; RUNNING ends in exactly one of the other three
+const (
+	jobStateRunning   = "RUNNING"
+	jobStateSucceeded = "SUCCEEDED"
+	jobStateFailed    = "FAILED"
+	jobStateCancelled = "CANCELLED"
+)
+
+// deviceJob is the tracked record of one long-running operation
+type deviceJob struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	DeviceIDs  []string  `json:"device-ids,omitempty"` // target devices, when known up front
+	State      string    `json:"state"`
+	Progress   int       `json:"progress"` // percent complete, best effort
+	Detail     string    `json:"detail,omitempty"`
+	Result     string    `json:"result,omitempty"` // runner-encoded outcome, set on completion
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started-at"`
+	FinishedAt time.Time `json:"finished-at,omitempty"`
+}
+
+// jobProgress reports how far a runner has come; percent is clamped to 0-100
+type jobProgress func(percent int, detail string)
+
+// jobRunner is the body of a job.  It runs detached from the originating request, under a
+// context cancelled when the job is; the returned string becomes the job's result.
+type jobRunner func(ctx context.Context, progress jobProgress) (string, error)
+
+// persistDeviceJob writes the job record to the KV store
+func (dMgr *DeviceManager) persistDeviceJob(ctx context.Context, job *deviceJob) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return dMgr.clusterDataProxy.AddWithID(ctx, deviceJobPath, job.ID, &ic.StrType{Val: string(encoded)})
+}
+
+// startDeviceJob starts a runner as a tracked job and returns its record without waiting
+// for it.  The job is refused when its record cannot be persisted - an untrackable job
+// defeats the point of running one.
+func (dMgr *DeviceManager) startDeviceJob(ctx context.Context, jobType string, deviceIDs []string, run jobRunner) (*deviceJob, error) {
+	job := &deviceJob{
+		ID:        CreateDeviceID(),
+		Type:      jobType,
+		DeviceIDs: deviceIDs,
+		State:     jobStateRunning,
+		StartedAt: time.Now(),
+	}
+	if err := dMgr.persistDeviceJob(ctx, job); err != nil {
+		return nil, newUnavailableError("device-job", job.ID, err)
+	}
+	// The job outlives the request that started it, so it runs under its own context
+	jobCtx, cancel := context.WithCancel(context.Background())
+	dMgr.runningJobs.Store(job.ID, cancel)
+	ctxInfow(ctx, "device-job-started", log.Fields{"job-id": job.ID, "job-type": jobType})
+
+	progress := func(percent int, detail string) {
+		if percent < 0 {
+			percent = 0
+		} else if percent > 100 {
+			percent = 100
+		}
+		job.Progress, job.Detail = percent, detail
+		if err := dMgr.persistDeviceJob(context.Background(), job); err != nil {
+			ctxWarnw(context.Background(), "failed-to-persist-job-progress", log.Fields{"job-id": job.ID, "error": err})
+		}
+	}
+	started := *job
+	go func() {
+		result, err := run(jobCtx, progress)
+		dMgr.finishDeviceJob(jobCtx, job, result, err)
+		cancel()
+	}()
+	return &started, nil
+}
+
+// finishDeviceJob records the final state of a job: CANCELLED when its context was
+// cancelled, FAILED on a runner error, SUCCEEDED otherwise.  A cancelled runner's partial
+// result is kept - it says how far the job got.
+func (dMgr *DeviceManager) finishDeviceJob(jobCtx context.Context, job *deviceJob, result string, err error) {
+	job.FinishedAt = time.Now()
+	job.Result = result
+	switch {
+	case jobCtx.Err() != nil:
+		job.State = jobStateCancelled
+	case err != nil:
+		job.State = jobStateFailed
+		job.Error = err.Error()
+	default:
+		job.State = jobStateSucceeded
+		job.Progress = 100
+	}
+	dMgr.runningJobs.Delete(job.ID)
+	if err := dMgr.persistDeviceJob(context.Background(), job); err != nil {
+		ctxWarnw(context.Background(), "failed-to-persist-job-outcome", log.Fields{"job-id": job.ID, "error": err})
+	}
+	ctxInfow(context.Background(), "device-job-finished", log.Fields{"job-id": job.ID, "job-type": job.Type, "state": job.State})
+}
+
+// getDeviceJob returns the current record of a job
+func (dMgr *DeviceManager) getDeviceJob(ctx context.Context, jobID string) (*deviceJob, error) {
+	entry := &ic.StrType{}
+	if found, err := dMgr.clusterDataProxy.Get(ctx, deviceJobPath+"/"+jobID, entry); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, newNotFoundError("device-job", jobID)
+	}
+	job := &deviceJob{}
+	if err := json.Unmarshal([]byte(entry.Val), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// listDeviceJobs returns the records of every job, running or finished
+func (dMgr *DeviceManager) listDeviceJobs(ctx context.Context) ([]*deviceJob, error) {
+	var entries []*ic.StrType
+	if err := dMgr.clusterDataProxy.List(ctx, deviceJobPath, &entries); err != nil {
+		return nil, err
+	}
+	jobs := make([]*deviceJob, 0, len(entries))
+	for _, entry := range entries {
+		job := &deviceJob{}
+		if err := json.Unmarshal([]byte(entry.Val), job); err != nil {
+			ctxWarnw(ctx, "skipping-corrupt-job-record", log.Fields{"error": err})
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// cancelDeviceJob asks a running job to stop.  The runner winds down at its next
+// cancellation point, so the job may finish a little after this returns.
+func (dMgr *DeviceManager) cancelDeviceJob(ctx context.Context, jobID string) error {
+	if cancel, running := dMgr.runningJobs.Load(jobID); running {
+		ctxInfow(ctx, "cancelling-device-job", log.Fields{"job-id": jobID})
+		cancel.(context.CancelFunc)()
+		return nil
+	}
+	job, err := dMgr.getDeviceJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	return newFailedPreconditionError("device-job", jobID, "job-already-"+job.State)
+}
+
+// GetDeviceJob is the NBI query returning the record of one job
+func (handler *APIHandler) GetDeviceJob(ctx context.Context, jobID string) (*deviceJob, error) {
+	ctxDebugw(ctx, "GetDeviceJob", log.Fields{"job-id": jobID})
+	return handler.deviceMgr.getDeviceJob(ctx, jobID)
+}
+
+// ListDeviceJobs is the NBI query returning every job record
+func (handler *APIHandler) ListDeviceJobs(ctx context.Context) ([]*deviceJob, error) {
+	ctxDebugw(ctx, "ListDeviceJobs", nil)
+	return handler.deviceMgr.listDeviceJobs(ctx)
+}
+
+// CancelDeviceJob is the NBI request asking a running job to stop
+func (handler *APIHandler) CancelDeviceJob(ctx context.Context, jobID string) error {
+	ctxDebugw(ctx, "CancelDeviceJob", log.Fields{"job-id": jobID})
+	return handler.deviceMgr.cancelDeviceJob(ctx, jobID)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// jobInState polls the job record until it reaches the wanted state
+func jobInState(t *testing.T, dMgr *DeviceManager, jobID string, state string) *deviceJob {
+	ctx := context.Background()
+	var job *deviceJob
+	waitUntil(t, 2*time.Second, "job never reached state "+state, func() bool {
+		var err error
+		job, err = dMgr.getDeviceJob(ctx, jobID)
+		return err == nil && job.State == state
+	})
+	return job
+}
+
+func TestDeviceJobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	step := make(chan struct{})
+	job, err := dMgr.startDeviceJob(ctx, "test-job", []string{"dev-1"}, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		<-step
+		progress(50, "halfway")
+		<-step
+		return "all-done", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, jobStateRunning, job.State)
+
+	// The record is queryable while the job runs and tracks its progress
+	stored, err := dMgr.getDeviceJob(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "test-job", stored.Type)
+	assert.Equal(t, []string{"dev-1"}, stored.DeviceIDs)
+	step <- struct{}{}
+	waitUntil(t, 2*time.Second, "progress was never recorded", func() bool {
+		stored, err := dMgr.getDeviceJob(ctx, job.ID)
+		return err == nil && stored.Progress == 50 && stored.Detail == "halfway"
+	})
+
+	step <- struct{}{}
+	stored = jobInState(t, dMgr, job.ID, jobStateSucceeded)
+	assert.Equal(t, "all-done", stored.Result)
+	assert.Equal(t, 100, stored.Progress)
+	assert.False(t, stored.FinishedAt.IsZero())
+
+	jobs, err := dMgr.listDeviceJobs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+}
+
+func TestDeviceJobFailureAndCancellation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	failing, err := dMgr.startDeviceJob(ctx, "test-job", nil, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		return "", errors.New("blew-up")
+	})
+	assert.Nil(t, err)
+	stored := jobInState(t, dMgr, failing.ID, jobStateFailed)
+	assert.Equal(t, "blew-up", stored.Error)
+
+	// Cancelling a finished job is refused; an unknown job is not found
+	err = dMgr.cancelDeviceJob(ctx, failing.ID)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "job-already-"+jobStateFailed)
+	assert.NotNil(t, dMgr.cancelDeviceJob(ctx, "no-such-job"))
+
+	// A running job winds down at its cancellation point, keeping its partial result
+	blocked, err := dMgr.startDeviceJob(ctx, "test-job", nil, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		<-jobCtx.Done()
+		return "partial", nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, dMgr.cancelDeviceJob(ctx, blocked.ID))
+	stored = jobInState(t, dMgr, blocked.ID, jobStateCancelled)
+	assert.Equal(t, "partial", stored.Result)
+}
+
+func TestBulkDeleteAsJob(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	first := createTestDevice(t, dMgr, "00:00:00:00:4a:42")
+	second := createTestDevice(t, dMgr, "00:00:00:00:4a:43")
+
+	// An empty filter is refused before a job exists
+	_, err := dMgr.deleteDevicesJob(ctx, deviceFilter{}, false)
+	assert.NotNil(t, err)
+
+	job, err := dMgr.deleteDevicesJob(ctx, deviceFilter{DeviceType: "olt"}, false)
+	assert.Nil(t, err)
+	stored := jobInState(t, dMgr, job.ID, jobStateSucceeded)
+
+	var results []bulkDeleteResult
+	assert.Nil(t, json.Unmarshal([]byte(stored.Result), &results))
+	assert.Equal(t, 2, len(results))
+	for _, result := range results {
+		assert.Equal(t, bulkDeleteDeleted, result.Action)
+	}
+	waitUntil(t, 2*time.Second, "devices were never deleted", func() bool {
+		return !dMgr.IsDeviceInCache(first.Id) && !dMgr.IsDeviceInCache(second.Id)
+	})
+}
+
+func TestBulkCreateAsJob(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	_, err := dMgr.createDevicesBulkJob(ctx, nil, false)
+	assert.NotNil(t, err)
+
+	specs := []*voltha.Device{
+		{Type: "olt", MacAddress: "00:00:00:00:4a:44"},
+		{Type: "olt", MacAddress: "00:00:00:00:4a:45"},
+	}
+	job, err := dMgr.createDevicesBulkJob(ctx, specs, false)
+	assert.Nil(t, err)
+	stored := jobInState(t, dMgr, job.ID, jobStateSucceeded)
+
+	var results []bulkCreateJobResult
+	assert.Nil(t, json.Unmarshal([]byte(stored.Result), &results))
+	assert.Equal(t, 2, len(results))
+	for _, result := range results {
+		assert.Equal(t, "", result.Error)
+		assert.True(t, dMgr.IsDeviceInCache(result.DeviceID))
+	}
+}
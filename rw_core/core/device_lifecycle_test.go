@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLifecycleTestDeviceManager assembles a device manager from the in-memory fakes so the
+// device lifecycle can be exercised without kafka or etcd
+func newLifecycleTestDeviceManager() (*DeviceManager, *fakeAdapterService, *fakeLogicalDeviceService) {
+	fas := &fakeAdapterService{}
+	fls := &fakeLogicalDeviceService{}
+	cdProxy := newInMemoryClusterProxy()
+	dMgr := &DeviceManager{
+		adapterProxy:            fas,
+		logicalDeviceMgr:        fls,
+		clusterDataProxy:        cdProxy,
+		kafkaICProxy:            &mock_kafka.MockKafkaICProxy{},
+		coreInstanceID:          "core-1",
+		rootDevices:             make(map[string]bool),
+		exitChannel:             make(chan int, 1),
+		defaultTimeout:          5 * time.Second,
+		deviceLoadingInProgress: make(map[string][]chan int),
+		relativesPrefetching:    make(map[string]struct{}),
+		deviceTopicMgr:          &DeviceTopicManager{adapterProxy: &AdapterProxy{}, clusterDataProxy: cdProxy},
+		// status validation ships permissive; strict-mode tests flip this explicitly
+		permissiveStatusValidation: true,
+	}
+	dMgr.adapterMgr = &AdapterManager{
+		deviceMgr:              dMgr,
+		deviceTypeToAdapterMap: map[string]string{"olt": "adapter-1", "onu": "adapter-1"},
+		adapterAgents: map[string]*AdapterAgent{
+			"adapter-1": newAdapterAgent(&voltha.Adapter{Id: "adapter-1"},
+				&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt"}, {Id: "onu"}}}),
+		},
+		flowValidators:      make(map[string]FlowValidator),
+		flowTableCapacities: make(map[string]flowTableCapacity),
+	}
+	dMgr.activationTracker = newActivationTracker(cdProxy)
+	dMgr.eventMgr = newEventManager(mock_kafka.NewKafkaClient(), cdProxy)
+	dMgr.packetStats = newPacketStatsManager(cdProxy, dMgr.eventMgr)
+	dMgr.interCoreProxy = newInterCoreProxy(nil, dMgr, "core-1", "rwcore", dMgr.defaultTimeout)
+	dMgr.stateTransitions = NewTransitionMap(dMgr)
+	return dMgr, fas, fls
+}
+
+// waitUntil polls the condition for up to the timeout, failing the test if it never holds
+func waitUntil(t *testing.T, timeout time.Duration, msg string, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, msg)
+}
+
+func TestDeviceLifecycleWithFakes(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, fls := newLifecycleTestDeviceManager()
+
+	// Create a root device
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:00:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	assert.True(t, device.Root)
+	assert.Equal(t, voltha.AdminState_PREPROVISIONED, device.AdminState)
+
+	// Enable it; the adapter must be asked to adopt it
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Equal(t, 1, fas.callCount("adoptDevice"))
+	device, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_ENABLED, device.AdminState)
+	assert.Equal(t, voltha.OperStatus_ACTIVATING, device.OperStatus)
+
+	// Add a PON port; the logical device manager is notified in the background
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT}))
+	device, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(device.Ports))
+	waitUntil(t, 2*time.Second, "logical port was never updated", func() bool {
+		return fls.callCount("updateLogicalPort") == 1
+	})
+
+	// Detect a child device on that port; it is created and enabled in the background
+	child, err := dMgr.childDeviceDetected(ctx, device.Id, 1, "onu", 1, "VEND", "SERIAL-1", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, child.ParentId)
+	_, err = dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	waitUntil(t, 2*time.Second, "child device was never adopted", func() bool {
+		return fas.callCount("adoptDevice") == 2
+	})
+
+	// Delete the root device; the adapter must be told
+	ch = make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	device, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_DELETED, device.AdminState)
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+}
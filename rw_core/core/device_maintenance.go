@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// An engineer troubleshooting a device wants the core's automation out of the way: the
+// transition handlers, the post-restart reconcile and the automatic enable of discovered
+// children must not fight manual steps.  A device can be placed in maintenance mode for
+// a bounded time, optionally covering its children; the marker is kept in the device
+// details so it survives a core restart and is visible in every GetDevice view, while
+// explicit manual operations stay allowed.  An expired marker is simply inert, so a
+// forgotten lock clears itself.  The delete transition is never suppressed - a manual
+// delete must complete its cleanup.
+
+// Markers persisting the maintenance mode in the device details
+const (
+	maintenanceUntilMarker    = "maintenance-until="
+	maintenanceChildrenMarker = "maintenance-children="
+)
+
+// parseMaintenanceMarker extracts the maintenance expiry and child coverage from the
+// device details; the last return value is false when no marker is present
+func parseMaintenanceMarker(extraArgs string) (time.Time, bool, bool) {
+	var expiry time.Time
+	var includeChildren, present bool
+	for _, entry := range strings.Split(extraArgs, ";") {
+		if strings.HasPrefix(entry, maintenanceUntilMarker) {
+			if unix, err := strconv.ParseInt(entry[len(maintenanceUntilMarker):], 10, 64); err == nil {
+				expiry = time.Unix(unix, 0)
+				present = true
+			}
+		} else if strings.HasPrefix(entry, maintenanceChildrenMarker) {
+			includeChildren = entry[len(maintenanceChildrenMarker):] == "true"
+		}
+	}
+	return expiry, includeChildren, present
+}
+
+// deviceInMaintenance reports whether the device itself carries an unexpired marker; the
+// second return value is whether the marker covers the children
+func deviceInMaintenance(device *voltha.Device) (bool, bool) {
+	expiry, includeChildren, present := parseMaintenanceMarker(device.GetExtraArgs())
+	active := present && time.Now().Before(expiry)
+	return active, active && includeChildren
+}
+
+// maintenanceActive reports whether automation is paused for the device, either through
+// its own marker or through a parent marker covering the children
+func (dMgr *DeviceManager) maintenanceActive(ctx context.Context, device *voltha.Device) bool {
+	if active, _ := deviceInMaintenance(device); active {
+		return true
+	}
+	if device.ParentId == "" {
+		return false
+	}
+	parent, err := dMgr.GetDevice(ctx, device.ParentId)
+	if err != nil {
+		return false
+	}
+	_, coversChildren := deviceInMaintenance(parent)
+	return coversChildren
+}
+
+// updateMaintenanceMarker rewrites the maintenance marker on the device; a zero expiry
+// clears it
+func (agent *DeviceAgent) updateMaintenanceMarker(ctx context.Context, expiry time.Time, includeChildren bool) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	cloned := agent.getDeviceWithoutLock()
+	var entries []string
+	for _, entry := range strings.Split(cloned.ExtraArgs, ";") {
+		if entry == "" || strings.HasPrefix(entry, maintenanceUntilMarker) || strings.HasPrefix(entry, maintenanceChildrenMarker) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if !expiry.IsZero() {
+		entries = append(entries, maintenanceUntilMarker+strconv.FormatInt(expiry.Unix(), 10))
+		if includeChildren {
+			entries = append(entries, maintenanceChildrenMarker+"true")
+		}
+	}
+	cloned.ExtraArgs = strings.Join(entries, ";")
+	return agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, "")
+}
+
+// setDeviceMaintenance places the device in maintenance mode for the given duration,
+// optionally covering its children
+func (dMgr *DeviceManager) setDeviceMaintenance(ctx context.Context, deviceID string, duration time.Duration, includeChildren bool) error {
+	if duration <= 0 {
+		return newInvalidArgumentError("maintenance-duration-must-be-positive")
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	expiry := time.Now().Add(duration)
+	if err := agent.updateMaintenanceMarker(ctx, expiry, includeChildren); err != nil {
+		return err
+	}
+	device, err := dMgr.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	ctxInfow(ctx, "device-maintenance-set", log.Fields{"device-id": deviceID, "expires-at": expiry, "include-children": includeChildren})
+	go dMgr.eventMgr.publishDeviceEvent(device, deviceMaintenanceSetEvent, map[string]string{
+		"expires-at":       expiry.Format(time.RFC3339),
+		"include-children": strconv.FormatBool(includeChildren),
+	})
+	return nil
+}
+
+// clearDeviceMaintenance lifts the maintenance mode from the device
+func (dMgr *DeviceManager) clearDeviceMaintenance(ctx context.Context, deviceID string) error {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	if err := agent.updateMaintenanceMarker(ctx, time.Time{}, false); err != nil {
+		return err
+	}
+	device, err := dMgr.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	ctxInfow(ctx, "device-maintenance-cleared", log.Fields{"device-id": deviceID})
+	go dMgr.eventMgr.publishDeviceEvent(device, deviceMaintenanceClearedEvent, map[string]string{})
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceBlocksAutomation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, fls := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:10:01")
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT}))
+
+	// An invalid duration and an unknown device are rejected
+	assert.NotNil(t, dMgr.setDeviceMaintenance(ctx, device.Id, 0, false))
+	assert.NotNil(t, dMgr.setDeviceMaintenance(ctx, "no-such-device", time.Hour, false))
+
+	// The maintenance marker is visible in the device view
+	assert.Nil(t, dMgr.setDeviceMaintenance(ctx, device.Id, time.Hour, true))
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Contains(t, view.ExtraArgs, maintenanceUntilMarker)
+	assert.Contains(t, view.ExtraArgs, maintenanceChildrenMarker+"true")
+
+	// The activation transition handlers are suppressed while in maintenance
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, fls.callCount("createLogicalDevice"))
+
+	// A discovered child is created but not auto-enabled
+	child, err := dMgr.childDeviceDetected(ctx, device.Id, 1, "onu", 1, "VEND", "SERIAL-M", 1)
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, fas.callCount("adoptDevice"))
+	childView, err := dMgr.GetDevice(ctx, child.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_PREPROVISIONED, childView.AdminState)
+
+	// Clearing removes the marker
+	assert.Nil(t, dMgr.clearDeviceMaintenance(ctx, device.Id))
+	view, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.NotContains(t, view.ExtraArgs, maintenanceUntilMarker)
+}
+
+func TestMaintenanceExpiresOnItsOwn(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, fls := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:10:02")
+
+	// Once the expiry passes the marker is inert and automation resumes
+	assert.Nil(t, dMgr.setDeviceMaintenance(ctx, device.Id, 30*time.Millisecond, false))
+	time.Sleep(1100 * time.Millisecond)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	waitUntil(t, 2*time.Second, "automation did not resume after expiry", func() bool {
+		return fls.callCount("createLogicalDevice") == 1
+	})
+}
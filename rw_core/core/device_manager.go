@@ -19,13 +19,18 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/opencord/voltha-go/db/model"
 	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
 	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
 	"github.com/opencord/voltha-lib-go/v3/pkg/log"
 	"github.com/opencord/voltha-lib-go/v3/pkg/probe"
@@ -38,21 +43,127 @@ import (
 
 // DeviceManager represent device manager attributes
 type DeviceManager struct {
-	deviceAgents            sync.Map
-	rootDevices             map[string]bool
-	lockRootDeviceMap       sync.RWMutex
-	core                    *Core
-	adapterProxy            *AdapterProxy
-	adapterMgr              *AdapterManager
-	logicalDeviceMgr        *LogicalDeviceManager
-	kafkaICProxy            kafka.InterContainerProxy
-	stateTransitions        *TransitionMap
-	clusterDataProxy        *model.Proxy
-	coreInstanceID          string
-	exitChannel             chan int
-	defaultTimeout          time.Duration
-	devicesLoadingLock      sync.RWMutex
-	deviceLoadingInProgress map[string][]chan int
+	deviceAgents             sync.Map
+	rootDevices              map[string]bool
+	lockRootDeviceMap        sync.RWMutex
+	core                     *Core
+	adapterProxy             adapterService
+	deviceTopicMgr           *DeviceTopicManager
+	eventMgr                 *EventManager
+	interCoreProxy           *InterCoreProxy
+	adapterMgr               *AdapterManager
+	logicalDeviceMgr         logicalDeviceService
+	kafkaICProxy             kafka.InterContainerProxy
+	stateTransitions         *TransitionMap
+	clusterDataProxy         *model.Proxy
+	coreInstanceID           string
+	exitChannel              chan int
+	stopOnce                 sync.Once
+	defaultTimeout           time.Duration
+	devicesLoadingLock       sync.RWMutex
+	deviceLoadingInProgress  map[string][]chan int
+	relativesLoadingLock     sync.Mutex
+	relativesPrefetching     map[string]struct{}
+	softDeleteRetention      time.Duration
+	permissiveFlowValidation bool
+	rejectFlowsAtCapacity    bool
+	skipFlowPortValidation   bool
+	// permissiveStatusValidation logs and counts invalid status updates instead of
+	// rejecting them
+	permissiveStatusValidation bool
+	invalidStatusUpdates       sync.Map // adapter name -> *int64 count of invalid status updates
+	proxyAddressMismatches     sync.Map // adapter name -> *int64 count of stale-parent proxy address lookups
+	// collectionRevision advances on every device mutation; deviceRevisions remembers the
+	// collection revision of each device's last change
+	collectionRevision       int64
+	deviceRevisions          sync.Map // device ID -> int64 revision of the last change
+	unservedDevices          sync.Map // device ID -> device type no registered adapter serves
+	packetInBufferWindow     time.Duration
+	skipLoadingDeletedAgents bool
+	kvClient                 kvstore.Client
+	kvStorePrefix            string
+	stuckSweepInterval       time.Duration
+	stuckObservations        sync.Map
+	clock                    coreClock // time source of the timer-driven logic; injectable for tests
+	readOnlyMode             int32     // non-zero while the core is in read-only maintenance; see readonly_mode.go
+	// Token buckets throttling the NBI entry points; see operation_quota.go
+	operationQuotaLock       sync.Mutex
+	operationQuotaDefault    quotaLimit
+	operationQuotaLimits     map[string]quotaLimit // per-operation overrides of the default limit
+	operationQuotaBuckets    map[string]*quotaBucket
+	operationQuotaGranted    map[string]int64
+	operationQuotaRejected   map[string]int64
+	runtimeCfg               *runtimeConfigManager
+	diagnostics              *diagnosticsCollector
+	idempotency              *idempotencyManager
+	activationTracker        *activationTracker
+	pendingRequests          *pendingRequestRegistry
+	reconcileRuns            sync.Map // adapter ID or selector key -> *adapterReconcileRun while a reconcile is in progress
+	reconcilingDevices       sync.Map // device ID -> struct{} while a selector-based reconcile holds the device
+	reconcileConcurrency     int
+	reconcileStartDelay      time.Duration
+	readConsistency          readConsistency
+	staleReadThreshold       time.Duration
+	strongReadCount          int64
+	activationWorkflows      map[string][]string
+	lockActivationWorkflows  sync.RWMutex
+	childCascadePolicies     map[string]childCascadePolicy
+	lockChildCascadePolicies sync.RWMutex
+	imageDownloadRetention   time.Duration
+	imageDownloadMaxRecords  int
+	stateHistorySize         int
+	stateHistoryPersist      time.Duration
+	snapshotCadence          time.Duration
+	footprintWarnBytes       int64
+	footprintLimitBytes      int64
+	imagePreflight           string
+	imageChecksumRequired    bool
+	parkUnreachableRequests  bool
+	parkedRequests           map[string][]parkedRequest
+	parkedRequestsLock       sync.Mutex
+	flowParkingWindow        time.Duration
+	flowParkingMax           int
+	deviceAuditInterval      time.Duration
+	deviceAuditRepair        bool
+	deletionBarrierTimeout   time.Duration
+	deviceAuditRuns          int64
+	deviceAuditFindings      int64
+	deviceAuditRepairs       int64
+	lastDeviceAudit          *deviceAuditReport
+	lastDeviceAuditLock      sync.Mutex
+	deviceDataOwners         []deviceDataOwner
+	deviceDataOwnersLock     sync.Mutex
+	deviceDataOnce           sync.Once
+	clusterEpoch             string
+	epochLock                sync.Mutex
+	packetStats              *packetStatsManager
+	pendingPacketIns         map[string][]pendingPacketIn
+	pendingPacketInsLock     sync.Mutex
+	packetOutDropCount       int64    // number of packet-outs dropped due to an invalid out port
+	packetOutDropLogTimes    sync.Map // device ID -> last time a dropped packet-out was logged
+	activationStartTimes     sync.Map // device ID -> time the last enable request was received
+	activatedDevices         sync.Map // device ID -> activation already handled since the last enable
+	deviceActivities         sync.Map // device ID -> *deviceActivity
+	ownershipRenewals        sync.Map // device ID -> time the ownership reservation was last reserved or renewed
+	ownershipTokens          sync.Map // device ID -> fencing token this instance acquired the device under
+	runningJobs              sync.Map // job ID -> cancel function of the running job
+	downloadBatches          sync.Map // job ID -> *imageDownloadBatch of a running batch download
+	// ponDownloadLimits caps concurrent downloads per PON port, per child device type
+	ponDownloadLimits       map[string]int
+	ponDownloadLimitsLock   sync.RWMutex
+	activityPersistInterval time.Duration
+	quietDeviceThreshold    time.Duration
+	// Batching of DeviceDiscovered kafka notifications; see discovery_notifier.go
+	discoveryBatchWindow time.Duration
+	discoveryBatchSize   int
+	perDeviceDiscovery   bool
+	discoveryLock        sync.Mutex
+	pendingDiscoveries   []discoveryNotification
+	discoveryFlushArmed  bool
+	failedDiscoveryCount int64
+	// visibilityFilter decides which devices the NBI reads expose to a caller; see
+	// device_visibility.go
+	visibilityFilter deviceVisibilityFilter
 }
 
 func newDeviceManager(core *Core) *DeviceManager {
@@ -61,30 +172,148 @@ func newDeviceManager(core *Core) *DeviceManager {
 	deviceMgr.exitChannel = make(chan int, 1)
 	deviceMgr.rootDevices = make(map[string]bool)
 	deviceMgr.kafkaICProxy = core.kmp
-	deviceMgr.adapterProxy = NewAdapterProxy(core.kmp, core.config.CorePairTopic)
+	adapterProxy := NewAdapterProxy(core.kmp, core.config.CorePairTopic)
+	deviceMgr.adapterProxy = adapterProxy
+	if core.config.EnableChaosInjection {
+		// Wrap the adapter requests with the chaos injection hooks; inert until rules are scripted
+		deviceMgr.adapterProxy = newChaosAdapterService(adapterProxy, defaultChaosInjector)
+	}
+	deviceMgr.deviceTopicMgr = newDeviceTopicManager(adapterProxy, core.kafkaClient, core.clusterDataProxy,
+		core.config.DeviceTopicPolicy, core.config.DeviceTopicDeviceTypes, core.config.DeviceTopicMsgRate)
+	adapterProxy.deviceTopicMgr = deviceMgr.deviceTopicMgr
+	adapterProxy.breakers = newCircuitBreakerRegistry(core.config.BreakerFailureThreshold, core.config.BreakerResetInterval)
+	adapterProxy.breakers.onStateChange = func(target string, previous breakerState, current breakerState) {
+		// An operator watching the event stream learns an adapter stopped answering long
+		// before the first complaint about a device times out
+		if deviceMgr.eventMgr != nil {
+			deviceMgr.eventMgr.publishDeviceEvent(&voltha.Device{Id: target}, adapterBreakerEvent, map[string]string{
+				"target":         target,
+				"previous-state": previous.String(),
+				"current-state":  current.String(),
+			})
+		}
+	}
+	deviceMgr.pendingRequests = adapterProxy.pendingRequests
+	deviceMgr.eventMgr = core.eventMgr
 	deviceMgr.coreInstanceID = core.instanceID
+	deviceMgr.interCoreProxy = newInterCoreProxy(core.kmp, &deviceMgr, core.instanceID, core.config.CoreTopic,
+		time.Duration(core.config.DefaultCoreTimeout)*time.Millisecond)
 	deviceMgr.clusterDataProxy = core.clusterDataProxy
 	deviceMgr.adapterMgr = core.adapterMgr
 	deviceMgr.lockRootDeviceMap = sync.RWMutex{}
 	deviceMgr.defaultTimeout = time.Duration(core.config.DefaultCoreTimeout) * time.Millisecond
 	deviceMgr.devicesLoadingLock = sync.RWMutex{}
 	deviceMgr.deviceLoadingInProgress = make(map[string][]chan int)
+	deviceMgr.relativesPrefetching = make(map[string]struct{})
+	deviceMgr.softDeleteRetention = core.config.SoftDeleteRetention
+	deviceMgr.permissiveFlowValidation = core.config.PermissiveFlowValidation
+	deviceMgr.permissiveStatusValidation = core.config.PermissiveStatusValidation
+	deviceMgr.rejectFlowsAtCapacity = core.config.RejectFlowsAtCapacity
+	deviceMgr.skipFlowPortValidation = core.config.SkipFlowPortValidation
+	deviceMgr.packetInBufferWindow = core.config.PacketInBufferWindow
+	deviceMgr.skipLoadingDeletedAgents = core.config.SkipLoadingDeletedAgents
+	deviceMgr.kvClient = core.kvClient
+	deviceMgr.kvStorePrefix = core.config.KVStoreDataPrefix
+	deviceMgr.stuckSweepInterval = core.config.StuckDeviceSweepInterval
+	deviceMgr.clock = realClock{}
+	deviceMgr.reconcileConcurrency = core.config.ReconcileConcurrency
+	deviceMgr.reconcileStartDelay = core.config.ReconcileStartDelay
+	if level, err := parseReadConsistency(core.config.ReadConsistency); err != nil {
+		logger.Warnw("invalid-read-consistency-level-using-default", log.Fields{"level": core.config.ReadConsistency})
+		deviceMgr.readConsistency = readConsistencyDefault
+	} else {
+		deviceMgr.readConsistency = level
+	}
+	deviceMgr.staleReadThreshold = core.config.StaleReadThreshold
+	deviceMgr.activationWorkflows = make(map[string][]string)
+	deviceMgr.childCascadePolicies = make(map[string]childCascadePolicy)
+	deviceMgr.imageDownloadRetention = core.config.ImageDownloadRetention
+	deviceMgr.imageDownloadMaxRecords = core.config.ImageDownloadMaxRecords
+	deviceMgr.stateHistorySize = core.config.StateHistorySize
+	deviceMgr.stateHistoryPersist = core.config.StateHistoryPersist
+	deviceMgr.snapshotCadence = core.config.DeviceSnapshotCadence
+	deviceMgr.footprintWarnBytes = core.config.DeviceFootprintWarnBytes
+	deviceMgr.footprintLimitBytes = core.config.DeviceFootprintLimitBytes
+	deviceMgr.imagePreflight = core.config.ImagePreflight
+	deviceMgr.imageChecksumRequired = core.config.ImageChecksumRequired
+	deviceMgr.activityPersistInterval = core.config.ActivityPersistInterval
+	deviceMgr.quietDeviceThreshold = core.config.QuietDeviceThreshold
+	deviceMgr.discoveryBatchWindow = core.config.DiscoveryBatchWindow
+	deviceMgr.discoveryBatchSize = core.config.DiscoveryBatchSize
+	deviceMgr.perDeviceDiscovery = core.config.PerDeviceDiscovery
+	if core.config.EnableTenantFiltering {
+		deviceMgr.visibilityFilter = tenantVisibility{}
+	} else {
+		deviceMgr.visibilityFilter = passThroughVisibility{}
+	}
+	deviceMgr.operationQuotaDefault = quotaLimit{RatePerMinute: core.config.OperationQuotaRate, Burst: core.config.OperationQuotaBurst}
+	deviceMgr.operationQuotaLimits = make(map[string]quotaLimit)
+	deviceMgr.operationQuotaBuckets = make(map[string]*quotaBucket)
+	deviceMgr.operationQuotaGranted = make(map[string]int64)
+	deviceMgr.operationQuotaRejected = make(map[string]int64)
+	deviceMgr.parkUnreachableRequests = core.config.ParkUnreachableRequests
+	deviceMgr.flowParkingWindow = core.config.FlowParkingWindow
+	deviceMgr.flowParkingMax = core.config.FlowParkingMax
+	deviceMgr.deviceAuditInterval = core.config.DeviceAuditInterval
+	deviceMgr.deviceAuditRepair = core.config.DeviceAuditRepair
+	deviceMgr.deletionBarrierTimeout = core.config.DeletionBarrierTimeout
+	deviceMgr.diagnostics = newDiagnosticsCollector(core.config.DiagnosticsStallThreshold)
+	deviceMgr.registerDiagnostics()
+	deviceMgr.diagnostics.register("adapter-proxy", adapterProxy.diagnostics(core.config.DiagnosticsStallThreshold))
+	deviceMgr.diagnostics.register("event-manager", core.eventMgr.diagnostics)
+	deviceMgr.runtimeCfg = newRuntimeConfigManager(core.clusterDataProxy)
+	deviceMgr.idempotency = newIdempotencyManager(core.clusterDataProxy)
+	deviceMgr.activationTracker = newActivationTracker(core.clusterDataProxy)
+	deviceMgr.registerRuntimeSettings()
+	deviceMgr.pendingPacketIns = make(map[string][]pendingPacketIn)
+	deviceMgr.packetStats = newPacketStatsManager(core.clusterDataProxy, core.eventMgr)
 	return &deviceMgr
 }
 
-func (dMgr *DeviceManager) start(ctx context.Context, logicalDeviceMgr *LogicalDeviceManager) {
+func (dMgr *DeviceManager) start(ctx context.Context, logicalDeviceMgr logicalDeviceService) {
 	logger.Info("starting-device-manager")
 	dMgr.logicalDeviceMgr = logicalDeviceMgr
 	dMgr.stateTransitions = NewTransitionMap(dMgr)
+	if dMgr.softDeleteRetention > 0 {
+		go dMgr.runTombstoneReaper(ctx)
+	}
+	go dMgr.packetStats.run(ctx, dMgr.exitChannel)
+	go dMgr.monitorClusterEpoch(ctx)
+	go dMgr.sweepStaleDeviceTopics(ctx)
+	if dMgr.stuckSweepInterval > 0 {
+		go dMgr.runStuckDeviceSweeper(ctx)
+	}
+	if dMgr.deviceAuditInterval > 0 {
+		go dMgr.runDeviceAudit(ctx)
+	}
+	if dMgr.imageDownloadRetention > 0 || dMgr.imageDownloadMaxRecords > 0 {
+		go dMgr.runImageDownloadSweeper(ctx)
+	}
+	if dMgr.activityPersistInterval > 0 {
+		go dMgr.runActivityPersister(ctx)
+	}
+	if dMgr.kvClient != nil {
+		go dMgr.runOwnershipRenewer(ctx)
+	}
+	go dMgr.runtimeCfg.run(ctx, dMgr.exitChannel)
+	go dMgr.auditPeerParentage(ctx)
+	// One-shot consistency pass continuing any delete a previous run left half-done
+	go dMgr.resumeStuckDeletions(ctx)
 	probe.UpdateStatusFromContext(ctx, "device-manager", probe.ServiceStatusRunning)
 	logger.Info("device-manager-started")
 }
 
 func (dMgr *DeviceManager) stop(ctx context.Context) {
-	logger.Info("stopping-device-manager")
-	dMgr.exitChannel <- 1
-	probe.UpdateStatusFromContext(ctx, "device-manager", probe.ServiceStatusStopped)
-	logger.Info("device-manager-stopped")
+	dMgr.stopOnce.Do(func() {
+		logger.Info("stopping-device-manager")
+		// A final snapshot per device lets the taking-over core warm up from the KV store
+		dMgr.snapshotAllAgents(ctx)
+		// Closing broadcasts the stop to every goroutine selecting on the channel -
+		// renewers, sweepers, flushers - where the old single send woke only one of them
+		close(dMgr.exitChannel)
+		probe.UpdateStatusFromContext(ctx, "device-manager", probe.ServiceStatusStopped)
+		logger.Info("device-manager-stopped")
+	})
 }
 
 func sendResponse(ctx context.Context, ch chan interface{}, result interface{}) {
@@ -99,18 +328,28 @@ func sendResponse(ctx context.Context, ch chan interface{}, result interface{})
 	}
 }
 
-func (dMgr *DeviceManager) addDeviceAgentToMap(agent *DeviceAgent) {
-	if _, exist := dMgr.deviceAgents.Load(agent.deviceID); !exist {
-		dMgr.deviceAgents.Store(agent.deviceID, agent)
+// addDeviceAgentToMap registers the agent as the one managing its device and returns the
+// agent the caller must continue with.  Concurrent registrations for the same device - a
+// create racing a reconcile - are decided atomically here: the first agent in wins, and a
+// losing agent is discarded with its resources released.  The loser must never go through
+// stop(), which would remove the winner's device from the KV store.
+func (dMgr *DeviceManager) addDeviceAgentToMap(agent *DeviceAgent) *DeviceAgent {
+	actual, lost := dMgr.deviceAgents.LoadOrStore(agent.deviceID, agent)
+	winner := actual.(*DeviceAgent)
+	if lost {
+		agent.discard()
+		return winner
 	}
+	dMgr.reserveDeviceOwnership(context.Background(), agent.deviceID)
 	dMgr.lockRootDeviceMap.Lock()
 	defer dMgr.lockRootDeviceMap.Unlock()
 	dMgr.rootDevices[agent.deviceID] = agent.isRootdevice
-
+	return winner
 }
 
 func (dMgr *DeviceManager) deleteDeviceAgentFromMap(agent *DeviceAgent) {
 	dMgr.deviceAgents.Delete(agent.deviceID)
+	dMgr.releaseDeviceOwnership(agent.deviceID)
 	dMgr.lockRootDeviceMap.Lock()
 	defer dMgr.lockRootDeviceMap.Unlock()
 	delete(dMgr.rootDevices, agent.deviceID)
@@ -149,18 +388,45 @@ func (dMgr *DeviceManager) listDeviceIdsFromMap() *voltha.IDs {
 }
 
 func (dMgr *DeviceManager) createDevice(ctx context.Context, device *voltha.Device, ch chan interface{}) {
+	ctx = withLogContext(ctx, "create-device", device.Id)
+	if err := dMgr.checkOperationQuota(ctx, "createDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if err := dMgr.checkWritable(ctx, "createDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if key := idempotencyKeyFromContext(ctx); key != "" && dMgr.idempotency != nil {
+		deviceID, reserved, err := dMgr.reserveCreateDeviceID(ctx, key)
+		if err != nil {
+			sendResponse(ctx, ch, err)
+			return
+		}
+		if reserved {
+			if existing, err := dMgr.GetDevice(ctx, deviceID); err == nil {
+				ctxInfow(ctx, "serving-repeated-create-device-from-idempotency-record", log.Fields{"device-id": deviceID})
+				sendResponse(ctx, ch, existing)
+				return
+			}
+			// The key was reserved but the creation never completed; redo it under the
+			// reserved ID so the retry cannot mint a second device
+		}
+		device.Id = deviceID
+	}
+	dMgr.stampTenantAnnotation(ctx, device)
 	deviceExist, err := dMgr.isParentDeviceExist(ctx, device)
 	if err != nil {
-		logger.Errorf("Failed to fetch parent device info")
+		ctxErrorw(ctx, "Failed to fetch parent device info", nil)
 		sendResponse(ctx, ch, err)
 		return
 	}
 	if deviceExist {
-		logger.Errorf("Device is Pre-provisioned already with same IP-Port or MAC Address")
+		ctxErrorw(ctx, "Device is Pre-provisioned already with same IP-Port or MAC Address", nil)
 		sendResponse(ctx, ch, errors.New("Device is already pre-provisioned"))
 		return
 	}
-	logger.Debugw("createDevice", log.Fields{"device": device, "aproxy": dMgr.adapterProxy})
+	ctxDebugw(ctx, "createDevice", log.Fields{"device": device, "aproxy": dMgr.adapterProxy})
 
 	// Ensure this device is set as root
 	device.Root = true
@@ -168,65 +434,228 @@ func (dMgr *DeviceManager) createDevice(ctx context.Context, device *voltha.Devi
 	agent := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
 	device, err = agent.start(ctx, device)
 	if err != nil {
-		logger.Errorw("Fail-to-start-device", log.Fields{"device-id": agent.deviceID, "error": err})
+		ctxErrorw(ctx, "Fail-to-start-device", log.Fields{"device-id": agent.deviceID, "error": err})
+		sendResponse(ctx, ch, err)
+		return
+	}
+	agent = dMgr.addDeviceAgentToMap(agent)
+	if device, err = agent.getDevice(ctx); err != nil {
 		sendResponse(ctx, ch, err)
 		return
 	}
-	dMgr.addDeviceAgentToMap(agent)
 
 	sendResponse(ctx, ch, device)
 }
 
+// forwardIfPeerOwned proxies the request to the owning core instance when the device is
+// owned by a peer.  It returns false when the device is local and the request should be
+// served here.
+func (dMgr *DeviceManager) forwardIfPeerOwned(ctx context.Context, rpc string, deviceID string) (error, bool) {
+	owner := dMgr.interCoreProxy.ownerOf(deviceID)
+	if owner == "" {
+		return nil, false
+	}
+	return dMgr.interCoreProxy.forwardRequest(ctx, rpc, owner, deviceID), true
+}
+
 func (dMgr *DeviceManager) enableDevice(ctx context.Context, id *voltha.ID, ch chan interface{}) {
-	logger.Debugw("enableDevice", log.Fields{"deviceid": id})
+	ctx = withLogContext(ctx, "enable-device", id.Id)
+	ctxDebugw(ctx, "enableDevice", nil)
+	if err := dMgr.checkOperationQuota(ctx, "enableDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if err := dMgr.checkWritable(ctx, "enableDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	dMgr.markDeviceActivity(ctx, id.Id, activityNBI)
+	// Track when the enable was requested so the activation latency can be reported, and allow
+	// the post-activation handler to run again for this enable cycle
+	dMgr.activationStartTimes.Store(id.Id, time.Now())
+	dMgr.activatedDevices.Delete(id.Id)
+	if err, forwarded := dMgr.forwardIfPeerOwned(ctx, "enableDevice", id.Id); forwarded {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if res, repeated := dMgr.repeatedRequestResult(ctx, "enableDevice", id.Id); repeated {
+		sendResponse(ctx, ch, res)
+		return
+	}
 	var res interface{}
 	if agent := dMgr.getDeviceAgent(ctx, id.Id); agent != nil {
 		res = agent.enableDevice(ctx)
-		logger.Debugw("EnableDevice-result", log.Fields{"result": res})
+		ctxDebugw(ctx, "EnableDevice-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", id.Id)
+		res = newNotFoundError("device", id.Id)
 	}
+	dMgr.storeRequestResult(ctx, "enableDevice", id.Id, res)
 
 	sendResponse(ctx, ch, res)
 }
 
 func (dMgr *DeviceManager) disableDevice(ctx context.Context, id *voltha.ID, ch chan interface{}) {
-	logger.Debugw("disableDevice", log.Fields{"deviceid": id})
+	ctx = withLogContext(ctx, "disable-device", id.Id)
+	ctxDebugw(ctx, "disableDevice", nil)
+	if err := dMgr.checkOperationQuota(ctx, "disableDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if err := dMgr.checkWritable(ctx, "disableDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	dMgr.markDeviceActivity(ctx, id.Id, activityNBI)
+	if err, forwarded := dMgr.forwardIfPeerOwned(ctx, "disableDevice", id.Id); forwarded {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if res, repeated := dMgr.repeatedRequestResult(ctx, "disableDevice", id.Id); repeated {
+		sendResponse(ctx, ch, res)
+		return
+	}
 	var res interface{}
 	if agent := dMgr.getDeviceAgent(ctx, id.Id); agent != nil {
 		res = agent.disableDevice(ctx)
-		logger.Debugw("disableDevice-result", log.Fields{"result": res})
+		ctxDebugw(ctx, "disableDevice-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", id.Id)
+		res = newNotFoundError("device", id.Id)
 	}
+	dMgr.storeRequestResult(ctx, "disableDevice", id.Id, res)
 
 	sendResponse(ctx, ch, res)
 }
 
 func (dMgr *DeviceManager) rebootDevice(ctx context.Context, id *voltha.ID, ch chan interface{}) {
-	logger.Debugw("rebootDevice", log.Fields{"deviceid": id})
+	ctx = withLogContext(ctx, "reboot-device", id.Id)
+	ctxDebugw(ctx, "rebootDevice", nil)
+	if err := dMgr.checkOperationQuota(ctx, "rebootDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if err := dMgr.checkWritable(ctx, "rebootDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	dMgr.markDeviceActivity(ctx, id.Id, activityNBI)
+	if err, forwarded := dMgr.forwardIfPeerOwned(ctx, "rebootDevice", id.Id); forwarded {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if res, repeated := dMgr.repeatedRequestResult(ctx, "rebootDevice", id.Id); repeated {
+		sendResponse(ctx, ch, res)
+		return
+	}
 	var res interface{}
 	if agent := dMgr.getDeviceAgent(ctx, id.Id); agent != nil {
+		// A reboot is pointless to replay once the device is back; suppress without parking
+		if err := dMgr.suppressIfUnreachable(ctx, agent, "reboot-device", nil); err != nil {
+			sendResponse(ctx, ch, err)
+			return
+		}
 		res = agent.rebootDevice(ctx)
-		logger.Debugw("rebootDevice-result", log.Fields{"result": res})
+		ctxDebugw(ctx, "rebootDevice-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", id.Id)
+		res = newNotFoundError("device", id.Id)
 	}
+	dMgr.storeRequestResult(ctx, "rebootDevice", id.Id, res)
 	sendResponse(ctx, ch, res)
 }
 
 func (dMgr *DeviceManager) deleteDevice(ctx context.Context, id *voltha.ID, ch chan interface{}) {
-	logger.Debugw("deleteDevice", log.Fields{"deviceid": id})
+	ctx = withLogContext(ctx, "delete-device", id.Id)
+	ctxDebugw(ctx, "deleteDevice", nil)
+	if err := dMgr.checkOperationQuota(ctx, "deleteDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if err := dMgr.checkWritable(ctx, "deleteDevice"); err != nil {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	dMgr.markDeviceActivity(ctx, id.Id, activityNBI)
+	if err, forwarded := dMgr.forwardIfPeerOwned(ctx, "deleteDevice", id.Id); forwarded {
+		sendResponse(ctx, ch, err)
+		return
+	}
+	if res, repeated := dMgr.repeatedRequestResult(ctx, "deleteDevice", id.Id); repeated {
+		sendResponse(ctx, ch, res)
+		return
+	}
 	var res interface{}
-	if agent := dMgr.getDeviceAgent(ctx, id.Id); agent != nil {
+	if intent, interrupted := dMgr.getDeletionIntent(ctx, id.Id); interrupted {
+		// An earlier delete never finished; continue it instead of starting over
+		res = dMgr.resumeDeletion(ctx, intent)
+		ctxDebugw(ctx, "deleteDevice-resumed", log.Fields{"result": res})
+	} else if agent := dMgr.getDeviceAgent(ctx, id.Id); agent != nil {
 		res = agent.deleteDevice(ctx)
-		logger.Debugw("deleteDevice-result", log.Fields{"result": res})
+		ctxDebugw(ctx, "deleteDevice-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", id.Id)
+		res = newNotFoundError("device", id.Id)
 	}
+	dMgr.storeRequestResult(ctx, "deleteDevice", id.Id, res)
 	sendResponse(ctx, ch, res)
 }
 
+// eventSuppressionMarker prefixes the suppression window annotation kept in the device details
+const eventSuppressionMarker = "event-suppression-until="
+
+// stripEventSuppressionMarker removes any suppression window annotation from the device extra arguments
+func stripEventSuppressionMarker(extraArgs string) string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(extraArgs, ";") {
+		if part != "" && !strings.HasPrefix(part, eventSuppressionMarker) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// suppressEvents opens an event suppression window on a device.  Events raised by the device or its
+// children during the window are suppressed; the window is recorded in the device details and survives
+// a core restart.  An empty category list suppresses all the categories.
+func (dMgr *DeviceManager) suppressEvents(ctx context.Context, deviceID string, duration time.Duration, categories []string) error {
+	logger.Infow("suppressEvents", log.Fields{"deviceId": deviceID, "duration": duration, "categories": categories})
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	expiry, err := dMgr.eventMgr.suppressEvents(ctx, deviceID, duration, categories)
+	if err != nil {
+		return err
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	// Make the suppression window visible in the device details
+	extraArgs := stripEventSuppressionMarker(device.ExtraArgs)
+	if extraArgs != "" {
+		extraArgs += ";"
+	}
+	extraArgs += eventSuppressionMarker + expiry.Format(time.RFC3339)
+	agent.updateDeviceAttribute(ctx, "ExtraArgs", extraArgs)
+	return nil
+}
+
+// unsuppressEvents removes the event suppression window of a device, restoring normal event publication
+func (dMgr *DeviceManager) unsuppressEvents(ctx context.Context, deviceID string) error {
+	logger.Infow("unsuppressEvents", log.Fields{"deviceId": deviceID})
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	if err := dMgr.eventMgr.unsuppressEvents(ctx, deviceID); err != nil {
+		return err
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	agent.updateDeviceAttribute(ctx, "ExtraArgs", stripEventSuppressionMarker(device.ExtraArgs))
+	return nil
+}
+
 // stopManagingDevice stops the management of the device as well as any of its reference device and logical device.
 // This function is called only in the Core that does not own this device.  In the Core that owns this device then a
 // deletion deletion also includes removal of any reference of this device.
@@ -238,11 +667,15 @@ func (dMgr *DeviceManager) stopManagingDevice(ctx context.Context, id string) {
 			_ = dMgr.logicalDeviceMgr.stopManagingLogicalDeviceWithDeviceID(ctx, id)
 		}
 		if agent := dMgr.getDeviceAgent(ctx, id); agent != nil {
+			// Preserve the device under a tombstone before its record is removed from the model
+			dMgr.tombstoneDevice(ctx, id)
 			if err := agent.stop(ctx); err != nil {
 				logger.Warnw("unable-to-stop-device-agent", log.Fields{"device-id": agent.deviceID, "error": err})
 			}
 			dMgr.deleteDeviceAgentFromMap(agent)
 		}
+		// Tear down the dedicated request topic of the device, if one was assigned
+		dMgr.deviceTopicMgr.releaseDeviceTopic(ctx, id)
 	}
 }
 
@@ -250,35 +683,51 @@ func (dMgr *DeviceManager) stopManagingDevice(ctx context.Context, id string) {
 func (dMgr *DeviceManager) RunPostDeviceDelete(ctx context.Context, cDevice *voltha.Device) error {
 	logger.Infow("RunPostDeviceDelete", log.Fields{"deviceId": cDevice.Id})
 	dMgr.stopManagingDevice(ctx, cDevice.Id)
+	// The cleanup chain completed; the delete no longer needs to be resumable
+	dMgr.clearDeletionIntent(ctx, cDevice.Id)
 	return nil
 }
 
-// GetDevice will returns a device, either from memory or from the dB, if present
+// GetDevice will returns a device, either from memory or from the dB, if present.  The
+// read is served at the configured default consistency level; callers needing a specific
+// level use getDeviceWithConsistency directly.
 func (dMgr *DeviceManager) GetDevice(ctx context.Context, id string) (*voltha.Device, error) {
 	logger.Debugw("GetDevice", log.Fields{"deviceid": id})
-	if agent := dMgr.getDeviceAgent(ctx, id); agent != nil {
-		return agent.getDevice(ctx)
+	device, err := dMgr.getDeviceWithConsistency(ctx, id, dMgr.readConsistency)
+	if err != nil {
+		return nil, err
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", id)
+	if !dMgr.deviceVisible(ctx, device) {
+		// a hidden device is reported as missing so its existence does not leak
+		return nil, newNotFoundError("device", id)
+	}
+	return device, nil
 }
 
-// GetChildDevice will return a device, either from memory or from the dB, if present
+// GetChildDevice will return a device, either from memory or from the dB, if present.
+// The failure modes are distinct so the caller can tell them apart: InvalidArgument when
+// neither the serial number nor the onu id is supplied, FailedPrecondition when the
+// parent has no children at all (the child was never created), and NotFound carrying the
+// child identity when children exist but none matches (likely the wrong parent).
 func (dMgr *DeviceManager) GetChildDevice(ctx context.Context, parentDeviceID string, serialNumber string, onuID int64, parentPortNo int64) (*voltha.Device, error) {
 	logger.Debugw("GetChildDevice", log.Fields{"parentDeviceid": parentDeviceID, "serialNumber": serialNumber,
 		"parentPortNo": parentPortNo, "onuId": onuID})
 
+	if serialNumber == "" && onuID <= 0 {
+		return nil, newInvalidArgumentError("either-serial-number-or-onu-id-must-be-provided")
+	}
 	var parentDevice *voltha.Device
 	var err error
 	if parentDevice, err = dMgr.GetDevice(ctx, parentDeviceID); err != nil {
-		return nil, status.Errorf(codes.Aborted, "%s", err.Error())
+		return nil, err
 	}
 	var childDeviceIds []string
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentDevice); err != nil {
-		return nil, status.Errorf(codes.Aborted, "%s", err.Error())
+		return nil, err
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-devices", log.Fields{"parentDeviceId": parentDevice.Id, "serialNumber": serialNumber, "onuId": onuID})
-		return nil, status.Errorf(codes.NotFound, "%s", parentDeviceID)
+		return nil, newFailedPreconditionError("parent-device", parentDeviceID, "no-child-devices")
 	}
 
 	var foundChildDevice *voltha.Device
@@ -322,7 +771,7 @@ func (dMgr *DeviceManager) GetChildDevice(ctx context.Context, parentDeviceID st
 
 	logger.Warnw("child-device-not-found", log.Fields{"parentDeviceId": parentDevice.Id,
 		"serialNumber": serialNumber, "onuId": onuID, "parentPortNo": parentPortNo})
-	return nil, status.Errorf(codes.NotFound, "%s", parentDeviceID)
+	return nil, newNotFoundError("child-device", fmt.Sprintf("serial:%s onu:%d", serialNumber, onuID))
 }
 
 // GetChildDeviceWithProxyAddress will return a device based on proxy address
@@ -332,21 +781,22 @@ func (dMgr *DeviceManager) GetChildDeviceWithProxyAddress(ctx context.Context, p
 	var parentDevice *voltha.Device
 	var err error
 	if parentDevice, err = dMgr.GetDevice(ctx, proxyAddress.DeviceId); err != nil {
-		return nil, status.Errorf(codes.Aborted, "%s", err.Error())
+		// The named parent may be stale after a migration; try the parent-agnostic lookup
+		return dMgr.findChildByProxyAddressFallback(ctx, proxyAddress)
 	}
 	var childDeviceIds []string
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentDevice); err != nil {
-		return nil, status.Errorf(codes.Aborted, "%s", err.Error())
+		return nil, err
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-devices", log.Fields{"parentDeviceId": parentDevice.Id})
-		return nil, status.Errorf(codes.NotFound, "%s", proxyAddress)
+		return dMgr.findChildByProxyAddressFallback(ctx, proxyAddress)
 	}
 
 	var foundChildDevice *voltha.Device
 	for _, childDeviceID := range childDeviceIds {
 		if searchDevice, err := dMgr.GetDevice(ctx, childDeviceID); err == nil {
-			if searchDevice.ProxyAddress == proxyAddress {
+			if proxyAddressMatches(searchDevice.ProxyAddress, proxyAddress) {
 				foundChildDevice = searchDevice
 				break
 			}
@@ -359,7 +809,7 @@ func (dMgr *DeviceManager) GetChildDeviceWithProxyAddress(ctx context.Context, p
 	}
 
 	logger.Warnw("child-device-not-found", log.Fields{"proxyAddress": proxyAddress})
-	return nil, status.Errorf(codes.NotFound, "%s", proxyAddress)
+	return dMgr.findChildByProxyAddressFallback(ctx, proxyAddress)
 }
 
 // IsDeviceInCache returns true if device is found in the map
@@ -398,15 +848,19 @@ func (dMgr *DeviceManager) ListDevices(ctx context.Context) (*voltha.Devices, er
 				logger.Warnw("failure-starting-agent", log.Fields{"deviceId": device.Id})
 			} else {
 				dMgr.addDeviceAgentToMap(agent)
+				dMgr.deviceTopicMgr.restoreDeviceTopic(ctx, device.Id)
 			}
 		}
+		if !dMgr.deviceVisible(ctx, device) {
+			continue
+		}
 		result.Items = append(result.Items, device)
 	}
 	logger.Debugw("ListDevices-end", log.Fields{"len": len(result.Items)})
 	return result, nil
 }
 
-//isParentDeviceExist checks whether device is already preprovisioned.
+// isParentDeviceExist checks whether device is already preprovisioned.
 func (dMgr *DeviceManager) isParentDeviceExist(ctx context.Context, newDevice *voltha.Device) (bool, error) {
 	hostPort := newDevice.GetHostAndPort()
 	var devices []*voltha.Device
@@ -428,23 +882,34 @@ func (dMgr *DeviceManager) isParentDeviceExist(ctx context.Context, newDevice *v
 	return false, nil
 }
 
-//getDeviceFromModelretrieves the device data from the model.
+// getDeviceFromModelretrieves the device data from the model.
 func (dMgr *DeviceManager) getDeviceFromModel(ctx context.Context, deviceID string) (*voltha.Device, error) {
 	device := &voltha.Device{}
 	if have, err := dMgr.clusterDataProxy.Get(ctx, "devices/"+deviceID, device); err != nil {
 		logger.Errorw("failed-to-get-device-info-from-cluster-proxy", log.Fields{"error": err})
 		return nil, err
 	} else if !have {
-		return nil, status.Error(codes.NotFound, deviceID)
+		return nil, newNotFoundError("device", deviceID)
 	}
 
 	return device, nil
 }
 
+// shouldCreateAgentOnLoad indicates whether a device found in the model warrants an in-memory
+// agent.  Preprovisioned devices get one - they are cheap to hold and keeping them visible lets
+// an enable right after a core restart take the fast path - while deleted devices are on their
+// way out of the model and are not resurrected unless the gating is disabled
+func (dMgr *DeviceManager) shouldCreateAgentOnLoad(device *voltha.Device) bool {
+	if dMgr.skipLoadingDeletedAgents && device.AdminState == voltha.AdminState_DELETED {
+		return false
+	}
+	return true
+}
+
 // loadDevice loads the deviceID in memory, if not present
 func (dMgr *DeviceManager) loadDevice(ctx context.Context, deviceID string) (*DeviceAgent, error) {
 	if deviceID == "" {
-		return nil, status.Error(codes.InvalidArgument, "deviceId empty")
+		return nil, newInvalidArgumentError("device-id-empty")
 	}
 	var err error
 	var device *voltha.Device
@@ -455,12 +920,17 @@ func (dMgr *DeviceManager) loadDevice(ctx context.Context, deviceID string) (*De
 			dMgr.devicesLoadingLock.Unlock()
 			// Proceed with the loading only if the device exist in the Model (could have been deleted)
 			if device, err = dMgr.getDeviceFromModel(ctx, deviceID); err == nil {
-				logger.Debugw("loading-device", log.Fields{"deviceId": deviceID})
-				agent := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
-				if _, err = agent.start(ctx, nil); err != nil {
-					logger.Warnw("Failure loading device", log.Fields{"deviceId": deviceID, "error": err})
+				if !dMgr.shouldCreateAgentOnLoad(device) {
+					logger.Debugw("not-recreating-agent-for-deleted-device", log.Fields{"deviceId": deviceID})
 				} else {
-					dMgr.addDeviceAgentToMap(agent)
+					logger.Debugw("loading-device", log.Fields{"deviceId": deviceID})
+					agent := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
+					if _, err = agent.start(ctx, nil); err != nil {
+						logger.Warnw("Failure loading device", log.Fields{"deviceId": deviceID, "error": err})
+					} else {
+						dMgr.addDeviceAgentToMap(agent)
+						dMgr.deviceTopicMgr.restoreDeviceTopic(ctx, deviceID)
+					}
 				}
 			} else {
 				logger.Debugw("Device not in model", log.Fields{"deviceId": deviceID})
@@ -487,7 +957,7 @@ func (dMgr *DeviceManager) loadDevice(ctx context.Context, deviceID string) (*De
 	if agent, ok := dMgr.deviceAgents.Load(deviceID); ok {
 		return agent.(*DeviceAgent), nil
 	}
-	return nil, status.Errorf(codes.Aborted, "Error loading device %s", deviceID)
+	return nil, newUnavailableError("load-device", deviceID, nil)
 }
 
 // loadRootDeviceParentAndChildren loads the children and parents of a root device in memory
@@ -519,10 +989,11 @@ func (dMgr *DeviceManager) loadRootDeviceParentAndChildren(ctx context.Context,
 	return nil
 }
 
-// load loads the deviceId in memory, if not present, and also loads its accompanying parents and children.  Loading
-// in memory is for improved performance.  It is not imperative that a device needs to be in memory when a request
-// acting on the device is received by the core. In such a scenario, the Core will load the device in memory first
-// and the proceed with the request.
+// load loads the deviceId in memory, if not present, and triggers a background prefetch of its
+// accompanying parents and children.  Loading in memory is for improved performance.  It is not
+// imperative that a device needs to be in memory when a request acting on the device is received
+// by the core. In such a scenario, the Core will load the device in memory first and the proceed
+// with the request.
 func (dMgr *DeviceManager) load(ctx context.Context, deviceID string) error {
 	logger.Debug("load...")
 	// First load the device - this may fail in case the device was deleted intentionally by the other core
@@ -537,37 +1008,109 @@ func (dMgr *DeviceManager) load(ctx context.Context, deviceID string) error {
 		return err
 	}
 
-	// If the device is in Pre-provisioning or deleted state stop here
+	// A device whose type no adapter serves - typically left behind by an adapter
+	// downgrade - is marked and left out of the automated machinery instead of producing
+	// agents whose every operation fails deep in getAdapterName
+	if _, err := dMgr.adapterMgr.getAdapterName(device.Type); err != nil {
+		dMgr.flagAdapterNotAvailable(ctx, device)
+		return nil
+	}
+
+	// A preprovisioned device has an agent (so it shows up in ListDeviceIds and an enable
+	// after a restart takes the fast path) but no parent or children to load yet; a deleted
+	// device is never followed further
 	if device.AdminState == voltha.AdminState_PREPROVISIONED || device.AdminState == voltha.AdminState_DELETED {
 		return nil
 	}
 
-	// Now we face two scenarios
-	if device.Root {
-		// Load all children as well as the parent of this device (logical_device)
-		if err := dMgr.loadRootDeviceParentAndChildren(ctx, device); err != nil {
-			logger.Warnw("failure-loading-device-parent-and-children", log.Fields{"deviceId": deviceID})
-			return err
+	// Loading the rest of the tree can drag in a large number of sibling agents; do it in
+	// the background so the original caller only pays for its own device
+	dMgr.prefetchRelatives(device)
+	return nil
+}
+
+const (
+	relativesPrefetchRetries       = 3
+	relativesPrefetchRetryInterval = 5 * time.Second
+)
+
+// prefetchRelatives asynchronously loads the parent and children of a device.  Prefetches are
+// deduplicated per root device so repeated cache misses do not spawn multiple ones.  Failures
+// are logged and retried, never surfaced to the caller that triggered the load.
+func (dMgr *DeviceManager) prefetchRelatives(device *voltha.Device) {
+	// The tree of a child device hangs off its root; prefetch from there
+	rootID := device.Id
+	if !device.Root {
+		if device.ParentId == "" {
+			return
 		}
-		logger.Debugw("successfully-loaded-parent-and-children", log.Fields{"deviceId": deviceID})
-	} else {
-		//	Scenario B - use the parentId of that device (root device) to trigger the loading
-		if device.ParentId != "" {
-			return dMgr.load(ctx, device.ParentId)
+		rootID = device.ParentId
+	}
+	dMgr.relativesLoadingLock.Lock()
+	if _, inProgress := dMgr.relativesPrefetching[rootID]; inProgress {
+		dMgr.relativesLoadingLock.Unlock()
+		return
+	}
+	dMgr.relativesPrefetching[rootID] = struct{}{}
+	dMgr.relativesLoadingLock.Unlock()
+
+	go func() {
+		defer func() {
+			dMgr.relativesLoadingLock.Lock()
+			delete(dMgr.relativesPrefetching, rootID)
+			dMgr.relativesLoadingLock.Unlock()
+		}()
+		ctx := context.Background()
+		for attempt := 1; ; attempt++ {
+			err := dMgr.loadRelativesOfRoot(ctx, rootID)
+			if err == nil {
+				logger.Debugw("successfully-loaded-parent-and-children", log.Fields{"deviceId": rootID})
+				return
+			}
+			logger.Warnw("failure-loading-device-parent-and-children", log.Fields{"deviceId": rootID, "attempt": attempt, "error": err})
+			if attempt >= relativesPrefetchRetries {
+				return
+			}
+			select {
+			case <-time.After(relativesPrefetchRetryInterval):
+			case <-dMgr.exitChannel:
+				return
+			}
 		}
+	}()
+}
+
+// loadRelativesOfRoot loads a root device and then its parent logical device and children
+func (dMgr *DeviceManager) loadRelativesOfRoot(ctx context.Context, rootID string) error {
+	agent, err := dMgr.loadDevice(ctx, rootID)
+	if err != nil {
+		return err
 	}
-	return nil
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	if !device.Root || device.AdminState == voltha.AdminState_PREPROVISIONED || device.AdminState == voltha.AdminState_DELETED {
+		return nil
+	}
+	return dMgr.loadRootDeviceParentAndChildren(ctx, device)
 }
 
 // ListDeviceIds retrieves the latest device IDs information from the data model (memory data only)
-func (dMgr *DeviceManager) ListDeviceIds() (*voltha.IDs, error) {
+func (dMgr *DeviceManager) ListDeviceIds(ctx context.Context) (*voltha.IDs, error) {
 	logger.Debug("ListDeviceIDs")
-	// Report only device IDs that are in the device agent map
-	return dMgr.listDeviceIdsFromMap(), nil
+	// Report only device IDs that are in the device agent map and visible to the caller
+	result := &voltha.IDs{Items: make([]*voltha.ID, 0)}
+	for _, id := range dMgr.listDeviceIdsFromMap().Items {
+		if dMgr.deviceIDVisible(ctx, id.Id) {
+			result.Items = append(result.Items, id)
+		}
+	}
+	return result, nil
 }
 
-//ReconcileDevices is a request to a voltha core to update its list of managed devices.  This will
-//trigger loading the devices along with their children and parent in memory
+// ReconcileDevices is a request to a voltha core to update its list of managed devices.  This will
+// trigger loading the devices along with their children and parent in memory
 func (dMgr *DeviceManager) ReconcileDevices(ctx context.Context, ids *voltha.IDs, ch chan interface{}) {
 	logger.Debugw("ReconcileDevices", log.Fields{"numDevices": len(ids.Items)})
 	var res interface{}
@@ -586,7 +1129,7 @@ func (dMgr *DeviceManager) ReconcileDevices(ctx context.Context, ids *voltha.IDs
 			res = status.Errorf(codes.DataLoss, "less-device-reconciled-than-requested:%d/%d", reconciled, toReconcile)
 		}
 	} else {
-		res = status.Errorf(codes.InvalidArgument, "empty-list-of-ids")
+		res = newInvalidArgumentError("empty-list-of-ids")
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -609,48 +1152,9 @@ func (dMgr *DeviceManager) adapterRestarted(ctx context.Context, adapter *voltha
 		return nil
 	}
 
-	responses := make([]utils.Response, 0)
-	for rootDeviceID := range dMgr.rootDevices {
-		if rootDevice, _ := dMgr.getDeviceFromModel(ctx, rootDeviceID); rootDevice != nil {
-			if rootDevice.Adapter == adapter.Id {
-				if isOkToReconcile(rootDevice) {
-					logger.Debugw("reconciling-root-device", log.Fields{"rootId": rootDevice.Id})
-					responses = append(responses, dMgr.sendReconcileDeviceRequest(ctx, rootDevice))
-				} else {
-					logger.Debugw("not-reconciling-root-device", log.Fields{"rootId": rootDevice.Id, "state": rootDevice.AdminState})
-				}
-			} else { // Should we be reconciling the root's children instead?
-			childManagedByAdapter:
-				for _, port := range rootDevice.Ports {
-					for _, peer := range port.Peers {
-						if childDevice, _ := dMgr.getDeviceFromModel(ctx, peer.DeviceId); childDevice != nil {
-							if childDevice.Adapter == adapter.Id {
-								if isOkToReconcile(childDevice) {
-									logger.Debugw("reconciling-child-device", log.Fields{"childId": childDevice.Id})
-									responses = append(responses, dMgr.sendReconcileDeviceRequest(ctx, childDevice))
-								} else {
-									logger.Debugw("not-reconciling-child-device", log.Fields{"childId": childDevice.Id, "state": childDevice.AdminState})
-								}
-							} else {
-								// All child devices under a parent device are typically managed by the same adapter type.
-								// Therefore we only need to check whether the first device we retrieved is managed by that adapter
-								break childManagedByAdapter
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-	if len(responses) > 0 {
-		// Wait for completion
-		if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, responses...); res != nil {
-			return status.Errorf(codes.Aborted, "errors-%s", res)
-		}
-	} else {
-		logger.Debugw("no-managed-device-to-reconcile", log.Fields{"adapterId": adapter.Id})
-	}
-	return nil
+	// The reconcile is paced - see adapter_reconcile_pacing.go - so a freshly restarted
+	// adapter is not flooded with the full device load at once
+	return dMgr.reconcileAdapterDevices(ctx, adapter.Id)
 }
 
 func (dMgr *DeviceManager) sendReconcileDeviceRequest(ctx context.Context, device *voltha.Device) utils.Response {
@@ -697,14 +1201,23 @@ func (dMgr *DeviceManager) reconcileChildDevices(ctx context.Context, parentDevi
 func (dMgr *DeviceManager) updateDeviceUsingAdapterData(ctx context.Context, device *voltha.Device) error {
 	logger.Debugw("updateDeviceUsingAdapterData", log.Fields{"deviceid": device.Id, "device": device})
 	if agent := dMgr.getDeviceAgent(ctx, device.Id); agent != nil {
+		dMgr.markDeviceActivity(ctx, device.Id, activityAdapter)
 		return agent.updateDeviceUsingAdapterData(ctx, device)
 	}
-	return status.Errorf(codes.NotFound, "%s", device.Id)
+	return newNotFoundError("device", device.Id)
 }
 
 func (dMgr *DeviceManager) addPort(ctx context.Context, deviceID string, port *voltha.Port) error {
 	agent := dMgr.getDeviceAgent(ctx, deviceID)
 	if agent != nil {
+		dMgr.markDeviceActivity(ctx, deviceID, activityAdapter)
+		device, err := dMgr.GetDevice(ctx, deviceID)
+		if err != nil {
+			return err
+		}
+		if err := dMgr.validatePortPeers(ctx, device, port); err != nil {
+			return err
+		}
 		if err := agent.addPort(ctx, port); err != nil {
 			return err
 		}
@@ -734,31 +1247,61 @@ func (dMgr *DeviceManager) addPort(ctx context.Context, deviceID string, port *v
 		}
 		return nil
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) addFlowsAndGroups(ctx context.Context, deviceID string, flows []*ofp.OfpFlowStats, groups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	logger.Debugw("addFlowsAndGroups", log.Fields{"deviceid": deviceID, "groups:": groups, "flowMetadata": flowMetadata})
+	ctx = withLogContext(ctx, "add-flows-and-groups", deviceID)
+	ctxDebugw(ctx, "addFlowsAndGroups", log.Fields{"groups:": groups, "flowMetadata": flowMetadata})
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
+		if err := dMgr.validateFlows(agent.deviceType, flows); err != nil {
+			return err
+		}
+		if err := dMgr.validateFlowPorts(ctx, agent, flows); err != nil {
+			return err
+		}
+		if err := dMgr.suppressIfUnreachable(ctx, agent, "add-flows-and-groups", func(ctx context.Context) error {
+			return dMgr.addFlowsAndGroups(ctx, deviceID, flows, groups, flowMetadata)
+		}); err != nil {
+			return err
+		}
 		return agent.addFlowsAndGroups(ctx, flows, groups, flowMetadata)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) deleteFlowsAndGroups(ctx context.Context, deviceID string, flows []*ofp.OfpFlowStats, groups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	logger.Debugw("deleteFlowsAndGroups", log.Fields{"deviceid": deviceID})
+	ctx = withLogContext(ctx, "delete-flows-and-groups", deviceID)
+	ctxDebugw(ctx, "deleteFlowsAndGroups", nil)
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
+		if err := dMgr.suppressIfUnreachable(ctx, agent, "delete-flows-and-groups", func(ctx context.Context) error {
+			return dMgr.deleteFlowsAndGroups(ctx, deviceID, flows, groups, flowMetadata)
+		}); err != nil {
+			return err
+		}
 		return agent.deleteFlowsAndGroups(ctx, flows, groups, flowMetadata)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) updateFlowsAndGroups(ctx context.Context, deviceID string, flows []*ofp.OfpFlowStats, groups []*ofp.OfpGroupEntry, flowMetadata *voltha.FlowMetadata) error {
-	logger.Debugw("updateFlowsAndGroups", log.Fields{"deviceid": deviceID})
+	ctx = withLogContext(ctx, "update-flows-and-groups", deviceID)
+	ctxDebugw(ctx, "updateFlowsAndGroups", nil)
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
+		if err := dMgr.validateFlows(agent.deviceType, flows); err != nil {
+			return err
+		}
+		if err := dMgr.validateFlowPorts(ctx, agent, flows); err != nil {
+			return err
+		}
+		if err := dMgr.suppressIfUnreachable(ctx, agent, "update-flows-and-groups", func(ctx context.Context) error {
+			return dMgr.updateFlowsAndGroups(ctx, deviceID, flows, groups, flowMetadata)
+		}); err != nil {
+			return err
+		}
 		return agent.updateFlowsAndGroups(ctx, flows, groups, flowMetadata)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 // updatePmConfigs updates the PM configs.  This is executed when the northbound gRPC API is invoked, typically
@@ -766,11 +1309,17 @@ func (dMgr *DeviceManager) updateFlowsAndGroups(ctx context.Context, deviceID st
 func (dMgr *DeviceManager) updatePmConfigs(ctx context.Context, pmConfigs *voltha.PmConfigs, ch chan interface{}) {
 	var res interface{}
 	if pmConfigs.Id == "" {
-		res = status.Errorf(codes.FailedPrecondition, "invalid-device-Id")
+		res = newInvalidArgumentError("invalid-device-id")
 	} else if agent := dMgr.getDeviceAgent(ctx, pmConfigs.Id); agent != nil {
-		res = agent.updatePmConfigs(ctx, pmConfigs)
+		if err := dMgr.suppressIfUnreachable(ctx, agent, "update-pm-configs", func(ctx context.Context) error {
+			return agent.updatePmConfigs(ctx, pmConfigs)
+		}); err != nil {
+			res = err
+		} else {
+			res = agent.updatePmConfigs(ctx, pmConfigs)
+		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", pmConfigs.Id)
+		res = newNotFoundError("device", pmConfigs.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -778,19 +1327,19 @@ func (dMgr *DeviceManager) updatePmConfigs(ctx context.Context, pmConfigs *volth
 // initPmConfigs initialize the pm configs as defined by the adapter.
 func (dMgr *DeviceManager) initPmConfigs(ctx context.Context, deviceID string, pmConfigs *voltha.PmConfigs) error {
 	if pmConfigs.Id == "" {
-		return status.Errorf(codes.FailedPrecondition, "invalid-device-Id")
+		return newInvalidArgumentError("invalid-device-id")
 	}
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.initPmConfigs(ctx, pmConfigs)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) listPmConfigs(ctx context.Context, deviceID string) (*voltha.PmConfigs, error) {
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.listPmConfigs(ctx)
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", deviceID)
+	return nil, newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) getSwitchCapability(ctx context.Context, deviceID string) (*ic.SwitchCapability, error) {
@@ -798,7 +1347,7 @@ func (dMgr *DeviceManager) getSwitchCapability(ctx context.Context, deviceID str
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.getSwitchCapability(ctx)
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", deviceID)
+	return nil, newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) getPorts(ctx context.Context, deviceID string, portType voltha.Port_PortType) (*voltha.Ports, error) {
@@ -806,23 +1355,27 @@ func (dMgr *DeviceManager) getPorts(ctx context.Context, deviceID string, portTy
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.getPorts(ctx, portType), nil
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", deviceID)
+	return nil, newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) getPortCapability(ctx context.Context, deviceID string, portNo uint32) (*ic.PortCapability, error) {
 	logger.Debugw("getPortCapability", log.Fields{"deviceid": deviceID})
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
-		return agent.getPortCapability(ctx, portNo)
+		return dMgr.getPortCapabilityWithFallback(ctx, agent, deviceID, portNo)
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", deviceID)
+	return nil, newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) updateDeviceStatus(ctx context.Context, deviceID string, operStatus voltha.OperStatus_Types, connStatus voltha.ConnectStatus_Types) error {
 	logger.Debugw("updateDeviceStatus", log.Fields{"deviceid": deviceID, "operStatus": operStatus, "connStatus": connStatus})
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
+		if err := dMgr.checkStatusUpdate(ctx, agent, operStatus, connStatus); err != nil {
+			return err
+		}
+		dMgr.markDeviceActivity(ctx, deviceID, activityAdapter)
 		return agent.updateDeviceStatus(ctx, operStatus, connStatus)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) updateChildrenStatus(ctx context.Context, deviceID string, operStatus voltha.OperStatus_Types, connStatus voltha.ConnectStatus_Types) error {
@@ -830,11 +1383,11 @@ func (dMgr *DeviceManager) updateChildrenStatus(ctx context.Context, deviceID st
 	var parentDevice *voltha.Device
 	var err error
 	if parentDevice, err = dMgr.GetDevice(ctx, deviceID); err != nil {
-		return status.Errorf(codes.Aborted, "%s", err.Error())
+		return err
 	}
 	var childDeviceIds []string
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentDevice); err != nil {
-		return status.Errorf(codes.Aborted, "%s", err.Error())
+		return err
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-device", log.Fields{"parentDeviceId": parentDevice.Id})
@@ -851,30 +1404,8 @@ func (dMgr *DeviceManager) updateChildrenStatus(ctx context.Context, deviceID st
 
 func (dMgr *DeviceManager) updatePortState(ctx context.Context, deviceID string, portType voltha.Port_PortType, portNo uint32, operStatus voltha.OperStatus_Types) error {
 	logger.Debugw("updatePortState", log.Fields{"deviceid": deviceID, "portType": portType, "portNo": portNo, "operStatus": operStatus})
-	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
-		if err := agent.updatePortState(ctx, portType, portNo, operStatus); err != nil {
-			logger.Errorw("updating-port-state-failed", log.Fields{"deviceid": deviceID, "portNo": portNo, "error": err})
-			return err
-		}
-		// Notify the logical device manager to change the port state
-		// Do this for NNI and UNIs only. PON ports are not known by logical device
-		if portType == voltha.Port_ETHERNET_NNI || portType == voltha.Port_ETHERNET_UNI {
-			go func() {
-				err := dMgr.logicalDeviceMgr.updatePortState(context.Background(), deviceID, portNo, operStatus)
-				if err != nil {
-					// While we want to handle (catch) and log when
-					// an update to a port was not able to be
-					// propagated to the logical port, we can report
-					// it as a warning and not an error because it
-					// doesn't stop or modify processing.
-					// TODO: VOL-2707
-					logger.Warnw("unable-to-update-logical-port-state", log.Fields{"error": err})
-				}
-			}()
-		}
-		return nil
-	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	dMgr.markDeviceActivity(ctx, deviceID, activityAdapter)
+	return dMgr.updatePortsStates(ctx, deviceID, []portStateChange{{portType: portType, portNo: portNo, operStatus: operStatus}})
 }
 
 func (dMgr *DeviceManager) deleteAllPorts(ctx context.Context, deviceID string) error {
@@ -899,10 +1430,10 @@ func (dMgr *DeviceManager) deleteAllPorts(ctx context.Context, deviceID string)
 		}
 		return nil
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
-//updatePortsState updates all ports on the device
+// updatePortsState updates all ports on the device
 func (dMgr *DeviceManager) updatePortsState(ctx context.Context, deviceID string, state voltha.OperStatus_Types) error {
 	logger.Debugw("updatePortsState", log.Fields{"deviceid": deviceID})
 
@@ -933,7 +1464,7 @@ func (dMgr *DeviceManager) updatePortsState(ctx context.Context, deviceID string
 		}
 		return nil
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) childDeviceDetected(ctx context.Context, parentDeviceID string, parentPortNo int64, deviceType string,
@@ -960,7 +1491,14 @@ func (dMgr *DeviceManager) childDeviceDetected(ctx context.Context, parentDevice
 	//if no match found for the vendorid,report adapter with the custom error message
 	if deviceType == "" {
 		logger.Errorw("failed-to-fetch-adapter-name ", log.Fields{"vendorId": vendorID})
-		return nil, status.Errorf(codes.NotFound, "%s", vendorID)
+		return nil, newNotFoundError("adapter-for-vendor", vendorID)
+	}
+
+	// Reject ids outside the addressable range before they are cast into the uint32
+	// proxy address fields
+	if err := validateChildProxyAddress(deviceType, parentPortNo, channelID, onuID); err != nil {
+		logger.Warnw("invalid-child-proxy-address", log.Fields{"parentDeviceId": parentDeviceID, "error": err})
+		return nil, err
 	}
 
 	// Create the ONU device
@@ -975,10 +1513,10 @@ func (dMgr *DeviceManager) childDeviceDetected(ctx context.Context, parentDevice
 	// Get parent device type
 	pAgent := dMgr.getDeviceAgent(ctx, parentDeviceID)
 	if pAgent == nil {
-		return nil, status.Errorf(codes.NotFound, "%s", parentDeviceID)
+		return nil, newNotFoundError("device", parentDeviceID)
 	}
 	if pAgent.deviceType == "" {
-		return nil, status.Errorf(codes.FailedPrecondition, "device Type not set %s", parentDeviceID)
+		return nil, newFailedPreconditionError("device", parentDeviceID, "device-type-not-set")
 	}
 
 	if device, err := dMgr.GetChildDevice(ctx, parentDeviceID, serialNumber, onuID, parentPortNo); err == nil {
@@ -986,34 +1524,46 @@ func (dMgr *DeviceManager) childDeviceDetected(ctx context.Context, parentDevice
 		return device, status.Errorf(codes.AlreadyExists, "%s", serialNumber)
 	}
 
+	// The peer core may have received the same discovery from the shared topic; only the
+	// core winning the KV claim creates the device
+	won, err := dMgr.claimChildDevice(ctx, parentDeviceID, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !won {
+		logger.Infow("child-device-claimed-by-peer-core", log.Fields{"parentId": parentDeviceID, "serialNumber": serialNumber})
+		return dMgr.waitForChildDevice(ctx, parentDeviceID, serialNumber, onuID, parentPortNo)
+	}
+	defer dMgr.releaseChildClaim(parentDeviceID, serialNumber)
+
 	childDevice.ProxyAddress = &voltha.Device_ProxyAddress{DeviceId: parentDeviceID, DeviceType: pAgent.deviceType, ChannelId: uint32(channelID), OnuId: uint32(onuID)}
 
 	// Create and start a device agent for that device
 	agent := newDeviceAgent(dMgr.adapterProxy, childDevice, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
-	childDevice, err := agent.start(ctx, childDevice)
+	childDevice, err = agent.start(ctx, childDevice)
 	if err != nil {
 		logger.Errorw("error-starting-child-device", log.Fields{"parent-device-id": childDevice.ParentId, "child-device-id": agent.deviceID, "error": err})
 		return nil, err
 	}
-	dMgr.addDeviceAgentToMap(agent)
+	agent = dMgr.addDeviceAgentToMap(agent)
 
-	// Activate the child device
+	// Activate the child device, unless the parent is in maintenance mode covering
+	// its children - the engineer will enable it manually
 	if agent = dMgr.getDeviceAgent(ctx, agent.deviceID); agent != nil {
-		go func() {
-			err := agent.enableDevice(context.Background())
-			if err != nil {
-				logger.Errorw("unable-to-enable-device", log.Fields{"error": err})
-			}
-		}()
+		if dMgr.maintenanceActive(ctx, childDevice) {
+			ctxInfow(ctx, "skipping-child-auto-enable-maintenance", log.Fields{"child-device-id": agent.deviceID, "parent-device-id": parentDeviceID})
+		} else {
+			go func() {
+				err := agent.enableDevice(context.Background())
+				if err != nil {
+					logger.Errorw("unable-to-enable-device", log.Fields{"error": err})
+				}
+			}()
+		}
 	}
 
 	// Publish on the messaging bus that we have discovered new devices
-	go func() {
-		err := dMgr.kafkaICProxy.DeviceDiscovered(agent.deviceID, deviceType, parentDeviceID, dMgr.coreInstanceID)
-		if err != nil {
-			logger.Errorw("unable-to-discover-the-device", log.Fields{"error": err})
-		}
-	}()
+	dMgr.notifyDeviceDiscovered(ctx, agent.deviceID, deviceType, parentDeviceID)
 
 	return childDevice, nil
 }
@@ -1029,33 +1579,103 @@ func (dMgr *DeviceManager) processTransition(ctx context.Context, device *voltha
 		"curr-oper-state":  device.OperStatus,
 		"curr-conn-state":  device.ConnectStatus,
 	})
-	handlers := dMgr.stateTransitions.GetTransitionHandler(device, previousState)
+	handlers := dMgr.effectiveTransitionHandlers(ctx, device, previousState)
 	if handlers == nil {
 		logger.Debugw("no-op-transition", log.Fields{"deviceId": device.Id})
 		return nil
 	}
+	// Automation is paused for a device in maintenance mode; only the delete cleanup
+	// is allowed through so a manual delete completes
+	if device.AdminState != voltha.AdminState_DELETED && dMgr.maintenanceActive(ctx, device) {
+		ctxInfow(ctx, "skipping-transition-handlers-device-in-maintenance", log.Fields{"deviceId": device.Id})
+		return nil
+	}
 	logger.Debugw("handler-found", log.Fields{"num-expectedHandlers": len(handlers), "isParent": device.Root, "current-data": device, "previous-state": previousState})
+	// state changes made by the handlers are attributed to the transition, not the NBI
+	ctx = withStateSource(ctx, stateSourceTransitionHandler)
+	executions := make([]handlerExecution, 0, len(handlers))
+	var failure error
 	for _, handler := range handlers {
 		logger.Debugw("running-handler", log.Fields{"handler": funcName(handler)})
-		if err := handler(ctx, device); err != nil {
+		begin := time.Now()
+		err := handler(ctx, device)
+		execution := handlerExecution{Name: handlerName(handler), Duration: time.Since(begin)}
+		if err != nil {
 			logger.Warnw("handler-failed", log.Fields{"handler": funcName(handler), "error": err})
-			return err
+			execution.Error = err.Error()
+			failure = err
+		}
+		executions = append(executions, execution)
+		if failure != nil {
+			break
 		}
 	}
-	return nil
+	// The executed chain - what actually ran, for how long, with what outcome - is
+	// kept on the state history entry of this transition
+	dMgr.recordExecutedTransition(ctx, device, executions)
+	return failure
 }
 
+// packetOutDropLogInterval limits how often dropped packet-outs are logged per device
+const packetOutDropLogInterval = time.Minute
+
 func (dMgr *DeviceManager) packetOut(ctx context.Context, deviceID string, outPort uint32, packet *ofp.OfpPacketOut) error {
 	logger.Debugw("packetOut", log.Fields{"deviceId": deviceID, "outPort": outPort})
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
+		if !dMgr.validPacketOutPort(ctx, agent, outPort) {
+			// The controller view is stale; drop rather than generating an adapter error
+			dropped := atomic.AddInt64(&dMgr.packetOutDropCount, 1)
+			if dMgr.shouldLogPacketOutDrop(deviceID) {
+				logger.Warnw("dropping-packet-out-to-invalid-port", log.Fields{"deviceId": deviceID, "outPort": outPort, "total-dropped": dropped})
+			}
+			return newInvalidArgumentError(fmt.Sprintf("packet-out-port-%d-not-on-logical-device", outPort))
+		}
+		dMgr.packetStats.recordPacketOut(deviceID, outPort)
 		return agent.packetOut(ctx, outPort, packet)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
+}
+
+// validPacketOutPort reports whether outPort may receive a packet-out.  The out port named
+// in a packet-out is an OpenFlow port of the logical device, so it is checked against the
+// logical port set rather than the physical ports of the root device.  Reserved OpenFlow
+// port numbers (controller, local, etc.) are always forwarded.
+func (dMgr *DeviceManager) validPacketOutPort(ctx context.Context, agent *DeviceAgent, outPort uint32) bool {
+	if outPort >= uint32(ofp.OfpPortNo_OFPP_MAX) {
+		return true
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		// Cannot validate; let the adapter decide
+		return true
+	}
+	ldAgent := dMgr.logicalDeviceMgr.getLogicalDeviceAgent(ctx, device.ParentId)
+	if ldAgent == nil {
+		// The logical device is not managed here; let the adapter decide
+		return true
+	}
+	return ldAgent.validPacketOutPort(ctx, outPort)
+}
+
+// getPacketStatistics returns a snapshot of the packet-in/packet-out counters of a device
+func (dMgr *DeviceManager) getPacketStatistics(deviceID string) []PacketStats {
+	return dMgr.packetStats.getPacketStats(deviceID)
+}
+
+// shouldLogPacketOutDrop rate-limits the dropped packet-out log per device
+func (dMgr *DeviceManager) shouldLogPacketOutDrop(deviceID string) bool {
+	now := time.Now()
+	if last, ok := dMgr.packetOutDropLogTimes.Load(deviceID); ok && now.Sub(last.(time.Time)) < packetOutDropLogInterval {
+		return false
+	}
+	dMgr.packetOutDropLogTimes.Store(deviceID, now)
+	return true
 }
 
 // PacketIn receives packet from adapter
 func (dMgr *DeviceManager) PacketIn(ctx context.Context, deviceID string, port uint32, transactionID string, packet []byte) error {
 	logger.Debugw("PacketIn", log.Fields{"deviceId": deviceID, "port": port})
+	dMgr.packetStats.recordPacketIn(deviceID, port)
 	// Get the logical device Id based on the deviceId
 	var device *voltha.Device
 	var err error
@@ -1065,7 +1685,16 @@ func (dMgr *DeviceManager) PacketIn(ctx context.Context, deviceID string, port u
 	}
 	if !device.Root {
 		logger.Errorw("device-not-root", log.Fields{"deviceId": deviceID})
-		return status.Errorf(codes.FailedPrecondition, "%s", deviceID)
+		return newFailedPreconditionError("device", deviceID, "not-a-root-device")
+	}
+
+	// The logical device may not exist yet for packets arriving during the activation window
+	if device.ParentId == "" || dMgr.logicalDeviceMgr.getLogicalDeviceAgent(ctx, device.ParentId) == nil {
+		if dMgr.packetInBufferWindow > 0 {
+			dMgr.bufferPacketIn(deviceID, port, transactionID, packet)
+			return nil
+		}
+		return newUnavailableError("logical-device", deviceID, nil)
 	}
 
 	if err := dMgr.logicalDeviceMgr.packetIn(ctx, device.ParentId, port, transactionID, packet); err != nil {
@@ -1079,7 +1708,7 @@ func (dMgr *DeviceManager) setParentID(ctx context.Context, device *voltha.Devic
 	if agent := dMgr.getDeviceAgent(ctx, device.Id); agent != nil {
 		return agent.setParentID(ctx, device, parentID)
 	}
-	return status.Errorf(codes.NotFound, "%s", device.Id)
+	return newNotFoundError("device", device.Id)
 }
 
 // CreateLogicalDevice creates logical device in core
@@ -1090,11 +1719,17 @@ func (dMgr *DeviceManager) CreateLogicalDevice(ctx context.Context, cDevice *vol
 		logger.Debugw("Parent device already exist.", log.Fields{"deviceId": cDevice.Id, "logicalDeviceId": cDevice.Id})
 		return nil
 	}
-	var err error
-	if _, err = dMgr.logicalDeviceMgr.createLogicalDevice(ctx, cDevice); err != nil {
+	logicalID, err := dMgr.logicalDeviceMgr.createLogicalDevice(ctx, cDevice)
+	if err != nil {
 		logger.Warnw("createlogical-device-error", log.Fields{"device": cDevice})
 		return err
 	}
+	// The logical device belongs to whichever instance owns its root device
+	if dMgr.interCoreProxy != nil {
+		dMgr.interCoreProxy.registerLogicalDevice(*logicalID, cDevice.Id)
+	}
+	// Deliver any packet-ins that arrived while the logical device was being created
+	go dMgr.replayPendingPacketIns(context.Background(), cDevice.Id, *logicalID)
 	return nil
 }
 
@@ -1106,6 +1741,10 @@ func (dMgr *DeviceManager) DeleteLogicalDevice(ctx context.Context, cDevice *vol
 		logger.Warnw("deleteLogical-device-error", log.Fields{"deviceId": cDevice.Id})
 		return err
 	}
+	// For a root device the ParentId carries its logical device ID
+	if dMgr.interCoreProxy != nil {
+		dMgr.interCoreProxy.unregisterLogicalDevice(cDevice.ParentId, cDevice.Id)
+	}
 	// Remove the logical device Id from the parent device
 	logicalID := ""
 	dMgr.UpdateDeviceAttribute(ctx, cDevice.Id, "ParentId", logicalID)
@@ -1149,8 +1788,8 @@ func (dMgr *DeviceManager) getParentDevice(ctx context.Context, childDevice *vol
 	return parentDevice
 }
 
-//childDevicesLost is invoked by an adapter to indicate that a parent device is in a state (Disabled) where it
-//cannot manage the child devices.  This will trigger the Core to disable all the child devices.
+// childDevicesLost is invoked by an adapter to indicate that a parent device is in a state (Disabled) where it
+// cannot manage the child devices.  This will trigger the Core to disable all the child devices.
 func (dMgr *DeviceManager) childDevicesLost(ctx context.Context, parentDeviceID string) error {
 	logger.Debug("childDevicesLost")
 	var err error
@@ -1162,8 +1801,10 @@ func (dMgr *DeviceManager) childDevicesLost(ctx context.Context, parentDeviceID
 	return dMgr.DisableAllChildDevices(ctx, parentDevice)
 }
 
-//childDevicesDetected is invoked by an adapter when child devices are found, typically after after a
-// disable/enable sequence.  This will trigger the Core to Enable all the child devices of that parent.
+// childDevicesDetected is invoked by an adapter when child devices are found, typically after after a
+// disable/enable sequence.  This will trigger the Core to reverse on the child devices whatever the
+// parent's cascade policy did when they were lost - re-enable them under CASCADE_DISABLE, restore
+// their reachability under MARK_UNREACHABLE_ONLY, nothing under NONE.
 func (dMgr *DeviceManager) childDevicesDetected(ctx context.Context, parentDeviceID string) error {
 	logger.Debug("childDevicesDetected")
 	var err error
@@ -1175,8 +1816,19 @@ func (dMgr *DeviceManager) childDevicesDetected(ctx context.Context, parentDevic
 		return err
 	}
 
+	switch policy := dMgr.childCascadePolicyFor(parentDevice); policy {
+	case cascadeNone:
+		// The children were left ENABLED and reachable; there is nothing to reverse
+		ctxDebugw(ctx, "child-cascade-skipped", log.Fields{"parentDeviceId": parentDeviceID, "policy": policy})
+		return nil
+	case cascadeMarkUnreachable:
+		// Marking a child REACHABLE replays any requests parked while it was unreachable;
+		// its flows never left the model or the device, so there is nothing to restore
+		return dMgr.markAllChildDevices(ctx, parentDevice, voltha.ConnectStatus_REACHABLE)
+	}
+
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentDevice); err != nil {
-		return status.Errorf(codes.NotFound, "%s", parentDevice.Id)
+		return newNotFoundError("device", parentDevice.Id)
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-device", log.Fields{"parentDeviceId": parentDevice.Id})
@@ -1192,7 +1844,7 @@ func (dMgr *DeviceManager) childDevicesDetected(ctx context.Context, parentDevic
 				}
 			}()
 		} else {
-			err = status.Errorf(codes.Unavailable, "no agent for child device %s", childDeviceID)
+			err = newUnavailableError("child-device-agent", childDeviceID, nil)
 			logger.Errorw("no-child-device-agent", log.Fields{"parentDeviceId": parentDevice.Id, "childId": childDeviceID})
 			allChildEnableRequestSent = false
 		}
@@ -1208,13 +1860,21 @@ All the functions below are callback functions where they are invoked with the l
 therefore use the data as is without trying to get the latest from the model.
 */
 
-//DisableAllChildDevices is invoked as a callback when the parent device is disabled
+// DisableAllChildDevices is invoked as a callback when the parent device is disabled.
+// What it does to the children depends on the parent's cascade policy.
 func (dMgr *DeviceManager) DisableAllChildDevices(ctx context.Context, parentCurrDevice *voltha.Device) error {
 	logger.Debug("DisableAllChildDevices")
 	var childDeviceIds []string
 	var err error
+	switch policy := dMgr.childCascadePolicyFor(parentCurrDevice); policy {
+	case cascadeNone:
+		ctxDebugw(ctx, "child-cascade-skipped", log.Fields{"parentDeviceId": parentCurrDevice.Id, "policy": policy})
+		return nil
+	case cascadeMarkUnreachable:
+		return dMgr.markAllChildDevices(ctx, parentCurrDevice, voltha.ConnectStatus_UNREACHABLE)
+	}
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentCurrDevice); err != nil {
-		return status.Errorf(codes.NotFound, "%s", parentCurrDevice.Id)
+		return newNotFoundError("device", parentCurrDevice.Id)
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-device", log.Fields{"parentDeviceId": parentCurrDevice.Id})
@@ -1230,17 +1890,20 @@ func (dMgr *DeviceManager) DisableAllChildDevices(ctx context.Context, parentCur
 	return nil
 }
 
-//DeleteAllChildDevices is invoked as a callback when the parent device is deleted
+// DeleteAllChildDevices is invoked as a callback when the parent device is deleted
 func (dMgr *DeviceManager) DeleteAllChildDevices(ctx context.Context, parentCurrDevice *voltha.Device) error {
 	logger.Debug("DeleteAllChildDevices")
 	var childDeviceIds []string
 	var err error
 	if childDeviceIds, err = dMgr.getAllChildDeviceIds(parentCurrDevice); err != nil {
-		return status.Errorf(codes.NotFound, "%s", parentCurrDevice.Id)
+		return newNotFoundError("device", parentCurrDevice.Id)
 	}
 	if len(childDeviceIds) == 0 {
 		logger.Debugw("no-child-device", log.Fields{"parentDeviceId": parentCurrDevice.Id})
 	}
+	// The parent is going away and takes the hardware state with it; the per-child
+	// flow removal barrier would only add delay
+	ctx = withForcedDeletion(ctx)
 	for _, childDeviceID := range childDeviceIds {
 		if agent := dMgr.getDeviceAgent(ctx, childDeviceID); agent != nil {
 			if err = agent.deleteDevice(ctx); err != nil {
@@ -1249,11 +1912,33 @@ func (dMgr *DeviceManager) DeleteAllChildDevices(ctx context.Context, parentCurr
 			// No further action is required here.  The deleteDevice will change the device state where the resulting
 			// callback will take care of cleaning the child device agent.
 		}
+		// Release any dedicated topic right away rather than waiting for the deletion
+		// callback - a child stuck in DELETING must not keep its assignment alive
+		dMgr.deviceTopicMgr.releaseDeviceTopic(ctx, childDeviceID)
 	}
 	return nil
 }
 
-//DeleteAllUNILogicalPorts is invoked as a callback when the parent device is deleted
+// sweepStaleDeviceTopics periodically drops per-device topic assignments for devices that no
+// longer exist in the model, e.g. children whose deletion callback never completed
+func (dMgr *DeviceManager) sweepStaleDeviceTopics(ctx context.Context) {
+	ticker := time.NewTicker(topicSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dMgr.deviceTopicMgr.sweepStaleTopics(ctx, func(deviceID string) bool {
+				_, err := dMgr.getDeviceFromModel(ctx, deviceID)
+				return err == nil
+			})
+		case <-dMgr.exitChannel:
+			logger.Info("device-topic-sweep-stopped")
+			return
+		}
+	}
+}
+
+// DeleteAllUNILogicalPorts is invoked as a callback when the parent device is deleted
 func (dMgr *DeviceManager) DeleteAllUNILogicalPorts(ctx context.Context, curr *voltha.Device) error {
 	logger.Debugw("delete-all-uni-logical-ports", log.Fields{"parent-device-id": curr.Id})
 	if err := dMgr.logicalDeviceMgr.deleteAllUNILogicalPorts(ctx, curr); err != nil {
@@ -1263,7 +1948,7 @@ func (dMgr *DeviceManager) DeleteAllUNILogicalPorts(ctx context.Context, curr *v
 	return nil
 }
 
-//DeleteAllLogicalPorts is invoked as a callback when the parent device's connection status moves to UNREACHABLE
+// DeleteAllLogicalPorts is invoked as a callback when the parent device's connection status moves to UNREACHABLE
 func (dMgr *DeviceManager) DeleteAllLogicalPorts(ctx context.Context, parentDevice *voltha.Device) error {
 	logger.Debugw("delete-all-logical-ports", log.Fields{"parent-device-id": parentDevice.Id})
 	if err := dMgr.logicalDeviceMgr.deleteAllLogicalPorts(ctx, parentDevice); err != nil {
@@ -1273,7 +1958,77 @@ func (dMgr *DeviceManager) DeleteAllLogicalPorts(ctx context.Context, parentDevi
 	return nil
 }
 
-//DeleteAllDeviceFlows is invoked as a callback when the parent device's connection status moves to UNREACHABLE
+// ActivateDevice is invoked as a callback when a device reaches the ACTIVE operational state.
+// It drives the post-activation setup: PM collection is started per the device PmConfigs, a
+// device-activated event carrying the activation latency is raised and the initial capability
+// fetches are scheduled.  The handler is idempotent as oper-status flapping can re-trigger it.
+func (dMgr *DeviceManager) ActivateDevice(ctx context.Context, cDevice *voltha.Device) error {
+	logger.Infow("ActivateDevice", log.Fields{"deviceId": cDevice.Id})
+	if _, already := dMgr.activatedDevices.LoadOrStore(cDevice.Id, true); already {
+		logger.Debugw("device-activation-already-handled", log.Fields{"deviceId": cDevice.Id})
+		return nil
+	}
+	agent := dMgr.getDeviceAgent(ctx, cDevice.Id)
+	if agent == nil {
+		return newNotFoundError("device", cDevice.Id)
+	}
+
+	// Kick off PM collection as configured on the device
+	if cDevice.PmConfigs != nil {
+		if err := agent.updatePmConfigs(ctx, cDevice.PmConfigs); err != nil {
+			logger.Warnw("failed-to-start-pm-collection", log.Fields{"deviceId": cDevice.Id, "error": err})
+		}
+	}
+
+	// Schedule the initial capability fetches; their results are cached by the adapter path
+	go func() {
+		subCtx, cancel := context.WithTimeout(context.Background(), dMgr.defaultTimeout)
+		defer cancel()
+		if _, err := agent.getSwitchCapability(subCtx); err != nil {
+			logger.Debugw("initial-switch-capability-fetch-failed", log.Fields{"deviceId": cDevice.Id, "error": err})
+		}
+	}()
+
+	eventContext := map[string]string{"oper-state": cDevice.OperStatus.String()}
+	if start, ok := dMgr.activationStartTimes.Load(cDevice.Id); ok {
+		latency := time.Since(start.(time.Time))
+		eventContext["activation-latency-ms"] = strconv.FormatInt(latency.Milliseconds(), 10)
+	}
+	dMgr.eventMgr.publishDeviceEvent(cDevice, deviceActivatedEvent, eventContext)
+	return nil
+}
+
+// ReEnableDevice is invoked as a callback when a disabled device is re-enabled.  It re-pushes the
+// flows and groups persisted over the disable to the adapter, restores the logical port states and
+// raises an event reporting the outcome of the restoration.
+func (dMgr *DeviceManager) ReEnableDevice(ctx context.Context, cDevice *voltha.Device) error {
+	logger.Infow("ReEnableDevice", log.Fields{"deviceId": cDevice.Id})
+	agent := dMgr.getDeviceAgent(ctx, cDevice.Id)
+	if agent == nil {
+		return newNotFoundError("device", cDevice.Id)
+	}
+	var restoreErr error
+	// The flows persisted at disable time are the source of truth for what to restore
+	if err := agent.restoreFlowsAndGroups(ctx); err != nil {
+		logger.Errorw("failed-to-restore-flows", log.Fields{"deviceId": cDevice.Id, "error": err})
+		restoreErr = err
+	}
+	// Bring the logical ports back to their pre-disable state
+	if err := dMgr.logicalDeviceMgr.updatePortsState(ctx, cDevice, voltha.OperStatus_ACTIVE); err != nil {
+		logger.Warnw("failed-to-restore-logical-port-states", log.Fields{"deviceId": cDevice.Id, "error": err})
+		if restoreErr == nil {
+			restoreErr = err
+		}
+	}
+	if restoreErr != nil {
+		dMgr.eventMgr.publishDeviceEvent(cDevice, flowsRestoreFailureEvent, map[string]string{"error": restoreErr.Error()})
+		return restoreErr
+	}
+	dMgr.eventMgr.publishDeviceEvent(cDevice, flowsRestoredEvent, nil)
+	return nil
+}
+
+// DeleteAllDeviceFlows is invoked as a callback when the parent device's connection status moves to UNREACHABLE
 func (dMgr *DeviceManager) DeleteAllDeviceFlows(ctx context.Context, parentDevice *voltha.Device) error {
 	logger.Debugw("delete-all-device-flows", log.Fields{"parent-device-id": parentDevice.Id})
 	if agent := dMgr.getDeviceAgent(ctx, parentDevice.Id); agent != nil {
@@ -1283,10 +2038,10 @@ func (dMgr *DeviceManager) DeleteAllDeviceFlows(ctx context.Context, parentDevic
 		}
 		return nil
 	}
-	return status.Errorf(codes.NotFound, "%s", parentDevice.Id)
+	return newNotFoundError("device", parentDevice.Id)
 }
 
-//getAllChildDeviceIds is a helper method to get all the child device IDs from the device passed as parameter
+// getAllChildDeviceIds is a helper method to get all the child device IDs from the device passed as parameter
 func (dMgr *DeviceManager) getAllChildDeviceIds(parentDevice *voltha.Device) ([]string, error) {
 	logger.Debugw("getAllChildDeviceIds", log.Fields{"parentDeviceId": parentDevice.Id})
 	childDeviceIds := make([]string, 0)
@@ -1301,7 +2056,7 @@ func (dMgr *DeviceManager) getAllChildDeviceIds(parentDevice *voltha.Device) ([]
 	return childDeviceIds, nil
 }
 
-//getAllChildDevices is a helper method to get all the child device IDs from the device passed as parameter
+// getAllChildDevices is a helper method to get all the child device IDs from the device passed as parameter
 func (dMgr *DeviceManager) getAllChildDevices(ctx context.Context, parentDeviceID string) (*voltha.Devices, error) {
 	logger.Debugw("getAllChildDevices", log.Fields{"parentDeviceId": parentDeviceID})
 	if parentDevice, err := dMgr.GetDevice(ctx, parentDeviceID); err == nil {
@@ -1315,7 +2070,7 @@ func (dMgr *DeviceManager) getAllChildDevices(ctx context.Context, parentDeviceI
 		}
 		return &voltha.Devices{Items: childDevices}, nil
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", parentDeviceID)
+	return nil, newNotFoundError("device", parentDeviceID)
 }
 
 // SetupUNILogicalPorts creates UNI ports on the logical device that represents a child UNI interface
@@ -1329,16 +2084,26 @@ func (dMgr *DeviceManager) SetupUNILogicalPorts(ctx context.Context, cDevice *vo
 }
 
 func (dMgr *DeviceManager) downloadImage(ctx context.Context, img *voltha.ImageDownload, ch chan interface{}) {
-	logger.Debugw("downloadImage", log.Fields{"deviceid": img.Id, "imageName": img.Name})
+	ctx = withLogContext(ctx, "download-image", img.Id)
+	ctxDebugw(ctx, "downloadImage", log.Fields{"imageName": img.Name})
 	var res interface{}
 	var err error
 	if agent := dMgr.getDeviceAgent(ctx, img.Id); agent != nil {
-		if res, err = agent.downloadImage(ctx, img); err != nil {
-			logger.Debugw("downloadImage-failed", log.Fields{"err": err, "imageName": img.Name})
+		if err = dMgr.preflightImage(ctx, agent, img); err != nil {
+			// A bad URL is rejected here, synchronously, not ten minutes into the download
+			ctxDebugw(ctx, "downloadImage-preflight-rejected", log.Fields{"imageName": img.Name, "url": img.Url, "err": err})
+			res = err
+		} else if err = dMgr.suppressIfUnreachable(ctx, agent, "download-image", func(ctx context.Context) error {
+			_, err := agent.downloadImage(ctx, img)
+			return err
+		}); err != nil {
+			res = err
+		} else if res, err = agent.downloadImage(ctx, img); err != nil {
+			ctxDebugw(ctx, "downloadImage-failed", log.Fields{"err": err, "imageName": img.Name})
 			res = err
 		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", img.Id)
+		res = newNotFoundError("device", img.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -1353,7 +2118,7 @@ func (dMgr *DeviceManager) cancelImageDownload(ctx context.Context, img *voltha.
 			res = err
 		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", img.Id)
+		res = newNotFoundError("device", img.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -1368,7 +2133,7 @@ func (dMgr *DeviceManager) activateImage(ctx context.Context, img *voltha.ImageD
 			res = err
 		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", img.Id)
+		res = newNotFoundError("device", img.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -1383,7 +2148,7 @@ func (dMgr *DeviceManager) revertImage(ctx context.Context, img *voltha.ImageDow
 			res = err
 		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", img.Id)
+		res = newNotFoundError("device", img.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -1398,7 +2163,7 @@ func (dMgr *DeviceManager) getImageDownloadStatus(ctx context.Context, img *volt
 			res = err
 		}
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", img.Id)
+		res = newNotFoundError("device", img.Id)
 	}
 	sendResponse(ctx, ch, res)
 }
@@ -1411,7 +2176,11 @@ func (dMgr *DeviceManager) updateImageDownload(ctx context.Context, deviceID str
 			return err
 		}
 	} else {
-		return status.Errorf(codes.NotFound, "%s", img.Id)
+		return newNotFoundError("device", img.Id)
+	}
+	// A terminal report may conclude a download a batch job is waiting on
+	if isTerminalDownloadState(img.DownloadState) {
+		dMgr.notifyBatchDownloadOutcome(deviceID, img.DownloadState)
 	}
 	return nil
 }
@@ -1421,7 +2190,7 @@ func (dMgr *DeviceManager) getImageDownload(ctx context.Context, img *voltha.Ima
 	if agent := dMgr.getDeviceAgent(ctx, img.Id); agent != nil {
 		return agent.getImageDownload(ctx, img)
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", img.Id)
+	return nil, newNotFoundError("device", img.Id)
 }
 
 func (dMgr *DeviceManager) listImageDownloads(ctx context.Context, deviceID string) (*voltha.ImageDownloads, error) {
@@ -1429,7 +2198,7 @@ func (dMgr *DeviceManager) listImageDownloads(ctx context.Context, deviceID stri
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.listImageDownloads(ctx, deviceID)
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", deviceID)
+	return nil, newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) NotifyInvalidTransition(_ context.Context, device *voltha.Device) error {
@@ -1483,7 +2252,7 @@ func (dMgr *DeviceManager) updateDeviceReason(ctx context.Context, deviceID stri
 	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent != nil {
 		return agent.updateDeviceReason(ctx, reason)
 	}
-	return status.Errorf(codes.NotFound, "%s", deviceID)
+	return newNotFoundError("device", deviceID)
 }
 
 func (dMgr *DeviceManager) enablePort(ctx context.Context, port *voltha.Port, ch chan interface{}) {
@@ -1493,7 +2262,7 @@ func (dMgr *DeviceManager) enablePort(ctx context.Context, port *voltha.Port, ch
 		res = agent.enablePort(ctx, port)
 		logger.Debugw("enablePort-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", port.DeviceId)
+		res = newNotFoundError("device", port.DeviceId)
 	}
 
 	sendResponse(ctx, ch, res)
@@ -1506,7 +2275,7 @@ func (dMgr *DeviceManager) disablePort(ctx context.Context, port *voltha.Port, c
 		res = agent.disablePort(ctx, port)
 		logger.Debugw("disablePort-result", log.Fields{"result": res})
 	} else {
-		res = status.Errorf(codes.NotFound, "%s", port.DeviceId)
+		res = newNotFoundError("device", port.DeviceId)
 	}
 
 	sendResponse(ctx, ch, res)
@@ -1535,5 +2304,5 @@ func (dMgr *DeviceManager) startOmciTest(ctx context.Context, omcitestrequest *v
 		logger.Debugw("Omci_test_Response_result-device-magnager", log.Fields{"result": res})
 		return res, nil
 	}
-	return nil, status.Errorf(codes.NotFound, "%s", omcitestrequest.Id)
+	return nil, newNotFoundError("device", omcitestrequest.Id)
 }
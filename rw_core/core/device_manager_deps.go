@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The device manager and its agents only need a narrow slice of the components they talk to.
+// These interfaces capture those slices so the device lifecycle logic can be unit tested with
+// in-memory fakes instead of a full kafka and etcd stack.  The concrete types are still wired
+// in by newDeviceManager; tests inject their own implementations.
+
+// adapterService is the adapter request surface used by the device manager and the device
+// agents.  AdapterProxy is the production implementation.
+type adapterService interface {
+	adoptDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	disableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	reEnableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	rebootDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	deleteDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	getOfpDeviceInfo(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	getOfpPortInfo(ctx context.Context, device *voltha.Device, portNo uint32) (chan *kafka.RpcResponse, error)
+	reconcileDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error)
+	downloadImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	preflightImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	getImageDownloadStatus(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	cancelImageDownload(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	activateImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	revertImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error)
+	packetOut(ctx context.Context, deviceType string, deviceID string, outPort uint32, packet *openflow_13.OfpPacketOut) (chan *kafka.RpcResponse, error)
+	updateFlowsBulk(ctx context.Context, device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error)
+	updateFlowsIncremental(ctx context.Context, device *voltha.Device, flowChanges *openflow_13.FlowChanges, groupChanges *openflow_13.FlowGroupChanges, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error)
+	updatePmConfigs(ctx context.Context, device *voltha.Device, pmConfigs *voltha.PmConfigs) (chan *kafka.RpcResponse, error)
+	simulateAlarm(ctx context.Context, device *voltha.Device, simulateReq *voltha.SimulateAlarmRequest) (chan *kafka.RpcResponse, error)
+	disablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error)
+	enablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error)
+	childDeviceLost(ctx context.Context, deviceType string, pDeviceID string, pPortNo uint32, onuID uint32) (chan *kafka.RpcResponse, error)
+	startOmciTest(ctx context.Context, device *voltha.Device, omcitestrequest *voltha.OmciTestRequest) (chan *kafka.RpcResponse, error)
+}
+
+// logicalDeviceService is the logical device surface used by the device manager.
+// LogicalDeviceManager is the production implementation.
+type logicalDeviceService interface {
+	createLogicalDevice(ctx context.Context, device *voltha.Device) (*string, error)
+	deleteLogicalDevice(ctx context.Context, device *voltha.Device) error
+	load(ctx context.Context, lDeviceID string) error
+	stopManagingLogicalDeviceWithDeviceID(ctx context.Context, id string) string
+	getLogicalDeviceAgent(ctx context.Context, logicalDeviceID string) *LogicalDeviceAgent
+	getLogicalPortID(ctx context.Context, device *voltha.Device) (*voltha.LogicalPortId, error)
+	updateLogicalPort(ctx context.Context, device *voltha.Device, port *voltha.Port) error
+	updateLogicalPortFeatures(ctx context.Context, device *voltha.Device, port *voltha.Port, ofpPort *openflow_13.OfpPort) error
+	deleteLogicalPort(ctx context.Context, lPortID *voltha.LogicalPortId) error
+	deleteLogicalPorts(ctx context.Context, deviceID string) error
+	deleteAllLogicalPorts(ctx context.Context, device *voltha.Device) error
+	deleteAllUNILogicalPorts(ctx context.Context, parentDevice *voltha.Device) error
+	setupUNILogicalPorts(ctx context.Context, childDevice *voltha.Device) error
+	updatePortState(ctx context.Context, deviceID string, portNo uint32, state voltha.OperStatus_Types) error
+	updatePortsState(ctx context.Context, device *voltha.Device, state voltha.OperStatus_Types) error
+	updateLogicalPortStates(ctx context.Context, deviceID string, changes []portStateChange) error
+	packetIn(ctx context.Context, logicalDeviceID string, port uint32, transactionID string, packet []byte) error
+}
+
+// Guard that the production types keep satisfying the interfaces
+var _ adapterService = &AdapterProxy{}
+var _ logicalDeviceService = &LogicalDeviceManager{}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldCreateAgentOnLoad(t *testing.T) {
+	dMgr := &DeviceManager{skipLoadingDeletedAgents: true}
+
+	// Preprovisioned devices are reloaded so they remain visible after a restart and an
+	// enable takes the fast path
+	assert.True(t, dMgr.shouldCreateAgentOnLoad(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_PREPROVISIONED}))
+	assert.True(t, dMgr.shouldCreateAgentOnLoad(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_ENABLED}))
+
+	// Deleted devices are not resurrected
+	assert.False(t, dMgr.shouldCreateAgentOnLoad(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_DELETED}))
+
+	// Unless the gating is disabled
+	dMgr.skipLoadingDeletedAgents = false
+	assert.True(t, dMgr.shouldCreateAgentOnLoad(&voltha.Device{Id: "1234", AdminState: voltha.AdminState_DELETED}))
+}
+
+func TestPrefetchRelativesDeduplication(t *testing.T) {
+	dMgr := &DeviceManager{relativesPrefetching: make(map[string]struct{})}
+
+	// A child device with no parent has no tree to prefetch
+	dMgr.prefetchRelatives(&voltha.Device{Id: "onu", Root: false})
+	assert.Empty(t, dMgr.relativesPrefetching)
+
+	// A prefetch already in progress for the root is not restarted
+	dMgr.relativesPrefetching["olt"] = struct{}{}
+	dMgr.prefetchRelatives(&voltha.Device{Id: "onu", Root: false, ParentId: "olt"})
+	dMgr.prefetchRelatives(&voltha.Device{Id: "olt", Root: true})
+	assert.Equal(t, 1, len(dMgr.relativesPrefetching))
+}
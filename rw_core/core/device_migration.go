@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Moving a device from one adapter implementation to another - trialing a new OLT adapter,
+// say - used to require deleting and recreating the device.  migrateDevice performs the
+// handoff in place: the old adapter is told to release the device, the new one re-adopts
+// it, the flows are re-pushed and the adapter binding is rewritten.  The agent's request
+// queue is held for the whole sequence, so every other operation on the device is parked
+// behind the migration.  A failure at any step restores the original binding and, best
+// effort, re-adopts through the original adapter.  Every migration leaves a record in the
+// device's state history and raises a success or failure event.
+
+// stateSourceMigration marks state history records written by an adapter migration
+const stateSourceMigration = "migration"
+
+// migrateDevice moves a device to the target adapter after checking that the adapter is
+// registered and serves the device's type
+func (dMgr *DeviceManager) migrateDevice(ctx context.Context, deviceID string, targetAdapter string) error {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	supported, err := dMgr.adapterMgr.adapterSupportsDeviceType(targetAdapter, agent.deviceType)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return newFailedPreconditionError("adapter", targetAdapter, "does-not-serve-device-type-"+agent.deviceType)
+	}
+	return agent.migrateDevice(ctx, targetAdapter)
+}
+
+// migrateDevice runs the handoff sequence under the agent's request queue
+func (agent *DeviceAgent) migrateDevice(ctx context.Context, targetAdapter string) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	ctx = withStateSource(ctx, stateSourceMigration)
+
+	device := agent.getDeviceWithoutLock()
+	if device.Adapter == "" {
+		return newFailedPreconditionError("device", agent.deviceID, "not-adopted-by-any-adapter")
+	}
+	if device.Adapter == targetAdapter {
+		return newInvalidArgumentError("device-" + agent.deviceID + "-already-bound-to-" + targetAdapter)
+	}
+	previousAdapter := device.Adapter
+	ctxInfow(ctx, "migrating-device", log.Fields{"device-id": agent.deviceID, "from-adapter": previousAdapter, "to-adapter": targetAdapter})
+
+	// Step 1: the current adapter releases the device.  Nothing has changed yet, so a
+	// failure here needs no rollback.
+	if err := agent.migrationStep(ctx, "release", func(stepCtx context.Context) (chan *kafka.RpcResponse, error) {
+		return agent.adapterProxy.deleteDevice(stepCtx, device)
+	}); err != nil {
+		agent.deviceMgr.publishMigrationEvent(device, deviceMigrationFailedEvent, previousAdapter, targetAdapter, "release", err)
+		return err
+	}
+
+	// Step 2: the target adapter adopts the device under its new binding
+	rebound := proto.Clone(device).(*voltha.Device)
+	rebound.Adapter = targetAdapter
+	if err := agent.migrationStep(ctx, "adopt", func(stepCtx context.Context) (chan *kafka.RpcResponse, error) {
+		return agent.adapterProxy.adoptDevice(stepCtx, rebound)
+	}); err != nil {
+		agent.rollbackMigration(ctx, device, previousAdapter)
+		agent.deviceMgr.publishMigrationEvent(device, deviceMigrationFailedEvent, previousAdapter, targetAdapter, "adopt", err)
+		return err
+	}
+
+	// Step 3: persist the new binding and move the device's request topic along with it
+	if err := agent.updateDeviceInStoreWithoutLock(ctx, rebound, false, ""); err != nil {
+		agent.rollbackMigration(ctx, device, previousAdapter)
+		agent.deviceMgr.publishMigrationEvent(device, deviceMigrationFailedEvent, previousAdapter, targetAdapter, "rebind", err)
+		return err
+	}
+	agent.deviceMgr.deviceTopicMgr.assignDeviceTopic(ctx, rebound)
+
+	// Step 4: the new adapter gets the flows the device is supposed to carry
+	if len(rebound.Flows.GetItems()) > 0 || len(rebound.FlowGroups.GetItems()) > 0 {
+		if err := agent.migrationStep(ctx, "flows", func(stepCtx context.Context) (chan *kafka.RpcResponse, error) {
+			return agent.adapterProxy.updateFlowsBulk(stepCtx, rebound, rebound.Flows, rebound.FlowGroups, nil)
+		}); err != nil {
+			agent.rollbackMigration(ctx, device, previousAdapter)
+			agent.deviceMgr.publishMigrationEvent(device, deviceMigrationFailedEvent, previousAdapter, targetAdapter, "flows", err)
+			return err
+		}
+	}
+
+	agent.appendStateRecord(ctx, rebound, stateSourceMigration)
+	agent.deviceMgr.publishMigrationEvent(rebound, deviceMigratedEvent, previousAdapter, targetAdapter, "", nil)
+	ctxInfow(ctx, "device-migrated", log.Fields{"device-id": agent.deviceID, "from-adapter": previousAdapter, "to-adapter": targetAdapter})
+	return nil
+}
+
+// migrationStep sends one adapter request of the handoff and waits for its answer
+func (agent *DeviceAgent) migrationStep(ctx context.Context, step string, send func(context.Context) (chan *kafka.RpcResponse, error)) error {
+	ctxDebugw(ctx, "migration-step", log.Fields{"device-id": agent.deviceID, "step": step})
+	subCtx, cancel := agent.adapterRequestContext()
+	defer cancel()
+	ch, err := send(subCtx)
+	if err != nil {
+		return err
+	}
+	select {
+	case rpcResponse, ok := <-ch:
+		if !ok {
+			return status.Errorf(codes.Aborted, "channel-closed-device-id-%s", agent.deviceID)
+		}
+		if rpcResponse.Err != nil {
+			return rpcResponse.Err
+		}
+		return nil
+	case <-subCtx.Done():
+		return agent.abortError(subCtx.Err())
+	}
+}
+
+// rollbackMigration restores the original adapter binding after a failed handoff and asks
+// the original adapter, best effort, to re-adopt the device
+func (agent *DeviceAgent) rollbackMigration(ctx context.Context, device *voltha.Device, previousAdapter string) {
+	restored := proto.Clone(device).(*voltha.Device)
+	restored.Adapter = previousAdapter
+	if err := agent.updateDeviceInStoreWithoutLock(ctx, restored, false, ""); err != nil {
+		ctxWarnw(ctx, "migration-rollback-rebind-failed", log.Fields{"device-id": agent.deviceID, "adapter": previousAdapter, "error": err})
+	}
+	if err := agent.migrationStep(ctx, "rollback-re-adopt", func(stepCtx context.Context) (chan *kafka.RpcResponse, error) {
+		return agent.adapterProxy.adoptDevice(stepCtx, restored)
+	}); err != nil {
+		ctxWarnw(ctx, "migration-rollback-re-adopt-failed", log.Fields{"device-id": agent.deviceID, "adapter": previousAdapter, "error": err})
+	}
+	agent.appendStateRecord(ctx, restored, stateSourceMigration)
+	ctxWarnw(ctx, "migration-rolled-back", log.Fields{"device-id": agent.deviceID, "adapter": previousAdapter})
+}
+
+// publishMigrationEvent raises the event recording the outcome of a migration
+func (dMgr *DeviceManager) publishMigrationEvent(device *voltha.Device, event string, fromAdapter string, toAdapter string, step string, err error) {
+	if dMgr.eventMgr == nil {
+		return
+	}
+	eventContext := map[string]string{"from-adapter": fromAdapter, "to-adapter": toAdapter}
+	if step != "" {
+		eventContext["failed-step"] = step
+	}
+	if err != nil {
+		eventContext["error"] = err.Error()
+	}
+	go dMgr.eventMgr.publishDeviceEvent(device, event, eventContext)
+}
+
+// MigrateDevice moves a device from its current adapter to the target adapter
+func (handler *APIHandler) MigrateDevice(ctx context.Context, deviceID string, targetAdapter string) error {
+	ctxDebugw(ctx, "MigrateDevice", log.Fields{"device-id": deviceID, "target-adapter": targetAdapter})
+	return handler.deviceMgr.migrateDevice(ctx, deviceID, targetAdapter)
+}
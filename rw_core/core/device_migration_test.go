@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// registerSecondAdapter adds another adapter serving the given device types to the fixture
+func registerSecondAdapter(dMgr *DeviceManager, deviceTypes ...string) {
+	items := make([]*voltha.DeviceType, 0, len(deviceTypes))
+	for _, deviceType := range deviceTypes {
+		items = append(items, &voltha.DeviceType{Id: deviceType})
+	}
+	dMgr.adapterMgr.adapterAgents["adapter-2"] = newAdapterAgent(&voltha.Adapter{Id: "adapter-2"},
+		&voltha.DeviceTypes{Items: items})
+}
+
+func TestMigrateDevice(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:15")
+	registerSecondAdapter(dMgr, "olt", "onu")
+
+	assert.Nil(t, dMgr.migrateDevice(ctx, device.Id, "adapter-2"))
+
+	// The old adapter released the device, the new one adopted it, the binding moved
+	migrated, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "adapter-2", migrated.Adapter)
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+	assert.Equal(t, 2, fas.callCount("adoptDevice")) // the enable plus the handoff
+
+	// The handoff left a record in the device's state history
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	history, err := agent.getStateHistory(ctx)
+	assert.Nil(t, err)
+	assert.True(t, len(history) > 0)
+	assert.Equal(t, stateSourceMigration, history[len(history)-1].Source)
+}
+
+func TestMigrateDeviceValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:16")
+
+	// Unknown target adapter
+	err := dMgr.migrateDevice(ctx, device.Id, "no-such-adapter")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The target adapter does not serve the device's type
+	registerSecondAdapter(dMgr, "onu")
+	err = dMgr.migrateDevice(ctx, device.Id, "adapter-2")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Migrating to the adapter already serving the device is rejected
+	err = dMgr.migrateDevice(ctx, device.Id, "adapter-1")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// None of the rejected requests touched the adapters
+	assert.Equal(t, 0, fas.callCount("deleteDevice"))
+	unchanged, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "adapter-1", unchanged.Adapter)
+}
+
+func TestMigrateDeviceRollsBackOnAdoptFailure(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:17")
+	registerSecondAdapter(dMgr, "olt")
+	fas.failWith("adoptDevice", errors.New("adapter-rejected"))
+
+	err := dMgr.migrateDevice(ctx, device.Id, "adapter-2")
+	assert.NotNil(t, err)
+
+	// The release went out, but the binding was restored after the adopt failed
+	assert.Equal(t, 1, fas.callCount("deleteDevice"))
+	restored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "adapter-1", restored.Adapter)
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// In a multi-core deployment an operator debugging "who is managing device X" should not
+// have to dig through ownership keys.  The view metadata of a device carries whether
+// this core instance owns it and, when it does, the instance identity.  The entries are
+// derived live from the agent's ownership state on every read - never persisted - so
+// they flip as soon as an ownership change lands.  ListOwnedDevices lets each core
+// report only its share.
+
+// Keys of the ownership entries in the device view metadata
+const (
+	ownedByThisCoreKey = "owned-by-this-core"
+	owningInstanceKey  = "owning-instance"
+)
+
+// deviceOwnedHere reports whether this core instance currently owns the device: its
+// agent is in memory and has not lost ownership to a peer
+func (dMgr *DeviceManager) deviceOwnedHere(deviceID string) bool {
+	agent, ok := dMgr.deviceAgents.Load(deviceID)
+	return ok && !agent.(*DeviceAgent).ownershipLost()
+}
+
+// stampOwnershipMeta stamps the ownership entries onto the view metadata of a device
+func (dMgr *DeviceManager) stampOwnershipMeta(deviceID string, meta map[string]string) {
+	owned := dMgr.deviceOwnedHere(deviceID)
+	meta[ownedByThisCoreKey] = strconv.FormatBool(owned)
+	if owned {
+		meta[owningInstanceKey] = dMgr.coreInstanceID
+	}
+}
+
+// listOwnedDevices returns the devices this core instance currently owns
+func (dMgr *DeviceManager) listOwnedDevices(ctx context.Context) (*voltha.Devices, error) {
+	devices, err := dMgr.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned := &voltha.Devices{}
+	for _, device := range devices.Items {
+		if dMgr.deviceOwnedHere(device.Id) {
+			owned.Items = append(owned.Items, device)
+		}
+	}
+	return owned, nil
+}
+
+// ListOwnedDevices is the NBI surface of the owned-by-me filter: each core reports only
+// the devices it currently manages
+func (handler *APIHandler) ListOwnedDevices(ctx context.Context) (*voltha.Devices, error) {
+	ctxDebugw(ctx, "ListOwnedDevices", nil)
+	return handler.deviceMgr.listOwnedDevices(ctx)
+}
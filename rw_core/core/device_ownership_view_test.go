@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestDevice(t *testing.T, dMgr *DeviceManager, mac string) *voltha.Device {
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(context.Background(), &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	return device
+}
+
+func TestDeviceViewsCarryOwnership(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:0e:01")
+
+	// The view metadata of a device this core manages names the owning instance
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	meta := dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, "true", meta[ownedByThisCoreKey])
+	assert.Equal(t, "core-1", meta[owningInstanceKey])
+
+	// The entries flip as soon as ownership moves to a peer
+	dMgr.cancelDeviceWork(device.Id)
+	meta = dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, "false", meta[ownedByThisCoreKey])
+	_, stamped := meta[owningInstanceKey]
+	assert.False(t, stamped)
+
+	// The entries live in the metadata only; the device itself is untouched
+	view, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.NotContains(t, view.ExtraArgs, ownedByThisCoreKey)
+}
+
+func TestListOwnedDevicesFiltersPeerDevices(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	mine := createTestDevice(t, dMgr, "00:00:00:00:0e:02")
+	theirs := createTestDevice(t, dMgr, "00:00:00:00:0e:03")
+	dMgr.cancelDeviceWork(theirs.Id)
+
+	all, err := dMgr.ListDevices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all.Items))
+
+	owned, err := dMgr.listOwnedDevices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(owned.Items))
+	assert.Equal(t, mine.Id, owned.Items[0].Id)
+	assert.Equal(t, "core-1", dMgr.deviceViewMeta(ctx, owned.Items[0])[owningInstanceKey])
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A device read served from a non-owning core's cached agent can be stale.  Callers now
+// pick a consistency level: cached hands back the in-memory copy as before, strong always
+// re-reads the device from the KV store, and default upgrades to a strong read only when
+// the cached copy has not been synced with the store within the configured threshold.
+// Strong reads are counted since they are the expensive path.
+
+type readConsistency int
+
+const (
+	// readConsistencyCached serves the in-memory agent copy without touching the store
+	readConsistencyCached readConsistency = iota
+	// readConsistencyDefault serves the cached copy unless it is older than the threshold
+	readConsistencyDefault
+	// readConsistencyStrong always re-reads the device from the KV store
+	readConsistencyStrong
+)
+
+func (rc readConsistency) String() string {
+	switch rc {
+	case readConsistencyDefault:
+		return "default"
+	case readConsistencyStrong:
+		return "strong"
+	default:
+		return "cached"
+	}
+}
+
+// parseReadConsistency maps the configured level name onto a readConsistency
+func parseReadConsistency(level string) (readConsistency, error) {
+	switch level {
+	case "cached":
+		return readConsistencyCached, nil
+	case "default":
+		return readConsistencyDefault, nil
+	case "strong":
+		return readConsistencyStrong, nil
+	default:
+		return readConsistencyCached, newInvalidArgumentError("unknown-read-consistency-level:" + level)
+	}
+}
+
+// getDeviceWithConsistency returns the device at the requested consistency level.  A
+// default-level read is upgraded to a strong one when the cached copy has not been synced
+// with the KV store within the stale read threshold.
+func (dMgr *DeviceManager) getDeviceWithConsistency(ctx context.Context, id string, level readConsistency) (*voltha.Device, error) {
+	agent := dMgr.getDeviceAgent(ctx, id)
+	if agent == nil {
+		return nil, newNotFoundError("device", id)
+	}
+	effective := level
+	if level == readConsistencyDefault {
+		age, err := agent.kvSyncAge(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if age > dMgr.staleReadThreshold {
+			effective = readConsistencyStrong
+		}
+	}
+
+	var device *voltha.Device
+	var err error
+	if effective == readConsistencyStrong {
+		atomic.AddInt64(&dMgr.strongReadCount, 1)
+		ctxDebugw(ctx, "strong-device-read", log.Fields{"device-id": id, "requested-level": level.String()})
+		device, err = agent.syncFromKVStore(ctx)
+	} else {
+		device, err = agent.getDevice(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// getStrongReadCount reports how many strong reads have been served since startup
+func (dMgr *DeviceManager) getStrongReadCount() int64 {
+	return atomic.LoadInt64(&dMgr.strongReadCount)
+}
+
+// kvSyncAge reports how long ago the agent's device copy was last synced with the KV store
+func (agent *DeviceAgent) kvSyncAge(ctx context.Context) (time.Duration, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return 0, err
+	}
+	defer agent.requestQueue.RequestComplete()
+	return time.Since(agent.lastKVSync), nil
+}
+
+// syncFromKVStore re-reads the device from the KV store, refreshes the agent's cached
+// copy and returns the result
+func (agent *DeviceAgent) syncFromKVStore(ctx context.Context) (*voltha.Device, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return nil, err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	device := &voltha.Device{}
+	if have, err := agent.clusterDataProxy.Get(ctx, "devices/"+agent.deviceID, device); err != nil {
+		return nil, err
+	} else if !have {
+		return nil, newNotFoundError("device", agent.deviceID)
+	}
+	agent.device = device
+	agent.lastKVSync = time.Now()
+	return proto.Clone(device).(*voltha.Device), nil
+}
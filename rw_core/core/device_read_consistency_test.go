@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadConsistency(t *testing.T) {
+	for name, want := range map[string]readConsistency{
+		"cached": readConsistencyCached, "default": readConsistencyDefault, "strong": readConsistencyStrong} {
+		level, err := parseReadConsistency(name)
+		assert.Nil(t, err)
+		assert.Equal(t, want, level)
+		assert.Equal(t, name, level.String())
+	}
+	_, err := parseReadConsistency("eventual")
+	assert.NotNil(t, err)
+}
+
+func TestReadConsistencyLevels(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:0c:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// Change the stored copy behind the agent's back, as the owning core would
+	stale, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	stale.Reason = "updated-by-owner"
+	assert.Nil(t, dMgr.clusterDataProxy.Update(ctx, "devices/"+device.Id, stale))
+
+	// A cached read keeps serving the in-memory copy
+	cached, err := dMgr.getDeviceWithConsistency(ctx, device.Id, readConsistencyCached)
+	assert.Nil(t, err)
+	assert.Equal(t, "", cached.Reason)
+
+	// A default read within the stale threshold is served from the cache as well
+	dMgr.staleReadThreshold = time.Hour
+	fresh, err := dMgr.getDeviceWithConsistency(ctx, device.Id, readConsistencyDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, "", fresh.Reason)
+	assert.Equal(t, int64(0), dMgr.getStrongReadCount())
+
+	// A strong read hits the store, is counted and refreshes the cache
+	strong, err := dMgr.getDeviceWithConsistency(ctx, device.Id, readConsistencyStrong)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated-by-owner", strong.Reason)
+	assert.Equal(t, int64(1), dMgr.getStrongReadCount())
+	cached, err = dMgr.getDeviceWithConsistency(ctx, device.Id, readConsistencyCached)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated-by-owner", cached.Reason)
+
+	// With the threshold at zero every default read is upgraded to a strong one
+	dMgr.staleReadThreshold = 0
+	_, err = dMgr.getDeviceWithConsistency(ctx, device.Id, readConsistencyDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), dMgr.getStrongReadCount())
+
+	// Unknown devices fail the same way at every level
+	_, err = dMgr.getDeviceWithConsistency(ctx, "no-such-device", readConsistencyStrong)
+	assert.NotNil(t, err)
+}
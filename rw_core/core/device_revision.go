@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The portal polls ListDevices every few seconds and the core rebuilt the full response
+// each time even when nothing had changed.  The device collection now carries a
+// monotonically increasing revision, bumped on every device mutation, and each device
+// remembers the collection revision of its last change.  A caller that keeps the revision
+// from its previous answer can ask what changed since: an unchanged collection costs a
+// counter comparison, and a changed one returns only the devices whose revision is newer
+// than the caller's.  The counters live in memory and restart from zero with the
+// instance, so a stale token from a previous life simply yields one full answer.  Any
+// later pagination of the device list must page over the same counters so a page sequence
+// and a since-revision answer describe the same collection state.
+//
+// The tokens travel as gRPC metadata on the existing RPCs: a caller hands its previous
+// revision to ListDevices in a request header, and every answer carries the current
+// collection revision - plus a not-modified flag when nothing changed - in the response
+// trailer.  The per-device revision rides along in the device view metadata.
+
+// deviceRevisionKey is the device view metadata key carrying the device's revision
+const deviceRevisionKey = "revision"
+
+// Metadata names of the revision tokens on the ListDevices RPC
+const (
+	// sinceRevisionHeader is the request header carrying the caller's previous revision
+	sinceRevisionHeader = "voltha-since-revision"
+	// collectionRevisionTrailer is the response trailer carrying the token for the next poll
+	collectionRevisionTrailer = "voltha-collection-revision"
+	// notModifiedTrailer flags an answer elided because nothing changed since the header
+	notModifiedTrailer = "voltha-not-modified"
+)
+
+// bumpDeviceRevision advances the collection revision and stamps it on the device; call
+// it whenever a device mutation reaches the KV store
+func (dMgr *DeviceManager) bumpDeviceRevision(deviceID string) {
+	if dMgr == nil {
+		return
+	}
+	rev := atomic.AddInt64(&dMgr.collectionRevision, 1)
+	dMgr.deviceRevisions.Store(deviceID, rev)
+}
+
+// dropDeviceRevision advances the collection revision for a device removal and forgets
+// the device's own revision
+func (dMgr *DeviceManager) dropDeviceRevision(deviceID string) {
+	if dMgr == nil {
+		return
+	}
+	atomic.AddInt64(&dMgr.collectionRevision, 1)
+	dMgr.deviceRevisions.Delete(deviceID)
+}
+
+// deviceRevision returns the collection revision of the device's last change, or zero
+// when the device has not changed since this instance started
+func (dMgr *DeviceManager) deviceRevision(deviceID string) int64 {
+	if rev, ok := dMgr.deviceRevisions.Load(deviceID); ok {
+		return rev.(int64)
+	}
+	return 0
+}
+
+// listRevision returns the current revision of the device collection
+func (dMgr *DeviceManager) listRevision() int64 {
+	return atomic.LoadInt64(&dMgr.collectionRevision)
+}
+
+// stampDeviceRevisionMeta stamps the device's revision onto its view metadata.  Like the
+// other view entries it is recomputed on every read and never persisted.
+func (dMgr *DeviceManager) stampDeviceRevisionMeta(deviceID string, meta map[string]string) {
+	meta[deviceRevisionKey] = strconv.FormatInt(dMgr.deviceRevision(deviceID), 10)
+}
+
+// sinceRevisionFromContext returns the revision token the caller supplied in the request
+// header, or zero for a caller without one
+func sinceRevisionFromContext(ctx context.Context) int64 {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0
+	}
+	values := md.Get(sinceRevisionHeader)
+	if len(values) == 0 {
+		return 0
+	}
+	since, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return since
+}
+
+// setCollectionRevisionTrailer hands the caller the token for its next poll, flagging an
+// answer elided because nothing changed.  Outside a gRPC request there is no trailer to
+// set and the token is simply not delivered.
+func setCollectionRevisionTrailer(ctx context.Context, revision int64, notModified bool) {
+	pairs := []string{collectionRevisionTrailer, strconv.FormatInt(revision, 10)}
+	if notModified {
+		pairs = append(pairs, notModifiedTrailer, "true")
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs(pairs...)); err != nil {
+		ctxDebugw(ctx, "collection-revision-not-deliverable", log.Fields{"error": err})
+	}
+}
+
+// listDevicesSince returns the devices changed after the given revision together with the
+// current collection revision.  A nil device list means the collection has not changed
+// since the caller's revision.  A non-positive or future revision - a fresh caller or a
+// token from a previous instance life - yields the full list.
+func (dMgr *DeviceManager) listDevicesSince(ctx context.Context, since int64) (*voltha.Devices, int64, error) {
+	current := dMgr.listRevision()
+	if since > 0 && since == current {
+		return nil, current, nil
+	}
+	devices, err := dMgr.ListDevices(ctx)
+	if err != nil {
+		return nil, current, err
+	}
+	if since <= 0 || since > current {
+		return devices, current, nil
+	}
+	changed := make([]*voltha.Device, 0, len(devices.Items))
+	for _, device := range devices.Items {
+		if dMgr.deviceRevision(device.Id) > since {
+			changed = append(changed, device)
+		}
+	}
+	return &voltha.Devices{Items: changed}, current, nil
+}
+
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDeviceRevisionsAdvanceOnMutation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:11")
+
+	// Creation bumped the collection and stamped the device
+	rev := dMgr.deviceRevision(device.Id)
+	assert.True(t, rev > 0)
+	assert.Equal(t, rev, dMgr.listRevision())
+
+	// A mutation advances both counters
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.True(t, dMgr.deviceRevision(device.Id) > rev)
+	assert.Equal(t, dMgr.deviceRevision(device.Id), dMgr.listRevision())
+
+	// The view metadata carries the revision; the device itself is untouched
+	view, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	meta := dMgr.deviceViewMeta(ctx, view)
+	assert.Equal(t, strconv.FormatInt(dMgr.deviceRevision(device.Id), 10), meta[deviceRevisionKey])
+	assert.NotContains(t, view.ExtraArgs, deviceRevisionKey)
+}
+
+func TestListDevicesSince(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	createTestDevice(t, dMgr, "00:00:00:00:4a:12")
+	newer := createTestDevice(t, dMgr, "00:00:00:00:4a:13")
+
+	// A fresh caller gets everything along with the current revision
+	devices, rev, err := dMgr.listDevicesSince(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices.Items))
+	assert.Equal(t, dMgr.listRevision(), rev)
+
+	// Nothing changed since: a nil list for the cost of a counter comparison
+	devices, rev2, err := dMgr.listDevicesSince(ctx, rev)
+	assert.Nil(t, err)
+	assert.Nil(t, devices)
+	assert.Equal(t, rev, rev2)
+
+	// Only the mutated device comes back
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, newer.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	devices, rev3, err := dMgr.listDevicesSince(ctx, rev)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices.Items))
+	assert.Equal(t, newer.Id, devices.Items[0].Id)
+	assert.True(t, rev3 > rev)
+
+	// A token from a previous instance life falls back to the full list
+	devices, _, err = dMgr.listDevicesSince(ctx, rev3+1000)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices.Items))
+}
+
+// trailerRecorder stands in for the gRPC layer, capturing the trailer a handler sets
+type trailerRecorder struct {
+	trailer metadata.MD
+}
+
+func (tr *trailerRecorder) Method() string                  { return "test" }
+func (tr *trailerRecorder) SetHeader(md metadata.MD) error  { return nil }
+func (tr *trailerRecorder) SendHeader(md metadata.MD) error { return nil }
+func (tr *trailerRecorder) SetTrailer(md metadata.MD) error {
+	tr.trailer = metadata.Join(tr.trailer, md)
+	return nil
+}
+
+// listDevicesWithSince invokes the ListDevices handler the way the gRPC layer would,
+// with the since-revision header set and the trailer captured
+func listDevicesWithSince(t *testing.T, handler *APIHandler, since int64) (*voltha.Devices, metadata.MD) {
+	recorder := &trailerRecorder{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), recorder)
+	if since > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(sinceRevisionHeader, strconv.FormatInt(since, 10)))
+	}
+	devices, err := handler.ListDevices(ctx, &empty.Empty{})
+	assert.Nil(t, err)
+	return devices, recorder.trailer
+}
+
+func TestListDevicesHonoursSinceRevisionHeader(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	handler := &APIHandler{deviceMgr: dMgr}
+	createTestDevice(t, dMgr, "00:00:00:00:4a:14")
+	changed := createTestDevice(t, dMgr, "00:00:00:00:4a:15")
+
+	// A caller without a token gets the full list and the token for its next poll
+	devices, trailer := listDevicesWithSince(t, handler, 0)
+	assert.Equal(t, 2, len(devices.Items))
+	token := trailer.Get(collectionRevisionTrailer)
+	assert.Equal(t, []string{strconv.FormatInt(dMgr.listRevision(), 10)}, token)
+	assert.Equal(t, 0, len(trailer.Get(notModifiedTrailer)))
+
+	// Nothing changed since: an empty answer flagged not-modified in the trailer
+	devices, trailer = listDevicesWithSince(t, handler, dMgr.listRevision())
+	assert.Equal(t, 0, len(devices.Items))
+	assert.Equal(t, []string{"true"}, trailer.Get(notModifiedTrailer))
+
+	// Only the mutated device comes back on the next poll
+	since := dMgr.listRevision()
+	assert.Nil(t, dMgr.updateDeviceStatus(context.Background(), changed.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	devices, trailer = listDevicesWithSince(t, handler, since)
+	assert.Equal(t, 1, len(devices.Items))
+	assert.Equal(t, changed.Id, devices.Items[0].Id)
+	assert.Equal(t, 0, len(trailer.Get(notModifiedTrailer)))
+}
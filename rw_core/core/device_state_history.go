@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A device showing ACTIVE now tells nothing about the outage a subscriber reported an
+// hour ago.  Each agent keeps a bounded history of its state transitions - the new
+// admin/oper/connect triple, when it happened and what triggered it (the adapter, the
+// NBI or a transition handler) - queryable through the device manager with an optional
+// time floor.  The history is flushed to the KV store at a configurable cadence rather
+// than on every change so a flapping device does not hammer the store, and the bound is
+// configurable for the same reason.
+
+const deviceStateHistoryPath = "device_state_history"
+
+// fallbackStateHistorySize applies when no size was configured (direct construction)
+const fallbackStateHistorySize = 32
+
+// Sources of a recorded state transition
+const (
+	stateSourceAdapter           = "adapter"
+	stateSourceNBI               = "nbi"
+	stateSourceTransitionHandler = "transition-handler"
+)
+
+// DeviceStateRecord describes one state transition of a device
+type DeviceStateRecord struct {
+	Admin   voltha.AdminState_Types
+	Oper    voltha.OperStatus_Types
+	Connect voltha.ConnectStatus_Types
+	Source  string
+	At      time.Time
+	// Executed is the handler chain the transition actually ran, attached once the
+	// handlers finished; empty for transitions with no handlers
+	Executed []handlerExecution
+}
+
+// stateSourceContextKey carries the transition source through the context
+type stateSourceContextKey struct{}
+
+// withStateSource marks the operations under the context as triggered by the given source
+func withStateSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, stateSourceContextKey{}, source)
+}
+
+// stateSourceOf returns the source marked on the context, or the fallback
+func stateSourceOf(ctx context.Context, fallback string) string {
+	if source, ok := ctx.Value(stateSourceContextKey{}).(string); ok {
+		return source
+	}
+	return fallback
+}
+
+// appendStateRecord appends a transition to the agent's history, trimming it to the
+// configured bound and flushing to the KV store when the cadence allows.  The caller
+// must hold the request queue.
+func (agent *DeviceAgent) appendStateRecord(ctx context.Context, device *voltha.Device, source string) {
+	size, cadence := fallbackStateHistorySize, time.Duration(0)
+	if agent.deviceMgr != nil {
+		if agent.deviceMgr.stateHistorySize > 0 {
+			size = agent.deviceMgr.stateHistorySize
+		}
+		cadence = agent.deviceMgr.stateHistoryPersist
+	}
+	agent.stateHistory = append(agent.stateHistory, &DeviceStateRecord{
+		Admin:   device.AdminState,
+		Oper:    device.OperStatus,
+		Connect: device.ConnectStatus,
+		Source:  source,
+		At:      time.Now(),
+	})
+	if len(agent.stateHistory) > size {
+		agent.stateHistory = agent.stateHistory[len(agent.stateHistory)-size:]
+	}
+	if time.Since(agent.lastHistoryPersist) >= cadence {
+		agent.lastHistoryPersist = time.Now()
+		entry := &ic.StrType{Val: encodeStateHistory(agent.stateHistory)}
+		if err := agent.clusterDataProxy.AddWithID(ctx, deviceStateHistoryPath, agent.deviceID, entry); err != nil {
+			ctxWarnw(ctx, "failed-to-persist-state-history", log.Fields{"device-id": agent.deviceID, "error": err})
+		}
+	}
+}
+
+// getStateHistory returns a copy of the agent's in-memory transition history
+func (agent *DeviceAgent) getStateHistory(ctx context.Context) ([]*DeviceStateRecord, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return nil, err
+	}
+	defer agent.requestQueue.RequestComplete()
+	return append([]*DeviceStateRecord{}, agent.stateHistory...), nil
+}
+
+// getDeviceStateHistory returns the recorded state transitions of a device at or after
+// the given floor (a zero floor returns everything), falling back to the persisted
+// history when the device is not tracked in memory
+func (dMgr *DeviceManager) getDeviceStateHistory(ctx context.Context, deviceID string, since time.Time) ([]*DeviceStateRecord, error) {
+	if agent, ok := dMgr.deviceAgents.Load(deviceID); ok {
+		records, err := agent.(*DeviceAgent).getStateHistory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return filterStateRecordsSince(records, since), nil
+	}
+	entry := &ic.StrType{}
+	if found, err := dMgr.clusterDataProxy.Get(ctx, deviceStateHistoryPath+"/"+deviceID, entry); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, newNotFoundError("device-state-history", deviceID)
+	}
+	return filterStateRecordsSince(decodeStateHistory(entry.Val), since), nil
+}
+
+func filterStateRecordsSince(records []*DeviceStateRecord, since time.Time) []*DeviceStateRecord {
+	if since.IsZero() {
+		return records
+	}
+	filtered := make([]*DeviceStateRecord, 0, len(records))
+	for _, record := range records {
+		if !record.At.Before(since) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// encodeStateHistory serializes the records of one device, one per ";"-separated entry
+// with "|"-separated fields; timestamps are unix nanoseconds.  The sixth field holds
+// the executed handler chain as ","-separated "name~nanos~error" triples.
+func encodeStateHistory(records []*DeviceStateRecord) string {
+	entries := make([]string, 0, len(records))
+	for _, record := range records {
+		executed := make([]string, 0, len(record.Executed))
+		for _, execution := range record.Executed {
+			executed = append(executed, strings.Join([]string{
+				execution.Name,
+				strconv.FormatInt(execution.Duration.Nanoseconds(), 10),
+				sanitizeHistoryField(execution.Error),
+			}, "~"))
+		}
+		entries = append(entries, strings.Join([]string{
+			record.Admin.String(),
+			record.Oper.String(),
+			record.Connect.String(),
+			record.Source,
+			strconv.FormatInt(record.At.UnixNano(), 10),
+			strings.Join(executed, ","),
+		}, "|"))
+	}
+	return strings.Join(entries, ";")
+}
+
+// sanitizeHistoryField keeps free-form text (handler error messages) from colliding
+// with the separators of the history encoding
+func sanitizeHistoryField(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ';', '|', ',', '~':
+			return '_'
+		}
+		return r
+	}, text)
+}
+
+// decodeStateHistory is the inverse of encodeStateHistory; malformed entries are
+// skipped.  Entries persisted before the executed chain was recorded carry five fields
+// and decode without one.
+func decodeStateHistory(encoded string) []*DeviceStateRecord {
+	var records []*DeviceStateRecord
+	for _, entry := range strings.Split(encoded, ";") {
+		fields := strings.Split(entry, "|")
+		if len(fields) != 5 && len(fields) != 6 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		record := &DeviceStateRecord{
+			Admin:   voltha.AdminState_Types(voltha.AdminState_Types_value[fields[0]]),
+			Oper:    voltha.OperStatus_Types(voltha.OperStatus_Types_value[fields[1]]),
+			Connect: voltha.ConnectStatus_Types(voltha.ConnectStatus_Types_value[fields[2]]),
+			Source:  fields[3],
+			At:      time.Unix(0, nanos),
+		}
+		if len(fields) == 6 && fields[5] != "" {
+			for _, encoded := range strings.Split(fields[5], ",") {
+				parts := strings.Split(encoded, "~")
+				if len(parts) != 3 {
+					continue
+				}
+				duration, err := strconv.ParseInt(parts[1], 10, 64)
+				if err != nil {
+					continue
+				}
+				record.Executed = append(record.Executed, handlerExecution{Name: parts[0], Duration: time.Duration(duration), Error: parts[2]})
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
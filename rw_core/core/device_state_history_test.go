@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeviceStateHistoryRecordsTransitions(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:12:01")
+
+	// Enabling was driven by the NBI
+	records, err := dMgr.getDeviceStateHistory(ctx, device.Id, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, voltha.AdminState_ENABLED, records[0].Admin)
+	assert.Equal(t, stateSourceNBI, records[0].Source)
+
+	// A status report is attributed to the adapter
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	records, err = dMgr.getDeviceStateHistory(ctx, device.Id, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, voltha.OperStatus_ACTIVE, records[1].Oper)
+	assert.Equal(t, stateSourceAdapter, records[1].Source)
+
+	// The since floor drops records older than it
+	since := records[1].At
+	filtered, err := dMgr.getDeviceStateHistory(ctx, device.Id, since)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(filtered))
+	assert.Equal(t, voltha.OperStatus_ACTIVE, filtered[0].Oper)
+	filtered, err = dMgr.getDeviceStateHistory(ctx, device.Id, since.Add(time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(filtered))
+}
+
+func TestDeviceStateHistoryBoundAndPersistedFallback(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.stateHistorySize = 2
+	device := enableOLT(t, dMgr, "00:00:00:00:12:02")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	ch := make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	// Three transitions happened but only the configured bound is kept
+	records, err := dMgr.getDeviceStateHistory(ctx, device.Id, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, voltha.OperStatus_ACTIVE, records[0].Oper)
+	assert.Equal(t, voltha.AdminState_DISABLED, records[1].Admin)
+
+	// With the agent gone the query falls back to the persisted history; let the
+	// transition goroutines finish before pulling the agent out from under them
+	time.Sleep(100 * time.Millisecond)
+	dMgr.deviceAgents.Delete(device.Id)
+	persisted, err := dMgr.getDeviceStateHistory(ctx, device.Id, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(persisted))
+	assert.Equal(t, stateSourceAdapter, persisted[0].Source)
+	assert.Equal(t, stateSourceNBI, persisted[1].Source)
+	assert.True(t, persisted[0].At.Equal(records[0].At))
+
+	// A device that never recorded anything has no history
+	_, err = dMgr.getDeviceStateHistory(ctx, "no-such-device", time.Time{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
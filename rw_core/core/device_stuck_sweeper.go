@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A lost adapter message can leave a device in ACTIVATING or DELETING forever, with nothing
+// retrying.  The sweeper below periodically walks the in-memory agents - the set of devices
+// managed by this core - and remediates devices that sat in the same transient state for
+// longer than the state's threshold.
+
+// Transient states the sweeper watches for
+const (
+	stuckStateActivating  = "activating"
+	stuckStateDeleting    = "deleting"
+	stuckStateDownloading = "downloading-image"
+)
+
+// stuckRemediation is the action applied to a stuck device
+type stuckRemediation string
+
+const (
+	// remediationRetry re-sends the request driving the transient state
+	remediationRetry stuckRemediation = "retry"
+	// remediationReconcile asks the adapter to reconcile the device
+	remediationReconcile stuckRemediation = "reconcile"
+	// remediationMarkFailed moves the device to FAILED and raises an event for the operators
+	remediationMarkFailed stuckRemediation = "mark-failed"
+)
+
+// stuckStatePolicy pairs how long a transient state is tolerated with the remediation applied
+// once that threshold is exceeded
+type stuckStatePolicy struct {
+	threshold   time.Duration
+	remediation stuckRemediation
+}
+
+// defaultStuckPolicies applies unless a per-device-type override is registered
+var defaultStuckPolicies = map[string]stuckStatePolicy{
+	stuckStateActivating:  {threshold: 10 * time.Minute, remediation: remediationReconcile},
+	stuckStateDeleting:    {threshold: 10 * time.Minute, remediation: remediationRetry},
+	stuckStateDownloading: {threshold: 30 * time.Minute, remediation: remediationMarkFailed},
+}
+
+// stuckPolicyOverrides allows adapters with known-slow devices to loosen the defaults,
+// keyed by device type then transient state
+var stuckPolicyOverrides = map[string]map[string]stuckStatePolicy{}
+
+// stuckPolicyFor returns the policy of a transient state for a device type
+func stuckPolicyFor(deviceType string, state string) stuckStatePolicy {
+	if overrides, ok := stuckPolicyOverrides[deviceType]; ok {
+		if policy, ok := overrides[state]; ok {
+			return policy
+		}
+	}
+	return defaultStuckPolicies[state]
+}
+
+// transientDeviceState maps a device to the transient state it is in, if any
+func transientDeviceState(device *voltha.Device) (string, bool) {
+	switch {
+	case device.OperStatus == voltha.OperStatus_ACTIVATING:
+		return stuckStateActivating, true
+	case device.AdminState == voltha.AdminState_DELETED:
+		return stuckStateDeleting, true
+	case device.AdminState == voltha.AdminState_DOWNLOADING_IMAGE:
+		return stuckStateDownloading, true
+	default:
+		return "", false
+	}
+}
+
+// stuckObservation records when the sweeper first saw a device in its current transient state.
+// Observations are only touched from the sweeper goroutine.
+type stuckObservation struct {
+	state string
+	since time.Time
+}
+
+// runStuckDeviceSweeper periodically sweeps for stuck devices until the device manager stops
+func (dMgr *DeviceManager) runStuckDeviceSweeper(ctx context.Context) {
+	for {
+		select {
+		case <-dMgr.after(dMgr.stuckSweepInterval):
+			dMgr.sweepStuckDevices(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("stuck-device-sweeper-stopped")
+			return
+		}
+	}
+}
+
+// sweepStuckDevices walks the devices managed by this core and remediates those sitting in a
+// transient state past the threshold of their policy
+func (dMgr *DeviceManager) sweepStuckDevices(ctx context.Context) {
+	now := dMgr.now()
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		device, err := agent.getDevice(ctx)
+		if err != nil {
+			return true
+		}
+		if dMgr.adapterNotAvailable(device.Id) {
+			// no adapter to remediate against; the device is parked until its type registers
+			return true
+		}
+		state, transient := transientDeviceState(device)
+		if !transient {
+			dMgr.stuckObservations.Delete(device.Id)
+			return true
+		}
+		obs, _ := dMgr.stuckObservations.LoadOrStore(device.Id, &stuckObservation{state: state, since: now})
+		observation := obs.(*stuckObservation)
+		if observation.state != state {
+			// The device moved to a different transient state; restart the clock
+			observation.state = state
+			observation.since = now
+			return true
+		}
+		policy := stuckPolicyFor(device.Type, state)
+		if policy.threshold <= 0 || now.Sub(observation.since) < policy.threshold {
+			return true
+		}
+		logger.Warnw("device-stuck-in-transient-state", log.Fields{
+			"device-id":   device.Id,
+			"state":       state,
+			"since":       observation.since,
+			"remediation": policy.remediation,
+		})
+		dMgr.remediateStuckDevice(ctx, agent, device, state, policy.remediation)
+		// Restart the clock so the device is not re-remediated on every sweep
+		observation.since = now
+		return true
+	})
+}
+
+// remediateStuckDevice applies the configured remediation to a stuck device
+func (dMgr *DeviceManager) remediateStuckDevice(ctx context.Context, agent *DeviceAgent, device *voltha.Device, state string, remediation stuckRemediation) {
+	switch remediation {
+	case remediationRetry:
+		// Re-send the request driving the transient state; for states with no dedicated
+		// request a reconcile serves the same purpose
+		if state == stuckStateDeleting {
+			// The first attempt already ran the flow removal barrier; the retry forces
+			if err := agent.deleteDevice(withForcedDeletion(ctx)); err != nil {
+				logger.Warnw("stuck-device-delete-retry-failed", log.Fields{"device-id": device.Id, "error": err})
+			}
+			return
+		}
+		dMgr.reconcileStuckDevice(ctx, device)
+	case remediationReconcile:
+		dMgr.reconcileStuckDevice(ctx, device)
+	case remediationMarkFailed:
+		if err := agent.updateDeviceStatus(ctx, voltha.OperStatus_FAILED, device.ConnectStatus); err != nil {
+			logger.Warnw("stuck-device-mark-failed-error", log.Fields{"device-id": device.Id, "error": err})
+			return
+		}
+		go dMgr.eventMgr.publishDeviceEvent(device, deviceStuckEvent, map[string]string{
+			"state":       state,
+			"remediation": string(remediationMarkFailed),
+		})
+		// An activation that never completed is closed out with the stuck state as the reason
+		agent.recordActivationFailure(ctx, device, state)
+	default:
+		logger.Warnw("unknown-stuck-device-remediation", log.Fields{"device-id": device.Id, "remediation": remediation})
+	}
+}
+
+// reconcileStuckDevice asks the adapter to reconcile the device, logging the outcome
+func (dMgr *DeviceManager) reconcileStuckDevice(ctx context.Context, device *voltha.Device) {
+	response := dMgr.sendReconcileDeviceRequest(ctx, device)
+	go func() {
+		if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+			logger.Warnw("stuck-device-reconcile-failed", log.Fields{"device-id": device.Id, "errors": res})
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransientDeviceState(t *testing.T) {
+	state, transient := transientDeviceState(&voltha.Device{OperStatus: voltha.OperStatus_ACTIVATING})
+	assert.True(t, transient)
+	assert.Equal(t, stuckStateActivating, state)
+
+	state, transient = transientDeviceState(&voltha.Device{AdminState: voltha.AdminState_DELETED})
+	assert.True(t, transient)
+	assert.Equal(t, stuckStateDeleting, state)
+
+	state, transient = transientDeviceState(&voltha.Device{AdminState: voltha.AdminState_DOWNLOADING_IMAGE})
+	assert.True(t, transient)
+	assert.Equal(t, stuckStateDownloading, state)
+
+	_, transient = transientDeviceState(&voltha.Device{AdminState: voltha.AdminState_ENABLED, OperStatus: voltha.OperStatus_ACTIVE})
+	assert.False(t, transient)
+}
+
+func TestStuckPolicyOverrides(t *testing.T) {
+	assert.Equal(t, defaultStuckPolicies[stuckStateActivating], stuckPolicyFor("olt", stuckStateActivating))
+
+	stuckPolicyOverrides["slow-onu"] = map[string]stuckStatePolicy{
+		stuckStateActivating: {threshold: time.Hour, remediation: remediationMarkFailed},
+	}
+	defer delete(stuckPolicyOverrides, "slow-onu")
+
+	policy := stuckPolicyFor("slow-onu", stuckStateActivating)
+	assert.Equal(t, time.Hour, policy.threshold)
+	assert.Equal(t, remediationMarkFailed, policy.remediation)
+
+	// States without an override keep the default
+	assert.Equal(t, defaultStuckPolicies[stuckStateDeleting], stuckPolicyFor("slow-onu", stuckStateDeleting))
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Which alloc-IDs and gem ports an ONU's flows ended up on lives inside the adapter, so
+// troubleshooting a service issue meant shelling into the adapter to find out.  Adapters
+// can now report their per-device technology resource assignments to the core - typically
+// after handling a flow add or remove - through the DeviceTechProfileUpdate
+// inter-container message.  The report is a list of named key/value groups the core
+// stores under the device and serves back verbatim through getDeviceTechProfileInfo: the
+// shape inside a group is the technology's own business, so new technologies can report
+// theirs without a proto change each time.
+
+const deviceTechProfilePath = "device_tech_profiles"
+
+// techResourceGroup is one named group of technology resources, e.g. "gem-ports" with an
+// entry per flow; the core does not interpret the entries
+type techResourceGroup struct {
+	Name      string            `json:"name"`
+	Resources map[string]string `json:"resources"`
+}
+
+// deviceTechProfileInfo is the stored technology resource report of one device
+type deviceTechProfileInfo struct {
+	DeviceID  string              `json:"device-id"`
+	UpdatedAt time.Time           `json:"updated-at"`
+	Groups    []techResourceGroup `json:"groups"`
+}
+
+// updateDeviceTechProfile stores the technology resource report of a device.  The encoded
+// report must be a JSON list of named key/value groups; anything else is refused so a
+// malformed adapter report cannot poison the stored record.
+func (dMgr *DeviceManager) updateDeviceTechProfile(ctx context.Context, deviceID string, encoded string) error {
+	ctxDebugw(ctx, "updateDeviceTechProfile", log.Fields{"device-id": deviceID})
+	if agent := dMgr.getDeviceAgent(ctx, deviceID); agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	var groups []techResourceGroup
+	if err := json.Unmarshal([]byte(encoded), &groups); err != nil {
+		return status.Errorf(codes.InvalidArgument, "malformed-tech-profile-report: %s", err)
+	}
+	info := &deviceTechProfileInfo{DeviceID: deviceID, UpdatedAt: time.Now(), Groups: groups}
+	stored, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := dMgr.clusterDataProxy.AddWithID(ctx, deviceTechProfilePath, deviceID, &ic.StrType{Val: string(stored)}); err != nil {
+		return status.Errorf(codes.Internal, "failed-storing-tech-profile-%s: %s", deviceID, err)
+	}
+	return nil
+}
+
+// getDeviceTechProfileInfo returns the technology resource report last stored for a device
+func (dMgr *DeviceManager) getDeviceTechProfileInfo(ctx context.Context, deviceID string) (*deviceTechProfileInfo, error) {
+	entry := &ic.StrType{}
+	if found, err := dMgr.clusterDataProxy.Get(ctx, deviceTechProfilePath+"/"+deviceID, entry); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, newNotFoundError("device-tech-profile", deviceID)
+	}
+	info := &deviceTechProfileInfo{}
+	if err := json.Unmarshal([]byte(entry.Val), info); err != nil {
+		return nil, status.Errorf(codes.Internal, "corrupt-tech-profile-%s: %s", deviceID, err)
+	}
+	return info, nil
+}
+
+// GetDeviceTechProfileInfo is the NBI query exposing the alloc/gem resource assignments
+// an adapter reported for a device
+func (handler *APIHandler) GetDeviceTechProfileInfo(ctx context.Context, deviceID string) (*deviceTechProfileInfo, error) {
+	ctxDebugw(ctx, "GetDeviceTechProfileInfo", log.Fields{"device-id": deviceID})
+	return handler.deviceMgr.getDeviceTechProfileInfo(ctx, deviceID)
+}
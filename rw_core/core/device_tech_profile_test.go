@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTechProfileUpdateAndQuery(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:39")
+
+	groups := []techResourceGroup{
+		{Name: "alloc-ids", Resources: map[string]string{"uni-0": "1024"}},
+		{Name: "gem-ports", Resources: map[string]string{"flow-101": "1025", "flow-102": "1026"}},
+	}
+	encoded, err := json.Marshal(groups)
+	assert.Nil(t, err)
+	assert.Nil(t, dMgr.updateDeviceTechProfile(ctx, device.Id, string(encoded)))
+
+	info, err := dMgr.getDeviceTechProfileInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, info.DeviceID)
+	assert.Equal(t, groups, info.Groups)
+	assert.False(t, info.UpdatedAt.IsZero())
+
+	// A later report replaces the stored one
+	assert.Nil(t, dMgr.updateDeviceTechProfile(ctx, device.Id, `[{"name":"gem-ports","resources":{"flow-101":"2048"}}]`))
+	info, err = dMgr.getDeviceTechProfileInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(info.Groups))
+	assert.Equal(t, "2048", info.Groups[0].Resources["flow-101"])
+}
+
+func TestTechProfileUpdateValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:3a")
+
+	// Unknown devices and malformed reports are refused
+	err := dMgr.updateDeviceTechProfile(ctx, "no-such-device", "[]")
+	assert.NotNil(t, err)
+	err = dMgr.updateDeviceTechProfile(ctx, device.Id, "{not-json")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "malformed-tech-profile-report")
+
+	// Nothing reported yet is a plain not-found
+	_, err = dMgr.getDeviceTechProfileInfo(ctx, device.Id)
+	assert.NotNil(t, err)
+}
+
+func TestTechProfileRemovedWithDevice(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:3b")
+	assert.Nil(t, dMgr.updateDeviceTechProfile(ctx, device.Id, `[{"name":"alloc-ids","resources":{"uni-0":"1024"}}]`))
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	waitUntil(t, 2*time.Second, "tech profile record was never removed", func() bool {
+		_, err := dMgr.getDeviceTechProfileInfo(ctx, device.Id)
+		return err != nil
+	})
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Soft-deleted devices are kept under a tombstone path for the configured retention period
+// instead of being purged immediately.  Tombstoned devices are excluded from ListDevices but
+// remain retrievable, and can be restored with undeleteDevice while the retention window is
+// open.  A background reaper purges tombstones once their retention expires.
+const (
+	deviceTombstonePath     = "device_tombstones"
+	deviceTombstoneMetaPath = "device_tombstone_meta"
+	tombstoneReaperInterval = time.Minute
+)
+
+// encodeTombstoneMeta serializes the purge deadline of a tombstoned device.  The device ID is
+// embedded in the value as the KV keys are not recoverable on a List operation.
+func encodeTombstoneMeta(deviceID string, expiry time.Time) string {
+	return fmt.Sprintf("%s|%s", deviceID, expiry.Format(time.RFC3339))
+}
+
+// decodeTombstoneMeta deserializes a tombstone meta entry produced by encodeTombstoneMeta
+func decodeTombstoneMeta(val string) (string, time.Time, error) {
+	parts := strings.SplitN(val, "|", 2)
+	if len(parts) != 2 {
+		return "", time.Time{}, newInvalidArgumentError("invalid-tombstone-meta")
+	}
+	expiry, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return parts[0], expiry, nil
+}
+
+// tombstoneDevice preserves the device record under the tombstone path before the device agent
+// removes it from the model.  It is a no-op when soft-delete retention is disabled.
+func (dMgr *DeviceManager) tombstoneDevice(ctx context.Context, id string) {
+	if dMgr.softDeleteRetention <= 0 {
+		return
+	}
+	agent := dMgr.getDeviceAgent(ctx, id)
+	if agent == nil {
+		return
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		logger.Warnw("failed-to-snapshot-device-for-tombstone", log.Fields{"device-id": id, "error": err})
+		return
+	}
+	if err := dMgr.clusterDataProxy.AddWithID(ctx, deviceTombstonePath, id, device); err != nil {
+		logger.Warnw("failed-to-tombstone-device", log.Fields{"device-id": id, "error": err})
+		return
+	}
+	expiry := time.Now().Add(dMgr.softDeleteRetention)
+	meta := &ic.StrType{Val: encodeTombstoneMeta(id, expiry)}
+	if err := dMgr.clusterDataProxy.AddWithID(ctx, deviceTombstoneMetaPath, id, meta); err != nil {
+		logger.Warnw("failed-to-persist-tombstone-meta", log.Fields{"device-id": id, "error": err})
+	}
+	logger.Infow("device-tombstoned", log.Fields{"device-id": id, "purge-at": expiry})
+}
+
+// listDeletedDevices returns the devices currently held under tombstones
+func (dMgr *DeviceManager) listDeletedDevices(ctx context.Context) (*voltha.Devices, error) {
+	logger.Debug("listDeletedDevices")
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, deviceTombstonePath, &devices); err != nil {
+		logger.Errorw("failed-to-list-tombstoned-devices", log.Fields{"error": err})
+		return nil, err
+	}
+	return &voltha.Devices{Items: devices}, nil
+}
+
+// getDeletedDevice returns a tombstoned device, if present
+func (dMgr *DeviceManager) getDeletedDevice(ctx context.Context, id string) (*voltha.Device, error) {
+	device := &voltha.Device{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, deviceTombstonePath+"/"+id, device)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, newNotFoundError("deleted-device", id)
+	}
+	return device, nil
+}
+
+// undeleteDevice restores a tombstoned device, and any tombstoned children, back into the
+// model and re-triggers reconciliation with the adapter.  It fails with NotFound once the
+// retention window has expired and the tombstone has been purged.
+func (dMgr *DeviceManager) undeleteDevice(ctx context.Context, id string) error {
+	logger.Infow("undeleteDevice", log.Fields{"device-id": id})
+	device, err := dMgr.getDeletedDevice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := dMgr.restoreTombstonedDevice(ctx, device); err != nil {
+		return err
+	}
+	// Restore any tombstoned children of the device
+	children, err := dMgr.listDeletedDevices(ctx)
+	if err != nil {
+		return err
+	}
+	for _, child := range children.Items {
+		if child.ParentId == id {
+			if err := dMgr.restoreTombstonedDevice(ctx, child); err != nil {
+				logger.Warnw("failed-to-restore-child-device", log.Fields{"device-id": child.Id, "error": err})
+			}
+		}
+	}
+	return nil
+}
+
+// restoreTombstonedDevice moves a single device from its tombstone back into the model and
+// requests the adapter to reconcile it
+func (dMgr *DeviceManager) restoreTombstonedDevice(ctx context.Context, device *voltha.Device) error {
+	if err := dMgr.removeTombstone(ctx, device.Id); err != nil {
+		return err
+	}
+	// The device left the model in the DELETED state; bring it back disabled so the operator
+	// explicitly re-enables it once satisfied with the restore.
+	if device.AdminState == voltha.AdminState_DELETED {
+		device.AdminState = voltha.AdminState_DISABLED
+	}
+	if err := dMgr.clusterDataProxy.AddWithID(ctx, "devices", device.Id, device); err != nil {
+		logger.Errorw("failed-to-restore-device", log.Fields{"device-id": device.Id, "error": err})
+		return err
+	}
+	dMgr.bumpDeviceRevision(device.Id)
+	if err := dMgr.load(ctx, device.Id); err != nil {
+		return err
+	}
+	logger.Infow("device-restored", log.Fields{"device-id": device.Id})
+	response := dMgr.sendReconcileDeviceRequest(ctx, device)
+	if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+		return status.Errorf(codes.Aborted, "errors-%s", res)
+	}
+	return nil
+}
+
+// removeTombstone deletes the tombstone record and its meta entry
+func (dMgr *DeviceManager) removeTombstone(ctx context.Context, id string) error {
+	if err := dMgr.clusterDataProxy.Remove(ctx, deviceTombstonePath+"/"+id); err != nil {
+		return err
+	}
+	if err := dMgr.clusterDataProxy.Remove(ctx, deviceTombstoneMetaPath+"/"+id); err != nil {
+		logger.Warnw("failed-to-remove-tombstone-meta", log.Fields{"device-id": id, "error": err})
+	}
+	return nil
+}
+
+// runTombstoneReaper periodically purges tombstones whose retention has expired.  It runs
+// until the device manager is stopped.
+func (dMgr *DeviceManager) runTombstoneReaper(ctx context.Context) {
+	ticker := time.NewTicker(tombstoneReaperInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dMgr.purgeExpiredTombstones(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("tombstone-reaper-stopped")
+			return
+		}
+	}
+}
+
+// purgeExpiredTombstones removes every tombstone whose retention window has closed
+func (dMgr *DeviceManager) purgeExpiredTombstones(ctx context.Context) {
+	var metas []*ic.StrType
+	if err := dMgr.clusterDataProxy.List(ctx, deviceTombstoneMetaPath, &metas); err != nil {
+		logger.Warnw("failed-to-list-tombstone-meta", log.Fields{"error": err})
+		return
+	}
+	now := time.Now()
+	for _, meta := range metas {
+		deviceID, expiry, err := decodeTombstoneMeta(meta.Val)
+		if err != nil {
+			logger.Warnw("invalid-tombstone-meta", log.Fields{"value": meta.Val, "error": err})
+			continue
+		}
+		if now.After(expiry) {
+			if err := dMgr.removeTombstone(ctx, deviceID); err != nil {
+				logger.Warnw("failed-to-purge-tombstone", log.Fields{"device-id": deviceID, "error": err})
+				continue
+			}
+			logger.Infow("tombstone-purged", log.Fields{"device-id": deviceID, "expired": expiry})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTombstoneMetaRoundTrip(t *testing.T) {
+	expiry := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	encoded := encodeTombstoneMeta("1234", expiry)
+
+	deviceID, decodedExpiry, err := decodeTombstoneMeta(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", deviceID)
+	assert.True(t, expiry.Equal(decodedExpiry))
+}
+
+func TestTombstoneMetaInvalid(t *testing.T) {
+	_, _, err := decodeTombstoneMeta("not-a-tombstone")
+	assert.NotNil(t, err)
+
+	_, _, err = decodeTombstoneMeta("1234|not-a-timestamp")
+	assert.NotNil(t, err)
+}
+
+func TestSoftDeletedDeviceHiddenAndRestorable(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.softDeleteRetention = time.Hour
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:52")
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	waitUntil(t, 2*time.Second, "device was never removed from the model", func() bool {
+		return deviceGone(dMgr, device.Id)
+	})
+
+	// The soft-deleted device is hidden from the normal listing but held under a tombstone
+	listed, err := dMgr.ListDevices(ctx)
+	assert.Nil(t, err)
+	for _, d := range listed.Items {
+		assert.NotEqual(t, device.Id, d.Id)
+	}
+	tombstoned, err := dMgr.getDeletedDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, tombstoned.Id)
+	deleted, err := dMgr.listDeletedDevices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deleted.Items))
+
+	// Undelete restores the device disabled and asks the adapter to reconcile it
+	assert.Nil(t, dMgr.undeleteDevice(ctx, device.Id))
+	restored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.AdminState_DISABLED, restored.AdminState)
+	assert.Equal(t, 1, fas.callCount("reconcileDevice"))
+
+	// The tombstone was consumed by the restore
+	_, err = dMgr.getDeletedDevice(ctx, device.Id)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestExpiredTombstonePurged(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.softDeleteRetention = time.Millisecond
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:53")
+
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	waitUntil(t, 2*time.Second, "device was never tombstoned", func() bool {
+		_, err := dMgr.getDeletedDevice(ctx, device.Id)
+		return err == nil
+	})
+
+	// Once the retention window closes the reaper pass purges the tombstone
+	waitUntil(t, 2*time.Second, "expired tombstone was never purged", func() bool {
+		dMgr.purgeExpiredTombstones(ctx)
+		_, err := dMgr.getDeletedDevice(ctx, device.Id)
+		return status.Code(err) == codes.NotFound
+	})
+
+	// And a purged device can no longer be restored
+	err := dMgr.undeleteDevice(ctx, device.Id)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2020-present Open Networking Foundation
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ * http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Policies controlling which devices are assigned a dedicated request topic
+const (
+	// topicPolicyDisabled keeps every device on its adapter topic
+	topicPolicyDisabled = "disabled"
+	// topicPolicyDeviceType assigns a dedicated topic to devices whose type is in the configured list
+	topicPolicyDeviceType = "device-type"
+	// topicPolicyMessageRate assigns a dedicated topic to a device once its request rate exceeds the configured threshold
+	topicPolicyMessageRate = "message-rate"
+)
+
+// requestRateWindow is the period over which per-device request rates are measured
+const requestRateWindow = time.Minute
+
+// DeviceTopicManager assigns dedicated kafka request topics to high-volume devices so that a chatty device
+// does not head-of-line block requests destined to the other devices handled by the same adapter.  Topic
+// assignments are persisted in the KV store and restored whenever the device is loaded back in memory.
+type DeviceTopicManager struct {
+	adapterProxy     *AdapterProxy
+	kafkaClient      kafka.Client
+	clusterDataProxy *model.Proxy
+	policy           string
+	deviceTypes      map[string]struct{}
+	msgRateThreshold int
+	requestCounts    sync.Map
+}
+
+// requestCounter tracks the number of requests sent to one device within the current rate window
+type requestCounter struct {
+	lock        sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+func newDeviceTopicManager(ap *AdapterProxy, kafkaClient kafka.Client, cdProxy *model.Proxy, policy string, deviceTypes string, msgRateThreshold int) *DeviceTopicManager {
+	dtMgr := &DeviceTopicManager{
+		adapterProxy:     ap,
+		kafkaClient:      kafkaClient,
+		clusterDataProxy: cdProxy,
+		policy:           policy,
+		deviceTypes:      make(map[string]struct{}),
+		msgRateThreshold: msgRateThreshold,
+	}
+	for _, dType := range strings.Split(deviceTypes, ",") {
+		if dType = strings.TrimSpace(dType); dType != "" {
+			dtMgr.deviceTypes[dType] = struct{}{}
+		}
+	}
+	if policy != topicPolicyDisabled && policy != topicPolicyDeviceType && policy != topicPolicyMessageRate {
+		logger.Warnw("unsupported-device-topic-policy", log.Fields{"policy": policy})
+		dtMgr.policy = topicPolicyDisabled
+	}
+	return dtMgr
+}
+
+// assignDeviceTopic assigns a dedicated request topic to the device if the configured policy calls for one.
+// It is invoked when a device is being enabled, i.e. once the handling adapter is known.
+func (dtMgr *DeviceTopicManager) assignDeviceTopic(ctx context.Context, device *voltha.Device) {
+	if dtMgr.policy != topicPolicyDeviceType {
+		return
+	}
+	if _, ok := dtMgr.deviceTypes[device.Type]; !ok {
+		return
+	}
+	if _, ok := dtMgr.adapterProxy.getRegisteredDeviceTopic(device.Id); ok {
+		return
+	}
+	dtMgr.activateDeviceTopic(ctx, device.Id, device.Adapter)
+}
+
+// recordRequest tracks the request rate towards a device and promotes the device to a dedicated topic once
+// that rate exceeds the configured threshold.  It is invoked on every adapter-bound request.
+func (dtMgr *DeviceTopicManager) recordRequest(ctx context.Context, deviceID string, adapterTopic string) {
+	if dtMgr.policy != topicPolicyMessageRate || dtMgr.msgRateThreshold <= 0 || deviceID == "" {
+		return
+	}
+	if _, ok := dtMgr.adapterProxy.getRegisteredDeviceTopic(deviceID); ok {
+		return
+	}
+	value, _ := dtMgr.requestCounts.LoadOrStore(deviceID, &requestCounter{windowStart: time.Now()})
+	counter := value.(*requestCounter)
+	counter.lock.Lock()
+	if time.Since(counter.windowStart) > requestRateWindow {
+		counter.windowStart = time.Now()
+		counter.count = 0
+	}
+	counter.count++
+	overThreshold := counter.count > dtMgr.msgRateThreshold
+	counter.lock.Unlock()
+
+	if overThreshold {
+		logger.Infow("device-request-rate-over-threshold", log.Fields{"device-id": deviceID, "threshold": dtMgr.msgRateThreshold})
+		dtMgr.requestCounts.Delete(deviceID)
+		dtMgr.activateDeviceTopic(ctx, deviceID, adapterTopic)
+	}
+}
+
+// activateDeviceTopic creates the dedicated topic, persists the assignment and registers it with the adapter
+// proxy so that subsequent requests to the device are routed to it.
+func (dtMgr *DeviceTopicManager) activateDeviceTopic(ctx context.Context, deviceID string, adapterName string) {
+	topic := fmt.Sprintf("%s_%s", adapterName, deviceID)
+	if err := dtMgr.kafkaClient.CreateTopic(&kafka.Topic{Name: topic}, 1, 1); err != nil {
+		logger.Errorw("unable-to-create-device-topic", log.Fields{"device-id": deviceID, "topic": topic, "error": err})
+		return
+	}
+	if err := dtMgr.clusterDataProxy.AddWithID(ctx, "device_topics", deviceID, &ic.StrType{Val: topic}); err != nil {
+		logger.Errorw("unable-to-persist-device-topic", log.Fields{"device-id": deviceID, "topic": topic, "error": err})
+		return
+	}
+	dtMgr.adapterProxy.registerDeviceTopic(deviceID, topic)
+	logger.Infow("device-topic-assigned", log.Fields{"device-id": deviceID, "topic": topic})
+}
+
+// restoreDeviceTopic re-registers a persisted topic assignment when a device is loaded back in memory
+func (dtMgr *DeviceTopicManager) restoreDeviceTopic(ctx context.Context, deviceID string) {
+	if dtMgr.policy == topicPolicyDisabled {
+		return
+	}
+	topic := &ic.StrType{}
+	have, err := dtMgr.clusterDataProxy.Get(ctx, "device_topics/"+deviceID, topic)
+	if err != nil {
+		logger.Errorw("failed-to-get-device-topic-from-cluster-proxy", log.Fields{"device-id": deviceID, "error": err})
+		return
+	}
+	if have {
+		dtMgr.adapterProxy.registerDeviceTopic(deviceID, topic.Val)
+		logger.Debugw("device-topic-restored", log.Fields{"device-id": deviceID, "topic": topic.Val})
+	}
+}
+
+// topicSweepInterval is the period of the stale topic assignment sweep
+const topicSweepInterval = 10 * time.Minute
+
+// findStaleDeviceTopics returns the devices holding a topic assignment even though they no
+// longer exist
+func (dtMgr *DeviceTopicManager) findStaleDeviceTopics(deviceExists func(string) bool) []string {
+	var stale []string
+	dtMgr.adapterProxy.deviceTopics.Range(func(key, value interface{}) bool {
+		if deviceID := key.(string); !deviceExists(deviceID) {
+			stale = append(stale, deviceID)
+		}
+		return true
+	})
+	return stale
+}
+
+// sweepStaleTopics releases topic assignments whose device no longer exists.  Deletion normally
+// releases the topic through the state transition callbacks, but a child stuck in DELETING or a
+// missed callback would otherwise keep the assignment alive forever.
+func (dtMgr *DeviceTopicManager) sweepStaleTopics(ctx context.Context, deviceExists func(string) bool) {
+	for _, deviceID := range dtMgr.findStaleDeviceTopics(deviceExists) {
+		logger.Infow("releasing-stale-device-topic", log.Fields{"device-id": deviceID})
+		dtMgr.releaseDeviceTopic(ctx, deviceID)
+	}
+}
+
+// releaseDeviceTopic tears down the dedicated topic of a device, if any, when the device is deleted
+func (dtMgr *DeviceTopicManager) releaseDeviceTopic(ctx context.Context, deviceID string) {
+	dtMgr.requestCounts.Delete(deviceID)
+	topic, ok := dtMgr.adapterProxy.unregisterDeviceTopic(deviceID)
+	if !ok {
+		return
+	}
+	if err := dtMgr.clusterDataProxy.Remove(ctx, "device_topics/"+deviceID); err != nil {
+		logger.Errorw("unable-to-remove-device-topic", log.Fields{"device-id": deviceID, "topic": topic, "error": err})
+	}
+	if err := dtMgr.kafkaClient.DeleteTopic(&kafka.Topic{Name: topic}); err != nil {
+		logger.Errorw("unable-to-delete-device-topic", log.Fields{"device-id": deviceID, "topic": topic, "error": err})
+	}
+	logger.Infow("device-topic-released", log.Fields{"device-id": deviceID, "topic": topic})
+}
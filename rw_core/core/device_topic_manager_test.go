@@ -0,0 +1,40 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindStaleDeviceTopics(t *testing.T) {
+	ap := &AdapterProxy{}
+	ap.registerDeviceTopic("olt", "adapter_olt")
+	ap.registerDeviceTopic("onu-1", "adapter_onu-1")
+	ap.registerDeviceTopic("onu-2", "adapter_onu-2")
+
+	dtMgr := &DeviceTopicManager{adapterProxy: ap}
+
+	// Only the assignments whose device no longer exists are reported
+	existing := map[string]bool{"olt": true, "onu-1": true}
+	stale := dtMgr.findStaleDeviceTopics(func(deviceID string) bool { return existing[deviceID] })
+	assert.Equal(t, []string{"onu-2"}, stale)
+
+	// Nothing is stale when every device still exists
+	stale = dtMgr.findStaleDeviceTopics(func(deviceID string) bool { return true })
+	assert.Empty(t, stale)
+}
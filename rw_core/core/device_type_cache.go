@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Resolving a device type through the adapter manager costs two map lookups under a write
+// lock, and the flow and child-discovery hot paths resolve the same type on every call.
+// Each device agent therefore caches its resolved *voltha.DeviceType and answers repeat
+// lookups from its own read lock.  Device types rarely change - only an adapter
+// re-registration can alter one - so the adapter manager notifies registered listeners
+// when a registration actually changes a type, and the device manager refreshes the cache
+// of every agent of that type.
+
+// onDeviceTypeChange registers a listener invoked whenever a device type registration
+// changes the stored definition.  Listeners run on the registration path and must be cheap.
+func (aMgr *AdapterManager) onDeviceTypeChange(listener func(*voltha.DeviceType)) {
+	aMgr.lockDeviceTypeListeners.Lock()
+	defer aMgr.lockDeviceTypeListeners.Unlock()
+	aMgr.deviceTypeListeners = append(aMgr.deviceTypeListeners, listener)
+}
+
+// notifyDeviceTypeChanged informs the registered listeners of a changed device type
+func (aMgr *AdapterManager) notifyDeviceTypeChanged(deviceType *voltha.DeviceType) {
+	aMgr.lockDeviceTypeListeners.RLock()
+	defer aMgr.lockDeviceTypeListeners.RUnlock()
+	for _, listener := range aMgr.deviceTypeListeners {
+		listener(deviceType)
+	}
+}
+
+// watchDeviceTypeChanges subscribes the device manager to device type changes so agent
+// caches stay coherent with adapter re-registrations
+func (dMgr *DeviceManager) watchDeviceTypeChanges() {
+	dMgr.adapterMgr.onDeviceTypeChange(func(deviceType *voltha.DeviceType) {
+		dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+			value.(*DeviceAgent).refreshDeviceType(deviceType)
+			return true
+		})
+	})
+}
+
+// loadDeviceType returns the device type of the agent's device, resolving it through the
+// adapter manager only on the first call
+func (agent *DeviceAgent) loadDeviceType() *voltha.DeviceType {
+	agent.deviceTypeCacheLock.RLock()
+	cached := agent.deviceTypeCache
+	agent.deviceTypeCacheLock.RUnlock()
+	if cached != nil && cached.Id == agent.deviceType {
+		return cached
+	}
+	resolved := agent.adapterMgr.getDeviceType(agent.deviceType)
+	if resolved != nil {
+		agent.deviceTypeCacheLock.Lock()
+		agent.deviceTypeCache = resolved
+		agent.deviceTypeCacheLock.Unlock()
+		agent.setCacheFootprint(resolved)
+	}
+	return resolved
+}
+
+// refreshDeviceType replaces the cached device type when the changed type is the one the
+// agent's device is of
+func (agent *DeviceAgent) refreshDeviceType(deviceType *voltha.DeviceType) {
+	if deviceType == nil || deviceType.Id != agent.deviceType {
+		return
+	}
+	cloned := proto.Clone(deviceType).(*voltha.DeviceType)
+	agent.deviceTypeCacheLock.Lock()
+	agent.deviceTypeCache = cloned
+	agent.deviceTypeCacheLock.Unlock()
+	agent.setCacheFootprint(cloned)
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceTypeCachedOnAgent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:26")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	resolved := agent.loadDeviceType()
+	assert.NotNil(t, resolved)
+	assert.Equal(t, "olt", resolved.Id)
+	// Repeat lookups answer from the cache instead of the adapter manager
+	assert.Same(t, resolved, agent.loadDeviceType())
+}
+
+func TestDeviceTypeCacheRefreshedOnRegistrationChange(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.watchDeviceTypeChanges()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:27")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	assert.False(t, agent.loadDeviceType().AcceptsBulkFlowUpdate)
+
+	// An adapter re-registration updating the type lands in the agent's cache
+	assert.Nil(t, dMgr.adapterMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt", Adapter: "adapter-1"}}}, false))
+	cached := agent.loadDeviceType()
+	assert.Equal(t, "adapter-1", cached.Adapter)
+
+	// Re-registering the identical definition must not churn the cache
+	assert.Nil(t, dMgr.adapterMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt", Adapter: "adapter-1"}}}, false))
+	assert.Same(t, cached, agent.loadDeviceType())
+
+	// A changed definition is pushed to the agent's cache
+	assert.Nil(t, dMgr.adapterMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "olt", Adapter: "adapter-1", AcceptsBulkFlowUpdate: true}}}, false))
+	assert.True(t, agent.loadDeviceType().AcceptsBulkFlowUpdate)
+
+	// A change to an unrelated type leaves the cache alone
+	refreshed := agent.loadDeviceType()
+	assert.Nil(t, dMgr.adapterMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "onu", Adapter: "adapter-1", AcceptsBulkFlowUpdate: true}}}, false))
+	assert.Same(t, refreshed, agent.loadDeviceType())
+}
+
+func BenchmarkChildDeviceDetected(b *testing.B) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newHotPathDevice(b, dMgr, "00:00:00:00:4a:28", 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", int64(n%200+1), "", fmt.Sprintf("BENCH%08d", n), int64(n%250+1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Operators and the portal ask which device types the pod supports and which adapter
+// serves each one.  The DeviceType proto carries the registration itself but has no room
+// for the adapter's health or for how many devices of the type actually exist, so the
+// core serves a summary view instead, built from the adapter manager's registrations and
+// the in-memory device agents - no KV reads and no adapter round-trips.
+
+// DeviceTypeSummary pairs a registered device type with its adapter binding and usage
+type DeviceTypeSummary struct {
+	DeviceType *voltha.DeviceType
+	// AdapterID is the adapter the type is bound to in the registration map
+	AdapterID string
+	// AdapterReachable reports whether that adapter was heard from within the liveness
+	// window; it is meaningful only when AdapterHealthKnown is true
+	AdapterReachable   bool
+	AdapterContactAge  time.Duration
+	AdapterHealthKnown bool
+	// AdapterClockSkew is the last measured offset of the adapter host's clock against
+	// this core's; it is meaningful only when AdapterSkewKnown is true
+	AdapterClockSkew time.Duration
+	AdapterSkewKnown bool
+	// DevicesInstantiated counts the non-deleted devices of this type managed by this core
+	DevicesInstantiated int
+}
+
+// countDevicesByType tallies the non-deleted devices managed by this core, keyed by
+// device type
+func (dMgr *DeviceManager) countDevicesByType(ctx context.Context) map[string]int {
+	counts := make(map[string]int)
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		device, err := agent.getDevice(ctx)
+		if err != nil {
+			return true
+		}
+		if device.AdminState == voltha.AdminState_DELETED {
+			return true
+		}
+		counts[device.Type]++
+		return true
+	})
+	return counts
+}
+
+// deviceTypeMatchesVendor reports whether a device type was registered for the vendor,
+// matching both the single VendorId and the VendorIds list case-insensitively
+func deviceTypeMatchesVendor(deviceType *voltha.DeviceType, vendor string) bool {
+	if strings.EqualFold(deviceType.VendorId, vendor) {
+		return true
+	}
+	for _, vendorID := range deviceType.VendorIds {
+		if strings.EqualFold(vendorID, vendor) {
+			return true
+		}
+	}
+	return false
+}
+
+// listDeviceTypeSummaries returns a summary per registered device type, sorted by type
+// ID.  An empty filter matches everything; the sentinel registrations are never reported.
+func (dMgr *DeviceManager) listDeviceTypeSummaries(ctx context.Context, adapterFilter string, vendorFilter string) []*DeviceTypeSummary {
+	counts := dMgr.countDevicesByType(ctx)
+	summaries := make([]*DeviceTypeSummary, 0)
+	for _, deviceType := range dMgr.adapterMgr.listDeviceTypes() {
+		adapterID, err := dMgr.adapterMgr.getAdapterName(deviceType.Id)
+		if err != nil {
+			// A type without an adapter binding cannot serve devices; don't report it
+			continue
+		}
+		if adapterFilter != "" && adapterID != adapterFilter {
+			continue
+		}
+		if vendorFilter != "" && !deviceTypeMatchesVendor(deviceType, vendorFilter) {
+			continue
+		}
+		reachable, age, known := dMgr.adapterMgr.adapterReachability(deviceType.Id)
+		skew, skewKnown := dMgr.adapterMgr.adapterClockSkew(adapterID)
+		summaries = append(summaries, &DeviceTypeSummary{
+			DeviceType:          deviceType,
+			AdapterID:           adapterID,
+			AdapterReachable:    reachable,
+			AdapterContactAge:   age,
+			AdapterHealthKnown:  known,
+			AdapterClockSkew:    skew,
+			AdapterSkewKnown:    skewKnown,
+			DevicesInstantiated: counts[deviceType.Id],
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].DeviceType.Id < summaries[j].DeviceType.Id })
+	return summaries
+}
+
+// getDeviceTypeSummary returns the summary of one registered device type
+func (dMgr *DeviceManager) getDeviceTypeSummary(ctx context.Context, deviceTypeID string) (*DeviceTypeSummary, error) {
+	if deviceTypeID == SentinelDevicetypeID {
+		// The sentinel is an implementation detail of the registration flow and must
+		// never surface, not even when asked for by name
+		return nil, newNotFoundError("device_type", deviceTypeID)
+	}
+	deviceType := dMgr.adapterMgr.getDeviceType(deviceTypeID)
+	if deviceType == nil {
+		return nil, newNotFoundError("device_type", deviceTypeID)
+	}
+	adapterID, err := dMgr.adapterMgr.getAdapterName(deviceTypeID)
+	if err != nil {
+		return nil, err
+	}
+	reachable, age, known := dMgr.adapterMgr.adapterReachability(deviceTypeID)
+	skew, skewKnown := dMgr.adapterMgr.adapterClockSkew(adapterID)
+	return &DeviceTypeSummary{
+		DeviceType:          deviceType,
+		AdapterID:           adapterID,
+		AdapterReachable:    reachable,
+		AdapterContactAge:   age,
+		AdapterHealthKnown:  known,
+		AdapterClockSkew:    skew,
+		AdapterSkewKnown:    skewKnown,
+		DevicesInstantiated: dMgr.countDevicesByType(ctx)[deviceTypeID],
+	}, nil
+}
+
+// ListDeviceTypeSummaries is the NBI surface of the device type inventory
+func (handler *APIHandler) ListDeviceTypeSummaries(ctx context.Context, adapterFilter string, vendorFilter string) ([]*DeviceTypeSummary, error) {
+	ctxDebugw(ctx, "ListDeviceTypeSummaries", log.Fields{"adapter-filter": adapterFilter, "vendor-filter": vendorFilter})
+	return handler.deviceMgr.listDeviceTypeSummaries(ctx, adapterFilter, vendorFilter), nil
+}
+
+// GetDeviceTypeSummary is the NBI surface of the summary of one device type
+func (handler *APIHandler) GetDeviceTypeSummary(ctx context.Context, deviceTypeID string) (*DeviceTypeSummary, error) {
+	ctxDebugw(ctx, "GetDeviceTypeSummary", log.Fields{"device-type": deviceTypeID})
+	return handler.deviceMgr.getDeviceTypeSummary(ctx, deviceTypeID)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newInventoryTestDeviceManager extends the lifecycle fixture with a second adapter that
+// carries vendor information, plus the sentinel registrations the inventory must hide
+func newInventoryTestDeviceManager() (*DeviceManager, *fakeAdapterService) {
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	aMgr := dMgr.adapterMgr
+	aMgr.deviceTypeToAdapterMap["xgspon-olt"] = "adapter-2"
+	aMgr.adapterAgents["adapter-2"] = newAdapterAgent(&voltha.Adapter{Id: "adapter-2"},
+		&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "xgspon-olt", VendorId: "VEND-B", VendorIds: []string{"VNDB"}}}})
+	aMgr.deviceTypeToAdapterMap[SentinelDevicetypeID] = SentinelAdapterID
+	aMgr.adapterAgents[SentinelAdapterID] = newAdapterAgent(&voltha.Adapter{Id: SentinelAdapterID},
+		&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: SentinelDevicetypeID}}})
+	return dMgr, fas
+}
+
+// setAdapterLastCommunication stamps when an adapter of the fixture was last heard from
+func setAdapterLastCommunication(t *testing.T, dMgr *DeviceManager, adapterID string, last time.Time) {
+	agent := dMgr.adapterMgr.adapterAgents[adapterID]
+	assert.NotNil(t, agent)
+	ts, err := ptypes.TimestampProto(last)
+	assert.Nil(t, err)
+	agent.lock.Lock()
+	agent.adapter.LastCommunication = ts
+	agent.lock.Unlock()
+}
+
+func TestDeviceTypeSummaries(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newInventoryTestDeviceManager()
+	setAdapterLastCommunication(t, dMgr, "adapter-1", time.Now())
+	setAdapterLastCommunication(t, dMgr, "adapter-2", time.Now().Add(-5*time.Minute))
+
+	// Two live olts; a deleted one must not be counted
+	createTestDevice(t, dMgr, "00:00:00:00:44:01")
+	createTestDevice(t, dMgr, "00:00:00:00:44:02")
+	deleted := createTestDevice(t, dMgr, "00:00:00:00:44:03")
+	ch := make(chan interface{}, 1)
+	dMgr.deleteDevice(ctx, &voltha.ID{Id: deleted.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	summaries := dMgr.listDeviceTypeSummaries(ctx, "", "")
+	assert.Equal(t, 3, len(summaries))
+	byType := make(map[string]*DeviceTypeSummary)
+	for _, summary := range summaries {
+		byType[summary.DeviceType.Id] = summary
+	}
+	assert.NotContains(t, byType, SentinelDevicetypeID)
+
+	olt := byType["olt"]
+	assert.Equal(t, "adapter-1", olt.AdapterID)
+	assert.True(t, olt.AdapterHealthKnown)
+	assert.True(t, olt.AdapterReachable)
+	assert.Equal(t, 2, olt.DevicesInstantiated)
+	assert.Equal(t, 0, byType["onu"].DevicesInstantiated)
+
+	// The stale adapter is known but no longer considered reachable
+	xgs := byType["xgspon-olt"]
+	assert.Equal(t, "adapter-2", xgs.AdapterID)
+	assert.True(t, xgs.AdapterHealthKnown)
+	assert.False(t, xgs.AdapterReachable)
+	assert.True(t, xgs.AdapterContactAge >= 5*time.Minute)
+
+	// The summaries come back sorted by type ID
+	assert.Equal(t, "olt", summaries[0].DeviceType.Id)
+	assert.Equal(t, "onu", summaries[1].DeviceType.Id)
+	assert.Equal(t, "xgspon-olt", summaries[2].DeviceType.Id)
+}
+
+func TestDeviceTypeSummaryFilters(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newInventoryTestDeviceManager()
+
+	byAdapter := dMgr.listDeviceTypeSummaries(ctx, "adapter-2", "")
+	assert.Equal(t, 1, len(byAdapter))
+	assert.Equal(t, "xgspon-olt", byAdapter[0].DeviceType.Id)
+
+	// The vendor filter is case-insensitive and also matches the VendorIds list
+	byVendor := dMgr.listDeviceTypeSummaries(ctx, "", "vend-b")
+	assert.Equal(t, 1, len(byVendor))
+	assert.Equal(t, "xgspon-olt", byVendor[0].DeviceType.Id)
+	byVendorList := dMgr.listDeviceTypeSummaries(ctx, "", "vndb")
+	assert.Equal(t, 1, len(byVendorList))
+
+	assert.Equal(t, 0, len(dMgr.listDeviceTypeSummaries(ctx, "no-such-adapter", "")))
+	assert.Equal(t, 0, len(dMgr.listDeviceTypeSummaries(ctx, "", "no-such-vendor")))
+}
+
+func TestGetDeviceTypeSummary(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newInventoryTestDeviceManager()
+	setAdapterLastCommunication(t, dMgr, "adapter-1", time.Now())
+	createTestDevice(t, dMgr, "00:00:00:00:44:04")
+
+	summary, err := dMgr.getDeviceTypeSummary(ctx, "olt")
+	assert.Nil(t, err)
+	assert.Equal(t, "adapter-1", summary.AdapterID)
+	assert.True(t, summary.AdapterReachable)
+	assert.Equal(t, 1, summary.DevicesInstantiated)
+
+	_, err = dMgr.getDeviceTypeSummary(ctx, "no-such-type")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The sentinel registration exists internally but must never be served
+	_, err = dMgr.getDeviceTypeSummary(ctx, SentinelDevicetypeID)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
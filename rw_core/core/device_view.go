@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The computed, never-persisted view entries of a device - who owns it, whether its
+// adapter is reachable, how long it has been quiet - used to be stamped into
+// Device.ExtraArgs on every read.  That mutated the proto northbound clients compare
+// against and fought with the persisted annotations sharing the field.  The entries now
+// travel out of band: in-process callers ask the device manager for the metadata map,
+// and northbound clients receive the same entries as gRPC trailer metadata on GetDevice.
+
+// deviceViewMetaPrefix prefixes every view entry delivered as trailer metadata
+const deviceViewMetaPrefix = "voltha-view-"
+
+// deviceViewMeta assembles the computed view entries of a device.  Each contributor
+// stamps only the keys it knows something about, so an empty map means the core has
+// nothing to report beyond the stored device.
+func (dMgr *DeviceManager) deviceViewMeta(ctx context.Context, device *voltha.Device) map[string]string {
+	meta := make(map[string]string)
+	if device == nil {
+		return meta
+	}
+	dMgr.stampOwnershipMeta(device.Id, meta)
+	dMgr.stampAdapterReachabilityMeta(device, meta)
+	dMgr.stampDeviceActivityMeta(ctx, device.Id, meta)
+	dMgr.stampDeviceRevisionMeta(device.Id, meta)
+	return meta
+}
+
+// setDeviceViewTrailer delivers the view entries of a device as gRPC trailer metadata.
+// Outside a gRPC request - unit tests, in-process callers - there is no trailer to set
+// and the entries are simply not delivered.
+func (handler *APIHandler) setDeviceViewTrailer(ctx context.Context, device *voltha.Device) {
+	meta := handler.deviceMgr.deviceViewMeta(ctx, device)
+	if len(meta) == 0 {
+		return
+	}
+	pairs := make([]string, 0, 2*len(meta))
+	for key, value := range meta {
+		pairs = append(pairs, deviceViewMetaPrefix+key, value)
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs(pairs...)); err != nil {
+		ctxDebugw(ctx, "device-view-not-deliverable", log.Fields{"device-id": device.Id, "error": err})
+	}
+}
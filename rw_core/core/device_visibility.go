@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/metadata"
+)
+
+// Deployments slicing one pod across business units want the NBI to only expose the
+// devices of the caller's tenant.  The caller's tenant travels as gRPC metadata
+// ("voltha-tenant"); a device's tenant is stamped as an annotation when the device is
+// created by a tenant-scoped caller.  The reads go through a pluggable visibility filter:
+// the default passes everything through, and the built-in tenant filter hides devices
+// annotated with a different tenant.  A hidden device is reported as NotFound - never
+// PermissionDenied - so its existence does not leak across tenants.  Internal contexts
+// (transition handlers, adapter callbacks) carry no tenant metadata and always see
+// everything.
+
+const (
+	// tenantHeader is the gRPC metadata header carrying the caller's tenant
+	tenantHeader = "voltha-tenant"
+	// tenantAnnotationKey is the device annotation recording which tenant created it
+	tenantAnnotationKey = "tenant"
+)
+
+// deviceVisibilityFilter decides whether a device is exposed to the caller of a read.
+// Implementations must not block; they are invoked once per device on every list.
+type deviceVisibilityFilter interface {
+	visible(ctx context.Context, device *voltha.Device) bool
+}
+
+// passThroughVisibility is the default filter: every device is visible to every caller
+type passThroughVisibility struct{}
+
+func (passThroughVisibility) visible(ctx context.Context, device *voltha.Device) bool {
+	return true
+}
+
+// tenantVisibility hides devices annotated with a tenant different from the caller's.
+// Devices without a tenant annotation are shared infrastructure and stay visible to
+// everyone; a caller without tenant metadata is an operator and sees everything.
+type tenantVisibility struct{}
+
+func (tenantVisibility) visible(ctx context.Context, device *voltha.Device) bool {
+	callerTenant := tenantFromContext(ctx)
+	if callerTenant == "" {
+		return true
+	}
+	deviceTenant := deviceAnnotations(device)[tenantAnnotationKey]
+	return deviceTenant == "" || deviceTenant == callerTenant
+}
+
+// tenantFromContext returns the tenant of the request, or an empty string when the caller
+// did not supply one
+func tenantFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(tenantHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// setVisibilityFilter plugs in a visibility filter; deployments with their own tenancy
+// model replace the built-in ones through this hook
+func (dMgr *DeviceManager) setVisibilityFilter(filter deviceVisibilityFilter) {
+	dMgr.visibilityFilter = filter
+}
+
+// deviceVisible applies the configured visibility filter to a device
+func (dMgr *DeviceManager) deviceVisible(ctx context.Context, device *voltha.Device) bool {
+	if dMgr.visibilityFilter == nil {
+		return true
+	}
+	return dMgr.visibilityFilter.visible(ctx, device)
+}
+
+// deviceIDVisible applies the visibility filter to a device known only by ID.  An unknown
+// device is reported visible; the lookup it feeds will fail on its own terms.
+func (dMgr *DeviceManager) deviceIDVisible(ctx context.Context, deviceID string) bool {
+	if dMgr.visibilityFilter == nil {
+		return true
+	}
+	value, have := dMgr.deviceAgents.Load(deviceID)
+	if !have {
+		return true
+	}
+	return dMgr.deviceVisible(ctx, value.(*DeviceAgent).getDeviceWithoutLock())
+}
+
+// changeEventVisible reports whether a change event of a logical device may be sent to
+// the stream's caller, by checking the visibility of the root device behind it
+func (handler *APIHandler) changeEventVisible(ctx context.Context, logicalDeviceID string) bool {
+	if handler.deviceMgr == nil || handler.deviceMgr.visibilityFilter == nil {
+		return true
+	}
+	agent := handler.logicalDeviceMgr.getLogicalDeviceAgent(ctx, logicalDeviceID)
+	if agent == nil {
+		return true
+	}
+	return handler.deviceMgr.deviceIDVisible(ctx, agent.rootDeviceID)
+}
+
+// stampTenantAnnotation records the caller's tenant on a device about to be created; a
+// caller without tenant metadata leaves the device unannotated
+func (dMgr *DeviceManager) stampTenantAnnotation(ctx context.Context, device *voltha.Device) {
+	tenant := tenantFromContext(ctx)
+	if tenant == "" {
+		return
+	}
+	annotations := deviceAnnotations(device)
+	annotations[tenantAnnotationKey] = tenant
+	device.ExtraArgs = encodeDeviceAnnotations(device.ExtraArgs, annotations)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenantContext builds the context of a caller scoped to the given tenant
+func tenantContext(tenant string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantHeader, tenant))
+}
+
+// createDeviceAs creates a root device under the given caller context
+func createDeviceAs(t *testing.T, dMgr *DeviceManager, ctx context.Context, mac string) *voltha.Device {
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	return device
+}
+
+func TestTenantStampedAndEnforcedOnGet(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.visibilityFilter = tenantVisibility{}
+
+	device := createDeviceAs(t, dMgr, tenantContext("acme"), "00:00:00:00:4a:18")
+	assert.Equal(t, "acme", deviceAnnotations(device)[tenantAnnotationKey])
+
+	// The creating tenant and an operator without tenant metadata see the device
+	_, err := dMgr.GetDevice(tenantContext("acme"), device.Id)
+	assert.Nil(t, err)
+	_, err = dMgr.GetDevice(context.Background(), device.Id)
+	assert.Nil(t, err)
+
+	// Another tenant gets NotFound, not PermissionDenied, so existence does not leak
+	_, err = dMgr.GetDevice(tenantContext("globex"), device.Id)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestTenantFilteringOnLists(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.visibilityFilter = tenantVisibility{}
+
+	acme := createDeviceAs(t, dMgr, tenantContext("acme"), "00:00:00:00:4a:19")
+	globex := createDeviceAs(t, dMgr, tenantContext("globex"), "00:00:00:00:4a:1a")
+	shared := createDeviceAs(t, dMgr, context.Background(), "00:00:00:00:4a:1b")
+
+	// A tenant sees its own devices and the unannotated shared ones
+	devices, err := dMgr.ListDevices(tenantContext("acme"))
+	assert.Nil(t, err)
+	listed := make(map[string]bool)
+	for _, device := range devices.Items {
+		listed[device.Id] = true
+	}
+	assert.True(t, listed[acme.Id])
+	assert.True(t, listed[shared.Id])
+	assert.False(t, listed[globex.Id])
+
+	ids, err := dMgr.ListDeviceIds(tenantContext("acme"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ids.Items))
+
+	// An operator without tenant metadata sees everything
+	devices, err = dMgr.ListDevices(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(devices.Items))
+}
+
+func TestPassThroughVisibilityPreservesBehaviour(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.setVisibilityFilter(passThroughVisibility{})
+
+	device := createDeviceAs(t, dMgr, tenantContext("acme"), "00:00:00:00:4a:1c")
+
+	// With the pass-through filter another tenant still sees the device
+	_, err := dMgr.GetDevice(tenantContext("globex"), device.Id)
+	assert.Nil(t, err)
+}
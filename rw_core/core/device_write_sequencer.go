@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The agent request queue serializes the in-memory updates of a device, but nothing used
+// to guarantee the KV store saw those states in the same order: writes issued from
+// different goroutines could overtake each other on the way out, and a crash could leave
+// the persisted copy reflecting an older state than the one last applied.  Every device
+// write is therefore stamped with a per-device sequence number at the moment it is
+// accepted in memory, and the writes are drained strictly in sequence order: a write that
+// has already been superseded by a newer persisted state is dropped instead of rolling
+// the store back, and rapid consecutive writes queued behind an in-flight one are batched
+// into a single KV update carrying the newest state.
+
+// deviceWriteSequencer orders the KV writes of one device.  Its zero value is ready for
+// use.
+type deviceWriteSequencer struct {
+	// nextSeq is the sequence stamped on the next accepted write; read atomically
+	nextSeq uint64
+	// lock guards the fields below and serializes the KV writes of the device
+	lock sync.Mutex
+	// pendingSeq and pendingDevice hold the newest accepted state not yet persisted
+	pendingSeq    uint64
+	pendingDevice *voltha.Device
+	// persistedSeq is the sequence of the newest state that reached the KV store
+	persistedSeq uint64
+	// writing is set while a goroutine is draining pending writes; later arrivals leave
+	// their state behind for it instead of queuing a KV write of their own
+	writing bool
+}
+
+// nextSequence stamps a state accepted in memory with its position in the applied order
+func (ws *deviceWriteSequencer) nextSequence() uint64 {
+	return atomic.AddUint64(&ws.nextSeq, 1)
+}
+
+// persistSequencedWrite persists the device state carrying the given sequence, dropping
+// it if a newer state already reached the KV store and folding it into an in-flight drain
+// when one is running.  On success the persisted state is also installed as the agent's
+// cached device, keeping cache and store in step.
+func (agent *DeviceAgent) persistSequencedWrite(ctx context.Context, seq uint64, device *voltha.Device) error {
+	ws := &agent.writeSequencer
+	ws.lock.Lock()
+	if seq > ws.pendingSeq {
+		ws.pendingSeq, ws.pendingDevice = seq, device
+	} else {
+		// A newer state was accepted after this one; persisting this write would put the
+		// store behind the applied order, so it is superseded instead
+		ctxDebugw(ctx, "device-write-superseded", log.Fields{"device-id": agent.deviceID, "write-seq": seq, "pending-seq": ws.pendingSeq})
+	}
+	if agent.deviceMgr != nil && agent.deviceMgr.isReadOnly() {
+		// Read-only maintenance: an adapter status report is kept as the cached device
+		// and left in the pending slot for the drain that runs when the mode is left;
+		// any other write is refused outright
+		if stateSourceOf(ctx, "") == stateSourceAdapter {
+			agent.device = ws.pendingDevice
+			ws.lock.Unlock()
+			ctxDebugw(ctx, "device-write-buffered-during-maintenance", log.Fields{"device-id": agent.deviceID, "write-seq": seq})
+			return nil
+		}
+		ws.lock.Unlock()
+		return status.Errorf(codes.Unavailable, "device-update-unavailable: maintenance-read-only")
+	}
+	if ws.writing {
+		// The draining goroutine will pick up the newest pending state, batching rapid
+		// consecutive writes into one KV update
+		ws.lock.Unlock()
+		return nil
+	}
+	return agent.drainPendingWritesLocked(ctx)
+}
+
+// drainPendingWritesLocked pushes the pending states out to the KV store in sequence
+// order.  The caller must hold the sequencer lock; it is released on return.
+func (agent *DeviceAgent) drainPendingWritesLocked(ctx context.Context) error {
+	ws := &agent.writeSequencer
+	ws.writing = true
+	var writeErr error
+	var lastWritten *voltha.Device
+	fenced := false
+	for ws.pendingSeq > ws.persistedSeq {
+		writeSeq, writeDevice := ws.pendingSeq, ws.pendingDevice
+		ws.lock.Unlock()
+		// The write goes out under the fencing token this instance acquired the device
+		// with; a store already carrying a newer token means a peer owns the device now
+		if agent.deviceMgr != nil {
+			if err := agent.deviceMgr.checkWriteFence(ctx, agent.deviceID); err != nil {
+				ws.lock.Lock()
+				writeErr = err
+				fenced = true
+				break
+			}
+		}
+		updateCtx := context.WithValue(ctx, model.RequestTimestamp, time.Now().UnixNano())
+		err := agent.clusterDataProxy.Update(updateCtx, "devices/"+agent.deviceID, writeDevice)
+		ws.lock.Lock()
+		if err != nil {
+			// A write failure can be the first sign of a KV store restored from backup
+			go agent.deviceMgr.checkClusterEpoch(context.Background())
+			writeErr = status.Errorf(codes.Internal, "failed-update-device:%s: %s", agent.deviceID, err)
+			break
+		}
+		ws.persistedSeq = writeSeq
+		agent.device = writeDevice
+		lastWritten = writeDevice
+		agent.lastKVSync = time.Now()
+		agent.deviceMgr.markDeviceActivity(ctx, agent.deviceID, activityModelWrite)
+		agent.deviceMgr.bumpDeviceRevision(agent.deviceID)
+		logger.Debugw("updated-device-in-store", log.Fields{"deviceId: ": agent.deviceID, "write-seq": writeSeq})
+	}
+	ws.writing = false
+	ws.lock.Unlock()
+	if fenced {
+		// A fenced write is an ownership loss learned the hard way: cancel the in-flight
+		// and queued work instead of letting it keep colliding with the new owner
+		agent.loseOwnership()
+	}
+	if writeErr == nil && lastWritten != nil {
+		agent.maybeSnapshot(ctx, lastWritten)
+	}
+	return writeErr
+}
+
+// flushPendingWrites persists any state buffered during read-only maintenance
+func (agent *DeviceAgent) flushPendingWrites(ctx context.Context) error {
+	ws := &agent.writeSequencer
+	ws.lock.Lock()
+	if ws.writing || ws.pendingSeq <= ws.persistedSeq {
+		ws.lock.Unlock()
+		return nil
+	}
+	return agent.drainPendingWritesLocked(ctx)
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-lib-go/v3/pkg/probe"
+)
+
+// When the core degrades the first question is always "what is it stuck on", and the
+// answer used to require a debugger.  The diagnostics collector below gives the core a
+// quick self-report: each subsystem registers a stats provider - per-device request queue
+// depths, ownership reservation counts, in-flight adapter requests, event publish
+// failures - and a single debug query returns all of them as one JSON document.  Providers
+// only tap their own locks or atomics so the endpoint can be polled frequently.  A
+// provider can raise alerts - an agent holding its queue beyond the stall threshold, an
+// adapter request in flight too long - and any active alert flips the dedicated probe
+// service to not-ready so the degradation shows up on the existing health surface.
+
+// diagnosticsProbeService is the probe service carrying the summarized health verdict
+const diagnosticsProbeService = "diagnostics"
+
+// diagnosticsProvider returns the current stats of one subsystem.  Alerts are reported
+// under the alerts key as a []string.
+type diagnosticsProvider func() map[string]interface{}
+
+// alertsKey is the stats key the collector inspects for threshold violations
+const alertsKey = "alerts"
+
+type diagnosticsCollector struct {
+	lock      sync.RWMutex
+	providers map[string]diagnosticsProvider
+	// probe is the health reporter fed with the summarized verdict; nil outside a
+	// full core (tests)
+	probe *probe.Probe
+	// stallThreshold is how long an operation may run before it counts as stuck
+	stallThreshold time.Duration
+}
+
+func newDiagnosticsCollector(stallThreshold time.Duration) *diagnosticsCollector {
+	return &diagnosticsCollector{
+		providers:      make(map[string]diagnosticsProvider),
+		stallThreshold: stallThreshold,
+	}
+}
+
+// register adds the stats provider of a subsystem, replacing any previous one
+func (dc *diagnosticsCollector) register(name string, provider diagnosticsProvider) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	dc.providers[name] = provider
+}
+
+// collect samples every provider and returns the stats per subsystem
+func (dc *diagnosticsCollector) collect() map[string]map[string]interface{} {
+	dc.lock.RLock()
+	providers := make(map[string]diagnosticsProvider, len(dc.providers))
+	for name, provider := range dc.providers {
+		providers[name] = provider
+	}
+	dc.lock.RUnlock()
+
+	stats := make(map[string]map[string]interface{}, len(providers))
+	for name, provider := range providers {
+		stats[name] = provider()
+	}
+	return stats
+}
+
+// alertsOf extracts the active alerts from a collected snapshot
+func alertsOf(stats map[string]map[string]interface{}) []string {
+	var alerts []string
+	for _, subsystem := range stats {
+		if raised, ok := subsystem[alertsKey].([]string); ok {
+			alerts = append(alerts, raised...)
+		}
+	}
+	return alerts
+}
+
+// collectJSON samples the providers, pushes the summarized verdict to the probe and
+// returns the snapshot as JSON
+func (dc *diagnosticsCollector) collectJSON(ctx context.Context) ([]byte, error) {
+	stats := dc.collect()
+	alerts := alertsOf(stats)
+	if dc.probe != nil {
+		if len(alerts) > 0 {
+			dc.probe.UpdateStatus(diagnosticsProbeService, probe.ServiceStatusNotReady)
+		} else {
+			dc.probe.UpdateStatus(diagnosticsProbeService, probe.ServiceStatusRunning)
+		}
+	}
+	if len(alerts) > 0 {
+		ctxWarnw(ctx, "diagnostics-alerts-active", log.Fields{"alerts": alerts})
+	}
+	return json.Marshal(stats)
+}
+
+// registerDiagnostics declares the device manager's stats providers: the per-device
+// request queues, the ownership reservations and the memory footprint accounting
+func (dMgr *DeviceManager) registerDiagnostics() {
+	dMgr.diagnostics.register("memory-footprint", dMgr.footprintDiagnostics)
+	dMgr.diagnostics.register("device-manager", func() map[string]interface{} {
+		devices := 0
+		queueDepths := make(map[string]int)
+		var alerts []string
+		dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+			devices++
+			agent := value.(*DeviceAgent)
+			if depth := agent.requestQueue.Depth(); depth > 0 {
+				queueDepths[agent.deviceID] = depth
+			}
+			if held := agent.requestQueue.InProgressFor(); held > dMgr.diagnostics.stallThreshold {
+				alerts = append(alerts, fmt.Sprintf("device-%s-operation-running-%v", agent.deviceID, held.Truncate(time.Millisecond)))
+			}
+			return true
+		})
+		return map[string]interface{}{
+			"devices":        devices,
+			"queue-depths":   queueDepths,
+			"read-only-mode": dMgr.isReadOnly(),
+			alertsKey:        alerts,
+		}
+	})
+	dMgr.diagnostics.register("device-ownership", func() map[string]interface{} {
+		reservations := 0
+		var oldest time.Duration
+		dMgr.ownershipRenewals.Range(func(key, value interface{}) bool {
+			reservations++
+			if age := dMgr.now().Sub(value.(time.Time)); age > oldest {
+				oldest = age
+			}
+			return true
+		})
+		return map[string]interface{}{
+			"reservations":       reservations,
+			"oldest-renewal-age": oldest.Truncate(time.Millisecond).String(),
+		}
+	})
+}
+
+// diagnostics is the adapter proxy's stats provider: the in-flight and recently failed
+// adapter requests
+func (ap *AdapterProxy) diagnostics(stallThreshold time.Duration) diagnosticsProvider {
+	return func() map[string]interface{} {
+		pending := ap.pendingRequests.listPending("")
+		var oldest time.Duration
+		var alerts []string
+		for _, request := range pending {
+			age := time.Since(request.sentAt)
+			if age > oldest {
+				oldest = age
+			}
+			if age > stallThreshold {
+				alerts = append(alerts, fmt.Sprintf("adapter-request-%s-device-%s-in-flight-%v", request.rpc, request.deviceID, age.Truncate(time.Millisecond)))
+			}
+		}
+		return map[string]interface{}{
+			"pending-requests":   len(pending),
+			"oldest-pending-age": oldest.Truncate(time.Millisecond).String(),
+			"timed-out-records":  len(ap.pendingRequests.listTimedOut()),
+			alertsKey:            alerts,
+		}
+	}
+}
+
+// diagnostics is the event manager's stats provider: how the kafka sends are doing
+func (eMgr *EventManager) diagnostics() map[string]interface{} {
+	return map[string]interface{}{
+		"events-published": atomic.LoadInt64(&eMgr.eventsPublished),
+		"publish-failures": atomic.LoadInt64(&eMgr.eventPublishFailures),
+	}
+}
+
+// GetDiagnostics is the debug query returning the self-report of every registered
+// subsystem as one JSON document
+func (handler *APIHandler) GetDiagnostics(ctx context.Context) (string, error) {
+	ctxDebugw(ctx, "GetDiagnostics", nil)
+	if handler.deviceMgr.diagnostics == nil {
+		return "", newUnavailableError("get-diagnostics", "collector", nil)
+	}
+	encoded, err := handler.deviceMgr.diagnostics.collectJSON(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
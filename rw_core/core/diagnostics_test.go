@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/probe"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDiagnosticsTestManager wires the collector the way newDeviceManager does, with an
+// injectable stall threshold
+func newDiagnosticsTestManager(stallThreshold time.Duration) (*DeviceManager, *diagnosticsCollector) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.diagnostics = newDiagnosticsCollector(stallThreshold)
+	dMgr.registerDiagnostics()
+	dMgr.diagnostics.register("event-manager", dMgr.eventMgr.diagnostics)
+	return dMgr, dMgr.diagnostics
+}
+
+func TestDiagnosticsSnapshotIsValidJSON(t *testing.T) {
+	ctx := context.Background()
+	dMgr, dc := newDiagnosticsTestManager(time.Minute)
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:29")
+	dMgr.ownershipRenewals.Store(device.Id, time.Now())
+
+	encoded, err := dc.collectJSON(ctx)
+	assert.Nil(t, err)
+	var decoded map[string]map[string]interface{}
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, float64(1), decoded["device-manager"]["devices"])
+	assert.Equal(t, float64(1), decoded["device-ownership"]["reservations"])
+	assert.NotNil(t, decoded["event-manager"]["events-published"])
+}
+
+func TestDiagnosticsFlagsStalledAgent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, dc := newDiagnosticsTestManager(time.Millisecond)
+	p := &probe.Probe{}
+	p.RegisterService(diagnosticsProbeService)
+	dc.probe = p
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:2a")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	// Hold the agent's queue past the stall threshold
+	assert.Nil(t, agent.requestQueue.WaitForGreenLight(ctx))
+	time.Sleep(20 * time.Millisecond)
+
+	stats := dc.collect()
+	alerts := alertsOf(stats)
+	assert.Equal(t, 1, len(alerts))
+	assert.Contains(t, alerts[0], device.Id)
+
+	// Polling the endpoint pushes the verdict onto the probe
+	_, err := dc.collectJSON(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, probe.ServiceStatusNotReady, p.GetStatus(diagnosticsProbeService))
+
+	// Releasing the queue clears the alert and the probe recovers
+	agent.requestQueue.RequestComplete()
+	_, err = dc.collectJSON(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(alertsOf(dc.collect())))
+	assert.Equal(t, probe.ServiceStatusRunning, p.GetStatus(diagnosticsProbeService))
+}
+
+func TestRequestQueueDepthAndProgressAccounting(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newDiagnosticsTestManager(time.Minute)
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:2b")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	assert.Equal(t, 0, agent.requestQueue.Depth())
+	assert.Equal(t, time.Duration(0), agent.requestQueue.InProgressFor())
+
+	assert.Nil(t, agent.requestQueue.WaitForGreenLight(ctx))
+	assert.Equal(t, 1, agent.requestQueue.Depth())
+	assert.True(t, agent.requestQueue.InProgressFor() >= 0)
+
+	// A canceled waiter must not leak into the depth
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NotNil(t, agent.requestQueue.WaitForGreenLight(canceled))
+	assert.Equal(t, 1, agent.requestQueue.Depth())
+
+	agent.requestQueue.RequestComplete()
+	assert.Equal(t, 0, agent.requestQueue.Depth())
+	assert.Equal(t, time.Duration(0), agent.requestQueue.InProgressFor())
+}
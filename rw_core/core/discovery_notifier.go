@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// A whole-OLT activation discovers hundreds of ONUs in a burst, and publishing one
+// DeviceDiscovered message per ONU from a fire-and-forget goroutine both hammers kafka and
+// loses notifications silently when the client hiccups.  Discoveries are instead collected
+// for a short window and published as one aggregated message per parent and device type,
+// with the device IDs comma-joined in the Id field.  Publishes are retried with backoff,
+// and notifications abandoned after the last attempt are counted so the loss is visible.
+// Consumers that cannot parse the aggregated form can set per_device_discovery to get the
+// original one-message-per-device behaviour, still with the retry.
+
+const (
+	// discoveryPublishAttempts is how often a discovery publish is tried before giving up
+	discoveryPublishAttempts = 3
+	// discoveryRetryBackoff is the wait before the first retry; it doubles per attempt
+	discoveryRetryBackoff = 500 * time.Millisecond
+)
+
+// discoveryNotification is one device awaiting publication on the discovery topic
+type discoveryNotification struct {
+	deviceID   string
+	deviceType string
+	parentID   string
+}
+
+// notifyDeviceDiscovered queues a discovery for batched publication, or publishes it on
+// its own when batching is disabled or the compatibility flag asks for per-device messages
+func (dMgr *DeviceManager) notifyDeviceDiscovered(ctx context.Context, deviceID string, deviceType string, parentID string) {
+	notification := discoveryNotification{deviceID: deviceID, deviceType: deviceType, parentID: parentID}
+	if dMgr.perDeviceDiscovery || dMgr.discoveryBatchWindow <= 0 {
+		go dMgr.publishDiscoveries(ctx, []discoveryNotification{notification})
+		return
+	}
+	dMgr.discoveryLock.Lock()
+	dMgr.pendingDiscoveries = append(dMgr.pendingDiscoveries, notification)
+	if dMgr.discoveryBatchSize > 0 && len(dMgr.pendingDiscoveries) >= dMgr.discoveryBatchSize {
+		batch := dMgr.pendingDiscoveries
+		dMgr.pendingDiscoveries = nil
+		dMgr.discoveryLock.Unlock()
+		go dMgr.publishDiscoveries(ctx, batch)
+		return
+	}
+	if !dMgr.discoveryFlushArmed {
+		dMgr.discoveryFlushArmed = true
+		go dMgr.flushDiscoveriesAfterWindow(context.Background())
+	}
+	dMgr.discoveryLock.Unlock()
+}
+
+// flushDiscoveriesAfterWindow publishes whatever accumulated once the batching window
+// closes.  One flush goroutine is armed at a time; the size-based flush above may empty
+// the queue first, in which case this one finds nothing to do.
+func (dMgr *DeviceManager) flushDiscoveriesAfterWindow(ctx context.Context) {
+	select {
+	case <-dMgr.after(dMgr.discoveryBatchWindow):
+	case <-dMgr.exitChannel:
+	}
+	dMgr.discoveryLock.Lock()
+	batch := dMgr.pendingDiscoveries
+	dMgr.pendingDiscoveries = nil
+	dMgr.discoveryFlushArmed = false
+	dMgr.discoveryLock.Unlock()
+	if len(batch) > 0 {
+		dMgr.publishDiscoveries(ctx, batch)
+	}
+}
+
+// publishDiscoveries groups a batch by parent and device type and publishes one aggregated
+// message per group, the device IDs comma-joined in the Id field
+func (dMgr *DeviceManager) publishDiscoveries(ctx context.Context, batch []discoveryNotification) {
+	type groupKey struct{ parentID, deviceType string }
+	groups := make(map[groupKey][]string)
+	var order []groupKey
+	for _, notification := range batch {
+		key := groupKey{parentID: notification.parentID, deviceType: notification.deviceType}
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], notification.deviceID)
+	}
+	for _, key := range order {
+		dMgr.publishDiscoveryWithRetry(ctx, strings.Join(groups[key], ","), key.deviceType, key.parentID)
+	}
+}
+
+// publishDiscoveryWithRetry publishes one discovery message, retrying with doubling
+// backoff; a message still failing after the last attempt is counted as abandoned
+func (dMgr *DeviceManager) publishDiscoveryWithRetry(ctx context.Context, deviceIDs string, deviceType string, parentID string) {
+	backoff := discoveryRetryBackoff
+	for attempt := 1; attempt <= discoveryPublishAttempts; attempt++ {
+		err := dMgr.kafkaICProxy.DeviceDiscovered(deviceIDs, deviceType, parentID, dMgr.coreInstanceID)
+		if err == nil {
+			return
+		}
+		ctxWarnw(ctx, "device-discovery-publish-failed", log.Fields{"device-ids": deviceIDs, "parent-id": parentID, "attempt": attempt, "error": err})
+		if attempt < discoveryPublishAttempts {
+			select {
+			case <-dMgr.after(backoff):
+			case <-dMgr.exitChannel:
+				return
+			}
+			backoff *= 2
+		}
+	}
+	atomic.AddInt64(&dMgr.failedDiscoveryCount, 1)
+	ctxErrorw(ctx, "device-discovery-publish-abandoned", log.Fields{"device-ids": deviceIDs, "parent-id": parentID, "attempts": discoveryPublishAttempts})
+}
+
+// failedDiscoveryNotifications returns how many discovery messages were abandoned after
+// exhausting their retries
+func (dMgr *DeviceManager) failedDiscoveryNotifications() int64 {
+	return atomic.LoadInt64(&dMgr.failedDiscoveryCount)
+}
+
+// GetFailedDiscoveryNotifications is the debug query exposing the abandoned discovery count
+func (handler *APIHandler) GetFailedDiscoveryNotifications(ctx context.Context) int64 {
+	ctxDebugw(ctx, "GetFailedDiscoveryNotifications", log.Fields{})
+	return handler.deviceMgr.failedDiscoveryNotifications()
+}
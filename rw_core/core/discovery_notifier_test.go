@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// publishedDiscovery records one DeviceDiscovered message sent to the fake kafka proxy
+type publishedDiscovery struct {
+	deviceIDs  string
+	deviceType string
+	parentID   string
+}
+
+// discoveryRecordingKafkaProxy counts DeviceDiscovered publishes and can be told to fail
+// the first n of them
+type discoveryRecordingKafkaProxy struct {
+	*mock_kafka.MockKafkaICProxy
+	lock      sync.Mutex
+	published []publishedDiscovery
+	failures  int
+	attempts  int
+}
+
+func (p *discoveryRecordingKafkaProxy) DeviceDiscovered(deviceID string, deviceType string, parentID string, publisher string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.attempts++
+	if p.failures > 0 {
+		p.failures--
+		return newUnavailableError("kafka", "discovery-topic", nil)
+	}
+	p.published = append(p.published, publishedDiscovery{deviceIDs: deviceID, deviceType: deviceType, parentID: parentID})
+	return nil
+}
+
+func (p *discoveryRecordingKafkaProxy) publishCount() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.published)
+}
+
+func (p *discoveryRecordingKafkaProxy) attemptCount() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.attempts
+}
+
+func (p *discoveryRecordingKafkaProxy) message(i int) publishedDiscovery {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.published[i]
+}
+
+func TestDiscoveryNotificationsAreBatched(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	proxy := &discoveryRecordingKafkaProxy{MockKafkaICProxy: &mock_kafka.MockKafkaICProxy{}}
+	dMgr.kafkaICProxy = proxy
+	dMgr.discoveryBatchWindow = 20 * time.Millisecond
+	dMgr.discoveryBatchSize = 10
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-1", "onu", "olt-1")
+	dMgr.notifyDeviceDiscovered(ctx, "onu-2", "onu", "olt-1")
+	dMgr.notifyDeviceDiscovered(ctx, "onu-3", "onu", "olt-1")
+
+	waitUntil(t, 2*time.Second, "batched discovery was never published", func() bool {
+		return proxy.publishCount() == 1
+	})
+	message := proxy.message(0)
+	assert.Equal(t, "onu-1,onu-2,onu-3", message.deviceIDs)
+	assert.Equal(t, "onu", message.deviceType)
+	assert.Equal(t, "olt-1", message.parentID)
+}
+
+func TestDiscoveryBatchSizeForcesFlush(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	proxy := &discoveryRecordingKafkaProxy{MockKafkaICProxy: &mock_kafka.MockKafkaICProxy{}}
+	dMgr.kafkaICProxy = proxy
+	// The window is far away; only the size limit can trigger the flush
+	dMgr.discoveryBatchWindow = time.Minute
+	dMgr.discoveryBatchSize = 2
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-1", "onu", "olt-1")
+	dMgr.notifyDeviceDiscovered(ctx, "onu-2", "onu", "olt-1")
+
+	waitUntil(t, 2*time.Second, "size limit did not flush the batch", func() bool {
+		return proxy.publishCount() == 1
+	})
+	assert.Equal(t, "onu-1,onu-2", proxy.message(0).deviceIDs)
+}
+
+func TestDiscoveryBatchesGroupByParent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	proxy := &discoveryRecordingKafkaProxy{MockKafkaICProxy: &mock_kafka.MockKafkaICProxy{}}
+	dMgr.kafkaICProxy = proxy
+	dMgr.discoveryBatchWindow = 20 * time.Millisecond
+	dMgr.discoveryBatchSize = 10
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-1", "onu", "olt-1")
+	dMgr.notifyDeviceDiscovered(ctx, "onu-2", "onu", "olt-2")
+
+	waitUntil(t, 2*time.Second, "per-parent batches were never published", func() bool {
+		return proxy.publishCount() == 2
+	})
+	assert.Equal(t, "onu-1", proxy.message(0).deviceIDs)
+	assert.Equal(t, "olt-1", proxy.message(0).parentID)
+	assert.Equal(t, "onu-2", proxy.message(1).deviceIDs)
+	assert.Equal(t, "olt-2", proxy.message(1).parentID)
+}
+
+func TestPerDeviceDiscoveryCompatibility(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	proxy := &discoveryRecordingKafkaProxy{MockKafkaICProxy: &mock_kafka.MockKafkaICProxy{}}
+	dMgr.kafkaICProxy = proxy
+	dMgr.discoveryBatchWindow = time.Minute
+	dMgr.perDeviceDiscovery = true
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-1", "onu", "olt-1")
+	dMgr.notifyDeviceDiscovered(ctx, "onu-2", "onu", "olt-1")
+
+	waitUntil(t, 2*time.Second, "per-device discoveries were never published", func() bool {
+		return proxy.publishCount() == 2
+	})
+	// The two messages are published from independent goroutines; order is not guaranteed
+	published := map[string]bool{proxy.message(0).deviceIDs: true, proxy.message(1).deviceIDs: true}
+	assert.True(t, published["onu-1"])
+	assert.True(t, published["onu-2"])
+}
+
+func TestDiscoveryPublishRetriesAndCountsFailures(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	clock := newFakeClock(time.Now())
+	dMgr.clock = clock
+	// Enough failures to exhaust every retry of the first message; the second succeeds
+	// on its retry
+	proxy := &discoveryRecordingKafkaProxy{MockKafkaICProxy: &mock_kafka.MockKafkaICProxy{}, failures: discoveryPublishAttempts + 1}
+	dMgr.kafkaICProxy = proxy
+	// Window 0: each notification publishes on its own, exercising only the retry path
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-1", "onu", "olt-1")
+	waitUntil(t, 2*time.Second, "first discovery was never abandoned", func() bool {
+		clock.advance(time.Second)
+		return dMgr.failedDiscoveryNotifications() == 1
+	})
+	assert.Equal(t, discoveryPublishAttempts, proxy.attemptCount())
+	assert.Equal(t, 0, proxy.publishCount())
+
+	dMgr.notifyDeviceDiscovered(ctx, "onu-2", "onu", "olt-1")
+	waitUntil(t, 2*time.Second, "second discovery never succeeded on retry", func() bool {
+		clock.advance(time.Second)
+		return proxy.publishCount() == 1
+	})
+	assert.Equal(t, int64(1), dMgr.failedDiscoveryNotifications())
+	assert.Equal(t, "onu-2", proxy.message(0).deviceIDs)
+}
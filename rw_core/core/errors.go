@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The helpers below produce the gRPC statuses returned by the core on failure.  Northbound
+// clients key their behaviour off the status code alone, so each failure class maps to one
+// stable code:
+//
+//	NotFound           - the referenced entity is not known to the core
+//	InvalidArgument    - a request argument is missing or malformed
+//	FailedPrecondition - the entity exists but its current state does not allow the operation
+//	Unavailable        - a dependent component could not be reached; the request may be retried
+//	ResourceExhausted  - the caller ran out of quota; the request may be retried after the hinted delay
+//
+// Each helper identifies the affected entity in the status message and preserves the
+// underlying cause when one is supplied.
+
+// newNotFoundError returns a NotFound status identifying the entity that could not be located
+func newNotFoundError(entity string, id interface{}) error {
+	return status.Errorf(codes.NotFound, "%s-not-found: %v", entity, id)
+}
+
+// newInvalidArgumentError returns an InvalidArgument status describing the offending argument
+func newInvalidArgumentError(reason string) error {
+	return status.Error(codes.InvalidArgument, reason)
+}
+
+// newFailedPreconditionError returns a FailedPrecondition status explaining why the current
+// state of the entity does not allow the requested operation
+func newFailedPreconditionError(entity string, id interface{}, reason string) error {
+	return status.Errorf(codes.FailedPrecondition, "%s %v: %s", entity, id, reason)
+}
+
+// newUnavailableError returns an Unavailable status for an operation that could not reach a
+// dependent component, preserving the underlying cause when one is supplied
+func newUnavailableError(operation string, id interface{}, cause error) error {
+	if cause != nil {
+		return status.Errorf(codes.Unavailable, "%s-failed: %v: %v", operation, id, cause)
+	}
+	return status.Errorf(codes.Unavailable, "%s-failed: %v", operation, id)
+}
+
+// newResourceExhaustedError returns a ResourceExhausted status carrying the delay after
+// which the caller may retry the operation
+func newResourceExhaustedError(operation string, retryAfter time.Duration) error {
+	return status.Errorf(codes.ResourceExhausted, "%s-quota-exceeded: retry-after %v", operation, retryAfter)
+}
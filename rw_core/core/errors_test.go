@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorHelperCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"not-found", newNotFoundError("device", "1234"), codes.NotFound},
+		{"invalid-argument", newInvalidArgumentError("device-id-empty"), codes.InvalidArgument},
+		{"failed-precondition", newFailedPreconditionError("device", "1234", "not-a-root-device"), codes.FailedPrecondition},
+		{"unavailable", newUnavailableError("load-device", "1234", nil), codes.Unavailable},
+		{"unavailable-with-cause", newUnavailableError("load-device", "1234", errors.New("kv-timeout")), codes.Unavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.code, status.Code(test.err))
+		})
+	}
+}
+
+func TestErrorHelperMessages(t *testing.T) {
+	err := newNotFoundError("device", "1234")
+	assert.True(t, strings.Contains(err.Error(), "device"))
+	assert.True(t, strings.Contains(err.Error(), "1234"))
+
+	err = newFailedPreconditionError("device", "1234", "not-a-root-device")
+	assert.True(t, strings.Contains(err.Error(), "not-a-root-device"))
+
+	// the underlying cause must be preserved in the status message
+	cause := errors.New("kv-timeout")
+	err = newUnavailableError("load-device", "1234", cause)
+	assert.True(t, strings.Contains(err.Error(), cause.Error()))
+}
@@ -0,0 +1,455 @@
+/*
+ * Copyright 2020-present Open Networking Foundation
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ * http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventTopic is the topic on which every event published by the core is sent
+const eventTopic = "voltha.events"
+
+const (
+	// deviceStateChangeEvent is the name of the device event raised on a device state transition
+	deviceStateChangeEvent = "DEVICE_STATE_CHANGE_EVENT"
+	// flowsRestoredEvent is raised when the flows of a re-enabled device were re-pushed to the adapter
+	flowsRestoredEvent = "DEVICE_FLOWS_RESTORED_EVENT"
+	// flowsRestoreFailureEvent is raised when re-pushing the flows of a re-enabled device failed
+	flowsRestoreFailureEvent = "DEVICE_FLOWS_RESTORE_FAILURE_EVENT"
+	// deviceActivatedEvent is raised when a device reaches the ACTIVE operational state
+	deviceActivatedEvent = "DEVICE_ACTIVATED_EVENT"
+	// deviceFlappingEvent is raised once when a flapping device enters the dampened state
+	deviceFlappingEvent = "DEVICE_FLAPPING_EVENT"
+	// flowTableUtilizationEvent is raised when the flow table of a device nears its capacity
+	flowTableUtilizationEvent = "DEVICE_FLOW_TABLE_UTILIZATION_EVENT"
+	// deviceFootprintEvent is raised when the memory footprint of a device crosses the soft cap
+	deviceFootprintEvent = "DEVICE_MEMORY_FOOTPRINT_EVENT"
+	// imageChecksumMismatchEvent is raised when a downloaded image fails checksum verification
+	imageChecksumMismatchEvent = "IMAGE_CHECKSUM_MISMATCH_EVENT"
+	// packetStormEvent is raised when the packet-in volume on a port crosses the storm threshold
+	packetStormEvent = "DEVICE_PACKET_STORM_EVENT"
+	// coreResyncedEvent is raised after the core resynced its state from a restored KV store
+	coreResyncedEvent = "CORE_RESYNCED_FROM_KV_EVENT"
+	// deviceStuckEvent is raised when a device stuck in a transient state is marked FAILED
+	deviceStuckEvent = "DEVICE_STUCK_IN_TRANSIENT_STATE_EVENT"
+	// duplicatePeerParentEvent is raised when a child device is peered under a device other than its recorded parent
+	duplicatePeerParentEvent = "DEVICE_DUPLICATE_PEER_PARENT_EVENT"
+	// deviceAddressChangeEvent is raised when the management address of a root device is rewritten
+	deviceAddressChangeEvent = "DEVICE_ADDRESS_CHANGE_EVENT"
+	// deviceMaintenanceSetEvent is raised when a device is placed in maintenance mode
+	deviceMaintenanceSetEvent = "DEVICE_MAINTENANCE_SET_EVENT"
+	// deviceMaintenanceClearedEvent is raised when maintenance mode is lifted from a device
+	deviceMaintenanceClearedEvent = "DEVICE_MAINTENANCE_CLEARED_EVENT"
+	// deletionBarrierFailedEvent is raised when a deletion proceeded without the adapter
+	// acknowledging the flow removal, so hardware cleanup may be needed
+	deletionBarrierFailedEvent = "DEVICE_DELETION_BARRIER_FAILED_EVENT"
+	// deletionStuckEvent is raised when a deletion-intent marker outlives the resume
+	// attempts past the staleness threshold
+	deletionStuckEvent = "DEVICE_DELETION_STUCK_EVENT"
+	// adapterBreakerEvent is raised when the circuit breaker of an adapter topic opens or closes
+	adapterBreakerEvent = "ADAPTER_CIRCUIT_BREAKER_EVENT"
+	// adapterNotAvailableEvent is raised when devices of a type no registered adapter serves
+	// are found in the KV store
+	adapterNotAvailableEvent = "DEVICE_ADAPTER_NOT_AVAILABLE_EVENT"
+	// portCollisionEvent is raised when an adapter announces a port number already taken
+	// by a different port of the same device
+	portCollisionEvent = "DEVICE_PORT_NUMBER_COLLISION_EVENT"
+	// deviceMigratedEvent is raised when a device was moved to a different adapter
+	deviceMigratedEvent = "DEVICE_MIGRATED_EVENT"
+	// deviceMigrationFailedEvent is raised when moving a device to a different adapter
+	// failed and the original adapter binding was restored
+	deviceMigrationFailedEvent = "DEVICE_MIGRATION_FAILED_EVENT"
+	// adapterClockSkewEvent is raised when the clock of an adapter host strays from this
+	// core's by more than the configured tolerance
+	adapterClockSkewEvent = "ADAPTER_CLOCK_SKEW_EVENT"
+	// coreReadOnlyEnteredEvent is raised when the core enters read-only maintenance
+	coreReadOnlyEnteredEvent = "CORE_READ_ONLY_ENTERED_EVENT"
+	// coreReadOnlyExitedEvent is raised when the core leaves read-only maintenance
+	coreReadOnlyExitedEvent = "CORE_READ_ONLY_EXITED_EVENT"
+)
+
+// EventManager publishes events onto the kafka event stream and manages the named subscription filters
+// that route matching events to their own dedicated topics.  This allows a consumer interested in a
+// handful of devices to follow a per-filter topic instead of ingesting the full event stream.  Filters
+// are persisted in the KV store so they survive a core restart.  An event is evaluated once against all
+// filters and only its routing differs per filter - the event payload itself is never copied.
+type EventManager struct {
+	kafkaClient      kafka.Client
+	clusterDataProxy *model.Proxy
+	eventFilters     map[string]*voltha.EventFilter
+	eventFilterLock  sync.RWMutex
+	suppressions     map[string]*eventSuppression
+	suppressionLock  sync.Mutex
+	// publish counters feeding the diagnostics self-report; see diagnostics.go
+	eventsPublished      int64
+	eventPublishFailures int64
+}
+
+// eventSuppression describes an active suppression window for one device and its children
+type eventSuppression struct {
+	deviceID   string
+	expiry     time.Time
+	categories map[string]struct{} // an empty map suppresses all categories
+}
+
+func newEventManager(kafkaClient kafka.Client, cdProxy *model.Proxy) *EventManager {
+	return &EventManager{
+		kafkaClient:      kafkaClient,
+		clusterDataProxy: cdProxy,
+		eventFilters:     make(map[string]*voltha.EventFilter),
+		suppressions:     make(map[string]*eventSuppression),
+	}
+}
+
+// start loads the event filters and suppression windows that were in effect before the core was restarted
+func (eMgr *EventManager) start(ctx context.Context) {
+	logger.Info("starting-event-manager")
+	var filters []*voltha.EventFilter
+	if err := eMgr.clusterDataProxy.List(ctx, "event_filters", &filters); err != nil {
+		logger.Errorw("failed-to-list-event-filters-from-cluster-proxy", log.Fields{"error": err})
+		return
+	}
+	eMgr.eventFilterLock.Lock()
+	for _, filter := range filters {
+		eMgr.eventFilters[filter.Id] = filter
+	}
+	eMgr.eventFilterLock.Unlock()
+
+	var blobs []*ic.StrType
+	if err := eMgr.clusterDataProxy.List(ctx, "event_suppressions", &blobs); err != nil {
+		logger.Errorw("failed-to-list-event-suppressions-from-cluster-proxy", log.Fields{"error": err})
+		return
+	}
+	eMgr.suppressionLock.Lock()
+	for _, blob := range blobs {
+		suppression, err := decodeEventSuppression(blob.Val)
+		if err != nil {
+			logger.Warnw("ignoring-invalid-event-suppression", log.Fields{"value": blob.Val, "error": err})
+			continue
+		}
+		eMgr.suppressions[suppression.deviceID] = suppression
+	}
+	eMgr.suppressionLock.Unlock()
+	logger.Infow("event-manager-started", log.Fields{"filter-count": len(filters), "suppression-count": len(blobs)})
+}
+
+// createEventFilter adds a new subscription filter and persists it
+func (eMgr *EventManager) createEventFilter(ctx context.Context, filter *voltha.EventFilter) (*voltha.EventFilter, error) {
+	if filter.Id == "" {
+		filter.Id = CreateEventFilterID()
+	}
+	eMgr.eventFilterLock.Lock()
+	defer eMgr.eventFilterLock.Unlock()
+	if _, exist := eMgr.eventFilters[filter.Id]; exist {
+		return nil, status.Errorf(codes.AlreadyExists, "event-filter-%s", filter.Id)
+	}
+	if err := eMgr.clusterDataProxy.AddWithID(ctx, "event_filters", filter.Id, filter); err != nil {
+		logger.Errorw("failed-to-save-event-filter-to-cluster-proxy", log.Fields{"filter-id": filter.Id, "error": err})
+		return nil, err
+	}
+	eMgr.eventFilters[filter.Id] = filter
+	logger.Infow("event-filter-created", log.Fields{"filter-id": filter.Id, "device-id": filter.DeviceId})
+	return filter, nil
+}
+
+// updateEventFilter modifies an existing subscription filter and persists the change
+func (eMgr *EventManager) updateEventFilter(ctx context.Context, filter *voltha.EventFilter) (*voltha.EventFilter, error) {
+	eMgr.eventFilterLock.Lock()
+	defer eMgr.eventFilterLock.Unlock()
+	if _, exist := eMgr.eventFilters[filter.Id]; !exist {
+		return nil, status.Errorf(codes.NotFound, "event-filter-%s", filter.Id)
+	}
+	if err := eMgr.clusterDataProxy.Update(ctx, "event_filters/"+filter.Id, filter); err != nil {
+		logger.Errorw("failed-to-update-event-filter-in-cluster-proxy", log.Fields{"filter-id": filter.Id, "error": err})
+		return nil, err
+	}
+	eMgr.eventFilters[filter.Id] = filter
+	logger.Infow("event-filter-updated", log.Fields{"filter-id": filter.Id, "device-id": filter.DeviceId})
+	return filter, nil
+}
+
+// deleteEventFilter removes a subscription filter from memory and from the KV store
+func (eMgr *EventManager) deleteEventFilter(ctx context.Context, filterID string) error {
+	eMgr.eventFilterLock.Lock()
+	defer eMgr.eventFilterLock.Unlock()
+	if _, exist := eMgr.eventFilters[filterID]; !exist {
+		return status.Errorf(codes.NotFound, "event-filter-%s", filterID)
+	}
+	if err := eMgr.clusterDataProxy.Remove(ctx, "event_filters/"+filterID); err != nil {
+		logger.Errorw("failed-to-remove-event-filter-from-cluster-proxy", log.Fields{"filter-id": filterID, "error": err})
+		return err
+	}
+	delete(eMgr.eventFilters, filterID)
+	logger.Infow("event-filter-deleted", log.Fields{"filter-id": filterID})
+	return nil
+}
+
+// getEventFilters returns the subscription filters in effect for a device
+func (eMgr *EventManager) getEventFilters(deviceID string) *voltha.EventFilters {
+	result := &voltha.EventFilters{Filters: make([]*voltha.EventFilter, 0)}
+	eMgr.eventFilterLock.RLock()
+	defer eMgr.eventFilterLock.RUnlock()
+	for _, filter := range eMgr.eventFilters {
+		if filter.DeviceId == deviceID {
+			result.Filters = append(result.Filters, filter)
+		}
+	}
+	return result
+}
+
+// listEventFilters returns all the subscription filters known to the core
+func (eMgr *EventManager) listEventFilters() *voltha.EventFilters {
+	result := &voltha.EventFilters{Filters: make([]*voltha.EventFilter, 0)}
+	eMgr.eventFilterLock.RLock()
+	defer eMgr.eventFilterLock.RUnlock()
+	for _, filter := range eMgr.eventFilters {
+		result.Filters = append(result.Filters, filter)
+	}
+	return result
+}
+
+// publishEvent sends the event onto the event topic and onto the dedicated topic of every enabled filter
+// that matches it.  The same event instance is handed to the kafka client for each destination.  Events
+// caught by an active suppression window on the originating device or its parent are tagged as suppressed
+// when they carry a device event, and dropped otherwise.
+func (eMgr *EventManager) publishEvent(event *voltha.Event, deviceID string, parentID string) {
+	if eMgr.isEventSuppressed(deviceID, parentID, event.Header.Category.String()) {
+		deviceEvent := event.GetDeviceEvent()
+		if deviceEvent == nil {
+			logger.Debugw("event-dropped-by-suppression-window", log.Fields{"device-id": deviceID})
+			return
+		}
+		if deviceEvent.Context == nil {
+			deviceEvent.Context = make(map[string]string)
+		}
+		deviceEvent.Context["suppressed"] = "true"
+	}
+	if err := eMgr.kafkaClient.Send(event, &kafka.Topic{Name: eventTopic}, deviceID); err != nil {
+		atomic.AddInt64(&eMgr.eventPublishFailures, 1)
+		logger.Errorw("failed-to-publish-event", log.Fields{"device-id": deviceID, "error": err})
+	} else {
+		atomic.AddInt64(&eMgr.eventsPublished, 1)
+	}
+	eMgr.eventFilterLock.RLock()
+	defer eMgr.eventFilterLock.RUnlock()
+	for _, filter := range eMgr.eventFilters {
+		if !filter.Enable || !eventMatchesFilter(event, deviceID, filter) {
+			continue
+		}
+		topic := kafka.Topic{Name: fmt.Sprintf("%s_%s", eventTopic, filter.Id)}
+		if err := eMgr.kafkaClient.Send(event, &topic, deviceID); err != nil {
+			logger.Errorw("failed-to-publish-event-to-filter-topic", log.Fields{"device-id": deviceID, "filter-id": filter.Id, "error": err})
+		}
+	}
+}
+
+// eventMatchesFilter verifies whether an event satisfies the device ID and all the rules of a filter
+func eventMatchesFilter(event *voltha.Event, deviceID string, filter *voltha.EventFilter) bool {
+	if filter.DeviceId != "" && filter.DeviceId != deviceID {
+		return false
+	}
+	for _, rule := range filter.Rules {
+		switch rule.Key {
+		case voltha.EventFilterRuleKey_filter_all:
+			// matches any event
+		case voltha.EventFilterRuleKey_category:
+			if event.Header.Category.String() != rule.Value {
+				return false
+			}
+		case voltha.EventFilterRuleKey_sub_category:
+			if event.Header.SubCategory.String() != rule.Value {
+				return false
+			}
+		case voltha.EventFilterRuleKey_device_event_type:
+			deviceEvent := event.GetDeviceEvent()
+			if deviceEvent == nil || deviceEvent.DeviceEventName != rule.Value {
+				return false
+			}
+		case voltha.EventFilterRuleKey_config_event_type:
+			configEvent := event.GetConfigEvent()
+			if configEvent == nil || configEvent.Type.String() != rule.Value {
+				return false
+			}
+		case voltha.EventFilterRuleKey_kpi_event_type:
+			if event.GetKpiEvent() == nil && event.GetKpiEvent2() == nil {
+				return false
+			}
+		default:
+			logger.Warnw("unsupported-event-filter-rule", log.Fields{"filter-id": filter.Id, "rule-key": rule.Key})
+			return false
+		}
+	}
+	return true
+}
+
+// publishDeviceEvent raises a device event with the supplied name and context
+func (eMgr *EventManager) publishDeviceEvent(device *voltha.Device, eventName string, eventContext map[string]string) {
+	raisedTs := ptypes.TimestampNow()
+	event := &voltha.Event{
+		Header: &voltha.EventHeader{
+			Id:          fmt.Sprintf("%s.%s", eventName, device.Id),
+			Category:    voltha.EventCategory_EQUIPMENT,
+			Type:        voltha.EventType_DEVICE_EVENT,
+			TypeVersion: "0.1",
+			RaisedTs:    raisedTs,
+			ReportedTs:  raisedTs,
+		},
+		EventType: &voltha.Event_DeviceEvent{
+			DeviceEvent: &voltha.DeviceEvent{
+				ResourceId:      device.Id,
+				DeviceEventName: eventName,
+				Context:         eventContext,
+			},
+		},
+	}
+	eMgr.publishEvent(event, device.Id, device.ParentId)
+}
+
+// publishDeviceStateChangeEvent raises a device event describing a device state transition
+func (eMgr *EventManager) publishDeviceStateChangeEvent(device *voltha.Device, previousState *DeviceState) {
+	eMgr.publishDeviceEvent(device, deviceStateChangeEvent, map[string]string{
+		"previous-admin-state":   previousState.Admin.String(),
+		"previous-connect-state": previousState.Connection.String(),
+		"previous-oper-state":    previousState.Operational.String(),
+		"admin-state":            device.AdminState.String(),
+		"connect-state":          device.ConnectStatus.String(),
+		"oper-state":             device.OperStatus.String(),
+	})
+}
+
+// suppressEvents opens a suppression window on a device.  Events from the device, or from its children,
+// matching one of the categories are suppressed until the window expires or is explicitly removed.  An
+// empty category list suppresses every category.  The window is persisted so it survives a core restart.
+func (eMgr *EventManager) suppressEvents(ctx context.Context, deviceID string, duration time.Duration, categories []string) (time.Time, error) {
+	if duration <= 0 {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid-suppression-duration-%v", duration)
+	}
+	suppression := &eventSuppression{
+		deviceID:   deviceID,
+		expiry:     time.Now().Add(duration),
+		categories: make(map[string]struct{}),
+	}
+	for _, category := range categories {
+		if category = strings.TrimSpace(category); category != "" {
+			suppression.categories[category] = struct{}{}
+		}
+	}
+	eMgr.suppressionLock.Lock()
+	defer eMgr.suppressionLock.Unlock()
+	if err := eMgr.clusterDataProxy.AddWithID(ctx, "event_suppressions", deviceID, &ic.StrType{Val: encodeEventSuppression(suppression)}); err != nil {
+		logger.Errorw("failed-to-save-event-suppression-to-cluster-proxy", log.Fields{"device-id": deviceID, "error": err})
+		return time.Time{}, err
+	}
+	eMgr.suppressions[deviceID] = suppression
+	logger.Infow("event-suppression-window-set", log.Fields{"device-id": deviceID, "expiry": suppression.expiry, "categories": categories})
+	return suppression.expiry, nil
+}
+
+// unsuppressEvents removes the suppression window of a device, restoring normal event publication
+func (eMgr *EventManager) unsuppressEvents(ctx context.Context, deviceID string) error {
+	eMgr.suppressionLock.Lock()
+	defer eMgr.suppressionLock.Unlock()
+	if _, exist := eMgr.suppressions[deviceID]; !exist {
+		return status.Errorf(codes.NotFound, "no-suppression-window-for-device-%s", deviceID)
+	}
+	if err := eMgr.clusterDataProxy.Remove(ctx, "event_suppressions/"+deviceID); err != nil {
+		logger.Errorw("failed-to-remove-event-suppression-from-cluster-proxy", log.Fields{"device-id": deviceID, "error": err})
+		return err
+	}
+	delete(eMgr.suppressions, deviceID)
+	logger.Infow("event-suppression-window-removed", log.Fields{"device-id": deviceID})
+	return nil
+}
+
+// isEventSuppressed verifies whether an event of the given category, originating from the device or one
+// of its children, falls within an active suppression window.  Expired windows are cleaned up as they are
+// encountered.
+func (eMgr *EventManager) isEventSuppressed(deviceID string, parentID string, category string) bool {
+	eMgr.suppressionLock.Lock()
+	defer eMgr.suppressionLock.Unlock()
+	for _, id := range []string{deviceID, parentID} {
+		if id == "" {
+			continue
+		}
+		suppression, exist := eMgr.suppressions[id]
+		if !exist {
+			continue
+		}
+		if time.Now().After(suppression.expiry) {
+			delete(eMgr.suppressions, id)
+			go func(deviceID string) {
+				if err := eMgr.clusterDataProxy.Remove(context.Background(), "event_suppressions/"+deviceID); err != nil {
+					logger.Errorw("failed-to-remove-expired-event-suppression", log.Fields{"device-id": deviceID, "error": err})
+				}
+			}(id)
+			continue
+		}
+		if len(suppression.categories) == 0 {
+			return true
+		}
+		if _, ok := suppression.categories[category]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeEventSuppression serializes a suppression window for the KV store
+func encodeEventSuppression(suppression *eventSuppression) string {
+	categories := make([]string, 0, len(suppression.categories))
+	for category := range suppression.categories {
+		categories = append(categories, category)
+	}
+	return fmt.Sprintf("%s|%s|%s", suppression.deviceID, suppression.expiry.Format(time.RFC3339), strings.Join(categories, ","))
+}
+
+// decodeEventSuppression deserializes a suppression window read back from the KV store
+func decodeEventSuppression(value string) (*eventSuppression, error) {
+	parts := strings.SplitN(value, "|", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, fmt.Errorf("malformed-event-suppression: %s", value)
+	}
+	expiry, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	suppression := &eventSuppression{
+		deviceID:   parts[0],
+		expiry:     expiry,
+		categories: make(map[string]struct{}),
+	}
+	for _, category := range strings.Split(parts[2], ",") {
+		if category != "" {
+			suppression.categories[category] = struct{}{}
+		}
+	}
+	return suppression, nil
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright 2020-present Open Networking Foundation
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ * http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingKafkaClient records every send so a test can verify which topics an event
+// reached; the mock client it embeds only accepts inter-container messages
+type recordingKafkaClient struct {
+	*mock_kafka.KafkaClient
+	lock   sync.Mutex
+	topics []string
+	events []*voltha.Event
+}
+
+func newRecordingKafkaClient() *recordingKafkaClient {
+	return &recordingKafkaClient{KafkaClient: mock_kafka.NewKafkaClient()}
+}
+
+func (kc *recordingKafkaClient) Send(msg interface{}, topic *kafka.Topic, keys ...string) error {
+	kc.lock.Lock()
+	defer kc.lock.Unlock()
+	kc.topics = append(kc.topics, topic.Name)
+	if event, ok := msg.(*voltha.Event); ok {
+		kc.events = append(kc.events, event)
+	}
+	return nil
+}
+
+// sentTopics returns the topic of every send in order
+func (kc *recordingKafkaClient) sentTopics() []string {
+	kc.lock.Lock()
+	defer kc.lock.Unlock()
+	return append([]string{}, kc.topics...)
+}
+
+// lastEvent returns the most recently sent event, nil when nothing was sent
+func (kc *recordingKafkaClient) lastEvent() *voltha.Event {
+	kc.lock.Lock()
+	defer kc.lock.Unlock()
+	if len(kc.events) == 0 {
+		return nil
+	}
+	return kc.events[len(kc.events)-1]
+}
+
+func TestEventFilterCRUD(t *testing.T) {
+	ctx := context.Background()
+	eMgr := newEventManager(newRecordingKafkaClient(), newInMemoryClusterProxy())
+
+	// A filter created without an ID gets one; a duplicate ID is rejected
+	created, err := eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "olt-1", Enable: true})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", created.Id)
+	_, err = eMgr.createEventFilter(ctx, &voltha.EventFilter{Id: created.Id})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Updates apply to known filters only
+	created.Enable = false
+	updated, err := eMgr.updateEventFilter(ctx, created)
+	assert.Nil(t, err)
+	assert.False(t, updated.Enable)
+	_, err = eMgr.updateEventFilter(ctx, &voltha.EventFilter{Id: "unknown-filter"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The per-device lookup narrows, the listing does not
+	second, err := eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "olt-2"})
+	assert.Nil(t, err)
+	forDevice := eMgr.getEventFilters("olt-1")
+	assert.Equal(t, 1, len(forDevice.Filters))
+	assert.Equal(t, created.Id, forDevice.Filters[0].Id)
+	assert.Equal(t, 2, len(eMgr.listEventFilters().Filters))
+
+	assert.Nil(t, eMgr.deleteEventFilter(ctx, second.Id))
+	assert.Equal(t, codes.NotFound, status.Code(eMgr.deleteEventFilter(ctx, second.Id)))
+	assert.Equal(t, 1, len(eMgr.listEventFilters().Filters))
+}
+
+func TestEventFiltersSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	cdProxy := newInMemoryClusterProxy()
+	eMgr := newEventManager(newRecordingKafkaClient(), cdProxy)
+	created, err := eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "olt-1", Enable: true})
+	assert.Nil(t, err)
+
+	// A manager started over the same KV store loads the persisted filters back
+	restarted := newEventManager(newRecordingKafkaClient(), cdProxy)
+	restarted.start(ctx)
+	filters := restarted.getEventFilters("olt-1")
+	assert.Equal(t, 1, len(filters.Filters))
+	assert.Equal(t, created.Id, filters.Filters[0].Id)
+	assert.True(t, filters.Filters[0].Enable)
+}
+
+func TestEventRoutingToFilterTopics(t *testing.T) {
+	ctx := context.Background()
+	kc := newRecordingKafkaClient()
+	eMgr := newEventManager(kc, newInMemoryClusterProxy())
+
+	matching, err := eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "olt-1", Enable: true,
+		Rules: []*voltha.EventFilterRule{{Key: voltha.EventFilterRuleKey_device_event_type, Value: deviceActivatedEvent}}})
+	assert.Nil(t, err)
+	_, err = eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "olt-1", Enable: false,
+		Rules: []*voltha.EventFilterRule{{Key: voltha.EventFilterRuleKey_filter_all}}})
+	assert.Nil(t, err)
+	_, err = eMgr.createEventFilter(ctx, &voltha.EventFilter{DeviceId: "other-olt", Enable: true,
+		Rules: []*voltha.EventFilterRule{{Key: voltha.EventFilterRuleKey_filter_all}}})
+	assert.Nil(t, err)
+
+	// The matching enabled filter routes the event to its dedicated topic on top of the
+	// event stream; the disabled one and the one scoped to another device do not
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	topics := kc.sentTopics()
+	assert.Equal(t, []string{eventTopic, fmt.Sprintf("%s_%s", eventTopic, matching.Id)}, topics)
+	assert.Equal(t, int64(1), eMgr.eventsPublished)
+
+	// An event the rule does not match only reaches the event stream
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceFlappingEvent, nil)
+	topics = kc.sentTopics()
+	assert.Equal(t, 3, len(topics))
+	assert.Equal(t, eventTopic, topics[2])
+}
+
+func TestEventMatchesFilterRules(t *testing.T) {
+	event := &voltha.Event{
+		Header: &voltha.EventHeader{Category: voltha.EventCategory_EQUIPMENT, Type: voltha.EventType_DEVICE_EVENT},
+		EventType: &voltha.Event_DeviceEvent{
+			DeviceEvent: &voltha.DeviceEvent{ResourceId: "olt-1", DeviceEventName: deviceActivatedEvent},
+		},
+	}
+	ruleFilter := func(key voltha.EventFilterRuleKey_EventFilterRuleType, value string) *voltha.EventFilter {
+		return &voltha.EventFilter{Rules: []*voltha.EventFilterRule{{Key: key, Value: value}}}
+	}
+
+	assert.True(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_filter_all, "")))
+	assert.True(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_category, "EQUIPMENT")))
+	assert.False(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_category, "COMMUNICATION")))
+	assert.True(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_device_event_type, deviceActivatedEvent)))
+	assert.False(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_device_event_type, deviceFlappingEvent)))
+	// A config-event rule cannot match a device event
+	assert.False(t, eventMatchesFilter(event, "olt-1", ruleFilter(voltha.EventFilterRuleKey_config_event_type, "add")))
+
+	// A device-scoped filter only matches its own device; every rule must hold
+	scoped := ruleFilter(voltha.EventFilterRuleKey_filter_all, "")
+	scoped.DeviceId = "other-olt"
+	assert.False(t, eventMatchesFilter(event, "olt-1", scoped))
+	both := &voltha.EventFilter{Rules: []*voltha.EventFilterRule{
+		{Key: voltha.EventFilterRuleKey_category, Value: "EQUIPMENT"},
+		{Key: voltha.EventFilterRuleKey_device_event_type, Value: deviceFlappingEvent},
+	}}
+	assert.False(t, eventMatchesFilter(event, "olt-1", both))
+}
+
+func TestEventSuppressionTagsAndDrops(t *testing.T) {
+	ctx := context.Background()
+	kc := newRecordingKafkaClient()
+	eMgr := newEventManager(kc, newInMemoryClusterProxy())
+
+	// A window needs a positive duration
+	_, err := eMgr.suppressEvents(ctx, "olt-1", 0, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	expiry, err := eMgr.suppressEvents(ctx, "olt-1", time.Hour, nil)
+	assert.Nil(t, err)
+	assert.True(t, expiry.After(time.Now()))
+
+	// A device event in the window is still published, tagged as suppressed
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	assert.Equal(t, 1, len(kc.sentTopics()))
+	assert.Equal(t, "true", kc.lastEvent().GetDeviceEvent().Context["suppressed"])
+
+	// An event without a device payload is dropped outright
+	kpi := &voltha.Event{
+		Header:    &voltha.EventHeader{Category: voltha.EventCategory_EQUIPMENT, Type: voltha.EventType_KPI_EVENT2},
+		EventType: &voltha.Event_KpiEvent2{KpiEvent2: &voltha.KpiEvent2{}},
+	}
+	eMgr.publishEvent(kpi, "olt-1", "")
+	assert.Equal(t, 1, len(kc.sentTopics()))
+
+	// Events from a child fall under the parent's window too
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "onu-1", ParentId: "olt-1"}, deviceActivatedEvent, nil)
+	assert.Equal(t, "true", kc.lastEvent().GetDeviceEvent().Context["suppressed"])
+
+	// Lifting the window restores normal publication; lifting it twice is an error
+	assert.Nil(t, eMgr.unsuppressEvents(ctx, "olt-1"))
+	assert.Equal(t, codes.NotFound, status.Code(eMgr.unsuppressEvents(ctx, "olt-1")))
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	assert.NotContains(t, kc.lastEvent().GetDeviceEvent().Context, "suppressed")
+}
+
+func TestEventSuppressionCategoryScope(t *testing.T) {
+	ctx := context.Background()
+	kc := newRecordingKafkaClient()
+	eMgr := newEventManager(kc, newInMemoryClusterProxy())
+	_, err := eMgr.suppressEvents(ctx, "olt-1", time.Hour, []string{"COMMUNICATION", " "})
+	assert.Nil(t, err)
+
+	// Device events carry the EQUIPMENT category, outside the window's scope
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	assert.NotContains(t, kc.lastEvent().GetDeviceEvent().Context, "suppressed")
+
+	// The same payload under a suppressed category is tagged
+	event := &voltha.Event{
+		Header: &voltha.EventHeader{Category: voltha.EventCategory_COMMUNICATION, Type: voltha.EventType_DEVICE_EVENT},
+		EventType: &voltha.Event_DeviceEvent{
+			DeviceEvent: &voltha.DeviceEvent{ResourceId: "olt-1", DeviceEventName: deviceActivatedEvent},
+		},
+	}
+	eMgr.publishEvent(event, "olt-1", "")
+	assert.Equal(t, "true", kc.lastEvent().GetDeviceEvent().Context["suppressed"])
+}
+
+func TestEventSuppressionExpires(t *testing.T) {
+	ctx := context.Background()
+	kc := newRecordingKafkaClient()
+	eMgr := newEventManager(kc, newInMemoryClusterProxy())
+	_, err := eMgr.suppressEvents(ctx, "olt-1", 10*time.Millisecond, nil)
+	assert.Nil(t, err)
+
+	// Once the window has lapsed events flow untagged and the window is cleaned up
+	time.Sleep(30 * time.Millisecond)
+	eMgr.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	assert.NotContains(t, kc.lastEvent().GetDeviceEvent().Context, "suppressed")
+	eMgr.suppressionLock.Lock()
+	_, exist := eMgr.suppressions["olt-1"]
+	eMgr.suppressionLock.Unlock()
+	assert.False(t, exist)
+}
+
+func TestEventSuppressionSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	cdProxy := newInMemoryClusterProxy()
+	eMgr := newEventManager(newRecordingKafkaClient(), cdProxy)
+	_, err := eMgr.suppressEvents(ctx, "olt-1", time.Hour, []string{"EQUIPMENT"})
+	assert.Nil(t, err)
+
+	// A manager started over the same KV store enforces the persisted window
+	kc := newRecordingKafkaClient()
+	restarted := newEventManager(kc, cdProxy)
+	restarted.start(ctx)
+	restarted.publishDeviceEvent(&voltha.Device{Id: "olt-1"}, deviceActivatedEvent, nil)
+	assert.Equal(t, "true", kc.lastEvent().GetDeviceEvent().Context["suppressed"])
+}
+
+func TestEventSuppressionCodec(t *testing.T) {
+	original := &eventSuppression{
+		deviceID:   "olt-1",
+		expiry:     time.Now().Add(time.Hour).Truncate(time.Second),
+		categories: map[string]struct{}{"EQUIPMENT": {}, "COMMUNICATION": {}},
+	}
+	decoded, err := decodeEventSuppression(encodeEventSuppression(original))
+	assert.Nil(t, err)
+	assert.Equal(t, original.deviceID, decoded.deviceID)
+	assert.True(t, original.expiry.Equal(decoded.expiry))
+	assert.Equal(t, original.categories, decoded.categories)
+
+	_, err = decodeEventSuppression("garbage")
+	assert.NotNil(t, err)
+	_, err = decodeEventSuppression("|2020-01-01T00:00:00Z|")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// A flow push persists the new flow table before the adapter has answered, so when the
+// adapter rejected the push the model kept claiming flows that were never installed and
+// the audit later flagged the divergence.  The sequence now distinguishes desired from
+// confirmed state: the intended flows are persisted and marked PENDING, the push goes to
+// the adapter, and the adapter's answer either marks them APPLIED or rolls the table back
+// to its prior content and marks them FAILED.  The device and logical flow tables - and
+// the OF stats derived from them - keep reporting the desired state as before; the
+// confirmation status is exposed separately through getFlowConfirmations.
+
+// Confirmation states of a pushed flow
+const (
+	flowPending = "PENDING" // persisted, adapter answer outstanding
+	flowApplied = "APPLIED" // the adapter confirmed the push
+	flowFailed  = "FAILED"  // the adapter rejected the push; the table was rolled back
+)
+
+// flowSnapshot captures what a push changed so the adapter's answer can settle it: the
+// table content to roll back to and the IDs of the flows added and removed
+type flowSnapshot struct {
+	priorFlows  []*ofp.OfpFlowStats
+	priorGroups []*ofp.OfpGroupEntry
+	added       []uint64
+	removed     []uint64
+}
+
+// flowIDsOf returns the IDs of the given flows
+func flowIDsOf(flows []*ofp.OfpFlowStats) []uint64 {
+	ids := make([]uint64, 0, len(flows))
+	for _, flow := range flows {
+		ids = append(ids, flow.Id)
+	}
+	return ids
+}
+
+// markFlowsPending records every flow the push touches as awaiting the adapter's answer
+func (agent *DeviceAgent) markFlowsPending(snapshot *flowSnapshot) {
+	for _, id := range snapshot.added {
+		agent.flowConfirmations.Store(id, flowPending)
+	}
+	for _, id := range snapshot.removed {
+		agent.flowConfirmations.Store(id, flowPending)
+	}
+}
+
+// confirmFlowPush settles a push the adapter accepted: added flows become APPLIED and
+// removed flows drop their confirmation record along with the flow
+func (agent *DeviceAgent) confirmFlowPush(snapshot *flowSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	for _, id := range snapshot.removed {
+		agent.flowConfirmations.Delete(id)
+	}
+	for _, id := range snapshot.added {
+		agent.flowConfirmations.Store(id, flowApplied)
+	}
+}
+
+// rollbackFlowPush settles a push the adapter rejected: the flow table is restored to its
+// content before the push and every flow the push touched is marked FAILED
+func (agent *DeviceAgent) rollbackFlowPush(ctx context.Context, snapshot *flowSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		ctxWarnw(ctx, "flow-rollback-aborted", log.Fields{"device-id": agent.deviceID, "error": err})
+		return
+	}
+	defer agent.requestQueue.RequestComplete()
+	device := agent.getDeviceWithoutLock()
+	device.Flows = &voltha.Flows{Items: snapshot.priorFlows}
+	device.FlowGroups = &voltha.FlowGroups{Items: snapshot.priorGroups}
+	if err := agent.updateDeviceWithoutLock(ctx, device); err != nil {
+		ctxWarnw(ctx, "flow-rollback-failed", log.Fields{"device-id": agent.deviceID, "error": err})
+		return
+	}
+	agent.setFlowsFootprint(snapshot.priorFlows)
+	agent.setGroupsFootprint(snapshot.priorGroups)
+	for _, id := range snapshot.added {
+		agent.flowConfirmations.Store(id, flowFailed)
+	}
+	for _, id := range snapshot.removed {
+		agent.flowConfirmations.Store(id, flowFailed)
+	}
+	ctxWarnw(ctx, "flow-push-rolled-back", log.Fields{"device-id": agent.deviceID, "added": snapshot.added, "removed": snapshot.removed})
+}
+
+// flowConfirmationStates returns the confirmation status of every flow pushed to this
+// device that still has a record
+func (agent *DeviceAgent) flowConfirmationStates() map[uint64]string {
+	states := make(map[uint64]string)
+	agent.flowConfirmations.Range(func(key, value interface{}) bool {
+		states[key.(uint64)] = value.(string)
+		return true
+	})
+	return states
+}
+
+// getFlowConfirmations returns the per-flow confirmation status of a device
+func (dMgr *DeviceManager) getFlowConfirmations(ctx context.Context, deviceID string) (map[uint64]string, error) {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return nil, newNotFoundError("device", deviceID)
+	}
+	return agent.flowConfirmationStates(), nil
+}
+
+// GetFlowConfirmations is the debug query exposing which pushed flows the adapter has
+// confirmed, rejected or not yet answered
+func (handler *APIHandler) GetFlowConfirmations(ctx context.Context, deviceID string) (map[uint64]string, error) {
+	ctxDebugw(ctx, "GetFlowConfirmations", log.Fields{"device-id": deviceID})
+	return handler.deviceMgr.getFlowConfirmations(ctx, deviceID)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowPushConfirmation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:0f")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// A confirmed push marks the flow APPLIED
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1, Cookie: 1}}, nil, nil))
+	states, err := dMgr.getFlowConfirmations(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, flowApplied, states[uint64(1)])
+
+	// A confirmed removal drops the record along with the flow
+	assert.Nil(t, dMgr.getDeviceAgent(ctx, device.Id).deleteFlowsAndGroups(ctx, []*ofp.OfpFlowStats{{Id: 1, Cookie: 1}}, nil, nil))
+	states, err = dMgr.getFlowConfirmations(ctx, device.Id)
+	assert.Nil(t, err)
+	_, have := states[uint64(1)]
+	assert.False(t, have)
+}
+
+func TestFailedFlowPushRollsBack(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:10")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1, Cookie: 1}}, nil, nil))
+
+	// The adapter rejects the second push: the model must not keep claiming the flow
+	fas.failWith("updateFlowsBulk", errors.New("adapter-rejected"))
+	fas.failWith("updateFlowsIncremental", errors.New("adapter-rejected"))
+	err := dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 2, Cookie: 2}}, nil, nil)
+	assert.NotNil(t, err)
+
+	// The table was rolled back to the confirmed flow, and the rejected one is on record
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stored.Flows.Items))
+	assert.Equal(t, uint64(1), stored.Flows.Items[0].Id)
+	states, err := dMgr.getFlowConfirmations(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, flowApplied, states[uint64(1)])
+	assert.Equal(t, flowFailed, states[uint64(2)])
+}
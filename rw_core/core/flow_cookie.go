@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Removing one subscriber's service used to mean recomputing and resending the whole flow
+// table of the device.  The lookups below let a caller ask for - or remove - exactly the
+// flows carrying a given cookie: the stored flow list is filtered, the removal is
+// forwarded to the adapter through the existing delete path (incremental where the
+// adapter supports it), and the matching flows are dropped from the logical device table
+// so the controller view stays consistent.  Cookies are expected to be unique, but
+// collisions do happen; every lookup therefore returns all matching flows rather than
+// silently picking one.
+
+// flowsMatchingCookie returns the flows of the device carrying the cookie
+func flowsMatchingCookie(device *voltha.Device, cookie uint64) []*ofp.OfpFlowStats {
+	var matches []*ofp.OfpFlowStats
+	for _, flow := range device.GetFlows().GetItems() {
+		if flow.Cookie == cookie {
+			matches = append(matches, flow)
+		}
+	}
+	return matches
+}
+
+// getFlowsByCookie returns every stored flow of the device carrying the cookie
+func (dMgr *DeviceManager) getFlowsByCookie(ctx context.Context, deviceID string, cookie uint64) ([]*ofp.OfpFlowStats, error) {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return nil, newNotFoundError("device", deviceID)
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches := flowsMatchingCookie(device, cookie)
+	if len(matches) == 0 {
+		return nil, newNotFoundError("flow-cookie", cookie)
+	}
+	return matches, nil
+}
+
+// deleteFlowsByCookie removes every flow of the device carrying the cookie, forwards the
+// removal to the adapter and drops the flows from the logical device table.  The removed
+// flows are returned to the caller.
+func (dMgr *DeviceManager) deleteFlowsByCookie(ctx context.Context, deviceID string, cookie uint64) ([]*ofp.OfpFlowStats, error) {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return nil, newNotFoundError("device", deviceID)
+	}
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches := flowsMatchingCookie(device, cookie)
+	if len(matches) == 0 {
+		return nil, newNotFoundError("flow-cookie", cookie)
+	}
+	if err := agent.deleteFlowsAndGroups(ctx, matches, nil, nil); err != nil {
+		return nil, err
+	}
+	dMgr.removeLogicalFlowsByCookie(ctx, device, cookie)
+	return matches, nil
+}
+
+// removeLogicalFlowsByCookie drops the flows carrying the cookie from the logical device
+// of the given device, keeping the controller view in step with the device table.  A
+// logical device not managed by this instance is left to its owning core.
+func (dMgr *DeviceManager) removeLogicalFlowsByCookie(ctx context.Context, device *voltha.Device, cookie uint64) {
+	if dMgr.logicalDeviceMgr == nil {
+		return
+	}
+	// For a root device the ParentId carries its logical device ID; a child reaches it
+	// through its parent
+	logicalDeviceID := device.ParentId
+	if !device.Root {
+		parentDevice, err := dMgr.GetDevice(ctx, device.ParentId)
+		if err != nil {
+			ctxWarnw(ctx, "parent-not-found-skipping-logical-flow-cleanup", log.Fields{"device-id": device.Id, "parent-id": device.ParentId})
+			return
+		}
+		logicalDeviceID = parentDevice.ParentId
+	}
+	agent := dMgr.logicalDeviceMgr.getLogicalDeviceAgent(ctx, logicalDeviceID)
+	if agent == nil {
+		return
+	}
+	if err := agent.removeFlowsByCookie(ctx, cookie); err != nil {
+		ctxWarnw(ctx, "failed-to-remove-logical-flows-by-cookie", log.Fields{"logical-device-id": logicalDeviceID, "cookie": cookie, "error": err})
+	}
+}
+
+// removeFlowsByCookie drops every flow carrying the cookie from the logical device table
+func (agent *LogicalDeviceAgent) removeFlowsByCookie(ctx context.Context, cookie uint64) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	ld := agent.getLogicalDeviceWithoutLock()
+	kept := make([]*ofp.OfpFlowStats, 0, len(ld.GetFlows().GetItems()))
+	for _, flow := range ld.GetFlows().GetItems() {
+		if flow.Cookie != cookie {
+			kept = append(kept, flow)
+		}
+	}
+	if len(kept) == len(ld.GetFlows().GetItems()) {
+		return nil
+	}
+	return agent.updateLogicalDeviceFlowsWithoutLock(ctx, &ofp.Flows{Items: kept})
+}
+
+// GetFlowsByCookie is the NBI surface of the cookie lookup; collisions return every match
+func (handler *APIHandler) GetFlowsByCookie(ctx context.Context, deviceID string, cookie uint64) ([]*ofp.OfpFlowStats, error) {
+	ctxDebugw(ctx, "GetFlowsByCookie", log.Fields{"device-id": deviceID, "cookie": cookie})
+	return handler.deviceMgr.getFlowsByCookie(ctx, deviceID, cookie)
+}
+
+// DeleteFlowsByCookie is the NBI surface of the cookie deletion
+func (handler *APIHandler) DeleteFlowsByCookie(ctx context.Context, deviceID string, cookie uint64) ([]*ofp.OfpFlowStats, error) {
+	ctxDebugw(ctx, "DeleteFlowsByCookie", log.Fields{"device-id": deviceID, "cookie": cookie})
+	return handler.deviceMgr.deleteFlowsByCookie(ctx, deviceID, cookie)
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newCookieTestDevice returns an active device carrying three flows, two of which share
+// cookie 100 to exercise the collision path
+func newCookieTestDevice(t *testing.T, dMgr *DeviceManager, mac string) *voltha.Device {
+	ctx := context.Background()
+	device := createTestDevice(t, dMgr, mac)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{
+		{Id: 1, Cookie: 100},
+		{Id: 2, Cookie: 200},
+		{Id: 3, Cookie: 100},
+	}, nil, nil))
+	return device
+}
+
+func TestGetFlowsByCookie(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := newCookieTestDevice(t, dMgr, "00:00:00:00:4a:01")
+
+	// A colliding cookie returns every match, never a silent pick
+	matches, err := dMgr.getFlowsByCookie(ctx, device.Id, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(matches))
+
+	matches, err = dMgr.getFlowsByCookie(ctx, device.Id, 200)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, uint64(2), matches[0].Id)
+
+	_, err = dMgr.getFlowsByCookie(ctx, device.Id, 999)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = dMgr.getFlowsByCookie(ctx, "no-such-device", 100)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestDeleteFlowsByCookie(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := newCookieTestDevice(t, dMgr, "00:00:00:00:4a:02")
+	pushed := fas.callCount("updateFlowsBulk")
+
+	removed, err := dMgr.deleteFlowsByCookie(ctx, device.Id, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(removed))
+
+	// Only the flow with the other cookie survives, and the removal reached the adapter
+	updated, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(updated.Flows.Items))
+	assert.Equal(t, uint64(200), updated.Flows.Items[0].Cookie)
+	assert.Equal(t, pushed+1, fas.callCount("updateFlowsBulk"))
+
+	// A second delete of the same cookie finds nothing
+	_, err = dMgr.deleteFlowsByCookie(ctx, device.Id, 100)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestRemoveLogicalFlowsByCookie(t *testing.T) {
+	ctx := context.Background()
+	agent := &LogicalDeviceAgent{
+		logicalDeviceID:  "ld-cookie",
+		clusterDataProxy: newInMemoryClusterProxy(),
+		requestQueue:     coreutils.NewRequestQueue(),
+		logicalDevice: &voltha.LogicalDevice{
+			Id: "ld-cookie",
+			Flows: &ofp.Flows{Items: []*ofp.OfpFlowStats{
+				{Id: 1, Cookie: 100},
+				{Id: 2, Cookie: 200},
+				{Id: 3, Cookie: 100},
+			}},
+		},
+	}
+
+	assert.Nil(t, agent.removeFlowsByCookie(ctx, 100))
+	assert.Equal(t, 1, len(agent.logicalDevice.Flows.Items))
+	assert.Equal(t, uint64(200), agent.logicalDevice.Flows.Items[0].Cookie)
+
+	// No match leaves the table untouched without a store round trip
+	assert.Nil(t, agent.removeFlowsByCookie(ctx, 999))
+	assert.Equal(t, 1, len(agent.logicalDevice.Flows.Items))
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	fu "github.com/opencord/voltha-lib-go/v3/pkg/flows"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// ONOS can push flows for an ONU a moment before the core marks it ACTIVE; forwarding
+// them mid-activation gets them rejected by the adapter while OMCI setup is still in
+// progress, and they were simply lost.  Flow updates arriving while the device is
+// ACTIVATING are now parked in the agent - persisted, merged with later updates and
+// bounded in size - and pushed automatically when the device reaches ACTIVE.  They are
+// dropped if activation fails or if they age beyond the parking window.  A parked flow
+// shows PARKED in the flow status query until it has been applied.
+
+const parkedFlowsPath = "parked_flows"
+const parkedGroupsPath = "parked_groups"
+
+// Fallbacks for a direct-constructed manager with no configured parking limits
+const (
+	fallbackFlowParkingWindow = 30 * time.Second
+	fallbackFlowParkingMax    = 128
+)
+
+// Flow statuses reported by getFlowStatus
+const (
+	flowStatusApplied = "APPLIED"
+	flowStatusParked  = "PARKED"
+)
+
+func (agent *DeviceAgent) flowParkingLimits() (time.Duration, int) {
+	window, max := fallbackFlowParkingWindow, fallbackFlowParkingMax
+	if agent.deviceMgr != nil {
+		if agent.deviceMgr.flowParkingWindow > 0 {
+			window = agent.deviceMgr.flowParkingWindow
+		}
+		if agent.deviceMgr.flowParkingMax > 0 {
+			max = agent.deviceMgr.flowParkingMax
+		}
+	}
+	return window, max
+}
+
+// parkFlowsIfActivating holds a flow update back when the device is still ACTIVATING,
+// merging it with anything already parked.  It reports whether the update was parked.
+func (agent *DeviceAgent) parkFlowsIfActivating(ctx context.Context, newFlows []*ofp.OfpFlowStats, newGroups []*ofp.OfpGroupEntry) (bool, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return false, err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	if agent.device.OperStatus != voltha.OperStatus_ACTIVATING {
+		return false, nil
+	}
+	_, max := agent.flowParkingLimits()
+	if len(agent.parkedFlows) == 0 && len(agent.parkedGroups) == 0 {
+		agent.parkedSince = time.Now()
+	}
+	// A later update of the same flow supersedes the parked one
+	for _, flow := range newFlows {
+		if idx := fu.FindFlows(agent.parkedFlows, flow); idx != -1 {
+			agent.parkedFlows[idx] = flow
+		} else {
+			agent.parkedFlows = append(agent.parkedFlows, flow)
+		}
+	}
+	if len(agent.parkedFlows) > max {
+		agent.parkedFlows = agent.parkedFlows[len(agent.parkedFlows)-max:]
+	}
+	agent.parkedGroups = append(agent.parkedGroups, newGroups...)
+	agent.persistParkedFlows(ctx)
+	ctxInfow(ctx, "flows-parked-device-activating", log.Fields{"device-id": agent.deviceID, "parked-flows": len(agent.parkedFlows), "parked-groups": len(agent.parkedGroups)})
+	return true, nil
+}
+
+// persistParkedFlows stores the parked entries so they survive a core restart.  The
+// caller must hold the request queue.
+func (agent *DeviceAgent) persistParkedFlows(ctx context.Context) {
+	if err := agent.clusterDataProxy.AddWithID(ctx, parkedFlowsPath, agent.deviceID, &voltha.Flows{Items: agent.parkedFlows}); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-parked-flows", log.Fields{"device-id": agent.deviceID, "error": err})
+	}
+	if err := agent.clusterDataProxy.AddWithID(ctx, parkedGroupsPath, agent.deviceID, &voltha.FlowGroups{Items: agent.parkedGroups}); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-parked-groups", log.Fields{"device-id": agent.deviceID, "error": err})
+	}
+}
+
+// takeParkedFlows removes and returns the parked entries, dropping them when they aged
+// beyond the parking window
+func (agent *DeviceAgent) takeParkedFlows(ctx context.Context) ([]*ofp.OfpFlowStats, []*ofp.OfpGroupEntry) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return nil, nil
+	}
+	flows, groups := agent.parkedFlows, agent.parkedGroups
+	agent.parkedFlows, agent.parkedGroups = nil, nil
+	window, _ := agent.flowParkingLimits()
+	expired := len(flows) > 0 && time.Since(agent.parkedSince) > window
+	agent.persistParkedFlows(ctx)
+	agent.requestQueue.RequestComplete()
+
+	if expired {
+		ctxWarnw(ctx, "parked-flows-expired", log.Fields{"device-id": agent.deviceID, "flows": len(flows), "window": window})
+		return nil, nil
+	}
+	return flows, groups
+}
+
+// replayParkedFlows pushes the flows parked during activation now that the device is
+// ACTIVE.  It must not be called while holding the request queue.
+func (agent *DeviceAgent) replayParkedFlows(ctx context.Context) {
+	flows, groups := agent.takeParkedFlows(ctx)
+	if (len(flows) | len(groups)) == 0 {
+		return
+	}
+	ctxInfow(ctx, "replaying-parked-flows", log.Fields{"device-id": agent.deviceID, "flows": len(flows), "groups": len(groups)})
+	if err := agent.addFlowsAndGroups(ctx, flows, groups, &voltha.FlowMetadata{}); err != nil {
+		ctxWarnw(ctx, "parked-flow-replay-failed", log.Fields{"device-id": agent.deviceID, "error": err})
+	}
+}
+
+// dropParkedFlows discards the parked entries; invoked when activation fails.  The
+// caller must hold the request queue.
+func (agent *DeviceAgent) dropParkedFlows(ctx context.Context) {
+	if (len(agent.parkedFlows) | len(agent.parkedGroups)) == 0 {
+		return
+	}
+	ctxWarnw(ctx, "dropping-parked-flows-activation-failed", log.Fields{"device-id": agent.deviceID, "flows": len(agent.parkedFlows), "groups": len(agent.parkedGroups)})
+	agent.parkedFlows, agent.parkedGroups = nil, nil
+	agent.persistParkedFlows(ctx)
+}
+
+// loadParkedFlows restores the parked entries of a device being reloaded from the KV store
+func (agent *DeviceAgent) loadParkedFlows(ctx context.Context) {
+	flows := &voltha.Flows{}
+	if have, err := agent.clusterDataProxy.Get(ctx, parkedFlowsPath+"/"+agent.deviceID, flows); err == nil && have {
+		agent.parkedFlows = flows.Items
+	}
+	groups := &voltha.FlowGroups{}
+	if have, err := agent.clusterDataProxy.Get(ctx, parkedGroupsPath+"/"+agent.deviceID, groups); err == nil && have {
+		agent.parkedGroups = groups.Items
+	}
+	if (len(agent.parkedFlows) | len(agent.parkedGroups)) != 0 {
+		agent.parkedSince = time.Now()
+		ctxInfow(ctx, "restored-parked-flows", log.Fields{"device-id": agent.deviceID, "flows": len(agent.parkedFlows), "groups": len(agent.parkedGroups)})
+	}
+}
+
+// getFlowStatus reports the tracking status of one flow of a device: PARKED while it is
+// held back waiting for activation, APPLIED once it is part of the device flow table
+func (dMgr *DeviceManager) getFlowStatus(ctx context.Context, deviceID string, flowID uint64) (string, error) {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return "", newNotFoundError("device", deviceID)
+	}
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return "", err
+	}
+	defer agent.requestQueue.RequestComplete()
+	for _, flow := range agent.parkedFlows {
+		if flow.Id == flowID {
+			return flowStatusParked, nil
+		}
+	}
+	if agent.device.Flows != nil {
+		for _, flow := range agent.device.Flows.Items {
+			if flow.Id == flowID {
+				return flowStatusApplied, nil
+			}
+		}
+	}
+	return "", newNotFoundError("flow", flowID)
+}
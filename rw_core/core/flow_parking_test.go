@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFlowsParkedWhileActivating(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:31:01")
+
+	// The device is still ACTIVATING; the push is parked instead of forwarded
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1, Priority: 1}}, nil, nil))
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+	parkStatus, err := dMgr.getFlowStatus(ctx, device.Id, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, flowStatusParked, parkStatus)
+
+	// A later update merges with what is already parked instead of duplicating it
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1, Priority: 1}, {Id: 2}}, nil, nil))
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.Equal(t, 2, len(agent.parkedFlows))
+
+	// The parked entries are persisted
+	persisted := &voltha.Flows{}
+	have, err := dMgr.clusterDataProxy.Get(ctx, parkedFlowsPath+"/"+device.Id, persisted)
+	assert.Nil(t, err)
+	assert.True(t, have)
+	assert.Equal(t, 2, len(persisted.Items))
+
+	// Reaching ACTIVE replays them to the adapter
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	waitUntil(t, 2*time.Second, "parked flows were not replayed", func() bool {
+		return fas.callCount("updateFlowsBulk") == 1
+	})
+	waitUntil(t, 2*time.Second, "replayed flow did not become applied", func() bool {
+		flowStatus, err := dMgr.getFlowStatus(ctx, device.Id, 1)
+		return err == nil && flowStatus == flowStatusApplied
+	})
+}
+
+func TestParkedFlowsDroppedOnFailureOrExpiry(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+
+	// Activation failure discards whatever was parked
+	failing := enableOLT(t, dMgr, "00:00:00:00:31:02")
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, failing.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil))
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, failing.Id, voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE))
+	_, err := dMgr.getFlowStatus(ctx, failing.Id, 1)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Entries older than the parking window are not replayed
+	dMgr.flowParkingWindow = time.Millisecond
+	expiring := enableOLT(t, dMgr, "00:00:00:00:31:03")
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, expiring.Id, []*ofp.OfpFlowStats{{Id: 3}}, nil, nil))
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, expiring.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+}
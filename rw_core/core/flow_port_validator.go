@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	fu "github.com/opencord/voltha-lib-go/v3/pkg/flows"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The controller occasionally computes flows against a stale port view - a UNI that was
+// removed, a NNI that never came up - and the core used to forward them; the adapter
+// failure came back long after and without context.  Port references in a flow are
+// therefore checked against the device's current port list before the flow is accepted:
+// the in_port match and the output action must name an existing port.  Reserved OF port
+// numbers (controller, local, ...) are always accepted, as are the tunnel-id and
+// write-metadata encoded peer ports the OLT pipelines use, which name a port on the child
+// device rather than on the target.  The check can be switched off with the
+// skip_flow_port_validation flag, and permissive mode downgrades rejections to warnings
+// just as it does for capability validation.
+
+// isReservedOfPort reports whether a port number is one of the reserved OF ports
+// (controller, local, in-port, ...) rather than a physical or logical port
+func isReservedOfPort(portNo uint32) bool {
+	return portNo >= uint32(ofp.OfpPortNo_OFPP_MAX)
+}
+
+// validateFlowPortReferences checks the in_port and output port of every flow against the
+// device's port list, naming the missing port in the rejection
+func validateFlowPortReferences(device *voltha.Device, flows []*ofp.OfpFlowStats) error {
+	ports := make(map[uint32]struct{}, len(device.Ports))
+	for _, port := range device.Ports {
+		ports[port.PortNo] = struct{}{}
+	}
+	known := func(portNo uint32) bool {
+		if portNo == 0 || isReservedOfPort(portNo) {
+			return true
+		}
+		_, ok := ports[portNo]
+		return ok
+	}
+	for _, flow := range flows {
+		// The OLT pipelines carry the peer port of the child device - typically the ONU
+		// UNI - in the tunnel id match and in the write-metadata action; a reference equal
+		// to one of those names a port that legitimately does not exist on this device
+		tunnelPort := fu.GetChildPortFromTunnelId(flow)
+		metadataPort := fu.GetEgressPortNumberFromWriteMetadata(flow)
+		encoded := func(portNo uint32) bool {
+			return (tunnelPort != 0 && portNo == tunnelPort) || (metadataPort != 0 && portNo == metadataPort)
+		}
+		var offending []string
+		if inPort := fu.GetInPort(flow); !known(inPort) && !encoded(inPort) {
+			offending = append(offending, fmt.Sprintf("in-port:%d", inPort))
+		}
+		if outPort := fu.GetOutPort(flow); !known(outPort) && !encoded(outPort) {
+			offending = append(offending, fmt.Sprintf("out-port:%d", outPort))
+		}
+		if len(offending) > 0 {
+			return newInvalidArgumentError(fmt.Sprintf("unknown-port-reference-on-device-%s: %s", device.Id, strings.Join(offending, ",")))
+		}
+	}
+	return nil
+}
+
+// validateFlowPorts applies validateFlowPortReferences to the current state of the target
+// device, honouring the skip flag and permissive mode
+func (dMgr *DeviceManager) validateFlowPorts(ctx context.Context, agent *DeviceAgent, flows []*ofp.OfpFlowStats) error {
+	if dMgr.skipFlowPortValidation || len(flows) == 0 {
+		return nil
+	}
+	device := agent.getDeviceWithoutLock()
+	if device == nil {
+		return nil
+	}
+	if err := validateFlowPortReferences(device, flows); err != nil {
+		if dMgr.permissiveFlowValidation {
+			ctxWarnw(ctx, "flow-port-validation-bypassed", log.Fields{"device-id": device.Id, "error": err})
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	fu "github.com/opencord/voltha-lib-go/v3/pkg/flows"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func mkPortTestFlow(t *testing.T, fa *fu.FlowArgs) *ofp.OfpFlowStats {
+	flow, err := fu.MkFlowStat(fa)
+	assert.Nil(t, err)
+	return flow
+}
+
+func TestValidateFlowPortReferences(t *testing.T) {
+	device := &voltha.Device{Id: "olt-ports", Ports: []*voltha.Port{
+		{PortNo: 1, Type: voltha.Port_ETHERNET_NNI},
+		{PortNo: 2, Type: voltha.Port_PON_OLT},
+	}}
+
+	valid := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(1)},
+		Actions:     []*ofp.OfpAction{fu.Output(2)},
+	})
+	assert.Nil(t, validateFlowPortReferences(device, []*ofp.OfpFlowStats{valid}))
+
+	// Reserved OF ports are always accepted
+	trap := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(2), fu.EthType(0x888e)},
+		Actions:     []*ofp.OfpAction{fu.Output(uint32(ofp.OfpPortNo_OFPP_CONTROLLER))},
+	})
+	assert.Nil(t, validateFlowPortReferences(device, []*ofp.OfpFlowStats{trap}))
+
+	// A reference to a port the device does not have names the port in the rejection
+	stale := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(1)},
+		Actions:     []*ofp.OfpAction{fu.Output(99)},
+	})
+	err := validateFlowPortReferences(device, []*ofp.OfpFlowStats{stale})
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "out-port:99")
+	assert.Contains(t, err.Error(), device.Id)
+}
+
+func TestFlowPortValidationUnderstandsEncodedPorts(t *testing.T) {
+	device := &voltha.Device{Id: "olt-ports", Ports: []*voltha.Port{
+		{PortNo: 1, Type: voltha.Port_ETHERNET_NNI},
+		{PortNo: 2, Type: voltha.Port_PON_OLT},
+	}}
+
+	// Upstream OLT flow: the output names the ONU UNI carried in the tunnel id, which is
+	// not a port of the OLT itself
+	upstream := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(2), fu.TunnelId(257)},
+		Actions:     []*ofp.OfpAction{fu.Output(257)},
+	})
+	assert.Nil(t, validateFlowPortReferences(device, []*ofp.OfpFlowStats{upstream}))
+
+	// Downstream OLT flow: the egress UNI is encoded in the write-metadata instruction
+	downstream := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(1)},
+		Actions:     []*ofp.OfpAction{fu.Output(258)},
+		KV:          fu.OfpFlowModArgs{"write_metadata": 258},
+	})
+	assert.Nil(t, validateFlowPortReferences(device, []*ofp.OfpFlowStats{downstream}))
+
+	// A tunnel id does not excuse an unrelated bogus reference
+	bogus := mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(2), fu.TunnelId(257)},
+		Actions:     []*ofp.OfpAction{fu.Output(300)},
+	})
+	err := validateFlowPortReferences(device, []*ofp.OfpFlowStats{bogus})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "out-port:300")
+}
+
+func TestFlowPortValidationSkipAndPermissiveModes(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	agent := registerTestAgent(t, dMgr, &voltha.Device{
+		Id: "olt-port-modes", Type: "olt", Root: true, Adapter: "adapter-1",
+		Ports: []*voltha.Port{{PortNo: 1, Type: voltha.Port_ETHERNET_NNI}},
+	})
+	stale := []*ofp.OfpFlowStats{mkPortTestFlow(t, &fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{fu.InPort(1)},
+		Actions:     []*ofp.OfpAction{fu.Output(99)},
+	})}
+
+	err := dMgr.validateFlowPorts(ctx, agent, stale)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	dMgr.skipFlowPortValidation = true
+	assert.Nil(t, dMgr.validateFlowPorts(ctx, agent, stale))
+	dMgr.skipFlowPortValidation = false
+
+	dMgr.permissiveFlowValidation = true
+	assert.Nil(t, dMgr.validateFlowPorts(ctx, agent, stale))
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	fu "github.com/opencord/voltha-lib-go/v3/pkg/flows"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The controller can send any OF flow, including matches and actions the target device type
+// cannot support; without validation that failure only surfaces as an opaque adapter error
+// long after the flow was accepted.  Flows are therefore validated against the device type
+// before they are persisted or forwarded: a baseline of matches and actions supported by
+// access devices applies to every type, and an adapter can narrow or widen it by registering
+// a per-type validator with the adapter manager.  Rejections are synchronous InvalidArgument
+// errors listing the offending fields.
+
+// FlowValidator checks one flow against the capabilities of a device type.  A nil error
+// accepts the flow.
+type FlowValidator func(deviceType *voltha.DeviceType, flow *ofp.OfpFlowStats) error
+
+// baselineOxmFields are the match fields any access device type is expected to support
+var baselineOxmFields = map[ofp.OxmOfbFieldTypes]struct{}{
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_IN_PORT:   {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_ETH_DST:   {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_ETH_TYPE:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_VLAN_VID:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_VLAN_PCP:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_IP_PROTO:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_IPV4_DST:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_UDP_SRC:   {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_UDP_DST:   {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_METADATA:  {},
+	ofp.OxmOfbFieldTypes_OFPXMT_OFB_TUNNEL_ID: {},
+}
+
+// baselineActions are the actions any access device type is expected to support
+var baselineActions = map[ofp.OfpActionType]struct{}{
+	ofp.OfpActionType_OFPAT_OUTPUT:    {},
+	ofp.OfpActionType_OFPAT_GROUP:     {},
+	ofp.OfpActionType_OFPAT_SET_FIELD: {},
+	ofp.OfpActionType_OFPAT_PUSH_VLAN: {},
+	ofp.OfpActionType_OFPAT_POP_VLAN:  {},
+}
+
+// validateFlowAgainstBaseline rejects matches and actions outside the baseline capability
+// set, listing every offending field in the error
+func validateFlowAgainstBaseline(deviceType *voltha.DeviceType, flow *ofp.OfpFlowStats) error {
+	var offending []string
+	for _, field := range fu.GetOfbFields(flow) {
+		if _, ok := baselineOxmFields[field.Type]; !ok {
+			offending = append(offending, "match:"+field.Type.String())
+		}
+	}
+	for _, action := range fu.GetActions(flow) {
+		if _, ok := baselineActions[action.Type]; !ok {
+			offending = append(offending, "action:"+action.Type.String())
+		}
+	}
+	if len(offending) > 0 {
+		return newInvalidArgumentError(fmt.Sprintf("unsupported-flow-fields-for-device-type-%s: %s", deviceType.Id, strings.Join(offending, ",")))
+	}
+	return nil
+}
+
+// validateFlows checks every flow against the device type of the target device.  In
+// permissive mode validation failures are logged but the flows are forwarded anyway.
+func (dMgr *DeviceManager) validateFlows(deviceType string, flows []*ofp.OfpFlowStats) error {
+	if len(flows) == 0 {
+		return nil
+	}
+	dType := dMgr.adapterMgr.getDeviceType(deviceType)
+	if dType == nil {
+		// An unknown device type cannot be validated; leave the decision to the adapter
+		return nil
+	}
+	validator := dMgr.adapterMgr.getFlowValidator(deviceType)
+	for _, flow := range flows {
+		if err := validator(dType, flow); err != nil {
+			if dMgr.permissiveFlowValidation {
+				logger.Warnw("flow-validation-bypassed", log.Fields{"deviceType": deviceType, "flow": flow.Id, "error": err})
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
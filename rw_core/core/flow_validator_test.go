@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+
+	fu "github.com/opencord/voltha-lib-go/v3/pkg/flows"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateFlowAgainstBaseline(t *testing.T) {
+	dType := &voltha.DeviceType{Id: "olt"}
+
+	supported, err := fu.MkFlowStat(&fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{
+			fu.InPort(1),
+			fu.EthType(0x888e),
+			fu.VlanVid(uint32(ofp.OfpVlanId_OFPVID_PRESENT) | 4091),
+		},
+		Actions: []*ofp.OfpAction{
+			fu.Output(uint32(ofp.OfpPortNo_OFPP_CONTROLLER)),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, validateFlowAgainstBaseline(dType, supported))
+
+	unsupported, err := fu.MkFlowStat(&fu.FlowArgs{
+		MatchFields: []*ofp.OfpOxmOfbField{
+			fu.InPort(1),
+			fu.Ipv6Dst([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}),
+		},
+		Actions: []*ofp.OfpAction{
+			fu.PopMpls(0x8847),
+		},
+	})
+	assert.Nil(t, err)
+	err = validateFlowAgainstBaseline(dType, unsupported)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "OFPXMT_OFB_IPV6_DST")
+	assert.Contains(t, err.Error(), "OFPAT_POP_MPLS")
+}
+
+func TestFlowValidatorOverridePerType(t *testing.T) {
+	aMgr := &AdapterManager{flowValidators: make(map[string]FlowValidator)}
+
+	// the baseline applies when no validator is registered
+	assert.NotNil(t, aMgr.getFlowValidator("olt"))
+
+	rejectAll := func(deviceType *voltha.DeviceType, flow *ofp.OfpFlowStats) error {
+		return newInvalidArgumentError("rejected")
+	}
+	aMgr.registerFlowValidator("olt", rejectAll)
+	err := aMgr.getFlowValidator("olt")(&voltha.DeviceType{Id: "olt"}, &ofp.OfpFlowStats{})
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
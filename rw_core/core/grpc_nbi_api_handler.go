@@ -162,26 +162,37 @@ func (handler *APIHandler) UpdateLogicalDeviceFlowGroupTable(ctx context.Context
 // GetDevice must be implemented in the read-only containers - should it also be implemented here?
 func (handler *APIHandler) GetDevice(ctx context.Context, id *voltha.ID) (*voltha.Device, error) {
 	logger.Debugw("GetDevice-request", log.Fields{"id": id})
-	return handler.deviceMgr.GetDevice(ctx, id.Id)
+	device, err := handler.deviceMgr.GetDevice(ctx, id.Id)
+	if err != nil {
+		return nil, err
+	}
+	handler.setDeviceViewTrailer(ctx, device)
+	return device, nil
 }
 
 // GetDevice must be implemented in the read-only containers - should it also be implemented here?
 
-// ListDevices retrieves the latest devices from the data model
+// ListDevices retrieves the latest devices from the data model.  A caller that supplies
+// the revision of its previous answer in the request header gets an empty list back when
+// nothing changed, with the response trailer saying so.
 func (handler *APIHandler) ListDevices(ctx context.Context, empty *empty.Empty) (*voltha.Devices, error) {
 	logger.Debug("ListDevices")
-	devices, err := handler.deviceMgr.ListDevices(ctx)
+	devices, revision, err := handler.deviceMgr.listDevicesSince(ctx, sinceRevisionFromContext(ctx))
 	if err != nil {
 		logger.Errorw("Failed to list devices", log.Fields{"error": err})
 		return nil, err
 	}
+	setCollectionRevisionTrailer(ctx, revision, devices == nil)
+	if devices == nil {
+		return &voltha.Devices{}, nil
+	}
 	return devices, nil
 }
 
 // ListDeviceIds returns the list of device ids managed by a voltha core
 func (handler *APIHandler) ListDeviceIds(ctx context.Context, empty *empty.Empty) (*voltha.IDs, error) {
 	logger.Debug("ListDeviceIDs")
-	return handler.deviceMgr.ListDeviceIds()
+	return handler.deviceMgr.ListDeviceIds(ctx)
 }
 
 //ReconcileDevices is a request to a voltha core to managed a list of devices  based on their IDs
@@ -533,31 +544,37 @@ func (handler *APIHandler) ListDevicePmConfigs(ctx context.Context, id *voltha.I
 	return handler.deviceMgr.listPmConfigs(ctx, id.Id)
 }
 
+// CreateEventFilter adds a new event subscription filter
 func (handler *APIHandler) CreateEventFilter(ctx context.Context, filter *voltha.EventFilter) (*voltha.EventFilter, error) {
 	logger.Debugw("CreateEventFilter-request", log.Fields{"filter": *filter})
-	return nil, errors.New("UnImplemented")
+	return handler.core.eventMgr.createEventFilter(ctx, filter)
 }
 
+// UpdateEventFilter modifies an existing event subscription filter
 func (handler *APIHandler) UpdateEventFilter(ctx context.Context, filter *voltha.EventFilter) (*voltha.EventFilter, error) {
 	logger.Debugw("UpdateEventFilter-request", log.Fields{"filter": *filter})
-	return nil, errors.New("UnImplemented")
+	return handler.core.eventMgr.updateEventFilter(ctx, filter)
 }
 
+// DeleteEventFilter removes an event subscription filter
 func (handler *APIHandler) DeleteEventFilter(ctx context.Context, filterInfo *voltha.EventFilter) (*empty.Empty, error) {
 	logger.Debugw("DeleteEventFilter-request", log.Fields{"device-id": filterInfo.DeviceId, "filter-id": filterInfo.Id})
-	return nil, errors.New("UnImplemented")
+	if err := handler.core.eventMgr.deleteEventFilter(ctx, filterInfo.Id); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
 }
 
 // GetEventFilter returns all the filters present for a device
 func (handler *APIHandler) GetEventFilter(ctx context.Context, id *voltha.ID) (*voltha.EventFilters, error) {
 	logger.Debugw("GetEventFilter-request", log.Fields{"device-id": id})
-	return nil, errors.New("UnImplemented")
+	return handler.core.eventMgr.getEventFilters(id.Id), nil
 }
 
 // ListEventFilters returns all the filters known to the system
 func (handler *APIHandler) ListEventFilters(ctx context.Context, empty *empty.Empty) (*voltha.EventFilters, error) {
 	logger.Debug("ListEventFilter-request")
-	return nil, errors.New("UnImplemented")
+	return handler.core.eventMgr.listEventFilters(), nil
 }
 
 func (handler *APIHandler) SelfTest(ctx context.Context, id *voltha.ID) (*voltha.SelfTestResponse, error) {
@@ -567,9 +584,12 @@ func (handler *APIHandler) SelfTest(ctx context.Context, id *voltha.ID) (*voltha
 
 func (handler *APIHandler) forwardPacketOut(ctx context.Context, packet *openflow_13.PacketOut) {
 	logger.Debugw("forwardPacketOut-request", log.Fields{"packet": packet})
-	//TODO: Update this logic once the OF Controller (OFAgent in this case) can include a transaction Id in its
-	// request.  For performance reason we can let both Cores in a Core-Pair forward the Packet to the adapters and
-	// let once of the shim layer (kafka proxy or adapter request handler filters out the duplicate packet)
+	// Only the instance owning the logical device forwards the packet; the peer drops its
+	// copy so the adapters never see the packet-out twice
+	if err := handler.logicalDeviceMgr.refuseIfNotOwned(ctx, packet.Id); err != nil {
+		logger.Debugw("packet-out-ignored-not-owner", log.Fields{"logicaldeviceID": packet.Id})
+		return
+	}
 	if agent := handler.logicalDeviceMgr.getLogicalDeviceAgent(ctx, packet.Id); agent != nil {
 		agent.packetOut(ctx, packet.PacketOut)
 	} else {
@@ -714,6 +734,9 @@ loop:
 		select {
 		// Dequeue a change event
 		case event := <-handler.changeEventQueue:
+			if !handler.changeEventVisible(changeEvents.Context(), event.Id) {
+				continue
+			}
 			logger.Debugw("sending-change-event", log.Fields{"event": event})
 			if err := changeEvents.Send(&event); err != nil {
 				logger.Errorw("failed-to-send-change-event", log.Fields{"error": err})
@@ -376,7 +376,7 @@ func (nb *NBTest) testEnableDevice(t *testing.T, nbi *APIHandler) {
 	// Try to enable the oltDevice and check the error message
 	_, err = nbi.EnableDevice(getContext(), &voltha.ID{Id: oltDeviceNoAdapter.Id})
 	assert.NotNil(t, err)
-	assert.Equal(t, "Adapter-not-registered-for-device-type noAdapterRegistered", err.Error())
+	assert.Equal(t, "rpc error: code = NotFound desc = adapter-for-device-type-not-found: noAdapterRegistered", err.Error())
 
 	//Remove the device
 	_, err = nbi.DeleteDevice(getContext(), &voltha.ID{Id: oltDeviceNoAdapter.Id})
@@ -816,7 +816,7 @@ func (nb *NBTest) testStartOmciTestAction(t *testing.T, nbi *APIHandler) {
 	request := &voltha.OmciTestRequest{Id: "123", Uuid: "456"}
 	_, err := nbi.StartOmciTestAction(getContext(), request)
 	assert.NotNil(t, err)
-	assert.Equal(t, "rpc error: code = NotFound desc = 123", err.Error())
+	assert.Equal(t, "rpc error: code = NotFound desc = device-not-found: 123", err.Error())
 
 	// -----------------------------------------------------------------------
 	// SubTest 2: Error should be returned for device with no adapter registered
@@ -830,7 +830,7 @@ func (nb *NBTest) testStartOmciTestAction(t *testing.T, nbi *APIHandler) {
 	request = &voltha.OmciTestRequest{Id: deviceNoAdapter.Id, Uuid: "456"}
 	_, err = nbi.StartOmciTestAction(getContext(), request)
 	assert.NotNil(t, err)
-	assert.Equal(t, "Adapter-not-registered-for-device-type noAdapterRegisteredOmciTest", err.Error())
+	assert.Equal(t, "rpc error: code = NotFound desc = adapter-for-device-type-not-found: noAdapterRegisteredOmciTest", err.Error())
 
 	//Remove the device
 	_, err = nbi.DeleteDevice(getContext(), &voltha.ID{Id: deviceNoAdapter.Id})
@@ -35,6 +35,11 @@ func CreateLogicalDeviceID() string {
 	return uuid.New().String()
 }
 
+// CreateEventFilterID produces an event filter ID. The event filter ID is a UUID
+func CreateEventFilterID() string {
+	return uuid.New().String()
+}
+
 // CreateLogicalPortID produces a random port ID for a logical device.
 func CreateLogicalPortID() uint32 {
 	//	A logical port is a uint32
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ONOS and orchestration retries duplicate mutating NBI calls.  A caller may tag a request
+// with an idempotency key (gRPC metadata header "idempotency-key"); the outcome of the first
+// execution is persisted under the key and returned verbatim for repeats within the
+// retention window instead of re-executing the operation.
+//
+// For createDevice the key is bound to the device ID before the device itself is written:
+// the reservation record names the ID the creation will use, so a retry after a crash
+// between the two writes redoes the creation under the same ID rather than minting a second
+// device.
+const (
+	// idempotencyKeyHeader is the gRPC metadata header carrying the caller's idempotency key
+	idempotencyKeyHeader = "idempotency-key"
+	// idempotencyPath is the KV path under which idempotency records are persisted
+	idempotencyPath = "idempotency_keys"
+	// defaultIdempotencyRetention is how long a stored outcome shields against repeats
+	defaultIdempotencyRetention = 15 * time.Minute
+)
+
+// idempotencyKeyFromContext returns the idempotency key of the request, or an empty string
+// when the caller did not supply one
+func idempotencyKeyFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(idempotencyKeyHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// idempotencyRecord is the persisted outcome of one keyed operation.  A createDevice record
+// is written before the device itself and acts as the reservation of the device ID.
+type idempotencyRecord struct {
+	operation string
+	deviceID  string
+	expiry    time.Time
+	errCode   codes.Code // codes.OK when the operation succeeded
+	errMsg    string
+}
+
+// result reconstructs the outcome stored in the record
+func (record *idempotencyRecord) result() error {
+	if record.errCode == codes.OK {
+		return nil
+	}
+	return status.Errorf(record.errCode, "%s", record.errMsg)
+}
+
+// encodeIdempotencyRecord serializes a record for the KV store
+func encodeIdempotencyRecord(record *idempotencyRecord) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%s", record.operation, record.deviceID,
+		record.expiry.Format(time.RFC3339), record.errCode, record.errMsg)
+}
+
+// decodeIdempotencyRecord deserializes a record read back from the KV store
+func decodeIdempotencyRecord(value string) (*idempotencyRecord, error) {
+	parts := strings.SplitN(value, "|", 5)
+	if len(parts) != 5 || parts[0] == "" {
+		return nil, fmt.Errorf("malformed-idempotency-record: %s", value)
+	}
+	expiry, err := time.Parse(time.RFC3339, parts[2])
+	if err != nil {
+		return nil, err
+	}
+	code, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, err
+	}
+	return &idempotencyRecord{
+		operation: parts[0],
+		deviceID:  parts[1],
+		expiry:    expiry,
+		errCode:   codes.Code(code),
+		errMsg:    parts[4],
+	}, nil
+}
+
+// idempotencyManager persists and recalls the outcomes of keyed operations
+type idempotencyManager struct {
+	clusterDataProxy *model.Proxy
+	retention        time.Duration
+	lock             sync.Mutex
+}
+
+func newIdempotencyManager(cdProxy *model.Proxy) *idempotencyManager {
+	return &idempotencyManager{
+		clusterDataProxy: cdProxy,
+		retention:        defaultIdempotencyRetention,
+	}
+}
+
+// get returns the record stored under the key.  An expired record is removed and reported
+// as a miss.
+func (mgr *idempotencyManager) get(ctx context.Context, key string) (*idempotencyRecord, bool) {
+	entry := &ic.StrType{}
+	found, err := mgr.clusterDataProxy.Get(ctx, idempotencyPath+"/"+key, entry)
+	if err != nil || !found {
+		return nil, false
+	}
+	record, err := decodeIdempotencyRecord(entry.Val)
+	if err != nil {
+		ctxWarnw(ctx, "ignoring-invalid-idempotency-record", log.Fields{"key": key, "value": entry.Val, "error": err})
+		return nil, false
+	}
+	if time.Now().After(record.expiry) {
+		if err := mgr.clusterDataProxy.Remove(ctx, idempotencyPath+"/"+key); err != nil {
+			ctxWarnw(ctx, "failed-to-remove-expired-idempotency-record", log.Fields{"key": key, "error": err})
+		}
+		return nil, false
+	}
+	return record, true
+}
+
+// put persists a record under the key
+func (mgr *idempotencyManager) put(ctx context.Context, key string, record *idempotencyRecord) error {
+	record.expiry = time.Now().Add(mgr.retention)
+	return mgr.clusterDataProxy.AddWithID(ctx, idempotencyPath, key, &ic.StrType{Val: encodeIdempotencyRecord(record)})
+}
+
+// repeatedRequestResult returns the stored outcome when the request carries the key of an
+// operation that already ran.  Reusing a key for a different operation or device is an error.
+func (dMgr *DeviceManager) repeatedRequestResult(ctx context.Context, operation string, deviceID string) (error, bool) {
+	key := idempotencyKeyFromContext(ctx)
+	if key == "" || dMgr.idempotency == nil {
+		return nil, false
+	}
+	dMgr.idempotency.lock.Lock()
+	defer dMgr.idempotency.lock.Unlock()
+	record, found := dMgr.idempotency.get(ctx, key)
+	if !found {
+		return nil, false
+	}
+	if record.operation != operation || record.deviceID != deviceID {
+		return status.Errorf(codes.InvalidArgument,
+			"idempotency-key-reused-for-%s-on-%s", record.operation, record.deviceID), true
+	}
+	ctxInfow(ctx, "serving-repeated-request-from-idempotency-record", log.Fields{"key": key, "operation": operation, "device-id": deviceID})
+	return record.result(), true
+}
+
+// storeRequestResult persists the outcome of a keyed operation so repeats are not re-executed
+func (dMgr *DeviceManager) storeRequestResult(ctx context.Context, operation string, deviceID string, res interface{}) {
+	key := idempotencyKeyFromContext(ctx)
+	if key == "" || dMgr.idempotency == nil {
+		return
+	}
+	record := &idempotencyRecord{operation: operation, deviceID: deviceID, errCode: codes.OK}
+	if err, ok := res.(error); ok && err != nil {
+		record.errCode = status.Code(err)
+		record.errMsg = status.Convert(err).Message()
+	}
+	dMgr.idempotency.lock.Lock()
+	defer dMgr.idempotency.lock.Unlock()
+	if err := dMgr.idempotency.put(ctx, key, record); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-idempotency-record", log.Fields{"key": key, "operation": operation, "error": err})
+	}
+}
+
+// reserveCreateDeviceID binds the idempotency key of a createDevice request to the device ID
+// the creation will use.  It returns the ID to create the device under and, when the device
+// already exists from a completed earlier attempt, reports that through the found flag at the
+// call site via the returned reused flag.
+func (dMgr *DeviceManager) reserveCreateDeviceID(ctx context.Context, key string) (deviceID string, reused bool, err error) {
+	dMgr.idempotency.lock.Lock()
+	defer dMgr.idempotency.lock.Unlock()
+	if record, found := dMgr.idempotency.get(ctx, key); found {
+		if record.operation != "createDevice" {
+			return "", false, status.Errorf(codes.InvalidArgument,
+				"idempotency-key-reused-for-%s-on-%s", record.operation, record.deviceID)
+		}
+		return record.deviceID, true, nil
+	}
+	deviceID = CreateDeviceID()
+	record := &idempotencyRecord{operation: "createDevice", deviceID: deviceID, errCode: codes.OK}
+	if err := dMgr.idempotency.put(ctx, key, record); err != nil {
+		return "", false, err
+	}
+	return deviceID, false, nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// withIdempotencyKey tags a context the way the gRPC transport would
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(idempotencyKeyHeader, key))
+}
+
+func TestIdempotencyRecordCodec(t *testing.T) {
+	record := &idempotencyRecord{
+		operation: "enableDevice",
+		deviceID:  "dev-1",
+		expiry:    time.Now().Add(time.Minute).Truncate(time.Second),
+		errCode:   codes.FailedPrecondition,
+		errMsg:    "bad-state",
+	}
+	decoded, err := decodeIdempotencyRecord(encodeIdempotencyRecord(record))
+	assert.Nil(t, err)
+	assert.Equal(t, record.operation, decoded.operation)
+	assert.Equal(t, record.deviceID, decoded.deviceID)
+	assert.Equal(t, record.errCode, decoded.errCode)
+	assert.Equal(t, record.errMsg, decoded.errMsg)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(decoded.result()))
+
+	_, err = decodeIdempotencyRecord("garbage")
+	assert.NotNil(t, err)
+}
+
+func TestCreateDeviceIdempotency(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.idempotency = newIdempotencyManager(dMgr.clusterDataProxy)
+	ctx := withIdempotencyKey(context.Background(), "create-key-1")
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:03:01"}, ch)
+	first, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// The retry returns the same device instead of creating a second one
+	ch = make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:03:01"}, ch)
+	second, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	assert.Equal(t, first.Id, second.Id)
+
+	devices, err := dMgr.ListDevices(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices.Items))
+}
+
+func TestCreateDeviceIdempotencyAfterCrash(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.idempotency = newIdempotencyManager(dMgr.clusterDataProxy)
+	ctx := withIdempotencyKey(context.Background(), "create-key-2")
+
+	// Simulate a crash after the reservation was written but before the device was: the
+	// retry must redo the creation under the reserved ID
+	reservedID, reserved, err := dMgr.reserveCreateDeviceID(ctx, "create-key-2")
+	assert.Nil(t, err)
+	assert.False(t, reserved)
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:03:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	assert.Equal(t, reservedID, device.Id)
+}
+
+func TestEnableDeviceIdempotency(t *testing.T) {
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.idempotency = newIdempotencyManager(dMgr.clusterDataProxy)
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(context.Background(), &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:03:03"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	ctx := withIdempotencyKey(context.Background(), "enable-key-1")
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Equal(t, 1, fas.callCount("adoptDevice"))
+
+	// The repeat is served from the stored outcome; the adapter is not asked again
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Equal(t, 1, fas.callCount("adoptDevice"))
+
+	// Reusing the key for a different operation is rejected
+	ch = make(chan interface{}, 1)
+	dMgr.rebootDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	err, ok := (<-ch).(error)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
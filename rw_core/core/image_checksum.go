@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The ImageDownload record has carried a CRC field forever, but the core never looked at
+// it - a corrupted transfer sailed through to activation and bricked the ONU.  The
+// checksum now works for its living: a request's CRC is kept on the stored record, the
+// adapter reports the CRC it computed over the received bytes in its updateImageDownload
+// status, and a mismatch marks the record DOWNLOAD_FAILED and raises an event.  The
+// activation path refuses a failed download, so a corrupt image cannot be activated.
+// Operators who want the protection to be mandatory set image_checksum_required, which
+// rejects download requests that carry no checksum at all.
+
+// validateImageChecksumPolicy refuses a download request without a checksum when the
+// deployment demands one
+func (dMgr *DeviceManager) validateImageChecksumPolicy(img *voltha.ImageDownload) error {
+	if dMgr.imageChecksumRequired && img.Crc == 0 {
+		return newInvalidArgumentError(fmt.Sprintf("image-checksum-required:%s", img.Name))
+	}
+	return nil
+}
+
+// verifyReportedImageChecksum compares the CRC the adapter computed over the downloaded
+// bytes against the one the download was requested with, demoting a successful report to
+// DOWNLOAD_FAILED on a mismatch.  A request without a CRC verifies nothing, as before.
+func (agent *DeviceAgent) verifyReportedImageChecksum(ctx context.Context, device *voltha.Device, requested *voltha.ImageDownload, reported *voltha.ImageDownload) {
+	if requested.Crc == 0 || reported.DownloadState != voltha.ImageDownload_DOWNLOAD_SUCCEEDED {
+		return
+	}
+	if reported.Crc == requested.Crc {
+		return
+	}
+	ctxWarnw(ctx, "image-checksum-mismatch", log.Fields{
+		"device-id":    agent.deviceID,
+		"image":        reported.Name,
+		"expected-crc": requested.Crc,
+		"computed-crc": reported.Crc,
+	})
+	reported.DownloadState = voltha.ImageDownload_DOWNLOAD_FAILED
+	reported.Reason = voltha.ImageDownload_UNKNOWN_ERROR
+	go agent.deviceMgr.eventMgr.publishDeviceEvent(device, imageChecksumMismatchEvent, map[string]string{
+		"image":        reported.Name,
+		"expected-crc": fmt.Sprintf("%d", requested.Crc),
+		"computed-crc": fmt.Sprintf("%d", reported.Crc),
+	})
+}
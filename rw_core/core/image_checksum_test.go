@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageChecksumRequiredPolicy(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.imageChecksumRequired = true
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:48")
+
+	ch := make(chan interface{}, 1)
+	dMgr.downloadImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw", Url: "http://images.local/sw.img"}, ch)
+	err, failed := (<-ch).(error)
+	assert.True(t, failed)
+	assert.Contains(t, err.Error(), "image-checksum-required")
+
+	ch = make(chan interface{}, 1)
+	dMgr.downloadImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw", Url: "http://images.local/sw.img", Crc: 1234}, ch)
+	_, failed = (<-ch).(error)
+	assert.False(t, failed)
+}
+
+func TestImageChecksumMismatchFailsDownload(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:49")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	seedImageDownloads(t, dMgr, device.Id, []*voltha.ImageDownload{
+		{Id: device.Id, Name: "sw", Crc: 1234, DownloadState: voltha.ImageDownload_DOWNLOAD_STARTED},
+	})
+
+	// The adapter computed a different checksum over the received bytes
+	assert.Nil(t, dMgr.updateImageDownload(ctx, device.Id, &voltha.ImageDownload{
+		Id: device.Id, Name: "sw", Crc: 9999, DownloadState: voltha.ImageDownload_DOWNLOAD_SUCCEEDED,
+	}))
+	record, err := agent.getImageDownload(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw"})
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.ImageDownload_DOWNLOAD_FAILED, record.DownloadState)
+	assert.Equal(t, voltha.ImageDownload_UNKNOWN_ERROR, record.Reason)
+
+	// The failed download cannot be activated
+	_, err = agent.activateImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "image-download-failed")
+}
+
+func TestImageChecksumMatchPassesThrough(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:4a")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	seedImageDownloads(t, dMgr, device.Id, []*voltha.ImageDownload{
+		{Id: device.Id, Name: "sw", Crc: 1234, DownloadState: voltha.ImageDownload_DOWNLOAD_STARTED},
+	})
+
+	assert.Nil(t, dMgr.updateImageDownload(ctx, device.Id, &voltha.ImageDownload{
+		Id: device.Id, Name: "sw", Crc: 1234, DownloadState: voltha.ImageDownload_DOWNLOAD_SUCCEEDED,
+	}))
+	record, err := agent.getImageDownload(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw"})
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.ImageDownload_DOWNLOAD_SUCCEEDED, record.DownloadState)
+}
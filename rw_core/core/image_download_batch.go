@@ -0,0 +1,364 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Pushing a firmware image to every ONU of an OLT at once saturates the downstream of
+// each PON port and disrupts the subscribers still online on it.  The batch download
+// below runs as a tracked job: the children of the parent are grouped by ParentPortNo
+// and at most a handful of downloads run concurrently per PON port - the rest wait in
+// a queue, visible as QUEUED against DOWNLOADING in the per-child status.  The per-PON
+// limit is configurable per device type, since a combo ONT tolerates more concurrent
+// transfers than a low-end bridge.  Cancelling the job drains the queue - children not
+// yet dispatched are marked cancelled - and asks the adapter to abort the downloads
+// already in flight.
+
+// downloadBatchJobType names the batch download in job records
+const downloadBatchJobType = "download-image-to-children"
+
+// defaultPonDownloadLimit is how many downloads run concurrently per PON port for a
+// device type without a configured limit
+const defaultPonDownloadLimit = 2
+
+// States a child moves through in a batch download.  QUEUED and DOWNLOADING are
+// transient; the other three are final.
+const (
+	childDownloadQueued      = "QUEUED"
+	childDownloadDownloading = "DOWNLOADING"
+	childDownloadSucceeded   = "SUCCEEDED"
+	childDownloadFailed      = "FAILED"
+	childDownloadCancelled   = "CANCELLED"
+)
+
+// childDownloadStatus is the per-child progress view of a batch download
+type childDownloadStatus struct {
+	DeviceID string `json:"device-id"`
+	PonPort  uint32 `json:"pon-port"`
+	State    string `json:"state"`
+	Error    string `json:"error,omitempty"`
+}
+
+// imageDownloadBatch tracks the children of one running batch download.  The adapter
+// reports download outcomes asynchronously through updateImageDownload, so each
+// dispatched child has a waiter the report is delivered to.
+type imageDownloadBatch struct {
+	lock     sync.Mutex
+	statuses map[string]*childDownloadStatus
+	waiters  map[string]chan voltha.ImageDownload_ImageDownloadState
+}
+
+func newImageDownloadBatch(children []*voltha.Device) *imageDownloadBatch {
+	batch := &imageDownloadBatch{
+		statuses: make(map[string]*childDownloadStatus, len(children)),
+		waiters:  make(map[string]chan voltha.ImageDownload_ImageDownloadState),
+	}
+	for _, child := range children {
+		batch.statuses[child.Id] = &childDownloadStatus{DeviceID: child.Id, PonPort: child.ParentPortNo, State: childDownloadQueued}
+	}
+	return batch
+}
+
+// setState moves a child to a new state, recording the error detail of a failure
+func (batch *imageDownloadBatch) setState(deviceID string, state string, detail string) {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	if status, tracked := batch.statuses[deviceID]; tracked {
+		status.State, status.Error = state, detail
+	}
+}
+
+// watch registers for the terminal download report of a child; called before the
+// download is dispatched so the report cannot slip by
+func (batch *imageDownloadBatch) watch(deviceID string) chan voltha.ImageDownload_ImageDownloadState {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	ch := make(chan voltha.ImageDownload_ImageDownloadState, 1)
+	batch.waiters[deviceID] = ch
+	return ch
+}
+
+// deliver hands a terminal download report to the child's waiter, if one is registered
+func (batch *imageDownloadBatch) deliver(deviceID string, state voltha.ImageDownload_ImageDownloadState) {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	if ch, waiting := batch.waiters[deviceID]; waiting {
+		delete(batch.waiters, deviceID)
+		ch <- state
+	}
+}
+
+// snapshot returns the per-child statuses, ordered by PON port then device ID so the
+// view is stable across polls
+func (batch *imageDownloadBatch) snapshot() []*childDownloadStatus {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	statuses := make([]*childDownloadStatus, 0, len(batch.statuses))
+	for _, status := range batch.statuses {
+		copied := *status
+		statuses = append(statuses, &copied)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].PonPort != statuses[j].PonPort {
+			return statuses[i].PonPort < statuses[j].PonPort
+		}
+		return statuses[i].DeviceID < statuses[j].DeviceID
+	})
+	return statuses
+}
+
+// finished counts the children that reached a final state
+func (batch *imageDownloadBatch) finished() int {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	count := 0
+	for _, status := range batch.statuses {
+		switch status.State {
+		case childDownloadSucceeded, childDownloadFailed, childDownloadCancelled:
+			count++
+		}
+	}
+	return count
+}
+
+// setPonDownloadLimit configures how many downloads run concurrently per PON port for
+// children of the given device type.  A non-positive limit removes the override.
+func (dMgr *DeviceManager) setPonDownloadLimit(deviceType string, limit int) error {
+	if deviceType == "" {
+		return newInvalidArgumentError("device-type-required")
+	}
+	dMgr.ponDownloadLimitsLock.Lock()
+	defer dMgr.ponDownloadLimitsLock.Unlock()
+	if limit <= 0 {
+		delete(dMgr.ponDownloadLimits, deviceType)
+		return nil
+	}
+	if dMgr.ponDownloadLimits == nil {
+		dMgr.ponDownloadLimits = make(map[string]int)
+	}
+	dMgr.ponDownloadLimits[deviceType] = limit
+	return nil
+}
+
+// ponDownloadLimit resolves the per-PON concurrency limit for a set of children on one
+// PON port: the smallest configured limit among their device types, or the default when
+// none of the types has one.  The smallest wins because the PON is shared - the most
+// sensitive ONU on it sets the pace.
+func (dMgr *DeviceManager) ponDownloadLimit(children []*voltha.Device) int {
+	dMgr.ponDownloadLimitsLock.RLock()
+	defer dMgr.ponDownloadLimitsLock.RUnlock()
+	limit := 0
+	for _, child := range children {
+		if configured, have := dMgr.ponDownloadLimits[child.Type]; have && (limit == 0 || configured < limit) {
+			limit = configured
+		}
+	}
+	if limit == 0 {
+		return defaultPonDownloadLimit
+	}
+	return limit
+}
+
+// downloadImageToChildren starts a batch download of an image to every child of the
+// parent device, tracked as a job.  The image's Id field is ignored; each child gets a
+// copy of the request addressed to itself.
+func (dMgr *DeviceManager) downloadImageToChildren(ctx context.Context, parentDeviceID string, img *voltha.ImageDownload) (*deviceJob, error) {
+	if _, ok := dMgr.deviceAgents.Load(parentDeviceID); !ok {
+		return nil, newNotFoundError("device", parentDeviceID)
+	}
+	// Children are resolved the same way the PON summaries resolve them - from the
+	// in-memory agents - so a child is targeted whether or not its peer entry made it
+	// onto the parent's port yet
+	var targets []*voltha.Device
+	var deviceIDs []string
+	dMgr.deviceAgents.Range(func(_, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		if agent.parentID != parentDeviceID {
+			return true
+		}
+		child, err := agent.getDevice(ctx)
+		if err != nil || child.AdminState == voltha.AdminState_DELETED {
+			return true
+		}
+		targets = append(targets, child)
+		deviceIDs = append(deviceIDs, child.Id)
+		return true
+	})
+	if len(targets) == 0 {
+		return nil, newFailedPreconditionError("device", parentDeviceID, "no-children-to-download-to")
+	}
+	batch := newImageDownloadBatch(targets)
+
+	// The batch must be registered under the job ID before the runner dispatches
+	// anything, or an early adapter report would find nobody to deliver to
+	var jobID string
+	ready := make(chan struct{})
+	job, err := dMgr.startDeviceJob(ctx, downloadBatchJobType, deviceIDs, func(jobCtx context.Context, progress jobProgress) (string, error) {
+		<-ready
+		defer dMgr.downloadBatches.Delete(jobID)
+		return dMgr.runImageDownloadBatch(jobCtx, batch, targets, img, progress)
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobID = job.ID
+	dMgr.downloadBatches.Store(job.ID, batch)
+	close(ready)
+	return job, nil
+}
+
+// runImageDownloadBatch drives the downloads: one worker pool per PON port, each
+// bounded by the resolved per-PON limit.  Cancellation drains the queue - children
+// still waiting for a slot are marked cancelled without being dispatched.
+func (dMgr *DeviceManager) runImageDownloadBatch(jobCtx context.Context, batch *imageDownloadBatch, targets []*voltha.Device, img *voltha.ImageDownload, progress jobProgress) (string, error) {
+	byPon := make(map[uint32][]*voltha.Device)
+	for _, child := range targets {
+		byPon[child.ParentPortNo] = append(byPon[child.ParentPortNo], child)
+	}
+	total := len(targets)
+	childDone := func() {
+		progress(batch.finished()*100/total, fmt.Sprintf("%d/%d-children-finished", batch.finished(), total))
+	}
+
+	var pons sync.WaitGroup
+	for _, children := range byPon {
+		pons.Add(1)
+		go func(children []*voltha.Device) {
+			defer pons.Done()
+			slots := make(chan struct{}, dMgr.ponDownloadLimit(children))
+			var downloads sync.WaitGroup
+			for _, child := range children {
+				select {
+				case <-jobCtx.Done():
+					batch.setState(child.Id, childDownloadCancelled, "cancelled-while-queued")
+					childDone()
+					continue
+				case slots <- struct{}{}:
+				}
+				downloads.Add(1)
+				go func(child *voltha.Device) {
+					defer downloads.Done()
+					defer func() { <-slots }()
+					dMgr.downloadImageToChild(jobCtx, batch, child, img)
+					childDone()
+				}(child)
+			}
+			downloads.Wait()
+		}(children)
+	}
+	pons.Wait()
+
+	encoded, err := json.Marshal(batch.snapshot())
+	return string(encoded), err
+}
+
+// downloadImageToChild dispatches one child's download and holds its PON slot until
+// the adapter reports a terminal state or the batch is cancelled
+func (dMgr *DeviceManager) downloadImageToChild(jobCtx context.Context, batch *imageDownloadBatch, child *voltha.Device, img *voltha.ImageDownload) {
+	childImg := proto.Clone(img).(*voltha.ImageDownload)
+	childImg.Id = child.Id
+	outcome := batch.watch(child.Id)
+	batch.setState(child.Id, childDownloadDownloading, "")
+
+	ch := make(chan interface{}, 1)
+	dMgr.downloadImage(jobCtx, childImg, ch)
+	if err, failed := (<-ch).(error); failed {
+		batch.setState(child.Id, childDownloadFailed, err.Error())
+		return
+	}
+
+	select {
+	case state := <-outcome:
+		switch state {
+		case voltha.ImageDownload_DOWNLOAD_SUCCEEDED:
+			batch.setState(child.Id, childDownloadSucceeded, "")
+		case voltha.ImageDownload_DOWNLOAD_CANCELLED:
+			batch.setState(child.Id, childDownloadCancelled, "")
+		default:
+			batch.setState(child.Id, childDownloadFailed, state.String())
+		}
+	case <-jobCtx.Done():
+		// The batch was cancelled with this download in flight; ask the adapter to
+		// abort it, detached from the already-cancelled job context
+		cancelCh := make(chan interface{}, 1)
+		dMgr.cancelImageDownload(context.Background(), childImg, cancelCh)
+		if err, failed := (<-cancelCh).(error); failed {
+			ctxWarnw(context.Background(), "failed-to-cancel-in-flight-download", log.Fields{"device-id": child.Id, "image": childImg.Name, "error": err})
+		}
+		batch.setState(child.Id, childDownloadCancelled, "cancelled-in-flight")
+	}
+}
+
+// notifyBatchDownloadOutcome hands a terminal download report to the batch tracking the
+// device, if any.  Called from updateImageDownload, where the adapter reports land.
+func (dMgr *DeviceManager) notifyBatchDownloadOutcome(deviceID string, state voltha.ImageDownload_ImageDownloadState) {
+	dMgr.downloadBatches.Range(func(_, value interface{}) bool {
+		value.(*imageDownloadBatch).deliver(deviceID, state)
+		return true
+	})
+}
+
+// getImageDownloadBatch returns the per-child statuses of a batch download job: the
+// live view while it runs, the persisted result once it has finished
+func (dMgr *DeviceManager) getImageDownloadBatch(ctx context.Context, jobID string) ([]*childDownloadStatus, error) {
+	if value, running := dMgr.downloadBatches.Load(jobID); running {
+		return value.(*imageDownloadBatch).snapshot(), nil
+	}
+	job, err := dMgr.getDeviceJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != downloadBatchJobType {
+		return nil, newFailedPreconditionError("device-job", jobID, "not-a-batch-download-job")
+	}
+	var statuses []*childDownloadStatus
+	if err := json.Unmarshal([]byte(job.Result), &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// DownloadImageToChildren is the NBI request starting a batch download of an image to
+// every child of a parent device, returning the job tracking it
+func (handler *APIHandler) DownloadImageToChildren(ctx context.Context, parentDeviceID string, img *voltha.ImageDownload) (*deviceJob, error) {
+	ctxDebugw(ctx, "DownloadImageToChildren", log.Fields{"parent-device-id": parentDeviceID, "image": img.Name})
+	return handler.deviceMgr.downloadImageToChildren(ctx, parentDeviceID, img)
+}
+
+// GetImageDownloadBatch is the NBI query returning the per-child statuses of a batch
+// download job
+func (handler *APIHandler) GetImageDownloadBatch(ctx context.Context, jobID string) ([]*childDownloadStatus, error) {
+	ctxDebugw(ctx, "GetImageDownloadBatch", log.Fields{"job-id": jobID})
+	return handler.deviceMgr.getImageDownloadBatch(ctx, jobID)
+}
+
+// SetPonDownloadLimit is the NBI request configuring the per-PON download concurrency
+// of a device type
+func (handler *APIHandler) SetPonDownloadLimit(ctx context.Context, deviceType string, limit int) error {
+	ctxDebugw(ctx, "SetPonDownloadLimit", log.Fields{"device-type": deviceType, "limit": limit})
+	return handler.deviceMgr.setPonDownloadLimit(deviceType, limit)
+}
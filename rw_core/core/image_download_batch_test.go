@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBatchDownloadParent provisions an enabled OLT and the requested number of
+// auto-enabled children per PON port, keyed by port number
+func newBatchDownloadParent(t *testing.T, dMgr *DeviceManager, mac string, childrenPerPon map[int64]int) *voltha.Device {
+	ctx := context.Background()
+	parent := newChildLookupParent(t, dMgr, mac)
+	var childIDs []string
+	onuID := int64(1)
+	for portNo, count := range childrenPerPon {
+		for i := 0; i < count; i++ {
+			child, err := dMgr.childDeviceDetected(ctx, parent.Id, portNo, "onu", portNo, "VEND", fmt.Sprintf("SERIAL-BD-%s-%d", mac[len(mac)-2:], onuID), onuID)
+			assert.Nil(t, err)
+			childIDs = append(childIDs, child.Id)
+			onuID++
+		}
+	}
+	waitUntil(t, 2*time.Second, "children were not auto-enabled", func() bool {
+		for _, id := range childIDs {
+			device, err := dMgr.GetDevice(ctx, id)
+			if err != nil || device.AdminState != voltha.AdminState_ENABLED {
+				return false
+			}
+		}
+		return true
+	})
+	return parent
+}
+
+// batchStateCounts tallies the per-child states of a batch, optionally restricted to
+// one PON port (port 0 means all)
+func batchStateCounts(t *testing.T, dMgr *DeviceManager, jobID string, port uint32) map[string]int {
+	statuses, err := dMgr.getImageDownloadBatch(context.Background(), jobID)
+	assert.Nil(t, err)
+	counts := make(map[string]int)
+	for _, status := range statuses {
+		if port == 0 || status.PonPort == port {
+			counts[status.State]++
+		}
+	}
+	return counts
+}
+
+// completeDownloading reports a successful download for every child currently in the
+// DOWNLOADING state and returns how many were completed
+func completeDownloading(t *testing.T, dMgr *DeviceManager, jobID string, name string) int {
+	ctx := context.Background()
+	statuses, err := dMgr.getImageDownloadBatch(ctx, jobID)
+	assert.Nil(t, err)
+	completed := 0
+	for _, status := range statuses {
+		if status.State == childDownloadDownloading {
+			assert.Nil(t, dMgr.updateImageDownload(ctx, status.DeviceID, &voltha.ImageDownload{
+				Id: status.DeviceID, Name: name, DownloadState: voltha.ImageDownload_DOWNLOAD_SUCCEEDED,
+			}))
+			completed++
+		}
+	}
+	return completed
+}
+
+func TestBatchDownloadThrottledPerPon(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	parent := newBatchDownloadParent(t, dMgr, "00:00:00:00:4a:4b", map[int64]int{1: 3, 2: 1})
+
+	_, err := dMgr.downloadImageToChildren(ctx, "no-such-device", &voltha.ImageDownload{Name: "sw", Url: "http://images.local/sw.img"})
+	assert.NotNil(t, err)
+
+	job, err := dMgr.downloadImageToChildren(ctx, parent.Id, &voltha.ImageDownload{Name: "sw", Url: "http://images.local/sw.img"})
+	assert.Nil(t, err)
+
+	// The default limit of 2 per PON holds the third child of port 1 in the queue
+	// while port 2's only child proceeds independently
+	waitUntil(t, 2*time.Second, "throttled dispatch never settled", func() bool {
+		port1 := batchStateCounts(t, dMgr, job.ID, 1)
+		port2 := batchStateCounts(t, dMgr, job.ID, 2)
+		return port1[childDownloadDownloading] == 2 && port1[childDownloadQueued] == 1 && port2[childDownloadDownloading] == 1
+	})
+	assert.Equal(t, 3, fas.callCount("downloadImage"))
+
+	// Completing the in-flight downloads frees the slots for the queued child
+	assert.Equal(t, 3, completeDownloading(t, dMgr, job.ID, "sw"))
+	waitUntil(t, 2*time.Second, "queued child was never dispatched", func() bool {
+		return batchStateCounts(t, dMgr, job.ID, 1)[childDownloadDownloading] == 1
+	})
+	assert.Equal(t, 1, completeDownloading(t, dMgr, job.ID, "sw"))
+
+	finished := jobInState(t, dMgr, job.ID, jobStateSucceeded)
+	assert.NotEmpty(t, finished.Result)
+	assert.Equal(t, 4, batchStateCounts(t, dMgr, job.ID, 0)[childDownloadSucceeded])
+}
+
+func TestBatchCancelDrainsQueue(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	parent := newBatchDownloadParent(t, dMgr, "00:00:00:00:4a:4c", map[int64]int{1: 3})
+	assert.Nil(t, dMgr.setPonDownloadLimit("onu", 1))
+
+	job, err := dMgr.downloadImageToChildren(ctx, parent.Id, &voltha.ImageDownload{Name: "sw", Url: "http://images.local/sw.img"})
+	assert.Nil(t, err)
+	waitUntil(t, 2*time.Second, "first child never started downloading", func() bool {
+		counts := batchStateCounts(t, dMgr, job.ID, 1)
+		return counts[childDownloadDownloading] == 1 && counts[childDownloadQueued] == 2
+	})
+	assert.Equal(t, 1, fas.callCount("downloadImage"))
+
+	// Cancelling drains the queue and aborts the in-flight download at the adapter
+	assert.Nil(t, dMgr.cancelDeviceJob(ctx, job.ID))
+	jobInState(t, dMgr, job.ID, jobStateCancelled)
+	assert.Equal(t, 3, batchStateCounts(t, dMgr, job.ID, 0)[childDownloadCancelled])
+	assert.Equal(t, 1, fas.callCount("downloadImage"))
+	assert.Equal(t, 1, fas.callCount("cancelImageDownload"))
+
+	statuses, err := dMgr.getImageDownloadBatch(ctx, job.ID)
+	assert.Nil(t, err)
+	inFlight, queued := 0, 0
+	for _, status := range statuses {
+		switch status.Error {
+		case "cancelled-in-flight":
+			inFlight++
+		case "cancelled-while-queued":
+			queued++
+		}
+	}
+	assert.Equal(t, 1, inFlight)
+	assert.Equal(t, 2, queued)
+}
+
+func TestPonDownloadLimitPerDeviceType(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	parent := newBatchDownloadParent(t, dMgr, "00:00:00:00:4a:4d", map[int64]int{1: 3})
+
+	assert.NotNil(t, dMgr.setPonDownloadLimit("", 3))
+	assert.Nil(t, dMgr.setPonDownloadLimit("onu", 3))
+
+	// With the raised limit all three children of the PON download at once
+	job, err := dMgr.downloadImageToChildren(ctx, parent.Id, &voltha.ImageDownload{Name: "sw", Url: "http://images.local/sw.img"})
+	assert.Nil(t, err)
+	waitUntil(t, 2*time.Second, "raised limit was not honoured", func() bool {
+		return batchStateCounts(t, dMgr, job.ID, 1)[childDownloadDownloading] == 3
+	})
+	assert.Equal(t, 3, fas.callCount("downloadImage"))
+	assert.Equal(t, 3, completeDownloading(t, dMgr, job.ID, "sw"))
+	jobInState(t, dMgr, job.ID, jobStateSucceeded)
+
+	// A non-positive limit removes the override, falling back to the default
+	assert.Nil(t, dMgr.setPonDownloadLimit("onu", 0))
+	assert.Equal(t, defaultPonDownloadLimit, dMgr.ponDownloadLimit([]*voltha.Device{{Type: "onu"}}))
+}
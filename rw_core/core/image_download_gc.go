@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// ImageDownload records accumulate under a device forever; an ONU through many firmware
+// cycles carries dozens of stale entries inflating its payload.  A background sweeper now
+// prunes terminal (succeeded, failed, cancelled or unsupported) records once they are
+// older than the configured retention or push the device past the configured count, and
+// an explicit purge drops all prunable records at once.  An in-flight download and the
+// most recent successful record are always kept - the latter tells the operator which
+// image the device runs.  The records carry no timestamp, so a record's age is counted
+// from when this core first saw it in a terminal state.
+
+// imageDownloadSweepInterval is how often devices are scanned for prunable records
+const imageDownloadSweepInterval = 10 * time.Minute
+
+// isTerminalDownloadState reports whether a download record can no longer progress
+func isTerminalDownloadState(state voltha.ImageDownload_ImageDownloadState) bool {
+	switch state {
+	case voltha.ImageDownload_DOWNLOAD_SUCCEEDED, voltha.ImageDownload_DOWNLOAD_FAILED,
+		voltha.ImageDownload_DOWNLOAD_CANCELLED, voltha.ImageDownload_DOWNLOAD_UNSUPPORTED:
+		return true
+	default:
+		return false
+	}
+}
+
+// pruneImageDownloads drops prunable download records from the device.  A record is
+// prunable when it is terminal and is not the most recent successful one; it is dropped
+// when purgeAll is set, when it has been terminal longer than the retention (0 disables
+// the age check) or, oldest first, while the prunable records outnumber maxRecords
+// (0 disables the count check).  Returns the number of records dropped.
+func (agent *DeviceAgent) pruneImageDownloads(ctx context.Context, retention time.Duration, maxRecords int, purgeAll bool) (int, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return 0, err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	cloned := agent.getDeviceWithoutLock()
+	if len(cloned.ImageDownloads) == 0 {
+		return 0, nil
+	}
+
+	// The last successful record in the list is the most recent one - records are
+	// appended in request order
+	lastSuccess := -1
+	for i, image := range cloned.ImageDownloads {
+		if image.DownloadState == voltha.ImageDownload_DOWNLOAD_SUCCEEDED {
+			lastSuccess = i
+		}
+	}
+
+	now := time.Now()
+	kept := make([]*voltha.ImageDownload, 0, len(cloned.ImageDownloads))
+	var prunable []int
+	for i, image := range cloned.ImageDownloads {
+		if !isTerminalDownloadState(image.DownloadState) || i == lastSuccess {
+			kept = append(kept, image)
+			continue
+		}
+		seenAt, seen := agent.imageDownloadTerminalAt[image.Name]
+		if !seen {
+			agent.imageDownloadTerminalAt[image.Name] = now
+			seenAt = now
+		}
+		if purgeAll || (retention > 0 && now.Sub(seenAt) > retention) {
+			delete(agent.imageDownloadTerminalAt, image.Name)
+			continue
+		}
+		prunable = append(prunable, len(kept))
+		kept = append(kept, image)
+	}
+	// Count-based pruning drops the oldest prunable survivors first
+	for len(prunable) > 0 && maxRecords > 0 && len(prunable) > maxRecords {
+		idx := prunable[0]
+		delete(agent.imageDownloadTerminalAt, kept[idx].Name)
+		kept = append(kept[:idx], kept[idx+1:]...)
+		prunable = prunable[1:]
+		for i := range prunable {
+			prunable[i]--
+		}
+	}
+
+	pruned := len(cloned.ImageDownloads) - len(kept)
+	if pruned == 0 {
+		return 0, nil
+	}
+	cloned.ImageDownloads = kept
+	if err := agent.updateDeviceInStoreWithoutLock(ctx, cloned, false, ""); err != nil {
+		return 0, err
+	}
+	ctxDebugw(ctx, "pruned-image-downloads", log.Fields{"device-id": agent.deviceID, "pruned": pruned, "remaining": len(kept)})
+	return pruned, nil
+}
+
+// purgeImageDownloads drops every prunable download record of the device at once
+func (dMgr *DeviceManager) purgeImageDownloads(ctx context.Context, deviceID string) (int, error) {
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return 0, newNotFoundError("device", deviceID)
+	}
+	return agent.pruneImageDownloads(ctx, 0, 0, true)
+}
+
+// sweepImageDownloads applies the configured retention to every device in memory
+func (dMgr *DeviceManager) sweepImageDownloads(ctx context.Context) {
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		if _, err := value.(*DeviceAgent).pruneImageDownloads(ctx, dMgr.imageDownloadRetention, dMgr.imageDownloadMaxRecords, false); err != nil {
+			ctxWarnw(ctx, "image-download-prune-failed", log.Fields{"device-id": key, "error": err})
+		}
+		return true
+	})
+}
+
+// runImageDownloadSweeper periodically prunes stale download records until the device
+// manager exits
+func (dMgr *DeviceManager) runImageDownloadSweeper(ctx context.Context) {
+	ticker := time.NewTicker(imageDownloadSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dMgr.sweepImageDownloads(ctx)
+		case <-dMgr.exitChannel:
+			return
+		}
+	}
+}
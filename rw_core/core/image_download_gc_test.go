@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedImageDownloads stores the given download records on the device and refreshes the
+// agent's cached copy
+func seedImageDownloads(t *testing.T, dMgr *DeviceManager, deviceID string, records []*voltha.ImageDownload) {
+	ctx := context.Background()
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	assert.NotNil(t, agent)
+	device, err := dMgr.GetDevice(ctx, deviceID)
+	assert.Nil(t, err)
+	device.ImageDownloads = records
+	assert.Nil(t, dMgr.clusterDataProxy.Update(ctx, "devices/"+deviceID, device))
+	_, err = agent.syncFromKVStore(ctx)
+	assert.Nil(t, err)
+}
+
+func downloadNames(downloads *voltha.ImageDownloads) []string {
+	var names []string
+	for _, image := range downloads.Items {
+		names = append(names, image.Name)
+	}
+	return names
+}
+
+func TestPurgeImageDownloads(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:0f:01")
+
+	seedImageDownloads(t, dMgr, device.Id, []*voltha.ImageDownload{
+		{Id: device.Id, Name: "v1-ok", DownloadState: voltha.ImageDownload_DOWNLOAD_SUCCEEDED},
+		{Id: device.Id, Name: "v2-failed", DownloadState: voltha.ImageDownload_DOWNLOAD_FAILED},
+		{Id: device.Id, Name: "v3-cancelled", DownloadState: voltha.ImageDownload_DOWNLOAD_CANCELLED},
+		{Id: device.Id, Name: "v4-ok", DownloadState: voltha.ImageDownload_DOWNLOAD_SUCCEEDED},
+		{Id: device.Id, Name: "v5-active", DownloadState: voltha.ImageDownload_DOWNLOAD_STARTED},
+	})
+
+	// The purge keeps the in-flight record and the most recent successful one
+	pruned, err := dMgr.purgeImageDownloads(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, pruned)
+	downloads, err := dMgr.listImageDownloads(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v4-ok", "v5-active"}, downloadNames(downloads))
+
+	// Purging an unknown device fails
+	_, err = dMgr.purgeImageDownloads(ctx, "no-such-device")
+	assert.NotNil(t, err)
+}
+
+func TestImageDownloadRetentionAndCount(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:0f:02")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	seedImageDownloads(t, dMgr, device.Id, []*voltha.ImageDownload{
+		{Id: device.Id, Name: "v1-failed", DownloadState: voltha.ImageDownload_DOWNLOAD_FAILED},
+		{Id: device.Id, Name: "v2-failed", DownloadState: voltha.ImageDownload_DOWNLOAD_FAILED},
+		{Id: device.Id, Name: "v3-failed", DownloadState: voltha.ImageDownload_DOWNLOAD_FAILED},
+	})
+
+	// The count cap drops the oldest prunable records first
+	pruned, err := agent.pruneImageDownloads(ctx, 0, 1, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pruned)
+	downloads, err := dMgr.listImageDownloads(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v3-failed"}, downloadNames(downloads))
+
+	// Age-based pruning counts from when the record was first seen terminal: the record
+	// above was just seen, so it survives an immediate sweep and falls to a later one
+	pruned, err = agent.pruneImageDownloads(ctx, time.Hour, 0, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pruned)
+	time.Sleep(5 * time.Millisecond)
+	pruned, err = agent.pruneImageDownloads(ctx, time.Millisecond, 0, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pruned)
+	downloads, err = dMgr.listImageDownloads(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(downloads.Items))
+}
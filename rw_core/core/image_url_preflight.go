@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A mistyped image URL used to be discovered ten minutes later, when the ONU download
+// timed out.  Image download requests are now validated before anything is dispatched:
+// the URL must parse and carry a supported scheme, and - depending on the image_preflight
+// mode - the image is probed for reachability first.  In "core" mode the core issues a
+// HEAD request itself, rejecting an unreachable image synchronously with the probe result
+// and recording the advertised size on the ImageDownload record for later verification;
+// in "adapter" mode the probe is delegated to the adapter, for images that live on
+// networks only the adapter can reach.  A CRC supplied by the operator rides along on the
+// record either way, for verification once the download completes.
+
+// imagePreflightTimeout bounds the reachability probe; a probe this slow would not make
+// the download any faster
+const imagePreflightTimeout = 5 * time.Second
+
+// Image preflight modes, selected by the image_preflight flag
+const (
+	imagePreflightOff     = "off"
+	imagePreflightCore    = "core"
+	imagePreflightAdapter = "adapter"
+)
+
+// supportedImageSchemes are the URL schemes adapters know how to fetch images over
+var supportedImageSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ftp":   true,
+	"tftp":  true,
+}
+
+// validateImageURL checks the URL of an image download request for the mistakes a parser
+// can catch: syntax, scheme and a missing host
+func validateImageURL(img *voltha.ImageDownload) error {
+	if img.Url == "" {
+		return newInvalidArgumentError("image-url-required")
+	}
+	parsed, err := url.Parse(img.Url)
+	if err != nil {
+		return newInvalidArgumentError(fmt.Sprintf("malformed-image-url:%s: %s", img.Url, err))
+	}
+	if !supportedImageSchemes[parsed.Scheme] {
+		return newInvalidArgumentError(fmt.Sprintf("unsupported-image-url-scheme:%s", parsed.Scheme))
+	}
+	if parsed.Host == "" {
+		return newInvalidArgumentError(fmt.Sprintf("image-url-missing-host:%s", img.Url))
+	}
+	return nil
+}
+
+// preflightImageFromCore probes the image with a HEAD request from the core.  Only HTTP
+// URLs can be probed this way; other schemes pass with a note.  A reachable image with an
+// advertised size fills in the record's FileSize when the operator left it unset, and
+// contradicts it with InvalidArgument when they did not.
+func (dMgr *DeviceManager) preflightImageFromCore(ctx context.Context, img *voltha.ImageDownload) error {
+	parsed, _ := url.Parse(img.Url)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		ctxDebugw(ctx, "image-preflight-skipped-unprobeable-scheme", log.Fields{"url": img.Url, "scheme": parsed.Scheme})
+		return nil
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, imagePreflightTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(probeCtx, http.MethodHead, img.Url, nil)
+	if err != nil {
+		return newInvalidArgumentError(fmt.Sprintf("malformed-image-url:%s: %s", img.Url, err))
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return newInvalidArgumentError(fmt.Sprintf("image-url-unreachable:%s: %s", img.Url, err))
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode >= 400 {
+		return newInvalidArgumentError(fmt.Sprintf("image-url-probe-failed:%s: %s", img.Url, response.Status))
+	}
+	if response.ContentLength > 0 {
+		advertised := uint32(response.ContentLength)
+		if img.FileSize != 0 && img.FileSize != advertised {
+			return newInvalidArgumentError(fmt.Sprintf("image-size-mismatch:%s: requested %d bytes, server advertises %d", img.Url, img.FileSize, advertised))
+		}
+		img.FileSize = advertised
+	}
+	ctxDebugw(ctx, "image-preflight-passed", log.Fields{"url": img.Url, "file-size": img.FileSize})
+	return nil
+}
+
+// preflightImageWithAdapter delegates the reachability probe to the adapter and waits for
+// its verdict, so an image only the adapter's network can reach is still probed
+func (agent *DeviceAgent) preflightImageWithAdapter(ctx context.Context, img *voltha.ImageDownload) error {
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, imagePreflightTimeout)
+	defer cancel()
+	ch, err := agent.adapterProxy.preflightImage(probeCtx, device, img)
+	if err != nil {
+		return err
+	}
+	select {
+	case rpcResponse, ok := <-ch:
+		if !ok {
+			return status.Errorf(codes.Aborted, "image-preflight-channel-closed-device-%s", agent.deviceID)
+		}
+		if rpcResponse.Err != nil {
+			return newInvalidArgumentError(fmt.Sprintf("image-preflight-rejected-by-adapter:%s: %s", img.Url, rpcResponse.Err))
+		}
+		return nil
+	case <-probeCtx.Done():
+		return newUnavailableError("image-preflight", agent.deviceID, probeCtx.Err())
+	}
+}
+
+// preflightImage validates an image download request before it is dispatched: the URL
+// syntax always, the reachability according to the configured mode
+func (dMgr *DeviceManager) preflightImage(ctx context.Context, agent *DeviceAgent, img *voltha.ImageDownload) error {
+	if err := validateImageURL(img); err != nil {
+		return err
+	}
+	if err := dMgr.validateImageChecksumPolicy(img); err != nil {
+		return err
+	}
+	switch dMgr.imagePreflight {
+	case imagePreflightCore:
+		return dMgr.preflightImageFromCore(ctx, img)
+	case imagePreflightAdapter:
+		return agent.preflightImageWithAdapter(ctx, img)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageURLValidation(t *testing.T) {
+	bad := []struct {
+		url  string
+		want string
+	}{
+		{"", "image-url-required"},
+		{"http://\x7f", "malformed-image-url"},
+		{"file:///images/sw.img", "unsupported-image-url-scheme"},
+		{"http://", "image-url-missing-host"},
+	}
+	for _, tc := range bad {
+		err := validateImageURL(&voltha.ImageDownload{Url: tc.url})
+		assert.NotNil(t, err, tc.url)
+		assert.Contains(t, err.Error(), tc.want)
+	}
+	assert.Nil(t, validateImageURL(&voltha.ImageDownload{Url: "http://images.local/sw.img"}))
+	assert.Nil(t, validateImageURL(&voltha.ImageDownload{Url: "tftp://10.0.0.1/sw.img"}))
+}
+
+func TestImagePreflightFromCore(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sw.img" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "4096")
+	}))
+	defer server.Close()
+
+	// A reachable image fills in the advertised size for later verification
+	img := &voltha.ImageDownload{Url: server.URL + "/sw.img"}
+	assert.Nil(t, dMgr.preflightImageFromCore(ctx, img))
+	assert.Equal(t, uint32(4096), img.FileSize)
+
+	// A size the server contradicts is rejected, as is a missing image
+	err := dMgr.preflightImageFromCore(ctx, &voltha.ImageDownload{Url: server.URL + "/sw.img", FileSize: 100})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "image-size-mismatch")
+	err = dMgr.preflightImageFromCore(ctx, &voltha.ImageDownload{Url: server.URL + "/no-such.img"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "image-url-probe-failed")
+
+	// Non-HTTP schemes cannot be probed from the core and pass through
+	assert.Nil(t, dMgr.preflightImageFromCore(ctx, &voltha.ImageDownload{Url: "tftp://10.0.0.1/sw.img"}))
+
+	server.Close()
+	err = dMgr.preflightImageFromCore(ctx, &voltha.ImageDownload{Url: server.URL + "/sw.img"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "image-url-unreachable")
+}
+
+func TestImagePreflightRejectsSynchronously(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.imagePreflight = imagePreflightCore
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:46")
+
+	ch := make(chan interface{}, 1)
+	dMgr.downloadImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw", Url: "file:///sw.img"}, ch)
+	err, failed := (<-ch).(error)
+	assert.True(t, failed)
+	assert.Contains(t, err.Error(), "unsupported-image-url-scheme")
+	assert.Equal(t, 0, fas.callCount("downloadImage"))
+}
+
+func TestImagePreflightDelegatedToAdapter(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.imagePreflight = imagePreflightAdapter
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:47")
+
+	fas.failWith("preflightImage", errors.New("no-route-to-image-server"))
+	ch := make(chan interface{}, 1)
+	dMgr.downloadImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw", Url: "http://images.local/sw.img"}, ch)
+	err, failed := (<-ch).(error)
+	assert.True(t, failed)
+	assert.Contains(t, err.Error(), "image-preflight-rejected-by-adapter")
+	assert.Equal(t, 0, fas.callCount("downloadImage"))
+
+	fas.failWith("preflightImage", nil)
+	ch = make(chan interface{}, 1)
+	dMgr.downloadImage(ctx, &voltha.ImageDownload{Id: device.Id, Name: "sw", Url: "http://images.local/sw.img"}, ch)
+	_, failed = (<-ch).(error)
+	assert.False(t, failed)
+	assert.Equal(t, 2, fas.callCount("preflightImage"))
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// interCoreForwardedKey marks a request that has already been forwarded once.  A request
+// carrying this argument must be served where it lands; it is never forwarded again.
+const interCoreForwardedKey = "forwarded_from"
+
+// InterCoreProxy forwards mutating device requests to the core instance owning the device
+// and serves requests forwarded here by peer instances.  Each instance listens on its own
+// topic, derived from the core topic and the instance ID.
+type InterCoreProxy struct {
+	kafkaICProxy          kafka.InterContainerProxy
+	deviceMgr             *DeviceManager
+	instanceID            string
+	coreTopic             string
+	defaultRequestTimeout time.Duration
+	deviceOwners          sync.Map // device ID -> owning core instance ID
+	logicalDeviceOwners   sync.Map // logical device ID -> owning core instance ID
+	logicalForRoot        sync.Map // root device ID -> logical device ID
+	forwardedCount        int32    // requests proxied to a peer instance
+	servedForwardCount    int32    // forwarded requests served on behalf of a peer
+}
+
+func newInterCoreProxy(kafkaICProxy kafka.InterContainerProxy, deviceMgr *DeviceManager, instanceID string,
+	coreTopic string, defaultRequestTimeout time.Duration) *InterCoreProxy {
+	return &InterCoreProxy{
+		kafkaICProxy:          kafkaICProxy,
+		deviceMgr:             deviceMgr,
+		instanceID:            instanceID,
+		coreTopic:             coreTopic,
+		defaultRequestTimeout: defaultRequestTimeout,
+	}
+}
+
+// instanceTopic returns the topic a core instance listens on for forwarded requests
+func (icp *InterCoreProxy) instanceTopic(instanceID string) kafka.Topic {
+	return kafka.Topic{Name: fmt.Sprintf("%s_%s", icp.coreTopic, instanceID)}
+}
+
+// start subscribes to this instance's topic so peer instances can forward requests here
+func (icp *InterCoreProxy) start(ctx context.Context) error {
+	topic := icp.instanceTopic(icp.instanceID)
+	if err := icp.kafkaICProxy.SubscribeWithRequestHandlerInterface(topic, icp); err != nil {
+		logger.Errorw("failed-to-subscribe-instance-topic", log.Fields{"topic": topic.Name, "error": err})
+		return err
+	}
+	logger.Infow("inter-core-proxy-started", log.Fields{"topic": topic.Name})
+	return nil
+}
+
+// setDeviceOwner records the core instance owning a device.  The ownership gate invokes it
+// whenever it learns a device is managed by a peer.  Recording a peer as the owner cancels
+// any in-flight or queued work this instance still has for the device.
+func (icp *InterCoreProxy) setDeviceOwner(deviceID string, instanceID string) {
+	icp.deviceOwners.Store(deviceID, instanceID)
+	// The logical device of a root device always follows its root on a handover
+	if logicalID, ok := icp.logicalForRoot.Load(deviceID); ok {
+		icp.logicalDeviceOwners.Store(logicalID.(string), instanceID)
+	}
+	if instanceID != icp.instanceID && icp.deviceMgr != nil {
+		icp.deviceMgr.cancelDeviceWork(deviceID)
+	}
+}
+
+// clearDeviceOwner removes the ownership record, making the device local again
+func (icp *InterCoreProxy) clearDeviceOwner(deviceID string) {
+	icp.deviceOwners.Delete(deviceID)
+	if logicalID, ok := icp.logicalForRoot.Load(deviceID); ok {
+		icp.logicalDeviceOwners.Delete(logicalID.(string))
+	}
+}
+
+// ownerOf returns the instance owning the device, or an empty string when the device is local
+func (icp *InterCoreProxy) ownerOf(deviceID string) string {
+	if owner, ok := icp.deviceOwners.Load(deviceID); ok && owner.(string) != icp.instanceID {
+		return owner.(string)
+	}
+	return ""
+}
+
+// forwardingCounts returns the number of requests forwarded to peers and the number of
+// forwarded requests served on behalf of peers
+func (icp *InterCoreProxy) forwardingCounts() (forwarded int32, served int32) {
+	return atomic.LoadInt32(&icp.forwardedCount), atomic.LoadInt32(&icp.servedForwardCount)
+}
+
+// forwardRequest proxies a mutating request to the owning instance and relays its response.
+// The caller's deadline is preserved; when none is set the default request timeout applies.
+func (icp *InterCoreProxy) forwardRequest(ctx context.Context, rpc string, owner string, deviceID string) error {
+	logger.Debugw("forwarding-request", log.Fields{"rpc": rpc, "device-id": deviceID, "owner": owner})
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, icp.defaultRequestTimeout)
+		defer cancel()
+	}
+	atomic.AddInt32(&icp.forwardedCount, 1)
+	toTopic := icp.instanceTopic(owner)
+	replyToTopic := icp.instanceTopic(icp.instanceID)
+	args := []*kafka.KVArg{
+		{Key: "device_id", Value: &voltha.ID{Id: deviceID}},
+		{Key: interCoreForwardedKey, Value: &ic.StrType{Val: icp.instanceID}},
+	}
+	respChnl := icp.kafkaICProxy.InvokeAsyncRPC(ctx, rpc, &toTopic, &replyToTopic, true, deviceID, args...)
+	for {
+		select {
+		case rpcResponse, ok := <-respChnl:
+			if !ok {
+				return newUnavailableError(rpc, deviceID, nil)
+			}
+			switch rpcResponse.MType {
+			case kafka.RpcSent:
+				// the request reached kafka; keep waiting for the owner's reply
+				continue
+			case kafka.RpcReply:
+				return rpcResponse.Err
+			default:
+				return newUnavailableError(rpc, deviceID, rpcResponse.Err)
+			}
+		case <-ctx.Done():
+			return newUnavailableError(rpc, deviceID, ctx.Err())
+		}
+	}
+}
+
+// parseForwardedArgs extracts the device ID and the forwarding instance from a forwarded request
+func (icp *InterCoreProxy) parseForwardedArgs(args []*ic.Argument) (string, string, error) {
+	deviceID := &voltha.ID{}
+	forwardedFrom := &ic.StrType{}
+	for _, arg := range args {
+		switch arg.Key {
+		case "device_id":
+			if err := ptypes.UnmarshalAny(arg.Value, deviceID); err != nil {
+				logger.Warnw("cannot-unmarshal-ID", log.Fields{"error": err})
+				return "", "", err
+			}
+		case interCoreForwardedKey:
+			if err := ptypes.UnmarshalAny(arg.Value, forwardedFrom); err != nil {
+				logger.Warnw("cannot-unmarshal-forwarded-from", log.Fields{"error": err})
+				return "", "", err
+			}
+		}
+	}
+	if deviceID.Id == "" {
+		return "", "", newInvalidArgumentError("device-id-empty")
+	}
+	return deviceID.Id, forwardedFrom.Val, nil
+}
+
+// serveForwardedRequest runs a forwarded operation locally.  A request that lands on an
+// instance which does not own the device is rejected rather than forwarded again.
+func (icp *InterCoreProxy) serveForwardedRequest(args []*ic.Argument, rpc string,
+	op func(context.Context, *DeviceAgent) error) (*empty.Empty, error) {
+	deviceID, forwardedFrom, err := icp.parseForwardedArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugw("serving-forwarded-request", log.Fields{"rpc": rpc, "device-id": deviceID, "forwarded-from": forwardedFrom})
+	atomic.AddInt32(&icp.servedForwardCount, 1)
+	if owner := icp.ownerOf(deviceID); owner != "" {
+		// loop prevention: never forward a request that has already been forwarded once
+		logger.Warnw("forwarded-request-for-peer-owned-device", log.Fields{"rpc": rpc, "device-id": deviceID, "owner": owner})
+		return nil, newFailedPreconditionError("device", deviceID, "not-owned-by-this-instance")
+	}
+	ctx := context.TODO()
+	agent := icp.deviceMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return nil, newNotFoundError("device", deviceID)
+	}
+	if err := op(ctx, agent); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// EnableDevice serves an enableDevice request forwarded by a peer instance
+func (icp *InterCoreProxy) EnableDevice(args []*ic.Argument) (*empty.Empty, error) {
+	return icp.serveForwardedRequest(args, "enableDevice", func(ctx context.Context, agent *DeviceAgent) error {
+		return agent.enableDevice(ctx)
+	})
+}
+
+// DisableDevice serves a disableDevice request forwarded by a peer instance
+func (icp *InterCoreProxy) DisableDevice(args []*ic.Argument) (*empty.Empty, error) {
+	return icp.serveForwardedRequest(args, "disableDevice", func(ctx context.Context, agent *DeviceAgent) error {
+		return agent.disableDevice(ctx)
+	})
+}
+
+// RebootDevice serves a rebootDevice request forwarded by a peer instance
+func (icp *InterCoreProxy) RebootDevice(args []*ic.Argument) (*empty.Empty, error) {
+	return icp.serveForwardedRequest(args, "rebootDevice", func(ctx context.Context, agent *DeviceAgent) error {
+		return agent.rebootDevice(ctx)
+	})
+}
+
+// DeleteDevice serves a deleteDevice request forwarded by a peer instance
+func (icp *InterCoreProxy) DeleteDevice(args []*ic.Argument) (*empty.Empty, error) {
+	return icp.serveForwardedRequest(args, "deleteDevice", func(ctx context.Context, agent *DeviceAgent) error {
+		return agent.deleteDevice(ctx)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestInterCoreProxy() *InterCoreProxy {
+	kmp := &mock_kafka.MockKafkaICProxy{
+		InvokeRpcSpy: mock_kafka.InvokeRpcSpy{Calls: make(map[int]mock_kafka.InvokeRpcArgs)},
+	}
+	return newInterCoreProxy(kmp, nil, "core-1", "rwcore", 50*time.Millisecond)
+}
+
+func TestInterCoreProxyOwnership(t *testing.T) {
+	icp := newTestInterCoreProxy()
+	assert.Equal(t, "", icp.ownerOf("1234"))
+
+	icp.setDeviceOwner("1234", "core-2")
+	assert.Equal(t, "core-2", icp.ownerOf("1234"))
+
+	// a device owned by this instance is local
+	icp.setDeviceOwner("5678", "core-1")
+	assert.Equal(t, "", icp.ownerOf("5678"))
+
+	icp.clearDeviceOwner("1234")
+	assert.Equal(t, "", icp.ownerOf("1234"))
+}
+
+func TestInterCoreProxyForwardToUnreachableOwner(t *testing.T) {
+	icp := newTestInterCoreProxy()
+	icp.setDeviceOwner("1234", "core-2")
+
+	// the mock never responds, simulating an unreachable owner; the caller's deadline applies
+	err := icp.forwardRequest(context.Background(), "enableDevice", "core-2", "1234")
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	forwarded, served := icp.forwardingCounts()
+	assert.Equal(t, int32(1), forwarded)
+	assert.Equal(t, int32(0), served)
+}
+
+func TestInterCoreProxyLoopPrevention(t *testing.T) {
+	icp := newTestInterCoreProxy()
+	// this instance does not own the device either; a forwarded request must not hop again
+	icp.setDeviceOwner("1234", "core-3")
+
+	deviceID, err := ptypes.MarshalAny(&voltha.ID{Id: "1234"})
+	assert.Nil(t, err)
+	forwardedFrom, err := ptypes.MarshalAny(&ic.StrType{Val: "core-2"})
+	assert.Nil(t, err)
+	args := []*ic.Argument{
+		{Key: "device_id", Value: deviceID},
+		{Key: interCoreForwardedKey, Value: forwardedFrom},
+	}
+
+	_, err = icp.EnableDevice(args)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, served := icp.forwardingCounts()
+	assert.Equal(t, int32(1), served)
+}
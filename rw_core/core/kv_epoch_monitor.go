@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// When etcd is restored from a backup the KV content can be older than what the running core
+// holds in memory, and writes based on in-memory state silently fork history.  A cluster data
+// epoch key is written once when the data set is first created; a restore from backup brings
+// back the epoch value of that backup, which no longer matches the epoch this core read at
+// startup.  The epoch is checked periodically and after KV write failures; on a change the
+// in-memory device agents are discarded, devices are reloaded from the restored KV, adapters
+// are asked to reconcile, and a resync event is raised for the operators.
+const (
+	clusterEpochPath   = "cluster_epoch"
+	epochCheckInterval = time.Minute
+)
+
+// loadOrCreateClusterEpoch reads the cluster data epoch, creating it when the data set has
+// none yet
+func (dMgr *DeviceManager) loadOrCreateClusterEpoch(ctx context.Context) (string, error) {
+	entry := &ic.StrType{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, clusterEpochPath, entry)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		epoch := fmt.Sprintf("%s_%d", dMgr.coreInstanceID, time.Now().UnixNano())
+		if err := dMgr.clusterDataProxy.Update(ctx, clusterEpochPath, &ic.StrType{Val: epoch}); err != nil {
+			return "", err
+		}
+		return epoch, nil
+	}
+	return entry.Val, nil
+}
+
+// monitorClusterEpoch periodically verifies the cluster data epoch until the device manager
+// is stopped
+func (dMgr *DeviceManager) monitorClusterEpoch(ctx context.Context) {
+	epoch, err := dMgr.loadOrCreateClusterEpoch(ctx)
+	if err != nil {
+		logger.Warnw("failed-to-read-cluster-epoch", log.Fields{"error": err})
+	} else {
+		dMgr.epochLock.Lock()
+		dMgr.clusterEpoch = epoch
+		dMgr.epochLock.Unlock()
+	}
+	ticker := time.NewTicker(epochCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dMgr.checkClusterEpoch(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("cluster-epoch-monitor-stopped")
+			return
+		}
+	}
+}
+
+// checkClusterEpoch re-reads the epoch and triggers a resync when it no longer matches the
+// one read at startup.  It is also invoked after KV write failures, which may be the first
+// sign of a restored store.
+func (dMgr *DeviceManager) checkClusterEpoch(ctx context.Context) {
+	entry := &ic.StrType{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, clusterEpochPath, entry)
+	if err != nil {
+		logger.Warnw("failed-to-check-cluster-epoch", log.Fields{"error": err})
+		return
+	}
+	current := ""
+	if found {
+		current = entry.Val
+	}
+	dMgr.epochLock.Lock()
+	known := dMgr.clusterEpoch
+	if known == "" || known == current {
+		dMgr.clusterEpoch = current
+		dMgr.epochLock.Unlock()
+		return
+	}
+	dMgr.clusterEpoch = current
+	dMgr.epochLock.Unlock()
+	dMgr.resyncFromRestoredKV(ctx, known, current)
+}
+
+// resyncFromRestoredKV discards every in-memory device agent, reloads the devices from the
+// restored KV content and asks the adapters to reconcile them
+func (dMgr *DeviceManager) resyncFromRestoredKV(ctx context.Context, previousEpoch, newEpoch string) {
+	logger.Warnw("cluster-epoch-changed-resyncing-from-kv", log.Fields{"previous-epoch": previousEpoch, "new-epoch": newEpoch})
+
+	// Drop the in-memory agents; their state may be ahead of the restored KV
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		dMgr.deviceAgents.Delete(key)
+		return true
+	})
+
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		logger.Errorw("failed-to-list-devices-after-kv-restore", log.Fields{"error": err})
+		return
+	}
+	for _, device := range devices {
+		if err := dMgr.load(ctx, device.Id); err != nil {
+			logger.Warnw("failed-to-reload-device-after-kv-restore", log.Fields{"device-id": device.Id, "error": err})
+			continue
+		}
+		response := dMgr.sendReconcileDeviceRequest(ctx, device)
+		go func(deviceID string, response utils.Response) {
+			if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+				logger.Warnw("adapter-resync-failed", log.Fields{"device-id": deviceID, "errors": res})
+			}
+		}(device.Id, response)
+	}
+
+	go dMgr.eventMgr.publishDeviceEvent(&voltha.Device{Id: dMgr.coreInstanceID}, coreResyncedEvent, map[string]string{
+		"previous-epoch": previousEpoch,
+		"new-epoch":      newEpoch,
+		"devices":        strconv.Itoa(len(devices)),
+	})
+}
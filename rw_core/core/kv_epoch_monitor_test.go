@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterEpochCreatedOnFirstLoad(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// A data set without an epoch gets one stamped with this core's identity
+	epoch, err := dMgr.loadOrCreateClusterEpoch(ctx)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(epoch, "core-1_"))
+
+	// Later reads return the stored value instead of minting a new one
+	again, err := dMgr.loadOrCreateClusterEpoch(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, epoch, again)
+}
+
+func TestCheckClusterEpochLeavesMatchingEpochAlone(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:55")
+	reconciles := fas.callCount("reconcileDevice")
+
+	// The first check adopts the stored epoch
+	epoch, err := dMgr.loadOrCreateClusterEpoch(ctx)
+	assert.Nil(t, err)
+	dMgr.checkClusterEpoch(ctx)
+	dMgr.epochLock.Lock()
+	assert.Equal(t, epoch, dMgr.clusterEpoch)
+	dMgr.epochLock.Unlock()
+
+	// A repeat check with an unchanged epoch keeps the agents and asks for no reconcile
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	dMgr.checkClusterEpoch(ctx)
+	assert.True(t, agent == dMgr.getDeviceAgent(ctx, device.Id))
+	assert.Equal(t, reconciles, fas.callCount("reconcileDevice"))
+}
+
+func TestClusterEpochChangeTriggersResync(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:56")
+	epoch, err := dMgr.loadOrCreateClusterEpoch(ctx)
+	assert.Nil(t, err)
+	dMgr.epochLock.Lock()
+	dMgr.clusterEpoch = epoch
+	dMgr.epochLock.Unlock()
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	reconciles := fas.callCount("reconcileDevice")
+
+	// A restore from backup brings back the epoch value of that backup
+	assert.Nil(t, dMgr.clusterDataProxy.Update(ctx, clusterEpochPath, &ic.StrType{Val: "backup_1"}))
+	dMgr.checkClusterEpoch(ctx)
+
+	// The known epoch moves to the restored one, the in-memory agents are rebuilt from the
+	// restored KV content and the adapter is asked to reconcile the device
+	dMgr.epochLock.Lock()
+	assert.Equal(t, "backup_1", dMgr.clusterEpoch)
+	dMgr.epochLock.Unlock()
+	assert.True(t, agent != dMgr.getDeviceAgent(ctx, device.Id))
+	assert.Equal(t, reconciles+1, fas.callCount("reconcileDevice"))
+	reloaded, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, reloaded.Id)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// Debugging a single ONU across the core logs requires every line to carry the same
+// identifying fields, and half the call sites forget the deviceId.  A log context is created
+// once at the northbound entry point and travels with the request context; the ctx logging
+// helpers below merge its fields into every line so individual call sites no longer need to
+// repeat them.  New code in this package should log through the helpers - the ratchet test in
+// log_context_test.go keeps the number of direct logger calls from growing.
+
+// logContextKey keys the operation log fields in a context.Context
+type logContextKey struct{}
+
+// withLogContext returns a context carrying the identifying fields of one operation.  A
+// correlation ID is generated so all the lines of one request can be tied together.
+func withLogContext(ctx context.Context, operation string, deviceID string) context.Context {
+	return context.WithValue(ctx, logContextKey{}, log.Fields{
+		"op":             operation,
+		"device-id":      deviceID,
+		"correlation-id": uuid.New().String(),
+	})
+}
+
+// logContextFields returns the operation fields carried by the context, if any
+func logContextFields(ctx context.Context) log.Fields {
+	if ctx == nil {
+		return nil
+	}
+	if fields, ok := ctx.Value(logContextKey{}).(log.Fields); ok {
+		return fields
+	}
+	return nil
+}
+
+// mergeLogFields lays the per-call fields over the operation fields of the context
+func mergeLogFields(ctx context.Context, fields log.Fields) log.Fields {
+	ctxFields := logContextFields(ctx)
+	if ctxFields == nil {
+		return fields
+	}
+	merged := make(log.Fields, len(ctxFields)+len(fields))
+	for k, v := range ctxFields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+func ctxDebugw(ctx context.Context, msg string, fields log.Fields) {
+	logger.Debugw(msg, mergeLogFields(ctx, fields))
+}
+
+func ctxInfow(ctx context.Context, msg string, fields log.Fields) {
+	logger.Infow(msg, mergeLogFields(ctx, fields))
+}
+
+func ctxWarnw(ctx context.Context, msg string, fields log.Fields) {
+	logger.Warnw(msg, mergeLogFields(ctx, fields))
+}
+
+func ctxErrorw(ctx context.Context, msg string, fields log.Fields) {
+	logger.Errorw(msg, mergeLogFields(ctx, fields))
+}
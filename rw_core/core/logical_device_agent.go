@@ -52,12 +52,16 @@ type LogicalDeviceAgent struct {
 	lockDeviceRoutes   sync.RWMutex
 	logicalPortsNo     map[uint32]bool //value is true for NNI port
 	lockLogicalPortsNo sync.RWMutex
+	uniIndex           uniPortIndex
 	flowDecomposer     *fd.FlowDecomposer
 	defaultTimeout     time.Duration
 	logicalDevice      *voltha.LogicalDevice
 	requestQueue       *coreutils.RequestQueue
 	startOnce          sync.Once
 	stopOnce           sync.Once
+	// deterministicUNINumbering mirrors the manager's setting; UNI OF port numbers are
+	// derived from the PON topology only when it is enabled
+	deterministicUNINumbering bool
 }
 
 func newLogicalDeviceAgent(id string, sn string, deviceID string, ldeviceMgr *LogicalDeviceManager,
@@ -73,6 +77,7 @@ func newLogicalDeviceAgent(id string, sn string, deviceID string, ldeviceMgr *Lo
 	agent.logicalPortsNo = make(map[uint32]bool)
 	agent.defaultTimeout = timeout
 	agent.requestQueue = coreutils.NewRequestQueue()
+	agent.deterministicUNINumbering = ldeviceMgr.deterministicUNINumbering
 	return &agent
 }
 
@@ -394,31 +399,7 @@ func (agent *LogicalDeviceAgent) setupNNILogicalPorts(ctx context.Context, devic
 // updatePortState updates the port state of the device
 func (agent *LogicalDeviceAgent) updatePortState(ctx context.Context, deviceID string, portNo uint32, operStatus voltha.OperStatus_Types) error {
 	logger.Infow("updatePortState-start", log.Fields{"logicalDeviceId": agent.logicalDeviceID, "portNo": portNo, "state": operStatus})
-	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
-		return err
-	}
-	defer agent.requestQueue.RequestComplete()
-	// Get the latest logical device info
-	original := agent.getLogicalDeviceWithoutLock()
-	updatedPorts := clonePorts(original.Ports)
-	for _, port := range updatedPorts {
-		if port.DeviceId == deviceID && port.DevicePortNo == portNo {
-			if operStatus == voltha.OperStatus_ACTIVE {
-				port.OfpPort.Config = port.OfpPort.Config & ^uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
-				port.OfpPort.State = uint32(ofp.OfpPortState_OFPPS_LIVE)
-			} else {
-				port.OfpPort.Config = port.OfpPort.Config | uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
-				port.OfpPort.State = uint32(ofp.OfpPortState_OFPPS_LINK_DOWN)
-			}
-			// Update the logical device
-			if err := agent.updateLogicalDevicePortsWithoutLock(ctx, original, updatedPorts); err != nil {
-				logger.Errorw("error-updating-logical-device", log.Fields{"error": err})
-				return err
-			}
-			return nil
-		}
-	}
-	return status.Errorf(codes.NotFound, "port-%d-not-exist", portNo)
+	return agent.updatePortStates(ctx, deviceID, []portStateChange{{portNo: portNo, operStatus: operStatus}})
 }
 
 // updatePortsState updates the ports state related to the device
@@ -1745,7 +1726,7 @@ func (agent *LogicalDeviceAgent) addNNILogicalPort(ctx context.Context, device *
 	lp := (proto.Clone(portCap.Port)).(*voltha.LogicalPort)
 	lp.DeviceId = device.Id
 	lp.Id = fmt.Sprintf("nni-%d", port.PortNo)
-	lp.OfpPort.PortNo = port.PortNo
+	lp.OfpPort.PortNo = agent.resolveOFPortNo(ctx, device, port, true)
 	lp.OfpPort.Name = lp.Id
 	lp.DevicePortNo = port.PortNo
 
@@ -1825,7 +1806,7 @@ func (agent *LogicalDeviceAgent) addUNILogicalPort(ctx context.Context, childDev
 	logger.Debugw("adding-uni", log.Fields{"deviceId": childDevice.Id})
 	portCap.Port.RootPort = false
 	portCap.Port.Id = port.Label
-	portCap.Port.OfpPort.PortNo = port.PortNo
+	portCap.Port.OfpPort.PortNo = agent.resolveOFPortNo(ctx, childDevice, port, false)
 	portCap.Port.DeviceId = childDevice.Id
 	portCap.Port.DevicePortNo = port.PortNo
 	clonedPorts := clonePorts(ldevice.Ports)
@@ -1848,6 +1829,30 @@ func (agent *LogicalDeviceAgent) addUNILogicalPort(ctx context.Context, childDev
 	return true, nil
 }
 
+// validPacketOutPort reports whether outPort refers to a port of this logical device.
+// Reserved OpenFlow port numbers (controller, local, etc.) do not correspond to logical
+// ports and always pass.
+func (agent *LogicalDeviceAgent) validPacketOutPort(ctx context.Context, outPort uint32) bool {
+	if outPort >= uint32(ofp.OfpPortNo_OFPP_MAX) {
+		return true
+	}
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		// Cannot validate; let the adapter decide
+		return true
+	}
+	ldevice := agent.logicalDevice
+	agent.requestQueue.RequestComplete()
+	if ldevice == nil {
+		return true
+	}
+	for _, lPort := range ldevice.Ports {
+		if lPort.OfpPort != nil && lPort.OfpPort.PortNo == outPort {
+			return true
+		}
+	}
+	return false
+}
+
 func (agent *LogicalDeviceAgent) packetOut(ctx context.Context, packet *ofp.OfpPacketOut) {
 	logger.Debugw("packet-out", log.Fields{
 		"packet": hex.EncodeToString(packet.Data),
@@ -1868,6 +1873,12 @@ func (agent *LogicalDeviceAgent) packetIn(port uint32, transactionID string, pac
 		"transactionId": transactionID,
 	})
 	packetIn := fu.MkPacketIn(port, packet)
+	// Carry the ingress port as tunnel metadata as well so controller applications get the
+	// UNI/NNI association without having to map the raw port number themselves
+	packetIn.Match.OxmFields = append(packetIn.Match.OxmFields, &ofp.OfpOxmField{
+		OxmClass: ofp.OfpOxmClass_OFPXMC_OPENFLOW_BASIC,
+		Field:    &ofp.OfpOxmField_OfbField{OfbField: fu.TunnelId(uint64(port))},
+	})
 	agent.ldeviceMgr.grpcNbiHdlr.sendPacketIn(agent.logicalDeviceID, transactionID, packetIn)
 	logger.Debugw("sending-packet-in", log.Fields{"packet": hex.EncodeToString(packetIn.Data)})
 }
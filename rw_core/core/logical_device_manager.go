@@ -45,6 +45,7 @@ type LogicalDeviceManager struct {
 	defaultTimeout                 time.Duration
 	logicalDevicesLoadingLock      sync.RWMutex
 	logicalDeviceLoadingInProgress map[string][]chan int
+	deterministicUNINumbering      bool
 }
 
 func newLogicalDeviceManager(core *Core, deviceMgr *DeviceManager, kafkaICProxy kafka.InterContainerProxy, cdProxy *model.Proxy, timeout time.Duration) *LogicalDeviceManager {
@@ -57,6 +58,7 @@ func newLogicalDeviceManager(core *Core, deviceMgr *DeviceManager, kafkaICProxy
 	logicalDeviceMgr.defaultTimeout = timeout
 	logicalDeviceMgr.logicalDevicesLoadingLock = sync.RWMutex{}
 	logicalDeviceMgr.logicalDeviceLoadingInProgress = make(map[string][]chan int)
+	logicalDeviceMgr.deterministicUNINumbering = core.config.DeterministicUNINumbering
 	return &logicalDeviceMgr
 }
 
@@ -129,6 +131,7 @@ func (ldMgr *LogicalDeviceManager) deleteLogicalDeviceAgent(logicalDeviceID stri
 func (ldMgr *LogicalDeviceManager) getLogicalDevice(ctx context.Context, id string) (*voltha.LogicalDevice, error) {
 	logger.Debugw("getlogicalDevice", log.Fields{"logicaldeviceid": id})
 	if agent := ldMgr.getLogicalDeviceAgent(ctx, id); agent != nil {
+		ldMgr.resyncBeforeReplay(ctx, agent)
 		return agent.GetLogicalDevice(ctx)
 	}
 	return nil, status.Errorf(codes.NotFound, "%s", id)
@@ -357,6 +360,7 @@ func (ldMgr *LogicalDeviceManager) ListLogicalDeviceFlowGroups(ctx context.Conte
 func (ldMgr *LogicalDeviceManager) ListLogicalDevicePorts(ctx context.Context, id string) (*voltha.LogicalPorts, error) {
 	logger.Debugw("ListLogicalDevicePorts", log.Fields{"logicaldeviceid": id})
 	if agent := ldMgr.getLogicalDeviceAgent(ctx, id); agent != nil {
+		ldMgr.resyncBeforeReplay(ctx, agent)
 		return agent.ListLogicalDevicePorts(ctx)
 	}
 	return nil, status.Errorf(codes.NotFound, "%s", id)
@@ -536,6 +540,10 @@ func (ldMgr *LogicalDeviceManager) updatePortsState(ctx context.Context, device
 func (ldMgr *LogicalDeviceManager) updateFlowTable(ctx context.Context, id string, flow *openflow_13.OfpFlowMod, ch chan interface{}) {
 	logger.Debugw("updateFlowTable", log.Fields{"logicalDeviceId": id})
 	var res interface{}
+	if err := ldMgr.refuseIfNotOwned(ctx, id); err != nil {
+		sendAPIResponse(ctx, ch, err)
+		return
+	}
 	if agent := ldMgr.getLogicalDeviceAgent(ctx, id); agent != nil {
 		res = agent.updateFlowTable(ctx, flow)
 		logger.Debugw("updateFlowTable-result", log.Fields{"result": res})
@@ -548,6 +556,10 @@ func (ldMgr *LogicalDeviceManager) updateFlowTable(ctx context.Context, id strin
 func (ldMgr *LogicalDeviceManager) updateMeterTable(ctx context.Context, id string, meter *openflow_13.OfpMeterMod, ch chan interface{}) {
 	logger.Debugw("updateMeterTable", log.Fields{"logicalDeviceId": id})
 	var res interface{}
+	if err := ldMgr.refuseIfNotOwned(ctx, id); err != nil {
+		sendAPIResponse(ctx, ch, err)
+		return
+	}
 	if agent := ldMgr.getLogicalDeviceAgent(ctx, id); agent != nil {
 		res = agent.updateMeterTable(ctx, meter)
 		logger.Debugw("updateMeterTable-result", log.Fields{"result": res})
@@ -568,6 +580,10 @@ func (ldMgr *LogicalDeviceManager) ListLogicalDeviceMeters(ctx context.Context,
 func (ldMgr *LogicalDeviceManager) updateGroupTable(ctx context.Context, id string, groupMod *openflow_13.OfpGroupMod, ch chan interface{}) {
 	logger.Debugw("updateGroupTable", log.Fields{"logicalDeviceId": id})
 	var res interface{}
+	if err := ldMgr.refuseIfNotOwned(ctx, id); err != nil {
+		sendAPIResponse(ctx, ch, err)
+		return
+	}
 	if agent := ldMgr.getLogicalDeviceAgent(ctx, id); agent != nil {
 		res = agent.updateGroupTable(ctx, groupMod)
 		logger.Debugw("updateGroupTable-result", log.Fields{"result": res})
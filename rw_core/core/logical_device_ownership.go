@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// A logical device had no ownership concept of its own: both cores of a pair would
+// process controller requests for it, duplicating packet-outs and flow pushes toward the
+// adapters.  The registrations below tie each logical device to its root device in the
+// inter-core ownership map, so the logical device is always owned by the instance owning
+// the root, follows the root on a handover, and the logical device manager can refuse
+// controller messages that belong to the peer.
+
+// registerLogicalDevice ties a logical device to its root device so ownership changes of
+// the root carry over.  The logical device starts out owned by whoever owns the root.
+func (icp *InterCoreProxy) registerLogicalDevice(logicalDeviceID string, rootDeviceID string) {
+	icp.logicalForRoot.Store(rootDeviceID, logicalDeviceID)
+	if owner, ok := icp.deviceOwners.Load(rootDeviceID); ok {
+		icp.logicalDeviceOwners.Store(logicalDeviceID, owner.(string))
+	}
+}
+
+// unregisterLogicalDevice drops the ownership records of a deleted logical device
+func (icp *InterCoreProxy) unregisterLogicalDevice(logicalDeviceID string, rootDeviceID string) {
+	icp.logicalForRoot.Delete(rootDeviceID)
+	icp.logicalDeviceOwners.Delete(logicalDeviceID)
+}
+
+// logicalDeviceOwnerOf returns the peer instance owning the logical device, or an empty
+// string when this instance may process requests for it
+func (icp *InterCoreProxy) logicalDeviceOwnerOf(logicalDeviceID string) string {
+	if owner, ok := icp.logicalDeviceOwners.Load(logicalDeviceID); ok && owner.(string) != icp.instanceID {
+		return owner.(string)
+	}
+	return ""
+}
+
+// refuseIfNotOwned rejects a controller message for a logical device owned by a peer
+// instance; that instance will serve the controller itself
+func (ldMgr *LogicalDeviceManager) refuseIfNotOwned(ctx context.Context, logicalDeviceID string) error {
+	if ldMgr.deviceMgr == nil || ldMgr.deviceMgr.interCoreProxy == nil {
+		return nil
+	}
+	if owner := ldMgr.deviceMgr.interCoreProxy.logicalDeviceOwnerOf(logicalDeviceID); owner != "" {
+		ctxDebugw(ctx, "logical-device-owned-by-peer", log.Fields{"logical-device-id": logicalDeviceID, "owner": owner})
+		return newFailedPreconditionError("logical_device", logicalDeviceID, "owned-by-"+owner)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogicalDeviceOwnershipFollowsRoot(t *testing.T) {
+	icp := newInterCoreProxy(nil, nil, "core-1", "rwcore", time.Second)
+	icp.registerLogicalDevice("ld-1", "dev-1")
+
+	// Locally owned root means a locally owned logical device
+	assert.Equal(t, "", icp.logicalDeviceOwnerOf("ld-1"))
+
+	// Handover of the root carries the logical device along
+	icp.setDeviceOwner("dev-1", "core-2")
+	assert.Equal(t, "core-2", icp.logicalDeviceOwnerOf("ld-1"))
+	icp.clearDeviceOwner("dev-1")
+	assert.Equal(t, "", icp.logicalDeviceOwnerOf("ld-1"))
+
+	// Registering while the root is already peer-owned starts out peer-owned
+	icp.setDeviceOwner("dev-2", "core-2")
+	icp.registerLogicalDevice("ld-2", "dev-2")
+	assert.Equal(t, "core-2", icp.logicalDeviceOwnerOf("ld-2"))
+	icp.unregisterLogicalDevice("ld-2", "dev-2")
+	assert.Equal(t, "", icp.logicalDeviceOwnerOf("ld-2"))
+}
+
+func TestControllerMessagesRefusedWhenPeerOwnsLogicalDevice(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	ldMgr := &LogicalDeviceManager{
+		deviceMgr:                      dMgr,
+		clusterDataProxy:               dMgr.clusterDataProxy,
+		logicalDeviceLoadingInProgress: make(map[string][]chan int),
+	}
+	dMgr.interCoreProxy.registerLogicalDevice("ld-1", "dev-1")
+	dMgr.interCoreProxy.setDeviceOwner("dev-1", "core-2")
+
+	ch := make(chan interface{}, 1)
+	ldMgr.updateFlowTable(ctx, "ld-1", &ofp.OfpFlowMod{}, ch)
+	err, ok := (<-ch).(error)
+	assert.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Once the root comes back to this instance the request proceeds past the gate;
+	// with no logical agent in memory that now surfaces as a plain not-found
+	dMgr.interCoreProxy.clearDeviceOwner("dev-1")
+	ch = make(chan interface{}, 1)
+	ldMgr.updateFlowTable(ctx, "ld-1", &ofp.OfpFlowMod{}, ch)
+	err, ok = (<-ch).(error)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestLogicalDeviceRegisteredOnCreation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, fls := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:46:01")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	waitUntil(t, 2*time.Second, "logical device was never created", func() bool {
+		return fls.callCount("createLogicalDevice") == 1
+	})
+
+	// The fake logical device service names the logical device after its root
+	logicalID, ok := dMgr.interCoreProxy.logicalForRoot.Load(device.Id)
+	assert.True(t, ok)
+	assert.Equal(t, "logical-"+device.Id, logicalID)
+}
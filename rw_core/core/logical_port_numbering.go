@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// OF port numbers used to be whatever physical port number arrived first, so a core restart
+// could renumber a UNI and break the subscriber-to-port mapping in ONOS.  With deterministic
+// numbering enabled, UNI numbers are derived from the PON ID, the ONU ID and the UNI index,
+// and every assignment is persisted per logical device so restarts and re-announcements
+// reuse it.  The scheme is opt-in (deterministic_uni_numbering) because it breaks the
+// long-standing invariant that a logical port carries the physical port number the adapter
+// reported; with it disabled the UNI keeps that number, as it always has.  Logical devices
+// created before deterministic numbering keep their stored values either way: an existing
+// logical port always wins over the computed candidate.
+const (
+	uniPonIDShift          = 12  // PON ID (the parent channel) occupies the bits above the ONU ID
+	uniOnuIDShift          = 4   // ONU ID occupies the bits above the UNI index
+	uniIndexMask           = 0xf // UNI index is the low bits of the physical port number
+	logicalPortNumbersPath = "logical_port_numbers"
+)
+
+// deterministicUNIPortNo derives the OF port number of a UNI from the PON ID and ONU ID in
+// the child's proxy address and the UNI index encoded in the physical port number
+func deterministicUNIPortNo(childDevice *voltha.Device, port *voltha.Port) uint32 {
+	proxyAddress := childDevice.GetProxyAddress()
+	return proxyAddress.GetChannelId()<<uniPonIDShift |
+		proxyAddress.GetOnuId()<<uniOnuIDShift |
+		port.PortNo&uniIndexMask
+}
+
+// portAssignmentID is the KV entry ID of one persisted assignment
+func portAssignmentID(deviceID string, devicePortNo uint32) string {
+	return fmt.Sprintf("%s_%d", deviceID, devicePortNo)
+}
+
+// encodePortAssignment encodes one assignment for the KV store.  The identifying fields are
+// embedded in the value because listing a KV path loses the keys.
+func encodePortAssignment(deviceID string, devicePortNo uint32, ofPortNo uint32) *ic.StrType {
+	return &ic.StrType{Val: fmt.Sprintf("%s|%d|%d", deviceID, devicePortNo, ofPortNo)}
+}
+
+// decodePortAssignment is the inverse of encodePortAssignment
+func decodePortAssignment(entry *ic.StrType) (string, uint32, uint32, error) {
+	parts := strings.SplitN(entry.Val, "|", 3)
+	if len(parts) != 3 {
+		return "", 0, 0, fmt.Errorf("invalid-port-assignment-entry-%s", entry.Val)
+	}
+	devicePortNo, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	ofPortNo, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return parts[0], uint32(devicePortNo), uint32(ofPortNo), nil
+}
+
+// loadPortAssignment returns the persisted OF port number for the physical port, if any
+func (agent *LogicalDeviceAgent) loadPortAssignment(ctx context.Context, deviceID string, devicePortNo uint32) (uint32, bool) {
+	entry := &ic.StrType{}
+	path := fmt.Sprintf("%s/%s/%s", logicalPortNumbersPath, agent.logicalDeviceID, portAssignmentID(deviceID, devicePortNo))
+	found, err := agent.clusterDataProxy.Get(ctx, path, entry)
+	if err != nil || !found {
+		return 0, false
+	}
+	_, _, ofPortNo, err := decodePortAssignment(entry)
+	if err != nil {
+		ctxWarnw(ctx, "invalid-persisted-port-assignment", log.Fields{"logical-device-id": agent.logicalDeviceID, "entry": entry.Val, "error": err})
+		return 0, false
+	}
+	return ofPortNo, true
+}
+
+// storePortAssignment persists one assignment
+func (agent *LogicalDeviceAgent) storePortAssignment(ctx context.Context, deviceID string, devicePortNo uint32, ofPortNo uint32) {
+	path := fmt.Sprintf("%s/%s", logicalPortNumbersPath, agent.logicalDeviceID)
+	if err := agent.clusterDataProxy.AddWithID(ctx, path, portAssignmentID(deviceID, devicePortNo),
+		encodePortAssignment(deviceID, devicePortNo, ofPortNo)); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-port-assignment", log.Fields{"logical-device-id": agent.logicalDeviceID, "device-id": deviceID, "error": err})
+	}
+}
+
+// ofPortNoInUse returns whether the OF port number is already taken on the logical device
+func ofPortNoInUse(ldevice *voltha.LogicalDevice, ofPortNo uint32) bool {
+	for _, lPort := range ldevice.Ports {
+		if lPort.OfpPort.PortNo == ofPortNo {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveOFPortNo returns the OF port number to use for the physical port.  The caller must
+// hold the request queue green light.  Resolution order: the number of an existing logical
+// port for the same physical port, then - with deterministic numbering enabled - a persisted
+// assignment, then the deterministic candidate, stepping over collisions.
+func (agent *LogicalDeviceAgent) resolveOFPortNo(ctx context.Context, device *voltha.Device, port *voltha.Port, isNNI bool) uint32 {
+	// A logical port that already carries this physical port keeps its number; this also
+	// preserves the arbitrary numbering of logical devices that predate deterministic
+	// assignment
+	ldevice := agent.getLogicalDeviceWithoutLock()
+	for _, lPort := range ldevice.Ports {
+		if lPort.DeviceId == device.Id && lPort.DevicePortNo == port.PortNo {
+			return lPort.OfpPort.PortNo
+		}
+	}
+
+	// Without deterministic numbering the UNI keeps the adapter-reported number
+	if !isNNI && !agent.deterministicUNINumbering {
+		return port.PortNo
+	}
+
+	// A re-announced port reuses the number it was given before
+	if assigned, ok := agent.loadPortAssignment(ctx, device.Id, port.PortNo); ok {
+		return assigned
+	}
+
+	// NNI numbers are already deterministic as reported by the adapter; UNI numbers are
+	// derived from the PON topology
+	candidate := port.PortNo
+	if !isNNI {
+		candidate = deterministicUNIPortNo(device, port)
+		if candidate == 0 {
+			candidate = port.PortNo
+		}
+	}
+	for ofPortNoInUse(ldevice, candidate) {
+		ctxWarnw(ctx, "of-port-number-collision", log.Fields{"logical-device-id": agent.logicalDeviceID, "device-id": device.Id, "of-port": candidate})
+		candidate++
+	}
+	agent.storePortAssignment(ctx, device.Id, port.PortNo, candidate)
+	return candidate
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPortNumberingTestAgent returns a logical device agent with just enough state to
+// exercise OF port number resolution
+func newPortNumberingTestAgent(ports []*voltha.LogicalPort) *LogicalDeviceAgent {
+	return &LogicalDeviceAgent{
+		logicalDeviceID:           "ld-numbering",
+		clusterDataProxy:          newInMemoryClusterProxy(),
+		logicalDevice:             &voltha.LogicalDevice{Id: "ld-numbering", Ports: ports},
+		deterministicUNINumbering: true,
+	}
+}
+
+func newNumberingChildDevice(ponID uint32, onuID uint32) *voltha.Device {
+	return &voltha.Device{
+		Id:           "onu-1",
+		ProxyAddress: &voltha.Device_ProxyAddress{ChannelId: ponID, OnuId: onuID},
+	}
+}
+
+func TestDeterministicUNIPortNo(t *testing.T) {
+	child := newNumberingChildDevice(2, 5)
+	port := &voltha.Port{PortNo: 3, Label: "uni-3"}
+	assert.Equal(t, uint32(2<<uniPonIDShift|5<<uniOnuIDShift|3), deterministicUNIPortNo(child, port))
+
+	// Only the UNI index bits of the physical port number participate
+	port = &voltha.Port{PortNo: 0x103, Label: "uni-3"}
+	assert.Equal(t, uint32(2<<uniPonIDShift|5<<uniOnuIDShift|3), deterministicUNIPortNo(child, port))
+}
+
+func TestResolveOFPortNoDeterministicAndPersisted(t *testing.T) {
+	ctx := context.Background()
+	agent := newPortNumberingTestAgent(nil)
+	child := newNumberingChildDevice(1, 2)
+	port := &voltha.Port{PortNo: 1, Label: "uni-1"}
+
+	expected := uint32(1<<uniPonIDShift | 2<<uniOnuIDShift | 1)
+	assert.Equal(t, expected, agent.resolveOFPortNo(ctx, child, port, false))
+
+	// A re-announcement after the logical port is gone reuses the persisted number
+	assert.Equal(t, expected, agent.resolveOFPortNo(ctx, child, port, false))
+
+	// NNI ports keep the adapter-reported number
+	nni := &voltha.Device{Id: "olt-1"}
+	assert.Equal(t, uint32(2), agent.resolveOFPortNo(ctx, nni, &voltha.Port{PortNo: 2, Label: "nni-2"}, true))
+}
+
+func TestResolveOFPortNoKeepsExistingAssignment(t *testing.T) {
+	ctx := context.Background()
+	// A logical device that predates deterministic numbering carries an arbitrary number
+	agent := newPortNumberingTestAgent([]*voltha.LogicalPort{
+		{Id: "uni-1", DeviceId: "onu-1", DevicePortNo: 1, OfpPort: &voltha.OfpPort{PortNo: 77}},
+	})
+	child := newNumberingChildDevice(1, 2)
+	port := &voltha.Port{PortNo: 1, Label: "uni-1"}
+	assert.Equal(t, uint32(77), agent.resolveOFPortNo(ctx, child, port, false))
+}
+
+func TestResolveOFPortNoCollision(t *testing.T) {
+	ctx := context.Background()
+	occupied := uint32(1<<uniPonIDShift | 2<<uniOnuIDShift | 1)
+	agent := newPortNumberingTestAgent([]*voltha.LogicalPort{
+		{Id: "uni-other", DeviceId: "onu-other", DevicePortNo: 9, OfpPort: &voltha.OfpPort{PortNo: occupied}},
+	})
+	child := newNumberingChildDevice(1, 2)
+	port := &voltha.Port{PortNo: 1, Label: "uni-1"}
+
+	// The deterministic candidate is taken, so the next free number is assigned and persisted
+	assert.Equal(t, occupied+1, agent.resolveOFPortNo(ctx, child, port, false))
+	assigned, ok := agent.loadPortAssignment(ctx, child.Id, port.PortNo)
+	assert.True(t, ok)
+	assert.Equal(t, occupied+1, assigned)
+}
+
+func TestPortAssignmentCodec(t *testing.T) {
+	entry := encodePortAssignment("dev-1", 3, 4099)
+	deviceID, devicePortNo, ofPortNo, err := decodePortAssignment(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "dev-1", deviceID)
+	assert.Equal(t, uint32(3), devicePortNo)
+	assert.Equal(t, uint32(4099), ofPortNo)
+
+	_, _, _, err = decodePortAssignment(&ic.StrType{Val: "garbage"})
+	assert.NotNil(t, err)
+}
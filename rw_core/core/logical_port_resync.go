@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A reconnecting OpenFlow controller rebuilds its picture of a logical device from the
+// features and port-desc replies, so serving it stale state - ports for ONUs that are
+// gone, LIVE states for devices that are down - leaves it wrong until the next real port
+// event.  The resync below reconciles the logical port list against the live device tree
+// just before those replies are served.  It only reads the in-memory device agents and
+// writes the logical device only when something actually drifted, so it is cheap enough
+// to run on every controller reconnect.
+
+// resyncBeforeReplay reconciles the logical ports against the device tree before a read
+// that feeds the controller.  A failed resync is logged and the reply served regardless -
+// slightly stale state beats no state.
+func (ldMgr *LogicalDeviceManager) resyncBeforeReplay(ctx context.Context, agent *LogicalDeviceAgent) {
+	if err := agent.resyncPortsAgainstDevices(ctx); err != nil {
+		ctxWarnw(ctx, "pre-replay-port-resync-failed", log.Fields{"logical-device-id": agent.logicalDeviceID, "error": err})
+	}
+}
+
+// resyncPortsAgainstDevices drops the logical ports whose backing device no longer exists
+// and realigns the state of the rest with what the device tree reports.  The logical
+// device is written once, and only when something changed, which also raises the matching
+// port change events towards the controller.
+func (agent *LogicalDeviceAgent) resyncPortsAgainstDevices(ctx context.Context) error {
+	if agent.deviceMgr == nil {
+		return nil
+	}
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	original := agent.getLogicalDeviceWithoutLock()
+
+	updatedPorts := make([]*voltha.LogicalPort, 0, len(original.Ports))
+	changed := false
+	for _, port := range clonePorts(original.Ports) {
+		device, err := agent.deviceMgr.GetDevice(ctx, port.DeviceId)
+		if err != nil {
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+			ctxInfow(ctx, "dropping-port-of-missing-device", log.Fields{"logical-device-id": agent.logicalDeviceID, "port-no": port.OfpPort.PortNo, "device-id": port.DeviceId})
+			changed = true
+			continue
+		}
+		if device.AdminState == voltha.AdminState_DELETED {
+			ctxInfow(ctx, "dropping-port-of-deleted-device", log.Fields{"logical-device-id": agent.logicalDeviceID, "port-no": port.OfpPort.PortNo, "device-id": port.DeviceId})
+			changed = true
+			continue
+		}
+		if alignPortWithDevice(port, device) {
+			changed = true
+		}
+		updatedPorts = append(updatedPorts, port)
+	}
+	if !changed {
+		return nil
+	}
+	ctxInfow(ctx, "resynced-logical-ports", log.Fields{"logical-device-id": agent.logicalDeviceID, "ports": len(updatedPorts), "was": len(original.Ports)})
+	return agent.updateLogicalDevicePortsWithoutLock(ctx, original, updatedPorts)
+}
+
+// alignPortWithDevice makes the OpenFlow state of a logical port match the device backing
+// it, reporting whether anything was adjusted
+func alignPortWithDevice(port *voltha.LogicalPort, device *voltha.Device) bool {
+	live := device.AdminState == voltha.AdminState_ENABLED && device.OperStatus == voltha.OperStatus_ACTIVE
+	newConfig, newState := port.OfpPort.Config, port.OfpPort.State
+	if live {
+		newConfig = newConfig & ^uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
+		newState = uint32(ofp.OfpPortState_OFPPS_LIVE)
+	} else {
+		newConfig = newConfig | uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
+		newState = uint32(ofp.OfpPortState_OFPPS_LINK_DOWN)
+	}
+	if newConfig == port.OfpPort.Config && newState == port.OfpPort.State {
+		return false
+	}
+	port.OfpPort.Config = newConfig
+	port.OfpPort.State = newState
+	return true
+}
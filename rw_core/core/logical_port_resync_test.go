@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortResyncBeforeControllerReplay(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	// A live OLT backs the NNI port; the UNI port references a device that is gone
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:0a:01"}, ch)
+	olt, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: olt.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, olt.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	changeEvents := make(chan ofp.ChangeEvent, 4)
+	agent := &LogicalDeviceAgent{
+		logicalDeviceID:  "ld-resync",
+		deviceMgr:        dMgr,
+		clusterDataProxy: newInMemoryClusterProxy(),
+		requestQueue:     coreutils.NewRequestQueue(),
+		ldeviceMgr:       &LogicalDeviceManager{grpcNbiHdlr: &APIHandler{changeEventQueue: changeEvents}},
+		logicalDevice: &voltha.LogicalDevice{
+			Id: "ld-resync",
+			Ports: []*voltha.LogicalPort{
+				{Id: "nni-1", DeviceId: olt.Id, DevicePortNo: 1, OfpPort: &ofp.OfpPort{PortNo: 1, State: uint32(ofp.OfpPortState_OFPPS_LINK_DOWN)}},
+				{Id: "uni-1", DeviceId: "gone-onu", DevicePortNo: 16, OfpPort: &ofp.OfpPort{PortNo: 16, State: uint32(ofp.OfpPortState_OFPPS_LIVE)}},
+			},
+		},
+	}
+
+	ldMgr := &LogicalDeviceManager{deviceMgr: dMgr}
+	ldMgr.addLogicalDeviceAgentToMap(agent)
+
+	// The replay path drops the orphaned port and realigns the surviving one
+	ports, err := ldMgr.ListLogicalDevicePorts(ctx, "ld-resync")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ports.Items))
+	assert.Equal(t, olt.Id, ports.Items[0].DeviceId)
+	assert.Equal(t, uint32(ofp.OfpPortState_OFPPS_LIVE), ports.Items[0].OfpPort.State)
+
+	// A second replay with nothing drifted leaves the logical device untouched
+	before := agent.logicalDevice
+	ports, err = ldMgr.ListLogicalDevicePorts(ctx, "ld-resync")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ports.Items))
+	assert.True(t, before == agent.logicalDevice)
+}
+
+func TestPortResyncFollowsDeviceState(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:0a:02"}, ch)
+	olt, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: olt.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, olt.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	agent := &LogicalDeviceAgent{
+		logicalDeviceID:  "ld-resync-state",
+		deviceMgr:        dMgr,
+		clusterDataProxy: newInMemoryClusterProxy(),
+		requestQueue:     coreutils.NewRequestQueue(),
+		ldeviceMgr:       &LogicalDeviceManager{grpcNbiHdlr: &APIHandler{changeEventQueue: make(chan ofp.ChangeEvent, 4)}},
+		logicalDevice: &voltha.LogicalDevice{
+			Id: "ld-resync-state",
+			Ports: []*voltha.LogicalPort{
+				{Id: "nni-1", DeviceId: olt.Id, DevicePortNo: 1, OfpPort: &ofp.OfpPort{PortNo: 1, State: uint32(ofp.OfpPortState_OFPPS_LIVE)}},
+			},
+		},
+	}
+
+	// Once the device goes down the replayed port must not claim LIVE any more
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: olt.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, agent.resyncPortsAgainstDevices(ctx))
+	port := agent.logicalDevice.Ports[0]
+	assert.Equal(t, uint32(ofp.OfpPortState_OFPPS_LINK_DOWN), port.OfpPort.State)
+	assert.NotZero(t, port.OfpPort.Config&uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN))
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"google.golang.org/grpc/metadata"
+)
+
+// A misconfigured orchestrator retry loop once generated thousands of enableDevice calls
+// per minute and starved real traffic.  The NBI entry points therefore run through a token
+// bucket per caller and operation: each call consumes a token, tokens refill at the
+// configured rate, and a caller out of tokens gets ResourceExhausted with a retry-after
+// hint in the message.  The caller is identified by the "voltha-caller-id" gRPC metadata
+// header; calls without incoming gRPC metadata - adapter callbacks, inter-container
+// requests, internal retries - are exempt.  The default limit comes from the
+// operation_quota_rate/operation_quota_burst flags (rate zero disables throttling), can be
+// overridden per operation, and the overrides are adjustable at runtime through the
+// "operation_quotas" runtime config setting.  Consumption is observable per caller and
+// operation through the NBI.
+
+// quotaCallerHeader is the gRPC metadata header identifying the caller a quota is
+// accounted against; callers without it share one anonymous bucket per operation
+const quotaCallerHeader = "voltha-caller-id"
+
+// quotaLimit is the token bucket sizing of one operation: RatePerMinute tokens refill per
+// minute up to a capacity of Burst.  A non-positive rate disables throttling.
+type quotaLimit struct {
+	RatePerMinute int
+	Burst         int
+}
+
+// quotaBucket is the live bucket of one caller and operation
+type quotaBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// OperationQuotaUsage is a consumption snapshot of one caller and operation
+type OperationQuotaUsage struct {
+	Caller    string
+	Operation string
+	Granted   int64
+	Rejected  int64
+}
+
+// quotaCallerOf extracts the caller identity a quota is accounted against.  The second
+// return is false when the context carries no incoming gRPC metadata at all, which marks
+// the call as internal and exempt from quotas.
+func quotaCallerOf(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if values := md.Get(quotaCallerHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0]), true
+	}
+	return "", true
+}
+
+// operationQuota resolves the limit in force for an operation
+func (dMgr *DeviceManager) operationQuota(operation string) quotaLimit {
+	if limit, have := dMgr.operationQuotaLimits[operation]; have {
+		return limit
+	}
+	return dMgr.operationQuotaDefault
+}
+
+// setOperationQuota overrides the quota of one operation at runtime; a non-positive burst
+// removes the override so the operation falls back to the default limit
+func (dMgr *DeviceManager) setOperationQuota(operation string, ratePerMinute int, burst int) {
+	dMgr.operationQuotaLock.Lock()
+	defer dMgr.operationQuotaLock.Unlock()
+	if dMgr.operationQuotaLimits == nil {
+		dMgr.operationQuotaLimits = make(map[string]quotaLimit)
+	}
+	if burst <= 0 {
+		delete(dMgr.operationQuotaLimits, operation)
+		return
+	}
+	dMgr.operationQuotaLimits[operation] = quotaLimit{RatePerMinute: ratePerMinute, Burst: burst}
+}
+
+// checkOperationQuota consumes one token from the caller's bucket for the operation.  It
+// returns nil when the call may proceed and ResourceExhausted - with a retry-after hint -
+// when the caller is out of tokens.  Internal calls carry no gRPC metadata and pass freely.
+func (dMgr *DeviceManager) checkOperationQuota(ctx context.Context, operation string) error {
+	caller, external := quotaCallerOf(ctx)
+	if !external {
+		return nil
+	}
+	dMgr.operationQuotaLock.Lock()
+	defer dMgr.operationQuotaLock.Unlock()
+	limit := dMgr.operationQuota(operation)
+	if limit.RatePerMinute <= 0 || limit.Burst <= 0 {
+		return nil
+	}
+	if dMgr.operationQuotaBuckets == nil {
+		dMgr.operationQuotaBuckets = make(map[string]*quotaBucket)
+		dMgr.operationQuotaGranted = make(map[string]int64)
+		dMgr.operationQuotaRejected = make(map[string]int64)
+	}
+	now := dMgr.now()
+	key := caller + "|" + operation
+	bucket, have := dMgr.operationQuotaBuckets[key]
+	if !have {
+		bucket = &quotaBucket{tokens: float64(limit.Burst), lastRefill: now}
+		dMgr.operationQuotaBuckets[key] = bucket
+	}
+	refill := now.Sub(bucket.lastRefill).Minutes() * float64(limit.RatePerMinute)
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+refill)
+	bucket.lastRefill = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		dMgr.operationQuotaGranted[key]++
+		return nil
+	}
+	dMgr.operationQuotaRejected[key]++
+	retryAfter := time.Duration((1 - bucket.tokens) / float64(limit.RatePerMinute) * float64(time.Minute))
+	ctxWarnw(ctx, "operation-quota-exceeded", log.Fields{"operation": operation, "caller": caller, "retry-after": retryAfter})
+	return newResourceExhaustedError(operation, retryAfter)
+}
+
+// operationQuotaUsage snapshots the per-caller, per-operation consumption counters
+func (dMgr *DeviceManager) operationQuotaUsage() []*OperationQuotaUsage {
+	dMgr.operationQuotaLock.Lock()
+	defer dMgr.operationQuotaLock.Unlock()
+	usage := make([]*OperationQuotaUsage, 0, len(dMgr.operationQuotaBuckets))
+	for key := range dMgr.operationQuotaBuckets {
+		parts := strings.SplitN(key, "|", 2)
+		usage = append(usage, &OperationQuotaUsage{
+			Caller:    parts[0],
+			Operation: parts[1],
+			Granted:   dMgr.operationQuotaGranted[key],
+			Rejected:  dMgr.operationQuotaRejected[key],
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Caller != usage[j].Caller {
+			return usage[i].Caller < usage[j].Caller
+		}
+		return usage[i].Operation < usage[j].Operation
+	})
+	return usage
+}
+
+// encodeOperationQuotas renders the per-operation overrides for the runtime config
+// manager as "operation=rate/burst" pairs, comma separated and sorted
+func (dMgr *DeviceManager) encodeOperationQuotas() string {
+	dMgr.operationQuotaLock.Lock()
+	defer dMgr.operationQuotaLock.Unlock()
+	pairs := make([]string, 0, len(dMgr.operationQuotaLimits))
+	for operation, limit := range dMgr.operationQuotaLimits {
+		pairs = append(pairs, fmt.Sprintf("%s=%d/%d", operation, limit.RatePerMinute, limit.Burst))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// applyOperationQuotas is the inverse of encodeOperationQuotas; an empty value clears all
+// overrides
+func (dMgr *DeviceManager) applyOperationQuotas(value string) error {
+	overrides := make(map[string]quotaLimit)
+	if value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return fmt.Errorf("invalid-operation-quota-entry-%s", pair)
+			}
+			sizing := strings.SplitN(parts[1], "/", 2)
+			if len(sizing) != 2 {
+				return fmt.Errorf("invalid-operation-quota-entry-%s", pair)
+			}
+			rate, err := strconv.Atoi(sizing[0])
+			if err != nil {
+				return err
+			}
+			burst, err := strconv.Atoi(sizing[1])
+			if err != nil {
+				return err
+			}
+			if burst <= 0 {
+				return fmt.Errorf("invalid-operation-quota-burst-%s", pair)
+			}
+			overrides[strings.TrimSpace(parts[0])] = quotaLimit{RatePerMinute: rate, Burst: burst}
+		}
+	}
+	dMgr.operationQuotaLock.Lock()
+	defer dMgr.operationQuotaLock.Unlock()
+	dMgr.operationQuotaLimits = overrides
+	return nil
+}
+
+// SetOperationQuota is the NBI surface for adjusting the quota of one operation
+func (handler *APIHandler) SetOperationQuota(ctx context.Context, operation string, ratePerMinute int, burst int) error {
+	ctxDebugw(ctx, "SetOperationQuota", log.Fields{"operation": operation, "rate-per-minute": ratePerMinute, "burst": burst})
+	if operation == "" {
+		return newInvalidArgumentError("operation-empty")
+	}
+	handler.deviceMgr.setOperationQuota(operation, ratePerMinute, burst)
+	return nil
+}
+
+// ListOperationQuotaUsage is the NBI surface of the quota consumption counters
+func (handler *APIHandler) ListOperationQuotaUsage(ctx context.Context) ([]*OperationQuotaUsage, error) {
+	ctxDebugw(ctx, "ListOperationQuotaUsage", nil)
+	return handler.deviceMgr.operationQuotaUsage(), nil
+}
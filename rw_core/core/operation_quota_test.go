@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// quotaContext builds the context of an external caller identified for quota accounting
+func quotaContext(caller string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(quotaCallerHeader, caller))
+}
+
+func TestOperationQuotaEnforcedAtNBI(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:1f")
+	dMgr.setOperationQuota("enableDevice", 1, 2)
+
+	// The caller's burst of two is consumed by the first two calls
+	ctx := quotaContext("orchestrator")
+	for i := 0; i < 2; i++ {
+		ch := make(chan interface{}, 1)
+		dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+		res := <-ch
+		if err, isError := res.(error); isError {
+			assert.NotEqual(t, codes.ResourceExhausted, status.Code(err))
+		}
+	}
+
+	// The third call is rejected with a retry-after hint
+	ch := make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	err, isError := (<-ch).(error)
+	assert.True(t, isError)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "retry-after"))
+
+	// An internal call without gRPC metadata is exempt even with the bucket empty
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(context.Background(), &voltha.ID{Id: device.Id}, ch)
+	if err, isError := (<-ch).(error); isError {
+		assert.NotEqual(t, codes.ResourceExhausted, status.Code(err))
+	}
+}
+
+func TestOperationQuotaRefillAndPerCallerIsolation(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	clock := newFakeClock(time.Now())
+	dMgr.clock = clock
+	// One token per second, bucket of one
+	dMgr.setOperationQuota("rebootDevice", 60, 1)
+
+	// The first call drains caller a's bucket; the second is rejected
+	assert.Nil(t, dMgr.checkOperationQuota(quotaContext("a"), "rebootDevice"))
+	err := dMgr.checkOperationQuota(quotaContext("a"), "rebootDevice")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Caller b has its own bucket
+	assert.Nil(t, dMgr.checkOperationQuota(quotaContext("b"), "rebootDevice"))
+
+	// The bucket refills over time
+	clock.advance(2 * time.Second)
+	assert.Nil(t, dMgr.checkOperationQuota(quotaContext("a"), "rebootDevice"))
+
+	// Consumption is observable per caller and operation
+	usage := dMgr.operationQuotaUsage()
+	assert.Equal(t, 2, len(usage))
+	assert.Equal(t, "a", usage[0].Caller)
+	assert.Equal(t, "rebootDevice", usage[0].Operation)
+	assert.Equal(t, int64(2), usage[0].Granted)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+	assert.Equal(t, "b", usage[1].Caller)
+	assert.Equal(t, int64(1), usage[1].Granted)
+}
+
+func TestOperationQuotaRuntimeOverrides(t *testing.T) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	assert.Nil(t, dMgr.applyOperationQuotas("enableDevice=5/5,deleteDevice=1/1"))
+	assert.Equal(t, "deleteDevice=1/1,enableDevice=5/5", dMgr.encodeOperationQuotas())
+	assert.Equal(t, quotaLimit{RatePerMinute: 1, Burst: 1}, dMgr.operationQuota("deleteDevice"))
+
+	// A malformed override is rejected without touching the limits in force
+	assert.NotNil(t, dMgr.applyOperationQuotas("bogus"))
+	assert.NotNil(t, dMgr.applyOperationQuotas("enableDevice=5/0"))
+	assert.Equal(t, "deleteDevice=1/1,enableDevice=5/5", dMgr.encodeOperationQuotas())
+
+	// An empty override clears everything back to the default
+	assert.Nil(t, dMgr.applyOperationQuotas(""))
+	assert.Equal(t, "", dMgr.encodeOperationQuotas())
+	assert.Equal(t, dMgr.operationQuotaDefault, dMgr.operationQuota("enableDevice"))
+}
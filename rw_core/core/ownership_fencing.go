@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The ownership reservation keeps two cores from both managing a device - until a KV
+// partition lets each renew against a different etcd member, after which both believe
+// owned=true and both write the model.  The TTL alone cannot break that tie, so each
+// acquisition now also advances a per-device fencing token: a counter the new owner bumps
+// when it takes the reservation.  An instance remembers the token it acquired under, and
+// every device model write is checked against the stored token before it goes out - a
+// write carrying an older token than the store means a peer acquired the device since,
+// and the write is fenced off instead of overwriting the new owner's state.  A fenced
+// write is handled like any other ownership loss: the agent's in-flight and queued work
+// is cancelled.  The renewer makes the same check so a fenced instance finds out on the
+// next renewal even when it has nothing to write.
+
+const deviceOwnershipTokenPath = "ownership_tokens"
+
+// ownershipTokenKey builds the KV key carrying the fencing token of a device
+func (dMgr *DeviceManager) ownershipTokenKey(deviceID string) string {
+	return fmt.Sprintf("%s/%s/%s", dMgr.kvStorePrefix, deviceOwnershipTokenPath, deviceID)
+}
+
+// readOwnershipToken returns the fencing token currently stored for a device; a device
+// never fenced has token zero
+func (dMgr *DeviceManager) readOwnershipToken(ctx context.Context, deviceID string) (int64, error) {
+	pair, err := dMgr.kvClient.Get(ctx, dMgr.ownershipTokenKey(deviceID))
+	if err != nil {
+		return 0, err
+	}
+	if pair == nil {
+		return 0, nil
+	}
+	value, err := kvstore.ToString(pair.Value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// advanceOwnershipToken bumps the fencing token of a freshly acquired device and records
+// the new token as the one this instance writes under.  It runs right after a successful
+// reservation: from that moment any writer still holding the previous token is stale.
+func (dMgr *DeviceManager) advanceOwnershipToken(ctx context.Context, deviceID string) {
+	token, err := dMgr.readOwnershipToken(ctx, deviceID)
+	if err != nil {
+		ctxWarnw(ctx, "failed-to-read-ownership-token", log.Fields{"device-id": deviceID, "error": err})
+		return
+	}
+	token++
+	if err := dMgr.kvClient.Put(ctx, dMgr.ownershipTokenKey(deviceID), strconv.FormatInt(token, 10)); err != nil {
+		ctxWarnw(ctx, "failed-to-advance-ownership-token", log.Fields{"device-id": deviceID, "error": err})
+		return
+	}
+	dMgr.ownershipTokens.Store(deviceID, token)
+}
+
+// heldOwnershipToken returns the token this instance acquired the device under, or zero
+// when the device was never fenced here (no KV store, or acquisition predates fencing)
+func (dMgr *DeviceManager) heldOwnershipToken(deviceID string) int64 {
+	if token, ok := dMgr.ownershipTokens.Load(deviceID); ok {
+		return token.(int64)
+	}
+	return 0
+}
+
+// checkWriteFence decides whether a model write for the device may proceed: the write
+// carries the token the instance acquired the device under, and a store holding a newer
+// token fences the write off.  A token read failure lets the write through - refusing all
+// writes whenever the KV store hiccups would trade a split-brain risk for an outage.
+func (dMgr *DeviceManager) checkWriteFence(ctx context.Context, deviceID string) error {
+	if dMgr.kvClient == nil {
+		return nil
+	}
+	held := dMgr.heldOwnershipToken(deviceID)
+	if held == 0 {
+		return nil
+	}
+	current, err := dMgr.readOwnershipToken(ctx, deviceID)
+	if err != nil {
+		ctxWarnw(ctx, "ownership-token-read-failed-letting-write-through", log.Fields{"device-id": deviceID, "error": err})
+		return nil
+	}
+	if current > held {
+		return status.Errorf(codes.Aborted, "write-fenced-stale-token-device-%s: held %d, current %d", deviceID, held, current)
+	}
+	return nil
+}
+
+// checkRenewalFence verifies after a renewal that the fencing token is still the one this
+// instance acquired under; a newer token means a peer took the device during a partition
+// and the local work is cancelled the way any other ownership loss is
+func (dMgr *DeviceManager) checkRenewalFence(ctx context.Context, deviceID string) {
+	held := dMgr.heldOwnershipToken(deviceID)
+	if held == 0 {
+		return
+	}
+	current, err := dMgr.readOwnershipToken(ctx, deviceID)
+	if err != nil || current <= held {
+		return
+	}
+	ctxWarnw(ctx, "device-ownership-fenced-during-renewal", log.Fields{"device-id": deviceID, "held-token": held, "current-token": current})
+	dMgr.ownershipTokens.Delete(deviceID)
+	dMgr.cancelDeviceWork(deviceID)
+}
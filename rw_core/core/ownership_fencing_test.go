@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnershipTokenAdvancesPerAcquisition(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:3e")
+
+	token, err := dMgr.readOwnershipToken(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), token)
+	assert.Equal(t, int64(1), dMgr.heldOwnershipToken(device.Id))
+
+	// Releasing drops the held token but not the stored one; the next acquisition - here
+	// by the same instance, in the field by whoever wins the device - advances it
+	dMgr.releaseDeviceOwnership(device.Id)
+	assert.Equal(t, int64(0), dMgr.heldOwnershipToken(device.Id))
+	dMgr.reserveDeviceOwnership(ctx, device.Id)
+	token, err = dMgr.readOwnershipToken(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), token)
+	assert.Equal(t, int64(2), dMgr.heldOwnershipToken(device.Id))
+}
+
+func TestFencedWriteRejectedAndCancelsWork(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:3f")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	// Writes under the token the device was acquired with go through
+	assert.Nil(t, agent.updateDeviceReason(ctx, "pre-partition"))
+
+	// A peer acquired the device during a partition, advancing the stored token past the
+	// one this instance still holds
+	assert.Nil(t, dMgr.kvClient.Put(ctx, dMgr.ownershipTokenKey(device.Id), "2"))
+
+	err := agent.updateDeviceReason(ctx, "post-partition")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "write-fenced-stale-token")
+	// The fenced write is treated as an ownership loss: local work is cancelled
+	assert.True(t, agent.ownershipLost())
+
+	// The new owner's state was not overwritten
+	stored, err := dMgr.getDeviceFromModel(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "pre-partition", stored.Reason)
+}
+
+func TestRenewalDetectsFencing(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:40")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	// Both sides renew successfully under a partition, so the renewal alone proves
+	// nothing; the token comparison is what detects the seizure
+	assert.Nil(t, dMgr.kvClient.Put(ctx, dMgr.ownershipTokenKey(device.Id), "7"))
+	dMgr.renewDeviceOwnerships(ctx)
+
+	assert.True(t, agent.ownershipLost())
+	assert.Equal(t, int64(0), dMgr.heldOwnershipToken(device.Id))
+}
+
+func TestUnfencedDevicesWriteFreely(t *testing.T) {
+	ctx := context.Background()
+	// Without a KV store there are no tokens and writes are never fenced, as before
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:41")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.Nil(t, agent.updateDeviceReason(ctx, "memory-only"))
+	assert.False(t, agent.ownershipLost())
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// Which instance manages a device was visible only in this core's memory, so debugging an
+// ownership disagreement between two cores meant reading etcd keys by hand.  Each device
+// an instance manages is now backed by a KV reservation - the instance ID under a
+// per-device key, carrying a TTL and renewed periodically - making ownership observable
+// across instances and self-expiring when an owner dies.  getOwnershipInfo reads that
+// reservation live, reports it next to this instance's cached view, and a discrepancy
+// (cached owned but the KV names a peer) immediately records the peer as the owner -
+// cancelling the local work the way any other ownership loss does - rather than just
+// being reported.
+
+const (
+	deviceOwnershipPath = "ownership"
+	// deviceOwnershipTTL is how long an unrenewed ownership reservation survives, in seconds
+	deviceOwnershipTTL = int64(60)
+	// ownershipRenewInterval keeps the reservations comfortably inside the TTL
+	ownershipRenewInterval = 20 * time.Second
+)
+
+// ownershipInfo is the debug view of the ownership reservation of one device
+type ownershipInfo struct {
+	Key          string        // the KV key carrying the reservation
+	KVOwner      string        // the instance named by the KV store, read live; empty when unreserved
+	CachedOwned  bool          // whether this instance believes it owns the device
+	LastRenewal  time.Time     // when this instance last reserved or renewed the key
+	RemainingTTL time.Duration // time left before this instance's reservation expires
+}
+
+// ownershipKey builds the KV key carrying the ownership reservation of a device
+func (dMgr *DeviceManager) ownershipKey(deviceID string) string {
+	return fmt.Sprintf("%s/%s/%s", dMgr.kvStorePrefix, deviceOwnershipPath, deviceID)
+}
+
+// reserveDeviceOwnership writes the ownership reservation when this instance starts
+// managing a device.  Without a shared KV store ownership stays memory-only, as before.
+func (dMgr *DeviceManager) reserveDeviceOwnership(ctx context.Context, deviceID string) {
+	if dMgr.kvClient == nil {
+		return
+	}
+	value, err := dMgr.kvClient.Reserve(ctx, dMgr.ownershipKey(deviceID), dMgr.coreInstanceID, deviceOwnershipTTL)
+	if err != nil {
+		ctxWarnw(ctx, "failed-to-reserve-device-ownership", log.Fields{"device-id": deviceID, "error": err})
+		return
+	}
+	owner, err := kvstore.ToString(value)
+	if err != nil || owner != dMgr.coreInstanceID {
+		// The reservation is already held - the peer has not released or expired it yet
+		ctxWarnw(ctx, "device-ownership-reserved-by-peer", log.Fields{"device-id": deviceID, "owner": owner})
+		return
+	}
+	dMgr.ownershipRenewals.Store(deviceID, dMgr.now())
+	// Taking the reservation fences off any writer still holding the previous token
+	dMgr.advanceOwnershipToken(ctx, deviceID)
+}
+
+// releaseDeviceOwnership drops the reservation when this instance stops managing the
+// device; the TTL covers the case where the instance dies before getting here
+func (dMgr *DeviceManager) releaseDeviceOwnership(deviceID string) {
+	dMgr.ownershipRenewals.Delete(deviceID)
+	dMgr.ownershipTokens.Delete(deviceID)
+	if dMgr.kvClient == nil {
+		return
+	}
+	if err := dMgr.kvClient.ReleaseReservation(context.Background(), dMgr.ownershipKey(deviceID)); err != nil {
+		logger.Warnw("failed-to-release-device-ownership", log.Fields{"device-id": deviceID, "error": err})
+	}
+}
+
+// runOwnershipRenewer periodically renews the reservations of the managed devices until
+// the device manager stops
+func (dMgr *DeviceManager) runOwnershipRenewer(ctx context.Context) {
+	for {
+		select {
+		case <-dMgr.after(ownershipRenewInterval):
+			dMgr.renewDeviceOwnerships(ctx)
+		case <-dMgr.exitChannel:
+			logger.Info("device-ownership-renewer-stopped")
+			return
+		}
+	}
+}
+
+// renewDeviceOwnerships renews the reservation of every device this instance still owns
+func (dMgr *DeviceManager) renewDeviceOwnerships(ctx context.Context) {
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		deviceID := key.(string)
+		if value.(*DeviceAgent).ownershipLost() {
+			return true
+		}
+		if err := dMgr.kvClient.RenewReservation(ctx, dMgr.ownershipKey(deviceID)); err != nil {
+			ctxWarnw(ctx, "failed-to-renew-device-ownership", log.Fields{"device-id": deviceID, "error": err})
+			return true
+		}
+		dMgr.ownershipRenewals.Store(deviceID, dMgr.now())
+		// A successful renewal is no proof of ownership under a partition - each side can
+		// renew against a different member - so the fencing token is checked as well
+		dMgr.checkRenewalFence(ctx, deviceID)
+		return true
+	})
+}
+
+// releaseAllOwnerships drops the reservation of every managed device.  It runs during
+// shutdown, after the agents have been signalled and while the KV client is still open,
+// so peers do not have to wait out the TTL before taking the devices over.
+func (dMgr *DeviceManager) releaseAllOwnerships() {
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		dMgr.releaseDeviceOwnership(key.(string))
+		return true
+	})
+}
+
+// getOwnershipInfo returns the ownership reservation details of a device: the KV key, the
+// owner named by the KV store read live, this instance's cached view, the last renewal
+// and the remaining TTL.  A discrepancy between the cached view and the KV store is acted
+// on immediately instead of just being reported.
+func (dMgr *DeviceManager) getOwnershipInfo(ctx context.Context, deviceID string) (*ownershipInfo, error) {
+	if dMgr.kvClient == nil {
+		return nil, newFailedPreconditionError("device-ownership", deviceID, "no-kv-store-configured")
+	}
+	info := &ownershipInfo{
+		Key:         dMgr.ownershipKey(deviceID),
+		CachedOwned: dMgr.deviceOwnedHere(deviceID),
+	}
+	pair, err := dMgr.kvClient.Get(ctx, info.Key)
+	if err != nil {
+		return nil, newUnavailableError("ownership-lookup", deviceID, err)
+	}
+	if pair != nil {
+		if owner, err := kvstore.ToString(pair.Value); err == nil {
+			info.KVOwner = owner
+		}
+	}
+	if renewal, ok := dMgr.ownershipRenewals.Load(deviceID); ok {
+		info.LastRenewal = renewal.(time.Time)
+		if remaining := time.Duration(deviceOwnershipTTL)*time.Second - dMgr.now().Sub(info.LastRenewal); remaining > 0 {
+			info.RemainingTTL = remaining
+		}
+	}
+	if info.CachedOwned && info.KVOwner != "" && info.KVOwner != dMgr.coreInstanceID {
+		// The KV store is the authority; record the peer as the owner now, which cancels
+		// the local work the way any other ownership change does
+		ctxWarnw(ctx, "device-ownership-discrepancy", log.Fields{"device-id": deviceID, "kv-owner": info.KVOwner, "instance": dMgr.coreInstanceID})
+		if dMgr.interCoreProxy != nil {
+			dMgr.interCoreProxy.setDeviceOwner(deviceID, info.KVOwner)
+		}
+	}
+	return info, nil
+}
+
+// GetOwnershipInfo is the debug surface of the ownership reservation lookup
+func (handler *APIHandler) GetOwnershipInfo(ctx context.Context, deviceID string) (*ownershipInfo, error) {
+	ctxDebugw(ctx, "GetOwnershipInfo", log.Fields{"device-id": deviceID})
+	return handler.deviceMgr.getOwnershipInfo(ctx, deviceID)
+}
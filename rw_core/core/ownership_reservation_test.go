@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOwnershipTestDeviceManager returns the lifecycle fixture backed by an in-memory KV
+// client so the ownership reservations have somewhere to live
+func newOwnershipTestDeviceManager() (*DeviceManager, *fakeAdapterService) {
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.kvClient = newInMemoryKVClient()
+	dMgr.kvStorePrefix = "service/voltha"
+	return dMgr, fas
+}
+
+func TestOwnershipInfoOfOwnedDevice(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:09")
+
+	info, err := dMgr.getOwnershipInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dMgr.ownershipKey(device.Id), info.Key)
+	assert.Equal(t, "core-1", info.KVOwner)
+	assert.True(t, info.CachedOwned)
+	assert.False(t, info.LastRenewal.IsZero())
+	assert.True(t, info.RemainingTTL > 0)
+}
+
+func TestOwnershipDiscrepancyTriggersRecheck(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:0a")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	// A peer seized the reservation behind this instance's back
+	assert.Nil(t, dMgr.kvClient.Delete(ctx, dMgr.ownershipKey(device.Id)))
+	assert.Nil(t, dMgr.kvClient.Put(ctx, dMgr.ownershipKey(device.Id), "core-2"))
+
+	info, err := dMgr.getOwnershipInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "core-2", info.KVOwner)
+	assert.True(t, info.CachedOwned)
+
+	// The discrepancy was acted on, not just reported: the peer is now recorded as the
+	// owner and the local work was cancelled
+	assert.Equal(t, "core-2", dMgr.interCoreProxy.ownerOf(device.Id))
+	assert.True(t, agent.ownershipLost())
+	info, err = dMgr.getOwnershipInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.False(t, info.CachedOwned)
+}
+
+func TestOwnershipReleasedWithAgent(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _ := newOwnershipTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:0b")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	dMgr.deleteDeviceAgentFromMap(agent)
+	info, err := dMgr.getOwnershipInfo(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "", info.KVOwner)
+	assert.False(t, info.CachedOwned)
+	assert.True(t, info.LastRenewal.IsZero())
+}
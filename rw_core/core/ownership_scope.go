@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Every adapter request issued by a device agent runs under an ownership-scoped context.
+// When this core loses ownership of the device - a peer seized the reservation and now
+// manages it - that context is cancelled, aborting the in-flight requests and the work
+// queued behind them instead of letting them complete and write stale data over the new
+// owner's updates.  The affected operations return Aborted with a lost-ownership cause.
+
+// initOwnershipScope arms the ownership-scoped context of the agent
+func (agent *DeviceAgent) initOwnershipScope() {
+	agent.ownershipCtx, agent.cancelOwnership = context.WithCancel(context.Background())
+}
+
+// loseOwnership cancels all the in-flight and queued work of the agent.  It is invoked when
+// the core learns that a peer instance took over the device.
+func (agent *DeviceAgent) loseOwnership() {
+	ctxWarnw(context.Background(), "cancelling-device-work-on-ownership-loss", log.Fields{"device-id": agent.deviceID})
+	agent.cancelOwnership()
+}
+
+// ownershipLost returns whether this core lost ownership of the device
+func (agent *DeviceAgent) ownershipLost() bool {
+	return agent.ownershipCtx.Err() != nil
+}
+
+// lostOwnershipError is the error returned by operations aborted by an ownership loss
+func (agent *DeviceAgent) lostOwnershipError() error {
+	return status.Errorf(codes.Aborted, "lost-ownership-device-%s", agent.deviceID)
+}
+
+// adapterRequestContext returns the context under which an adapter request for this device
+// runs: bounded by the default timeout and cancelled when ownership of the device is lost
+func (agent *DeviceAgent) adapterRequestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(agent.ownershipCtx, agent.defaultTimeout)
+}
+
+// abortError maps the cancellation of an adapter request to the error surfaced to the
+// caller, distinguishing an ownership loss from an ordinary timeout or cancellation
+func (agent *DeviceAgent) abortError(err error) error {
+	if agent.ownershipLost() {
+		return agent.lostOwnershipError()
+	}
+	return err
+}
+
+// cancelDeviceWork cancels the in-flight and queued work for a device after its ownership
+// moved to a peer instance.  Only an agent already in memory is affected; nothing is loaded
+// from the KV store for a device this core no longer manages.
+func (dMgr *DeviceManager) cancelDeviceWork(deviceID string) {
+	if agent, ok := dMgr.deviceAgents.Load(deviceID); ok {
+		agent.(*DeviceAgent).loseOwnership()
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestOwnershipLossCancelsPendingFlowPush simulates a peer seizing the device reservation
+// while a flow push is held up in the adapter: the pending push must be aborted with a
+// lost-ownership cause instead of completing against the new owner's data
+func TestOwnershipLossCancelsPendingFlowPush(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	injector := newChaosInjector()
+	dMgr.adapterProxy = newChaosAdapterService(fas, injector)
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:02:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	// Activation must complete or the push below would be parked, not forwarded
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// Hold the flow push in the adapter so it is still pending when ownership moves
+	injector.addRule(&chaosRule{operation: "updateFlowsBulk", target: device.Id, timeout: true})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil)
+	}()
+
+	// Let the push reach the adapter, then let a peer seize the reservation
+	time.Sleep(100 * time.Millisecond)
+	dMgr.interCoreProxy.setDeviceOwner(device.Id, "core-2")
+
+	err := <-errCh
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "lost-ownership"))
+	// The adapter never saw the request; nothing stale was pushed to the new owner's device
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+}
+
+// TestOwnershipScopeLocalOwnerUnaffected verifies that recording this instance as the owner
+// does not cancel the device's work
+func TestOwnershipScopeLocalOwnerUnaffected(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:02:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	dMgr.interCoreProxy.setDeviceOwner(device.Id, dMgr.interCoreProxy.instanceID)
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	assert.NotNil(t, agent)
+	assert.False(t, agent.ownershipLost())
+
+	dMgr.interCoreProxy.setDeviceOwner(device.Id, "core-2")
+	assert.True(t, agent.ownershipLost())
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// Packets can arrive from the adapter while the logical device of a root device is still
+// being created; previously those packet-ins were dropped with only an error log.  They are
+// now buffered per device for a configurable window and replayed once the logical device is
+// ready.  When buffering is disabled a distinct retryable error is returned instead so the
+// adapter can resend.
+
+// maxPendingPacketIns bounds the number of packet-ins buffered per device; beyond it the
+// oldest packets are discarded
+const maxPendingPacketIns = 64
+
+type pendingPacketIn struct {
+	port          uint32
+	transactionID string
+	packet        []byte
+	received      time.Time
+}
+
+// bufferPacketIn queues a packet-in that arrived before the logical device was ready
+func (dMgr *DeviceManager) bufferPacketIn(deviceID string, port uint32, transactionID string, packet []byte) {
+	dMgr.pendingPacketInsLock.Lock()
+	defer dMgr.pendingPacketInsLock.Unlock()
+	pending := append(dMgr.pendingPacketIns[deviceID], pendingPacketIn{port: port, transactionID: transactionID, packet: packet, received: time.Now()})
+	if len(pending) > maxPendingPacketIns {
+		pending = pending[len(pending)-maxPendingPacketIns:]
+	}
+	dMgr.pendingPacketIns[deviceID] = pending
+	logger.Infow("packet-in-buffered", log.Fields{"deviceId": deviceID, "port": port, "pending": len(pending)})
+}
+
+// replayPendingPacketIns forwards the packet-ins buffered while the logical device of a
+// device was being created.  Packets that aged beyond the buffer window are discarded.
+func (dMgr *DeviceManager) replayPendingPacketIns(ctx context.Context, deviceID string, logicalDeviceID string) {
+	dMgr.pendingPacketInsLock.Lock()
+	pending := dMgr.pendingPacketIns[deviceID]
+	delete(dMgr.pendingPacketIns, deviceID)
+	dMgr.pendingPacketInsLock.Unlock()
+
+	now := time.Now()
+	for _, entry := range pending {
+		if now.Sub(entry.received) > dMgr.packetInBufferWindow {
+			logger.Debugw("discarding-expired-packet-in", log.Fields{"deviceId": deviceID, "port": entry.port})
+			continue
+		}
+		if err := dMgr.logicalDeviceMgr.packetIn(ctx, logicalDeviceID, entry.port, entry.transactionID, entry.packet); err != nil {
+			logger.Warnw("failed-to-replay-packet-in", log.Fields{"deviceId": deviceID, "port": entry.port, "error": err})
+		}
+	}
+	if len(pending) > 0 {
+		logger.Infow("replayed-buffered-packet-ins", log.Fields{"deviceId": deviceID, "count": len(pending)})
+	}
+}
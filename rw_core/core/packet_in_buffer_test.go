@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferPacketInBounded(t *testing.T) {
+	dMgr := &DeviceManager{
+		packetInBufferWindow: 5 * time.Second,
+		pendingPacketIns:     make(map[string][]pendingPacketIn),
+	}
+
+	for i := 0; i < maxPendingPacketIns+10; i++ {
+		dMgr.bufferPacketIn("1234", uint32(i), "", []byte{0x01})
+	}
+
+	pending := dMgr.pendingPacketIns["1234"]
+	assert.Equal(t, maxPendingPacketIns, len(pending))
+	// the oldest packets were discarded
+	assert.Equal(t, uint32(10), pending[0].port)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newPacketOutTestAgent returns a logical device agent carrying one NNI and one UNI logical
+// port, enough to exercise packet-out port validation
+func newPacketOutTestAgent() *LogicalDeviceAgent {
+	return &LogicalDeviceAgent{
+		logicalDeviceID: "ld-packet-out",
+		requestQueue:    coreutils.NewRequestQueue(),
+		logicalDevice: &voltha.LogicalDevice{Id: "ld-packet-out", Ports: []*voltha.LogicalPort{
+			{Id: "nni-1", DeviceId: "olt-1", DevicePortNo: 1, OfpPort: &voltha.OfpPort{PortNo: 0x100}},
+			{Id: "uni-0", DeviceId: "onu-1", DevicePortNo: 0, OfpPort: &voltha.OfpPort{PortNo: 0x1020}},
+		}},
+	}
+}
+
+func TestValidPacketOutPortAgainstLogicalPorts(t *testing.T) {
+	ctx := context.Background()
+	agent := newPacketOutTestAgent()
+
+	// Ports of the logical device are valid, whatever physical port number backs them
+	assert.True(t, agent.validPacketOutPort(ctx, 0x100))
+	assert.True(t, agent.validPacketOutPort(ctx, 0x1020))
+
+	// A physical port number that is not an OF port of the logical device is not
+	assert.False(t, agent.validPacketOutPort(ctx, 1))
+	assert.False(t, agent.validPacketOutPort(ctx, 0x9999))
+
+	// Reserved OpenFlow port numbers are not logical ports and always pass
+	assert.True(t, agent.validPacketOutPort(ctx, uint32(ofp.OfpPortNo_OFPP_CONTROLLER)))
+}
+
+func TestPacketOutToInvalidPortFails(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, fls := newLifecycleTestDeviceManager()
+	fls.agent = newPacketOutTestAgent()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:54")
+
+	// A packet-out to a known logical port reaches the adapter
+	assert.Nil(t, dMgr.packetOut(ctx, device.Id, 0x1020, &ofp.OfpPacketOut{}))
+	assert.Equal(t, 1, fas.callCount("packetOut"))
+
+	// One to an unknown port is rejected, counted and never forwarded
+	err := dMgr.packetOut(ctx, device.Id, 0x9999, &ofp.OfpPacketOut{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, fas.callCount("packetOut"))
+	assert.Equal(t, int64(1), dMgr.packetOutDropCount)
+
+	// Reserved port numbers are forwarded untouched
+	assert.Nil(t, dMgr.packetOut(ctx, device.Id, uint32(ofp.OfpPortNo_OFPP_CONTROLLER), &ofp.OfpPacketOut{}))
+	assert.Equal(t, 2, fas.callCount("packetOut"))
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Control-plane packet volumes per subscriber port are needed to detect EAPOL/DHCP storms
+// and to settle billing disputes.  The packet stats manager counts packet-ins and
+// packet-outs per device and port, keeps a rolling rate window in memory, raises a storm
+// event when the in-window rate crosses the threshold, and periodically checkpoints the
+// counters to the KV store so they survive agent eviction.  Counters are restored on a
+// best-effort basis and need not be exact across core restarts.
+const (
+	packetStatsPath               = "packet_stats"
+	packetStatsWindow             = time.Minute
+	packetStatsCheckpointInterval = 5 * time.Minute
+	// packetStormThreshold is the number of packet-ins within the window above which the
+	// storm event is raised; 0 disables the event
+	packetStormThreshold = 1000
+)
+
+// portPacketCounters accumulates the totals and the current rate window of one port
+type portPacketCounters struct {
+	packetIn    uint64
+	packetOut   uint64
+	windowStart time.Time
+	windowIn    uint64
+	windowOut   uint64
+	stormRaised bool
+}
+
+// PacketStats is a point-in-time snapshot of the counters of one port
+type PacketStats struct {
+	Port      uint32
+	PacketIn  uint64
+	PacketOut uint64
+}
+
+type packetStatsManager struct {
+	lock             sync.Mutex
+	counters         map[string]map[uint32]*portPacketCounters // device ID -> port -> counters
+	clusterDataProxy *model.Proxy
+	eventMgr         *EventManager
+}
+
+func newPacketStatsManager(cdProxy *model.Proxy, eventMgr *EventManager) *packetStatsManager {
+	return &packetStatsManager{
+		counters:         make(map[string]map[uint32]*portPacketCounters),
+		clusterDataProxy: cdProxy,
+		eventMgr:         eventMgr,
+	}
+}
+
+func (psm *packetStatsManager) countersFor(deviceID string, port uint32) *portPacketCounters {
+	ports, ok := psm.counters[deviceID]
+	if !ok {
+		ports = make(map[uint32]*portPacketCounters)
+		psm.counters[deviceID] = ports
+	}
+	counters, ok := ports[port]
+	if !ok {
+		counters = &portPacketCounters{windowStart: time.Now()}
+		ports[port] = counters
+	}
+	return counters
+}
+
+// rollWindow resets the rate window once it has elapsed
+func (counters *portPacketCounters) rollWindow(now time.Time) {
+	if now.Sub(counters.windowStart) >= packetStatsWindow {
+		counters.windowStart = now
+		counters.windowIn = 0
+		counters.windowOut = 0
+		counters.stormRaised = false
+	}
+}
+
+// recordPacketIn counts one packet-in and raises the storm event when the in-window volume
+// crosses the threshold
+func (psm *packetStatsManager) recordPacketIn(deviceID string, port uint32) {
+	psm.lock.Lock()
+	defer psm.lock.Unlock()
+	counters := psm.countersFor(deviceID, port)
+	counters.rollWindow(time.Now())
+	counters.packetIn++
+	counters.windowIn++
+	if packetStormThreshold > 0 && counters.windowIn >= packetStormThreshold && !counters.stormRaised {
+		counters.stormRaised = true
+		logger.Warnw("packet-in-storm-detected", log.Fields{"deviceId": deviceID, "port": port, "in-window": counters.windowIn})
+		go psm.eventMgr.publishDeviceEvent(&voltha.Device{Id: deviceID}, packetStormEvent, map[string]string{
+			"port":      strconv.FormatUint(uint64(port), 10),
+			"in-window": strconv.FormatUint(counters.windowIn, 10),
+			"window":    packetStatsWindow.String(),
+		})
+	}
+}
+
+// recordPacketOut counts one packet-out
+func (psm *packetStatsManager) recordPacketOut(deviceID string, port uint32) {
+	psm.lock.Lock()
+	defer psm.lock.Unlock()
+	counters := psm.countersFor(deviceID, port)
+	counters.rollWindow(time.Now())
+	counters.packetOut++
+	counters.windowOut++
+}
+
+// getPacketStats returns a snapshot of the counters of every port of a device
+func (psm *packetStatsManager) getPacketStats(deviceID string) []PacketStats {
+	psm.lock.Lock()
+	defer psm.lock.Unlock()
+	var stats []PacketStats
+	for port, counters := range psm.counters[deviceID] {
+		stats = append(stats, PacketStats{Port: port, PacketIn: counters.packetIn, PacketOut: counters.packetOut})
+	}
+	return stats
+}
+
+// encodePacketStats serializes the counters of one port.  The identifying fields are part of
+// the value as the KV keys are not recoverable on a List operation.
+func encodePacketStats(deviceID string, port uint32, packetIn, packetOut uint64) string {
+	return fmt.Sprintf("%s|%d|%d|%d", deviceID, port, packetIn, packetOut)
+}
+
+// decodePacketStats deserializes an entry produced by encodePacketStats
+func decodePacketStats(val string) (string, uint32, uint64, uint64, error) {
+	parts := strings.Split(val, "|")
+	if len(parts) != 4 {
+		return "", 0, 0, 0, newInvalidArgumentError("invalid-packet-stats")
+	}
+	port, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, 0, 0, err
+	}
+	packetIn, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, 0, 0, err
+	}
+	packetOut, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return "", 0, 0, 0, err
+	}
+	return parts[0], uint32(port), packetIn, packetOut, nil
+}
+
+// checkpoint persists the current totals so they survive agent eviction
+func (psm *packetStatsManager) checkpoint(ctx context.Context) {
+	psm.lock.Lock()
+	defer psm.lock.Unlock()
+	for deviceID, ports := range psm.counters {
+		for port, counters := range ports {
+			entry := &ic.StrType{Val: encodePacketStats(deviceID, port, counters.packetIn, counters.packetOut)}
+			if err := psm.clusterDataProxy.AddWithID(ctx, packetStatsPath, fmt.Sprintf("%s_%d", deviceID, port), entry); err != nil {
+				logger.Warnw("failed-to-checkpoint-packet-stats", log.Fields{"deviceId": deviceID, "port": port, "error": err})
+				return
+			}
+		}
+	}
+}
+
+// load restores the checkpointed totals; rate windows restart empty
+func (psm *packetStatsManager) load(ctx context.Context) {
+	var entries []*ic.StrType
+	if err := psm.clusterDataProxy.List(ctx, packetStatsPath, &entries); err != nil {
+		logger.Warnw("failed-to-load-packet-stats", log.Fields{"error": err})
+		return
+	}
+	psm.lock.Lock()
+	defer psm.lock.Unlock()
+	for _, entry := range entries {
+		deviceID, port, packetIn, packetOut, err := decodePacketStats(entry.Val)
+		if err != nil {
+			logger.Warnw("invalid-packet-stats-entry", log.Fields{"value": entry.Val, "error": err})
+			continue
+		}
+		counters := psm.countersFor(deviceID, port)
+		counters.packetIn = packetIn
+		counters.packetOut = packetOut
+	}
+}
+
+// run periodically checkpoints the counters until the device manager is stopped
+func (psm *packetStatsManager) run(ctx context.Context, exitChannel chan int) {
+	psm.load(ctx)
+	ticker := time.NewTicker(packetStatsCheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			psm.checkpoint(ctx)
+		case <-exitChannel:
+			logger.Info("packet-stats-manager-stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketStatsCounting(t *testing.T) {
+	psm := newPacketStatsManager(nil, nil)
+
+	psm.recordPacketIn("1234", 1)
+	psm.recordPacketIn("1234", 1)
+	psm.recordPacketOut("1234", 1)
+	psm.recordPacketIn("1234", 2)
+
+	stats := psm.getPacketStats("1234")
+	assert.Equal(t, 2, len(stats))
+	for _, portStats := range stats {
+		switch portStats.Port {
+		case 1:
+			assert.Equal(t, uint64(2), portStats.PacketIn)
+			assert.Equal(t, uint64(1), portStats.PacketOut)
+		case 2:
+			assert.Equal(t, uint64(1), portStats.PacketIn)
+			assert.Equal(t, uint64(0), portStats.PacketOut)
+		default:
+			t.Fatalf("unexpected port %d", portStats.Port)
+		}
+	}
+	assert.Empty(t, psm.getPacketStats("no-such-device"))
+}
+
+func TestPacketStatsWindowRoll(t *testing.T) {
+	counters := &portPacketCounters{windowStart: time.Now().Add(-2 * packetStatsWindow), windowIn: 42, stormRaised: true}
+	counters.rollWindow(time.Now())
+	assert.Equal(t, uint64(0), counters.windowIn)
+	assert.False(t, counters.stormRaised)
+}
+
+func TestPacketStatsRoundTrip(t *testing.T) {
+	deviceID, port, packetIn, packetOut, err := decodePacketStats(encodePacketStats("1234", 16, 100, 50))
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", deviceID)
+	assert.Equal(t, uint32(16), port)
+	assert.Equal(t, uint64(100), packetIn)
+	assert.Equal(t, uint64(50), packetOut)
+
+	_, _, _, _, err = decodePacketStats("not-a-stats-entry")
+	assert.NotNil(t, err)
+}
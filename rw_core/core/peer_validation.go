@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A child device must only ever be peered under the device recorded as its parent.  A botched
+// migration can leave an ONU appearing as a peer on ports of two different OLTs, after which
+// cascade operations act on it twice with conflicting parents.  Port additions that would
+// introduce such a peering are rejected here, and the startup audit flags violations already
+// present in the KV store.
+
+// validatePortPeers verifies that every peer entry on the port respects the recorded
+// parent/child relationships.  A child device may only peer with its recorded parent, and a
+// root device may only be peered with children that record it as their parent.  A violation
+// is reported as FailedPrecondition and raised as a device event.
+func (dMgr *DeviceManager) validatePortPeers(ctx context.Context, device *voltha.Device, port *voltha.Port) error {
+	for _, peer := range port.Peers {
+		if !device.Root && device.ParentId != "" && peer.DeviceId != device.ParentId {
+			dMgr.raiseDuplicatePeerParentEvent(device, device.Id, peer.DeviceId)
+			return status.Errorf(codes.FailedPrecondition,
+				"device-%s-peered-under-%s-but-parent-is-%s", device.Id, peer.DeviceId, device.ParentId)
+		}
+		if device.Root {
+			peerDevice, err := dMgr.GetDevice(ctx, peer.DeviceId)
+			if err != nil {
+				// The peer may not have been created yet; nothing to validate against
+				continue
+			}
+			if !peerDevice.Root && peerDevice.ParentId != "" && peerDevice.ParentId != device.Id {
+				dMgr.raiseDuplicatePeerParentEvent(device, peer.DeviceId, device.Id)
+				return status.Errorf(codes.FailedPrecondition,
+					"device-%s-peered-under-%s-but-parent-is-%s", peer.DeviceId, device.Id, peerDevice.ParentId)
+			}
+		}
+	}
+	return nil
+}
+
+// raiseDuplicatePeerParentEvent raises the device event describing a rejected or detected
+// peering of a child under a foreign parent
+func (dMgr *DeviceManager) raiseDuplicatePeerParentEvent(device *voltha.Device, childID string, offendingParentID string) {
+	if dMgr.eventMgr == nil {
+		return
+	}
+	dMgr.eventMgr.publishDeviceEvent(device, duplicatePeerParentEvent, map[string]string{
+		"child-device-id":     childID,
+		"offending-parent-id": offendingParentID,
+	})
+}
+
+// auditPeerParentage scans the devices persisted in the KV store for children peered under a
+// device other than their recorded parent.  Existing violations are flagged with a warning
+// and a device event; they are not repaired automatically.  It returns the detected
+// violations so it can also be exercised directly.
+func (dMgr *DeviceManager) auditPeerParentage(ctx context.Context) []string {
+	var devices []*voltha.Device
+	if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+		ctxErrorw(ctx, "failed-to-list-devices-for-peer-audit", log.Fields{"error": err})
+		return nil
+	}
+	parents := make(map[string]string, len(devices))
+	for _, device := range devices {
+		if !device.Root {
+			parents[device.Id] = device.ParentId
+		}
+	}
+	var violations []string
+	for _, device := range devices {
+		if !device.Root {
+			continue
+		}
+		for _, port := range device.Ports {
+			for _, peer := range port.Peers {
+				parentID, isChild := parents[peer.DeviceId]
+				if !isChild || parentID == "" || parentID == device.Id {
+					continue
+				}
+				ctxWarnw(ctx, "child-peered-under-foreign-parent", log.Fields{
+					"child-device-id":     peer.DeviceId,
+					"recorded-parent-id":  parentID,
+					"offending-parent-id": device.Id,
+					"port-no":             port.PortNo,
+				})
+				dMgr.raiseDuplicatePeerParentEvent(device, peer.DeviceId, device.Id)
+				violations = append(violations, peer.DeviceId)
+			}
+		}
+	}
+	return violations
+}
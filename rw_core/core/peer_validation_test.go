@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newPeerValidationFixture returns a device manager with an enabled OLT, a detected child
+// ONU of that OLT and a second, unrelated OLT
+func newPeerValidationFixture(t *testing.T) (*DeviceManager, *voltha.Device, *voltha.Device, *voltha.Device) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:01:01"}, ch)
+	parent, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: parent.Id}, ch)
+	assert.Nil(t, <-ch)
+
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-PEER", 1)
+	assert.Nil(t, err)
+
+	ch = make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:01:02"}, ch)
+	other, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	return dMgr, parent, child, other
+}
+
+func TestAddPortRejectsForeignParentPeer(t *testing.T) {
+	ctx := context.Background()
+	dMgr, parent, child, other := newPeerValidationFixture(t)
+
+	// The child may not be peered under an OLT that is not its recorded parent
+	err := dMgr.addPort(ctx, other.Id, &voltha.Port{
+		PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: child.Id, PortNo: 1}},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The child may not announce a port peered with a foreign parent either
+	err = dMgr.addPort(ctx, child.Id, &voltha.Port{
+		PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: other.Id, PortNo: 1}},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Peering under the recorded parent is accepted
+	assert.Nil(t, dMgr.addPort(ctx, child.Id, &voltha.Port{
+		PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: parent.Id, PortNo: 1}},
+	}))
+}
+
+func TestAuditPeerParentageFlagsExistingViolations(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, child, other := newPeerValidationFixture(t)
+
+	// A clean store reports no violations
+	assert.Equal(t, 0, len(dMgr.auditPeerParentage(ctx)))
+
+	// Plant a pre-existing violation directly through the device agent, bypassing the
+	// addPort validation, as a botched migration would have
+	agent := dMgr.getDeviceAgent(ctx, other.Id)
+	assert.NotNil(t, agent)
+	assert.Nil(t, agent.addPort(ctx, &voltha.Port{
+		PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: child.Id, PortNo: 1}},
+	}))
+
+	violations := dMgr.auditPeerParentage(ctx)
+	assert.Equal(t, []string{child.Id}, violations)
+}
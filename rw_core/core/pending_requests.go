@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// When an operation hangs, the first question support asks is what the core is waiting
+// on: which adapter request, sent when, against what deadline.  Every request going
+// through the adapter proxy is therefore registered while in flight and removed when its
+// response arrives.  A request that times out instead moves to a bounded record of recent
+// failures, so the evidence survives the timeout instead of vanishing with it.
+
+// maxTimedOutRequestRecords bounds the record of recently timed-out requests
+const maxTimedOutRequestRecords = 32
+
+// pendingAdapterRequest describes one in-flight request towards an adapter
+type pendingAdapterRequest struct {
+	messageID string
+	deviceID  string
+	rpc       string
+	sentAt    time.Time
+	// deadline is zero when the request context carries none
+	deadline time.Time
+	// origin and failure are set once the request failed
+	origin  errorOrigin
+	failure string
+}
+
+// pendingRequestRegistry tracks the in-flight adapter requests and remembers the ones
+// that failed, with the origin of each failure
+type pendingRequestRegistry struct {
+	lock     sync.RWMutex
+	pending  map[string]*pendingAdapterRequest
+	timedOut []*pendingAdapterRequest
+}
+
+func newPendingRequestRegistry() *pendingRequestRegistry {
+	return &pendingRequestRegistry{pending: make(map[string]*pendingAdapterRequest)}
+}
+
+// watch registers the request and returns a channel relaying the adapter responses; the
+// registry entry is cleaned up when the terminal response passes through
+func (reg *pendingRequestRegistry) watch(ctx context.Context, rpc string, deviceID string, responses chan *kafka.RpcResponse) chan *kafka.RpcResponse {
+	request := &pendingAdapterRequest{
+		messageID: uuid.New().String(),
+		deviceID:  deviceID,
+		rpc:       rpc,
+		sentAt:    time.Now(),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		request.deadline = deadline
+	}
+	reg.lock.Lock()
+	reg.pending[request.messageID] = request
+	reg.lock.Unlock()
+
+	relayed := make(chan *kafka.RpcResponse, 1)
+	go func() {
+		defer close(relayed)
+		for response := range responses {
+			switch response.MType {
+			case kafka.RpcTimeout:
+				response.Err = classifyError(originTransportTimeout, response.Err)
+				reg.recordFailure(request, originTransportTimeout, response.Err)
+			default:
+				if response.Err != nil {
+					// The adapter answered with an error: it actively rejected the request
+					response.Err = classifyError(originAdapterRejected, response.Err)
+					reg.recordFailure(request, originAdapterRejected, response.Err)
+				}
+				reg.remove(request.messageID)
+			}
+			relayed <- response
+		}
+		// The channel can close without a terminal message when the system shuts down
+		reg.remove(request.messageID)
+	}()
+	return relayed
+}
+
+// remove drops a pending entry once its response was seen
+func (reg *pendingRequestRegistry) remove(messageID string) {
+	reg.lock.Lock()
+	delete(reg.pending, messageID)
+	reg.lock.Unlock()
+}
+
+// recordTimeout moves a pending entry to the failure record, trimming the oldest
+// entries beyond the cap
+func (reg *pendingRequestRegistry) recordTimeout(request *pendingAdapterRequest) {
+	reg.recordFailure(request, originTransportTimeout, nil)
+}
+
+// recordFailure moves a pending entry to the failure record with its origin
+// classification, trimming the oldest entries beyond the cap
+func (reg *pendingRequestRegistry) recordFailure(request *pendingAdapterRequest, origin errorOrigin, cause error) {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	if _, have := reg.pending[request.messageID]; !have {
+		return
+	}
+	delete(reg.pending, request.messageID)
+	request.origin = origin
+	if cause != nil {
+		request.failure = cause.Error()
+	}
+	reg.timedOut = append(reg.timedOut, request)
+	if len(reg.timedOut) > maxTimedOutRequestRecords {
+		reg.timedOut = reg.timedOut[len(reg.timedOut)-maxTimedOutRequestRecords:]
+	}
+	ctxWarnw(context.Background(), "adapter-request-failed", log.Fields{
+		"device-id": request.deviceID, "rpc": request.rpc, "message-id": request.messageID,
+		"origin": origin, "sent-at": request.sentAt, "deadline": request.deadline})
+}
+
+// listPending returns copies of the in-flight requests, all of them when deviceID is empty
+func (reg *pendingRequestRegistry) listPending(deviceID string) []pendingAdapterRequest {
+	reg.lock.RLock()
+	defer reg.lock.RUnlock()
+	requests := make([]pendingAdapterRequest, 0, len(reg.pending))
+	for _, request := range reg.pending {
+		if deviceID == "" || request.deviceID == deviceID {
+			requests = append(requests, *request)
+		}
+	}
+	return requests
+}
+
+// listTimedOut returns copies of the recently timed-out requests, oldest first
+func (reg *pendingRequestRegistry) listTimedOut() []pendingAdapterRequest {
+	reg.lock.RLock()
+	defer reg.lock.RUnlock()
+	requests := make([]pendingAdapterRequest, 0, len(reg.timedOut))
+	for _, request := range reg.timedOut {
+		requests = append(requests, *request)
+	}
+	return requests
+}
+
+// listPendingAdapterRequests is the debug query behind "what is the core waiting on for
+// this device"; an empty device ID lists every in-flight request
+func (dMgr *DeviceManager) listPendingAdapterRequests(deviceID string) []pendingAdapterRequest {
+	if dMgr.pendingRequests == nil {
+		return nil
+	}
+	return dMgr.pendingRequests.listPending(deviceID)
+}
+
+// listTimedOutAdapterRequests returns the recent requests that expired without a response
+func (dMgr *DeviceManager) listTimedOutAdapterRequests() []pendingAdapterRequest {
+	if dMgr.pendingRequests == nil {
+		return nil
+	}
+	return dMgr.pendingRequests.listTimedOut()
+}
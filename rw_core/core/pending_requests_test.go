@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gatedICProxy acks the send immediately but holds the terminal response until the test
+// releases it, keeping the request in flight for as long as the test needs
+type gatedICProxy struct {
+	mock_kafka.MockKafkaICProxy
+	release chan *kafka.RpcResponse
+}
+
+func (p *gatedICProxy) InvokeAsyncRPC(ctx context.Context, rpc string, toTopic *kafka.Topic, replyToTopic *kafka.Topic,
+	waitForResponse bool, key string, kvArgs ...*kafka.KVArg) chan *kafka.RpcResponse {
+	ch := make(chan *kafka.RpcResponse, 2)
+	ch <- &kafka.RpcResponse{MType: kafka.RpcSent}
+	go func() {
+		defer close(ch)
+		ch <- <-p.release
+	}()
+	return ch
+}
+
+func newPendingRequestFixture() (*AdapterProxy, *DeviceManager, *gatedICProxy) {
+	proxy := &gatedICProxy{release: make(chan *kafka.RpcResponse, 1)}
+	ap := &AdapterProxy{kafkaICProxy: proxy, corePairTopic: "core-pair", pendingRequests: newPendingRequestRegistry()}
+	dMgr := &DeviceManager{pendingRequests: ap.pendingRequests}
+	return ap, dMgr, proxy
+}
+
+func TestPendingRequestVisibleWhileInFlight(t *testing.T) {
+	ap, dMgr, proxy := newPendingRequestFixture()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ch, err := ap.disableDevice(ctx, &voltha.Device{Id: "olt-1", Adapter: "adapter-1"})
+	assert.Nil(t, err)
+
+	// While the adapter has not answered, the registry tells what the core waits on
+	pending := dMgr.listPendingAdapterRequests("olt-1")
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, "disable_device", pending[0].rpc)
+	assert.NotEqual(t, "", pending[0].messageID)
+	assert.False(t, pending[0].sentAt.IsZero())
+	assert.False(t, pending[0].deadline.IsZero())
+	assert.Equal(t, 0, len(dMgr.listPendingAdapterRequests("other-device")))
+	assert.Equal(t, 1, len(dMgr.listPendingAdapterRequests("")))
+
+	// The response clears the entry before it reaches the caller
+	proxy.release <- &kafka.RpcResponse{MType: kafka.RpcReply}
+	response := <-ch
+	assert.Equal(t, kafka.RpcReply, response.MType)
+	assert.Equal(t, 0, len(dMgr.listPendingAdapterRequests("")))
+	assert.Equal(t, 0, len(dMgr.listTimedOutAdapterRequests()))
+}
+
+func TestTimedOutRequestMovesToFailureRecord(t *testing.T) {
+	ap, dMgr, proxy := newPendingRequestFixture()
+
+	ch, err := ap.rebootDevice(context.Background(), &voltha.Device{Id: "olt-2", Adapter: "adapter-1"})
+	assert.Nil(t, err)
+	proxy.release <- &kafka.RpcResponse{MType: kafka.RpcTimeout, Err: status.Error(codes.DeadlineExceeded, "timeout")}
+	response := <-ch
+	assert.Equal(t, kafka.RpcTimeout, response.MType)
+
+	// The entry did not vanish with the timeout; it moved to the failure record
+	assert.Equal(t, 0, len(dMgr.listPendingAdapterRequests("")))
+	timedOut := dMgr.listTimedOutAdapterRequests()
+	assert.Equal(t, 1, len(timedOut))
+	assert.Equal(t, "olt-2", timedOut[0].deviceID)
+	assert.Equal(t, "reboot_device", timedOut[0].rpc)
+}
+
+func TestTimedOutRecordIsBounded(t *testing.T) {
+	reg := newPendingRequestRegistry()
+	for i := 0; i < maxTimedOutRequestRecords+5; i++ {
+		request := &pendingAdapterRequest{messageID: CreateDeviceID(), deviceID: "olt-3", rpc: "reboot_device", sentAt: time.Now()}
+		reg.lock.Lock()
+		reg.pending[request.messageID] = request
+		reg.lock.Unlock()
+		reg.recordTimeout(request)
+	}
+	assert.Equal(t, maxTimedOutRequestRecords, len(reg.listTimedOut()))
+}
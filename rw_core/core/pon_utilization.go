@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// NOC dashboards poll for per-PON-port occupancy of an OLT: how many ONUs hang off each
+// port, how many of them are active or unreachable, and how many flows they carry.
+// getPonPortSummary aggregates that from the in-memory device agents - no per-device KV
+// reads - grouping the children of the parent by ParentPortNo, so it is cheap enough to
+// be called every few seconds.
+
+// PonPortSummary aggregates the children of one PON port of an OLT
+type PonPortSummary struct {
+	PortNo          uint32
+	OnusProvisioned int
+	OnusActive      int
+	OnusUnreachable int
+	FlowsInstalled  int
+}
+
+// getPonPortSummary returns the per-PON-port aggregates of the children of a parent
+// device, keyed by the parent port number.  Deleted children are not counted.
+func (dMgr *DeviceManager) getPonPortSummary(ctx context.Context, parentDeviceID string) (map[uint32]*PonPortSummary, error) {
+	if _, ok := dMgr.deviceAgents.Load(parentDeviceID); !ok {
+		return nil, newNotFoundError("device", parentDeviceID)
+	}
+	summaries := make(map[uint32]*PonPortSummary)
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		if agent.parentID != parentDeviceID {
+			return true
+		}
+		device, err := agent.getDevice(ctx)
+		if err != nil {
+			ctxWarnw(ctx, "skipping-child-in-pon-summary", log.Fields{"device-id": agent.deviceID, "error": err})
+			return true
+		}
+		if device.AdminState == voltha.AdminState_DELETED {
+			return true
+		}
+		summary, have := summaries[device.ParentPortNo]
+		if !have {
+			summary = &PonPortSummary{PortNo: device.ParentPortNo}
+			summaries[device.ParentPortNo] = summary
+		}
+		summary.OnusProvisioned++
+		if device.OperStatus == voltha.OperStatus_ACTIVE {
+			summary.OnusActive++
+		}
+		if device.ConnectStatus == voltha.ConnectStatus_UNREACHABLE {
+			summary.OnusUnreachable++
+		}
+		if device.Flows != nil {
+			summary.FlowsInstalled += len(device.Flows.Items)
+		}
+		return true
+	})
+	return summaries, nil
+}
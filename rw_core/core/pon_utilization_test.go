@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPonPortSummary(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:40:01")
+
+	_, err := dMgr.getPonPortSummary(ctx, "no-such-device")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	c1, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-U1", 1)
+	assert.Nil(t, err)
+	c2, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-U2", 2)
+	assert.Nil(t, err)
+	_, err = dMgr.childDeviceDetected(ctx, parent.Id, 2, "onu", 2, "VEND", "SERIAL-U3", 3)
+	assert.Nil(t, err)
+
+	// Wait for the auto-enable so the status updates below are not raced by it
+	waitUntil(t, 2*time.Second, "children were not auto-enabled", func() bool {
+		for _, id := range []string{c1.Id, c2.Id} {
+			device, err := dMgr.GetDevice(ctx, id)
+			if err != nil || device.AdminState != voltha.AdminState_ENABLED {
+				return false
+			}
+		}
+		return true
+	})
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, c1.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, c2.Id, voltha.OperStatus_UNKNOWN, voltha.ConnectStatus_UNREACHABLE))
+	assert.Nil(t, dMgr.addFlowsAndGroups(ctx, c1.Id, []*ofp.OfpFlowStats{{Id: 1}, {Id: 2, Priority: 1}}, nil, nil))
+
+	summaries, err := dMgr.getPonPortSummary(ctx, parent.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(summaries))
+	assert.Equal(t, 2, summaries[1].OnusProvisioned)
+	assert.Equal(t, 1, summaries[1].OnusActive)
+	assert.Equal(t, 1, summaries[1].OnusUnreachable)
+	assert.Equal(t, 2, summaries[1].FlowsInstalled)
+	assert.Equal(t, 1, summaries[2].OnusProvisioned)
+	assert.Equal(t, 0, summaries[2].OnusActive)
+
+	// The parent itself has no children registered under it elsewhere
+	assert.Equal(t, uint32(1), summaries[1].PortNo)
+}
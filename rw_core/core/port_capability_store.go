@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// The port capability an adapter reports carries the OF features (advertised, peer,
+// current, supported) of the port.  It used to be consumed once during logical port
+// creation and then dropped, so a later rebuild of a logical port had no features to
+// advertise to ONOS.  Every fetched capability is now persisted per physical port and
+// serves as the fallback source when the adapter cannot be reached, and a capability
+// refresh folds new features into the existing logical port and notifies the controller.
+const portCapabilitiesPath = "port_capabilities"
+
+// portCapabilityID is the KV entry ID of the stored capability of one physical port
+func portCapabilityID(deviceID string, portNo uint32) string {
+	return fmt.Sprintf("%s_%d", deviceID, portNo)
+}
+
+// storePortCapability persists the port description fetched from the adapter
+func (dMgr *DeviceManager) storePortCapability(ctx context.Context, deviceID string, portNo uint32, lp *voltha.LogicalPort) {
+	cloned := proto.Clone(lp).(*voltha.LogicalPort)
+	if err := dMgr.clusterDataProxy.AddWithID(ctx, portCapabilitiesPath, portCapabilityID(deviceID, portNo), cloned); err != nil {
+		ctxWarnw(ctx, "failed-to-persist-port-capability", log.Fields{"device-id": deviceID, "port-no": portNo, "error": err})
+	}
+}
+
+// loadPortCapability returns the last persisted port description of a physical port
+func (dMgr *DeviceManager) loadPortCapability(ctx context.Context, deviceID string, portNo uint32) (*voltha.LogicalPort, bool) {
+	lp := &voltha.LogicalPort{}
+	found, err := dMgr.clusterDataProxy.Get(ctx, portCapabilitiesPath+"/"+portCapabilityID(deviceID, portNo), lp)
+	if err != nil || !found {
+		return nil, false
+	}
+	return lp, true
+}
+
+// refreshPortCapabilities re-fetches the port capabilities of every port of a device from
+// its adapter, persists them and folds the refreshed OF features into the corresponding
+// logical ports, which notifies the controller with a PORT_STATUS modify.  It is meant to
+// run after an event that may have changed the port hardware capabilities, such as a
+// software image update.
+func (dMgr *DeviceManager) refreshPortCapabilities(ctx context.Context, deviceID string) error {
+	ctxInfow(ctx, "refreshing-port-capabilities", log.Fields{"device-id": deviceID})
+	device, err := dMgr.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	for _, port := range device.Ports {
+		if port.Type != voltha.Port_ETHERNET_NNI && port.Type != voltha.Port_ETHERNET_UNI {
+			continue
+		}
+		portCap, err := dMgr.getPortCapability(ctx, deviceID, port.PortNo)
+		if err != nil {
+			ctxWarnw(ctx, "failed-to-refresh-port-capability", log.Fields{"device-id": deviceID, "port-no": port.PortNo, "error": err})
+			continue
+		}
+		if err := dMgr.logicalDeviceMgr.updateLogicalPortFeatures(ctx, device, port, portCap.Port.OfpPort); err != nil {
+			ctxWarnw(ctx, "failed-to-update-logical-port-features", log.Fields{"device-id": deviceID, "port-no": port.PortNo, "error": err})
+		}
+	}
+	return nil
+}
+
+// updateLogicalPortFeatures folds refreshed OF features into the logical port backing a
+// physical port
+func (ldMgr *LogicalDeviceManager) updateLogicalPortFeatures(ctx context.Context, device *voltha.Device, port *voltha.Port, ofpPort *ofp.OfpPort) error {
+	ldID, err := ldMgr.getLogicalDeviceID(ctx, device)
+	if err != nil || *ldID == "" {
+		// The logical device may not exist yet; the features will flow in with the port creation
+		return nil
+	}
+	if agent := ldMgr.getLogicalDeviceAgent(ctx, *ldID); agent != nil {
+		return agent.updatePortFeatures(ctx, device.Id, port.PortNo, ofpPort)
+	}
+	return nil
+}
+
+// updatePortFeatures replaces the OF features of the logical port backing the physical
+// port and saves the logical device, which raises a PORT_STATUS modify towards the
+// controller
+func (agent *LogicalDeviceAgent) updatePortFeatures(ctx context.Context, deviceID string, devicePortNo uint32, ofpPort *ofp.OfpPort) error {
+	if ofpPort == nil {
+		return nil
+	}
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+
+	ldevice := agent.getLogicalDeviceWithoutLock()
+	cloned := clonePorts(ldevice.Ports)
+	for _, lPort := range cloned {
+		if lPort.DeviceId != deviceID || lPort.DevicePortNo != devicePortNo {
+			continue
+		}
+		lPort.OfpPort.Curr = ofpPort.Curr
+		lPort.OfpPort.Advertised = ofpPort.Advertised
+		lPort.OfpPort.Supported = ofpPort.Supported
+		lPort.OfpPort.Peer = ofpPort.Peer
+		lPort.OfpPort.CurrSpeed = ofpPort.CurrSpeed
+		lPort.OfpPort.MaxSpeed = ofpPort.MaxSpeed
+		return agent.updateLogicalDevicePortsWithoutLock(ctx, ldevice, cloned)
+	}
+	return newNotFoundError("logical-port", portCapabilityID(deviceID, devicePortNo))
+}
+
+// getPortCapabilityWithFallback retrieves the port capability from the adapter, persisting
+// a successful answer, and falls back to the stored capability when the adapter cannot
+// provide one
+func (dMgr *DeviceManager) getPortCapabilityWithFallback(ctx context.Context, agent *DeviceAgent, deviceID string, portNo uint32) (*ic.PortCapability, error) {
+	portCap, err := agent.getPortCapability(ctx, portNo)
+	if err == nil && portCap.Port != nil {
+		dMgr.storePortCapability(ctx, deviceID, portNo, portCap.Port)
+		return portCap, nil
+	}
+	if stored, found := dMgr.loadPortCapability(ctx, deviceID, portNo); found {
+		ctxWarnw(ctx, "serving-port-capability-from-store", log.Fields{"device-id": deviceID, "port-no": portNo, "error": err})
+		return &ic.PortCapability{Port: stored}, nil
+	}
+	return portCap, err
+}
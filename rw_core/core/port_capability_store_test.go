@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// portCapAdapter serves getOfpPortInfo with a real port capability payload and can be
+// switched to fail, exercising the stored-capability fallback
+type portCapAdapter struct {
+	*fakeAdapterService
+	capability *voltha.LogicalPort
+	fail       bool
+}
+
+func (pca *portCapAdapter) getOfpPortInfo(ctx context.Context, device *voltha.Device, portNo uint32) (chan *kafka.RpcResponse, error) {
+	if _, err := pca.fakeAdapterService.getOfpPortInfo(ctx, device, portNo); err != nil {
+		return nil, err
+	}
+	ch := make(chan *kafka.RpcResponse, 1)
+	if pca.fail {
+		ch <- &kafka.RpcResponse{MType: kafka.RpcReply, Err: newUnavailableError("getOfpPortInfo", device.Id, nil)}
+		return ch, nil
+	}
+	reply, err := ptypes.MarshalAny(&ic.PortCapability{Port: pca.capability})
+	if err != nil {
+		return nil, err
+	}
+	ch <- &kafka.RpcResponse{MType: kafka.RpcReply, Reply: reply}
+	return ch, nil
+}
+
+func newTestPortCapability() *voltha.LogicalPort {
+	return &voltha.LogicalPort{
+		Id: "nni-1",
+		OfpPort: &ofp.OfpPort{
+			PortNo:     1,
+			Curr:       uint32(ofp.OfpPortFeatures_OFPPF_1GB_FD),
+			Advertised: uint32(ofp.OfpPortFeatures_OFPPF_1GB_FD | ofp.OfpPortFeatures_OFPPF_10GB_FD),
+			Supported:  uint32(ofp.OfpPortFeatures_OFPPF_10GB_FD),
+			CurrSpeed:  1000000,
+			MaxSpeed:   10000000,
+		},
+	}
+}
+
+func TestGetPortCapabilityPersistsAndFallsBack(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	adapter := &portCapAdapter{fakeAdapterService: fas, capability: newTestPortCapability()}
+	dMgr.adapterProxy = adapter
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:05:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+
+	// The fetched capability is persisted alongside the physical port
+	portCap, err := dMgr.getPortCapability(ctx, device.Id, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(ofp.OfpPortFeatures_OFPPF_1GB_FD), portCap.Port.OfpPort.Curr)
+	stored, found := dMgr.loadPortCapability(ctx, device.Id, 1)
+	assert.True(t, found)
+	assert.Equal(t, portCap.Port.OfpPort.Advertised, stored.OfpPort.Advertised)
+
+	// When the adapter cannot answer, the stored capability is served instead
+	adapter.fail = true
+	portCap, err = dMgr.getPortCapability(ctx, device.Id, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(ofp.OfpPortFeatures_OFPPF_10GB_FD), portCap.Port.OfpPort.Supported)
+}
+
+func TestRefreshPortCapabilitiesUpdatesLogicalPorts(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, fls := newLifecycleTestDeviceManager()
+	adapter := &portCapAdapter{fakeAdapterService: fas, capability: newTestPortCapability()}
+	dMgr.adapterProxy = adapter
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:05:02"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "nni-1", Type: voltha.Port_ETHERNET_NNI}))
+
+	assert.Nil(t, dMgr.refreshPortCapabilities(ctx, device.Id))
+	assert.Equal(t, 1, fls.callCount("updateLogicalPortFeatures"))
+}
+
+func TestUpdatePortFeaturesNotifiesController(t *testing.T) {
+	ctx := context.Background()
+	changeEvents := make(chan ofp.ChangeEvent, 1)
+	agent := &LogicalDeviceAgent{
+		logicalDeviceID:  "ld-features",
+		clusterDataProxy: newInMemoryClusterProxy(),
+		requestQueue:     coreutils.NewRequestQueue(),
+		ldeviceMgr:       &LogicalDeviceManager{grpcNbiHdlr: &APIHandler{changeEventQueue: changeEvents}},
+		logicalDevice: &voltha.LogicalDevice{
+			Id: "ld-features",
+			Ports: []*voltha.LogicalPort{
+				{Id: "nni-1", DeviceId: "olt-1", DevicePortNo: 1, OfpPort: &ofp.OfpPort{PortNo: 1}},
+			},
+		},
+	}
+
+	refreshed := newTestPortCapability().OfpPort
+	assert.Nil(t, agent.updatePortFeatures(ctx, "olt-1", 1, refreshed))
+	assert.Equal(t, refreshed.Advertised, agent.logicalDevice.Ports[0].OfpPort.Advertised)
+
+	// The controller is notified of the modified port
+	select {
+	case event := <-changeEvents:
+		portStatus := event.GetPortStatus()
+		assert.NotNil(t, portStatus)
+		assert.Equal(t, ofp.OfpPortReason_OFPPR_MODIFY, portStatus.Reason)
+		assert.Equal(t, refreshed.Advertised, portStatus.Desc.Advertised)
+	case <-time.After(2 * time.Second):
+		t.Error("no-change-event-received")
+	}
+
+	// An unknown port is reported
+	assert.NotNil(t, agent.updatePortFeatures(ctx, "olt-1", 99, refreshed))
+}
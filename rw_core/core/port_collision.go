@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A buggy adapter occasionally announces two different ports with the same PortNo on one
+// device.  The append-based port storage used to keep both, leaving the downstream
+// lookups - which assume the port number identifies the port - to behave unpredictably.
+// addPort now rejects such an announcement with AlreadyExists naming both ports and
+// raises an event so the adapter bug is visible, and the consistency audit reports
+// collisions already sitting in the KV store.  A collision is never auto-repaired: the
+// core cannot know which of the two ports the hardware actually has.
+
+// conflictingPortIdentity reports whether two ports of the same type and number describe
+// different things; an empty label carries no identity and never conflicts
+func conflictingPortIdentity(existing *voltha.Port, candidate *voltha.Port) bool {
+	return existing.Label != "" && candidate.Label != "" && existing.Label != candidate.Label
+}
+
+// describePort renders the identity of a port for collision messages
+func describePort(port *voltha.Port) string {
+	return fmt.Sprintf("%s(label=%q)", port.Type, port.Label)
+}
+
+// rejectPortCollision raises the collision event and returns the AlreadyExists status
+// naming both the existing and the rejected port
+func (agent *DeviceAgent) rejectPortCollision(ctx context.Context, device *voltha.Device, existing *voltha.Port, rejected *voltha.Port) error {
+	ctxWarnw(ctx, "port-number-collision", log.Fields{
+		"device-id":     agent.deviceID,
+		"port-no":       rejected.PortNo,
+		"existing-port": describePort(existing),
+		"rejected-port": describePort(rejected),
+	})
+	if agent.deviceMgr != nil && agent.deviceMgr.eventMgr != nil {
+		go agent.deviceMgr.eventMgr.publishDeviceEvent(device, portCollisionEvent, map[string]string{
+			"port-no":       fmt.Sprintf("%d", rejected.PortNo),
+			"existing-port": describePort(existing),
+			"rejected-port": describePort(rejected),
+		})
+	}
+	return status.Errorf(codes.AlreadyExists, "port-no-%d-collision-on-%s: existing %s, rejected %s",
+		rejected.PortNo, agent.deviceID, describePort(existing), describePort(rejected))
+}
+
+// portCollisionsOf returns one descriptor per port number of the device carried by two
+// conflicting ports
+func portCollisionsOf(device *voltha.Device) []string {
+	var findings []string
+	seen := make(map[uint32]*voltha.Port, len(device.Ports))
+	for _, port := range device.Ports {
+		existing, have := seen[port.PortNo]
+		if !have {
+			seen[port.PortNo] = port
+			continue
+		}
+		if existing.Type != port.Type || conflictingPortIdentity(existing, port) {
+			findings = append(findings, fmt.Sprintf("%s:%d: %s vs %s",
+				device.Id, port.PortNo, describePort(existing), describePort(port)))
+		}
+	}
+	return findings
+}
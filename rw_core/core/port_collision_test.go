@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAddPortRejectsCollision(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:07")
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Type: voltha.Port_ETHERNET_NNI, Label: "nni-1"}))
+
+	// Re-announcing the same port stays idempotent
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Type: voltha.Port_ETHERNET_NNI, Label: "nni-1"}))
+
+	// The same number with a different type is rejected, naming both ports
+	err := dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Type: voltha.Port_ETHERNET_UNI, Label: "uni-1"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "ETHERNET_NNI"))
+	assert.True(t, strings.Contains(err.Error(), "ETHERNET_UNI"))
+
+	// So is the same number and type under a different identity
+	err = dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Type: voltha.Port_ETHERNET_NNI, Label: "nni-x"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "nni-1"))
+	assert.True(t, strings.Contains(err.Error(), "nni-x"))
+
+	// Only the original port was kept
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stored.Ports))
+	assert.Equal(t, "nni-1", stored.Ports[0].Label)
+}
+
+func TestAuditDetectsPortCollisions(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := createTestDevice(t, dMgr, "00:00:00:00:4a:08")
+
+	// A collision already sitting in the KV store - persisted before the addPort guard
+	// existed - is reported by the audit but never auto-repaired
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	cloned := shallowCopyDevice(agent.device)
+	cloned.Ports = []*voltha.Port{
+		{PortNo: 1, Type: voltha.Port_ETHERNET_NNI, Label: "nni-1"},
+		{PortNo: 1, Type: voltha.Port_PON_OLT, Label: "pon-1"},
+	}
+	assert.Nil(t, dMgr.clusterDataProxy.Update(ctx, "devices/"+device.Id, cloned))
+
+	report := dMgr.auditDeviceAgents(ctx)
+	assert.Equal(t, 1, len(report.PortCollisions))
+	assert.True(t, strings.Contains(report.PortCollisions[0], device.Id))
+	assert.Equal(t, 0, report.Repaired)
+}
+
+func TestPortCollisionsOf(t *testing.T) {
+	device := &voltha.Device{Id: "d1", Ports: []*voltha.Port{
+		{PortNo: 1, Type: voltha.Port_ETHERNET_NNI, Label: "nni-1"},
+		{PortNo: 2, Type: voltha.Port_PON_OLT, Label: "pon-1"},
+		// a duplicate announcement of the same port is not a collision
+		{PortNo: 2, Type: voltha.Port_PON_OLT, Label: "pon-1"},
+		{PortNo: 1, Type: voltha.Port_ETHERNET_UNI, Label: "uni-1"},
+	}}
+	findings := portCollisionsOf(device)
+	assert.Equal(t, 1, len(findings))
+	assert.True(t, strings.Contains(findings[0], "d1:1"))
+}
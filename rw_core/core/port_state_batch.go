@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// An OLT adapter rediscovering its state after a restart reports the state of every port
+// it knows, which used to translate into one PortStateUpdate message and one model write
+// per port.  The batch path below applies an entire set of port state changes in a single
+// device agent transaction, folds the NNI and UNI deltas into the logical device in one
+// pass and lets the resulting single ports update raise the change events towards the
+// controller.  The single-port entry points now delegate to the batch implementation.
+
+// portStateChange is one entry of a bulk port state update
+type portStateChange struct {
+	portType   voltha.Port_PortType
+	portNo     uint32
+	operStatus voltha.OperStatus_Types
+}
+
+// updatePortsStates applies a batch of port state changes on a device in one agent
+// transaction and notifies the logical device manager of all NNI and UNI changes at once
+func (dMgr *DeviceManager) updatePortsStates(ctx context.Context, deviceID string, changes []portStateChange) error {
+	ctxDebugw(ctx, "updatePortsStates", log.Fields{"device-id": deviceID, "changes": len(changes)})
+	if len(changes) == 0 {
+		return nil
+	}
+	agent := dMgr.getDeviceAgent(ctx, deviceID)
+	if agent == nil {
+		return newNotFoundError("device", deviceID)
+	}
+	if err := agent.updatePortStates(ctx, changes); err != nil {
+		ctxErrorw(ctx, "updating-port-states-failed", log.Fields{"device-id": deviceID, "error": err})
+		return err
+	}
+	// Notify the logical device manager of the state changes.  Do this for NNI and UNIs
+	// only as PON ports are not known by the logical device.
+	logicalChanges := make([]portStateChange, 0, len(changes))
+	for _, change := range changes {
+		if change.portType == voltha.Port_ETHERNET_NNI || change.portType == voltha.Port_ETHERNET_UNI {
+			logicalChanges = append(logicalChanges, change)
+		}
+	}
+	if len(logicalChanges) > 0 {
+		go func() {
+			err := dMgr.logicalDeviceMgr.updateLogicalPortStates(context.Background(), deviceID, logicalChanges)
+			if err != nil {
+				// While we want to handle (catch) and log when
+				// an update to a port was not able to be
+				// propagated to the logical port, we can report
+				// it as a warning and not an error because it
+				// doesn't stop or modify processing.
+				// TODO: VOL-2707
+				ctxWarnw(context.Background(), "unable-to-update-logical-port-states", log.Fields{"device-id": deviceID, "error": err})
+			}
+		}()
+	}
+	return nil
+}
+
+// updatePortStates applies all the port state changes on the latest device data and
+// stores the device once
+func (agent *DeviceAgent) updatePortStates(ctx context.Context, changes []portStateChange) error {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	// Work only on latest data; only the ports change, so copy them and share the rest
+	// of the device instead of cloning its flow table
+	cloned := shallowCopyDevice(agent.device)
+	copyPortsOf(cloned)
+
+	// Ensure the enums passed in are valid - they will be invalid if they are not set when this function is invoked
+	for _, change := range changes {
+		if _, ok := voltha.Port_PortType_value[change.portType.String()]; !ok {
+			return status.Errorf(codes.InvalidArgument, "%s", change.portType)
+		}
+	}
+	for _, change := range changes {
+		for _, port := range cloned.Ports {
+			if port.Type == change.portType && port.PortNo == change.portNo {
+				port.OperStatus = change.operStatus
+			}
+		}
+	}
+	ctxDebugw(ctx, "portsStatusUpdate", log.Fields{"device-id": cloned.Id, "changes": len(changes)})
+	// Store the device
+	return agent.storeDeviceOwnedWithoutLock(ctx, cloned)
+}
+
+// updateLogicalPortStates folds a batch of port state changes into the logical device
+// backing the device
+func (ldMgr *LogicalDeviceManager) updateLogicalPortStates(ctx context.Context, deviceID string, changes []portStateChange) error {
+	ctxDebugw(ctx, "updateLogicalPortStates", log.Fields{"device-id": deviceID, "changes": len(changes)})
+
+	var ldID *string
+	var err error
+	//Get the logical device Id for this device
+	if ldID, err = ldMgr.getLogicalDeviceIDFromDeviceID(ctx, deviceID); err != nil {
+		ctxWarnw(ctx, "no-logical-device-found", log.Fields{"device-id": deviceID, "error": err})
+		return err
+	}
+	if agent := ldMgr.getLogicalDeviceAgent(ctx, *ldID); agent != nil {
+		return agent.updatePortStates(ctx, deviceID, changes)
+	}
+	return nil
+}
+
+// updatePortStates applies all the port state changes on the logical ports of a device
+// and saves the logical device once, which computes the port deltas in a single pass and
+// raises the change events towards the controller
+func (agent *LogicalDeviceAgent) updatePortStates(ctx context.Context, deviceID string, changes []portStateChange) error {
+	ctxInfow(ctx, "updatePortStates-start", log.Fields{"logical-device-id": agent.logicalDeviceID, "changes": len(changes)})
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return err
+	}
+	defer agent.requestQueue.RequestComplete()
+	// Get the latest logical device info
+	original := agent.getLogicalDeviceWithoutLock()
+	updatedPorts := clonePorts(original.Ports)
+	matched := 0
+	for _, change := range changes {
+		for _, port := range updatedPorts {
+			if port.DeviceId != deviceID || port.DevicePortNo != change.portNo {
+				continue
+			}
+			if change.operStatus == voltha.OperStatus_ACTIVE {
+				port.OfpPort.Config = port.OfpPort.Config & ^uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
+				port.OfpPort.State = uint32(ofp.OfpPortState_OFPPS_LIVE)
+			} else {
+				port.OfpPort.Config = port.OfpPort.Config | uint32(ofp.OfpPortConfig_OFPPC_PORT_DOWN)
+				port.OfpPort.State = uint32(ofp.OfpPortState_OFPPS_LINK_DOWN)
+			}
+			matched++
+		}
+	}
+	if matched == 0 {
+		return status.Errorf(codes.NotFound, "port-%d-not-exist", changes[0].portNo)
+	}
+	// Updating the logical device will trigger the port change events to be populated to the controller
+	if err := agent.updateLogicalDevicePortsWithoutLock(ctx, original, updatedPorts); err != nil {
+		ctxErrorw(ctx, "error-updating-logical-device", log.Fields{"error": err})
+		return err
+	}
+	return nil
+}
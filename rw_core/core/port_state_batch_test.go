@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBulkPortStateUpdate(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, fls := newLifecycleTestDeviceManager()
+
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:06:01"}, ch)
+	device, ok := (<-ch).(*voltha.Device)
+	assert.True(t, ok)
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 1, Label: "nni-1", Type: voltha.Port_ETHERNET_NNI}))
+	assert.Nil(t, dMgr.addPort(ctx, device.Id, &voltha.Port{PortNo: 2, Label: "pon-1", Type: voltha.Port_PON_OLT}))
+
+	// All changes land in one transaction
+	assert.Nil(t, dMgr.updatePortsStates(ctx, device.Id, []portStateChange{
+		{portType: voltha.Port_ETHERNET_NNI, portNo: 1, operStatus: voltha.OperStatus_ACTIVE},
+		{portType: voltha.Port_PON_OLT, portNo: 2, operStatus: voltha.OperStatus_ACTIVE},
+	}))
+	device, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	for _, port := range device.Ports {
+		assert.Equal(t, voltha.OperStatus_ACTIVE, port.OperStatus)
+	}
+	// Only the NNI change is forwarded to the logical device, in a single call
+	waitUntil(t, 2*time.Second, "logical port states were never updated", func() bool {
+		return fls.callCount("updateLogicalPortStates") == 1
+	})
+
+	// The single-port API shares the bulk implementation
+	assert.Nil(t, dMgr.updatePortState(ctx, device.Id, voltha.Port_PON_OLT, 2, voltha.OperStatus_FAILED))
+	device, err = dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	for _, port := range device.Ports {
+		if port.PortNo == 2 {
+			assert.Equal(t, voltha.OperStatus_FAILED, port.OperStatus)
+		}
+	}
+	// A PON change never reaches the logical device
+	assert.Equal(t, 1, fls.callCount("updateLogicalPortStates"))
+
+	assert.NotNil(t, dMgr.updatePortsStates(ctx, "absent-device", []portStateChange{
+		{portType: voltha.Port_ETHERNET_NNI, portNo: 1, operStatus: voltha.OperStatus_ACTIVE},
+	}))
+}
+
+func TestLogicalAgentBatchPortStates(t *testing.T) {
+	ctx := context.Background()
+	changeEvents := make(chan ofp.ChangeEvent, 2)
+	agent := &LogicalDeviceAgent{
+		logicalDeviceID:  "ld-batch",
+		clusterDataProxy: newInMemoryClusterProxy(),
+		requestQueue:     coreutils.NewRequestQueue(),
+		ldeviceMgr:       &LogicalDeviceManager{grpcNbiHdlr: &APIHandler{changeEventQueue: changeEvents}},
+		logicalDevice: &voltha.LogicalDevice{
+			Id: "ld-batch",
+			Ports: []*voltha.LogicalPort{
+				{Id: "nni-1", DeviceId: "olt-1", DevicePortNo: 1, OfpPort: &ofp.OfpPort{PortNo: 1}},
+				{Id: "nni-2", DeviceId: "olt-1", DevicePortNo: 2, OfpPort: &ofp.OfpPort{PortNo: 2}},
+			},
+		},
+	}
+
+	// Both deltas are applied in one save, raising one change event per modified port
+	assert.Nil(t, agent.updatePortStates(ctx, "olt-1", []portStateChange{
+		{portNo: 1, operStatus: voltha.OperStatus_ACTIVE},
+		{portNo: 2, operStatus: voltha.OperStatus_FAILED},
+	}))
+	assert.Equal(t, uint32(ofp.OfpPortState_OFPPS_LIVE), agent.logicalDevice.Ports[0].OfpPort.State)
+	assert.Equal(t, uint32(ofp.OfpPortState_OFPPS_LINK_DOWN), agent.logicalDevice.Ports[1].OfpPort.State)
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-changeEvents:
+			assert.NotNil(t, event.GetPortStatus())
+			assert.Equal(t, ofp.OfpPortReason_OFPPR_MODIFY, event.GetPortStatus().Reason)
+		case <-time.After(2 * time.Second):
+			t.Error("missing-change-event")
+		}
+	}
+
+	// A batch matching no port is reported
+	err := agent.updatePortStates(ctx, "olt-1", []portStateChange{{portNo: 99, operStatus: voltha.OperStatus_ACTIVE}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// GetChildDeviceWithProxyAddress trusts the parent named in the proxy address, but an
+// adapter can pass a stale parent after an ONU migration and the lookup then reported
+// NotFound for a child that exists under a different parent.  When the parent-scoped
+// search fails the managed devices are searched by the proxy identity alone - onu id,
+// channel id and device type, ignoring the parent - and a hit is returned annotated with
+// the actual parent so the adapter can correct its state.  Each fallback hit is logged
+// and counted against the adapter managing the child, to surface the underlying bug.
+
+// parentMismatchAnnotationKey carries the child's actual parent on a device returned
+// through the fallback lookup
+const parentMismatchAnnotationKey = "parent-mismatch"
+
+// findChildByProxyIdentity searches the managed devices for a child whose proxy address
+// matches everything but the parent
+func (dMgr *DeviceManager) findChildByProxyIdentity(proxyAddress *voltha.Device_ProxyAddress) *voltha.Device {
+	var found *voltha.Device
+	dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		device := value.(*DeviceAgent).getDeviceWithoutLock()
+		if device == nil || device.Root || device.ProxyAddress == nil {
+			return true
+		}
+		candidate := device.ProxyAddress
+		if candidate.OnuId != proxyAddress.OnuId || candidate.ChannelId != proxyAddress.ChannelId {
+			return true
+		}
+		if proxyAddress.DeviceType != "" && candidate.DeviceType != proxyAddress.DeviceType {
+			return true
+		}
+		found = device
+		return false
+	})
+	return found
+}
+
+// findChildByProxyAddressFallback resolves a proxy address whose parent turned out to be
+// stale.  A hit is returned as a clone annotated with the actual parent; the mismatch is
+// counted against the adapter managing the child.
+func (dMgr *DeviceManager) findChildByProxyAddressFallback(ctx context.Context, proxyAddress *voltha.Device_ProxyAddress) (*voltha.Device, error) {
+	child := dMgr.findChildByProxyIdentity(proxyAddress)
+	if child == nil {
+		return nil, newNotFoundError("child-device", proxyAddress)
+	}
+	if child.ParentId == proxyAddress.DeviceId {
+		// The parent was right after all - the scoped search failed for another reason,
+		// such as a parent port index not yet populated - so there is nothing to flag
+		return child, nil
+	}
+	count := dMgr.countProxyAddressMismatch(child.Adapter)
+	ctxWarnw(ctx, "child-found-under-different-parent", log.Fields{
+		"child-id": child.Id, "stale-parent-id": proxyAddress.DeviceId, "actual-parent-id": child.ParentId,
+		"adapter": child.Adapter, "mismatches": count})
+	cloned := proto.Clone(child).(*voltha.Device)
+	annotations := deviceAnnotations(cloned)
+	annotations[parentMismatchAnnotationKey] = child.ParentId
+	cloned.ExtraArgs = encodeDeviceAnnotations(cloned.ExtraArgs, annotations)
+	return cloned, nil
+}
+
+// countProxyAddressMismatch bumps the stale-parent counter of an adapter and returns the
+// new count
+func (dMgr *DeviceManager) countProxyAddressMismatch(adapter string) int64 {
+	counter, _ := dMgr.proxyAddressMismatches.LoadOrStore(adapter, new(int64))
+	return atomic.AddInt64(counter.(*int64), 1)
+}
+
+// proxyAddressMismatchCounts returns how many stale-parent lookups each adapter has
+// issued since this instance started
+func (dMgr *DeviceManager) proxyAddressMismatchCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	dMgr.proxyAddressMismatches.Range(func(key, value interface{}) bool {
+		counts[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return counts
+}
+
+// GetProxyAddressMismatchCounts is the debug query returning the per-adapter counts of
+// stale-parent proxy address lookups
+func (handler *APIHandler) GetProxyAddressMismatchCounts(ctx context.Context) (map[string]int64, error) {
+	ctxDebugw(ctx, "GetProxyAddressMismatchCounts", log.Fields{})
+	return handler.deviceMgr.proxyAddressMismatchCounts(), nil
+}
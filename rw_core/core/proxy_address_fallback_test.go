@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// registerTestAgent creates, starts and registers an agent for the given device
+func registerTestAgent(t *testing.T, dMgr *DeviceManager, device *voltha.Device) *DeviceAgent {
+	agent := newDeviceAgent(dMgr.adapterProxy, device, dMgr, dMgr.clusterDataProxy, dMgr.defaultTimeout)
+	_, err := agent.start(context.Background(), device)
+	assert.Nil(t, err)
+	return dMgr.addDeviceAgentToMap(agent)
+}
+
+func TestProxyAddressFallbackFindsMigratedChild(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := enableOLT(t, dMgr, "00:00:00:00:4a:24")
+	registerTestAgent(t, dMgr, &voltha.Device{
+		Id: "onu-moved", Type: "onu", Adapter: "adapter-1", ParentId: parent.Id,
+		ProxyAddress: &voltha.Device_ProxyAddress{DeviceId: parent.Id, DeviceType: "olt", ChannelId: 7, OnuId: 3},
+	})
+
+	// The adapter asks with the parent it knew before the migration
+	stale := &voltha.Device_ProxyAddress{DeviceId: "olt-gone", DeviceType: "olt", ChannelId: 7, OnuId: 3}
+	child, err := dMgr.GetChildDeviceWithProxyAddress(ctx, stale)
+	assert.Nil(t, err)
+	assert.Equal(t, "onu-moved", child.Id)
+	// The mismatch is flagged with the actual parent and counted against the adapter
+	assert.Equal(t, parent.Id, deviceAnnotations(child)[parentMismatchAnnotationKey])
+	assert.Equal(t, int64(1), dMgr.proxyAddressMismatchCounts()["adapter-1"])
+
+	// An identity nothing matches still reports NotFound
+	unknown := &voltha.Device_ProxyAddress{DeviceId: "olt-gone", DeviceType: "olt", ChannelId: 7, OnuId: 9}
+	_, err = dMgr.GetChildDeviceWithProxyAddress(ctx, unknown)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestProxyAddressExactLookupNotFlagged(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := registerTestAgent(t, dMgr, &voltha.Device{
+		Id: "olt-here", Type: "olt", Root: true, Adapter: "adapter-1",
+		Ports: []*voltha.Port{{PortNo: 1, Type: voltha.Port_PON_OLT, Peers: []*voltha.Port_PeerPort{{DeviceId: "onu-here"}}}},
+	})
+	registerTestAgent(t, dMgr, &voltha.Device{
+		Id: "onu-here", Type: "onu", Adapter: "adapter-1", ParentId: parent.deviceID,
+		ProxyAddress: &voltha.Device_ProxyAddress{DeviceId: parent.deviceID, DeviceType: "olt", ChannelId: 2, OnuId: 1},
+	})
+
+	exact := &voltha.Device_ProxyAddress{DeviceId: parent.deviceID, DeviceType: "olt", ChannelId: 2, OnuId: 1}
+	child, err := dMgr.GetChildDeviceWithProxyAddress(ctx, exact)
+	assert.Nil(t, err)
+	assert.Equal(t, "onu-here", child.Id)
+	// A correct parent is neither annotated nor counted
+	assert.Equal(t, "", deviceAnnotations(child)[parentMismatchAnnotationKey])
+	assert.Equal(t, 0, len(dMgr.proxyAddressMismatchCounts()))
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// childDeviceDetected used to cast its raw int64 parameters straight into the uint32
+// ProxyAddress fields; a negative or oversized channel or onu id wrapped silently and
+// produced a child the adapter could never address.  The ranges are now validated before
+// the child is created - per device type, since access technologies bound the ids
+// differently - and out-of-range values are rejected with InvalidArgument naming the
+// offending parameter.  Proxy address matching is done field by field with normalized
+// optional fields so a lookup by proxy address is reliable.
+
+// proxyAddressLimits bounds the addressable id ranges of a child device
+type proxyAddressLimits struct {
+	maxOnuID          int64
+	maxChannelID      int64
+	maxChannelGroupID int64
+}
+
+// defaultProxyAddressLimits applies to device types without a specific entry
+var defaultProxyAddressLimits = proxyAddressLimits{maxOnuID: 255, maxChannelID: math.MaxUint16, maxChannelGroupID: math.MaxUint16}
+
+// proxyAddressLimitsByType holds the per-device-type overrides of the id ranges
+var proxyAddressLimitsByType = map[string]proxyAddressLimits{}
+
+func proxyAddressLimitsFor(deviceType string) proxyAddressLimits {
+	if limits, have := proxyAddressLimitsByType[deviceType]; have {
+		return limits
+	}
+	return defaultProxyAddressLimits
+}
+
+// validateChildProxyAddress rejects id values outside the addressable range of the
+// device type before they are cast into the uint32 proxy address fields
+func validateChildProxyAddress(deviceType string, parentPortNo int64, channelID int64, onuID int64) error {
+	limits := proxyAddressLimitsFor(deviceType)
+	if parentPortNo < 0 || parentPortNo > math.MaxUint32 {
+		return newInvalidArgumentError(fmt.Sprintf("parent-port-no-out-of-range:%d", parentPortNo))
+	}
+	if channelID < 0 || channelID > limits.maxChannelID {
+		return newInvalidArgumentError(fmt.Sprintf("channel-id-out-of-range:%d-max-%d", channelID, limits.maxChannelID))
+	}
+	if onuID < 0 || onuID > limits.maxOnuID {
+		return newInvalidArgumentError(fmt.Sprintf("onu-id-out-of-range:%d-max-%d", onuID, limits.maxOnuID))
+	}
+	return nil
+}
+
+// proxyAddressMatches compares two proxy addresses field by field, normalizing the
+// optional channel termination so an unset value and blank padding compare equal
+func proxyAddressMatches(a *voltha.Device_ProxyAddress, b *voltha.Device_ProxyAddress) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.DeviceId == b.DeviceId &&
+		a.ChannelId == b.ChannelId &&
+		a.ChannelGroupId == b.ChannelGroupId &&
+		a.OnuId == b.OnuId &&
+		strings.TrimSpace(a.ChannelTermination) == strings.TrimSpace(b.ChannelTermination)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChildProxyAddressBoundaries(t *testing.T) {
+	cases := []struct {
+		name                           string
+		parentPortNo, channelID, onuID int64
+		wantErr                        string
+	}{
+		{"all-minimums", 0, 0, 0, ""},
+		{"all-maximums", math.MaxUint32, math.MaxUint16, 255, ""},
+		{"negative-port", -1, 0, 1, "parent-port-no"},
+		{"oversized-port", math.MaxUint32 + 1, 0, 1, "parent-port-no"},
+		{"negative-channel", 0, -1, 1, "channel-id"},
+		{"oversized-channel", 0, math.MaxUint16 + 1, 1, "channel-id"},
+		{"negative-onu", 0, 0, -7, "onu-id"},
+		{"oversized-onu", 0, 0, 256, "onu-id"},
+		{"wrapping-onu", 0, 0, math.MaxUint32 + 2, "onu-id"},
+	}
+	for _, tc := range cases {
+		err := validateChildProxyAddress("onu", tc.parentPortNo, tc.channelID, tc.onuID)
+		if tc.wantErr == "" {
+			assert.Nil(t, err, tc.name)
+		} else {
+			assert.NotNil(t, err, tc.name)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), tc.name)
+			assert.Contains(t, err.Error(), tc.wantErr, tc.name)
+		}
+	}
+}
+
+func TestChildDeviceDetectedRejectsOutOfRangeIds(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:11:01")
+
+	for _, onuID := range []int64{-1, 1 << 33} {
+		_, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 1, "VEND", "SERIAL-R", onuID)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	_, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", -3, "VEND", "SERIAL-R", 1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// None of the rejected detections created a child
+	_, err = dMgr.GetChildDevice(ctx, parent.Id, "SERIAL-R", 1, 1)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestGetChildDeviceWithProxyAddressMatching(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	parent := newChildLookupParent(t, dMgr, "00:00:00:00:11:02")
+
+	child, err := dMgr.childDeviceDetected(ctx, parent.Id, 1, "onu", 2, "VEND", "SERIAL-P", 3)
+	assert.Nil(t, err)
+	assert.Nil(t, dMgr.addPort(ctx, child.Id, &voltha.Port{PortNo: 1, Label: "pon-onu", Type: voltha.Port_PON_ONU,
+		Peers: []*voltha.Port_PeerPort{{DeviceId: parent.Id, PortNo: 1}}}))
+
+	// The lookup matches on field values, not pointer identity
+	found, err := dMgr.GetChildDeviceWithProxyAddress(ctx, &voltha.Device_ProxyAddress{
+		DeviceId: parent.Id, DeviceType: "olt", ChannelId: 2, OnuId: 3, ChannelTermination: " "})
+	assert.Nil(t, err)
+	assert.Equal(t, child.Id, found.Id)
+
+	// A differing onu id does not match
+	_, err = dMgr.GetChildDeviceWithProxyAddress(ctx, &voltha.Device_ProxyAddress{
+		DeviceId: parent.Id, DeviceType: "olt", ChannelId: 2, OnuId: 4})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
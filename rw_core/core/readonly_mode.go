@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-lib-go/v3/pkg/probe"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// During KV store maintenance the core should keep answering reads and forwarding packets
+// but must not half-apply a change it cannot persist.  A core-wide read-only flag -
+// settable through the "read_only_mode" runtime config setting or the admin call - is
+// checked at the mutating NBI entry points and in the device write sequencer: NBI writes
+// are refused with Unavailable naming maintenance as the reason, while adapter-originated
+// status updates are buffered in the sequencer's pending slot and drained to the KV store
+// when the mode is left, so no adapter report is lost.  Entering and leaving the mode
+// emits an event and flips the device-manager probe status.
+
+// isReadOnly reports whether the core is in read-only maintenance
+func (dMgr *DeviceManager) isReadOnly() bool {
+	return atomic.LoadInt32(&dMgr.readOnlyMode) != 0
+}
+
+// checkWritable rejects a mutating operation while the core is in read-only maintenance
+func (dMgr *DeviceManager) checkWritable(ctx context.Context, operation string) error {
+	if !dMgr.isReadOnly() {
+		return nil
+	}
+	return status.Errorf(codes.Unavailable, "%s-unavailable: maintenance-read-only", operation)
+}
+
+// setReadOnlyMode enters or leaves read-only maintenance.  Leaving the mode drains the
+// device states buffered in the write sequencers while it was on.
+func (dMgr *DeviceManager) setReadOnlyMode(ctx context.Context, enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	if atomic.SwapInt32(&dMgr.readOnlyMode, value) == value {
+		return
+	}
+	event := coreReadOnlyExitedEvent
+	probeStatus := probe.ServiceStatusRunning
+	if enabled {
+		event = coreReadOnlyEnteredEvent
+		probeStatus = probe.ServiceStatusNotReady
+		ctxWarnw(ctx, "core-entered-read-only-maintenance", nil)
+	} else {
+		ctxInfow(ctx, "core-left-read-only-maintenance", nil)
+	}
+	probe.UpdateStatusFromContext(ctx, "device-manager", probeStatus)
+	if dMgr.eventMgr != nil {
+		go dMgr.eventMgr.publishDeviceEvent(&voltha.Device{Id: dMgr.coreInstanceID}, event, map[string]string{
+			"core-instance": dMgr.coreInstanceID,
+		})
+	}
+	if !enabled {
+		dMgr.deviceAgents.Range(func(key, agentValue interface{}) bool {
+			if err := agentValue.(*DeviceAgent).flushPendingWrites(ctx); err != nil {
+				ctxWarnw(ctx, "failed-to-flush-buffered-device-write", log.Fields{"device-id": key.(string), "error": err})
+			}
+			return true
+		})
+	}
+}
+
+// SetReadOnlyMode is the admin surface for entering and leaving read-only maintenance
+func (handler *APIHandler) SetReadOnlyMode(ctx context.Context, enabled bool) error {
+	ctxDebugw(ctx, "SetReadOnlyMode", log.Fields{"enabled": enabled})
+	handler.deviceMgr.setReadOnlyMode(ctx, enabled)
+	return nil
+}
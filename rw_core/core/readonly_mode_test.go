@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadOnlyModeRejectsWritesAndAllowsReads(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:21")
+
+	dMgr.setReadOnlyMode(ctx, true)
+
+	// Mutating NBI entry points are refused with Unavailable naming maintenance
+	ch := make(chan interface{}, 1)
+	dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: "00:00:00:00:4a:22"}, ch)
+	err, isError := (<-ch).(error)
+	assert.True(t, isError)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, strings.Contains(err.Error(), "maintenance"))
+
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	err, isError = (<-ch).(error)
+	assert.True(t, isError)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// An agent write without an adapter origin is refused at the sequencer as well
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+	err = agent.updateDeviceReason(ctx, "operator-note")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// Reads keep working
+	fetched, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, device.Id, fetched.Id)
+
+	// Leaving the mode restores writes
+	dMgr.setReadOnlyMode(ctx, false)
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+}
+
+func TestReadOnlyModeBuffersAdapterStatusUpdates(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:23")
+	agent := dMgr.getDeviceAgent(ctx, device.Id)
+
+	dMgr.setReadOnlyMode(ctx, true)
+
+	// The adapter's status report is accepted in memory instead of being rejected
+	assert.Nil(t, agent.updateDeviceStatus(ctx, voltha.OperStatus_FAILED, voltha.ConnectStatus_UNREACHABLE))
+	fetched, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_FAILED, fetched.OperStatus)
+
+	// The KV store still carries the pre-maintenance state
+	stored := &voltha.Device{}
+	have, err := dMgr.clusterDataProxy.Get(ctx, "devices/"+device.Id, stored)
+	assert.Nil(t, err)
+	assert.True(t, have)
+	assert.NotEqual(t, voltha.OperStatus_FAILED, stored.OperStatus)
+
+	// Leaving the mode drains the buffered state out to the KV store
+	dMgr.setReadOnlyMode(ctx, false)
+	stored = &voltha.Device{}
+	_, err = dMgr.clusterDataProxy.Get(ctx, "devices/"+device.Id, stored)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_FAILED, stored.OperStatus)
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReconcileDevices takes explicit IDs, but after a partial outage operators want
+// "reconcile everything under OLT X" or "every device of type Y" without computing the ID
+// list themselves.  A selector names a parent device, a device type, or an adapter; the
+// core expands it to the concrete device set - children enumerated from the parent's
+// ports, types and adapters from the KV list - and pushes the set through the existing
+// paced per-device reconcile.  Each run registers under the selector's key in the same
+// progress registry the adapter-restart reconcile uses, so it can be queried while it
+// goes, and a device claimed by one run is skipped by any overlapping run instead of
+// being reconciled twice.
+
+// reconcileSelector names the device set of one selector-based reconcile.  Exactly one
+// field must be set.
+type reconcileSelector struct {
+	ParentID   string
+	DeviceType string
+	AdapterID  string
+}
+
+// validate rejects a selector that names no dimension or more than one
+func (sel reconcileSelector) validate() error {
+	set := 0
+	for _, field := range []string{sel.ParentID, sel.DeviceType, sel.AdapterID} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return newInvalidArgumentError("selector-must-set-exactly-one-of-parent-id-device-type-adapter-id")
+	}
+	return nil
+}
+
+// key identifies the run in the reconcile progress registry
+func (sel reconcileSelector) key() string {
+	switch {
+	case sel.ParentID != "":
+		return "parent:" + sel.ParentID
+	case sel.DeviceType != "":
+		return "type:" + sel.DeviceType
+	default:
+		return "adapter:" + sel.AdapterID
+	}
+}
+
+// expandReconcileSelector resolves the selector to the concrete devices worth
+// reconciling, applying the same state and maintenance screening as the adapter-restart
+// reconcile
+func (dMgr *DeviceManager) expandReconcileSelector(ctx context.Context, sel reconcileSelector) ([]*voltha.Device, error) {
+	var candidates []*voltha.Device
+	if sel.ParentID != "" {
+		parent, err := dMgr.getDeviceFromModel(ctx, sel.ParentID)
+		if err != nil || parent == nil {
+			return nil, newNotFoundError("device", sel.ParentID)
+		}
+		candidates = append(candidates, parent)
+		childDeviceIds, err := dMgr.getAllChildDeviceIds(parent)
+		if err != nil {
+			return nil, err
+		}
+		for _, childID := range childDeviceIds {
+			if child, _ := dMgr.getDeviceFromModel(ctx, childID); child != nil {
+				candidates = append(candidates, child)
+			}
+		}
+	} else {
+		var devices []*voltha.Device
+		if err := dMgr.clusterDataProxy.List(ctx, "devices", &devices); err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			if sel.DeviceType != "" && device.Type != sel.DeviceType {
+				continue
+			}
+			if sel.AdapterID != "" && device.Adapter != sel.AdapterID {
+				continue
+			}
+			candidates = append(candidates, device)
+		}
+	}
+	devices := make([]*voltha.Device, 0, len(candidates))
+	for _, device := range candidates {
+		if inMaintenance, _ := deviceInMaintenance(device); inMaintenance {
+			ctxDebugw(ctx, "not-reconciling-device-in-maintenance", log.Fields{"device-id": device.Id})
+			continue
+		}
+		if !isOkToReconcile(device) {
+			ctxDebugw(ctx, "not-reconciling-device", log.Fields{"device-id": device.Id, "state": device.AdminState})
+			continue
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// claimReconcile marks a device as being reconciled; it returns false when another run
+// already holds the device
+func (dMgr *DeviceManager) claimReconcile(deviceID string) bool {
+	_, alreadyClaimed := dMgr.reconcilingDevices.LoadOrStore(deviceID, struct{}{})
+	return !alreadyClaimed
+}
+
+// releaseReconcile is the inverse of claimReconcile
+func (dMgr *DeviceManager) releaseReconcile(deviceID string) {
+	dMgr.reconcilingDevices.Delete(deviceID)
+}
+
+// reconcileBySelector expands the selector and runs the paced reconcile over the result,
+// returning the final progress of the run.  A second run with the same selector while one
+// is active is a no-op, and devices claimed by an overlapping run are skipped.
+func (dMgr *DeviceManager) reconcileBySelector(ctx context.Context, sel reconcileSelector) (adapterReconcileProgress, error) {
+	if err := sel.validate(); err != nil {
+		return adapterReconcileProgress{}, err
+	}
+	stored, loaded := dMgr.reconcileRuns.LoadOrStore(sel.key(), &adapterReconcileRun{adapterID: sel.key(), startedAt: time.Now()})
+	run := stored.(*adapterReconcileRun)
+	if loaded {
+		ctxInfow(ctx, "selector-reconcile-already-in-progress", log.Fields{"selector": sel.key()})
+		return run.progress(), nil
+	}
+	defer dMgr.reconcileRuns.Delete(sel.key())
+
+	devices, err := dMgr.expandReconcileSelector(ctx, sel)
+	if err != nil {
+		return run.progress(), err
+	}
+
+	// Claim the devices for this run; a device already claimed by an overlapping run is
+	// dropped here so it is not reconciled twice
+	var parents, children []*voltha.Device
+	for _, device := range devices {
+		if !dMgr.claimReconcile(device.Id) {
+			ctxDebugw(ctx, "device-already-being-reconciled", log.Fields{"device-id": device.Id, "selector": sel.key()})
+			continue
+		}
+		defer dMgr.releaseReconcile(device.Id)
+		if device.Root {
+			parents = append(parents, device)
+		} else {
+			children = append(children, device)
+		}
+	}
+	if len(parents) == 0 && len(children) == 0 {
+		ctxDebugw(ctx, "no-device-matches-reconcile-selector", log.Fields{"selector": sel.key()})
+		return run.progress(), nil
+	}
+	run.setPending(len(parents) + len(children))
+	ctxInfow(ctx, "selector-reconcile-start", log.Fields{"selector": sel.key(), "parents": len(parents), "children": len(children)})
+
+	// Parents first, for the same reason the adapter-restart reconcile orders them first
+	dMgr.reconcileBatch(ctx, run, parents)
+	dMgr.reconcileBatch(ctx, run, children)
+
+	result := run.progress()
+	if result.failed > 0 {
+		return result, status.Errorf(codes.Aborted, "reconcile-failed-for-%d-of-%d-devices-selector-%s",
+			result.failed, result.failed+result.succeeded, sel.key())
+	}
+	return result, nil
+}
+
+// ReconcileBySelector is the NBI surface of the selector-based reconcile.  Exactly one of
+// the arguments must be set; the call blocks until the run completes and returns its
+// final progress.
+func (handler *APIHandler) ReconcileBySelector(ctx context.Context, parentID string, deviceType string, adapterID string) (adapterReconcileProgress, error) {
+	ctxDebugw(ctx, "ReconcileBySelector", log.Fields{"parent-id": parentID, "device-type": deviceType, "adapter-id": adapterID})
+	return handler.deviceMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: parentID, DeviceType: deviceType, AdapterID: adapterID})
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// seedSelectorModel stores two parents with one child each plus a preprovisioned device
+// that must never be selected
+func seedSelectorModel(t *testing.T, dMgr *DeviceManager) {
+	ctx := context.Background()
+	devices := []*voltha.Device{
+		{Id: "olt-X", Root: true, Type: "olt", Adapter: "adapter-1", AdminState: voltha.AdminState_ENABLED,
+			Ports: []*voltha.Port{{PortNo: 1, Type: voltha.Port_PON_OLT, Peers: []*voltha.Port_PeerPort{{DeviceId: "onu-X1"}}}}},
+		{Id: "onu-X1", Type: "onu", Adapter: "adapter-1", AdminState: voltha.AdminState_ENABLED},
+		{Id: "olt-Y", Root: true, Type: "olt", Adapter: "adapter-2", AdminState: voltha.AdminState_ENABLED,
+			Ports: []*voltha.Port{{PortNo: 1, Type: voltha.Port_PON_OLT, Peers: []*voltha.Port_PeerPort{{DeviceId: "onu-Y1"}}}}},
+		{Id: "onu-Y1", Type: "onu", Adapter: "adapter-2", AdminState: voltha.AdminState_ENABLED},
+		{Id: "onu-new", Type: "onu", Adapter: "adapter-1", AdminState: voltha.AdminState_PREPROVISIONED},
+	}
+	for _, device := range devices {
+		assert.Nil(t, dMgr.clusterDataProxy.AddWithID(ctx, "devices", device.Id, device))
+	}
+}
+
+func TestReconcileByParentSelector(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	recorder := &reconcileRecorder{fakeAdapterService: fas}
+	dMgr.adapterProxy = recorder
+	dMgr.reconcileConcurrency = 1
+	seedSelectorModel(t, dMgr)
+
+	progress, err := dMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: "olt-X"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, progress.succeeded)
+	assert.Equal(t, 0, progress.failed)
+	// The parent is reconciled before its child
+	assert.Equal(t, []string{"olt-X", "onu-X1"}, recorder.recorded())
+}
+
+func TestReconcileByTypeAndAdapterSelectors(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	recorder := &reconcileRecorder{fakeAdapterService: fas}
+	dMgr.adapterProxy = recorder
+	dMgr.reconcileConcurrency = 1
+	seedSelectorModel(t, dMgr)
+
+	// Every onu in a reconcilable state, across both adapters; the preprovisioned one is
+	// screened out
+	progress, err := dMgr.reconcileBySelector(ctx, reconcileSelector{DeviceType: "onu"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, progress.succeeded)
+
+	// Every device bound to adapter-2
+	progress, err = dMgr.reconcileBySelector(ctx, reconcileSelector{AdapterID: "adapter-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, progress.succeeded)
+}
+
+func TestReconcileSelectorValidation(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	_, err := dMgr.reconcileBySelector(ctx, reconcileSelector{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = dMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: "olt-X", DeviceType: "onu"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = dMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: "no-such-device"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOverlappingSelectorRunsDeduplicateDevices(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	recorder := &reconcileRecorder{fakeAdapterService: fas}
+	dMgr.adapterProxy = recorder
+	dMgr.reconcileConcurrency = 1
+	seedSelectorModel(t, dMgr)
+
+	// Another run already claimed onu-X1; the parent-scoped run must skip it
+	assert.True(t, dMgr.claimReconcile("onu-X1"))
+	progress, err := dMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: "olt-X"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, progress.succeeded)
+	assert.Equal(t, []string{"olt-X"}, recorder.recorded())
+
+	// Once the claim is released the device is reconcilable again
+	dMgr.releaseReconcile("onu-X1")
+	progress, err = dMgr.reconcileBySelector(ctx, reconcileSelector{ParentID: "olt-X"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, progress.succeeded)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	ic "github.com/opencord/voltha-protos/v3/go/inter_container"
+)
+
+// Some tunables - request timeouts, buffer windows, validation switches - can safely change
+// at runtime, and requiring a restart to tune them drops devices.  The runtime config manager
+// polls a KV path for overrides and applies the ones its registered settings can take
+// dynamically; values that cannot change at runtime are rejected with a log.  The effective
+// configuration is written back to the KV store so operators can query what is actually in
+// force.
+const (
+	runtimeConfigPath          = "runtime_config"
+	effectiveConfigPath        = "runtime_config_effective"
+	runtimeConfigCheckInterval = time.Minute
+)
+
+// runtimeSetting describes one tunable: how to read its current value and, when the value can
+// change at runtime, how to apply a new one
+type runtimeSetting struct {
+	get   func() string
+	apply func(value string) error // nil when the value cannot change at runtime
+}
+
+type runtimeConfigManager struct {
+	lock             sync.Mutex
+	settings         map[string]*runtimeSetting
+	applied          map[string]string // last raw value handled per setting, to detect changes
+	clusterDataProxy *model.Proxy
+}
+
+func newRuntimeConfigManager(cdProxy *model.Proxy) *runtimeConfigManager {
+	return &runtimeConfigManager{
+		settings:         make(map[string]*runtimeSetting),
+		applied:          make(map[string]string),
+		clusterDataProxy: cdProxy,
+	}
+}
+
+// register adds a tunable.  A nil apply marks the value as fixed at process start; overrides
+// for it are rejected.
+func (rcm *runtimeConfigManager) register(name string, get func() string, apply func(string) error) {
+	rcm.lock.Lock()
+	defer rcm.lock.Unlock()
+	rcm.settings[name] = &runtimeSetting{get: get, apply: apply}
+}
+
+// effectiveConfiguration returns the values currently in force for every registered setting
+func (rcm *runtimeConfigManager) effectiveConfiguration() map[string]string {
+	rcm.lock.Lock()
+	defer rcm.lock.Unlock()
+	effective := make(map[string]string, len(rcm.settings))
+	for name, setting := range rcm.settings {
+		effective[name] = setting.get()
+	}
+	return effective
+}
+
+// encodeRuntimeConfig encodes one override for the KV store.  The name is embedded in the
+// value because listing a KV path loses the keys.
+func encodeRuntimeConfig(name string, value string) *ic.StrType {
+	return &ic.StrType{Val: fmt.Sprintf("%s|%s", name, value)}
+}
+
+// decodeRuntimeConfig is the inverse of encodeRuntimeConfig
+func decodeRuntimeConfig(entry *ic.StrType) (string, string, error) {
+	parts := strings.SplitN(entry.Val, "|", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("invalid-runtime-config-entry-%s", entry.Val)
+	}
+	return parts[0], parts[1], nil
+}
+
+// applyOverride applies one override, logging the old and new values.  Unknown settings and
+// values fixed at process start are rejected; a repeated identical override is a no-op.
+func (rcm *runtimeConfigManager) applyOverride(ctx context.Context, name string, value string) {
+	rcm.lock.Lock()
+	defer rcm.lock.Unlock()
+	if rcm.applied[name] == value {
+		return
+	}
+	setting, ok := rcm.settings[name]
+	if !ok {
+		ctxWarnw(ctx, "unknown-runtime-config-setting", log.Fields{"name": name, "value": value})
+		rcm.applied[name] = value
+		return
+	}
+	if setting.apply == nil {
+		ctxWarnw(ctx, "runtime-config-setting-not-dynamic", log.Fields{"name": name, "value": value})
+		rcm.applied[name] = value
+		return
+	}
+	old := setting.get()
+	if err := setting.apply(value); err != nil {
+		ctxWarnw(ctx, "runtime-config-apply-failed", log.Fields{"name": name, "value": value, "error": err})
+		rcm.applied[name] = value
+		return
+	}
+	rcm.applied[name] = value
+	ctxInfow(ctx, "runtime-config-applied", log.Fields{"name": name, "old": old, "new": setting.get()})
+}
+
+// checkOnce reads the overrides from the KV store, applies the new ones and publishes the
+// resulting effective configuration
+func (rcm *runtimeConfigManager) checkOnce(ctx context.Context) {
+	var entries []*ic.StrType
+	if err := rcm.clusterDataProxy.List(ctx, runtimeConfigPath, &entries); err != nil {
+		ctxWarnw(ctx, "failed-to-list-runtime-config", log.Fields{"error": err})
+		return
+	}
+	for _, entry := range entries {
+		name, value, err := decodeRuntimeConfig(entry)
+		if err != nil {
+			ctxWarnw(ctx, "skipping-invalid-runtime-config-entry", log.Fields{"error": err})
+			continue
+		}
+		rcm.applyOverride(ctx, name, value)
+	}
+	for name, value := range rcm.effectiveConfiguration() {
+		if err := rcm.clusterDataProxy.AddWithID(ctx, effectiveConfigPath, name, encodeRuntimeConfig(name, value)); err != nil {
+			ctxWarnw(ctx, "failed-to-publish-effective-config", log.Fields{"name": name, "error": err})
+		}
+	}
+}
+
+// registerRuntimeSettings declares which device manager tunables may change at runtime.
+// Values driving tickers armed at startup are registered as fixed so overrides for them are
+// rejected rather than silently ignored.
+func (dMgr *DeviceManager) registerRuntimeSettings() {
+	dMgr.runtimeCfg.register("default_request_timeout",
+		func() string { return dMgr.defaultTimeout.String() },
+		func(value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			dMgr.defaultTimeout = d
+			return nil
+		})
+	dMgr.runtimeCfg.register("packet_in_buffer_window",
+		func() string { return dMgr.packetInBufferWindow.String() },
+		func(value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			dMgr.packetInBufferWindow = d
+			return nil
+		})
+	dMgr.runtimeCfg.register("permissive_flow_validation",
+		func() string { return strconv.FormatBool(dMgr.permissiveFlowValidation) },
+		func(value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			dMgr.permissiveFlowValidation = b
+			return nil
+		})
+	dMgr.runtimeCfg.register("skip_flow_port_validation",
+		func() string { return strconv.FormatBool(dMgr.skipFlowPortValidation) },
+		func(value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			dMgr.skipFlowPortValidation = b
+			return nil
+		})
+	dMgr.runtimeCfg.register("reject_flows_at_capacity",
+		func() string { return strconv.FormatBool(dMgr.rejectFlowsAtCapacity) },
+		func(value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			dMgr.rejectFlowsAtCapacity = b
+			return nil
+		})
+	dMgr.runtimeCfg.register("stuck_device_sweep_interval",
+		func() string { return dMgr.stuckSweepInterval.String() }, nil)
+	dMgr.runtimeCfg.register("read_only_mode",
+		func() string { return strconv.FormatBool(dMgr.isReadOnly()) },
+		func(value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			dMgr.setReadOnlyMode(context.Background(), b)
+			return nil
+		})
+	dMgr.runtimeCfg.register("operation_quotas",
+		func() string { return dMgr.encodeOperationQuotas() },
+		func(value string) error { return dMgr.applyOperationQuotas(value) })
+}
+
+// run polls for runtime configuration overrides until the core stops
+func (rcm *runtimeConfigManager) run(ctx context.Context, exitChannel chan int) {
+	ticker := time.NewTicker(runtimeConfigCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rcm.checkOnce(ctx)
+		case <-exitChannel:
+			ctxInfow(ctx, "runtime-config-manager-stopped", nil)
+			return
+		}
+	}
+}
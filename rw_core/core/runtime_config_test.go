@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeConfigRoundTrip(t *testing.T) {
+	name, value, err := decodeRuntimeConfig(encodeRuntimeConfig("default_request_timeout", "10s"))
+	assert.Nil(t, err)
+	assert.Equal(t, "default_request_timeout", name)
+	assert.Equal(t, "10s", value)
+
+	_, _, err = decodeRuntimeConfig(encodeRuntimeConfig("", "orphan"))
+	assert.NotNil(t, err)
+}
+
+func TestRuntimeConfigApplyOverride(t *testing.T) {
+	rcm := newRuntimeConfigManager(nil)
+
+	timeout := 5 * time.Second
+	rcm.register("timeout",
+		func() string { return timeout.String() },
+		func(value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			timeout = d
+			return nil
+		})
+	// A setting without an apply function is fixed at process start
+	rcm.register("kv_store_address", func() string { return "etcd:2379" }, nil)
+
+	rcm.applyOverride(context.Background(), "timeout", "10s")
+	assert.Equal(t, 10*time.Second, timeout)
+
+	// An invalid value leaves the setting untouched
+	rcm.applyOverride(context.Background(), "timeout", "not-a-duration")
+	assert.Equal(t, 10*time.Second, timeout)
+
+	// Overrides for fixed or unknown settings are rejected without effect
+	rcm.applyOverride(context.Background(), "kv_store_address", "other:2379")
+	rcm.applyOverride(context.Background(), "no-such-setting", "1")
+
+	effective := rcm.effectiveConfiguration()
+	assert.Equal(t, "10s", effective["timeout"])
+	assert.Equal(t, "etcd:2379", effective["kv_store_address"])
+}
+
+func TestRuntimeConfigDeviceManagerSettings(t *testing.T) {
+	dMgr := &DeviceManager{defaultTimeout: 8 * time.Second, runtimeCfg: newRuntimeConfigManager(nil)}
+	dMgr.registerRuntimeSettings()
+
+	dMgr.runtimeCfg.applyOverride(context.Background(), "default_request_timeout", "15s")
+	assert.Equal(t, 15*time.Second, dMgr.defaultTimeout)
+
+	dMgr.runtimeCfg.applyOverride(context.Background(), "permissive_flow_validation", "true")
+	assert.True(t, dMgr.permissiveFlowValidation)
+
+	// The sweep interval drives a ticker armed at startup and cannot change at runtime
+	dMgr.runtimeCfg.applyOverride(context.Background(), "stuck_device_sweep_interval", "1h")
+	assert.Equal(t, time.Duration(0), dMgr.stuckSweepInterval)
+}
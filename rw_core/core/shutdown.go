@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+)
+
+// Shutdown used to stop components in whatever order the Stop method listed them, which
+// let adapter callbacks arrive after the device manager had stopped and ownership
+// renewals keep running against a closing KV client.  The sequence is now explicit: the
+// NBI stops first so no new work is accepted, the inter-container subscriptions are
+// dropped so no adapter callback lands on a stopping manager, the managers stop, the
+// ownership reservations are released while the KV client is still usable, and the kafka
+// proxy closes last.  Each stage is bounded by a timeout so a hung component cannot wedge
+// the whole shutdown.  The KV client itself belongs to main and is closed there after
+// Stop returns.
+
+// shutdownStage is one step of the ordered shutdown
+type shutdownStage struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// shutdownSequence is the ordered list of stages Core.Stop executes
+func (core *Core) shutdownSequence() []shutdownStage {
+	return []shutdownStage{
+		{name: "stop-nbi", run: func(ctx context.Context) {
+			if core.grpcServer != nil {
+				core.grpcServer.Stop()
+			}
+		}},
+		{name: "quiesce-inter-container", run: func(ctx context.Context) {
+			if core.kmp == nil {
+				return
+			}
+			for _, topic := range []string{core.config.CoreTopic, core.config.CorePairTopic} {
+				if err := core.kmp.UnSubscribeFromRequestHandler(kafka.Topic{Name: topic}); err != nil {
+					ctxWarnw(ctx, "failed-to-unsubscribe-request-handler", log.Fields{"topic": topic, "error": err})
+				}
+			}
+		}},
+		{name: "stop-logical-device-manager", run: func(ctx context.Context) {
+			if core.logicalDeviceMgr != nil {
+				core.logicalDeviceMgr.stop(ctx)
+			}
+		}},
+		{name: "stop-device-manager", run: func(ctx context.Context) {
+			if core.deviceMgr != nil {
+				core.deviceMgr.stop(ctx)
+			}
+		}},
+		{name: "release-ownership", run: func(ctx context.Context) {
+			if core.deviceMgr != nil {
+				core.deviceMgr.releaseAllOwnerships()
+			}
+		}},
+		{name: "close-kafka-proxy", run: func(ctx context.Context) {
+			if core.kmp != nil {
+				core.kmp.Stop()
+			}
+		}},
+	}
+}
+
+// runShutdownStage runs one stage, bounded by the per-stage timeout.  A stage that does
+// not finish in time is logged and abandoned; the next stage proceeds regardless.
+func (core *Core) runShutdownStage(ctx context.Context, stage shutdownStage, timeout time.Duration) {
+	started := time.Now()
+	done := make(chan struct{})
+	go func() {
+		stage.run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+		ctxDebugw(ctx, "shutdown-stage-completed", log.Fields{"stage": stage.name, "took": time.Since(started)})
+	case <-time.After(timeout):
+		ctxWarnw(ctx, "shutdown-stage-timed-out", log.Fields{"stage": stage.name, "timeout": timeout})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-go/rw_core/config"
+	mock_kafka "github.com/opencord/voltha-lib-go/v3/pkg/mocks/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// newShutdownTestCore assembles the minimal core the shutdown sequence touches
+func newShutdownTestCore() (*Core, *DeviceManager) {
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	core := &Core{
+		deviceMgr:   dMgr,
+		kmp:         &mock_kafka.MockKafkaICProxy{},
+		exitChannel: make(chan struct{}),
+		config: &config.RWCoreFlags{
+			CoreTopic:            "rwcore",
+			CorePairTopic:        "core-pair-1",
+			ShutdownStageTimeout: time.Second,
+		},
+	}
+	return core, dMgr
+}
+
+func TestShutdownSequenceOrder(t *testing.T) {
+	core, _ := newShutdownTestCore()
+	names := make([]string, 0)
+	for _, stage := range core.shutdownSequence() {
+		names = append(names, stage.name)
+	}
+	assert.Equal(t, []string{
+		"stop-nbi",
+		"quiesce-inter-container",
+		"stop-logical-device-manager",
+		"stop-device-manager",
+		"release-ownership",
+		"close-kafka-proxy",
+	}, names)
+}
+
+func TestShutdownUnderLoadReleasesOwnershipAndDoesNotPanic(t *testing.T) {
+	ctx := context.Background()
+	core, dMgr := newShutdownTestCore()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:20")
+	dMgr.ownershipRenewals.Store(device.Id, time.Now())
+
+	// Hammer reads while the shutdown runs
+	var wg sync.WaitGroup
+	stopReaders := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stopReaders:
+					return
+				default:
+					_, _ = dMgr.ListDeviceIds(ctx)
+				}
+			}
+		}()
+	}
+	core.Stop(ctx)
+	close(stopReaders)
+	wg.Wait()
+
+	// The device manager's exit channel was closed, so every waiter saw the stop
+	select {
+	case <-dMgr.exitChannel:
+	default:
+		assert.Fail(t, "device-manager-exit-channel-not-closed")
+	}
+	// The release-ownership stage dropped the reservation bookkeeping
+	_, renewing := dMgr.ownershipRenewals.Load(device.Id)
+	assert.False(t, renewing)
+
+	// A repeated stop, from any path, is a no-op rather than a double close
+	core.Stop(ctx)
+	dMgr.stop(ctx)
+}
+
+func TestShutdownStageTimeoutAbandonsHungStage(t *testing.T) {
+	ctx := context.Background()
+	core, _ := newShutdownTestCore()
+	started := time.Now()
+	core.runShutdownStage(ctx, shutdownStage{
+		name: "hung-stage",
+		run:  func(ctx context.Context) { select {} },
+	}, 50*time.Millisecond)
+	assert.True(t, time.Since(started) < time.Second)
+}
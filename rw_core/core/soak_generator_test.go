@@ -0,0 +1,304 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// Performance work on the core needs repeatable large populations without racks of
+// hardware.  The generator below drives the DeviceManager directly - create, enable,
+// ports, child detection, flow pushes and status churn - against the fake adapter, and
+// records the latency of every operation class so the benchmark can report throughput
+// and regressions show up in CI performance jobs.  The flags let those jobs scale the
+// population without code changes.
+var (
+	soakOlts        = flag.Int("soak_olts", 2, "number of OLTs the soak benchmark provisions")
+	soakOnusPerOlt  = flag.Int("soak_onus_per_olt", 8, "number of ONUs hung off each OLT in the soak benchmark")
+	soakFlowsPerOnu = flag.Int("soak_flows_per_onu", 2, "number of flows pushed per ONU in the soak benchmark")
+	soakChurn       = flag.Int("soak_churn", 2, "number of status transitions applied per ONU in the soak benchmark")
+)
+
+// soakOpStats accumulates the latencies of one operation class
+type soakOpStats struct {
+	count int
+	total time.Duration
+	max   time.Duration
+}
+
+func (s *soakOpStats) record(d time.Duration) {
+	s.count++
+	s.total += d
+	if d > s.max {
+		s.max = d
+	}
+}
+
+// average returns the mean latency of the operation class
+func (s *soakOpStats) average() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return s.total / time.Duration(s.count)
+}
+
+// soakReport summarizes one generator run
+type soakReport struct {
+	Olts              int
+	Onus              int
+	FlowsPushed       int
+	StatusTransitions int
+	Duration          time.Duration
+	Ops               map[string]*soakOpStats
+}
+
+// operations returns the total number of timed core operations in the run
+func (r *soakReport) operations() int {
+	total := 0
+	for _, stats := range r.Ops {
+		total += stats.count
+	}
+	return total
+}
+
+// throughput returns the timed operations per second over the whole run
+func (r *soakReport) throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.operations()) / r.Duration.Seconds()
+}
+
+// soakGenerator provisions a synthetic device population against one device manager
+type soakGenerator struct {
+	dMgr   *DeviceManager
+	report *soakReport
+}
+
+func newSoakGenerator(dMgr *DeviceManager) *soakGenerator {
+	return &soakGenerator{dMgr: dMgr, report: &soakReport{Ops: make(map[string]*soakOpStats)}}
+}
+
+// timed runs one core operation and records its latency under the operation class
+func (g *soakGenerator) timed(op string, f func() error) error {
+	stats, have := g.report.Ops[op]
+	if !have {
+		stats = &soakOpStats{}
+		g.report.Ops[op] = stats
+	}
+	start := time.Now()
+	err := f()
+	stats.record(time.Since(start))
+	return err
+}
+
+// waitFor polls the condition, failing the run if it never holds within the timeout
+func (g *soakGenerator) waitFor(tb testing.TB, timeout time.Duration, msg string, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tb.Fatalf("soak generator timed out: %s", msg)
+}
+
+// countEnabledOnus tallies the child devices that completed their automatic enable
+func (g *soakGenerator) countEnabledOnus(ctx context.Context) int {
+	count := 0
+	g.dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		device, err := value.(*DeviceAgent).getDevice(ctx)
+		if err == nil && device.Type == "onu" && device.AdminState == voltha.AdminState_ENABLED {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// run provisions the population and returns the report.  Churn is capped below the flap
+// damping threshold so the generator measures the core, not the damper.
+func (g *soakGenerator) run(tb testing.TB, olts, onusPerOlt, flowsPerOnu, churn int) *soakReport {
+	ctx := context.Background()
+	if churn > 3 {
+		churn = 3
+	}
+	start := time.Now()
+	oltIDs := make([]string, 0, olts)
+	for o := 0; o < olts; o++ {
+		mac := fmt.Sprintf("02:00:00:00:%02x:%02x", o/256, o%256)
+		var olt *voltha.Device
+		if err := g.timed("create-device", func() error {
+			ch := make(chan interface{}, 1)
+			g.dMgr.createDevice(ctx, &voltha.Device{Type: "olt", MacAddress: mac}, ch)
+			device, ok := (<-ch).(*voltha.Device)
+			if !ok {
+				return fmt.Errorf("create of olt %d failed", o)
+			}
+			olt = device
+			return nil
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		if err := g.timed("enable-device", func() error {
+			ch := make(chan interface{}, 1)
+			g.dMgr.enableDevice(ctx, &voltha.ID{Id: olt.Id}, ch)
+			if res := <-ch; res != nil {
+				return fmt.Errorf("enable of olt %d failed: %v", o, res)
+			}
+			return nil
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		if err := g.timed("add-port", func() error {
+			return g.dMgr.addPort(ctx, olt.Id, &voltha.Port{PortNo: 1, Label: "pon-1", Type: voltha.Port_PON_OLT})
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		if err := g.timed("update-status", func() error {
+			return g.dMgr.updateDeviceStatus(ctx, olt.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE)
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		g.report.StatusTransitions++
+		oltIDs = append(oltIDs, olt.Id)
+		g.report.Olts++
+
+		for i := 0; i < onusPerOlt; i++ {
+			serial := fmt.Sprintf("SOAK%04d%04d", o, i)
+			var onu *voltha.Device
+			if err := g.timed("child-detected", func() error {
+				child, err := g.dMgr.childDeviceDetected(ctx, olt.Id, 1, "onu", int64(i+1), "VEND", serial, int64(i+1))
+				onu = child
+				return err
+			}); err != nil {
+				tb.Fatal(err)
+			}
+			if err := g.timed("add-port", func() error {
+				return g.dMgr.addPort(ctx, onu.Id, &voltha.Port{
+					PortNo: 1, Label: "pon-onu", Type: voltha.Port_PON_ONU,
+					Peers: []*voltha.Port_PeerPort{{DeviceId: olt.Id, PortNo: 1}},
+				})
+			}); err != nil {
+				tb.Fatal(err)
+			}
+			g.report.Onus++
+		}
+	}
+
+	// The detected children enable themselves asynchronously; wait for the population
+	// to settle before pushing flows at it
+	expectedOnus := olts * onusPerOlt
+	g.waitFor(tb, time.Duration(expectedOnus+1)*time.Second, "onus never finished enabling", func() bool {
+		return g.countEnabledOnus(ctx) == expectedOnus
+	})
+
+	onuIDs := make([]string, 0, expectedOnus)
+	g.dMgr.deviceAgents.Range(func(key, value interface{}) bool {
+		agent := value.(*DeviceAgent)
+		if device, err := agent.getDevice(ctx); err == nil && device.Type == "onu" {
+			onuIDs = append(onuIDs, device.Id)
+		}
+		return true
+	})
+
+	// Bring the ONUs to ACTIVE and REACHABLE as the adapter would; flows pushed at an
+	// ACTIVATING device would only be parked for later replay
+	for _, onuID := range onuIDs {
+		if err := g.timed("update-status", func() error {
+			return g.dMgr.updateDeviceStatus(ctx, onuID, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE)
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		g.report.StatusTransitions++
+	}
+
+	for _, onuID := range onuIDs {
+		flows := make([]*ofp.OfpFlowStats, flowsPerOnu)
+		for f := range flows {
+			flows[f] = &ofp.OfpFlowStats{Id: uint64(f + 1)}
+		}
+		if err := g.timed("push-flows", func() error {
+			return g.dMgr.addFlowsAndGroups(ctx, onuID, flows, nil, nil)
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		g.report.FlowsPushed += flowsPerOnu
+	}
+
+	// Status churn: flip the connectivity back and forth the configured number of times
+	for c := 0; c < churn; c++ {
+		conn := voltha.ConnectStatus_UNREACHABLE
+		if c%2 == 1 {
+			conn = voltha.ConnectStatus_REACHABLE
+		}
+		for _, onuID := range onuIDs {
+			if err := g.timed("update-status", func() error {
+				return g.dMgr.updateDeviceStatus(ctx, onuID, voltha.OperStatus_ACTIVE, conn)
+			}); err != nil {
+				tb.Fatal(err)
+			}
+			g.report.StatusTransitions++
+		}
+	}
+
+	g.report.Duration = time.Since(start)
+	return g.report
+}
+
+func TestSoakGeneratorSmoke(t *testing.T) {
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	report := newSoakGenerator(dMgr).run(t, 2, 3, 1, 2)
+
+	assert.Equal(t, 2, report.Olts)
+	assert.Equal(t, 6, report.Onus)
+	assert.Equal(t, 6, report.FlowsPushed)
+	assert.Equal(t, 2, report.Ops["create-device"].count)
+	assert.Equal(t, 6, report.Ops["child-detected"].count)
+	assert.True(t, report.throughput() > 0)
+	// Every OLT was adopted and every ONU saw its flow push
+	assert.Equal(t, 8, fas.callCount("adoptDevice"))
+	assert.Equal(t, 6, fas.callCount("updateFlowsBulk"))
+}
+
+func BenchmarkSoakPopulation(b *testing.B) {
+	totalOps := 0
+	var totalDuration time.Duration
+	var last *soakReport
+	for n := 0; n < b.N; n++ {
+		dMgr, _, _ := newLifecycleTestDeviceManager()
+		last = newSoakGenerator(dMgr).run(b, *soakOlts, *soakOnusPerOlt, *soakFlowsPerOnu, *soakChurn)
+		totalOps += last.operations()
+		totalDuration += last.Duration
+	}
+	if totalDuration > 0 {
+		b.ReportMetric(float64(totalOps)/totalDuration.Seconds(), "coreops/s")
+	}
+	for _, op := range []string{"create-device", "enable-device", "child-detected", "push-flows", "update-status"} {
+		if stats, have := last.Ops[op]; have {
+			b.ReportMetric(float64(stats.average().Microseconds()), "µs/"+op)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Adapters have sent status updates carrying values outside the proto enums, and legal
+// values in combinations that make no sense (oper ACTIVE on a device whose connection is
+// UNREACHABLE), which the core stored verbatim and the transition machinery later choked
+// on.  Updates are now validated on the way in: each status must either be the
+// conventional "not reporting this one" sentinel or a value the proto defines, and the
+// pair the device would end up with is checked against a small legality matrix.  Invalid
+// updates are rejected with InvalidArgument and counted per adapter so chronic offenders
+// are visible.  Because existing adapters are known to send some of these, validation
+// starts out permissive - invalid updates are logged and counted but still applied -
+// until the offenders are cleaned up and the flag can be flipped.
+
+// statusUnset is the conventional sentinel an adapter passes for a status it is not
+// reporting; the current value is kept.  Any other value outside the proto enum is an
+// adapter bug.
+const statusUnset = -1
+
+// illegalStatusPairs lists the oper/connect combinations a device is never allowed to end
+// up in: a device cannot be operationally active over a connection the core cannot reach
+var illegalStatusPairs = map[voltha.OperStatus_Types][]voltha.ConnectStatus_Types{
+	voltha.OperStatus_ACTIVE: {voltha.ConnectStatus_UNREACHABLE},
+}
+
+// validateStatusUpdate checks an incoming status update against the proto enums and the
+// legality matrix.  The matrix is evaluated on the pair the device would end up with, so
+// an update touching only one status cannot sneak the device into an illegal combination.
+func validateStatusUpdate(device *voltha.Device, operStatus voltha.OperStatus_Types, connStatus voltha.ConnectStatus_Types) error {
+	_, operDefined := voltha.OperStatus_Types_name[int32(operStatus)]
+	if !operDefined && int32(operStatus) != statusUnset {
+		return newInvalidArgumentError(fmt.Sprintf("undefined-oper-status-%d", operStatus))
+	}
+	_, connDefined := voltha.ConnectStatus_Types_name[int32(connStatus)]
+	if !connDefined && int32(connStatus) != statusUnset {
+		return newInvalidArgumentError(fmt.Sprintf("undefined-connect-status-%d", connStatus))
+	}
+	effectiveOper, effectiveConn := device.OperStatus, device.ConnectStatus
+	if operDefined {
+		effectiveOper = operStatus
+	}
+	if connDefined {
+		effectiveConn = connStatus
+	}
+	for _, illegal := range illegalStatusPairs[effectiveOper] {
+		if effectiveConn == illegal {
+			return newInvalidArgumentError(fmt.Sprintf("illegal-status-combination-%s-with-%s", effectiveOper, effectiveConn))
+		}
+	}
+	return nil
+}
+
+// checkStatusUpdate validates an incoming status update for the device the agent serves.
+// An invalid update is counted against the adapter that sent it and either rejected or,
+// in permissive mode, logged and allowed through.
+func (dMgr *DeviceManager) checkStatusUpdate(ctx context.Context, agent *DeviceAgent, operStatus voltha.OperStatus_Types, connStatus voltha.ConnectStatus_Types) error {
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	verr := validateStatusUpdate(device, operStatus, connStatus)
+	if verr == nil {
+		return nil
+	}
+	adapter := device.Adapter
+	if adapter == "" {
+		adapter = "unknown"
+	}
+	count := dMgr.countInvalidStatusUpdate(adapter)
+	fields := log.Fields{"device-id": device.Id, "adapter": adapter, "oper-status": operStatus, "connect-status": connStatus, "count": count, "error": verr}
+	if dMgr.permissiveStatusValidation {
+		ctxWarnw(ctx, "invalid-status-update-applied-permissive", fields)
+		return nil
+	}
+	ctxWarnw(ctx, "invalid-status-update-rejected", fields)
+	return verr
+}
+
+// countInvalidStatusUpdate bumps the invalid-update counter of an adapter and returns the
+// new count
+func (dMgr *DeviceManager) countInvalidStatusUpdate(adapter string) int64 {
+	counter, _ := dMgr.invalidStatusUpdates.LoadOrStore(adapter, new(int64))
+	return atomic.AddInt64(counter.(*int64), 1)
+}
+
+// invalidStatusUpdateCounts returns how many invalid status updates each adapter has sent
+// since this instance started
+func (dMgr *DeviceManager) invalidStatusUpdateCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	dMgr.invalidStatusUpdates.Range(func(key, value interface{}) bool {
+		counts[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return counts
+}
+
+// GetInvalidStatusUpdateCounts is the debug query returning the per-adapter counts of
+// invalid status updates
+func (handler *APIHandler) GetInvalidStatusUpdateCounts(ctx context.Context) (map[string]int64, error) {
+	ctxDebugw(ctx, "GetInvalidStatusUpdateCounts", log.Fields{})
+	return handler.deviceMgr.invalidStatusUpdateCounts(), nil
+}
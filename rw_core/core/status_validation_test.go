@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusValidationStrictMode(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.permissiveStatusValidation = false
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:0c")
+
+	// A legal update goes through
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// An illegal combination is rejected and the device keeps its statuses
+	err := dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_UNREACHABLE)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// So is a value the proto does not define
+	err = dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_Types(99), voltha.ConnectStatus_REACHABLE)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_ACTIVE, stored.OperStatus)
+	assert.Equal(t, voltha.ConnectStatus_REACHABLE, stored.ConnectStatus)
+
+	// Both rejections were counted against the adapter that sent them
+	assert.Equal(t, int64(2), dMgr.invalidStatusUpdateCounts()["adapter-1"])
+}
+
+func TestStatusValidationUnsetSentinel(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	dMgr.permissiveStatusValidation = false
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:0d")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	// The legality matrix sees the pair the device would end up with: leaving the oper
+	// status untouched does not let UNREACHABLE slip past an ACTIVE device
+	err := dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_Types(statusUnset), voltha.ConnectStatus_UNREACHABLE)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The sentinel itself stays legal: only the reported status changes
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_FAILED, voltha.ConnectStatus_Types(statusUnset)))
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_FAILED, stored.OperStatus)
+	assert.Equal(t, voltha.ConnectStatus_REACHABLE, stored.ConnectStatus)
+}
+
+func TestStatusValidationPermissiveMode(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:0e")
+
+	// In permissive mode the illegal combination is applied anyway, but counted
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_UNREACHABLE))
+	stored, err := dMgr.GetDevice(ctx, device.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, voltha.OperStatus_ACTIVE, stored.OperStatus)
+	assert.Equal(t, voltha.ConnectStatus_UNREACHABLE, stored.ConnectStatus)
+	assert.Equal(t, int64(1), dMgr.invalidStatusUpdateCounts()["adapter-1"])
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opencord/voltha-go/db/model"
+	"github.com/opencord/voltha-lib-go/v3/pkg/db"
+	"github.com/opencord/voltha-lib-go/v3/pkg/db/kvstore"
+	"github.com/opencord/voltha-lib-go/v3/pkg/kafka"
+	"github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// In-memory fakes for the device manager dependencies declared in device_manager_deps.go.
+// They let the device lifecycle logic run in unit tests without kafka or etcd.
+
+// inMemoryKVClient is a kvstore.Client backed by a map.  Only the operations the model proxy
+// and the device manager use are meaningful; the rest are no-ops.
+type inMemoryKVClient struct {
+	lock sync.Mutex
+	data map[string][]byte
+}
+
+func newInMemoryKVClient() *inMemoryKVClient {
+	return &inMemoryKVClient{data: make(map[string][]byte)}
+}
+
+func toByteSlice(value interface{}) []byte {
+	switch val := value.(type) {
+	case []byte:
+		return val
+	case string:
+		return []byte(val)
+	default:
+		return nil
+	}
+}
+
+func (c *inMemoryKVClient) List(ctx context.Context, key string) (map[string]*kvstore.KVPair, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	pairs := make(map[string]*kvstore.KVPair)
+	for k, v := range c.data {
+		if len(k) >= len(key) && k[:len(key)] == key {
+			pairs[k] = kvstore.NewKVPair(k, v, "", 0, 0)
+		}
+	}
+	return pairs, nil
+}
+
+func (c *inMemoryKVClient) Get(ctx context.Context, key string) (*kvstore.KVPair, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if v, ok := c.data[key]; ok {
+		return kvstore.NewKVPair(key, v, "", 0, 0), nil
+	}
+	return nil, nil
+}
+
+func (c *inMemoryKVClient) Put(ctx context.Context, key string, value interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.data[key] = toByteSlice(value)
+	return nil
+}
+
+func (c *inMemoryKVClient) Delete(ctx context.Context, key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.data, key)
+	return nil
+}
+
+// Reserve grants the claim to the first caller and returns the holder's value to everyone
+func (c *inMemoryKVClient) Reserve(ctx context.Context, key string, value interface{}, ttl int64) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if existing, ok := c.data[key]; ok {
+		return existing, nil
+	}
+	c.data[key] = toByteSlice(value)
+	return value, nil
+}
+
+func (c *inMemoryKVClient) ReleaseReservation(ctx context.Context, key string) error {
+	return c.Delete(ctx, key)
+}
+
+func (c *inMemoryKVClient) ReleaseAllReservations(ctx context.Context) error { return nil }
+func (c *inMemoryKVClient) RenewReservation(ctx context.Context, key string) error {
+	return nil
+}
+func (c *inMemoryKVClient) Watch(ctx context.Context, key string, withPrefix bool) chan *kvstore.Event {
+	return nil
+}
+func (c *inMemoryKVClient) AcquireLock(ctx context.Context, lockName string, timeout int) error {
+	return nil
+}
+func (c *inMemoryKVClient) ReleaseLock(lockName string) error             { return nil }
+func (c *inMemoryKVClient) IsConnectionUp(ctx context.Context) bool       { return true }
+func (c *inMemoryKVClient) CloseWatch(key string, ch chan *kvstore.Event) {}
+func (c *inMemoryKVClient) Close()                                        {}
+
+// newInMemoryClusterProxy returns a model proxy persisting into an in-memory KV client
+func newInMemoryClusterProxy() *model.Proxy {
+	backend := &db.Backend{Client: newInMemoryKVClient(), StoreType: "memory", PathPrefix: "service/voltha"}
+	return model.NewProxy(backend, "/")
+}
+
+// fakeAdapterService implements adapterService, recording the requests and answering each
+// with a successful reply unless a failure was injected for the method
+type fakeAdapterService struct {
+	lock     sync.Mutex
+	calls    []string
+	failures map[string]error
+}
+
+// failWith makes every subsequent request of the given type answer with a failure reply
+func (fas *fakeAdapterService) failWith(method string, err error) {
+	fas.lock.Lock()
+	defer fas.lock.Unlock()
+	if fas.failures == nil {
+		fas.failures = make(map[string]error)
+	}
+	fas.failures[method] = err
+}
+
+func (fas *fakeAdapterService) respond(method string) (chan *kafka.RpcResponse, error) {
+	fas.lock.Lock()
+	fas.calls = append(fas.calls, method)
+	failure := fas.failures[method]
+	fas.lock.Unlock()
+	ch := make(chan *kafka.RpcResponse, 1)
+	ch <- &kafka.RpcResponse{MType: kafka.RpcReply, Err: failure}
+	return ch, nil
+}
+
+// callCount returns how many requests of the given type were received
+func (fas *fakeAdapterService) callCount(method string) int {
+	fas.lock.Lock()
+	defer fas.lock.Unlock()
+	count := 0
+	for _, call := range fas.calls {
+		if call == method {
+			count++
+		}
+	}
+	return count
+}
+
+func (fas *fakeAdapterService) adoptDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("adoptDevice")
+}
+func (fas *fakeAdapterService) disableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("disableDevice")
+}
+func (fas *fakeAdapterService) reEnableDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("reEnableDevice")
+}
+func (fas *fakeAdapterService) rebootDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("rebootDevice")
+}
+func (fas *fakeAdapterService) deleteDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("deleteDevice")
+}
+func (fas *fakeAdapterService) getOfpDeviceInfo(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("getOfpDeviceInfo")
+}
+func (fas *fakeAdapterService) getOfpPortInfo(ctx context.Context, device *voltha.Device, portNo uint32) (chan *kafka.RpcResponse, error) {
+	return fas.respond("getOfpPortInfo")
+}
+func (fas *fakeAdapterService) reconcileDevice(ctx context.Context, device *voltha.Device) (chan *kafka.RpcResponse, error) {
+	return fas.respond("reconcileDevice")
+}
+func (fas *fakeAdapterService) downloadImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("downloadImage")
+}
+func (fas *fakeAdapterService) preflightImage(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("preflightImage")
+}
+func (fas *fakeAdapterService) getImageDownloadStatus(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("getImageDownloadStatus")
+}
+func (fas *fakeAdapterService) cancelImageDownload(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("cancelImageDownload")
+}
+func (fas *fakeAdapterService) activateImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("activateImageUpdate")
+}
+func (fas *fakeAdapterService) revertImageUpdate(ctx context.Context, device *voltha.Device, download *voltha.ImageDownload) (chan *kafka.RpcResponse, error) {
+	return fas.respond("revertImageUpdate")
+}
+func (fas *fakeAdapterService) packetOut(ctx context.Context, deviceType string, deviceID string, outPort uint32, packet *openflow_13.OfpPacketOut) (chan *kafka.RpcResponse, error) {
+	return fas.respond("packetOut")
+}
+func (fas *fakeAdapterService) updateFlowsBulk(ctx context.Context, device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
+	return fas.respond("updateFlowsBulk")
+}
+func (fas *fakeAdapterService) updateFlowsIncremental(ctx context.Context, device *voltha.Device, flowChanges *openflow_13.FlowChanges, groupChanges *openflow_13.FlowGroupChanges, flowMetadata *voltha.FlowMetadata) (chan *kafka.RpcResponse, error) {
+	return fas.respond("updateFlowsIncremental")
+}
+func (fas *fakeAdapterService) updatePmConfigs(ctx context.Context, device *voltha.Device, pmConfigs *voltha.PmConfigs) (chan *kafka.RpcResponse, error) {
+	return fas.respond("updatePmConfigs")
+}
+func (fas *fakeAdapterService) simulateAlarm(ctx context.Context, device *voltha.Device, simulateReq *voltha.SimulateAlarmRequest) (chan *kafka.RpcResponse, error) {
+	return fas.respond("simulateAlarm")
+}
+func (fas *fakeAdapterService) disablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
+	return fas.respond("disablePort")
+}
+func (fas *fakeAdapterService) enablePort(ctx context.Context, device *voltha.Device, port *voltha.Port) (chan *kafka.RpcResponse, error) {
+	return fas.respond("enablePort")
+}
+func (fas *fakeAdapterService) childDeviceLost(ctx context.Context, deviceType string, pDeviceID string, pPortNo uint32, onuID uint32) (chan *kafka.RpcResponse, error) {
+	return fas.respond("childDeviceLost")
+}
+func (fas *fakeAdapterService) startOmciTest(ctx context.Context, device *voltha.Device, omcitestrequest *voltha.OmciTestRequest) (chan *kafka.RpcResponse, error) {
+	return fas.respond("startOmciTest")
+}
+
+// fakeLogicalDeviceService implements logicalDeviceService, recording the calls
+type fakeLogicalDeviceService struct {
+	lock  sync.Mutex
+	calls []string
+	// agent, when set, is handed out by getLogicalDeviceAgent
+	agent *LogicalDeviceAgent
+}
+
+func (fls *fakeLogicalDeviceService) record(method string) {
+	fls.lock.Lock()
+	defer fls.lock.Unlock()
+	fls.calls = append(fls.calls, method)
+}
+
+// callCount returns how many calls of the given type were received
+func (fls *fakeLogicalDeviceService) callCount(method string) int {
+	fls.lock.Lock()
+	defer fls.lock.Unlock()
+	count := 0
+	for _, call := range fls.calls {
+		if call == method {
+			count++
+		}
+	}
+	return count
+}
+
+func (fls *fakeLogicalDeviceService) createLogicalDevice(ctx context.Context, device *voltha.Device) (*string, error) {
+	fls.record("createLogicalDevice")
+	id := "logical-" + device.Id
+	return &id, nil
+}
+func (fls *fakeLogicalDeviceService) deleteLogicalDevice(ctx context.Context, device *voltha.Device) error {
+	fls.record("deleteLogicalDevice")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) load(ctx context.Context, lDeviceID string) error {
+	fls.record("load")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) stopManagingLogicalDeviceWithDeviceID(ctx context.Context, id string) string {
+	fls.record("stopManagingLogicalDeviceWithDeviceID")
+	return ""
+}
+func (fls *fakeLogicalDeviceService) getLogicalDeviceAgent(ctx context.Context, logicalDeviceID string) *LogicalDeviceAgent {
+	fls.record("getLogicalDeviceAgent")
+	return fls.agent
+}
+func (fls *fakeLogicalDeviceService) getLogicalPortID(ctx context.Context, device *voltha.Device) (*voltha.LogicalPortId, error) {
+	fls.record("getLogicalPortID")
+	return nil, newNotFoundError("logical-port", device.Id)
+}
+func (fls *fakeLogicalDeviceService) updateLogicalPort(ctx context.Context, device *voltha.Device, port *voltha.Port) error {
+	fls.record("updateLogicalPort")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) updateLogicalPortFeatures(ctx context.Context, device *voltha.Device, port *voltha.Port, ofpPort *openflow_13.OfpPort) error {
+	fls.record("updateLogicalPortFeatures")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) deleteLogicalPort(ctx context.Context, lPortID *voltha.LogicalPortId) error {
+	fls.record("deleteLogicalPort")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) deleteLogicalPorts(ctx context.Context, deviceID string) error {
+	fls.record("deleteLogicalPorts")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) deleteAllLogicalPorts(ctx context.Context, device *voltha.Device) error {
+	fls.record("deleteAllLogicalPorts")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) deleteAllUNILogicalPorts(ctx context.Context, parentDevice *voltha.Device) error {
+	fls.record("deleteAllUNILogicalPorts")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) setupUNILogicalPorts(ctx context.Context, childDevice *voltha.Device) error {
+	fls.record("setupUNILogicalPorts")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) updatePortState(ctx context.Context, deviceID string, portNo uint32, state voltha.OperStatus_Types) error {
+	fls.record("updatePortState")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) updateLogicalPortStates(ctx context.Context, deviceID string, changes []portStateChange) error {
+	fls.record("updateLogicalPortStates")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) updatePortsState(ctx context.Context, device *voltha.Device, state voltha.OperStatus_Types) error {
+	fls.record("updatePortsState")
+	return nil
+}
+func (fls *fakeLogicalDeviceService) packetIn(ctx context.Context, logicalDeviceID string, port uint32, transactionID string, packet []byte) error {
+	fls.record("packetIn")
+	return nil
+}
+
+// Keep the fakes in sync with the interfaces
+var _ adapterService = &fakeAdapterService{}
+var _ logicalDeviceService = &fakeLogicalDeviceService{}
+var _ kvstore.Client = &inMemoryKVClient{}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// When a device misbehaves, the first question is which handlers processTransition
+// chose for the state change - and until now the answer hid in debug logs naming raw
+// function pointers.  The introspection below answers it directly: a preview returns
+// the ordered handler names the transition table would run for a previous/current pair,
+// computed by the same selection logic the executing path uses so the two cannot drift,
+// and every executed transition records its actual chain - per-handler durations and
+// errors included - on the device's state history entry for that transition.
+
+// handlerExecution records one executed transition handler
+type handlerExecution struct {
+	Name     string
+	Duration time.Duration
+	Error    string
+}
+
+// handlerName reduces a handler's runtime function name to its method name:
+// ".../core.(*DeviceManager).DeleteLogicalDevice-fm" becomes "DeleteLogicalDevice"
+func handlerName(handler TransitionHandler) string {
+	name := strings.TrimSuffix(funcName(handler), "-fm")
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+// effectiveTransitionHandlers is the selection half of processTransition: the table
+// lookup plus the activation workflow override.  Shared between the executing path and
+// the preview so a previewed chain cannot drift from the one that would run.
+func (dMgr *DeviceManager) effectiveTransitionHandlers(ctx context.Context, device *voltha.Device, previousState *DeviceState) []TransitionHandler {
+	handlers := dMgr.stateTransitions.GetTransitionHandler(device, previousState)
+	if handlers == nil {
+		return nil
+	}
+	// A device type with a registered activation workflow overrides the built-in
+	// activation handler sequence
+	if isActivationTransition(handlers) {
+		if custom, have := dMgr.activationWorkflowHandlers(device); have {
+			ctxDebugw(ctx, "using-registered-activation-workflow", log.Fields{"deviceId": device.Id, "deviceType": device.Type})
+			handlers = custom
+		}
+	}
+	return handlers
+}
+
+// previewTransition returns the ordered handler names the transition table would run
+// for the state change between two snapshots of a device
+func (dMgr *DeviceManager) previewTransition(ctx context.Context, previous *voltha.Device, current *voltha.Device) []string {
+	handlers := dMgr.effectiveTransitionHandlers(ctx, current, getDeviceStates(previous))
+	names := make([]string, 0, len(handlers))
+	for _, handler := range handlers {
+		names = append(names, handlerName(handler))
+	}
+	return names
+}
+
+// previewTransitionStates is the state-tuple form of previewTransition, for callers
+// holding no snapshots.  Only the root flag of the device influences the selection
+// beyond the states themselves.
+func (dMgr *DeviceManager) previewTransitionStates(ctx context.Context, root bool, previous DeviceState, current DeviceState) []string {
+	return dMgr.previewTransition(ctx,
+		&voltha.Device{Root: root, AdminState: previous.Admin, ConnectStatus: previous.Connection, OperStatus: previous.Operational},
+		&voltha.Device{Root: root, AdminState: current.Admin, ConnectStatus: current.Connection, OperStatus: current.Operational})
+}
+
+// attachHandlerExecutions records the executed chain of a transition on the state
+// history entry that transition appended, matched by its state triple from the most
+// recent entry backwards
+func (agent *DeviceAgent) attachHandlerExecutions(ctx context.Context, device *voltha.Device, executions []handlerExecution) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return
+	}
+	defer agent.requestQueue.RequestComplete()
+	for i := len(agent.stateHistory) - 1; i >= 0; i-- {
+		record := agent.stateHistory[i]
+		if record.Admin == device.AdminState && record.Oper == device.OperStatus && record.Connect == device.ConnectStatus {
+			record.Executed = executions
+			return
+		}
+	}
+}
+
+// recordExecutedTransition attaches the executed chain to the device's state history,
+// when the device is still tracked - the last handler of a deletion removes the agent
+func (dMgr *DeviceManager) recordExecutedTransition(ctx context.Context, device *voltha.Device, executions []handlerExecution) {
+	if len(executions) == 0 {
+		return
+	}
+	if agent, ok := dMgr.deviceAgents.Load(device.Id); ok {
+		agent.(*DeviceAgent).attachHandlerExecutions(ctx, device, executions)
+	}
+}
+
+// PreviewDeviceTransition is the NBI query returning the ordered handler names the
+// transition table would run between two device snapshots
+func (handler *APIHandler) PreviewDeviceTransition(ctx context.Context, previous *voltha.Device, current *voltha.Device) []string {
+	ctxDebugw(ctx, "PreviewDeviceTransition", log.Fields{"device-id": current.Id})
+	return handler.deviceMgr.previewTransition(ctx, previous, current)
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTransitionChainsPinned pins the handler chains of the documented transitions so
+// an accidental edit of the transition table is caught by name rather than by a device
+// misbehaving in the field
+func TestTransitionChainsPinned(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	state := func(admin voltha.AdminState_Types, conn voltha.ConnectStatus_Types, oper voltha.OperStatus_Types) DeviceState {
+		return DeviceState{Admin: admin, Connection: conn, Operational: oper}
+	}
+	tests := []struct {
+		name     string
+		root     bool
+		previous DeviceState
+		current  DeviceState
+		expected []string
+	}{
+		{
+			name:     "parent-activation",
+			root:     true,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_ACTIVATING),
+			current:  state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_ACTIVE),
+			expected: []string{"CreateLogicalDevice", "ActivateDevice"},
+		},
+		{
+			name:     "child-activation",
+			root:     false,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_DISCOVERED),
+			current:  state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_ACTIVE),
+			expected: []string{"SetupUNILogicalPorts", "ActivateDevice"},
+		},
+		{
+			name:     "preprovisioned-delete",
+			root:     false,
+			previous: state(voltha.AdminState_PREPROVISIONED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			current:  state(voltha.AdminState_DELETED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			expected: []string{"RunPostDeviceDelete"},
+		},
+		{
+			name:     "parent-delete",
+			root:     true,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE),
+			current:  state(voltha.AdminState_DELETED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			expected: []string{"DisableAllChildDevices", "DeleteAllUNILogicalPorts", "DeleteAllChildDevices", "DeleteAllLogicalPorts", "DeleteLogicalDevice", "RunPostDeviceDelete"},
+		},
+		{
+			name:     "child-delete",
+			root:     false,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			current:  state(voltha.AdminState_DELETED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			expected: []string{"ChildDeviceLost", "DeleteLogicalPorts", "RunPostDeviceDelete"},
+		},
+		{
+			name:     "re-enable",
+			root:     true,
+			previous: state(voltha.AdminState_DISABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			current:  state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNKNOWN, voltha.OperStatus_UNKNOWN),
+			expected: []string{"ReEnableDevice"},
+		},
+		{
+			name:     "parent-lost-connectivity",
+			root:     true,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE),
+			current:  state(voltha.AdminState_ENABLED, voltha.ConnectStatus_UNREACHABLE, voltha.OperStatus_UNKNOWN),
+			expected: []string{"DeleteAllLogicalPorts", "DeleteLogicalDevice", "DeleteAllChildDevices", "DeleteAllDeviceFlows"},
+		},
+		{
+			name:     "no-state-change",
+			root:     true,
+			previous: state(voltha.AdminState_ENABLED, voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE),
+			current:  state(voltha.AdminState_ENABLED, voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE),
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, dMgr.previewTransitionStates(ctx, test.root, test.previous, test.current))
+		})
+	}
+}
+
+// The preview computes its chain through the same selection the executing path uses, so
+// a registered activation workflow must show up in the preview too
+func TestPreviewReflectsActivationWorkflow(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	assert.Nil(t, dMgr.registerActivationWorkflow("olt", []string{activationStepActivate}))
+
+	names := dMgr.previewTransition(ctx,
+		&voltha.Device{Type: "olt", Root: true, AdminState: voltha.AdminState_ENABLED, OperStatus: voltha.OperStatus_ACTIVATING},
+		&voltha.Device{Type: "olt", Root: true, AdminState: voltha.AdminState_ENABLED, OperStatus: voltha.OperStatus_ACTIVE})
+	assert.NotContains(t, names, "CreateLogicalDevice")
+}
+
+func TestExecutedChainRecordedInHistory(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+
+	device := enableOLT(t, dMgr, "00:00:00:00:4a:51")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+
+	var executed []handlerExecution
+	waitUntil(t, 2*time.Second, "executed chain was never recorded", func() bool {
+		records, err := dMgr.getDeviceStateHistory(ctx, device.Id, time.Time{})
+		if err != nil {
+			return false
+		}
+		for _, record := range records {
+			if len(record.Executed) > 0 {
+				executed = record.Executed
+				return true
+			}
+		}
+		return false
+	})
+	assert.Equal(t, "CreateLogicalDevice", executed[0].Name)
+	for _, execution := range executed {
+		assert.Empty(t, execution.Error)
+	}
+}
+
+// The executed chain must survive the trip through the persisted encoding, and entries
+// persisted before the chain was recorded must still decode
+func TestStateHistoryEncodingCarriesExecutions(t *testing.T) {
+	records := []*DeviceStateRecord{{
+		Admin:   voltha.AdminState_ENABLED,
+		Oper:    voltha.OperStatus_ACTIVE,
+		Connect: voltha.ConnectStatus_REACHABLE,
+		Source:  stateSourceTransitionHandler,
+		At:      time.Unix(0, 1234),
+		Executed: []handlerExecution{
+			{Name: "CreateLogicalDevice", Duration: 5 * time.Millisecond},
+			{Name: "ActivateDevice", Duration: time.Millisecond, Error: "sent; but |not, ack~ed"},
+		},
+	}}
+
+	decoded := decodeStateHistory(encodeStateHistory(records))
+	assert.Equal(t, 1, len(decoded))
+	assert.Equal(t, 2, len(decoded[0].Executed))
+	assert.Equal(t, records[0].Executed[0], decoded[0].Executed[0])
+	assert.Equal(t, "ActivateDevice", decoded[0].Executed[1].Name)
+	// Separators in the error text were sanitized away, the rest survives
+	assert.Equal(t, "sent_ but _not_ ack_ed", decoded[0].Executed[1].Error)
+
+	// A five-field entry from before the chain existed still decodes
+	legacy := decodeStateHistory("ENABLED|ACTIVE|REACHABLE|adapter|1234")
+	assert.Equal(t, 1, len(legacy))
+	assert.Empty(t, legacy[0].Executed)
+}
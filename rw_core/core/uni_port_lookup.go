@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// ONOS applications identify a subscriber by the OF port number on the logical device, and
+// translating that back to the ONU used to require joining logical ports, physical ports
+// and children by hand.  The index below maps OF port number to the child device behind it
+// and, in reverse, a child device and UNI index to its OF port.  It is derived from the
+// logical ports - which already carry the deterministic numbering - and is rebuilt lazily
+// whenever the logical device is replaced, so each lookup is O(1) once built.
+
+// uniPortIndex caches the UNI lookups of one version of a logical device.  Its zero value
+// is ready for use.
+type uniPortIndex struct {
+	lock sync.Mutex
+	// indexed is the logical device version the maps below were built from; the agent
+	// replaces its logical device wholesale on every update, so a pointer comparison
+	// tells whether the maps are current
+	indexed *voltha.LogicalDevice
+	// byOfPort maps an OF port number to the child device behind it; NNI ports of the
+	// root device are not child-facing and are excluded
+	byOfPort map[uint32]string
+	// byDeviceUNI maps a child device ID and UNI index to the OF port number
+	byDeviceUNI map[string]map[uint32]uint32
+}
+
+// rebuildIfNeeded brings the maps up to date with the given logical device version
+func (idx *uniPortIndex) rebuildIfNeeded(ld *voltha.LogicalDevice, rootDeviceID string) {
+	if idx.indexed == ld {
+		return
+	}
+	idx.byOfPort = make(map[uint32]string)
+	idx.byDeviceUNI = make(map[string]map[uint32]uint32)
+	for _, lPort := range ld.Ports {
+		if lPort.DeviceId == rootDeviceID || lPort.OfpPort == nil {
+			continue
+		}
+		idx.byOfPort[lPort.OfpPort.PortNo] = lPort.DeviceId
+		uniPorts, ok := idx.byDeviceUNI[lPort.DeviceId]
+		if !ok {
+			uniPorts = make(map[uint32]uint32)
+			idx.byDeviceUNI[lPort.DeviceId] = uniPorts
+		}
+		// The UNI index is the low bits of the physical port number, matching the
+		// deterministic OF numbering
+		uniPorts[lPort.DevicePortNo&uniIndexMask] = lPort.OfpPort.PortNo
+	}
+	idx.indexed = ld
+}
+
+// childDeviceIDForOFPort returns the child device behind the OF port number
+func (agent *LogicalDeviceAgent) childDeviceIDForOFPort(ctx context.Context, ofPortNo uint32) (string, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return "", err
+	}
+	ld := agent.logicalDevice
+	agent.requestQueue.RequestComplete()
+
+	agent.uniIndex.lock.Lock()
+	defer agent.uniIndex.lock.Unlock()
+	agent.uniIndex.rebuildIfNeeded(ld, agent.rootDeviceID)
+	if deviceID, ok := agent.uniIndex.byOfPort[ofPortNo]; ok {
+		return deviceID, nil
+	}
+	return "", newNotFoundError("uni-port", ofPortNo)
+}
+
+// ofPortForChildUNI returns the OF port number of the given UNI of a child device
+func (agent *LogicalDeviceAgent) ofPortForChildUNI(ctx context.Context, childDeviceID string, uniIndex uint32) (uint32, error) {
+	if err := agent.requestQueue.WaitForGreenLight(ctx); err != nil {
+		return 0, err
+	}
+	ld := agent.logicalDevice
+	agent.requestQueue.RequestComplete()
+
+	agent.uniIndex.lock.Lock()
+	defer agent.uniIndex.lock.Unlock()
+	agent.uniIndex.rebuildIfNeeded(ld, agent.rootDeviceID)
+	if ofPortNo, ok := agent.uniIndex.byDeviceUNI[childDeviceID][uniIndex]; ok {
+		return ofPortNo, nil
+	}
+	return 0, newNotFoundError("uni", childDeviceID)
+}
+
+// getChildDeviceByOFPort returns the child device behind an OF port of a logical device
+func (ldMgr *LogicalDeviceManager) getChildDeviceByOFPort(ctx context.Context, logicalDeviceID string, ofPortNo uint32) (*voltha.Device, error) {
+	agent := ldMgr.getLogicalDeviceAgent(ctx, logicalDeviceID)
+	if agent == nil {
+		return nil, newNotFoundError("logical_device", logicalDeviceID)
+	}
+	childDeviceID, err := agent.childDeviceIDForOFPort(ctx, ofPortNo)
+	if err != nil {
+		return nil, err
+	}
+	return ldMgr.deviceMgr.GetDevice(ctx, childDeviceID)
+}
+
+// getChildUNIOFPort returns the OF port number of a UNI of a child device, resolving the
+// logical device through the child's parent
+func (ldMgr *LogicalDeviceManager) getChildUNIOFPort(ctx context.Context, childDeviceID string, uniIndex uint32) (uint32, error) {
+	childDevice, err := ldMgr.deviceMgr.GetDevice(ctx, childDeviceID)
+	if err != nil {
+		return 0, err
+	}
+	if childDevice.Root {
+		return 0, newInvalidArgumentError("device-is-not-a-child: " + childDeviceID)
+	}
+	parentDevice, err := ldMgr.deviceMgr.GetDevice(ctx, childDevice.ParentId)
+	if err != nil {
+		return 0, err
+	}
+	// For a root device the ParentId carries its logical device ID
+	agent := ldMgr.getLogicalDeviceAgent(ctx, parentDevice.ParentId)
+	if agent == nil {
+		return 0, newNotFoundError("logical_device", parentDevice.ParentId)
+	}
+	return agent.ofPortForChildUNI(ctx, childDeviceID, uniIndex)
+}
+
+// GetChildDeviceByOFPort is the NBI surface of the OF-port-to-child-device lookup
+func (handler *APIHandler) GetChildDeviceByOFPort(ctx context.Context, logicalDeviceID string, ofPortNo uint32) (*voltha.Device, error) {
+	ctxDebugw(ctx, "GetChildDeviceByOFPort", log.Fields{"logical-device-id": logicalDeviceID, "of-port": ofPortNo})
+	return handler.logicalDeviceMgr.getChildDeviceByOFPort(ctx, logicalDeviceID, ofPortNo)
+}
+
+// GetChildUNIOFPort is the NBI surface of the reverse lookup
+func (handler *APIHandler) GetChildUNIOFPort(ctx context.Context, childDeviceID string, uniIndex uint32) (uint32, error) {
+	ctxDebugw(ctx, "GetChildUNIOFPort", log.Fields{"device-id": childDeviceID, "uni-index": uniIndex})
+	return handler.logicalDeviceMgr.getChildUNIOFPort(ctx, childDeviceID, uniIndex)
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+
+	coreutils "github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newUNILookupTestAgent returns a logical device agent carrying one NNI and the UNIs of
+// two children, numbered the deterministic way
+func newUNILookupTestAgent(rootDeviceID string, ports []*voltha.LogicalPort) *LogicalDeviceAgent {
+	return &LogicalDeviceAgent{
+		logicalDeviceID: "ld-lookup",
+		rootDeviceID:    rootDeviceID,
+		requestQueue:    coreutils.NewRequestQueue(),
+		logicalDevice:   &voltha.LogicalDevice{Id: "ld-lookup", Ports: ports},
+	}
+}
+
+func uniLookupPorts(rootDeviceID string, childDeviceID string) []*voltha.LogicalPort {
+	return []*voltha.LogicalPort{
+		{Id: "nni-1", DeviceId: rootDeviceID, DevicePortNo: 1, OfpPort: &voltha.OfpPort{PortNo: 1}},
+		{Id: "uni-0", DeviceId: childDeviceID, DevicePortNo: 0, OfpPort: &voltha.OfpPort{PortNo: 0x1020}},
+		{Id: "uni-1", DeviceId: childDeviceID, DevicePortNo: 1, OfpPort: &voltha.OfpPort{PortNo: 0x1021}},
+	}
+}
+
+func TestChildDeviceLookupByOFPort(t *testing.T) {
+	ctx := context.Background()
+	agent := newUNILookupTestAgent("olt-1", uniLookupPorts("olt-1", "onu-1"))
+
+	deviceID, err := agent.childDeviceIDForOFPort(ctx, 0x1021)
+	assert.Nil(t, err)
+	assert.Equal(t, "onu-1", deviceID)
+
+	// An NNI port is not child-facing and an unknown port does not resolve
+	_, err = agent.childDeviceIDForOFPort(ctx, 1)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = agent.childDeviceIDForOFPort(ctx, 0x9999)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOFPortLookupByChildUNI(t *testing.T) {
+	ctx := context.Background()
+	agent := newUNILookupTestAgent("olt-1", uniLookupPorts("olt-1", "onu-1"))
+
+	ofPortNo, err := agent.ofPortForChildUNI(ctx, "onu-1", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0x1021), ofPortNo)
+
+	_, err = agent.ofPortForChildUNI(ctx, "onu-1", 7)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = agent.ofPortForChildUNI(ctx, "onu-9", 1)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestUNIIndexFollowsLogicalDeviceUpdates(t *testing.T) {
+	ctx := context.Background()
+	agent := newUNILookupTestAgent("olt-1", uniLookupPorts("olt-1", "onu-1"))
+	_, err := agent.childDeviceIDForOFPort(ctx, 0x1030)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The agent replaces the logical device wholesale on an update; the index follows
+	agent.logicalDevice = &voltha.LogicalDevice{Id: "ld-lookup", Ports: append(uniLookupPorts("olt-1", "onu-1"),
+		&voltha.LogicalPort{Id: "uni-2", DeviceId: "onu-2", DevicePortNo: 0, OfpPort: &voltha.OfpPort{PortNo: 0x1030}})}
+	deviceID, err := agent.childDeviceIDForOFPort(ctx, 0x1030)
+	assert.Nil(t, err)
+	assert.Equal(t, "onu-2", deviceID)
+}
+
+func TestChildDeviceByOFPortOverManager(t *testing.T) {
+	ctx := context.Background()
+	dMgr, _, _ := newLifecycleTestDeviceManager()
+	child := createTestDevice(t, dMgr, "00:00:00:00:49:01")
+
+	ldMgr := &LogicalDeviceManager{
+		deviceMgr:                      dMgr,
+		clusterDataProxy:               dMgr.clusterDataProxy,
+		logicalDeviceLoadingInProgress: make(map[string][]chan int),
+	}
+	agent := newUNILookupTestAgent("olt-1", uniLookupPorts("olt-1", child.Id))
+	ldMgr.logicalDeviceAgents.Store(agent.logicalDeviceID, agent)
+
+	resolved, err := ldMgr.getChildDeviceByOFPort(ctx, agent.logicalDeviceID, 0x1020)
+	assert.Nil(t, err)
+	assert.Equal(t, child.Id, resolved.Id)
+
+	_, err = ldMgr.getChildDeviceByOFPort(ctx, "no-such-ld", 0x1020)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opencord/voltha-go/rw_core/utils"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// After an adapter downgrade the KV store can hold devices of a type no registered
+// adapter serves.  Loading them used to create agents whose every operation failed deep
+// in getAdapterName with an error that said nothing about the real problem.  The
+// unserved-type condition is now detected when the device is loaded: the device is marked
+// with the "adapter-not-available" reason, excluded from the automated machinery that
+// would otherwise keep poking a non-existent adapter, and announced with an event listing
+// the affected devices.  When an adapter later registers the missing type the mark is
+// cleared and the devices are reconciled automatically.
+
+// adapterNotAvailableReason marks a device whose type no registered adapter serves
+const adapterNotAvailableReason = "adapter-not-available"
+
+// flagAdapterNotAvailable marks a loaded device whose type no adapter serves and raises
+// the event listing every known device in that situation for the same type
+func (dMgr *DeviceManager) flagAdapterNotAvailable(ctx context.Context, device *voltha.Device) {
+	if _, already := dMgr.unservedDevices.LoadOrStore(device.Id, device.Type); already {
+		return
+	}
+	ctxWarnw(ctx, "no-adapter-for-device-type", log.Fields{"device-id": device.Id, "device-type": device.Type})
+	if device.Reason != adapterNotAvailableReason {
+		if err := dMgr.updateDeviceReason(ctx, device.Id, adapterNotAvailableReason); err != nil {
+			ctxWarnw(ctx, "failed-to-mark-unserved-device", log.Fields{"device-id": device.Id, "error": err})
+		}
+	}
+	if dMgr.eventMgr != nil {
+		go dMgr.eventMgr.publishDeviceEvent(device, adapterNotAvailableEvent, map[string]string{
+			"device-type": device.Type,
+			"devices":     strings.Join(dMgr.unservedDevicesOfType(device.Type), ","),
+		})
+	}
+}
+
+// adapterNotAvailable reports whether the device's type currently has no adapter; such
+// devices are skipped by the automated machinery
+func (dMgr *DeviceManager) adapterNotAvailable(deviceID string) bool {
+	_, unserved := dMgr.unservedDevices.Load(deviceID)
+	return unserved
+}
+
+// unservedDevicesOfType returns the IDs of the known devices of a type no adapter serves
+func (dMgr *DeviceManager) unservedDevicesOfType(deviceType string) []string {
+	var deviceIDs []string
+	dMgr.unservedDevices.Range(func(key, value interface{}) bool {
+		if value.(string) == deviceType {
+			deviceIDs = append(deviceIDs, key.(string))
+		}
+		return true
+	})
+	return deviceIDs
+}
+
+// deviceTypeRegistered is invoked when an adapter registers a device type: devices parked
+// under the adapter-not-available mark for that type are cleared and reconciled
+func (dMgr *DeviceManager) deviceTypeRegistered(ctx context.Context, deviceType string) {
+	deviceIDs := dMgr.unservedDevicesOfType(deviceType)
+	if len(deviceIDs) == 0 {
+		return
+	}
+	ctxInfow(ctx, "adapter-now-serves-device-type", log.Fields{"device-type": deviceType, "devices": deviceIDs})
+	for _, deviceID := range deviceIDs {
+		dMgr.unservedDevices.Delete(deviceID)
+		device, err := dMgr.GetDevice(ctx, deviceID)
+		if err != nil {
+			ctxWarnw(ctx, "unserved-device-lookup-failed", log.Fields{"device-id": deviceID, "error": err})
+			continue
+		}
+		if device.Reason == adapterNotAvailableReason {
+			if err := dMgr.updateDeviceReason(ctx, deviceID, ""); err != nil {
+				ctxWarnw(ctx, "failed-to-clear-unserved-device", log.Fields{"device-id": deviceID, "error": err})
+			}
+		}
+		response := dMgr.sendReconcileDeviceRequest(ctx, device)
+		go func(id string) {
+			if res := utils.WaitForNilOrErrorResponses(dMgr.defaultTimeout, response); res != nil {
+				logger.Warnw("unserved-device-reconcile-failed", log.Fields{"device-id": id, "errors": res})
+			}
+		}(deviceID)
+	}
+}
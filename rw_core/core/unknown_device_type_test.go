@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedUnservedDevice writes a device of a type no adapter serves straight into the KV
+// store, the way an adapter downgrade leaves one behind
+func seedUnservedDevice(t *testing.T, dMgr *DeviceManager, id string) {
+	device := &voltha.Device{
+		Id:         id,
+		Type:       "xgs-olt",
+		Root:       true,
+		MacAddress: "00:00:00:00:4a:14",
+		AdminState: voltha.AdminState_ENABLED,
+	}
+	assert.Nil(t, dMgr.clusterDataProxy.AddWithID(context.Background(), "devices", id, device))
+}
+
+func TestUnknownDeviceTypeFlaggedOnLoad(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	seedUnservedDevice(t, dMgr, "unserved-1")
+
+	assert.Nil(t, dMgr.load(ctx, "unserved-1"))
+	assert.True(t, dMgr.adapterNotAvailable("unserved-1"))
+	device, err := dMgr.GetDevice(ctx, "unserved-1")
+	assert.Nil(t, err)
+	assert.Equal(t, adapterNotAvailableReason, device.Reason)
+
+	// The stuck-device sweeper leaves the parked device alone
+	dMgr.sweepStuckDevices(ctx)
+	assert.Equal(t, 0, fas.callCount("reconcileDevice"))
+}
+
+func TestDeviceTypeRegistrationClearsUnserved(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	seedUnservedDevice(t, dMgr, "unserved-2")
+	assert.Nil(t, dMgr.load(ctx, "unserved-2"))
+	assert.True(t, dMgr.adapterNotAvailable("unserved-2"))
+
+	// An adapter registering the missing type clears the mark and reconciles the device
+	assert.Nil(t, dMgr.adapterMgr.addDeviceTypes(&voltha.DeviceTypes{Items: []*voltha.DeviceType{{Id: "xgs-olt", Adapter: "adapter-1"}}}, false))
+	waitUntil(t, 2*time.Second, "unserved device was not reconciled", func() bool {
+		return fas.callCount("reconcileDevice") == 1
+	})
+	assert.False(t, dMgr.adapterNotAvailable("unserved-2"))
+	device, err := dMgr.GetDevice(ctx, "unserved-2")
+	assert.Nil(t, err)
+	assert.Equal(t, "", device.Reason)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+// Requests queued at an unreachable device time out one by one and clog the per-adapter
+// queue in the meantime.  Non-essential operations - flow pushes, PM changes, image
+// operations, reboots - are short-circuited with a fast Unavailable error while the
+// device's ConnectStatus is UNREACHABLE; operations that can restore connectivity
+// (enable, reconcile, delete) are never suppressed.  When parking is enabled a
+// suppressed request that can be replayed is held and rerun automatically once the
+// device reports REACHABLE again.
+
+// maxParkedRequests bounds the requests parked per device; beyond it the oldest are dropped
+const maxParkedRequests = 16
+
+type parkedRequest struct {
+	operation string
+	replay    func(context.Context) error
+	parked    time.Time
+}
+
+// suppressIfUnreachable returns a fast Unavailable error for a non-essential operation
+// against a device whose ConnectStatus is UNREACHABLE, and nil otherwise.  A non-nil
+// replay is parked for automatic rerun on reachability when parking is enabled.
+func (dMgr *DeviceManager) suppressIfUnreachable(ctx context.Context, agent *DeviceAgent, operation string, replay func(context.Context) error) error {
+	device, err := agent.getDevice(ctx)
+	if err != nil {
+		return err
+	}
+	if device.ConnectStatus != voltha.ConnectStatus_UNREACHABLE {
+		return nil
+	}
+	if dMgr.parkUnreachableRequests && replay != nil {
+		dMgr.parkRequest(agent.deviceID, operation, replay)
+		ctxInfow(ctx, "request-suppressed-and-parked", log.Fields{"device-id": agent.deviceID, "operation": operation})
+	} else {
+		ctxInfow(ctx, "request-suppressed-device-unreachable", log.Fields{"device-id": agent.deviceID, "operation": operation})
+	}
+	return newUnavailableError(operation, agent.deviceID, newFailedPreconditionError("device", agent.deviceID, "unreachable"))
+}
+
+func (dMgr *DeviceManager) parkRequest(deviceID string, operation string, replay func(context.Context) error) {
+	dMgr.parkedRequestsLock.Lock()
+	defer dMgr.parkedRequestsLock.Unlock()
+	if dMgr.parkedRequests == nil {
+		dMgr.parkedRequests = make(map[string][]parkedRequest)
+	}
+	parked := append(dMgr.parkedRequests[deviceID], parkedRequest{operation: operation, replay: replay, parked: time.Now()})
+	if len(parked) > maxParkedRequests {
+		parked = parked[len(parked)-maxParkedRequests:]
+	}
+	dMgr.parkedRequests[deviceID] = parked
+}
+
+// replayParkedRequests reruns the requests parked while a device was unreachable.  It is
+// invoked when the device reports REACHABLE; a replay that finds the device unreachable
+// again simply parks itself anew.
+func (dMgr *DeviceManager) replayParkedRequests(ctx context.Context, deviceID string) {
+	dMgr.parkedRequestsLock.Lock()
+	parked := dMgr.parkedRequests[deviceID]
+	delete(dMgr.parkedRequests, deviceID)
+	dMgr.parkedRequestsLock.Unlock()
+
+	for _, entry := range parked {
+		if err := entry.replay(ctx); err != nil {
+			ctxWarnw(ctx, "parked-request-replay-failed", log.Fields{"device-id": deviceID, "operation": entry.operation, "error": err})
+		}
+	}
+	if len(parked) > 0 {
+		ctxInfow(ctx, "replayed-parked-requests", log.Fields{"device-id": deviceID, "count": len(parked)})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ofp "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnreachableDeviceSuppressesRequests(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	device := enableOLT(t, dMgr, "00:00:00:00:30:01")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_UNREACHABLE))
+
+	// Non-essential operations fail fast without reaching the adapter
+	err := dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+
+	ch := make(chan interface{}, 1)
+	dMgr.updatePmConfigs(ctx, &voltha.PmConfigs{Id: device.Id}, ch)
+	err, _ = (<-ch).(error)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, fas.callCount("updatePmConfigs"))
+
+	ch = make(chan interface{}, 1)
+	dMgr.rebootDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	err, _ = (<-ch).(error)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, fas.callCount("rebootDevice"))
+
+	// Operations that can restore connectivity still go through
+	ch = make(chan interface{}, 1)
+	dMgr.disableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Equal(t, 1, fas.callCount("disableDevice"))
+	ch = make(chan interface{}, 1)
+	dMgr.enableDevice(ctx, &voltha.ID{Id: device.Id}, ch)
+	assert.Nil(t, <-ch)
+	assert.Equal(t, 1, fas.callCount("reEnableDevice"))
+}
+
+func TestParkedRequestsReplayOnReachable(t *testing.T) {
+	ctx := context.Background()
+	dMgr, fas, _ := newLifecycleTestDeviceManager()
+	dMgr.parkUnreachableRequests = true
+	device := enableOLT(t, dMgr, "00:00:00:00:30:02")
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_UNREACHABLE))
+
+	// The suppressed flow push is parked
+	err := dMgr.addFlowsAndGroups(ctx, device.Id, []*ofp.OfpFlowStats{{Id: 1}}, nil, nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, fas.callCount("updateFlowsBulk"))
+
+	// Reachability triggers the replay, which now reaches the adapter
+	assert.Nil(t, dMgr.updateDeviceStatus(ctx, device.Id, voltha.OperStatus_ACTIVE, voltha.ConnectStatus_REACHABLE))
+	waitUntil(t, 2*time.Second, "parked flow push was not replayed", func() bool {
+		return fas.callCount("updateFlowsBulk") == 1
+	})
+}
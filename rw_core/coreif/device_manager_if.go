@@ -41,4 +41,6 @@ type DeviceManager interface {
 	DeleteAllUNILogicalPorts(ctx context.Context, curr *voltha.Device) error
 	DeleteAllLogicalPorts(ctx context.Context, curr *voltha.Device) error
 	DeleteAllDeviceFlows(ctx context.Context, curr *voltha.Device) error
+	ReEnableDevice(ctx context.Context, curr *voltha.Device) error
+	ActivateDevice(ctx context.Context, curr *voltha.Device) error
 }
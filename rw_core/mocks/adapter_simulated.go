@@ -0,0 +1,248 @@
+/*
+ * Copyright 2020-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/opencord/voltha-lib-go/v3/pkg/adapters/adapterif"
+	com "github.com/opencord/voltha-lib-go/v3/pkg/adapters/common"
+	"github.com/opencord/voltha-lib-go/v3/pkg/log"
+	of "github.com/opencord/voltha-protos/v3/go/openflow_13"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SimulatedAdapterConfig controls how the simulated adapter behaves.  Latencies are applied
+// before a request is handled; the failure switches make the matching requests fail so error
+// paths can be exercised in CI.
+type SimulatedAdapterConfig struct {
+	AdoptLatency      time.Duration
+	FlowLatency       time.Duration
+	ImageLatency      time.Duration
+	FailAdopt         bool
+	FailFlowUpdate    bool
+	FailImageDownload bool
+	ChildDeviceType   string        // device type announced for synthetic children
+	ChildrenPerCycle  int           // children announced on each cycle
+	ChildInterval     time.Duration // time between announcement cycles
+}
+
+// SimulatedAdapter is a lightweight in-tree adapter used to drive DeviceManager flows without
+// a real adapter deployment.  It adopts devices, accepts flow and image requests with
+// configurable latencies and failure injection, and can announce synthetic child devices on a
+// schedule.
+type SimulatedAdapter struct {
+	flows        map[uint64]*voltha.OfpFlowStats
+	lock         sync.Mutex
+	config       SimulatedAdapterConfig
+	childSeq     int
+	stopChildren chan struct{}
+	Adapter
+}
+
+// NewSimulatedAdapter creates a simulated adapter instance
+func NewSimulatedAdapter(cp adapterif.CoreProxy, config SimulatedAdapterConfig) *SimulatedAdapter {
+	return &SimulatedAdapter{
+		flows:  map[uint64]*voltha.OfpFlowStats{},
+		config: config,
+		Adapter: Adapter{
+			coreProxy: cp,
+		},
+	}
+}
+
+// pause applies one of the configured latencies
+func (sa *SimulatedAdapter) pause(latency time.Duration) {
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// Adopt_device activates the device after the configured latency, or rejects it when adopt
+// failures are being injected
+func (sa *SimulatedAdapter) Adopt_device(device *voltha.Device) error { // nolint
+	sa.pause(sa.config.AdoptLatency)
+	if sa.config.FailAdopt {
+		return status.Errorf(codes.Internal, "simulated-adopt-failure-%s", device.Id)
+	}
+	go func() {
+		d := proto.Clone(device).(*voltha.Device)
+		d.Vendor = "simulated_adapter"
+		d.Model = "go-simulated"
+		d.SerialNumber = com.GetRandomSerialNumber()
+		d.MacAddress = strings.ToUpper(com.GetRandomMacAddress())
+		sa.storeDevice(d)
+		if err := sa.coreProxy.DeviceUpdate(context.TODO(), d); err != nil {
+			logger.Errorw("device-update-failed", log.Fields{"device-id": d.Id, "error": err})
+			return
+		}
+		if err := sa.coreProxy.PortCreated(context.TODO(), d.Id, &voltha.Port{
+			PortNo:     2,
+			Label:      fmt.Sprintf("nni-%d", 2),
+			Type:       voltha.Port_ETHERNET_NNI,
+			OperStatus: voltha.OperStatus_ACTIVE,
+		}); err != nil {
+			logger.Errorw("port-created-failed", log.Fields{"device-id": d.Id, "error": err})
+			return
+		}
+		if err := sa.coreProxy.PortCreated(context.TODO(), d.Id, &voltha.Port{
+			PortNo:     1,
+			Label:      fmt.Sprintf("pon-%d", 1),
+			Type:       voltha.Port_PON_OLT,
+			OperStatus: voltha.OperStatus_ACTIVE,
+		}); err != nil {
+			logger.Errorw("port-created-failed", log.Fields{"device-id": d.Id, "error": err})
+			return
+		}
+		if err := sa.coreProxy.DeviceStateUpdate(context.TODO(), d.Id,
+			voltha.ConnectStatus_REACHABLE, voltha.OperStatus_ACTIVE); err != nil {
+			logger.Errorw("device-state-update-failed", log.Fields{"device-id": d.Id, "error": err})
+		}
+	}()
+	return nil
+}
+
+// Update_flows_bulk replaces the flow table after the configured latency, or fails when flow
+// failures are being injected
+func (sa *SimulatedAdapter) Update_flows_bulk(device *voltha.Device, flows *voltha.Flows, groups *voltha.FlowGroups, flowMetadata *voltha.FlowMetadata) error { // nolint
+	sa.pause(sa.config.FlowLatency)
+	if sa.config.FailFlowUpdate {
+		return status.Errorf(codes.Internal, "simulated-flow-failure-%s", device.Id)
+	}
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	sa.flows = map[uint64]*voltha.OfpFlowStats{}
+	for _, f := range flows.Items {
+		sa.flows[f.Id] = f
+	}
+	return nil
+}
+
+// Update_flows_incrementally applies the flow changes after the configured latency, or fails
+// when flow failures are being injected
+func (sa *SimulatedAdapter) Update_flows_incrementally(device *voltha.Device, flows *of.FlowChanges, groups *of.FlowGroupChanges, flowMetadata *voltha.FlowMetadata) error { // nolint
+	sa.pause(sa.config.FlowLatency)
+	if sa.config.FailFlowUpdate {
+		return status.Errorf(codes.Internal, "simulated-flow-failure-%s", device.Id)
+	}
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	if flows.ToAdd != nil {
+		for _, f := range flows.ToAdd.Items {
+			sa.flows[f.Id] = f
+		}
+	}
+	if flows.ToRemove != nil {
+		for _, f := range flows.ToRemove.Items {
+			delete(sa.flows, f.Id)
+		}
+	}
+	return nil
+}
+
+// Download_image acknowledges the download after the configured latency, or fails when image
+// failures are being injected
+func (sa *SimulatedAdapter) Download_image(device *voltha.Device, request *voltha.ImageDownload) (*voltha.ImageDownload, error) { // nolint
+	sa.pause(sa.config.ImageLatency)
+	if sa.config.FailImageDownload {
+		return nil, status.Errorf(codes.Internal, "simulated-image-failure-%s", device.Id)
+	}
+	download := proto.Clone(request).(*voltha.ImageDownload)
+	download.DownloadState = voltha.ImageDownload_DOWNLOAD_SUCCEEDED
+	return download, nil
+}
+
+// Get_image_download_status reports the download as complete
+func (sa *SimulatedAdapter) Get_image_download_status(device *voltha.Device, request *voltha.ImageDownload) (*voltha.ImageDownload, error) { // nolint
+	download := proto.Clone(request).(*voltha.ImageDownload)
+	download.DownloadState = voltha.ImageDownload_DOWNLOAD_SUCCEEDED
+	return download, nil
+}
+
+// StartChildAnnouncements announces synthetic child devices under the parent on the configured
+// schedule until StopChildAnnouncements is called
+func (sa *SimulatedAdapter) StartChildAnnouncements(parentDeviceID string) {
+	if sa.config.ChildInterval <= 0 || sa.config.ChildrenPerCycle <= 0 {
+		return
+	}
+	sa.lock.Lock()
+	if sa.stopChildren != nil {
+		sa.lock.Unlock()
+		return
+	}
+	sa.stopChildren = make(chan struct{})
+	stop := sa.stopChildren
+	sa.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(sa.config.ChildInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for i := 0; i < sa.config.ChildrenPerCycle; i++ {
+					sa.announceChild(parentDeviceID)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopChildAnnouncements stops the child announcement schedule
+func (sa *SimulatedAdapter) StopChildAnnouncements() {
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	if sa.stopChildren != nil {
+		close(sa.stopChildren)
+		sa.stopChildren = nil
+	}
+}
+
+// announceChild reports one synthetic child device to the core
+func (sa *SimulatedAdapter) announceChild(parentDeviceID string) {
+	sa.lock.Lock()
+	sa.childSeq++
+	seq := sa.childSeq
+	sa.lock.Unlock()
+	if _, err := sa.coreProxy.ChildDeviceDetected(
+		context.TODO(),
+		parentDeviceID,
+		1,
+		sa.config.ChildDeviceType,
+		startingUNIPortNo+seq,
+		sa.config.ChildDeviceType,
+		com.GetRandomSerialNumber(),
+		int64(seq)); err != nil {
+		logger.Errorw("child-device-detected-failed", log.Fields{"parent-device-id": parentDeviceID, "error": err})
+	}
+}
+
+// GetFlowCount returns the number of flows held for the device
+func (sa *SimulatedAdapter) GetFlowCount() int {
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	return len(sa.flows)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2020-present Open Networking Foundation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mocks
+
+import (
+	"testing"
+
+	"github.com/opencord/voltha-lib-go/v3/pkg/adapters"
+	"github.com/opencord/voltha-protos/v3/go/voltha"
+)
+
+func TestSimulatedAdapterImplementsIAdapter(t *testing.T) {
+	adapter := NewSimulatedAdapter(nil, SimulatedAdapterConfig{})
+
+	if _, ok := interface{}(adapter).(adapters.IAdapter); !ok {
+		t.Error("simulated adapter does not implement voltha-lib-go/v2/pkg/adapters/IAdapter interface")
+	}
+}
+
+func TestSimulatedAdapterFailureInjection(t *testing.T) {
+	adapter := NewSimulatedAdapter(nil, SimulatedAdapterConfig{FailAdopt: true, FailFlowUpdate: true, FailImageDownload: true})
+	device := &voltha.Device{Id: "1234"}
+
+	if err := adapter.Adopt_device(device); err == nil {
+		t.Error("expected injected adopt failure")
+	}
+	if err := adapter.Update_flows_bulk(device, &voltha.Flows{}, &voltha.FlowGroups{}, nil); err == nil {
+		t.Error("expected injected flow failure")
+	}
+	if _, err := adapter.Download_image(device, &voltha.ImageDownload{Id: "1234"}); err == nil {
+		t.Error("expected injected image failure")
+	}
+}
+
+func TestSimulatedAdapterFlowTable(t *testing.T) {
+	adapter := NewSimulatedAdapter(nil, SimulatedAdapterConfig{})
+	device := &voltha.Device{Id: "1234"}
+
+	flows := &voltha.Flows{Items: []*voltha.OfpFlowStats{{Id: 1}, {Id: 2}}}
+	if err := adapter.Update_flows_bulk(device, flows, &voltha.FlowGroups{}, nil); err != nil {
+		t.Errorf("unexpected bulk flow failure: %v", err)
+	}
+	if count := adapter.GetFlowCount(); count != 2 {
+		t.Errorf("expected 2 flows, got %d", count)
+	}
+}
@@ -93,6 +93,16 @@ func (dm *DeviceManager) RunPostDeviceDelete(ctx context.Context, cDevice *volth
 	return nil
 }
 
+// ReEnableDevice -
+func (dm *DeviceManager) ReEnableDevice(ctx context.Context, cDevice *voltha.Device) error {
+	return nil
+}
+
+// ActivateDevice -
+func (dm *DeviceManager) ActivateDevice(ctx context.Context, cDevice *voltha.Device) error {
+	return nil
+}
+
 // childDeviceLost -
 func (dm *DeviceManager) ChildDeviceLost(ctx context.Context, cDevice *voltha.Device) error {
 	return nil
@@ -19,6 +19,7 @@ package utils
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 type request struct {
@@ -33,6 +34,11 @@ type RequestQueue struct {
 
 	last, current  *request
 	lastCompleteCh <-chan struct{}
+
+	// queued counts the requests waiting or in progress; currentSince is when the current
+	// request got the green light.  Both only feed the diagnostics accessors below.
+	queued       int
+	currentSince time.Time
 }
 
 // NewRequestQueue creates a new request queue
@@ -58,6 +64,7 @@ func (rq *RequestQueue) WaitForGreenLight(ctx context.Context) error {
 		rq.last.next, r.prev = r, rq.last
 	}
 	rq.last = r
+	rq.queued++
 	rq.mutex.Unlock()
 
 	// wait for our turn
@@ -72,6 +79,7 @@ func (rq *RequestQueue) WaitForGreenLight(ctx context.Context) error {
 			// chan has been closed, so the lock has been acquired
 			// context is canceled, so just release the lock immediately
 			rq.current = r
+			rq.queued--
 			rq.releaseWithoutLock()
 		default:
 			// on abort, skip our position in the queue
@@ -84,12 +92,16 @@ func (rq *RequestQueue) WaitForGreenLight(ctx context.Context) error {
 				rq.last = r.prev
 				r.prev.next = nil
 			}
+			rq.queued--
 		}
 		return ctx.Err()
 
 	case <-waitingOn:
 		// lock is acquired
 		rq.current = r
+		rq.mutex.Lock()
+		rq.currentSince = time.Now()
+		rq.mutex.Unlock()
 		return nil
 	}
 }
@@ -100,10 +112,30 @@ func (rq *RequestQueue) RequestComplete() {
 	rq.mutex.Lock()
 	defer rq.mutex.Unlock()
 
+	rq.queued--
 	rq.releaseWithoutLock()
 }
 
+// Depth returns how many requests are waiting for or holding the green light
+func (rq *RequestQueue) Depth() int {
+	rq.mutex.Lock()
+	defer rq.mutex.Unlock()
+	return rq.queued
+}
+
+// InProgressFor returns how long the current request has held the green light; zero when
+// nothing is in progress
+func (rq *RequestQueue) InProgressFor() time.Duration {
+	rq.mutex.Lock()
+	defer rq.mutex.Unlock()
+	if rq.queued == 0 || rq.currentSince.IsZero() {
+		return 0
+	}
+	return time.Since(rq.currentSince)
+}
+
 func (rq *RequestQueue) releaseWithoutLock() {
+	rq.currentSince = time.Time{}
 	// Notify the next waiting request.  This will panic if the lock is released more than once.
 	close(rq.current.notifyOnComplete)
 